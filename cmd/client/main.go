@@ -5,21 +5,32 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os"
 	"time"
 
 	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
 )
 
 // Configuration constants
 const (
-	DefaultMQTTHost = "localhost"
-	DefaultMQTTPort = 1883
+	DefaultMQTTHost   = "localhost"
+	DefaultMQTTPort   = 1883
+	TaskTopic         = "tasks/new"
+	WorkflowTaskTopic = "tasks/workflow/%s"
+	BatchTopic        = "orchestrator/batch"
+
+	// Capability negotiation with the orchestrator
+	CapabilitiesTopic      = "orchestrator/capabilities"
+	CapabilitiesReplyTopic = "orchestrator/capabilities/reply/%s"
+	CapabilitiesTimeout    = 5 * time.Second
 )
 
 // WorkflowClient provides a standalone interface to trigger workflows
 type WorkflowClient struct {
-	mqttClient *mqtt.Client
+	mqttClient mqtt.ClientInterface
 	ctx        context.Context
 	cancel     context.CancelFunc
 }
@@ -62,7 +73,7 @@ func (c *WorkflowClient) CreateDocument(docType, outputFile string) error {
 		},
 	}
 
-	data, err := json.Marshal(request)
+	data, err := types.WrapMessage(types.MessageTypeWorkflowRequest, "", request)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
@@ -77,29 +88,163 @@ func (c *WorkflowClient) CreateDocument(docType, outputFile string) error {
 	return c.mqttClient.Publish(ctx, "orchestrator/workflow", data)
 }
 
-// ListAvailableDocuments returns available document types
-func (c *WorkflowClient) ListAvailableDocuments() []string {
-	return []string{
-		"go_coding_standards",
-		"python_coding_standards",
-		"bash_coding_standards",
-		"project_documentation",
-		"api_documentation",
-		// Add more document types here as they are implemented
+// PublishTask validates a Task or WorkflowTask JSON document and publishes it.
+// Documents carrying workflow_id or stage are treated as workflow tasks and sent
+// to their stage topic; anything else is published as a plain task.
+func (c *WorkflowClient) PublishTask(data []byte) (string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", fmt.Errorf("invalid task JSON: %w", err)
+	}
+
+	var topic string
+	var payload []byte
+	var err error
+
+	_, hasWorkflowID := fields["workflow_id"]
+	_, hasStage := fields["stage"]
+	if hasWorkflowID || hasStage {
+		var task types.WorkflowTask
+		if err := json.Unmarshal(data, &task); err != nil {
+			return "", fmt.Errorf("invalid workflow task: %w", err)
+		}
+		if task.CreatedAt.IsZero() {
+			task.CreatedAt = time.Now()
+		}
+		if err := task.Validate(); err != nil {
+			return "", fmt.Errorf("invalid workflow task: %w", err)
+		}
+		topic = fmt.Sprintf(WorkflowTaskTopic, task.Stage)
+		payload, err = types.WrapMessage(types.MessageTypeWorkflowTask, task.WorkflowID, task)
+	} else {
+		var task types.Task
+		if err := json.Unmarshal(data, &task); err != nil {
+			return "", fmt.Errorf("invalid task: %w", err)
+		}
+		if task.CreatedAt.IsZero() {
+			task.CreatedAt = time.Now()
+		}
+		if err := task.Validate(); err != nil {
+			return "", fmt.Errorf("invalid task: %w", err)
+		}
+		topic = TaskTopic
+		payload, err = types.WrapMessage(types.MessageTypeTask, task.ID, task)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal task: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := c.mqttClient.Publish(ctx, topic, payload); err != nil {
+		return "", err
+	}
+
+	return topic, nil
+}
+
+// PublishBatch validates a batch request JSON document and publishes it to
+// the orchestrator. A non-empty policy overrides the document's own.
+func (c *WorkflowClient) PublishBatch(data []byte, policy string) (int, error) {
+	var request struct {
+		Documents []json.RawMessage `json:"documents"`
+		Policy    string            `json:"policy,omitempty"`
+	}
+	if err := json.Unmarshal(data, &request); err != nil {
+		return 0, fmt.Errorf("invalid batch JSON: %w", err)
+	}
+	if len(request.Documents) == 0 {
+		return 0, fmt.Errorf("batch has no documents")
+	}
+	if policy != "" {
+		request.Policy = policy
+	}
+
+	payload, err := types.WrapMessage(types.MessageTypeBatchRequest, "", request)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := c.mqttClient.Publish(ctx, BatchTopic, payload); err != nil {
+		return 0, err
+	}
+	return len(request.Documents), nil
+}
+
+// readTaskInput reads task JSON from a file path, or from stdin when path is "-"
+func readTaskInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// QueryCapabilities asks the orchestrator for the document types and models
+// it currently supports
+func (c *WorkflowClient) QueryCapabilities(timeout time.Duration) (types.OrchestratorCapabilities, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, timeout)
+	defer cancel()
+
+	requestID := fmt.Sprintf("capabilities-%d", time.Now().UnixNano())
+	replyTopic := fmt.Sprintf(CapabilitiesReplyTopic, requestID)
+
+	replies := make(chan types.OrchestratorCapabilities, 1)
+	err := c.mqttClient.Subscribe(ctx, replyTopic, func(payload []byte) {
+		var capabilities types.OrchestratorCapabilities
+		envelope, err := types.UnwrapMessage(payload, types.MessageTypeCapabilities, &capabilities)
+		if err != nil {
+			log.Printf("Ignoring malformed capabilities reply: %v", err)
+			return
+		}
+		if envelope.CorrelationID != requestID {
+			return
+		}
+		select {
+		case replies <- capabilities:
+		default:
+		}
+	})
+	if err != nil {
+		return types.OrchestratorCapabilities{}, err
+	}
+	defer c.mqttClient.Unsubscribe(context.Background(), replyTopic)
+
+	data, err := types.WrapMessage(types.MessageTypeCapabilitiesReq, requestID, types.CapabilitiesRequest{ReplyTo: replyTopic})
+	if err != nil {
+		return types.OrchestratorCapabilities{}, fmt.Errorf("failed to marshal capabilities request: %w", err)
+	}
+	if err := c.mqttClient.Publish(ctx, CapabilitiesTopic, data); err != nil {
+		return types.OrchestratorCapabilities{}, err
+	}
+
+	select {
+	case capabilities := <-replies:
+		return capabilities, nil
+	case <-ctx.Done():
+		return types.OrchestratorCapabilities{}, fmt.Errorf("no capabilities reply from orchestrator: %w", ctx.Err())
 	}
 }
 
-// ListAvailableModels returns available local models
+// ListAvailableDocuments returns the document types the orchestrator supports
+func (c *WorkflowClient) ListAvailableDocuments() ([]string, error) {
+	capabilities, err := c.QueryCapabilities(CapabilitiesTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return capabilities.DocumentTypes, nil
+}
+
+// ListAvailableModels returns the local models the orchestrator reports as configured
 func (c *WorkflowClient) ListAvailableModels() ([]string, error) {
-	// This would query the model manager for available models
-	// For now, return a static list based on configuration
-	return []string{
-		"qwen-omni",
-		"qwen-vl",
-		"llava",
-		"mimo",
-		"qwen-embedding-4b",
-	}, nil
+	capabilities, err := c.QueryCapabilities(CapabilitiesTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return capabilities.Models, nil
 }
 
 func main() {
@@ -108,11 +253,14 @@ func main() {
 		mqttHost    = flag.String("mqtt-host", DefaultMQTTHost, "MQTT broker host")
 		mqttPort    = flag.Int("mqtt-port", DefaultMQTTPort, "MQTT broker port")
 		docType     = flag.String("doc-type", "", "Document type to create")
-		outputFile  = flag.String("output", "", "Output file path")
+		outputFile  = flag.String("output", "", "Output file path, relative to the orchestrator's -output-root")
 		list        = flag.Bool("list", false, "List available document types")
 		listModels  = flag.Bool("list-models", false, "List available local models")
 		preferLocal = flag.Bool("prefer-local", false, "Prefer local models over external AI helpers")
 		modelType   = flag.String("model-type", "", "Specify model type for task")
+		taskFile    = flag.String("task-file", "", "Publish a Task or WorkflowTask JSON file (use - for stdin)")
+		batchFile   = flag.String("batch-file", "", "Publish a batch of workflow requests from a JSON file (use - for stdin)")
+		batchPolicy = flag.String("batch-policy", "", "Batch failure policy: stop_on_first_error (default) or continue")
 		verbose     = flag.Bool("verbose", false, "Enable verbose logging")
 	)
 	flag.Parse()
@@ -131,10 +279,42 @@ func main() {
 		log.Fatalf("Failed to connect to MQTT broker: %v", err)
 	}
 
+	// Publish an arbitrary task document
+	if *taskFile != "" {
+		data, err := readTaskInput(*taskFile)
+		if err != nil {
+			log.Fatalf("Failed to read task input: %v", err)
+		}
+		topic, err := client.PublishTask(data)
+		if err != nil {
+			log.Fatalf("Failed to publish task: %v", err)
+		}
+		log.Printf("Task published to %s", topic)
+		return
+	}
+
+	// Publish a batch of documents
+	if *batchFile != "" {
+		data, err := readTaskInput(*batchFile)
+		if err != nil {
+			log.Fatalf("Failed to read batch input: %v", err)
+		}
+		count, err := client.PublishBatch(data, *batchPolicy)
+		if err != nil {
+			log.Fatalf("Failed to publish batch: %v", err)
+		}
+		log.Printf("Batch of %d documents published to %s", count, BatchTopic)
+		return
+	}
+
 	// Handle list commands
 	if *list {
+		documentTypes, err := client.ListAvailableDocuments()
+		if err != nil {
+			log.Fatalf("Failed to list document types: %v", err)
+		}
 		fmt.Println("Available document types:")
-		for _, docType := range client.ListAvailableDocuments() {
+		for _, docType := range documentTypes {
 			fmt.Printf("  - %s\n", docType)
 		}
 		return
@@ -162,8 +342,12 @@ func main() {
 	}
 
 	// Check if document type is supported
+	documentTypes, err := client.ListAvailableDocuments()
+	if err != nil {
+		log.Fatalf("Failed to query supported document types: %v", err)
+	}
 	supported := false
-	for _, availableType := range client.ListAvailableDocuments() {
+	for _, availableType := range documentTypes {
 		if availableType == *docType {
 			supported = true
 			break
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// message is one publish seen by recordingClient
+type message struct {
+	topic   string
+	payload []byte
+}
+
+// recordingClient records publishes and delivers them to subscribers
+type recordingClient struct {
+	mu        sync.Mutex
+	published []message
+	handlers  map[string]mqtt.MessageHandler
+	onPublish func(topic string, payload []byte) // Answers publishes, as another service would
+}
+
+func (c *recordingClient) Connect(context.Context) error { return nil }
+func (c *recordingClient) Disconnect()                   {}
+func (c *recordingClient) IsConnected() bool             { return true }
+
+func (c *recordingClient) Subscribe(ctx context.Context, topic string, handler mqtt.MessageHandler) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.handlers == nil {
+		c.handlers = make(map[string]mqtt.MessageHandler)
+	}
+	c.handlers[topic] = handler
+	return nil
+}
+
+func (c *recordingClient) Unsubscribe(ctx context.Context, topic string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.handlers, topic)
+	return nil
+}
+
+func (c *recordingClient) Publish(ctx context.Context, topic string, payload []byte) error {
+	c.mu.Lock()
+	c.published = append(c.published, message{topic, payload})
+	onPublish := c.onPublish
+	c.mu.Unlock()
+
+	if onPublish != nil {
+		onPublish(topic, payload)
+	}
+	return nil
+}
+
+// deliver passes payload to the handler subscribed to topic, if any
+func (c *recordingClient) deliver(topic string, payload []byte) {
+	c.mu.Lock()
+	handler := c.handlers[topic]
+	c.mu.Unlock()
+
+	if handler != nil {
+		handler(payload)
+	}
+}
+
+// newTestClient creates a workflow client publishing through a recordingClient
+func newTestClient(t *testing.T) (*WorkflowClient, *recordingClient) {
+	t.Helper()
+	broker := &recordingClient{}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &WorkflowClient{mqttClient: broker, ctx: ctx, cancel: cancel}, broker
+}
+
+func TestPublishTask(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantTopic string
+		wantType  types.MessageType
+		wantErr   bool
+	}{
+		{"plain task", `{"id":"t1","type":"echo","payload":{"message":"hi"}}`, TaskTopic, types.MessageTypeTask, false},
+		{"workflow task", `{"id":"t2","type":"create_document","workflow_id":"wf-1","stage":"development","required_role":"developer"}`, "tasks/workflow/development", types.MessageTypeWorkflowTask, false},
+		{"missing type", `{"id":"t3"}`, "", "", true},
+		{"workflow task without role", `{"id":"t4","type":"create_document","workflow_id":"wf-1","stage":"review"}`, "", "", true},
+		{"stage without workflow", `{"id":"t5","type":"create_document","stage":"review","required_role":"reviewer"}`, "", "", true},
+		{"not json", `task`, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, broker := newTestClient(t)
+
+			topic, err := client.PublishTask([]byte(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("PublishTask succeeded, want a validation error")
+				}
+				if len(broker.published) != 0 {
+					t.Errorf("published %d messages for an invalid task", len(broker.published))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PublishTask: %v", err)
+			}
+			if topic != tt.wantTopic || len(broker.published) != 1 || broker.published[0].topic != tt.wantTopic {
+				t.Fatalf("published to %q, want %q", topic, tt.wantTopic)
+			}
+
+			var task types.WorkflowTask
+			if _, err := types.UnwrapMessage(broker.published[0].payload, tt.wantType, &task); err != nil {
+				t.Fatalf("published payload: %v", err)
+			}
+			if task.CreatedAt.IsZero() {
+				t.Error("published task has no created_at")
+			}
+		})
+	}
+}
+
+func TestReadTaskInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "task.json")
+	if err := os.WriteFile(path, []byte(`{"id":"t1"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := readTaskInput(path); err != nil || string(data) != `{"id":"t1"}` {
+		t.Errorf("readTaskInput = %q, %v", data, err)
+	}
+	if _, err := readTaskInput(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("readTaskInput succeeded for a missing file")
+	}
+}
+
+// capabilitiesResponder answers capabilities requests with capabilities,
+// replying under correlationID, or the request's own ID when it is empty
+func capabilitiesResponder(t *testing.T, broker *recordingClient, capabilities types.OrchestratorCapabilities, correlationID string) func(string, []byte) {
+	return func(topic string, payload []byte) {
+		if topic != CapabilitiesTopic {
+			return
+		}
+		var request types.CapabilitiesRequest
+		envelope, err := types.UnwrapMessage(payload, types.MessageTypeCapabilitiesReq, &request)
+		if err != nil {
+			t.Errorf("capabilities request: %v", err)
+			return
+		}
+		id := correlationID
+		if id == "" {
+			id = envelope.CorrelationID
+		}
+		reply, err := types.WrapMessage(types.MessageTypeCapabilities, id, capabilities)
+		if err != nil {
+			t.Errorf("WrapMessage: %v", err)
+			return
+		}
+		broker.deliver(request.ReplyTo, reply)
+	}
+}
+
+func TestListAvailableDocuments(t *testing.T) {
+	client, broker := newTestClient(t)
+	advertised := types.OrchestratorCapabilities{
+		DocumentTypes: []string{"api_guide", "incident_runbook"},
+		Models:        []string{"qwen-omni-3b"},
+	}
+	broker.onPublish = capabilitiesResponder(t, broker, advertised, "")
+
+	documents, err := client.ListAvailableDocuments()
+	if err != nil {
+		t.Fatalf("ListAvailableDocuments: %v", err)
+	}
+	if !slices.Contains(documents, "incident_runbook") {
+		t.Errorf("documents = %v, want the advertised custom type", documents)
+	}
+	models, err := client.ListAvailableModels()
+	if err != nil || !slices.Equal(models, advertised.Models) {
+		t.Errorf("ListAvailableModels = %v, %v, want %v", models, err, advertised.Models)
+	}
+
+	// Reply topics are released once answered
+	if len(broker.handlers) != 0 {
+		t.Errorf("%d reply subscriptions left open", len(broker.handlers))
+	}
+}
+
+func TestQueryCapabilitiesTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond bool
+	}{
+		{"no orchestrator", false},
+		{"reply for another request", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, broker := newTestClient(t)
+			if tt.respond {
+				broker.onPublish = capabilitiesResponder(t, broker, types.OrchestratorCapabilities{DocumentTypes: []string{"api_guide"}}, "capabilities-other")
+			}
+			if _, err := client.QueryCapabilities(20 * time.Millisecond); err == nil {
+				t.Error("QueryCapabilities succeeded without a matching reply")
+			}
+		})
+	}
+}
+
+func TestPublishBatch(t *testing.T) {
+	documents := `{"documents":[{"type":"api_guide"},{"type":"user_manual"}],"policy":"continue"}`
+
+	tests := []struct {
+		name       string
+		input      string
+		policy     string
+		wantCount  int
+		wantPolicy string
+		wantErr    bool
+	}{
+		{name: "document policy", input: documents, wantCount: 2, wantPolicy: "continue"},
+		{name: "flag overrides policy", input: documents, policy: "stop_on_first_error", wantCount: 2, wantPolicy: "stop_on_first_error"},
+		{name: "no documents", input: `{"documents":[]}`, wantErr: true},
+		{name: "not json", input: `batch`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, broker := newTestClient(t)
+
+			count, err := client.PublishBatch([]byte(tt.input), tt.policy)
+			if tt.wantErr {
+				if err == nil || len(broker.published) != 0 {
+					t.Errorf("PublishBatch() error = %v after %d publishes, want a validation error", err, len(broker.published))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("PublishBatch() error = %v", err)
+			}
+			if count != tt.wantCount || len(broker.published) != 1 || broker.published[0].topic != BatchTopic {
+				t.Fatalf("published %d documents to %v, want %d to %s", count, broker.published, tt.wantCount, BatchTopic)
+			}
+
+			var request struct {
+				Documents []map[string]string `json:"documents"`
+				Policy    string              `json:"policy"`
+			}
+			if _, err := types.UnwrapMessage(broker.published[0].payload, types.MessageTypeBatchRequest, &request); err != nil {
+				t.Fatalf("published payload: %v", err)
+			}
+			if request.Policy != tt.wantPolicy || len(request.Documents) != tt.wantCount {
+				t.Errorf("published batch = %+v, want %d documents with policy %q", request, tt.wantCount, tt.wantPolicy)
+			}
+		})
+	}
+}
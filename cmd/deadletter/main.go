@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// Configuration constants
+const (
+	DefaultMQTTHost      = "localhost"
+	DefaultMQTTPort      = 1883
+	DeadLetterTopic      = "tasks/deadletter/%s"
+	DeadLetterTopicAll   = "tasks/deadletter/#"
+	DefaultCollectWindow = 2 * time.Second
+)
+
+// brokerClient is the MQTT client dead letters are read and requeued through
+type brokerClient interface {
+	mqtt.ClientInterface
+	mqtt.RetainedPublisher
+}
+
+// DeadLetterClient reads retained dead letters and requeues their tasks
+type DeadLetterClient struct {
+	mqttClient brokerClient
+	ctx        context.Context
+	cancel     context.CancelFunc
+
+	mu          sync.Mutex
+	deadLetters map[string]types.DeadLetter // Keyed by workflow ID
+}
+
+// NewDeadLetterClient creates a new dead letter client
+func NewDeadLetterClient(mqttHost string, mqttPort int) *DeadLetterClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	clientID := fmt.Sprintf("deadletter-cli-%d", time.Now().UnixNano())
+
+	return &DeadLetterClient{
+		mqttClient:  mqtt.NewClientWithID(mqttHost, mqttPort, clientID),
+		ctx:         ctx,
+		cancel:      cancel,
+		deadLetters: make(map[string]types.DeadLetter),
+	}
+}
+
+// Start connects the client to MQTT
+func (c *DeadLetterClient) Start() error {
+	connectCtx, connectCancel := context.WithTimeout(c.ctx, 10*time.Second)
+	defer connectCancel()
+
+	return c.mqttClient.Connect(connectCtx)
+}
+
+// Stop disconnects the client
+func (c *DeadLetterClient) Stop() {
+	c.cancel()
+	if c.mqttClient != nil {
+		c.mqttClient.Disconnect()
+	}
+}
+
+// Collect subscribes to the dead letter topics and gathers the retained
+// messages the broker replays, plus any published during window
+func (c *DeadLetterClient) Collect(window time.Duration) ([]types.DeadLetter, error) {
+	if err := c.mqttClient.Subscribe(c.ctx, DeadLetterTopicAll, c.handleDeadLetter); err != nil {
+		return nil, fmt.Errorf("failed to subscribe to %s: %w", DeadLetterTopicAll, err)
+	}
+
+	select {
+	case <-time.After(window):
+	case <-c.ctx.Done():
+		return nil, c.ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadLetters := make([]types.DeadLetter, 0, len(c.deadLetters))
+	for _, deadLetter := range c.deadLetters {
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	sort.Slice(deadLetters, func(i, j int) bool {
+		return deadLetters[i].DeadLetteredAt.Before(deadLetters[j].DeadLetteredAt)
+	})
+	return deadLetters, nil
+}
+
+// handleDeadLetter records one dead letter; empty payloads are cleared entries
+func (c *DeadLetterClient) handleDeadLetter(payload []byte) {
+	if len(payload) == 0 {
+		return
+	}
+
+	var deadLetter types.DeadLetter
+	if _, err := types.UnwrapMessage(payload, types.MessageTypeDeadLetter, &deadLetter); err != nil {
+		log.Printf("Skipping malformed dead letter: %v", err)
+		return
+	}
+
+	// Rejected payloads were never decoded, so they have no workflow ID
+	key := deadLetter.Task.WorkflowID
+	if key == "" {
+		key = deadLetter.Topic + "@" + deadLetter.DeadLetteredAt.String()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadLetters[key] = deadLetter
+}
+
+// Requeue republishes a dead-lettered task to its original stage topic and
+// clears the dead letter. The deadline is dropped so workers do not discard
+// the task as expired; the orchestrator sets a fresh one when it reopens
+// the workflow.
+func (c *DeadLetterClient) Requeue(deadLetter types.DeadLetter) error {
+	task := deadLetter.Task
+	if task.ID == "" {
+		return fmt.Errorf("dead letter from %s has no decoded task to requeue", deadLetter.Topic)
+	}
+	task.Deadline = time.Time{}
+	task.RetryCount = 0
+
+	data, err := types.WrapMessage(types.MessageTypeWorkflowTask, task.WorkflowID, task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task %s: %w", task.ID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+	defer cancel()
+
+	if err := c.mqttClient.Publish(ctx, deadLetter.Topic, data); err != nil {
+		return fmt.Errorf("failed to requeue task %s: %w", task.ID, err)
+	}
+
+	// An empty retained message removes the dead letter from the broker
+	topic := fmt.Sprintf(DeadLetterTopic, task.WorkflowID)
+	if err := c.mqttClient.PublishRetained(ctx, topic, nil); err != nil {
+		return fmt.Errorf("task %s requeued but dead letter not cleared: %w", task.ID, err)
+	}
+	return nil
+}
+
+// printDeadLetters writes a summary of each dead letter and its error history
+func printDeadLetters(deadLetters []types.DeadLetter) {
+	if len(deadLetters) == 0 {
+		fmt.Println("No dead-lettered tasks")
+		return
+	}
+
+	for _, deadLetter := range deadLetters {
+		task := deadLetter.Task
+		if task.ID == "" {
+			fmt.Printf("rejected  topic=%s size=%d at=%s\n", deadLetter.Topic, deadLetter.PayloadSize,
+				deadLetter.DeadLetteredAt.Format(time.RFC3339))
+			fmt.Printf("  reason: %s\n", deadLetter.Reason)
+			continue
+		}
+		fmt.Printf("%s  task=%s stage=%s type=%s at=%s\n", task.WorkflowID, task.ID, task.Stage, task.Type,
+			deadLetter.DeadLetteredAt.Format(time.RFC3339))
+		fmt.Printf("  reason: %s\n", deadLetter.Reason)
+		for i, message := range deadLetter.Errors {
+			fmt.Printf("  error %d: %s\n", i+1, strings.ReplaceAll(message, "\n", " "))
+		}
+	}
+}
+
+func main() {
+	// Parse command line flags
+	var (
+		mqttHost = flag.String("mqtt-host", DefaultMQTTHost, "MQTT broker host")
+		mqttPort = flag.Int("mqtt-port", DefaultMQTTPort, "MQTT broker port")
+		window   = flag.Duration("wait", DefaultCollectWindow, "How long to collect dead letters from the broker")
+		requeue  = flag.String("requeue", "", "Republish the dead-lettered task of this workflow ID to its stage topic")
+		verbose  = flag.Bool("verbose", false, "Enable verbose logging")
+	)
+	flag.Parse()
+
+	// Configure logging
+	if *verbose {
+		log.SetFlags(log.LstdFlags | log.Lshortfile)
+	}
+
+	client := NewDeadLetterClient(*mqttHost, *mqttPort)
+	defer client.Stop()
+
+	if err := client.Start(); err != nil {
+		log.Fatalf("Failed to connect to MQTT broker: %v", err)
+	}
+
+	deadLetters, err := client.Collect(*window)
+	if err != nil {
+		log.Fatalf("Failed to collect dead letters: %v", err)
+	}
+
+	if *requeue == "" {
+		printDeadLetters(deadLetters)
+		return
+	}
+
+	for _, deadLetter := range deadLetters {
+		if deadLetter.Task.WorkflowID != *requeue {
+			continue
+		}
+		if err := client.Requeue(deadLetter); err != nil {
+			log.Fatalf("Failed to requeue: %v", err)
+		}
+		log.Printf("Requeued task %s to %s", deadLetter.Task.ID, deadLetter.Topic)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "No dead letter for workflow %s\n", *requeue)
+	os.Exit(1)
+}
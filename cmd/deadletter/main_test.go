@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// message is one publish seen by recordingClient
+type message struct {
+	topic    string
+	payload  []byte
+	retained bool
+}
+
+// recordingClient replays retained payloads to subscribers and records publishes
+type recordingClient struct {
+	mu        sync.Mutex
+	retained  [][]byte // Replayed to every subscriber, in order
+	published []message
+}
+
+func (c *recordingClient) Connect(context.Context) error             { return nil }
+func (c *recordingClient) Disconnect()                               {}
+func (c *recordingClient) IsConnected() bool                         { return true }
+func (c *recordingClient) Unsubscribe(context.Context, string) error { return nil }
+
+func (c *recordingClient) Subscribe(ctx context.Context, topic string, handler mqtt.MessageHandler) error {
+	for _, payload := range c.retained {
+		handler(payload)
+	}
+	return nil
+}
+
+func (c *recordingClient) Publish(ctx context.Context, topic string, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.published = append(c.published, message{topic, payload, false})
+	return nil
+}
+
+func (c *recordingClient) PublishRetained(ctx context.Context, topic string, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.published = append(c.published, message{topic, payload, true})
+	return nil
+}
+
+// newTestClient creates a dead letter client replaying retained through a recordingClient
+func newTestClient(t *testing.T, retained ...[]byte) (*DeadLetterClient, *recordingClient) {
+	t.Helper()
+	broker := &recordingClient{retained: retained}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &DeadLetterClient{
+		mqttClient:  broker,
+		ctx:         ctx,
+		cancel:      cancel,
+		deadLetters: make(map[string]types.DeadLetter),
+	}, broker
+}
+
+// deadLetterPayload wraps a dead letter for workflowID as the orchestrator publishes it
+func deadLetterPayload(t *testing.T, workflowID string, at time.Time) []byte {
+	t.Helper()
+	deadLetter := types.DeadLetter{
+		Task: types.WorkflowTask{
+			Task:       types.Task{ID: workflowID + "-review-2", Type: "create_document"},
+			WorkflowID: workflowID,
+			Stage:      types.StageReview,
+			RetryCount: 2,
+			Deadline:   at.Add(time.Hour),
+		},
+		Topic:          "tasks/workflow/review",
+		Reason:         "max retries (2) exceeded",
+		Errors:         []string{"first", "second"},
+		DeadLetteredAt: at,
+	}
+	data, err := types.WrapMessage(types.MessageTypeDeadLetter, workflowID, deadLetter)
+	if err != nil {
+		t.Fatalf("WrapMessage: %v", err)
+	}
+	return data
+}
+
+func TestCollect(t *testing.T) {
+	now := time.Now()
+	client, _ := newTestClient(t,
+		deadLetterPayload(t, "wf-2", now),
+		deadLetterPayload(t, "wf-1", now.Add(-time.Minute)),
+		[]byte(`not json`),
+		nil,                               // A cleared dead letter
+		deadLetterPayload(t, "wf-2", now), // Replayed again
+	)
+
+	deadLetters, err := client.Collect(time.Millisecond)
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if len(deadLetters) != 2 {
+		t.Fatalf("collected %d dead letters, want 2", len(deadLetters))
+	}
+	for i, want := range []string{"wf-1", "wf-2"} {
+		if got := deadLetters[i].Task.WorkflowID; got != want {
+			t.Errorf("dead letter %d is %s, want %s (oldest first)", i, got, want)
+		}
+	}
+	if len(deadLetters[0].Errors) != 2 {
+		t.Errorf("Errors = %v, want the error history", deadLetters[0].Errors)
+	}
+}
+
+func TestRequeue(t *testing.T) {
+	client, broker := newTestClient(t, deadLetterPayload(t, "wf-1", time.Now()))
+	deadLetters, err := client.Collect(time.Millisecond)
+	if err != nil || len(deadLetters) != 1 {
+		t.Fatalf("Collect = %d dead letters, %v", len(deadLetters), err)
+	}
+
+	if err := client.Requeue(deadLetters[0]); err != nil {
+		t.Fatalf("Requeue: %v", err)
+	}
+	if len(broker.published) != 2 {
+		t.Fatalf("published %d messages, want the task and the cleared dead letter", len(broker.published))
+	}
+
+	requeued := broker.published[0]
+	if requeued.topic != "tasks/workflow/review" || requeued.retained {
+		t.Errorf("requeued to %q (retained %v), want the review stage topic", requeued.topic, requeued.retained)
+	}
+	var task types.WorkflowTask
+	if _, err := types.UnwrapMessage(requeued.payload, types.MessageTypeWorkflowTask, &task); err != nil {
+		t.Fatalf("requeued payload: %v", err)
+	}
+	if task.ID != "wf-1-review-2" || !task.Deadline.IsZero() || task.RetryCount != 0 {
+		t.Errorf("requeued task %s deadline=%v retries=%d, want no deadline and no retries", task.ID, task.Deadline, task.RetryCount)
+	}
+
+	cleared := broker.published[1]
+	if cleared.topic != "tasks/deadletter/wf-1" || !cleared.retained || len(cleared.payload) != 0 {
+		t.Errorf("cleared %q retained=%v payload=%q, want an empty retained message", cleared.topic, cleared.retained, cleared.payload)
+	}
+}
+
+func TestRequeueRejectedPayload(t *testing.T) {
+	client, broker := newTestClient(t)
+	err := client.Requeue(types.DeadLetter{Topic: "tasks/workflow/review", PayloadSize: 2 << 20})
+	if err == nil {
+		t.Error("Requeue succeeded for a dead letter without a decoded task")
+	}
+	if len(broker.published) != 0 {
+		t.Errorf("published %d messages, want none", len(broker.published))
+	}
+}
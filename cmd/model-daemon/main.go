@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+)
+
+// Configuration constants
+const (
+	DefaultListenAddr   = "127.0.0.1:8090"
+	DefaultModelsConfig = "./configs/models.yaml"
+	DefaultMaxGPUMemory = 5632 // 5.5GB for RTX 3060
+	ShutdownTimeout     = 30 * time.Second
+)
+
+// ModelDaemon owns the local model processes and serves them to workers over HTTP
+type ModelDaemon struct {
+	manager *localmodels.Manager
+	server  *http.Server
+}
+
+// NewModelDaemon creates a daemon serving the manager's models on listenAddr
+func NewModelDaemon(manager *localmodels.Manager, listenAddr string) *ModelDaemon {
+	daemon := &ModelDaemon{manager: manager}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", daemon.handleHealth)
+	mux.HandleFunc("GET /models", daemon.handleStatus)
+	mux.HandleFunc("POST /models/{name}/load", daemon.handleLoad)
+	mux.HandleFunc("POST /models/{name}/predict", daemon.handlePredict)
+
+	daemon.server = &http.Server{
+		Addr:              listenAddr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return daemon
+}
+
+// handleHealth reports daemon liveness
+func (d *ModelDaemon) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleStatus reports the state of every configured model
+func (d *ModelDaemon) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, d.manager.GetModelStatus())
+}
+
+// handleLoad loads a model if it is not already resident
+func (d *ModelDaemon) handleLoad(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := d.manager.LoadModel(r.Context(), name); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"model": name, "status": "loaded"})
+}
+
+// handlePredict runs inference on a model, loading it on demand
+func (d *ModelDaemon) handlePredict(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var input localmodels.ModelInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, fmt.Sprintf("invalid model input: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := d.manager.LoadModel(r.Context(), name); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	model, err := d.manager.GetModel(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	output, err := model.Predict(r.Context(), input)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, output)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+func main() {
+	var (
+		listenAddr   = flag.String("listen", DefaultListenAddr, "Address to serve models on")
+		modelsConfig = flag.String("models-config", DefaultModelsConfig, "Model configuration file")
+		maxGPUMemory = flag.Uint64("max-gpu-memory", DefaultMaxGPUMemory, "Maximum GPU memory in MB")
+		idleTimeout  = flag.Duration("idle-timeout", 0, "Unload models unused for this long, reloading on the next request (0 keeps them loaded)")
+		maxInference = flag.Int("max-concurrent-inference", localmodels.DefaultMaxConcurrentInference, "Simultaneous predictions per model")
+		headroom     = flag.Uint64("memory-headroom", localmodels.DefaultMemoryHeadroom, "GPU memory in MB kept free after loading a model")
+		verbose      = flag.Bool("verbose", false, "Enable verbose logging")
+	)
+	flag.Parse()
+
+	if *verbose {
+		log.SetFlags(log.LstdFlags | log.Lshortfile)
+	}
+
+	models, err := localmodels.LoadModelConfigs(*modelsConfig)
+	if err != nil {
+		log.Fatalf("Failed to load model configuration: %v", err)
+	}
+
+	aliases, err := localmodels.LoadModelAliases(*modelsConfig)
+	if err != nil {
+		log.Fatalf("Failed to load model aliases: %v", err)
+	}
+
+	manager, err := localmodels.NewManager(localmodels.ModelManagerConfig{
+		MaxGPUMemory:    *maxGPUMemory,
+		NvidiaSMIPath:   "/usr/bin/nvidia-smi",
+		MonitorInterval: 30 * time.Second,
+		Models:          models,
+		Aliases:         aliases,
+
+		MaxConcurrentInference: *maxInference,
+		MemoryHeadroom:         *headroom,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create model manager: %v", err)
+	}
+	manager.SetIdleTimeout(*idleTimeout)
+
+	daemon := NewModelDaemon(manager, *listenAddr)
+
+	go func() {
+		log.Printf("Model daemon serving %d models on %s", len(models), *listenAddr)
+		if err := daemon.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Model daemon failed: %v", err)
+		}
+	}()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+
+	if err := daemon.server.Shutdown(ctx); err != nil {
+		log.Printf("Failed to shut down HTTP server: %v", err)
+	}
+	if err := manager.Shutdown(ctx); err != nil {
+		log.Printf("Failed to shut down model manager: %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+)
+
+// newTestDaemon serves a manager whose models are backed by an upstream
+// daemon that answers predictions for "qwen" and refuses every other model
+func newTestDaemon(t *testing.T) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/models/qwen/") {
+			http.Error(w, "unknown model", http.StatusNotFound)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/predict") {
+			var input localmodels.ModelInput
+			json.NewDecoder(r.Body).Decode(&input)
+			if input.Text == "fail" {
+				http.Error(w, "inference failed", http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(localmodels.ModelOutput{Text: "echo: " + input.Text})
+		}
+	}))
+	t.Cleanup(upstream.Close)
+
+	manager, err := localmodels.NewManager(localmodels.ModelManagerConfig{
+		DaemonURL: upstream.URL,
+		Models:    map[string]localmodels.ModelConfig{"qwen": {Name: "qwen", Type: localmodels.ModelTypeText}},
+	})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	server := httptest.NewServer(NewModelDaemon(manager, "").server.Handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestModelDaemonEndpoints(t *testing.T) {
+	server := newTestDaemon(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"health", http.MethodGet, "/health", "", http.StatusOK, `"ok"`},
+		{"load", http.MethodPost, "/models/qwen/load", "", http.StatusOK, `"loaded"`},
+		{"predict", http.MethodPost, "/models/qwen/predict", `{"text":"hi"}`, http.StatusOK, `"echo: hi"`},
+		{"bad input", http.MethodPost, "/models/qwen/predict", `{`, http.StatusBadRequest, "invalid model input"},
+		{"inference fails", http.MethodPost, "/models/qwen/predict", `{"text":"fail"}`, http.StatusInternalServerError, "inference failed"},
+		{"unknown model", http.MethodPost, "/models/llama/predict", `{"text":"hi"}`, http.StatusServiceUnavailable, "unknown model"},
+		{"status", http.MethodGet, "/models", "", http.StatusOK, `"name":"qwen"`},
+		{"wrong method", http.MethodGet, "/models/qwen/predict", "", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+			}
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d (%s)", resp.StatusCode, tt.wantStatus, body)
+			}
+			if !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", body, tt.wantBody)
+			}
+		})
+	}
+}
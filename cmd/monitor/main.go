@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/ai"
+	"github.com/niko/mqtt-agent-orchestration/internal/api"
+	"github.com/niko/mqtt-agent-orchestration/internal/health"
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/internal/rag"
+)
+
+// Configuration constants
+const (
+	DefaultMQTTHost      = "localhost"
+	DefaultMQTTPort      = 1883
+	DefaultAIConfig      = "./configs/ai_helpers.toml"
+	DefaultNvidiaSMIPath = "/usr/bin/nvidia-smi"
+)
+
+// MonitorApp publishes the aggregate system health
+type MonitorApp struct {
+	mqttClient *mqtt.Client
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+// NewMonitorApp creates a monitor connecting to the MQTT broker
+func NewMonitorApp(mqttHost string, mqttPort int) *MonitorApp {
+	ctx, cancel := context.WithCancel(context.Background())
+	clientID := fmt.Sprintf("monitor-%d", time.Now().UnixNano())
+
+	return &MonitorApp{
+		mqttClient: mqtt.NewClientWithID(mqttHost, mqttPort, clientID),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Start connects to MQTT and the configured subsystems, then publishes
+// system health every interval. Empty paths leave a subsystem unmonitored.
+func (app *MonitorApp) Start(config health.Config, staleAfter time.Duration, qdrantURL, aiConfigPath, nvidiaSMIPath string) error {
+	connectCtx, connectCancel := context.WithTimeout(app.ctx, 10*time.Second)
+	defer connectCancel()
+
+	if err := app.mqttClient.Connect(connectCtx); err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker: %w", err)
+	}
+
+	workers := api.NewStatusAggregator(app.mqttClient, staleAfter)
+	if err := workers.Start(app.ctx); err != nil {
+		return fmt.Errorf("failed to start worker status aggregator: %w", err)
+	}
+	sources := health.Sources{Workers: workers}
+
+	if qdrantURL != "" {
+		service, err := rag.NewService("qdrant", qdrantURL)
+		if err != nil {
+			return fmt.Errorf("failed to create RAG service: %w", err)
+		}
+		sources.RAG = service
+	}
+
+	if aiConfigPath != "" {
+		aiConfig, err := ai.LoadAIHelperConfig(aiConfigPath)
+		if err != nil {
+			log.Printf("Warning: Failed to load AI config, providers are not monitored: %v", err)
+		} else {
+			interval := aiConfig.Defaults.GetHealthCheckInterval()
+			if interval <= 0 {
+				interval = config.Interval
+			}
+			aiConfig.StartHealthChecks(app.ctx, interval, nil)
+			sources.Providers = aiConfig
+		}
+	}
+
+	if nvidiaSMIPath != "" {
+		sources.GPU = func(ctx context.Context) (localmodels.GPUMemoryInfo, error) {
+			return localmodels.QueryGPUMemory(ctx, nvidiaSMIPath)
+		}
+	}
+
+	// Let workers report before the first snapshot so they are not counted missing
+	monitor := health.NewMonitor(app.mqttClient, sources, config)
+	go func() {
+		select {
+		case <-time.After(api.DefaultStaleAfter / 3):
+			monitor.Run(app.ctx)
+		case <-app.ctx.Done():
+		}
+	}()
+
+	log.Printf("Publishing system health on %s every %v", health.SystemHealthTopic, config.Interval)
+	return nil
+}
+
+// Stop disconnects the monitor
+func (app *MonitorApp) Stop() {
+	app.cancel()
+	if app.mqttClient != nil {
+		app.mqttClient.Disconnect()
+	}
+}
+
+func main() {
+	defaults := health.DefaultConfig()
+
+	var (
+		mqttHost    = flag.String("mqtt-host", DefaultMQTTHost, "MQTT broker host")
+		mqttPort    = flag.Int("mqtt-port", DefaultMQTTPort, "MQTT broker port")
+		interval    = flag.Duration("interval", defaults.Interval, "Time between system health snapshots")
+		workerStale = flag.Duration("worker-stale-after", api.DefaultStaleAfter, "Count workers as gone after this long without a status update")
+		qdrantURL   = flag.String("qdrant-url", "", "Qdrant URL whose availability is checked; empty leaves RAG unmonitored")
+		aiConfig    = flag.String("ai-config", DefaultAIConfig, "AI provider configuration to health-check; empty leaves providers unmonitored")
+		nvidiaSMI   = flag.String("nvidia-smi", DefaultNvidiaSMIPath, "nvidia-smi used to read GPU memory; empty leaves the GPU unmonitored")
+		gpuHeadroom = flag.Uint64("gpu-headroom", defaults.GPUHeadroom, "Report GPU memory degraded below this many free MB")
+		verbose     = flag.Bool("verbose", false, "Enable verbose logging")
+	)
+	flag.Parse()
+
+	if *verbose {
+		log.SetFlags(log.LstdFlags | log.Lshortfile)
+	}
+
+	config := defaults
+	config.Interval = *interval
+	config.GPUHeadroom = *gpuHeadroom
+
+	app := NewMonitorApp(*mqttHost, *mqttPort)
+	if err := app.Start(config, *workerStale, *qdrantURL, *aiConfig, *nvidiaSMI); err != nil {
+		log.Fatalf("Failed to start monitor: %v", err)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	app.Stop()
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/api"
+	appconfig "github.com/niko/mqtt-agent-orchestration/internal/config"
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/internal/orchestrator"
+	"github.com/niko/mqtt-agent-orchestration/internal/rag"
+)
+
+// Configuration constants
+const (
+	DefaultMQTTHost = "localhost"
+	DefaultMQTTPort = 1883
+)
+
+// OrchestratorApp wires the workflow orchestrator to the MQTT broker
+type OrchestratorApp struct {
+	mqttClient   *mqtt.Client
+	orchestrator *orchestrator.Orchestrator
+	apiServer    *http.Server
+	ctx          context.Context
+	cancel       context.CancelFunc
+}
+
+// NewOrchestratorApp creates a new orchestrator application
+func NewOrchestratorApp(mqttHost string, mqttPort int, config orchestrator.Config) *OrchestratorApp {
+	ctx, cancel := context.WithCancel(context.Background())
+	mqttClient := mqtt.NewClientWithID(mqttHost, mqttPort, "orchestrator")
+
+	return &OrchestratorApp{
+		mqttClient:   mqttClient,
+		orchestrator: orchestrator.New(mqttClient, config),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Start connects to MQTT and begins accepting workflows
+func (app *OrchestratorApp) Start() error {
+	log.Printf("Starting orchestrator")
+
+	connectCtx, connectCancel := context.WithTimeout(app.ctx, 10*time.Second)
+	defer connectCancel()
+
+	if err := app.mqttClient.Connect(connectCtx); err != nil {
+		return fmt.Errorf("failed to connect to MQTT broker: %w", err)
+	}
+
+	log.Printf("Connected to MQTT broker")
+
+	if err := app.orchestrator.Start(app.ctx); err != nil {
+		return fmt.Errorf("failed to start orchestrator: %w", err)
+	}
+
+	log.Printf("Orchestrator ready, listening on %s", orchestrator.WorkflowRequestTopic)
+	return nil
+}
+
+// EnableAPI serves the read-only JSON API on addr. qdrantURL enables
+// collection statistics and may be empty.
+func (app *OrchestratorApp) EnableAPI(addr, qdrantURL string, staleAfter time.Duration) error {
+	workers := api.NewStatusAggregator(app.mqttClient, staleAfter)
+	if err := workers.Start(app.ctx); err != nil {
+		return fmt.Errorf("failed to start worker status aggregator: %w", err)
+	}
+
+	var collections api.CollectionSource
+	if qdrantURL != "" {
+		service, err := rag.NewService("qdrant", qdrantURL)
+		if err != nil {
+			return fmt.Errorf("failed to create RAG service: %w", err)
+		}
+		collections = service
+	}
+
+	server := api.NewServer(workers, app.orchestrator, collections)
+	app.apiServer = &http.Server{
+		Addr:              addr,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Printf("API listening on %s", addr)
+		if err := app.apiServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("API server failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+// EnableTrainingCapture stores sampled review and approval verdicts in the
+// Qdrant training collection read by the training exporter
+func (app *OrchestratorApp) EnableTrainingCapture(qdrantURL string) error {
+	service, err := rag.NewService("qdrant", qdrantURL)
+	if err != nil {
+		return fmt.Errorf("failed to create RAG service: %w", err)
+	}
+
+	initCtx, cancel := context.WithTimeout(app.ctx, 30*time.Second)
+	defer cancel()
+	if err := service.InitializeCollections(initCtx); err != nil {
+		return fmt.Errorf("failed to initialize RAG collections: %w", err)
+	}
+
+	app.orchestrator.SetTrainingSink(orchestrator.NewKnowledgeTrainingSink(service, orchestrator.DefaultTrainingCollection))
+	log.Printf("Capturing training samples to %s", orchestrator.DefaultTrainingCollection)
+	return nil
+}
+
+// Stop stops the orchestrator
+func (app *OrchestratorApp) Stop() {
+	log.Printf("Stopping orchestrator")
+	if app.apiServer != nil {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := app.apiServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Failed to shut down API server: %v", err)
+		}
+		shutdownCancel()
+	}
+	app.cancel()
+	if app.mqttClient != nil {
+		app.mqttClient.Disconnect()
+	}
+}
+
+// loadCapabilities reads the document types and model names to advertise,
+// falling back to the built-in document types when the registry is missing
+func loadCapabilities(templatesPath, modelsPath string) ([]string, []string) {
+	templates, err := appconfig.LoadDocumentTemplateConfig(templatesPath)
+	if err != nil {
+		log.Printf("Warning: Failed to load document templates, using defaults: %v", err)
+		templates = appconfig.DefaultDocumentTemplateConfig()
+	}
+
+	var models []string
+	modelConfigs, err := localmodels.LoadModelConfigs(modelsPath)
+	if err != nil {
+		log.Printf("Warning: Failed to load model configuration, advertising no models: %v", err)
+	}
+	for name := range modelConfigs {
+		models = append(models, name)
+	}
+	sort.Strings(models)
+
+	return templates.DocumentTypes(), models
+}
+
+func main() {
+	defaults := orchestrator.DefaultConfig()
+
+	var (
+		mqttHost        = flag.String("mqtt-host", DefaultMQTTHost, "MQTT broker host")
+		mqttPort        = flag.Int("mqtt-port", DefaultMQTTPort, "MQTT broker port")
+		workflowTimeout = flag.Duration("workflow-timeout", defaults.WorkflowTimeout, "Overall deadline for a workflow across all stages")
+		maxRetries      = flag.Int("max-retries", defaults.MaxRetries, "Retries allowed before a workflow is failed")
+		stageTimeout    = flag.Duration("stage-timeout", defaults.StageTimeout, "Re-dispatch a stage with no result after this long (0 disables)")
+		reviewQuorum    = flag.Int("review-quorum", defaults.ReviewQuorum, "Reviewer responses to wait for before combining verdicts")
+		quorumTimeout   = flag.Duration("quorum-timeout", defaults.QuorumTimeout, "Decide a review with the responses received after this long")
+		apiAddr         = flag.String("api-addr", "", "Serve the read-only JSON API on this address (e.g. :8081); empty disables")
+		qdrantURL       = flag.String("qdrant-url", "", "Qdrant URL for /rag/collections and training capture; empty disables")
+		workerStale     = flag.Duration("worker-stale-after", api.DefaultStaleAfter, "Drop workers from /workers after this long without a status update")
+		retention       = flag.Duration("retention", defaults.Retention, "Evict finished workflows this long after they end, keeping a summary (0 keeps them)")
+		outputRoot      = flag.String("output-root", defaults.OutputRoot, "Directory final documents are written under; output_file paths are relative to it")
+		versioned       = flag.Bool("versioned-output", false, "Keep earlier final documents as <output_file>.vN with a version manifest")
+		templatesPath   = flag.String("document-templates", "./configs/document_templates.yaml", "Document template registry advertised to clients")
+		modelsPath      = flag.String("models-config", "./configs/models.yaml", "Model configuration advertised to clients")
+		stageSLA        = flag.String("stage-sla", "", "Target p95 latency per stage, e.g. development=10m,review=5m; breaches are logged and counted")
+		trainingRate    = flag.Float64("training-sample-rate", defaults.TrainingSampleRate, "Fraction of approved documents captured for training (needs -qdrant-url)")
+		keepRejections  = flag.Bool("training-keep-rejections", defaults.TrainingKeepRejections, "Capture every rejected document as a negative training example")
+		resultWorkers   = flag.Int("result-workers", defaults.ResultWorkers, "Workflows whose stage results are handled in parallel (1 handles results one at a time)")
+		maxConcurrent   = flag.Int("max-concurrent-workflows", defaults.MaxConcurrentWorkflows, "Workflows in flight at once; later requests are queued (0 is unlimited)")
+		compress        = flag.Int("compress-threshold", 0, "Gzip published messages of at least this many bytes (0 disables)")
+		verbose         = flag.Bool("verbose", false, "Enable verbose logging")
+	)
+	flag.Parse()
+
+	if *verbose {
+		log.SetFlags(log.LstdFlags | log.Lshortfile)
+	}
+
+	config := defaults
+	config.WorkflowTimeout = *workflowTimeout
+	config.MaxRetries = *maxRetries
+	config.StageTimeout = *stageTimeout
+	config.ReviewQuorum = *reviewQuorum
+	config.QuorumTimeout = *quorumTimeout
+	config.OutputRoot = *outputRoot
+	config.VersionedOutput = *versioned
+	config.Retention = *retention
+	config.ResultWorkers = *resultWorkers
+	config.MaxConcurrentWorkflows = *maxConcurrent
+	targets, err := orchestrator.ParseStageSLA(*stageSLA)
+	if err != nil {
+		log.Fatalf("Invalid -stage-sla: %v", err)
+	}
+	config.StageSLA = targets
+	config.TrainingSampleRate = *trainingRate
+	config.TrainingKeepRejections = *keepRejections
+	config.DocumentTypes, config.Models = loadCapabilities(*templatesPath, *modelsPath)
+
+	app := NewOrchestratorApp(*mqttHost, *mqttPort, config)
+	app.mqttClient.SetCompressThreshold(*compress)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	if err := app.Start(); err != nil {
+		log.Fatalf("Failed to start orchestrator: %v", err)
+	}
+
+	if *qdrantURL != "" && (*trainingRate > 0 || *keepRejections) {
+		if err := app.EnableTrainingCapture(*qdrantURL); err != nil {
+			log.Printf("Warning: training capture disabled: %v", err)
+		}
+	}
+
+	if *apiAddr != "" {
+		if err := app.EnableAPI(*apiAddr, *qdrantURL, *workerStale); err != nil {
+			log.Fatalf("Failed to start API: %v", err)
+		}
+	}
+
+	<-sigChan
+
+	app.Stop()
+}
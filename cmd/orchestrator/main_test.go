@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeFile writes content to name in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadCapabilities(t *testing.T) {
+	templates := writeFile(t, "document_templates.yaml", `
+document_templates:
+  incident_runbook:
+    description: "Incident runbook"
+    required_sections: ["Detection", "Mitigation"]
+  api_guide:
+    description: "API guide"
+`)
+	models := writeFile(t, "models.yaml", `
+models:
+  phi-4:
+    name: "Phi-4"
+    binary_path: "/bin/llama-cli"
+    model_path: "/models/phi-4.gguf"
+    type: "text"
+  minicpm-v:
+    name: "MiniCPM-V"
+    binary_path: "/bin/llama-mtmd-cli"
+    model_path: "/models/minicpm-v.gguf"
+    type: "multimodal"
+`)
+	missing := filepath.Join(t.TempDir(), "missing.yaml")
+
+	tests := []struct {
+		name          string
+		templates     string
+		models        string
+		wantDocuments []string
+		wantModels    []string
+	}{
+		{"configured", templates, models, []string{"api_guide", "incident_runbook"}, []string{"minicpm-v", "phi-4"}},
+		{"missing files", missing, missing, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documents, gotModels := loadCapabilities(tt.templates, tt.models)
+			if tt.wantDocuments == nil {
+				// The built-in document types are advertised instead
+				if !slices.Contains(documents, "go_coding_standards") {
+					t.Errorf("documents = %v, want the built-in types", documents)
+				}
+			} else if !slices.Equal(documents, tt.wantDocuments) {
+				t.Errorf("documents = %v, want %v", documents, tt.wantDocuments)
+			}
+			if !slices.Equal(gotModels, tt.wantModels) {
+				t.Errorf("models = %v, want %v", gotModels, tt.wantModels)
+			}
+		})
+	}
+}
@@ -4,28 +4,36 @@ import (
 	"context"
 	"crypto/md5"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/niko/mqtt-agent-orchestration/internal/rag"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
 	"github.com/qdrant/go-client/qdrant"
 )
 
 const (
-	QdrantHost        = "localhost"
-	QdrantPort        = 6334 // gRPC port
-	CollectionName    = "agent_rag"
-	EmbeddingDim      = 2560 // Qwen3-Embedding-4B dimensions
-	EmbeddingModel    = "./models/Qwen3-Embedding-4B-Q8_0.gguf"
-	LlamaEmbeddingBin = "/home/niko/bin/llama-embedding"
+	QdrantHost     = "localhost"
+	QdrantPort     = 6334 // gRPC port
+	CollectionName = "agent_rag"
+
+	// DefaultMinTrainingLength skips fragments too short to be useful training examples
+	DefaultMinTrainingLength = 50
 )
 
+// embeddingTimeout is set by the --embedding-timeout option
+var embeddingTimeout = rag.DefaultEmbeddingTimeout
+
 type RAGService struct {
-	client *qdrant.Client
+	client   *qdrant.Client
+	embedder *rag.Service // Embeds documents and queries with the shared local model
 }
 
 type Document struct {
@@ -37,7 +45,12 @@ type Document struct {
 }
 
 func main() {
-	if len(os.Args) < 2 {
+	flag.DurationVar(&embeddingTimeout, "embedding-timeout", rag.DefaultEmbeddingTimeout, "Kill a llama-embedding run after this long (0 waits forever)")
+	flag.Usage = showUsage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 1 {
 		showUsage()
 		os.Exit(1)
 	}
@@ -51,7 +64,7 @@ func main() {
 		log.Fatalf("Failed to connect to Qdrant: %v", err)
 	}
 
-	service := &RAGService{client: client}
+	service := &RAGService{client: client, embedder: newKnowledgeBase()}
 
 	// Initialize collection
 	err = service.initializeCollection()
@@ -59,20 +72,28 @@ func main() {
 		log.Printf("Warning: Collection initialization: %v", err)
 	}
 
-	command := os.Args[1]
+	command := args[0]
 	switch command {
 	case "register":
-		handleRegister(service, os.Args[2:])
+		handleRegister(service, args[1:])
 	case "store-standards":
-		handleStoreStandards(service, os.Args[2:])
+		handleStoreStandards(service, args[1:])
+	case "store-prompt":
+		handleStorePrompt(args[1:])
 	case "search":
-		handleSearch(service, os.Args[2:])
+		handleSearch(service, args[1:])
 	case "context":
-		handleContext(service, os.Args[2:])
+		handleContext(service, args[1:])
 	case "list-projects":
 		handleListProjects(service)
+	case "tenants":
+		handleTenants(args[1:])
 	case "export-training-data":
-		handleExportTrainingData(service, os.Args[2:])
+		handleExportTrainingData(service, args[1:])
+	case "snapshot":
+		handleSnapshot(args[1:])
+	case "restore":
+		handleRestore(args[1:])
 	case "version":
 		fmt.Println("rag-service v1.0.0 - Real Qdrant Integration")
 	default:
@@ -89,7 +110,7 @@ func (r *RAGService) initializeCollection() error {
 	err := r.client.CreateCollection(ctx, &qdrant.CreateCollection{
 		CollectionName: CollectionName,
 		VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
-			Size:     EmbeddingDim,
+			Size:     rag.VectorDimension,
 			Distance: qdrant.Distance_Cosine,
 		}),
 	})
@@ -102,7 +123,7 @@ func (r *RAGService) storeDocument(doc Document) error {
 	ctx := context.Background()
 
 	// Generate real embedding using Qwen3 model
-	embedding, err := generateEmbedding(doc.Content)
+	embedding, err := r.embedder.Embed(ctx, doc.Content)
 	if err != nil {
 		return fmt.Errorf("failed to generate embedding: %w", err)
 	}
@@ -137,7 +158,7 @@ func (r *RAGService) searchDocuments(query string, limit int) ([]Document, error
 	ctx := context.Background()
 
 	// Generate embedding for query
-	queryEmbedding, err := generateEmbedding(query)
+	queryEmbedding, err := r.embedder.Embed(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
@@ -156,33 +177,64 @@ func (r *RAGService) searchDocuments(query string, limit int) ([]Document, error
 
 	var docs []Document
 	for _, point := range searchResult {
-		doc := Document{
-			ID:       fmt.Sprintf("%d", point.Id.GetNum()),
-			Metadata: make(map[string]string),
+		docs = append(docs, documentFromPayload(point.Id, point.Payload))
+	}
+
+	return docs, nil
+}
+
+// scrollDocuments reads every document in collection, one scroll page at a time
+func (r *RAGService) scrollDocuments(ctx context.Context, collection string) ([]Document, error) {
+	var docs []Document
+	var offset *qdrant.PointId
+	for {
+		points, next, err := r.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: collection,
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(rag.TrainingExportBatchSize)),
+			WithPayload:    qdrant.NewWithPayload(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scroll collection %s: %w", collection, err)
 		}
 
-		if point.Payload != nil {
-			if content, ok := point.Payload["content"]; ok {
-				if strVal, ok := content.GetKind().(*qdrant.Value_StringValue); ok {
-					doc.Content = strVal.StringValue
-				}
+		for _, point := range points {
+			docs = append(docs, documentFromPayload(point.Id, point.Payload))
+		}
+
+		if next == nil {
+			return docs, nil
+		}
+		offset = next
+	}
+}
+
+// documentFromPayload builds a document from a stored point's ID and payload
+func documentFromPayload(id *qdrant.PointId, payload map[string]*qdrant.Value) Document {
+	doc := Document{
+		ID:       fmt.Sprintf("%d", id.GetNum()),
+		Metadata: make(map[string]string),
+	}
+
+	if payload != nil {
+		if content, ok := payload["content"]; ok {
+			if strVal, ok := content.GetKind().(*qdrant.Value_StringValue); ok {
+				doc.Content = strVal.StringValue
 			}
-			if docType, ok := point.Payload["type"]; ok {
-				if strVal, ok := docType.GetKind().(*qdrant.Value_StringValue); ok {
-					doc.Type = strVal.StringValue
-				}
+		}
+		if docType, ok := payload["type"]; ok {
+			if strVal, ok := docType.GetKind().(*qdrant.Value_StringValue); ok {
+				doc.Type = strVal.StringValue
 			}
-			if source, ok := point.Payload["source"]; ok {
-				if strVal, ok := source.GetKind().(*qdrant.Value_StringValue); ok {
-					doc.Source = strVal.StringValue
-				}
+		}
+		if source, ok := payload["source"]; ok {
+			if strVal, ok := source.GetKind().(*qdrant.Value_StringValue); ok {
+				doc.Source = strVal.StringValue
 			}
 		}
-
-		docs = append(docs, doc)
 	}
 
-	return docs, nil
+	return doc
 }
 
 func handleRegister(service *RAGService, args []string) {
@@ -345,75 +397,6 @@ func handleListProjects(service *RAGService) {
 }
 
 // Helper functions
-func generateEmbedding(text string) ([]float32, error) {
-	// Use llama-embedding binary with Qwen3 model
-	cmd := exec.Command(LlamaEmbeddingBin,
-		"-m", EmbeddingModel,
-		"-p", text,
-		"--embedding")
-
-	output, err := cmd.Output()
-	if err != nil {
-		log.Printf("Embedding generation failed: %v", err)
-		// Fallback to simple embedding
-		return generateSimpleEmbedding(text), nil
-	}
-
-	// Parse the embedding output
-	lines := strings.Split(string(output), "\n")
-	var embedding []float32
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
-			// Remove brackets and split by spaces/commas
-			line = strings.Trim(line, "[]")
-			values := strings.Fields(strings.ReplaceAll(line, ",", " "))
-
-			for _, val := range values {
-				if f, err := strconv.ParseFloat(val, 32); err == nil {
-					embedding = append(embedding, float32(f))
-				}
-			}
-			break
-		}
-	}
-
-	if len(embedding) == 0 {
-		// Fallback if parsing fails
-		return generateSimpleEmbedding(text), nil
-	}
-
-	return embedding, nil
-}
-
-func generateSimpleEmbedding(text string) []float32 {
-	// Fallback hash-based embedding
-	embedding := make([]float32, EmbeddingDim)
-
-	hash := 0
-	for i, char := range text {
-		hash = hash*31 + int(char)
-		if i < len(embedding) {
-			embedding[i] = float32((hash%200)-100) / 100.0
-		}
-	}
-
-	// Simple normalization
-	var magnitude float32
-	for _, val := range embedding {
-		magnitude += val * val
-	}
-	if magnitude > 0 {
-		magnitude = 1.0 / (1.0 + magnitude)
-		for i := range embedding {
-			embedding[i] *= magnitude
-		}
-	}
-
-	return embedding
-}
-
 func hashString(s string) uint64 {
 	hash := md5.Sum([]byte(s))
 	result := uint64(0)
@@ -432,7 +415,7 @@ func truncate(s string, maxLen int) string {
 
 func handleExportTrainingData(service *RAGService, args []string) {
 	if len(args) < 1 {
-		fmt.Println("Usage: rag-service export-training-data --format <format> [--collection <name>] [--min-score <score>]")
+		fmt.Println("Usage: rag-service export-training-data --format <format> [--collection <name>] [--min-score <score>] [--min-length <chars>]")
 		fmt.Println("Formats: llama-finetune, jsonl")
 		os.Exit(1)
 	}
@@ -440,6 +423,7 @@ func handleExportTrainingData(service *RAGService, args []string) {
 	var format string = "llama-finetune"
 	var collection string = CollectionName
 	var minScore float64 = 0.7
+	var minLength int = DefaultMinTrainingLength
 
 	// Parse arguments
 	for i, arg := range args {
@@ -447,6 +431,10 @@ func handleExportTrainingData(service *RAGService, args []string) {
 			format = args[i+1]
 		} else if arg == "--collection" && i+1 < len(args) {
 			collection = args[i+1]
+		} else if arg == "--min-length" && i+1 < len(args) {
+			if length, err := strconv.Atoi(args[i+1]); err == nil {
+				minLength = length
+			}
 		} else if arg == "--min-score" && i+1 < len(args) {
 			if score, err := strconv.ParseFloat(args[i+1], 64); err == nil {
 				minScore = score
@@ -454,18 +442,18 @@ func handleExportTrainingData(service *RAGService, args []string) {
 		}
 	}
 
-	// Search for successful coding interactions in specified collection
-	log.Printf("Searching collection '%s' for training data with min score %.2f", collection, minScore)
-	docs, err := service.searchDocuments("successful code compile test", 1000)
+	// Read every document in the specified collection
+	log.Printf("Reading collection '%s' for training data with min score %.2f", collection, minScore)
+	docs, err := service.scrollDocuments(context.Background(), collection)
 	if err != nil {
-		log.Fatalf("Failed to search training data: %v", err)
+		log.Fatalf("Failed to read training data: %v", err)
 	}
 
 	// Convert to training format
 	var examples []map[string]interface{}
 	for _, doc := range docs {
 		// Only include high-quality examples above minimum score threshold
-		if len(doc.Content) < 50 {
+		if len(doc.Content) < minLength {
 			continue
 		}
 		
@@ -509,24 +497,171 @@ func handleExportTrainingData(service *RAGService, args []string) {
 	log.Printf("Exported %d training examples in %s format", len(examples), format)
 }
 
+// handleStorePrompt stores a role's system prompt in agent_prompts, refusing
+// to overwrite a very different stored prompt unless --force is given
+func handleStorePrompt(args []string) {
+	var role, promptFile string
+	var force bool
+	maxDrift := rag.DefaultPromptDriftThreshold
+
+	for i, arg := range args {
+		switch {
+		case arg == "--role" && i+1 < len(args):
+			role = args[i+1]
+		case arg == "--file" && i+1 < len(args):
+			promptFile = args[i+1]
+		case arg == "--max-drift" && i+1 < len(args):
+			if drift, err := strconv.ParseFloat(args[i+1], 64); err == nil {
+				maxDrift = drift
+			}
+		case arg == "--force":
+			force = true
+		}
+	}
+
+	if role == "" || promptFile == "" {
+		fmt.Println("Usage: rag-service store-prompt --role <role> --file <prompt.md> [--force] [--max-drift <distance>]")
+		os.Exit(1)
+	}
+
+	prompt, err := os.ReadFile(promptFile)
+	if err != nil {
+		log.Fatalf("Failed to read prompt file: %v", err)
+	}
+
+	service := newKnowledgeBase()
+	service.SetPromptDriftThreshold(maxDrift)
+
+	ctx := context.Background()
+	if force {
+		err = service.ReplaceSystemPrompt(ctx, types.WorkerRole(role), string(prompt))
+	} else {
+		err = service.StoreSystemPrompt(ctx, types.WorkerRole(role), string(prompt))
+	}
+	if errors.Is(err, rag.ErrPromptDrift) {
+		log.Fatalf("%v\nRe-run with --force if the new prompt is intended.", err)
+	}
+	if err != nil {
+		log.Fatalf("Failed to store system prompt: %v", err)
+	}
+
+	fmt.Printf("Stored system prompt for role %s\n", role)
+}
+
+// handleTenants lists tenant collections, or creates them with --create <tenant>
+func handleTenants(args []string) {
+	var create string
+	for i, arg := range args {
+		if arg == "--create" && i+1 < len(args) {
+			create = args[i+1]
+		}
+	}
+
+	service := newKnowledgeBase()
+
+	ctx := context.Background()
+	if create != "" {
+		if err := service.CreateTenantCollections(ctx, create); err != nil {
+			log.Fatalf("Failed to create tenant collections: %v", err)
+		}
+		fmt.Printf("Tenant %s collections ready\n", create)
+		return
+	}
+
+	tenants, err := service.ListTenantCollections(ctx)
+	if err != nil {
+		log.Fatalf("Failed to list tenant collections: %v", err)
+	}
+	if len(tenants) == 0 {
+		fmt.Println("No tenant collections")
+		return
+	}
+
+	names := make([]string, 0, len(tenants))
+	for tenant := range tenants {
+		names = append(names, tenant)
+	}
+	sort.Strings(names)
+	for _, tenant := range names {
+		fmt.Printf("%s: %s\n", tenant, strings.Join(tenants[tenant], ", "))
+	}
+}
+
+// handleSnapshot exports every configured and tenant collection to a snapshot directory
+func handleSnapshot(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: rag-service snapshot <dir>")
+		os.Exit(1)
+	}
+
+	service := newKnowledgeBase()
+
+	manifest, err := service.Snapshot(context.Background(), args[0])
+	if err != nil {
+		log.Fatalf("Failed to snapshot knowledge base: %v", err)
+	}
+	for _, collection := range manifest.Collections {
+		fmt.Printf("%s: %d points\n", collection.Name, collection.Points)
+	}
+	fmt.Printf("Snapshot written to %s\n", args[0])
+}
+
+// handleRestore re-imports a snapshot directory written by snapshot
+func handleRestore(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: rag-service restore <dir>")
+		os.Exit(1)
+	}
+
+	service := newKnowledgeBase()
+
+	manifest, err := service.Restore(context.Background(), args[0])
+	if err != nil {
+		log.Fatalf("Failed to restore knowledge base: %v", err)
+	}
+	for _, collection := range manifest.Collections {
+		fmt.Printf("%s: %d points\n", collection.Name, collection.Points)
+	}
+	fmt.Printf("Restored snapshot taken %s\n", manifest.CreatedAt.Format(time.RFC3339))
+}
+
+// newKnowledgeBase connects the shared RAG service to Qdrant, with the
+// --embedding-timeout applied to its embedding runs
+func newKnowledgeBase() *rag.Service {
+	service, err := rag.NewService("", fmt.Sprintf("%s:%d", QdrantHost, QdrantPort))
+	if err != nil {
+		log.Fatalf("Failed to create RAG service: %v", err)
+	}
+	service.SetEmbeddingTimeout(embeddingTimeout)
+	return service
+}
+
 func showUsage() {
 	fmt.Println(`RAG Service v1.0.0 - Real Qdrant Integration
 
-Usage: rag-service <command> [args...]
+Usage: rag-service [--embedding-timeout <duration>] <command> [args...]
 
 Commands:
   register <project> <path> <technologies>    Register project in vector DB
   store-standards                             Store Claude standards in vector DB
+  store-prompt --role <role> --file <path>    Store a role system prompt (--force to replace a divergent one)
   search <query>                             Semantic search across all data
   context <project> <type> <query>           Get relevant context
   list-projects                              List registered projects
+  tenants [--create <tenant>]                List or create tenant-scoped collections
   export-training-data --format <format>     Export training data for LoRA fine-tuning
+  snapshot <dir>                             Back up every collection with vectors and payloads
+  restore <dir>                              Re-import a snapshot, creating missing collections
   version                                    Show version
 
+Options:
+  --embedding-timeout <duration>             Kill llama-embedding after this long (default 2m, 0 disables)
+
 Examples:
   rag-service store-standards
   rag-service register myapp /path/to/app go,local
   rag-service search "error handling best practices"
   rag-service context myapp development "create HTTP handler"
-  rag-service export-training-data --format llama-finetune > training.jsonl`)
+  rag-service export-training-data --format llama-finetune > training.jsonl
+  rag-service snapshot ./backups/2024-06-01`)
 }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/rag"
+	"github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc"
+)
+
+// fakePoints serves a collection of numbered points through Qdrant's scroll API
+type fakePoints struct {
+	qdrant.UnimplementedPointsServer
+	points []*qdrant.RetrievedPoint
+	pages  atomic.Int32
+}
+
+func (f *fakePoints) Scroll(ctx context.Context, request *qdrant.ScrollPoints) (*qdrant.ScrollResponse, error) {
+	f.pages.Add(1)
+	start := int(request.GetOffset().GetNum())
+	end := min(start+int(request.GetLimit()), len(f.points))
+
+	response := &qdrant.ScrollResponse{Result: f.points[start:end]}
+	if end < len(f.points) {
+		response.NextPageOffset = f.points[end].Id
+	}
+	return response, nil
+}
+
+// newFakeQdrant starts a Qdrant gRPC server holding count documents and
+// returns a client connected to it
+func newFakeQdrant(t *testing.T, count int) (*fakePoints, *qdrant.Client) {
+	t.Helper()
+	fake := &fakePoints{}
+	for i := range count {
+		fake.points = append(fake.points, &qdrant.RetrievedPoint{
+			Id: qdrant.NewIDNum(uint64(i)),
+			Payload: qdrant.NewValueMap(map[string]any{
+				"content": fmt.Sprintf("document %d", i),
+				"type":    "code",
+			}),
+		})
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := grpc.NewServer()
+	qdrant.RegisterPointsServer(server, fake)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	address := listener.Addr().(*net.TCPAddr)
+	client, err := qdrant.NewClient(&qdrant.Config{Host: "127.0.0.1", Port: address.Port, SkipCompatibilityCheck: true})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return fake, client
+}
+
+func TestScrollDocumentsReadsEveryPage(t *testing.T) {
+	tests := []struct {
+		name      string
+		count     int
+		wantPages int32
+	}{
+		{"empty", 0, 1},
+		{"one page", 10, 1},
+		{"exact pages", 2 * rag.TrainingExportBatchSize, 2},
+		{"several pages", 2*rag.TrainingExportBatchSize + 1, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, client := newFakeQdrant(t, tt.count)
+			service := &RAGService{client: client}
+
+			docs, err := service.scrollDocuments(context.Background(), CollectionName)
+			if err != nil {
+				t.Fatalf("scrollDocuments: %v", err)
+			}
+			if len(docs) != tt.count {
+				t.Errorf("got %d documents, want %d", len(docs), tt.count)
+			}
+			if got := fake.pages.Load(); got != tt.wantPages {
+				t.Errorf("scrolled %d pages, want %d", got, tt.wantPages)
+			}
+			if tt.count > 0 && docs[tt.count-1].Content != fmt.Sprintf("document %d", tt.count-1) {
+				t.Errorf("last document = %+v", docs[tt.count-1])
+			}
+		})
+	}
+}
@@ -2,19 +2,22 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
+	"unicode"
 
 	"github.com/niko/mqtt-agent-orchestration/internal/ai"
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
 	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
 	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/internal/prompts"
 	"github.com/niko/mqtt-agent-orchestration/internal/rag"
 	"github.com/niko/mqtt-agent-orchestration/internal/worker"
 	"github.com/niko/mqtt-agent-orchestration/pkg/types"
@@ -25,60 +28,101 @@ const (
 	DefaultMQTTHost      = "localhost"
 	DefaultMQTTPort      = 1883
 	DefaultQdrantURL     = "localhost:6333"
+	DefaultRAGBackend    = "qdrant"
 	StatusUpdateInterval = 30 * time.Second
 	TaskTimeout          = 10 * time.Minute
+	SelfTestTopic        = "workers/selftest/%s"
 )
 
-// RoleWorkerApp represents a role-specific worker application
+// brokerClient is the MQTT client a role worker publishes through
+type brokerClient interface {
+	mqtt.ClientInterface
+	mqtt.RetainedPublisher
+	SetCompressThreshold(threshold int)
+}
+
+// RoleWorkerApp represents a role-specific worker application. One process
+// may serve several roles, subscribing to each role's stage topic.
 type RoleWorkerApp struct {
-	workerID   string
-	role       types.WorkerRole
-	mqttClient *mqtt.Client
-	processor  *worker.RoleBasedProcessor
-	ragService *rag.Service
-	ctx        context.Context
-	cancel     context.CancelFunc
+	workerID     string
+	roles        []types.WorkerRole // Every role served, all named in the MQTT client ID and ID claim
+	mqttClient   brokerClient
+	registration *worker.Registration
+	processors   map[types.WorkerRole]*worker.RoleBasedProcessor // One per workflow role
+	ragService   worker.ContextProvider
+	ingester     *worker.Ingester     // Set for the embedder role
+	modelManager *localmodels.Manager // Nil when local models are unavailable
+	maxPayload   int                  // Largest task message accepted, in bytes
+	taskTTL      time.Duration        // Tasks older than this when consumed are discarded
+	ctx          context.Context
+	cancel       context.CancelFunc
+
+	// Roles whose status changed, published in order by publishStatusPeriodically
+	statusMu      sync.Mutex
+	statusPending map[types.WorkerRole]bool
+	statusWake    chan struct{}
 }
 
-// NewRoleWorkerApp creates a new role-specific worker
-func NewRoleWorkerApp(workerID string, role types.WorkerRole, mqttHost string, mqttPort int, qdrantURL string) (*RoleWorkerApp, error) {
+// NewRoleWorkerApp creates a worker serving roles
+func NewRoleWorkerApp(workerID string, roles []types.WorkerRole, mqttHost string, mqttPort int, qdrantURL, ragBackend, modelDaemonURL string) (*RoleWorkerApp, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	clientID := fmt.Sprintf("%s-%s", role, workerID)
+	// Suffix the client ID per process so a duplicate worker cannot knock the
+	// original off the broker before the ID claim detects the conflict
+	instance := worker.NewInstanceID()
+	clientID := fmt.Sprintf("%s-%s-%s", worker.RoleLabel(roles), workerID, instance)
 	mqttClient := mqtt.NewClientWithID(mqttHost, mqttPort, clientID)
 
+	// Load per-task-type retrieval settings - defaults match the historical TopK 3 / threshold 0.5
+	retrieval, err := config.LoadRetrievalConfig("./configs/retrieval.yaml")
+	if err != nil {
+		log.Printf("Warning: Failed to load retrieval config, using defaults: %v", err)
+		retrieval = config.DefaultRetrievalConfig()
+	}
+
 	// Create RAG service - fail fast if unavailable
-	ragService, err := rag.NewService("qdrant", qdrantURL)
+	ragService, err := newRAGBackend(ragBackend, qdrantURL, retrieval)
 	if err != nil {
 		cancel()
 		return nil, fmt.Errorf("failed to create RAG service: %v", err)
 	}
 
-	// Load model configurations
-	modelConfigs := make(map[string]localmodels.ModelConfig)
-	// For now, create a simple config - in production, load from configs/models.yaml
-	modelConfigs["qwen-text"] = localmodels.ModelConfig{
-		Name:        "Qwen2.5-Omni-3B",
-		BinaryPath:  "/home/niko/bin/llama-server",
-		ModelPath:   "/data/models/Qwen2.5-Omni-3B-Q8_0.gguf",
-		Type:        localmodels.ModelTypeText,
-		MemoryLimit: 5500,
+	// Load model configurations - the fallback is the single text model the worker shipped with
+	modelConfigs, aliases, err := loadModelConfigs("./configs/models.yaml")
+	if err != nil {
+		cancel()
+		return nil, err
 	}
 
-	// Create local models manager
+	// Create local models manager - with a model daemon, models are shared
+	// with colocated workers instead of spawned per process
 	modelManager, err := localmodels.NewManager(localmodels.ModelManagerConfig{
 		MaxGPUMemory:    5632, // 5.5GB for RTX 3060
 		NvidiaSMIPath:   "/usr/bin/nvidia-smi",
 		MonitorInterval: 30 * time.Second,
 		Models:          modelConfigs,
+		Aliases:         aliases,
+		DaemonURL:       modelDaemonURL,
+		MemoryHeadroom:  localmodels.DefaultMemoryHeadroom,
 	})
 	if err != nil {
 		log.Printf("Warning: Failed to create model manager: %v", err)
 		modelManager = nil
 	}
 
+	// Load per-role model preferences - roles without any keep the built-in routing
+	rolePreferences, err := localmodels.LoadRolePreferences("./configs/models.yaml")
+	if err != nil {
+		log.Printf("Warning: Failed to load role model preferences, using built-in routing: %v", err)
+	} else if err := rolePreferences.Validate(modelConfigs, aliases); err != nil {
+		cancel()
+		return nil, fmt.Errorf("./configs/models.yaml: %w", err)
+	}
+
 	// Create content analyzer
 	contentAnalyzer := worker.NewContentAnalyzer(modelConfigs)
+	contentAnalyzer.SetAliases(aliases)
+	contentAnalyzer.SetRolePreferences(rolePreferences)
 
 	// Load AI helper configuration
 	aiConfig, err := ai.LoadAIHelperConfig("./configs/ai_helpers.toml")
@@ -86,23 +130,136 @@ func NewRoleWorkerApp(workerID string, role types.WorkerRole, mqttHost string, m
 		log.Printf("Warning: Failed to load AI config, will use local models only: %v", err)
 	}
 
-	// Create role-based processor
-	processor := worker.NewRoleBasedProcessor(role, ragService, modelManager, contentAnalyzer, aiConfig)
+	// Load tester toolchains - defaults assume go, python3 and shellcheck on PATH
+	toolchains, err := config.LoadToolchainConfig("./configs/toolchains.yaml")
+	if err != nil {
+		log.Printf("Warning: Failed to load toolchain config, using defaults: %v", err)
+	}
+
+	// Load prompt templates - the built-in templates cover anything the file omits
+	promptSet, err := prompts.Load("./configs/prompts.yaml")
+	if err != nil {
+		log.Printf("Warning: Failed to load prompt templates, using defaults: %v", err)
+	}
+
+	// Load prompt budget - defaults cap prompts at 6000 tokens, trimming RAG context first
+	promptBudget, err := config.LoadPromptBudgetConfig("./configs/prompt_budget.yaml")
+	if err != nil {
+		log.Printf("Warning: Failed to load prompt budget config, using defaults: %v", err)
+	}
+
+	// Load document templates - defaults match configs/document_templates.yaml
+	templates, err := config.LoadDocumentTemplateConfig("./configs/document_templates.yaml")
+	if err != nil {
+		log.Printf("Warning: Failed to load document templates, using defaults: %v", err)
+	}
+
+	// Load complexity scoring - defaults reproduce the built-in keyword tiers
+	complexityConfig, err := config.LoadComplexityConfig("./configs/complexity.yaml")
+	if err != nil {
+		log.Printf("Warning: Failed to load complexity config, using defaults: %v", err)
+	}
+
+	// Audit AI interactions into the knowledge base when configured - keys and PII are redacted
+	var auditSink ai.AuditSink
+	if aiConfig != nil && aiConfig.Defaults.AuditInteractions {
+		store, ok := ragService.(worker.DocumentStore)
+		if !ok {
+			cancel()
+			return nil, fmt.Errorf("RAG backend %s cannot store audit records", ragBackend)
+		}
+		auditSink = worker.NewKnowledgeAuditSink(store, aiConfig.Defaults.AuditCollection, workerID)
+	}
+
+	// Ping providers in the background so an unreachable one is skipped during selection
+	if aiConfig != nil {
+		aiConfig.StartHealthChecks(ctx, aiConfig.Defaults.GetHealthCheckInterval(), nil)
+	}
+
+	// Create a role-based processor for every workflow role this worker serves
+	processors := make(map[types.WorkerRole]*worker.RoleBasedProcessor)
+	var ingester *worker.Ingester
+	for _, role := range roles {
+		// The embedder stores documents instead of processing workflow stages
+		if role == types.RoleEmbedder {
+			store, ok := ragService.(worker.DocumentStore)
+			if !ok {
+				cancel()
+				return nil, fmt.Errorf("RAG backend %s cannot store documents", ragBackend)
+			}
+			ingester = worker.NewIngester(store)
+			continue
+		}
+
+		processor := worker.NewRoleBasedProcessor(role, ragService, modelManager, contentAnalyzer, aiConfig)
+		processor.SetRetrievalConfig(retrieval)
+		processor.SetRolePreferences(rolePreferences)
+
+		if toolchains != nil {
+			processor.SetToolchains(toolchains)
+		}
+		if promptSet != nil {
+			processor.SetPrompts(promptSet)
+		}
+		if promptBudget != nil {
+			processor.SetPromptBudget(promptBudget)
+		}
+		if templates != nil {
+			processor.SetDocumentTemplates(templates)
+		}
+		if complexityConfig != nil {
+			processor.SetComplexityConfig(complexityConfig)
+		}
+		if auditSink != nil {
+			processor.SetAuditSink(auditSink)
+		}
+		processors[role] = processor
+	}
 
 	return &RoleWorkerApp{
-		workerID:   workerID,
-		role:       role,
-		mqttClient: mqttClient,
-		processor:  processor,
-		ragService: ragService,
-		ctx:        ctx,
-		cancel:     cancel,
+		workerID:     workerID,
+		roles:        roles,
+		mqttClient:   mqttClient,
+		registration: worker.NewRegistration(mqttClient, workerID, roles, instance),
+		processors:   processors,
+		ragService:   ragService,
+		ingester:     ingester,
+		modelManager: modelManager,
+		maxPayload:   worker.DefaultMaxPayloadSize,
+		taskTTL:      worker.DefaultTaskTTL,
+		ctx:          ctx,
+		cancel:       cancel,
+
+		statusPending: make(map[types.WorkerRole]bool),
+		statusWake:    make(chan struct{}, 1),
 	}, nil
 }
 
+// newRAGBackend creates the knowledge base selected by the --rag-backend flag
+func newRAGBackend(backend, qdrantURL string, retrieval *config.RetrievalConfig) (worker.ContextProvider, error) {
+	switch backend {
+	case "qdrant":
+		service, err := rag.NewService("qdrant", qdrantURL)
+		if err != nil {
+			return nil, err
+		}
+		service.SetRetrievalConfig(retrieval)
+		service.SetEmbeddingCacheSize(retrieval.EmbeddingCacheSize)
+		service.SetSearchCache(retrieval.SearchCacheSize, retrieval.SearchCacheTTL)
+		return service, nil
+	case "memory":
+		log.Printf("Using in-memory RAG backend (offline mode)")
+		service := rag.NewMemoryService()
+		service.SetRetrievalConfig(retrieval)
+		return service, nil
+	default:
+		return nil, fmt.Errorf("unknown RAG backend %q (must be qdrant or memory)", backend)
+	}
+}
+
 // Start starts the role worker
 func (app *RoleWorkerApp) Start() error {
-	log.Printf("Starting %s worker %s", app.role, app.workerID)
+	log.Printf("Starting %s worker %s", worker.RoleLabel(app.roles), app.workerID)
 
 	// Connect to MQTT
 	connectCtx, connectCancel := context.WithTimeout(app.ctx, 10*time.Second)
@@ -114,49 +271,141 @@ func (app *RoleWorkerApp) Start() error {
 
 	log.Printf("Connected to MQTT broker")
 
-	// Subscribe to role-specific task topic
-	taskTopic := fmt.Sprintf("tasks/workflow/%s", app.getStageForRole())
-	if err := app.mqttClient.Subscribe(app.ctx, taskTopic, app.handleTask); err != nil {
-		return fmt.Errorf("failed to subscribe to %s: %w", taskTopic, err)
+	// Refuse to start if another worker already uses this ID
+	if err := app.registration.Claim(app.ctx, worker.DefaultClaimWindow); err != nil {
+		app.mqttClient.Disconnect()
+		return err
 	}
 
-	log.Printf("Subscribed to task topic: %s", taskTopic)
+	// Subscribe to the task topic of every role served
+	for _, role := range app.roles {
+		taskTopic := worker.IngestionTopic
+		handler := app.handleIngestion
+		if role != types.RoleEmbedder {
+			taskTopic = fmt.Sprintf("tasks/workflow/%s", stageForRole(role))
+			handler = func(payload []byte) { app.handleTask(taskTopic, payload) }
+		}
+		if err := app.mqttClient.Subscribe(app.ctx, taskTopic, handler); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", taskTopic, err)
+		}
+
+		log.Printf("Subscribed to task topic: %s", taskTopic)
+	}
 
-	// Start status updates
+	// Start status updates, publishing early as tasks start, progress and finish
+	for role, processor := range app.processors {
+		processor.Progress().OnChange(func() { app.requestStatus(role) })
+	}
 	go app.publishStatusPeriodically()
 
 	// Check RAG availability
 	if app.ragService.IsAvailable(app.ctx) {
-		log.Printf("RAG service (qdrant) is available")
+		log.Printf("RAG service is available")
 	} else {
-		log.Printf("RAG service (qdrant) is not available, using fallback knowledge")
+		log.Printf("RAG service is not available, using fallback knowledge")
+	}
+
+	log.Printf("%s worker %s is ready", worker.RoleLabel(app.roles), app.workerID)
+	return nil
+}
+
+// SelfTest checks the MQTT, RAG and model paths with synthetic traffic. It
+// neither claims the worker ID nor subscribes to real tasks.
+func (app *RoleWorkerApp) SelfTest(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(app.ctx, timeout)
+	defer cancel()
+
+	// MQTT: a message published to a private topic must come back
+	if err := app.mqttClient.Connect(ctx); err != nil {
+		return fmt.Errorf("MQTT: failed to connect: %w", err)
+	}
+	defer app.mqttClient.Disconnect()
+
+	received := make(chan struct{}, 1)
+	topic := fmt.Sprintf(SelfTestTopic, app.workerID)
+	err := app.mqttClient.Subscribe(ctx, topic, func(payload []byte) {
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("MQTT: failed to subscribe to %s: %w", topic, err)
+	}
+	if err := app.mqttClient.Publish(ctx, topic, []byte("ping")); err != nil {
+		return fmt.Errorf("MQTT: failed to publish to %s: %w", topic, err)
+	}
+	select {
+	case <-received:
+		log.Printf("Self-test: MQTT round trip ok")
+	case <-ctx.Done():
+		return fmt.Errorf("MQTT: no round trip on %s: %w", topic, ctx.Err())
 	}
 
-	log.Printf("%s worker %s is ready", app.role, app.workerID)
+	// RAG: the knowledge base must answer
+	if !app.ragService.IsAvailable(ctx) {
+		return fmt.Errorf("RAG: backend is not available")
+	}
+	log.Printf("Self-test: RAG backend ok")
+
+	// Model: the embedder runs no model, every other role processes a synthetic task
+	for _, role := range app.roles {
+		processor, exists := app.processors[role]
+		if !exists {
+			continue
+		}
+		result, err := processor.SelfTest(ctx)
+		if err != nil {
+			return fmt.Errorf("model (%s): %w", role, err)
+		}
+		log.Printf("Self-test: %s synthetic task ok (%d bytes of output)", role, len(result))
+	}
 	return nil
 }
 
 // Stop stops the worker
 func (app *RoleWorkerApp) Stop() {
-	log.Printf("Stopping %s worker %s", app.role, app.workerID)
+	log.Printf("Stopping %s worker %s", worker.RoleLabel(app.roles), app.workerID)
 	app.cancel()
 	if app.mqttClient != nil {
 		app.mqttClient.Disconnect()
 	}
+
+	// Audit records are stored in the background; keep them
+	for _, processor := range app.processors {
+		processor.WaitForAudits()
+	}
 }
 
-// handleTask processes incoming workflow tasks
-func (app *RoleWorkerApp) handleTask(payload []byte) {
+// handleTask processes workflow tasks received on taskTopic, routing each to
+// the processor for its required role
+func (app *RoleWorkerApp) handleTask(taskTopic string, payload []byte) {
+	// Check the size before unmarshalling so an oversized message cannot exhaust memory
+	if err := worker.CheckPayloadSize(payload, app.maxPayload); err != nil {
+		log.Printf("Rejecting task message: %v", err)
+		app.deadLetterPayload(taskTopic, len(payload), err)
+		return
+	}
+
 	var workflowTask types.WorkflowTask
-	if err := json.Unmarshal(payload, &workflowTask); err != nil {
+	if _, err := types.UnwrapMessage(payload, types.MessageTypeWorkflowTask, &workflowTask); err != nil {
 		log.Printf("Failed to unmarshal workflow task: %v", err)
 		return
 	}
 
-	// Check if this task is for our role
-	if workflowTask.RequiredRole != app.role {
+	// Check if this task is for one of our roles
+	processor, exists := app.processors[workflowTask.RequiredRole]
+	if !exists {
 		log.Printf("Ignoring task %s - requires role %s, we are %s",
-			workflowTask.ID, workflowTask.RequiredRole, app.role)
+			workflowTask.ID, workflowTask.RequiredRole, worker.RoleLabel(app.roles))
+		return
+	}
+
+	// A task left in a retained topic or a slow queue has likely been
+	// re-dispatched already, so its result would be dropped as stale anyway
+	if err := worker.CheckTaskAge(workflowTask.CreatedAt, app.taskTTL, time.Now()); err != nil {
+		log.Printf("Discarding task %s (stage: %s, workflow: %s): %v",
+			workflowTask.ID, workflowTask.Stage, workflowTask.WorkflowID, err)
 		return
 	}
 
@@ -167,8 +416,16 @@ func (app *RoleWorkerApp) handleTask(payload []byte) {
 	taskCtx, taskCancel := context.WithTimeout(app.ctx, TaskTimeout)
 	defer taskCancel()
 
+	// Never outlive the overall workflow deadline set by the orchestrator
+	if !workflowTask.Deadline.IsZero() {
+		var deadlineCancel context.CancelFunc
+		taskCtx, deadlineCancel = context.WithDeadline(taskCtx, workflowTask.Deadline)
+		defer deadlineCancel()
+	}
+
 	// Process workflow task with role-based processor
-	result, err := app.processor.ProcessWorkflowTask(taskCtx, &workflowTask)
+	outcome, err := processor.ProcessWorkflowTask(taskCtx, &workflowTask)
+	result := outcome.Output
 
 	// Create workflow result
 	workflowResult := types.WorkflowResult{
@@ -180,9 +437,11 @@ func (app *RoleWorkerApp) handleTask(payload []byte) {
 		},
 		WorkflowID: workflowTask.WorkflowID,
 		Stage:      workflowTask.Stage,
-		WorkerRole: app.role,
+		WorkerRole: workflowTask.RequiredRole,
 	}
 
+	// A stage states its verdict with the word its output leads with
+	verdict, feedback := leadingVerdict(result)
 	if err != nil {
 		workflowResult.Success = false
 		workflowResult.Error = err.Error()
@@ -190,35 +449,129 @@ func (app *RoleWorkerApp) handleTask(payload []byte) {
 	} else {
 		workflowResult.Success = true
 		workflowResult.Result = result
+		workflowResult.Truncated = outcome.FinishReason == localmodels.FinishReasonLength
+		workflowResult.ServedBy = outcome.ServedBy
+		if workflowResult.Truncated {
+			log.Printf("Task %s output was truncated at the token limit", workflowTask.ID)
+		}
 
-		// Check for approval/rejection patterns
-		resultUpper := strings.ToUpper(result)
-		if strings.Contains(resultUpper, "APPROVED") {
-			workflowResult.Approved = true
-		} else if strings.Contains(resultUpper, "REJECTED") {
-			workflowResult.RequiresRetry = true
-			// Extract feedback
-			lines := strings.Split(result, "\n")
-			for _, line := range lines {
-				if strings.Contains(strings.ToUpper(line), "REJECTED:") {
-					workflowResult.ReviewFeedback = strings.TrimSpace(strings.TrimPrefix(line, "REJECTED:"))
-					break
-				}
+		// Only reviews and approvals decide; a draft mentioning APPROVED is not a verdict
+		if workflowTask.Stage == types.StageReview || workflowTask.Stage == types.StageApproval {
+			switch verdict {
+			case "APPROVED":
+				workflowResult.Approved = true
+			case "REJECTED":
+				workflowResult.RequiresRetry = true
+				workflowResult.ReviewFeedback = feedback
 			}
 		}
 
+		// Keep the reviewer's itemized issues so the retry can address each one
+		workflowResult.ReviewItems = worker.ParseReviewItems(result)
+		if workflowResult.RequiresRetry && workflowResult.ReviewFeedback == "" && len(workflowResult.ReviewItems) > 0 {
+			workflowResult.ReviewFeedback = worker.FormatReviewItems(workflowResult.ReviewItems)
+		}
+
 		log.Printf("Task %s completed successfully", workflowTask.ID)
 	}
 
+	// The orchestrator sends back approvals that do not say APPROVED and
+	// failed tests, so those are rejections too
+	rejected := workflowResult.RequiresRetry ||
+		(workflowTask.Stage == types.StageApproval && !workflowResult.Approved) ||
+		(workflowTask.Stage == types.StageTesting && verdict == "FAILED")
+	workflowResult.Status = types.StatusForError(err, rejected)
+
 	// Publish result
-	if err := app.publishResult(workflowResult); err != nil {
+	if err := app.publishResult(workflowResult, workflowTask.ReplyTo); err != nil {
 		log.Printf("Failed to publish result for task %s: %v", workflowTask.ID, err)
 	}
 }
 
-// publishResult publishes workflow result
-func (app *RoleWorkerApp) publishResult(result types.WorkflowResult) error {
-	data, err := json.Marshal(result)
+// leadingVerdict returns the upper-cased word output leads with, such as
+// APPROVED, REJECTED or FAILED, and the rest of its first line after an
+// optional colon
+func leadingVerdict(output string) (verdict, rest string) {
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	end := strings.IndexFunc(line, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		end = len(line)
+	}
+	rest = strings.TrimSpace(line[end:])
+	return strings.ToUpper(line[:end]), strings.TrimSpace(strings.TrimPrefix(rest, ":"))
+}
+
+// deadLetterPayload records a task message that was rejected without decoding
+func (app *RoleWorkerApp) deadLetterPayload(taskTopic string, size int, reason error) {
+	deadLetter := worker.RejectedPayloadDeadLetter(taskTopic, size, reason, time.Now())
+
+	data, err := types.WrapMessage(types.MessageTypeDeadLetter, app.workerID, deadLetter)
+	if err != nil {
+		log.Printf("Failed to marshal dead letter: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(app.ctx, 5*time.Second)
+	defer cancel()
+
+	topic := fmt.Sprintf(worker.RejectedTaskTopic, app.workerID)
+	if err := app.mqttClient.PublishRetained(ctx, topic, data); err != nil {
+		log.Printf("Failed to publish dead letter to %s: %v", topic, err)
+	}
+}
+
+// handleIngestion embeds and stores the documents of an ingestion task
+func (app *RoleWorkerApp) handleIngestion(payload []byte) {
+	var task types.IngestionTask
+	if _, err := types.UnwrapMessage(payload, types.MessageTypeIngestionTask, &task); err != nil {
+		log.Printf("Failed to unmarshal ingestion task: %v", err)
+		return
+	}
+
+	log.Printf("Ingesting %d documents into %s (task %s)", len(task.Documents), task.Collection, task.ID)
+
+	taskCtx, taskCancel := context.WithTimeout(app.ctx, TaskTimeout)
+	defer taskCancel()
+
+	stored, err := app.ingester.Ingest(taskCtx, task)
+
+	result := types.IngestionResult{
+		TaskID:      task.ID,
+		WorkerID:    app.workerID,
+		Collection:  task.Collection,
+		Stored:      stored,
+		Success:     err == nil,
+		ProcessedAt: time.Now(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+		log.Printf("Ingestion task %s failed after %d documents: %v", task.ID, stored, err)
+	}
+
+	data, err := types.WrapMessage(types.MessageTypeIngestionResult, task.ID, result)
+	if err != nil {
+		log.Printf("Failed to marshal ingestion result: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(app.ctx, 5*time.Second)
+	defer cancel()
+
+	topics := []string{fmt.Sprintf(worker.IngestionResultTopic, task.ID)}
+	if task.ReplyTo != "" {
+		topics = append(topics, task.ReplyTo)
+	}
+	for _, topic := range topics {
+		if err := app.mqttClient.Publish(ctx, topic, data); err != nil {
+			log.Printf("Failed to publish ingestion result to %s: %v", topic, err)
+		}
+	}
+}
+
+// publishResult publishes workflow result to the stage topic and, when the
+// task requested it, to its reply topic
+func (app *RoleWorkerApp) publishResult(result types.WorkflowResult, replyTo string) error {
+	data, err := types.WrapMessage(types.MessageTypeWorkflowResult, result.WorkflowID, result)
 	if err != nil {
 		return fmt.Errorf("failed to marshal result: %w", err)
 	}
@@ -227,10 +580,36 @@ func (app *RoleWorkerApp) publishResult(result types.WorkflowResult) error {
 	defer cancel()
 
 	topic := fmt.Sprintf("results/workflow/%s", result.Stage)
-	return app.mqttClient.Publish(ctx, topic, data)
+	if err := app.mqttClient.Publish(ctx, topic, data); err != nil {
+		return err
+	}
+
+	if replyTo != "" {
+		if err := app.mqttClient.Publish(ctx, replyTo, data); err != nil {
+			return fmt.Errorf("failed to publish to reply topic %s: %w", replyTo, err)
+		}
+	}
+	return nil
 }
 
-// publishStatusPeriodically publishes status updates
+// requestStatus schedules a status publish for role without blocking the
+// task reporting the change. Requests made while one is pending coalesce, as
+// the publish reads the state current when it runs.
+func (app *RoleWorkerApp) requestStatus(role types.WorkerRole) {
+	app.statusMu.Lock()
+	app.statusPending[role] = true
+	app.statusMu.Unlock()
+
+	select {
+	case app.statusWake <- struct{}{}:
+	default: // A wake-up is already pending
+	}
+}
+
+// publishStatusPeriodically publishes a status update for every role served,
+// and for roles whose status changed as soon as requested. Publishing from
+// this one goroutine keeps updates in order, so a task's final status is
+// never overtaken by an earlier one.
 func (app *RoleWorkerApp) publishStatusPeriodically() {
 	ticker := time.NewTicker(StatusUpdateInterval)
 	defer ticker.Stop()
@@ -238,28 +617,21 @@ func (app *RoleWorkerApp) publishStatusPeriodically() {
 	for {
 		select {
 		case <-ticker.C:
-			status := types.ExtendedWorkerStatus{
-				WorkerStatus: types.WorkerStatus{
-					ID:       app.workerID,
-					Status:   "idle",
-					LastSeen: time.Now(),
-				},
-				Role:         app.role,
-				Capabilities: worker.GetCapabilitiesForRole(app.role),
+			for _, role := range app.roles {
+				app.publishStatus(role)
 			}
 
-			data, err := json.Marshal(status)
-			if err != nil {
-				log.Printf("Failed to marshal status: %v", err)
-				continue
-			}
+		case <-app.statusWake:
+			app.statusMu.Lock()
+			pending := app.statusPending
+			app.statusPending = make(map[types.WorkerRole]bool)
+			app.statusMu.Unlock()
 
-			ctx, cancel := context.WithTimeout(app.ctx, 5*time.Second)
-			topic := fmt.Sprintf("workers/status/%s/%s", app.role, app.workerID)
-			if err := app.mqttClient.Publish(ctx, topic, data); err != nil {
-				log.Printf("Failed to publish status: %v", err)
+			for _, role := range app.roles {
+				if pending[role] {
+					app.publishStatus(role)
+				}
 			}
-			cancel()
 
 		case <-app.ctx.Done():
 			return
@@ -267,9 +639,43 @@ func (app *RoleWorkerApp) publishStatusPeriodically() {
 	}
 }
 
-// getStageForRole maps roles to workflow stages
-func (app *RoleWorkerApp) getStageForRole() types.WorkflowStage {
-	switch app.role {
+// publishStatus publishes the worker's status under one of its roles
+func (app *RoleWorkerApp) publishStatus(role types.WorkerRole) {
+	status := types.ExtendedWorkerStatus{
+		WorkerStatus: types.WorkerStatus{
+			ID:       app.workerID,
+			Status:   "idle",
+			LastSeen: time.Now(),
+		},
+		Role:         role,
+		Capabilities: worker.GetCapabilitiesForRole(role),
+	}
+	if processor, exists := app.processors[role]; exists {
+		if taskID, progress := processor.Progress().Current(); taskID != "" {
+			status.Status = "busy"
+			status.CurrentTask = taskID
+			status.Progress = progress
+		}
+	}
+
+	data, err := types.WrapMessage(types.MessageTypeWorkerStatus, status.ID, status)
+	if err != nil {
+		log.Printf("Failed to marshal status: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(app.ctx, 5*time.Second)
+	defer cancel()
+
+	topic := fmt.Sprintf("workers/status/%s/%s", role, app.workerID)
+	if err := app.mqttClient.Publish(ctx, topic, data); err != nil {
+		log.Printf("Failed to publish status: %v", err)
+	}
+}
+
+// stageForRole maps roles to workflow stages
+func stageForRole(role types.WorkerRole) types.WorkflowStage {
+	switch role {
 	case types.RoleDeveloper:
 		return types.StageDevelopment
 	case types.RoleReviewer:
@@ -283,15 +689,83 @@ func (app *RoleWorkerApp) getStageForRole() types.WorkflowStage {
 	}
 }
 
+// loadModelConfigs reads the model definitions and their aliases, falling
+// back to a single text model when the file is missing. Aliases that point at
+// models which are not configured are an error, so mismatched names surface
+// at startup instead of as "model config not found" on the first task.
+func loadModelConfigs(path string) (map[string]localmodels.ModelConfig, localmodels.ModelAliases, error) {
+	modelConfigs, err := localmodels.LoadModelConfigs(path)
+	if err != nil {
+		log.Printf("Warning: Failed to load model configuration, using the default text model: %v", err)
+		modelConfigs = map[string]localmodels.ModelConfig{
+			"qwen-omni-3b": {
+				Name:        "Qwen2.5-Omni-3B",
+				BinaryPath:  "/home/niko/bin/llama-server",
+				ModelPath:   "/data/models/Qwen2.5-Omni-3B-Q8_0.gguf",
+				Type:        localmodels.ModelTypeText,
+				MemoryLimit: 5500,
+			},
+		}
+		aliases := localmodels.ModelAliases{"qwen-omni": "qwen-omni-3b", "qwen-text": "qwen-omni-3b"}
+		return modelConfigs, aliases, nil
+	}
+
+	aliases, err := localmodels.LoadModelAliases(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load model aliases: %w", err)
+	}
+	if err := aliases.Validate(modelConfigs); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return modelConfigs, aliases, nil
+}
+
+// parseRoles parses the comma-separated --role flag, rejecting unknown and repeated roles
+func parseRoles(value string) ([]types.WorkerRole, error) {
+	var roles []types.WorkerRole
+	seen := make(map[types.WorkerRole]bool)
+	for _, name := range strings.Split(value, ",") {
+		var role types.WorkerRole
+		switch strings.TrimSpace(name) {
+		case "developer":
+			role = types.RoleDeveloper
+		case "reviewer":
+			role = types.RoleReviewer
+		case "approver":
+			role = types.RoleApprover
+		case "tester":
+			role = types.RoleTester
+		case "embedder":
+			role = types.RoleEmbedder
+		default:
+			return nil, fmt.Errorf("invalid role %q: must be one of developer, reviewer, approver, tester, embedder", strings.TrimSpace(name))
+		}
+		if seen[role] {
+			return nil, fmt.Errorf("role %s listed more than once", role)
+		}
+		seen[role] = true
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
 func main() {
 	// Parse command line flags
 	var (
-		workerID  = flag.String("id", "worker-1", "Worker ID")
-		role      = flag.String("role", "developer", "Worker role (developer, reviewer, approver, tester)")
-		mqttHost  = flag.String("mqtt-host", DefaultMQTTHost, "MQTT broker host")
-		mqttPort  = flag.Int("mqtt-port", DefaultMQTTPort, "MQTT broker port")
-		qdrantURL = flag.String("qdrant-url", DefaultQdrantURL, "Qdrant URL for RAG")
-		verbose   = flag.Bool("verbose", false, "Enable verbose logging")
+		workerID   = flag.String("id", "worker-1", "Worker ID")
+		role       = flag.String("role", "developer", "Worker role, or comma-separated roles served by one process (developer, reviewer, approver, tester, embedder)")
+		mqttHost   = flag.String("mqtt-host", DefaultMQTTHost, "MQTT broker host")
+		mqttPort   = flag.Int("mqtt-port", DefaultMQTTPort, "MQTT broker port")
+		qdrantURL  = flag.String("qdrant-url", DefaultQdrantURL, "Qdrant URL for RAG")
+		ragBackend = flag.String("rag-backend", DefaultRAGBackend, "RAG backend (qdrant, memory)")
+		daemonURL  = flag.String("model-daemon", "", "Model daemon URL (e.g. http://127.0.0.1:8090); empty runs models in-process")
+		compress   = flag.Int("compress-threshold", 0, "Gzip published messages of at least this many bytes (0 disables)")
+		maxPayload = flag.Int("max-payload", worker.DefaultMaxPayloadSize, "Reject task messages larger than this many bytes (0 disables)")
+		taskTTL    = flag.Duration("task-ttl", worker.DefaultTaskTTL, "Discard tasks consumed longer than this after they were published (0 disables)")
+		modelIdle  = flag.Duration("model-idle-timeout", 0, "Unload local models unused for this long, reloading on the next task (0 keeps them loaded)")
+		continues  = flag.Int("max-continuations", worker.DefaultMaxContinuations, "Follow-up generations that extend output truncated at the token limit (0 disables)")
+		selfTest   = flag.Bool("self-test", false, "Run a synthetic task through MQTT, RAG and the model, then exit non-zero on failure")
+		verbose    = flag.Bool("verbose", false, "Enable verbose logging")
 	)
 	flag.Parse()
 
@@ -300,26 +774,34 @@ func main() {
 		log.SetFlags(log.LstdFlags | log.Lshortfile)
 	}
 
-	// Parse role
-	var workerRole types.WorkerRole
-	switch *role {
-	case "developer":
-		workerRole = types.RoleDeveloper
-	case "reviewer":
-		workerRole = types.RoleReviewer
-	case "approver":
-		workerRole = types.RoleApprover
-	case "tester":
-		workerRole = types.RoleTester
-	default:
-		log.Fatalf("Invalid role: %s. Must be one of: developer, reviewer, approver, tester", *role)
+	// Parse roles
+	workerRoles, err := parseRoles(*role)
+	if err != nil {
+		log.Fatalf("Invalid --role: %v", err)
 	}
 
 	// Create worker application
-	app, err := NewRoleWorkerApp(*workerID, workerRole, *mqttHost, *mqttPort, *qdrantURL)
+	app, err := NewRoleWorkerApp(*workerID, workerRoles, *mqttHost, *mqttPort, *qdrantURL, *ragBackend, *daemonURL)
 	if err != nil {
 		log.Fatalf("Failed to create worker application: %v", err)
 	}
+	app.mqttClient.SetCompressThreshold(*compress)
+	app.maxPayload = *maxPayload
+	app.taskTTL = *taskTTL
+	for _, processor := range app.processors {
+		processor.SetMaxContinuations(*continues)
+	}
+	if app.modelManager != nil {
+		app.modelManager.SetIdleTimeout(*modelIdle)
+	}
+
+	if *selfTest {
+		if err := app.SelfTest(TaskTimeout); err != nil {
+			log.Fatalf("Self-test failed: %v", err)
+		}
+		log.Printf("Self-test passed for %s worker %s", worker.RoleLabel(app.roles), app.workerID)
+		return
+	}
 
 	// Set up signal handling
 	sigChan := make(chan os.Signal, 1)
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/internal/rag"
+	"github.com/niko/mqtt-agent-orchestration/internal/worker"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// message is one publish seen by recordingClient
+type message struct {
+	topic    string
+	payload  []byte
+	retained bool
+}
+
+// recordingClient records every publish. With loopback set it delivers
+// publishes to handlers subscribed to the same topic, like a broker would.
+type recordingClient struct {
+	mu         sync.Mutex
+	published  []message
+	loopback   bool
+	handlers   map[string]mqtt.MessageHandler
+	connectErr error
+}
+
+func (c *recordingClient) Connect(context.Context) error             { return c.connectErr }
+func (c *recordingClient) Disconnect()                               {}
+func (c *recordingClient) IsConnected() bool                         { return true }
+func (c *recordingClient) Unsubscribe(context.Context, string) error { return nil }
+func (c *recordingClient) SetCompressThreshold(int)                  {}
+
+func (c *recordingClient) Subscribe(ctx context.Context, topic string, handler mqtt.MessageHandler) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.handlers == nil {
+		c.handlers = make(map[string]mqtt.MessageHandler)
+	}
+	c.handlers[topic] = handler
+	return nil
+}
+
+func (c *recordingClient) Publish(ctx context.Context, topic string, payload []byte) error {
+	c.mu.Lock()
+	c.published = append(c.published, message{topic: topic, payload: payload})
+	handler := c.handlers[topic]
+	c.mu.Unlock()
+
+	if c.loopback && handler != nil {
+		handler(payload)
+	}
+	return nil
+}
+
+func (c *recordingClient) PublishRetained(ctx context.Context, topic string, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.published = append(c.published, message{topic: topic, payload: payload, retained: true})
+	return nil
+}
+
+// topics returns the topics published to, in order
+func (c *recordingClient) topics() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	topics := make([]string, len(c.published))
+	for i, m := range c.published {
+		topics[i] = m.topic
+	}
+	return topics
+}
+
+// newTestApp creates a role worker publishing through a recordingClient
+func newTestApp(t *testing.T, roles ...types.WorkerRole) (*RoleWorkerApp, *recordingClient) {
+	t.Helper()
+	broker := &recordingClient{}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &RoleWorkerApp{
+		workerID:   "w1",
+		roles:      roles,
+		mqttClient: broker,
+		ctx:        ctx,
+		cancel:     cancel,
+	}, broker
+}
+
+func TestNewRAGBackend(t *testing.T) {
+	provider, err := newRAGBackend("memory", "", config.DefaultRetrievalConfig())
+	if err != nil {
+		t.Fatalf("newRAGBackend(memory): %v", err)
+	}
+	if _, ok := provider.(*rag.MemoryService); !ok {
+		t.Errorf("memory backend is %T, want *rag.MemoryService", provider)
+	}
+
+	if _, err := newRAGBackend("sqlite", "", config.DefaultRetrievalConfig()); err == nil {
+		t.Error("newRAGBackend accepted an unknown backend")
+	}
+}
+
+func TestPublishWorkflowResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		replyTo string
+		want    []string
+	}{
+		{"stage topic only", "", []string{"results/workflow/review"}},
+		{"reply topic", "clients/c1/results", []string{"results/workflow/review", "clients/c1/results"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, broker := newTestApp(t, types.RoleReviewer)
+			result := types.WorkflowResult{
+				TaskResult: types.TaskResult{TaskID: "t1", Success: true},
+				WorkflowID: "wf-1",
+				Stage:      types.StageReview,
+			}
+			if err := app.publishResult(result, tt.replyTo); err != nil {
+				t.Fatalf("publishResult: %v", err)
+			}
+
+			topics := broker.topics()
+			if len(topics) != len(tt.want) {
+				t.Fatalf("published to %v, want %v", topics, tt.want)
+			}
+			for i, want := range tt.want {
+				if topics[i] != want {
+					t.Errorf("publish %d went to %q, want %q", i, topics[i], want)
+				}
+				var got types.WorkflowResult
+				if _, err := types.UnwrapMessage(broker.published[i].payload, types.MessageTypeWorkflowResult, &got); err != nil || got.WorkflowID != "wf-1" {
+					t.Errorf("publish %d payload = %+v, %v", i, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleIngestion(t *testing.T) {
+	tests := []struct {
+		name        string
+		task        types.IngestionTask
+		wantTopics  []string
+		wantSuccess bool
+	}{
+		{"stores the document", types.IngestionTask{
+			ID: "ingest-1", Collection: "documentation", ReplyTo: "client/ingest-1",
+			Documents: []types.RAGDocument{{Content: "retries use exponential backoff", Source: "retry.md"}},
+		}, []string{"results/ingest/ingest-1", "client/ingest-1"}, true},
+		{"invalid task", types.IngestionTask{
+			ID: "ingest-2", Documents: []types.RAGDocument{{Content: "no collection"}},
+		}, []string{"results/ingest/ingest-2"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, broker := newTestApp(t, types.RoleEmbedder)
+			store := rag.NewMemoryService()
+			app.ingester = worker.NewIngester(store)
+
+			payload, err := types.WrapMessage(types.MessageTypeIngestionTask, tt.task.ID, tt.task)
+			if err != nil {
+				t.Fatalf("WrapMessage: %v", err)
+			}
+			app.handleIngestion(payload)
+
+			if got := broker.topics(); !slices.Equal(got, tt.wantTopics) {
+				t.Fatalf("published to %v, want %v", got, tt.wantTopics)
+			}
+			var result types.IngestionResult
+			if _, err := types.UnwrapMessage(broker.published[0].payload, types.MessageTypeIngestionResult, &result); err != nil {
+				t.Fatalf("result payload: %v", err)
+			}
+			if result.Success != tt.wantSuccess || result.TaskID != tt.task.ID || result.WorkerID != "w1" {
+				t.Errorf("result = %+v, want success %v", result, tt.wantSuccess)
+			}
+			if !tt.wantSuccess {
+				return
+			}
+
+			response, err := store.SearchKnowledge(context.Background(), types.RAGQuery{Query: "exponential backoff", Collection: "documentation"})
+			if err != nil {
+				t.Fatalf("SearchKnowledge: %v", err)
+			}
+			if response.TotalHits != 1 || response.Documents[0].Source != "retry.md" {
+				t.Errorf("stored documents = %+v, want the ingested one", response.Documents)
+			}
+		})
+	}
+}
+
+func TestHandleTaskRejectsOversizedPayload(t *testing.T) {
+	task, err := types.WrapMessage(types.MessageTypeWorkflowTask, "wf-1", types.WorkflowTask{
+		Task:         types.Task{ID: "wf-1-review-0", Type: "create_document"},
+		WorkflowID:   "wf-1",
+		Stage:        types.StageReview,
+		RequiredRole: types.RoleReviewer,
+	})
+	if err != nil {
+		t.Fatalf("WrapMessage: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		payload    []byte
+		maxPayload int
+		wantReject bool
+	}{
+		{"within limit", task, 1024, false},
+		// Not JSON either, so a rejection proves it was never unmarshalled
+		{"oversized", []byte(strings.Repeat("x", 2048)), 1024, true},
+		{"limit disabled", task, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, broker := newTestApp(t, types.RoleDeveloper)
+			app.maxPayload = tt.maxPayload
+
+			app.handleTask("tasks/workflow/review", tt.payload)
+
+			if !tt.wantReject {
+				if len(broker.published) != 0 {
+					t.Errorf("published to %v, want nothing", broker.topics())
+				}
+				return
+			}
+			if got := broker.topics(); len(got) != 1 || got[0] != "tasks/deadletter/rejected/w1" || !broker.published[0].retained {
+				t.Fatalf("published to %v, want a retained dead letter", got)
+			}
+			var deadLetter types.DeadLetter
+			if _, err := types.UnwrapMessage(broker.published[0].payload, types.MessageTypeDeadLetter, &deadLetter); err != nil {
+				t.Fatalf("dead letter payload: %v", err)
+			}
+			if deadLetter.PayloadSize != len(tt.payload) || deadLetter.Topic != "tasks/workflow/review" || !strings.Contains(deadLetter.Reason, "payload too large") {
+				t.Errorf("dead letter = %+v", deadLetter)
+			}
+		})
+	}
+}
+
+// unavailableRAG is a knowledge base that is down
+type unavailableRAG struct {
+	*rag.MemoryService
+}
+
+func (unavailableRAG) IsAvailable(context.Context) bool { return false }
+
+// newFakeModels returns a model manager whose daemon answers every
+// prediction with reply
+func newFakeModels(t *testing.T, reply string) *localmodels.Manager {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/predict") {
+			json.NewEncoder(w).Encode(localmodels.ModelOutput{Text: reply, FinishReason: localmodels.FinishReasonStop})
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	manager, err := localmodels.NewManager(localmodels.ModelManagerConfig{DaemonURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return manager
+}
+
+func TestSelfTest(t *testing.T) {
+	tests := []struct {
+		name       string
+		loopback   bool
+		connectErr error
+		ragService worker.ContextProvider
+		reply      string
+		wantErr    string
+	}{
+		{"passes", true, nil, rag.NewMemoryService(), "OK", ""},
+		{"broker unreachable", true, errors.New("connection refused"), rag.NewMemoryService(), "OK", "MQTT: failed to connect"},
+		{"no round trip", false, nil, rag.NewMemoryService(), "OK", "MQTT: no round trip"},
+		{"RAG down", true, nil, unavailableRAG{rag.NewMemoryService()}, "OK", "RAG: backend is not available"},
+		{"empty model output", true, nil, rag.NewMemoryService(), "", "model (developer): self-test task returned an empty result"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, broker := newTestApp(t, types.RoleDeveloper)
+			broker.loopback = tt.loopback
+			broker.connectErr = tt.connectErr
+			app.ragService = tt.ragService
+			app.processors = map[types.WorkerRole]*worker.RoleBasedProcessor{
+				types.RoleDeveloper: worker.NewRoleBasedProcessor(types.RoleDeveloper, tt.ragService, newFakeModels(t, tt.reply), nil, nil),
+			}
+
+			err := app.SelfTest(200 * time.Millisecond)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("SelfTest: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("SelfTest = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandleTaskResultStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		stage        types.WorkflowStage
+		document     string
+		reply        string
+		cancelled    bool
+		wantStatus   types.ResultStatus
+		wantSuccess  bool
+		wantApproved bool
+		wantRetry    bool
+	}{
+		{"approved", types.StageApproval, "design", "APPROVED: complete and accurate", false, types.StatusCompleted, true, true, false},
+		{"rejected", types.StageApproval, "design", "REJECTED: missing examples", false, types.StatusRejected, true, false, true},
+		{"rejected mentioning approved", types.StageApproval, "design", "REJECTED: the outline was approved but examples are missing", false, types.StatusRejected, true, false, true},
+		{"no verdict is a rejection", types.StageApproval, "design", "Looks mostly fine", false, types.StatusRejected, true, false, false},
+		{"worker shutting down", types.StageApproval, "design", "APPROVED: fine", true, types.StatusCancelled, false, false, false},
+		{"review rejects", types.StageReview, "design", "REJECTED: intro too short", false, types.StatusRejected, true, false, true},
+		{"tests fail", types.StageTesting, "go_coding_standards", "", false, types.StatusRejected, true, false, false},
+		{"developer draft", types.StageDevelopment, "design", "# Design\nSections are APPROVED or REJECTED by the reviewer.", false, types.StatusCompleted, true, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			role := tt.stage.RequiredRole()
+			app, broker := newTestApp(t, role)
+			app.processors = map[types.WorkerRole]*worker.RoleBasedProcessor{
+				role: worker.NewRoleBasedProcessor(role, rag.NewMemoryService(), newFakeModels(t, tt.reply), nil, nil),
+			}
+			if tt.cancelled {
+				app.cancel()
+			}
+
+			task := types.WorkflowTask{
+				Task:         types.Task{ID: "wf-1-" + string(tt.stage) + "-0", Type: "create_document", Payload: map[string]string{"document_type": tt.document}, CreatedAt: time.Now()},
+				WorkflowID:   "wf-1",
+				Stage:        tt.stage,
+				RequiredRole: role,
+			}
+			if tt.stage != types.StageDevelopment {
+				task.PreviousOutput = "# Design"
+			}
+			payload, err := types.WrapMessage(types.MessageTypeWorkflowTask, "wf-1", task)
+			if err != nil {
+				t.Fatalf("WrapMessage: %v", err)
+			}
+
+			app.handleTask("tasks/workflow/"+string(tt.stage), payload)
+
+			if len(broker.published) != 1 {
+				t.Fatalf("published to %v, want one result", broker.topics())
+			}
+			var result types.WorkflowResult
+			if _, err := types.UnwrapMessage(broker.published[0].payload, types.MessageTypeWorkflowResult, &result); err != nil {
+				t.Fatalf("result payload: %v", err)
+			}
+			if result.Status != tt.wantStatus || result.Success != tt.wantSuccess {
+				t.Errorf("result status %s, success %v; want %s, %v (error %q)", result.Status, result.Success, tt.wantStatus, tt.wantSuccess, result.Error)
+			}
+			if result.Approved != tt.wantApproved || result.RequiresRetry != tt.wantRetry {
+				t.Errorf("result approved %v, retry %v; want %v, %v", result.Approved, result.RequiresRetry, tt.wantApproved, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestHandleTaskDiscardsStaleTasks(t *testing.T) {
+	tests := []struct {
+		name        string
+		age         time.Duration
+		ttl         time.Duration
+		wantResults int
+	}{
+		{"fresh", time.Minute, worker.DefaultTaskTTL, 1},
+		{"stale", time.Hour, worker.DefaultTaskTTL, 0},
+		{"check disabled", time.Hour, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, broker := newTestApp(t, types.RoleApprover)
+			app.taskTTL = tt.ttl
+			app.processors = map[types.WorkerRole]*worker.RoleBasedProcessor{
+				types.RoleApprover: worker.NewRoleBasedProcessor(types.RoleApprover, rag.NewMemoryService(), newFakeModels(t, "APPROVED: fine"), nil, nil),
+			}
+
+			task := types.WorkflowTask{
+				Task:           types.Task{ID: "wf-1-approval-0", Type: "create_document", Payload: map[string]string{"document_type": "design"}, CreatedAt: time.Now().Add(-tt.age)},
+				WorkflowID:     "wf-1",
+				Stage:          types.StageApproval,
+				RequiredRole:   types.RoleApprover,
+				PreviousOutput: "# Design",
+			}
+			payload, err := types.WrapMessage(types.MessageTypeWorkflowTask, "wf-1", task)
+			if err != nil {
+				t.Fatalf("WrapMessage: %v", err)
+			}
+
+			app.handleTask("tasks/workflow/approval", payload)
+
+			if got := broker.topics(); len(got) != tt.wantResults {
+				t.Errorf("published to %v, want %d results", got, tt.wantResults)
+			}
+		})
+	}
+}
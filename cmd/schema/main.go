@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+func main() {
+	// Parse command line flags
+	var (
+		typeName = flag.String("type", "", "Emit the schema for one message type only (e.g. WorkflowTask)")
+		list     = flag.Bool("list", false, "List the message types with schemas and exit")
+	)
+	flag.Parse()
+
+	messageTypes := types.MessageTypes()
+	names := make([]string, 0, len(messageTypes))
+	for name := range messageTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if *list {
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return
+	}
+
+	var output any
+	if *typeName != "" {
+		value, exists := messageTypes[*typeName]
+		if !exists {
+			log.Fatalf("Unknown message type %q (run with --list to see the supported types)", *typeName)
+		}
+		output = types.JSONSchema(*typeName, value)
+	} else {
+		schemas := make(map[string]any, len(messageTypes))
+		for _, name := range names {
+			schemas[name] = types.JSONSchema(name, messageTypes[name])
+		}
+		output = schemas
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(output); err != nil {
+		log.Fatalf("Failed to write schema: %v", err)
+	}
+}
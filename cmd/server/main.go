@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
@@ -24,18 +23,21 @@ const (
 // TestServer represents a simple test server for the MQTT system
 type TestServer struct {
 	mqttClient *mqtt.Client
+	inflight   *mqtt.InflightLimiter
 	ctx        context.Context
 	cancel     context.CancelFunc
 }
 
-// NewTestServer creates a new test server
-func NewTestServer(mqttHost string, mqttPort int) *TestServer {
+// NewTestServer creates a new test server. maxInflight caps the number of
+// published tasks awaiting a result; zero disables the cap.
+func NewTestServer(mqttHost string, mqttPort int, maxInflight int) *TestServer {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	mqttClient := mqtt.NewClientWithID(mqttHost, mqttPort, "test-server")
 
 	return &TestServer{
 		mqttClient: mqttClient,
+		inflight:   mqtt.NewInflightLimiter(maxInflight),
 		ctx:        ctx,
 		cancel:     cancel,
 	}
@@ -79,7 +81,7 @@ func (s *TestServer) Stop() {
 	}
 }
 
-// PublishTestTask publishes a test task
+// PublishTestTask publishes a test task, blocking while the inflight cap is reached
 func (s *TestServer) PublishTestTask(taskType string, payload map[string]string) error {
 	task := types.Task{
 		ID:        fmt.Sprintf("task-%d", time.Now().UnixNano()),
@@ -89,26 +91,38 @@ func (s *TestServer) PublishTestTask(taskType string, payload map[string]string)
 		Priority:  1,
 	}
 
-	data, err := json.Marshal(task)
+	data, err := types.WrapMessage(types.MessageTypeTask, task.ID, task)
 	if err != nil {
 		return fmt.Errorf("failed to marshal task: %w", err)
 	}
 
+	// Throttle until workers have caught up with outstanding tasks
+	if err := s.inflight.Acquire(s.ctx, task.ID); err != nil {
+		return fmt.Errorf("failed to acquire inflight slot for task %s: %w", task.ID, err)
+	}
+
 	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
 	defer cancel()
 
-	log.Printf("Publishing task %s of type %s", task.ID, task.Type)
-	return s.mqttClient.Publish(ctx, TaskTopic, data)
+	log.Printf("Publishing task %s of type %s (inflight: %d)", task.ID, task.Type, s.inflight.Inflight())
+	if err := s.mqttClient.Publish(ctx, TaskTopic, data); err != nil {
+		s.inflight.Release(task.ID)
+		return err
+	}
+
+	return nil
 }
 
 // handleResult handles incoming task results
 func (s *TestServer) handleResult(payload []byte) {
 	var result types.TaskResult
-	if err := json.Unmarshal(payload, &result); err != nil {
+	if _, err := types.UnwrapMessage(payload, types.MessageTypeTaskResult, &result); err != nil {
 		log.Printf("Failed to unmarshal result: %v", err)
 		return
 	}
 
+	s.inflight.Release(result.TaskID)
+
 	log.Printf("Received result for task %s from worker %s (success: %v, duration: %dms)",
 		result.TaskID, result.WorkerID, result.Success, result.Duration)
 
@@ -122,7 +136,7 @@ func (s *TestServer) handleResult(payload []byte) {
 // handleWorkerStatus handles worker status updates
 func (s *TestServer) handleWorkerStatus(payload []byte) {
 	var status types.WorkerStatus
-	if err := json.Unmarshal(payload, &status); err != nil {
+	if _, err := types.UnwrapMessage(payload, types.MessageTypeWorkerStatus, &status); err != nil {
 		log.Printf("Failed to unmarshal worker status: %v", err)
 		return
 	}
@@ -134,17 +148,18 @@ func (s *TestServer) handleWorkerStatus(payload []byte) {
 func main() {
 	// Parse command line flags
 	var (
-		mqttHost = flag.String("mqtt-host", DefaultMQTTHost, "MQTT broker host")
-		mqttPort = flag.Int("mqtt-port", DefaultMQTTPort, "MQTT broker port")
-		taskType = flag.String("task-type", "echo", "Type of test task to send")
-		message  = flag.String("message", "Hello from test server!", "Message for test task")
-		numTasks = flag.Int("num-tasks", 3, "Number of test tasks to send")
-		interval = flag.Duration("interval", 2*time.Second, "Interval between tasks")
+		mqttHost    = flag.String("mqtt-host", DefaultMQTTHost, "MQTT broker host")
+		mqttPort    = flag.Int("mqtt-port", DefaultMQTTPort, "MQTT broker port")
+		taskType    = flag.String("task-type", "echo", "Type of test task to send")
+		message     = flag.String("message", "Hello from test server!", "Message for test task")
+		numTasks    = flag.Int("num-tasks", 3, "Number of test tasks to send")
+		interval    = flag.Duration("interval", 2*time.Second, "Interval between tasks")
+		maxInflight = flag.Int("max-inflight", 0, "Maximum tasks awaiting results before publishing blocks (0 = unlimited)")
 	)
 	flag.Parse()
 
 	// Create and start server
-	server := NewTestServer(*mqttHost, *mqttPort)
+	server := NewTestServer(*mqttHost, *mqttPort, *maxInflight)
 
 	if err := server.Start(); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
@@ -12,6 +11,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/niko/mqtt-agent-orchestration/internal/docstore"
 	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
 	"github.com/niko/mqtt-agent-orchestration/internal/worker"
 	"github.com/niko/mqtt-agent-orchestration/pkg/types"
@@ -30,7 +30,9 @@ const (
 )
 
 // SimpleTaskProcessor implements basic task processing for testing
-type SimpleTaskProcessor struct{}
+type SimpleTaskProcessor struct {
+	versionedOutput bool // Keep earlier documents as <output_file>.vN
+}
 
 // ProcessTask processes tasks based on their type
 func (p *SimpleTaskProcessor) ProcessTask(ctx context.Context, task types.Task) (string, error) {
@@ -140,7 +142,15 @@ Format as markdown with clear examples of good and bad patterns. Make it compreh
 		return "", fmt.Errorf("failed to generate Go coding standards: %w", err)
 	}
 
-	// Write to output file
+	// Write to output file, archiving the previous version when enabled
+	if p.versionedOutput {
+		version, err := docstore.WriteVersioned(outputFile, output)
+		if err != nil {
+			return "", fmt.Errorf("failed to write output file %s: %w", outputFile, err)
+		}
+		return fmt.Sprintf("Successfully created Go coding standards document: %s (version %d, %d bytes)", outputFile, version.Version, len(output)), nil
+	}
+
 	err = os.WriteFile(outputFile, output, 0644)
 	if err != nil {
 		return "", fmt.Errorf("failed to write output file %s: %w", outputFile, err)
@@ -151,27 +161,32 @@ Format as markdown with clear examples of good and bad patterns. Make it compreh
 
 // WorkerApp represents the main worker application
 type WorkerApp struct {
-	workerID   string
-	mqttClient *mqtt.Client
-	worker     *worker.Worker
-	ctx        context.Context
-	cancel     context.CancelFunc
+	workerID     string
+	mqttClient   mqtt.ClientInterface
+	registration *worker.Registration
+	worker       *worker.Worker
+	ctx          context.Context
+	cancel       context.CancelFunc
 }
 
 // NewWorkerApp creates a new worker application
-func NewWorkerApp(workerID, mqttHost string, mqttPort int) *WorkerApp {
+func NewWorkerApp(workerID, mqttHost string, mqttPort int, versionedOutput bool) *WorkerApp {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	mqttClient := mqtt.NewClientWithID(mqttHost, mqttPort, fmt.Sprintf("worker-%s", workerID))
-	processor := &SimpleTaskProcessor{}
+	// Suffix the client ID per process so a duplicate worker cannot knock the
+	// original off the broker before the ID claim detects the conflict
+	instance := worker.NewInstanceID()
+	mqttClient := mqtt.NewClientWithID(mqttHost, mqttPort, fmt.Sprintf("worker-%s-%s", workerID, instance))
+	processor := &SimpleTaskProcessor{versionedOutput: versionedOutput}
 	w := worker.NewWorker(workerID, processor)
 
 	return &WorkerApp{
-		workerID:   workerID,
-		mqttClient: mqttClient,
-		worker:     w,
-		ctx:        ctx,
-		cancel:     cancel,
+		workerID:     workerID,
+		mqttClient:   mqttClient,
+		registration: worker.NewRegistration(mqttClient, workerID, nil, instance),
+		worker:       w,
+		ctx:          ctx,
+		cancel:       cancel,
 	}
 }
 
@@ -189,6 +204,12 @@ func (app *WorkerApp) Start() error {
 
 	log.Printf("Connected to MQTT broker")
 
+	// Refuse to start if another worker already uses this ID
+	if err := app.registration.Claim(app.ctx, worker.DefaultClaimWindow); err != nil {
+		app.mqttClient.Disconnect()
+		return err
+	}
+
 	// Subscribe to task topic
 	if err := app.mqttClient.Subscribe(app.ctx, TaskTopic, app.handleTask); err != nil {
 		return fmt.Errorf("failed to subscribe to task topic: %w", err)
@@ -223,7 +244,7 @@ func (app *WorkerApp) Stop() {
 // handleTask processes incoming task messages
 func (app *WorkerApp) handleTask(payload []byte) {
 	var task types.Task
-	if err := json.Unmarshal(payload, &task); err != nil {
+	if _, err := types.UnwrapMessage(payload, types.MessageTypeTask, &task); err != nil {
 		log.Printf("Failed to unmarshal task: %v", err)
 		return
 	}
@@ -237,16 +258,17 @@ func (app *WorkerApp) handleTask(payload []byte) {
 	result := app.worker.ProcessTask(taskCtx, task)
 
 	// Publish result
-	if err := app.publishResult(result); err != nil {
+	if err := app.publishResult(result, task.ReplyTo); err != nil {
 		log.Printf("Failed to publish result for task %s: %v", task.ID, err)
 	} else {
 		log.Printf("Published result for task %s (success: %v)", task.ID, result.Success)
 	}
 }
 
-// publishResult publishes a task result to the results topic
-func (app *WorkerApp) publishResult(result types.TaskResult) error {
-	data, err := json.Marshal(result)
+// publishResult publishes a task result to the results topic and, when the
+// task requested it, to its reply topic
+func (app *WorkerApp) publishResult(result types.TaskResult, replyTo string) error {
+	data, err := types.WrapMessage(types.MessageTypeTaskResult, result.TaskID, result)
 	if err != nil {
 		return fmt.Errorf("failed to marshal result: %w", err)
 	}
@@ -254,14 +276,23 @@ func (app *WorkerApp) publishResult(result types.TaskResult) error {
 	ctx, cancel := context.WithTimeout(app.ctx, 5*time.Second)
 	defer cancel()
 
-	return app.mqttClient.Publish(ctx, ResultTopic, data)
+	if err := app.mqttClient.Publish(ctx, ResultTopic, data); err != nil {
+		return err
+	}
+
+	if replyTo != "" {
+		if err := app.mqttClient.Publish(ctx, replyTo, data); err != nil {
+			return fmt.Errorf("failed to publish to reply topic %s: %w", replyTo, err)
+		}
+	}
+	return nil
 }
 
 // publishStatus publishes worker status
 func (app *WorkerApp) publishStatus() error {
 	status := app.worker.GetStatus()
 
-	data, err := json.Marshal(status)
+	data, err := types.WrapMessage(types.MessageTypeWorkerStatus, status.ID, status)
 	if err != nil {
 		return fmt.Errorf("failed to marshal status: %w", err)
 	}
@@ -294,10 +325,11 @@ func (app *WorkerApp) publishStatusPeriodically() {
 func main() {
 	// Parse command line flags - explicit configuration
 	var (
-		workerID = flag.String("id", DefaultWorkerID, "Worker ID")
-		mqttHost = flag.String("mqtt-host", DefaultMQTTHost, "MQTT broker host")
-		mqttPort = flag.Int("mqtt-port", DefaultMQTTPort, "MQTT broker port")
-		verbose  = flag.Bool("verbose", false, "Enable verbose logging")
+		workerID  = flag.String("id", DefaultWorkerID, "Worker ID")
+		mqttHost  = flag.String("mqtt-host", DefaultMQTTHost, "MQTT broker host")
+		mqttPort  = flag.Int("mqtt-port", DefaultMQTTPort, "MQTT broker port")
+		versioned = flag.Bool("versioned-output", false, "Keep earlier documents as <output_file>.vN with a version manifest")
+		verbose   = flag.Bool("verbose", false, "Enable verbose logging")
 	)
 	flag.Parse()
 
@@ -307,7 +339,7 @@ func main() {
 	}
 
 	// Create worker application
-	app := NewWorkerApp(*workerID, *mqttHost, *mqttPort)
+	app := NewWorkerApp(*workerID, *mqttHost, *mqttPort, *versioned)
 
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
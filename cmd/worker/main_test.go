@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// recordingClient records publishes, failing those to failTopic
+type recordingClient struct {
+	mu        sync.Mutex
+	published []string
+	failTopic string
+}
+
+func (c *recordingClient) Connect(context.Context) error                                { return nil }
+func (c *recordingClient) Disconnect()                                                  {}
+func (c *recordingClient) IsConnected() bool                                            { return true }
+func (c *recordingClient) Subscribe(context.Context, string, mqtt.MessageHandler) error { return nil }
+func (c *recordingClient) Unsubscribe(context.Context, string) error                    { return nil }
+
+func (c *recordingClient) Publish(ctx context.Context, topic string, payload []byte) error {
+	if topic == c.failTopic {
+		return errors.New("broker unavailable")
+	}
+	var result types.TaskResult
+	if _, err := types.UnwrapMessage(payload, types.MessageTypeTaskResult, &result); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.published = append(c.published, topic)
+	return nil
+}
+
+func TestPublishResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		replyTo   string
+		failTopic string
+		want      []string
+		wantErr   bool
+	}{
+		{"results topic only", "", "", []string{ResultTopic}, false},
+		{"reply topic", "clients/c1/results", "", []string{ResultTopic, "clients/c1/results"}, false},
+		{"reply topic fails", "clients/c1/results", "clients/c1/results", []string{ResultTopic}, true},
+		{"results topic fails", "clients/c1/results", ResultTopic, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := &recordingClient{failTopic: tt.failTopic}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			app := &WorkerApp{workerID: "w1", mqttClient: broker, ctx: ctx, cancel: cancel}
+
+			err := app.publishResult(types.TaskResult{TaskID: "t1", Success: true}, tt.replyTo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("publishResult err = %v, want error %v", err, tt.wantErr)
+			}
+			if len(broker.published) != len(tt.want) {
+				t.Fatalf("published to %v, want %v", broker.published, tt.want)
+			}
+			for i, topic := range tt.want {
+				if broker.published[i] != topic {
+					t.Errorf("publish %d went to %q, want %q", i, broker.published[i], topic)
+				}
+			}
+		})
+	}
+}
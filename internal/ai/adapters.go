@@ -0,0 +1,244 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// completion is a provider response reduced to the fields callAPI needs
+type completion struct {
+	Content      string
+	FinishReason string
+	Usage        TokenUsage
+}
+
+// providerAdapter shapes requests and parses responses for one provider API schema
+type providerAdapter interface {
+	buildRequest(model string, apiConfig APIConfig, messages []Message, stream bool) ([]byte, error)
+	parseResponse(body []byte) (completion, error)
+
+	// streamURL returns the endpoint for server-sent event streaming
+	streamURL(apiURL string) string
+	// parseStreamChunk parses one SSE data payload into an incremental completion
+	parseStreamChunk(data []byte) (completion, error)
+}
+
+// adapterFor returns the request adapter for a provider. Everything except
+// Gemini speaks the OpenAI chat completions schema.
+func adapterFor(provider string) providerAdapter {
+	switch provider {
+	case "gemini":
+		return geminiAdapter{}
+	default:
+		return openAIAdapter{}
+	}
+}
+
+// openAIAdapter handles OpenAI-compatible chat completions (Cerebras, NVIDIA, Groq, Grok)
+type openAIAdapter struct{}
+
+// buildRequest marshals a chat completions request
+func (openAIAdapter) buildRequest(model string, apiConfig APIConfig, messages []Message, stream bool) ([]byte, error) {
+	return json.Marshal(ChatRequest{
+		Model:       model,
+		Messages:    messages,
+		MaxTokens:   apiConfig.MaxTokens,
+		Temperature: apiConfig.Temperature,
+		TopP:        apiConfig.TopP,
+		Stream:      stream,
+	})
+}
+
+// streamURL returns apiURL; OpenAI-style providers stream from the same endpoint
+func (openAIAdapter) streamURL(apiURL string) string {
+	return apiURL
+}
+
+// chatStreamChunk is one chat.completion.chunk event
+type chatStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// parseStreamChunk extracts the content delta from a chat.completion.chunk
+func (openAIAdapter) parseStreamChunk(data []byte) (completion, error) {
+	var chunk chatStreamChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return completion{}, fmt.Errorf("failed to parse stream chunk: %w", err)
+	}
+
+	var result completion
+	if len(chunk.Choices) > 0 {
+		result.Content = chunk.Choices[0].Delta.Content
+		result.FinishReason = chunk.Choices[0].FinishReason
+	}
+	if chunk.Usage != nil {
+		result.Usage = TokenUsage{
+			InputTokens:  chunk.Usage.PromptTokens,
+			OutputTokens: chunk.Usage.CompletionTokens,
+			TotalTokens:  chunk.Usage.TotalTokens,
+		}
+	}
+	return result, nil
+}
+
+// parseResponse extracts the first choice from a chat completions response
+func (openAIAdapter) parseResponse(body []byte) (completion, error) {
+	var chatResp ChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return completion{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return completion{}, fmt.Errorf("no choices in response")
+	}
+
+	return completion{
+		Content:      chatResp.Choices[0].Message.Content,
+		FinishReason: chatResp.Choices[0].FinishReason,
+		Usage: TokenUsage{
+			InputTokens:  chatResp.Usage.PromptTokens,
+			OutputTokens: chatResp.Usage.CompletionTokens,
+			TotalTokens:  chatResp.Usage.TotalTokens,
+		},
+	}, nil
+}
+
+// geminiPart is a single piece of Gemini content
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+// geminiContent is one turn in a Gemini conversation
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+// geminiGenerationConfig holds Gemini sampling settings
+type geminiGenerationConfig struct {
+	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
+	Temperature     float64 `json:"temperature,omitempty"`
+	TopP            float64 `json:"topP,omitempty"`
+}
+
+// GeminiRequest is the body of a Gemini generateContent call
+type GeminiRequest struct {
+	Contents          []geminiContent        `json:"contents"`
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+// GeminiResponse is the body returned by generateContent
+type GeminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// geminiAdapter handles the Gemini generateContent schema (contents/parts)
+type geminiAdapter struct{}
+
+// buildRequest converts chat messages into Gemini contents. System messages
+// become the system instruction and assistant turns use the "model" role.
+func (geminiAdapter) buildRequest(model string, apiConfig APIConfig, messages []Message, stream bool) ([]byte, error) {
+	request := GeminiRequest{
+		GenerationConfig: geminiGenerationConfig{
+			MaxOutputTokens: apiConfig.MaxTokens,
+			Temperature:     apiConfig.Temperature,
+			TopP:            apiConfig.TopP,
+		},
+	}
+
+	var system []geminiPart
+	for _, message := range messages {
+		switch message.Role {
+		case "system":
+			system = append(system, geminiPart{Text: message.Content})
+		case "assistant":
+			request.Contents = append(request.Contents, geminiContent{Role: "model", Parts: []geminiPart{{Text: message.Content}}})
+		default:
+			request.Contents = append(request.Contents, geminiContent{Role: "user", Parts: []geminiPart{{Text: message.Content}}})
+		}
+	}
+
+	if len(system) > 0 {
+		request.SystemInstruction = &geminiContent{Parts: system}
+	}
+
+	return json.Marshal(request)
+}
+
+// parseResponse joins the text parts of the first candidate
+func (geminiAdapter) parseResponse(body []byte) (completion, error) {
+	var geminiResp GeminiResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		return completion{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(geminiResp.Candidates) == 0 {
+		return completion{}, fmt.Errorf("no candidates in response")
+	}
+
+	return geminiCompletion(geminiResp), nil
+}
+
+// streamURL switches generateContent to streamGenerateContent with SSE framing
+func (geminiAdapter) streamURL(apiURL string) string {
+	streamURL := strings.Replace(apiURL, ":generateContent", ":streamGenerateContent", 1)
+	separator := "?"
+	if strings.Contains(streamURL, "?") {
+		separator = "&"
+	}
+	return streamURL + separator + "alt=sse"
+}
+
+// parseStreamChunk parses one streamed GenerateContentResponse. Chunks
+// without candidates carry only usage metadata.
+func (geminiAdapter) parseStreamChunk(data []byte) (completion, error) {
+	var geminiResp GeminiResponse
+	if err := json.Unmarshal(data, &geminiResp); err != nil {
+		return completion{}, fmt.Errorf("failed to parse stream chunk: %w", err)
+	}
+	return geminiCompletion(geminiResp), nil
+}
+
+// geminiCompletion joins the text parts of the first candidate, if any
+func geminiCompletion(geminiResp GeminiResponse) completion {
+	result := completion{
+		Usage: TokenUsage{
+			InputTokens:  geminiResp.UsageMetadata.PromptTokenCount,
+			OutputTokens: geminiResp.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:  geminiResp.UsageMetadata.TotalTokenCount,
+		},
+	}
+	if len(geminiResp.Candidates) == 0 {
+		return result
+	}
+
+	candidate := geminiResp.Candidates[0]
+	var content strings.Builder
+	for _, part := range candidate.Content.Parts {
+		content.WriteString(part.Text)
+	}
+
+	result.Content = content.String()
+	result.FinishReason = candidate.FinishReason
+	return result
+}
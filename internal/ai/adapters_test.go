@@ -0,0 +1,130 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// conversation has a system prompt, an earlier exchange and a new question
+var conversation = []Message{
+	{Role: "system", Content: "You write Go."},
+	{Role: "user", Content: "Explain errors."},
+	{Role: "assistant", Content: "Errors are values."},
+	{Role: "user", Content: "And wrapping?"},
+}
+
+func TestBuildRequestPerProvider(t *testing.T) {
+	tests := []struct {
+		provider string
+		want     string // Top-level field holding the conversation
+		absent   string
+	}{
+		{"gemini", "contents", "messages"},
+		{"cerebras", "messages", "contents"},
+		{"groq", "messages", "contents"},
+		{"nvidia", "messages", "contents"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			body, err := adapterFor(tt.provider).buildRequest("model-x", APIConfig{MaxTokens: 64}, conversation, false)
+			if err != nil {
+				t.Fatalf("buildRequest: %v", err)
+			}
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(body, &fields); err != nil {
+				t.Fatalf("request body %s: %v", body, err)
+			}
+			if _, ok := fields[tt.want]; !ok {
+				t.Errorf("body %s has no %q", body, tt.want)
+			}
+			if _, ok := fields[tt.absent]; ok {
+				t.Errorf("body %s has %q", body, tt.absent)
+			}
+		})
+	}
+}
+
+func TestGeminiBuildRequest(t *testing.T) {
+	body, err := geminiAdapter{}.buildRequest("gemini-2.5-pro", APIConfig{MaxTokens: 64, Temperature: 0.2, TopP: 0.9}, conversation, false)
+	if err != nil {
+		t.Fatalf("buildRequest: %v", err)
+	}
+
+	var request GeminiRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatalf("request body %s: %v", body, err)
+	}
+	wantContents := []geminiContent{
+		{Role: "user", Parts: []geminiPart{{Text: "Explain errors."}}},
+		{Role: "model", Parts: []geminiPart{{Text: "Errors are values."}}},
+		{Role: "user", Parts: []geminiPart{{Text: "And wrapping?"}}},
+	}
+	if !reflect.DeepEqual(request.Contents, wantContents) {
+		t.Errorf("contents = %+v, want %+v", request.Contents, wantContents)
+	}
+	if request.SystemInstruction == nil || request.SystemInstruction.Parts[0].Text != "You write Go." {
+		t.Errorf("systemInstruction = %+v, want the system prompt", request.SystemInstruction)
+	}
+	if request.GenerationConfig != (geminiGenerationConfig{MaxOutputTokens: 64, Temperature: 0.2, TopP: 0.9}) {
+		t.Errorf("generationConfig = %+v", request.GenerationConfig)
+	}
+}
+
+func TestParseResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		body     string
+		want     completion
+		wantErr  bool
+	}{
+		{"openai", "cerebras",
+			`{"choices":[{"message":{"role":"assistant","content":"Wrap with %w."},"finish_reason":"stop"}],"usage":{"prompt_tokens":4,"completion_tokens":3,"total_tokens":7}}`,
+			completion{Content: "Wrap with %w.", FinishReason: "stop", Usage: TokenUsage{InputTokens: 4, OutputTokens: 3, TotalTokens: 7}}, false},
+		{"gemini parts joined", "gemini",
+			`{"candidates":[{"content":{"role":"model","parts":[{"text":"Wrap "},{"text":"with %w."}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":3,"totalTokenCount":7}}`,
+			completion{Content: "Wrap with %w.", FinishReason: "STOP", Usage: TokenUsage{InputTokens: 4, OutputTokens: 3, TotalTokens: 7}}, false},
+		{"openai without choices", "cerebras", `{"choices":[]}`, completion{}, true},
+		{"gemini without candidates", "gemini", `{"candidates":[]}`, completion{}, true},
+		{"not json", "gemini", `<html>`, completion{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adapterFor(tt.provider).parseResponse([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseResponse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWithGemini(t *testing.T) {
+	var gotBody, gotKey string
+	config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBody, gotKey = string(body), r.URL.Query().Get("key")
+		io.WriteString(w, `{"candidates":[{"content":{"parts":[{"text":"Wrap with %w."}]},"finishReason":"STOP"}]}`)
+	}))
+	config.Gemini, config.Groq = config.Groq, APIConfig{}
+
+	response, err := NewAIClientWithConfig(config).GenerateDetailedWithProvider(context.Background(), "gemini", testMessages)
+	if err != nil {
+		t.Fatalf("GenerateDetailedWithProvider: %v", err)
+	}
+	if response.Content != "Wrap with %w." || response.Provider != "gemini" {
+		t.Errorf("response = %+v", response)
+	}
+	if !strings.Contains(gotBody, `"contents"`) || gotKey != "test-key" {
+		t.Errorf("request body %s with key %q, want Gemini contents and the API key", gotBody, gotKey)
+	}
+}
@@ -0,0 +1,147 @@
+package ai
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// AuditTimeout bounds how long recording one interaction may take
+const AuditTimeout = 10 * time.Second
+
+// Interaction is a redacted record of one request to an AI provider and its response
+type Interaction struct {
+	Provider  string
+	Model     string
+	Messages  []Message
+	Response  string
+	Error     string
+	Usage     TokenUsage
+	CreatedAt time.Time
+}
+
+// AuditSink stores AI interactions for compliance auditing
+type AuditSink interface {
+	RecordInteraction(ctx context.Context, interaction Interaction) error
+}
+
+// Redaction placeholders
+const (
+	RedactedSecret = "[REDACTED]"
+	RedactedEmail  = "[REDACTED_EMAIL]"
+	RedactedPhone  = "[REDACTED_PHONE]"
+)
+
+// secretPatterns match credentials that must never reach the audit store
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(?:sk|gsk|xai|nvapi|csk)[-_][A-Za-z0-9_\-]{16,}`),
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{30,}`),
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._\-~+/]+=*`),
+}
+
+// assignmentPattern matches key=value and key: value credential assignments
+var assignmentPattern = regexp.MustCompile(`(?i)\b(api[_-]?key|access[_-]?token|token|secret|password|passwd)(\s*[:=]\s*)["']?[^\s"'&,;]+["']?`)
+
+// PII patterns
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?\(?\b\d{3}\)?[\s.-]\d{3}[\s.-]\d{4}\b`)
+)
+
+// Redactor removes API keys, credentials and PII from text
+type Redactor struct {
+	secrets []string // Literal values, such as configured API keys, always redacted
+}
+
+// NewRedactor creates a redactor that also removes the given literal secrets
+func NewRedactor(secrets ...string) *Redactor {
+	r := &Redactor{}
+	for _, secret := range secrets {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			r.secrets = append(r.secrets, secret)
+		}
+	}
+	return r
+}
+
+// Redact returns text with secrets and PII replaced by placeholders
+func (r *Redactor) Redact(text string) string {
+	for _, secret := range r.secrets {
+		text = strings.ReplaceAll(text, secret, RedactedSecret)
+	}
+	for _, pattern := range secretPatterns {
+		text = pattern.ReplaceAllString(text, RedactedSecret)
+	}
+	text = assignmentPattern.ReplaceAllString(text, "${1}${2}"+RedactedSecret)
+	text = emailPattern.ReplaceAllString(text, RedactedEmail)
+	return phonePattern.ReplaceAllString(text, RedactedPhone)
+}
+
+// RedactInteraction returns a copy of interaction with every text field redacted
+func (r *Redactor) RedactInteraction(interaction Interaction) Interaction {
+	messages := make([]Message, len(interaction.Messages))
+	for i, message := range interaction.Messages {
+		messages[i] = Message{Role: message.Role, Content: r.Redact(message.Content)}
+	}
+	interaction.Messages = messages
+	interaction.Response = r.Redact(interaction.Response)
+	interaction.Error = r.Redact(interaction.Error)
+	return interaction
+}
+
+// SetAuditSink records every provider interaction, redacted, to sink; nil disables auditing
+func (c *AIClient) SetAuditSink(sink AuditSink) {
+	c.auditSink = sink
+	if sink == nil {
+		c.redactor = nil
+		return
+	}
+
+	var keys []string
+	for _, apiConfig := range c.config.GetAvailableAPIs() {
+		keys = append(keys, apiConfig.GetAPIKey())
+	}
+	c.redactor = NewRedactor(keys...)
+}
+
+// audit redacts an interaction and hands it to the audit sink in the
+// background, so embedding the record never delays the request. Failures
+// are logged rather than returned so auditing never fails a request.
+func (c *AIClient) audit(ctx context.Context, provider, model string, messages []Message, response Response, err error) {
+	if c.auditSink == nil {
+		return
+	}
+
+	interaction := Interaction{
+		Provider:  provider,
+		Model:     model,
+		Messages:  messages,
+		Response:  response.Content,
+		Usage:     response.Usage,
+		CreatedAt: time.Now(),
+	}
+	if err != nil {
+		interaction.Error = err.Error()
+	}
+	interaction = c.redactor.RedactInteraction(interaction)
+
+	sink := c.auditSink
+	auditCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), AuditTimeout)
+	c.auditing.Add(1)
+	go func() {
+		defer c.auditing.Done()
+		defer cancel()
+
+		if err := sink.RecordInteraction(auditCtx, interaction); err != nil {
+			log.Printf("Warning: failed to record %s interaction for audit: %v", provider, err)
+		}
+	}()
+}
+
+// WaitForAudits blocks until every interaction handed to the audit sink has
+// been recorded or has failed, so none are lost on shutdown
+func (c *AIClient) WaitForAudits() {
+	c.auditing.Wait()
+}
@@ -0,0 +1,150 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink keeps every interaction it is given, optionally blocking
+// until released
+type recordingSink struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	release      chan struct{}
+}
+
+func (s *recordingSink) RecordInteraction(ctx context.Context, interaction Interaction) error {
+	if s.release != nil {
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interactions = append(s.interactions, interaction)
+	return nil
+}
+
+func (s *recordingSink) recorded() []Interaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Interaction(nil), s.interactions...)
+}
+
+func TestRedact(t *testing.T) {
+	redactor := NewRedactor("literal-configured-key")
+
+	tests := []struct {
+		name   string
+		text   string
+		want   string
+		leaked string
+	}{
+		{"configured key", "key is literal-configured-key", "key is " + RedactedSecret, "literal-configured-key"},
+		{"provider key", "use sk-abcdefghijklmnop1234 now", "use " + RedactedSecret + " now", "sk-abcdefghijklmnop1234"},
+		{"bearer token", "Authorization: Bearer abc.def.ghi", "Authorization: " + RedactedSecret, "abc.def.ghi"},
+		{"assignment", "password=hunter22", "password=" + RedactedSecret, "hunter22"},
+		{"email", "mail jane.doe@example.com", "mail " + RedactedEmail, "jane.doe@example.com"},
+		{"phone", "call 555-123-4567", "call " + RedactedPhone, "555-123-4567"},
+		{"plain text", "nothing to hide", "nothing to hide", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactor.Redact(tt.text)
+			if got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if tt.leaked != "" && strings.Contains(got, tt.leaked) {
+				t.Errorf("Redact(%q) leaked %q", tt.text, tt.leaked)
+			}
+		})
+	}
+}
+
+func TestAuditRecordsEveryOutcome(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		stream    bool
+		closed    bool // Point the provider at a closed server
+		wantError bool
+	}{
+		{"success", func(w http.ResponseWriter, r *http.Request) { writeCompletion(w, "reply to jane@example.com") }, false, false, false},
+		{"http error", func(w http.ResponseWriter, r *http.Request) { http.Error(w, "bad", http.StatusBadRequest) }, false, false, true},
+		{"transport error", nil, false, true, true},
+		{"stream success", func(w http.ResponseWriter, r *http.Request) { writeStream(w, "streamed") }, true, false, false},
+		{"stream http error", func(w http.ResponseWriter, r *http.Request) { http.Error(w, "bad", http.StatusBadRequest) }, true, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig(t, tt.handler)
+			config.Defaults.RetryCount = 0
+			if tt.closed {
+				config.Groq.APIURL = "http://127.0.0.1:1"
+			}
+			client := NewAIClientWithConfig(config)
+			sink := &recordingSink{}
+			client.SetAuditSink(sink)
+
+			messages := []Message{{Role: "user", Content: "my key is test-key and password=hunter22"}}
+			var err error
+			if tt.stream {
+				_, err = client.GenerateStreamWithProvider(context.Background(), "groq", "", messages, nil)
+			} else {
+				_, err = client.GenerateDetailedWithProvider(context.Background(), "groq", messages)
+			}
+			if (err != nil) != tt.wantError {
+				t.Fatalf("err = %v, wantError %v", err, tt.wantError)
+			}
+			client.WaitForAudits()
+
+			recorded := sink.recorded()
+			if len(recorded) != 1 {
+				t.Fatalf("recorded %d interactions, want 1", len(recorded))
+			}
+			interaction := recorded[0]
+			if (interaction.Error != "") != tt.wantError {
+				t.Errorf("interaction error = %q, wantError %v", interaction.Error, tt.wantError)
+			}
+			for _, secret := range []string{"test-key", "hunter22", "jane@example.com"} {
+				if strings.Contains(interaction.Messages[0].Content, secret) || strings.Contains(interaction.Response, secret) {
+					t.Errorf("audit record leaked %q: %+v", secret, interaction)
+				}
+			}
+		})
+	}
+}
+
+func TestAuditDoesNotDelayRequest(t *testing.T) {
+	config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "fast")
+	}))
+	client := NewAIClientWithConfig(config)
+	sink := &recordingSink{release: make(chan struct{})}
+	client.SetAuditSink(sink)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.GenerateDetailedWithProvider(context.Background(), "groq", testMessages)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request waited for the audit sink")
+	}
+
+	close(sink.release)
+	client.WaitForAudits()
+	if len(sink.recorded()) != 1 {
+		t.Errorf("audit record lost")
+	}
+}
@@ -3,12 +3,16 @@ package ai
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/tokenizer"
 )
 
 // Message represents a chat message
@@ -52,6 +56,18 @@ type ChatResponse struct {
 type AIClient struct {
 	config     *AIHelperConfig
 	httpClient *http.Client
+
+	// Compliance boilerplate added to the system message of every request
+	systemPrefix string
+	systemSuffix string
+
+	// Redacted interactions are recorded here when set
+	auditSink AuditSink
+	redactor  *Redactor
+	auditing  sync.WaitGroup // Interactions still being recorded
+
+	// Shares responses between identical requests when set
+	responseCache *ResponseCache
 }
 
 // NewAIClient creates a new AI client
@@ -61,48 +77,164 @@ func NewAIClient(configPath string) (*AIClient, error) {
 		return nil, fmt.Errorf("failed to load AI config: %w", err)
 	}
 
+	return NewAIClientWithConfig(config), nil
+}
+
+// NewAIClientWithConfig creates an AI client from an already loaded configuration
+func NewAIClientWithConfig(config *AIHelperConfig) *AIClient {
 	client := &AIClient{
 		config: config,
 		httpClient: &http.Client{
 			Timeout: 120 * time.Second,
 		},
 	}
+	if config.Defaults.DedupRequests || config.Defaults.ResponseCacheSize > 0 {
+		client.SetResponseCache(NewResponseCache(config.Defaults.ResponseCacheSize, config.Defaults.GetResponseCacheTTL()))
+	}
+	return client
+}
 
-	return client, nil
+// SetResponseCache shares responses between identical requests through
+// cache; nil sends every request upstream
+func (c *AIClient) SetResponseCache(cache *ResponseCache) {
+	c.responseCache = cache
+}
+
+// SetCompliance sets boilerplate placed at the start and end of the system
+// message of every request; requests without one get a system message
+func (c *AIClient) SetCompliance(prefix, suffix string) {
+	c.systemPrefix = strings.TrimSpace(prefix)
+	c.systemSuffix = strings.TrimSpace(suffix)
+}
+
+// withCompliance returns messages with the compliance boilerplate in the
+// system message. Boilerplate already present in a message, as when the
+// caller assembled the prompt with it, is not added again.
+func (c *AIClient) withCompliance(messages []Message) []Message {
+	prefix, suffix := c.systemPrefix, c.systemSuffix
+	for _, message := range messages {
+		if prefix != "" && strings.Contains(message.Content, prefix) {
+			prefix = ""
+		}
+		if suffix != "" && strings.Contains(message.Content, suffix) {
+			suffix = ""
+		}
+	}
+	if prefix == "" && suffix == "" {
+		return messages
+	}
+
+	result := make([]Message, 0, len(messages)+1)
+	system := Message{Role: "system"}
+	rest := messages
+	if len(messages) > 0 && messages[0].Role == "system" {
+		system, rest = messages[0], messages[1:]
+	}
+
+	parts := make([]string, 0, 3)
+	for _, part := range []string{prefix, system.Content, suffix} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	system.Content = strings.Join(parts, "\n\n")
+	return append(append(result, system), rest...)
 }
 
 // GenerateResponse generates a response using the best available AI API
 func (c *AIClient) GenerateResponse(ctx context.Context, messages []Message, taskComplexity string) (string, error) {
+	response, err := c.GenerateDetailed(ctx, messages, taskComplexity)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response.Content), nil
+}
+
+// GenerateDetailed generates a response using the best available AI API and
+// returns it with provider metadata such as the finish reason
+func (c *AIClient) GenerateDetailed(ctx context.Context, messages []Message, taskComplexity string) (Response, error) {
+	if c.config.Defaults.Routing == RoutingModeCost {
+		return c.generateByCost(ctx, messages, taskComplexity)
+	}
+
 	provider, apiConfig, err := c.config.GetPreferredAPI(taskComplexity)
 	if err != nil {
-		return "", fmt.Errorf("no AI API available: %w", err)
+		return Response{}, fmt.Errorf("no AI API available: %w", err)
 	}
 
 	return c.generateWithProvider(ctx, provider, apiConfig, messages)
 }
 
+// generateByCost tries providers from cheapest to most expensive until one succeeds
+func (c *AIClient) generateByCost(ctx context.Context, messages []Message, taskComplexity string) (Response, error) {
+	ladder := c.config.CostLadder(requiredCapability(taskComplexity))
+	if len(ladder) == 0 {
+		return Response{}, fmt.Errorf("no AI API available: no AI APIs available")
+	}
+
+	available := c.config.GetAvailableAPIs()
+	var lastErr error
+	for _, provider := range ladder {
+		response, err := c.generateWithProvider(ctx, provider, available[provider], messages)
+		if err == nil {
+			return response, nil
+		}
+		if ctx.Err() != nil {
+			return Response{}, err
+		}
+		lastErr = err
+	}
+	return Response{}, fmt.Errorf("every provider on the cost ladder failed: %w", lastErr)
+}
+
 // GenerateWithProvider generates a response using a specific provider
 func (c *AIClient) GenerateWithProvider(ctx context.Context, provider string, messages []Message) (string, error) {
+	response, err := c.GenerateDetailedWithProvider(ctx, provider, messages)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response.Content), nil
+}
+
+// GenerateDetailedWithProvider generates a response using a specific provider
+// and returns it with provider metadata and usage
+func (c *AIClient) GenerateDetailedWithProvider(ctx context.Context, provider string, messages []Message) (Response, error) {
 	available := c.config.GetAvailableAPIs()
 	apiConfig, exists := available[provider]
 	if !exists {
-		return "", fmt.Errorf("provider %s not available", provider)
+		return Response{}, fmt.Errorf("provider %s not available", provider)
 	}
 
 	return c.generateWithProvider(ctx, provider, apiConfig, messages)
 }
 
+// GenerateDetailedWithModel generates a response using one specific model
+// of a provider, without trying its other models
+func (c *AIClient) GenerateDetailedWithModel(ctx context.Context, provider, model string, messages []Message) (Response, error) {
+	apiConfig, exists := c.config.GetAvailableAPIs()[provider]
+	if !exists {
+		return Response{}, fmt.Errorf("provider %s not available", provider)
+	}
+	if !slices.Contains(apiConfig.Models, model) {
+		return Response{}, fmt.Errorf("model %s not configured for provider %s", model, provider)
+	}
+
+	apiConfig.Models = []string{model}
+	return c.generateWithProvider(ctx, provider, apiConfig, messages)
+}
+
 // generateWithProvider handles the actual API call
-func (c *AIClient) generateWithProvider(ctx context.Context, provider string, apiConfig APIConfig, messages []Message) (string, error) {
+func (c *AIClient) generateWithProvider(ctx context.Context, provider string, apiConfig APIConfig, messages []Message) (Response, error) {
 	// Retry logic
 	var lastErr error
+	var delay time.Duration
 	for attempt := 0; attempt <= c.config.Defaults.RetryCount; attempt++ {
 		if attempt > 0 {
 			select {
-			case <-time.After(c.config.Defaults.GetRetryDelay()):
+			case <-time.After(delay):
 				// Continue to retry
 			case <-ctx.Done():
-				return "", ctx.Err()
+				return Response{}, ctx.Err()
 			}
 		}
 
@@ -111,6 +243,11 @@ func (c *AIClient) generateWithProvider(ctx context.Context, provider string, ap
 			return result, nil
 		}
 
+		var retry bool
+		if delay, retry = c.retryDelay(ctx, err); !retry {
+			return Response{}, err
+		}
+
 		lastErr = err
 
 		// Try next model in the list
@@ -119,43 +256,161 @@ func (c *AIClient) generateWithProvider(ctx context.Context, provider string, ap
 		}
 	}
 
-	return "", fmt.Errorf("all attempts failed for provider %s: %w", provider, lastErr)
+	return Response{}, fmt.Errorf("all attempts failed for provider %s: %w", provider, lastErr)
+}
+
+// retryDelay returns how long to wait before retrying a call that failed
+// with err, or false when retrying cannot help. Failures without an HTTP
+// status, such as timeouts, are retried after the retry delay. The wait is
+// capped at the maximum retry delay, and a retry that could not start before
+// ctx's deadline is not attempted.
+func (c *AIClient) retryDelay(ctx context.Context, err error) (time.Duration, bool) {
+	delay, retry := c.classifyRetry(err)
+	if !retry {
+		return 0, false
+	}
+
+	delay = min(delay, c.config.Defaults.GetMaxRetryDelay())
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+		return 0, false
+	}
+	return delay, true
+}
+
+// classifyRetry returns the wait the failure asks for before a retry, or
+// false when it must not be retried
+func (c *AIClient) classifyRetry(err error) (time.Duration, bool) {
+	if errors.Is(err, ErrRequestTooLarge) {
+		return 0, false // Retrying sends the same oversized prompt
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return c.config.Defaults.GetRetryDelay(), true
+	}
+
+	switch c.config.Defaults.ClassifyStatus(apiErr.StatusCode) {
+	case RetryNow:
+		return c.config.Defaults.GetRetryDelay(), true
+	case RetryAfterDelay:
+		if apiErr.RetryAfter > 0 {
+			return apiErr.RetryAfter, true
+		}
+		return c.config.Defaults.GetRateLimitDelay(), true
+	default:
+		return 0, false
+	}
 }
 
-// callAPI makes the actual HTTP request to the AI API
-func (c *AIClient) callAPI(ctx context.Context, provider string, apiConfig APIConfig, messages []Message) (string, error) {
+// callAPI sends a request to the AI API, or answers it from the response
+// cache when an identical request was just made or is in flight
+func (c *AIClient) callAPI(ctx context.Context, provider string, apiConfig APIConfig, messages []Message) (Response, error) {
 	// Select the first available model for this attempt
 	if len(apiConfig.Models) == 0 {
-		return "", fmt.Errorf("no models configured for provider %s", provider)
+		return Response{}, fmt.Errorf("no models configured for provider %s", provider)
 	}
 
 	model := apiConfig.Models[0]
+	messages = c.withCompliance(messages)
+	if err := checkRequestSize(provider, model, apiConfig, messages); err != nil {
+		return Response{}, err
+	}
+
+	if c.responseCache == nil {
+		return c.sendRequest(ctx, provider, model, apiConfig, messages)
+	}
+	return c.responseCache.Do(ctx, responseKey(provider, model, apiConfig, messages), func(ctx context.Context) (Response, error) {
+		return c.sendRequest(ctx, provider, model, apiConfig, messages)
+	})
+}
+
+// sendRequest makes the actual HTTP request to the AI API. Every outcome,
+// transport and HTTP failures included, is audited.
+func (c *AIClient) sendRequest(ctx context.Context, provider, model string, apiConfig APIConfig, messages []Message) (response Response, err error) {
+	startTime := time.Now()
+	defer func() {
+		c.audit(ctx, provider, model, messages, response, err)
+	}()
+
+	// Shape the request for the provider's API schema
+	adapter := adapterFor(provider)
+	requestBody, err := adapter.buildRequest(model, apiConfig, messages, false)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := newAPIRequest(ctx, provider, apiConfig, resolveAPIURL(apiConfig, model), requestBody)
+	if err != nil {
+		return Response{}, err
+	}
+
+	// Set timeout
+	client := &http.Client{
+		Timeout: apiConfig.GetTimeout(),
+	}
+
+	// Make request
+	resp, err := client.Do(req)
+	if err != nil {
+		return Response{}, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Read response
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Response{}, newAPIError(resp, body)
+	}
 
-	// Create request
-	request := ChatRequest{
-		Model:       model,
-		Messages:    messages,
-		MaxTokens:   apiConfig.MaxTokens,
-		Temperature: apiConfig.Temperature,
-		TopP:        apiConfig.TopP,
-		Stream:      false,
+	// Some providers report errors in the body of a 200 response
+	if err := checkErrorBody(body); err != nil {
+		return Response{}, err
 	}
 
-	// Marshal request
-	requestBody, err := json.Marshal(request)
+	// Parse response
+	result, err := adapter.parseResponse(body)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return Response{}, err
+	}
+
+	return buildResponse(provider, model, apiConfig, messages, result, startTime)
+}
+
+// checkRequestSize fails with ErrRequestTooLarge when the estimated prompt
+// plus the reserved output tokens cannot fit the model's context window, so
+// an oversized request is rejected without a round-trip. Providers with an
+// unknown context window are not checked.
+func checkRequestSize(provider, model string, apiConfig APIConfig, messages []Message) error {
+	if apiConfig.ContextWindow <= 0 {
+		return nil
+	}
+
+	promptTokens := 0
+	for _, message := range messages {
+		promptTokens += tokenizer.Estimate(message.Content)
 	}
 
-	// Create HTTP request
-	apiURL := apiConfig.APIURL
-	if strings.Contains(apiURL, "{model}") {
-		apiURL = strings.ReplaceAll(apiURL, "{model}", model)
+	if promptTokens+apiConfig.MaxTokens > apiConfig.ContextWindow {
+		return fmt.Errorf("%w: prompt is ~%d tokens and %d are reserved for output, but %s/%s has a %d-token context window; shorten the prompt or RAG context, or lower max_tokens",
+			ErrRequestTooLarge, promptTokens, apiConfig.MaxTokens, provider, model, apiConfig.ContextWindow)
 	}
+	return nil
+}
 
+// resolveAPIURL substitutes the model into the configured API URL
+func resolveAPIURL(apiConfig APIConfig, model string) string {
+	return strings.ReplaceAll(apiConfig.APIURL, "{model}", model)
+}
+
+// newAPIRequest creates an authenticated POST request for a provider
+func newAPIRequest(ctx context.Context, provider string, apiConfig APIConfig, apiURL string, requestBody []byte) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(requestBody))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
@@ -163,7 +418,7 @@ func (c *AIClient) callAPI(ctx context.Context, provider string, apiConfig APICo
 
 	apiKey := apiConfig.GetAPIKey()
 	if apiKey == "" {
-		return "", fmt.Errorf("API key not found for provider %s", provider)
+		return nil, fmt.Errorf("API key not found for provider %s", provider)
 	}
 
 	// Different providers use different auth headers
@@ -174,54 +429,57 @@ func (c *AIClient) callAPI(ctx context.Context, provider string, apiConfig APICo
 		req.Header.Set("Authorization", "Bearer "+apiKey)
 	case "gemini":
 		// Gemini uses API key as query parameter
-		if !strings.Contains(apiURL, "key=") {
-			separator := "?"
-			if strings.Contains(apiURL, "?") {
-				separator = "&"
-			}
-			apiURL += separator + "key=" + apiKey
-			req.URL.RawQuery = "key=" + apiKey
+		query := req.URL.Query()
+		if query.Get("key") == "" {
+			query.Set("key", apiKey)
+			req.URL.RawQuery = query.Encode()
 		}
 	}
 
-	// Set timeout
-	client := &http.Client{
-		Timeout: apiConfig.GetTimeout(),
-	}
-
-	// Make request
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("API request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
+	return req, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+// buildResponse converts a parsed provider completion into a Response. The
+// content is kept verbatim: whitespace at the edges of a truncated output
+// matters when a continuation is appended to it.
+func buildResponse(provider, model string, apiConfig APIConfig, messages []Message, result completion, startTime time.Time) (Response, error) {
+	if err := checkFinishReason(result.FinishReason); err != nil {
+		return Response{}, err
 	}
 
-	// Parse response
-	var chatResp ChatResponse
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+	content := result.Content
+	if strings.TrimSpace(content) == "" {
+		return Response{}, fmt.Errorf("empty response content")
 	}
 
-	if len(chatResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
-	}
+	finishReason := result.FinishReason
+	finishedAt := time.Now()
 
-	content := strings.TrimSpace(chatResp.Choices[0].Message.Content)
-	if content == "" {
-		return "", fmt.Errorf("empty response content")
+	// Prefer the provider's token count; estimate when it does not report usage
+	contextUsed := result.Usage.TotalTokens
+	if contextUsed == 0 {
+		for _, message := range messages {
+			contextUsed += tokenizer.Estimate(message.Content)
+		}
+		contextUsed += tokenizer.Estimate(content)
 	}
 
-	return content, nil
+	usage := result.Usage
+	usage.CostUSD = apiConfig.CostUSD(usage)
+
+	return Response{
+		Content:      content,
+		Model:        model,
+		Provider:     provider,
+		CreatedAt:    startTime,
+		FinishedAt:   finishedAt,
+		Latency:      finishedAt.Sub(startTime),
+		FinishReason: finishReason,
+		Truncated:    isLengthFinish(finishReason),
+		ContextUsed:  contextUsed,
+		ContextLimit: apiConfig.ContextWindow,
+		Usage:        usage,
+	}, nil
 }
 
 // GetAvailableProviders returns list of available AI providers
@@ -0,0 +1,277 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/tokenizer"
+)
+
+func TestRetryDelay(t *testing.T) {
+	defaults := DefaultsConfig{RetryDelay: 2, RateLimitDelay: 5, MaxRetryDelay: 30}
+
+	tests := []struct {
+		name      string
+		err       error
+		deadline  time.Duration // Zero for no deadline
+		want      time.Duration
+		wantRetry bool
+	}{
+		{"transport error", errors.New("connection reset"), 0, 2 * time.Second, true},
+		{"server error", &APIError{StatusCode: http.StatusBadGateway}, 0, 2 * time.Second, true},
+		{"rate limit without header", &APIError{StatusCode: http.StatusTooManyRequests}, 0, 5 * time.Second, true},
+		{"rate limit with header", &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 12 * time.Second}, 0, 12 * time.Second, true},
+		{"retry-after capped", &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}, 0, 30 * time.Second, true},
+		{"client error", &APIError{StatusCode: http.StatusUnauthorized}, 0, 0, false},
+		{"request too large", ErrRequestTooLarge, 0, 0, false},
+		{"wait outlasts deadline", &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 20 * time.Second}, 10 * time.Second, 0, false},
+		{"wait within deadline", &APIError{StatusCode: http.StatusBadGateway}, time.Minute, 2 * time.Second, true},
+	}
+
+	client := NewAIClientWithConfig(&AIHelperConfig{Defaults: defaults})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.deadline > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.deadline)
+				defer cancel()
+			}
+
+			got, retry := client.retryDelay(ctx, tt.err)
+			if got != tt.want || retry != tt.wantRetry {
+				t.Errorf("retryDelay = %v, %v; want %v, %v", got, retry, tt.want, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestMaxRetryDelayDefault(t *testing.T) {
+	if got := (&DefaultsConfig{}).GetMaxRetryDelay(); got != DefaultMaxRetryDelay {
+		t.Errorf("GetMaxRetryDelay = %v, want %v", got, DefaultMaxRetryDelay)
+	}
+}
+
+func TestGenerateGivesUpWhenRetryAfterOutlastsDeadline(t *testing.T) {
+	var calls atomic.Int32
+	config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	client := NewAIClientWithConfig(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.GenerateDetailedWithProvider(ctx, "groq", testMessages)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("err = %v, want the rate limit error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second || calls.Load() != 1 {
+		t.Errorf("took %v over %d requests; want one request and no wait", elapsed, calls.Load())
+	}
+}
+
+func TestGenerateReportsFinishReason(t *testing.T) {
+	tests := []struct {
+		reason        string
+		wantTruncated bool
+	}{
+		{"stop", false},
+		{"length", true},
+		{"max_tokens", true},
+		{"MAX_OUTPUT_TOKENS", true},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":"partial"},"finish_reason":%q}],"usage":{"prompt_tokens":5,"completion_tokens":3,"total_tokens":8}}`, tt.reason)
+			}))
+
+			response, err := NewAIClientWithConfig(config).GenerateDetailedWithProvider(context.Background(), "groq", testMessages)
+			if err != nil {
+				t.Fatalf("GenerateDetailedWithProvider: %v", err)
+			}
+			if response.FinishReason != tt.reason || response.Truncated != tt.wantTruncated {
+				t.Errorf("finish reason %q, truncated %v; want %q, %v", response.FinishReason, response.Truncated, tt.reason, tt.wantTruncated)
+			}
+			if response.Provider != "groq" || response.Model != "test-model" || response.Usage.TotalTokens != 8 {
+				t.Errorf("response = %+v", response)
+			}
+		})
+	}
+}
+
+func TestGenerateReportsContextUsage(t *testing.T) {
+	prompt := strings.Repeat("describe the error handling rules ", 20)
+	answer := strings.Repeat("wrap errors with context ", 30)
+	estimate := tokenizer.Estimate(prompt) + tokenizer.Estimate(answer)
+
+	tests := []struct {
+		name     string
+		usage    string
+		window   int
+		wantUsed int
+		wantNear bool
+	}{
+		{"provider usage", `{"prompt_tokens":150,"completion_tokens":160,"total_tokens":310}`, 4096, 310, false},
+		{"estimated without usage", `{}`, 4096, estimate, false},
+		{"near the limit", `{"total_tokens":310}`, 320, 310, true},
+		{"unknown window", `{"total_tokens":310}`, 0, 310, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%q},"finish_reason":"stop"}],"usage":%s}`, answer, tt.usage)
+			}))
+			config.Groq.ContextWindow = tt.window
+			config.Groq.MaxTokens = 1
+
+			response, err := NewAIClientWithConfig(config).GenerateDetailedWithProvider(context.Background(), "groq", []Message{{Role: "user", Content: prompt}})
+			if err != nil {
+				t.Fatalf("GenerateDetailedWithProvider: %v", err)
+			}
+			if response.ContextUsed != tt.wantUsed || response.ContextLimit != tt.window {
+				t.Errorf("context %d of %d, want %d of %d", response.ContextUsed, response.ContextLimit, tt.wantUsed, tt.window)
+			}
+			if response.NearContextLimit() != tt.wantNear {
+				t.Errorf("NearContextLimit = %v, want %v", response.NearContextLimit(), tt.wantNear)
+			}
+		})
+	}
+}
+
+func TestWithCompliance(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		suffix   string
+		messages []Message
+		want     []Message
+	}{
+		{
+			name:     "no boilerplate",
+			messages: testMessages,
+			want:     testMessages,
+		},
+		{
+			name:     "adds a system message",
+			prefix:   "Never output secrets.",
+			suffix:   "Cite sources.",
+			messages: testMessages,
+			want: []Message{
+				{Role: "system", Content: "Never output secrets.\n\nCite sources."},
+				{Role: "user", Content: "hello"},
+			},
+		},
+		{
+			name:     "wraps the existing system message",
+			prefix:   "Never output secrets.",
+			messages: []Message{{Role: "system", Content: "You are a reviewer."}, {Role: "user", Content: "hello"}},
+			want: []Message{
+				{Role: "system", Content: "Never output secrets.\n\nYou are a reviewer."},
+				{Role: "user", Content: "hello"},
+			},
+		},
+		{
+			name:     "boilerplate already in the prompt is not repeated",
+			prefix:   "Never output secrets.",
+			suffix:   "Cite sources.",
+			messages: []Message{{Role: "user", Content: "Never output secrets.\n\nhello"}},
+			want: []Message{
+				{Role: "system", Content: "Cite sources."},
+				{Role: "user", Content: "Never output secrets.\n\nhello"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewAIClientWithConfig(&AIHelperConfig{})
+			client.SetCompliance(tt.prefix, tt.suffix)
+			if got := client.withCompliance(tt.messages); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withCompliance() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSendsCompliance(t *testing.T) {
+	var system atomic.Value
+	config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Messages []Message `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err == nil && len(request.Messages) > 0 {
+			system.Store(request.Messages[0])
+		}
+		writeCompletion(w, "ok")
+	}))
+
+	client := NewAIClientWithConfig(config)
+	client.SetCompliance("Never output secrets.", "")
+	if _, err := client.GenerateDetailedWithProvider(context.Background(), "groq", testMessages); err != nil {
+		t.Fatalf("GenerateDetailedWithProvider: %v", err)
+	}
+
+	want := Message{Role: "system", Content: "Never output secrets."}
+	if got, _ := system.Load().(Message); got != want {
+		t.Errorf("first message sent = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckRequestSize(t *testing.T) {
+	short := []Message{{Role: "user", Content: "hello"}}
+	long := []Message{{Role: "user", Content: strings.Repeat("too long ", 200)}}
+
+	tests := []struct {
+		name     string
+		window   int
+		messages []Message
+		wantErr  bool
+	}{
+		{"fits", 4096, long, false},
+		{"unknown window", 0, long, false},
+		{"prompt too large", 300, long, true},
+		{"output reservation too large", 200, short, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiConfig := APIConfig{ContextWindow: tt.window, MaxTokens: 256}
+			err := checkRequestSize("groq", "test-model", apiConfig, tt.messages)
+			if tt.wantErr != errors.Is(err, ErrRequestTooLarge) {
+				t.Errorf("checkRequestSize() error = %v, want ErrRequestTooLarge %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateRejectsOversizedRequest(t *testing.T) {
+	var calls atomic.Int32
+	config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		writeCompletion(w, "ok")
+	}))
+	config.Groq.ContextWindow = 300
+
+	messages := []Message{{Role: "user", Content: strings.Repeat("too long ", 200)}}
+	_, err := NewAIClientWithConfig(config).GenerateDetailedWithProvider(context.Background(), "groq", messages)
+	if !errors.Is(err, ErrRequestTooLarge) || calls.Load() != 0 {
+		t.Errorf("err = %v after %d requests; want ErrRequestTooLarge without a request", err, calls.Load())
+	}
+}
@@ -3,6 +3,7 @@ package ai
 import (
 	"fmt"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -13,12 +14,18 @@ type APIConfig struct {
 	APIKeyVariable string   `toml:"api_key_variable" yaml:"api_key_variable"`
 	Models         []string `toml:"models" yaml:"models"`
 	MaxTokens      int      `toml:"max_tokens" yaml:"max_tokens"`
+	ContextWindow  int      `toml:"context_window,omitempty" yaml:"context_window,omitempty"` // Tokens; 0 when unknown
 	Temperature    float64  `toml:"temperature" yaml:"temperature"`
 	TopP           float64  `toml:"top_p" yaml:"top_p"`
 	Timeout        int      `toml:"timeout" yaml:"timeout"`
 	APIURL         string   `toml:"api_url" yaml:"api_url"`
 	OCRURL         string   `toml:"ocr_api_url,omitempty" yaml:"ocr_api_url,omitempty"`
 	Description    string   `toml:"description" yaml:"description"`
+
+	// Pricing and capabilities used by cost routing
+	PricePerKInputTokens  float64  `toml:"price_per_k_input_tokens,omitempty" yaml:"price_per_k_input_tokens,omitempty"`   // USD
+	PricePerKOutputTokens float64  `toml:"price_per_k_output_tokens,omitempty" yaml:"price_per_k_output_tokens,omitempty"` // USD
+	Capabilities          []string `toml:"capabilities,omitempty" yaml:"capabilities,omitempty"`
 }
 
 // DefaultsConfig represents default configuration
@@ -28,6 +35,40 @@ type DefaultsConfig struct {
 	LogRequests   bool   `toml:"log_requests" yaml:"log_requests"`
 	SaveResponses bool   `toml:"save_responses" yaml:"save_responses"`
 	ResponseDir   string `toml:"response_dir" yaml:"response_dir"`
+	Routing       string `toml:"routing" yaml:"routing"` // "complexity" (default) or "cost"
+
+	// Store every request and response, with keys and PII redacted, in the
+	// knowledge base so audits can search them
+	AuditInteractions bool   `toml:"audit_interactions" yaml:"audit_interactions"`
+	AuditCollection   string `toml:"audit_collection" yaml:"audit_collection"`
+
+	// Seconds between provider reachability checks; providers failing
+	// one are skipped during selection. Zero disables health checks.
+	HealthCheckInterval int `toml:"health_check_interval" yaml:"health_check_interval"`
+
+	// Identical requests made while one is in flight share its response
+	// when DedupRequests is set or responses are cached. Up to
+	// ResponseCacheSize responses are reused for ResponseCacheTTL seconds.
+	DedupRequests     bool `toml:"dedup_requests" yaml:"dedup_requests"`
+	ResponseCacheSize int  `toml:"response_cache_size" yaml:"response_cache_size"`
+	ResponseCacheTTL  int  `toml:"response_cache_ttl" yaml:"response_cache_ttl"`
+
+	// HTTP status codes retried after RetryDelay seconds, and rate limit
+	// codes retried after the provider's Retry-After or RateLimitDelay
+	// seconds. Other statuses fail at once; unset lists use the defaults.
+	RetryStatusCodes     []int `toml:"retry_status_codes" yaml:"retry_status_codes"`
+	RateLimitStatusCodes []int `toml:"rate_limit_status_codes" yaml:"rate_limit_status_codes"`
+	RateLimitDelay       int   `toml:"rate_limit_delay" yaml:"rate_limit_delay"`
+
+	// Longest wait in seconds before a retry, capping the provider's
+	// Retry-After; zero uses DefaultMaxRetryDelay
+	MaxRetryDelay int `toml:"max_retry_delay" yaml:"max_retry_delay"`
+}
+
+// HelpersConfig locates the AI helper scripts
+type HelpersConfig struct {
+	ScriptDir string            `toml:"script_dir" yaml:"script_dir"` // Directory holding the scripts
+	Scripts   map[string]string `toml:"scripts" yaml:"scripts"`       // Per-script path overrides; relative paths are under ScriptDir
 }
 
 // AIHelperConfig represents the complete AI helper configuration
@@ -39,6 +80,9 @@ type AIHelperConfig struct {
 	Grok      APIConfig      `toml:"grok" yaml:"grok"`
 	Groq      APIConfig      `toml:"groq" yaml:"groq"`
 	Defaults  DefaultsConfig `toml:"defaults" yaml:"defaults"`
+	Helpers   HelpersConfig  `toml:"helpers" yaml:"helpers"`
+
+	health atomic.Pointer[ProviderHealth] // Set once health checks start
 }
 
 // LoadAIHelperConfig loads AI helper configuration from TOML file
@@ -70,7 +114,16 @@ func validateConfig(config *AIHelperConfig) error {
 		"groq":       config.Groq,
 	}
 
+	switch config.Defaults.Routing {
+	case "", RoutingModeComplexity, RoutingModeCost:
+	default:
+		return fmt.Errorf("unknown routing mode %q (must be %s or %s)", config.Defaults.Routing, RoutingModeComplexity, RoutingModeCost)
+	}
+
 	for name, provider := range providers {
+		if provider.PricePerKInputTokens < 0 || provider.PricePerKOutputTokens < 0 {
+			return fmt.Errorf("negative pricing for %s", name)
+		}
 		if provider.APIKeyVariable == "" {
 			return fmt.Errorf("missing api_key_variable for %s", name)
 		}
@@ -105,6 +158,46 @@ func (d *DefaultsConfig) GetRetryDelay() time.Duration {
 	return time.Duration(d.RetryDelay) * time.Second
 }
 
+// GetRateLimitDelay returns the wait before retrying a rate-limited call
+// without a Retry-After header as duration
+func (d *DefaultsConfig) GetRateLimitDelay() time.Duration {
+	if d.RateLimitDelay <= 0 {
+		return DefaultRateLimitDelay
+	}
+	return time.Duration(d.RateLimitDelay) * time.Second
+}
+
+// GetMaxRetryDelay returns the cap on the wait before a retry as duration
+func (d *DefaultsConfig) GetMaxRetryDelay() time.Duration {
+	if d.MaxRetryDelay <= 0 {
+		return DefaultMaxRetryDelay
+	}
+	return time.Duration(d.MaxRetryDelay) * time.Second
+}
+
+// ClassifyStatus returns how a call that failed with statusCode is retried
+// under the configured status codes
+func (d *DefaultsConfig) ClassifyStatus(statusCode int) RetryDecision {
+	retryCodes, rateLimitCodes := d.RetryStatusCodes, d.RateLimitStatusCodes
+	if retryCodes == nil {
+		retryCodes = DefaultRetryStatusCodes
+	}
+	if rateLimitCodes == nil {
+		rateLimitCodes = DefaultRateLimitStatusCodes
+	}
+	return ClassifyStatus(statusCode, retryCodes, rateLimitCodes)
+}
+
+// GetHealthCheckInterval returns the provider health check interval as duration
+func (d *DefaultsConfig) GetHealthCheckInterval() time.Duration {
+	return time.Duration(d.HealthCheckInterval) * time.Second
+}
+
+// GetResponseCacheTTL returns how long a cached response is reused as duration
+func (d *DefaultsConfig) GetResponseCacheTTL() time.Duration {
+	return time.Duration(d.ResponseCacheTTL) * time.Second
+}
+
 // GetAvailableAPIs returns list of available AI APIs
 func (c *AIHelperConfig) GetAvailableAPIs() map[string]APIConfig {
 	apis := make(map[string]APIConfig)
@@ -131,9 +224,14 @@ func (c *AIHelperConfig) GetAvailableAPIs() map[string]APIConfig {
 	return apis
 }
 
-// GetPreferredAPI returns the preferred API based on task complexity
+// GetPreferredAPI returns the preferred API based on task complexity, or the
+// cheapest capable one when cost routing is configured
 func (c *AIHelperConfig) GetPreferredAPI(taskComplexity string) (string, APIConfig, error) {
-	available := c.GetAvailableAPIs()
+	if c.Defaults.Routing == RoutingModeCost {
+		return c.GetCheapestAPI(requiredCapability(taskComplexity))
+	}
+
+	available := c.GetHealthyAPIs()
 
 	if len(available) == 0 {
 		return "", APIConfig{}, fmt.Errorf("no AI APIs available")
@@ -0,0 +1,92 @@
+package ai
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// Routing modes for choosing an external provider
+const (
+	RoutingModeComplexity = "complexity" // Fixed provider order per task complexity (default)
+	RoutingModeCost       = "cost"       // Cheapest provider with the required capability first
+)
+
+// CapabilityReasoning marks providers able to take high-complexity tasks in cost mode
+const CapabilityReasoning = "reasoning"
+
+// textProviders are the chat providers eligible for routing; OCR is excluded
+var textProviders = []string{"cerebras", "nvidia", "gemini", "grok", "groq"}
+
+// requiredCapability returns the capability a provider needs to serve a task
+// of the given complexity in cost mode; empty means any provider qualifies
+func requiredCapability(taskComplexity string) string {
+	if taskComplexity == "high" {
+		return CapabilityReasoning
+	}
+	return ""
+}
+
+// HasCapability reports whether the provider declares capability
+func (c *APIConfig) HasCapability(capability string) bool {
+	return capability == "" || slices.Contains(c.Capabilities, capability)
+}
+
+// PricePerKTokens returns the combined input and output price per thousand
+// tokens, or false when the provider has no pricing configured
+func (c *APIConfig) PricePerKTokens() (float64, bool) {
+	price := c.PricePerKInputTokens + c.PricePerKOutputTokens
+	return price, price > 0
+}
+
+// CostUSD prices token usage at the provider's configured rates; it is 0
+// when the provider has no pricing
+func (c *APIConfig) CostUSD(usage TokenUsage) float64 {
+	return (float64(usage.InputTokens)*c.PricePerKInputTokens + float64(usage.OutputTokens)*c.PricePerKOutputTokens) / 1000
+}
+
+// CostLadder returns the available text providers with capability, cheapest
+// first. Providers without pricing sort last since their cost is unknown.
+// When no provider has the capability, every available provider is returned
+// in price order so the task can still run. Providers failing health checks
+// are left out.
+func (c *AIHelperConfig) CostLadder(capability string) []string {
+	available := c.GetHealthyAPIs()
+
+	var qualifying, all []string
+	for _, provider := range textProviders {
+		config, exists := available[provider]
+		if !exists {
+			continue
+		}
+		all = append(all, provider)
+		if config.HasCapability(capability) {
+			qualifying = append(qualifying, provider)
+		}
+	}
+
+	ladder := qualifying
+	if len(ladder) == 0 {
+		ladder = all
+	}
+
+	sort.SliceStable(ladder, func(i, j int) bool {
+		iConfig, jConfig := available[ladder[i]], available[ladder[j]]
+		iPrice, iPriced := iConfig.PricePerKTokens()
+		jPrice, jPriced := jConfig.PricePerKTokens()
+		if iPriced != jPriced {
+			return iPriced
+		}
+		return iPrice < jPrice
+	})
+	return ladder
+}
+
+// GetCheapestAPI returns the cheapest available provider with capability
+func (c *AIHelperConfig) GetCheapestAPI(capability string) (string, APIConfig, error) {
+	ladder := c.CostLadder(capability)
+	if len(ladder) == 0 {
+		return "", APIConfig{}, fmt.Errorf("no AI APIs available")
+	}
+	return ladder[0], c.GetAvailableAPIs()[ladder[0]], nil
+}
@@ -0,0 +1,111 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+// pricedProvider configures a provider with a price per thousand tokens and capabilities
+func pricedProvider(url string, price float64, capabilities ...string) APIConfig {
+	return APIConfig{
+		APIKeyVariable:        testAPIKeyVariable,
+		Models:                []string{"test-model"},
+		MaxTokens:             256,
+		Timeout:               5,
+		APIURL:                url,
+		PricePerKInputTokens:  price / 2,
+		PricePerKOutputTokens: price / 2,
+		Capabilities:          capabilities,
+	}
+}
+
+// newPricedConfig returns a cost-routed configuration with four providers:
+// groq is cheapest, then cerebras and nvidia with reasoning, and grok unpriced
+func newPricedConfig(t *testing.T) *AIHelperConfig {
+	t.Helper()
+	t.Setenv(testAPIKeyVariable, "test-key")
+	return &AIHelperConfig{
+		Cerebras: pricedProvider("http://127.0.0.1:0", 0.6, CapabilityReasoning),
+		Nvidia:   pricedProvider("http://127.0.0.1:0", 2.0, CapabilityReasoning),
+		Grok:     pricedProvider("http://127.0.0.1:0", 0),
+		Groq:     pricedProvider("http://127.0.0.1:0", 0.1),
+		Defaults: DefaultsConfig{Routing: RoutingModeCost},
+	}
+}
+
+func TestCostLadder(t *testing.T) {
+	config := newPricedConfig(t)
+
+	tests := []struct {
+		name       string
+		capability string
+		want       []string
+	}{
+		{"any provider", "", []string{"groq", "cerebras", "nvidia", "grok"}},
+		{"reasoning", CapabilityReasoning, []string{"cerebras", "nvidia"}},
+		{"nobody qualifies", "vision", []string{"groq", "cerebras", "nvidia", "grok"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.CostLadder(tt.capability); !slices.Equal(got, tt.want) {
+				t.Errorf("CostLadder(%q) = %v, want %v", tt.capability, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetPreferredAPICostMode(t *testing.T) {
+	config := newPricedConfig(t)
+
+	tests := []struct {
+		complexity string
+		want       string
+	}{
+		{"low", "groq"},
+		{"medium", "groq"},
+		{"high", "cerebras"}, // Cheapest with reasoning
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.complexity, func(t *testing.T) {
+			provider, _, err := config.GetPreferredAPI(tt.complexity)
+			if err != nil || provider != tt.want {
+				t.Errorf("GetPreferredAPI(%s) = %s, %v, want %s", tt.complexity, provider, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateByCostFallsBack(t *testing.T) {
+	var cheapCalls atomic.Int32
+	cheap := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cheapCalls.Add(1)
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer cheap.Close()
+	next := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeCompletion(w, "from cerebras")
+	}))
+	defer next.Close()
+
+	config := newPricedConfig(t)
+	config.Groq = pricedProvider(cheap.URL, 0.1)
+	config.Cerebras = pricedProvider(next.URL, 0.6, CapabilityReasoning)
+	config.Nvidia, config.Grok = APIConfig{}, APIConfig{}
+
+	response, err := NewAIClientWithConfig(config).GenerateDetailed(context.Background(), testMessages, "low")
+	if err != nil {
+		t.Fatalf("GenerateDetailed: %v", err)
+	}
+	if response.Provider != "cerebras" || response.Content != "from cerebras" {
+		t.Errorf("response from %s: %q, want the next provider up the ladder", response.Provider, response.Content)
+	}
+	if cheapCalls.Load() == 0 {
+		t.Error("the cheapest provider was not tried first")
+	}
+}
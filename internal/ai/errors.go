@@ -3,6 +3,11 @@ package ai
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Common AI system errors
@@ -24,6 +29,7 @@ var (
 	ErrInvalidResponse = errors.New("invalid response")
 	ErrRequestTooLarge = errors.New("request exceeds size limits")
 	ErrRateLimited     = errors.New("rate limit exceeded")
+	ErrContentFiltered = errors.New("response blocked by content filter")
 
 	// Cost errors
 	ErrCostLimitExceeded = errors.New("cost limit exceeded")
@@ -38,6 +44,93 @@ var (
 	ErrMissingCredentials = errors.New("missing credentials")
 )
 
+// Default HTTP status codes of failed API calls that are retried
+var (
+	DefaultRetryStatusCodes     = []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	DefaultRateLimitStatusCodes = []int{http.StatusTooManyRequests}
+)
+
+// DefaultRateLimitDelay is the wait before retrying a rate-limited call
+// whose response has no Retry-After header
+const DefaultRateLimitDelay = 10 * time.Second
+
+// DefaultMaxRetryDelay caps the wait before any retry, including one a
+// provider's Retry-After asks for
+const DefaultMaxRetryDelay = 60 * time.Second
+
+// RetryDecision is how a failed API call is retried
+type RetryDecision int
+
+const (
+	RetryNever      RetryDecision = iota // Fail without retrying
+	RetryNow                             // Retry after the retry delay
+	RetryAfterDelay                      // Retry after the provider's Retry-After or the rate limit delay
+)
+
+// APIError is a non-200 HTTP response from an AI API
+type APIError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // From the Retry-After header; zero when absent
+}
+
+// newAPIError builds the error for a non-200 response with the body read
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+// Is matches the sentinel errors the status code stands for
+func (e *APIError) Is(target error) bool {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	case e.StatusCode == http.StatusBadRequest:
+		return target == ErrInvalidRequest
+	case e.StatusCode >= http.StatusInternalServerError:
+		return target == ErrProviderUnavailable
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date; it returns zero when the header is absent, invalid or in the past
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// ClassifyStatus returns how a call that failed with statusCode is retried:
+// rate limit codes after a delay, retry codes at once, anything else never
+func ClassifyStatus(statusCode int, retryCodes, rateLimitCodes []int) RetryDecision {
+	switch {
+	case slices.Contains(rateLimitCodes, statusCode):
+		return RetryAfterDelay
+	case slices.Contains(retryCodes, statusCode):
+		return RetryNow
+	default:
+		return RetryNever
+	}
+}
+
 // AIError wraps errors with additional context
 type AIError struct {
 	Code      string
@@ -108,6 +201,9 @@ func WrapProviderError(err error, provider, model, requestID string) error {
 	case errors.Is(err, ErrInvalidRequest):
 		code = "INVALID_REQUEST"
 		message = "Invalid request format"
+	case errors.Is(err, ErrContentFiltered):
+		code = "CONTENT_FILTERED"
+		message = "Response blocked by provider content filter"
 	case errors.Is(err, ErrInvalidResponse):
 		code = "INVALID_RESPONSE"
 		message = "Invalid response from provider"
@@ -125,6 +221,13 @@ func IsRetryableError(err error) bool {
 		return false
 	}
 
+	// HTTP responses are classified by status code; rate limits are not
+	// retried immediately
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return ClassifyStatus(apiErr.StatusCode, DefaultRetryStatusCodes, DefaultRateLimitStatusCodes) == RetryNow
+	}
+
 	// Check for specific retryable errors
 	switch {
 	case errors.Is(err, ErrProviderTimeout):
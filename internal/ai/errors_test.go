@@ -0,0 +1,153 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClassifyStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   RetryDecision
+	}{
+		{http.StatusTooManyRequests, RetryAfterDelay},
+		{http.StatusInternalServerError, RetryNow},
+		{http.StatusBadGateway, RetryNow},
+		{http.StatusServiceUnavailable, RetryNow},
+		{http.StatusGatewayTimeout, RetryNow},
+		{http.StatusBadRequest, RetryNever},
+		{http.StatusUnauthorized, RetryNever},
+		{http.StatusNotFound, RetryNever},
+	}
+
+	defaults := &DefaultsConfig{}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			if got := defaults.ClassifyStatus(tt.status); got != tt.want {
+				t.Errorf("ClassifyStatus(%d) = %v, want %v", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyStatusConfigured(t *testing.T) {
+	defaults := &DefaultsConfig{
+		RetryStatusCodes:     []int{http.StatusRequestTimeout},
+		RateLimitStatusCodes: []int{http.StatusServiceUnavailable},
+	}
+
+	tests := []struct {
+		status int
+		want   RetryDecision
+	}{
+		{http.StatusRequestTimeout, RetryNow},
+		{http.StatusServiceUnavailable, RetryAfterDelay},
+		{http.StatusInternalServerError, RetryNever}, // Replaced, not added to
+		{http.StatusTooManyRequests, RetryNever},
+	}
+
+	for _, tt := range tests {
+		if got := defaults.ClassifyStatus(tt.status); got != tt.want {
+			t.Errorf("ClassifyStatus(%d) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header string
+		want   time.Duration
+	}{
+		{"absent", "", 0},
+		{"seconds", " 12 ", 12 * time.Second},
+		{"http date", now.Add(time.Minute).Format(http.TimeFormat), time.Minute},
+		{"date in the past", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"negative seconds", "-5", 0},
+		{"invalid", "soon", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.header, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIErrorSentinels(t *testing.T) {
+	tests := []struct {
+		status        int
+		want          error
+		wantRetryable bool
+	}{
+		{http.StatusTooManyRequests, ErrRateLimited, false},
+		{http.StatusBadRequest, ErrInvalidRequest, false},
+		{http.StatusServiceUnavailable, ErrProviderUnavailable, true},
+		{http.StatusUnauthorized, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			err := WrapProviderError(&APIError{StatusCode: tt.status}, "groq", "test-model", "")
+			for _, sentinel := range []error{ErrRateLimited, ErrInvalidRequest, ErrProviderUnavailable} {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+					t.Errorf("errors.Is(%v) = %v", sentinel, got)
+				}
+			}
+			if got := IsRetryableError(err); got != tt.wantRetryable {
+				t.Errorf("IsRetryableError() = %v, want %v", got, tt.wantRetryable)
+			}
+		})
+	}
+}
+
+func TestGenerateRetriesByStatus(t *testing.T) {
+	tests := []struct {
+		status    int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{http.StatusInternalServerError, 2, false},
+		{http.StatusBadGateway, 2, false},
+		{http.StatusServiceUnavailable, 2, false},
+		{http.StatusBadRequest, 1, true},
+		{http.StatusUnauthorized, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			// The first request fails with the status, the next succeeds
+			var calls atomic.Int32
+			config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) == 1 {
+					http.Error(w, "failed", tt.status)
+					return
+				}
+				writeCompletion(w, "recovered")
+			}))
+
+			response, err := NewAIClientWithConfig(config).GenerateDetailedWithProvider(context.Background(), "groq", testMessages)
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("made %d requests, want %d", calls.Load(), tt.wantCalls)
+			}
+			if tt.wantErr {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+					t.Errorf("err = %v, want the %d API error", err, tt.status)
+				}
+				return
+			}
+			if err != nil || response.Content != "recovered" {
+				t.Errorf("GenerateDetailedWithProvider() = %q, %v; want the retried reply", response.Content, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HealthCheckTimeout bounds a single provider ping
+const HealthCheckTimeout = 5 * time.Second
+
+// HealthProbe checks that a provider's endpoint is reachable
+type HealthProbe func(ctx context.Context, provider string, config APIConfig) error
+
+// ProviderHealth records the outcome of the latest health check per
+// provider; providers never checked are assumed healthy
+type ProviderHealth struct {
+	mu        sync.RWMutex
+	unhealthy map[string]string // Provider -> reason of its failed check
+}
+
+// NewProviderHealth creates a health record with every provider healthy
+func NewProviderHealth() *ProviderHealth {
+	return &ProviderHealth{unhealthy: make(map[string]string)}
+}
+
+// IsHealthy reports whether provider passed its latest health check
+func (h *ProviderHealth) IsHealthy(provider string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	_, down := h.unhealthy[provider]
+	return !down
+}
+
+// Record stores the result of a health check for provider
+func (h *ProviderHealth) Record(provider string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, wasDown := h.unhealthy[provider]
+	switch {
+	case err != nil:
+		if !wasDown {
+			log.Printf("Warning: AI provider %s failed its health check and is skipped: %v", provider, err)
+		}
+		h.unhealthy[provider] = err.Error()
+	case wasDown:
+		log.Printf("AI provider %s is healthy again", provider)
+		delete(h.unhealthy, provider)
+	}
+}
+
+// Unhealthy returns the providers that failed their latest check and why
+func (h *ProviderHealth) Unhealthy() map[string]string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	unhealthy := make(map[string]string, len(h.unhealthy))
+	for provider, reason := range h.unhealthy {
+		unhealthy[provider] = reason
+	}
+	return unhealthy
+}
+
+// PingProvider is the default health probe. Any HTTP response below 500,
+// including an auth or method error, shows the endpoint is up; it costs no tokens.
+func PingProvider(ctx context.Context, provider string, config APIConfig) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, config.APIURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create ping request: %w", err)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("%s unreachable: %w", provider, err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%s returned HTTP %d", provider, response.StatusCode)
+	}
+	return nil
+}
+
+// StartHealthChecks pings every provider with an API key now and then every
+// interval until ctx is done. Providers failing the check are skipped by
+// provider selection. A nil probe uses PingProvider.
+func (c *AIHelperConfig) StartHealthChecks(ctx context.Context, interval time.Duration, probe HealthProbe) {
+	if interval <= 0 {
+		return
+	}
+	if probe == nil {
+		probe = PingProvider
+	}
+	c.CheckProviderHealth(ctx, probe)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.CheckProviderHealth(ctx, probe)
+			}
+		}
+	}()
+}
+
+// CheckProviderHealth probes every provider with an API key concurrently and
+// records the results
+func (c *AIHelperConfig) CheckProviderHealth(ctx context.Context, probe HealthProbe) {
+	health := c.startHealth()
+
+	var wg sync.WaitGroup
+	for provider, config := range c.GetAvailableAPIs() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+			defer cancel()
+			health.Record(provider, probe(probeCtx, provider, config))
+		}()
+	}
+	wg.Wait()
+}
+
+// ProviderHealth returns the providers that failed their latest health check
+// and why; empty when health checks are not running
+func (c *AIHelperConfig) ProviderHealth() map[string]string {
+	health := c.health.Load()
+	if health == nil {
+		return map[string]string{}
+	}
+	return health.Unhealthy()
+}
+
+// startHealth returns the provider health record, creating it on the first
+// check. Checks and provider selection run on different goroutines.
+func (c *AIHelperConfig) startHealth() *ProviderHealth {
+	if health := c.health.Load(); health != nil {
+		return health
+	}
+	c.health.CompareAndSwap(nil, NewProviderHealth())
+	return c.health.Load()
+}
+
+// GetHealthyAPIs returns the available providers that passed their latest
+// health check. When every provider failed, all available providers are
+// returned so requests are still attempted rather than refused outright.
+func (c *AIHelperConfig) GetHealthyAPIs() map[string]APIConfig {
+	available := c.GetAvailableAPIs()
+	health := c.health.Load()
+	if health == nil {
+		return available
+	}
+
+	healthy := make(map[string]APIConfig, len(available))
+	for provider, config := range available {
+		if health.IsHealthy(provider) {
+			healthy[provider] = config
+		}
+	}
+	if len(healthy) == 0 {
+		return available
+	}
+	return healthy
+}
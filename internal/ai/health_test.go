@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestGetHealthyAPIs(t *testing.T) {
+	tests := []struct {
+		name      string
+		probe     HealthProbe // nil skips health checks
+		want      int
+		unhealthy int
+	}{
+		{"not checked", nil, 1, 0},
+		{"healthy", func(context.Context, string, APIConfig) error { return nil }, 1, 0},
+		// The only provider failing keeps it available rather than refusing requests
+		{"all failing", func(context.Context, string, APIConfig) error { return errors.New("down") }, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig(t, http.NotFoundHandler())
+			if tt.probe != nil {
+				config.CheckProviderHealth(context.Background(), tt.probe)
+			}
+			if got := len(config.GetHealthyAPIs()); got != tt.want {
+				t.Errorf("GetHealthyAPIs returned %d providers, want %d", got, tt.want)
+			}
+			if got := len(config.ProviderHealth()); got != tt.unhealthy {
+				t.Errorf("ProviderHealth reported %d unhealthy, want %d", got, tt.unhealthy)
+			}
+		})
+	}
+}
+
+func TestHealthChecksConcurrentWithSelection(t *testing.T) {
+	config := newTestConfig(t, http.NotFoundHandler())
+	probe := func(context.Context, string, APIConfig) error { return nil }
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			config.CheckProviderHealth(context.Background(), probe)
+		}()
+		go func() {
+			defer wg.Done()
+			config.GetHealthyAPIs()
+			config.ProviderHealth()
+		}()
+	}
+	wg.Wait()
+
+	if len(config.GetHealthyAPIs()) != 1 {
+		t.Errorf("healthy provider lost")
+	}
+}
@@ -3,7 +3,9 @@ package ai
 import (
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -19,6 +21,46 @@ const (
 	HelperGroq     HelperType = "groq"     // Ultra-fast inference
 )
 
+// Helper script names, resolved to paths by HelpersConfig.ScriptPath
+const (
+	ScriptCerebrasCodeAnalyzer = "cerebras_code_analyzer"
+	ScriptNvidiaEnhanceHelper  = "nvidia_enhance_helper"
+	ScriptGeminiCodeAnalyzer   = "gemini_code_analyzer"
+	ScriptGrokCodeHelper       = "grok_code_helper"
+	ScriptGroqFastAnalyzer     = "groq_fast_analyzer"
+)
+
+// DefaultHelperScriptDir is where helper scripts live when neither the
+// configuration nor HelperScriptDirEnv names a directory
+const DefaultHelperScriptDir = "/home/niko/.claude"
+
+// HelperScriptDirEnv overrides the configured helper script directory
+const HelperScriptDirEnv = "AI_HELPERS_DIR"
+
+// Dir returns the directory helper scripts are resolved against
+func (h HelpersConfig) Dir() string {
+	if dir := os.Getenv(HelperScriptDirEnv); dir != "" {
+		return dir
+	}
+	if h.ScriptDir != "" {
+		return h.ScriptDir
+	}
+	return DefaultHelperScriptDir
+}
+
+// ScriptPath returns the path of the named helper script, preferring a
+// per-script override over the script directory
+func (h HelpersConfig) ScriptPath(name string) string {
+	path, overridden := h.Scripts[name]
+	if !overridden || path == "" {
+		path = name
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(h.Dir(), path)
+}
+
 // HelperConfig holds configuration for AI helpers
 type HelperConfig struct {
 	Type        HelperType    `yaml:"type"`
@@ -50,13 +92,18 @@ type HelperManager struct {
 	configs map[HelperType]HelperConfig
 }
 
-// NewHelperManager creates a new helper manager
+// NewHelperManager creates a helper manager using the default script locations
 func NewHelperManager() *HelperManager {
+	return NewHelperManagerWithConfig(HelpersConfig{})
+}
+
+// NewHelperManagerWithConfig creates a helper manager resolving scripts through helpers
+func NewHelperManagerWithConfig(helpers HelpersConfig) *HelperManager {
 	return &HelperManager{
 		configs: map[HelperType]HelperConfig{
 			HelperCerebras: {
 				Type:        HelperCerebras,
-				ScriptPath:  "/home/niko/.claude/cerebras_code_analyzer",
+				ScriptPath:  helpers.ScriptPath(ScriptCerebrasCodeAnalyzer),
 				Description: "Fast code analysis, review, and generation",
 				MaxTokens:   4000,
 				Timeout:     60 * time.Second,
@@ -64,7 +111,7 @@ func NewHelperManager() *HelperManager {
 			},
 			HelperNvidia: {
 				Type:        HelperNvidia,
-				ScriptPath:  "/home/niko/.claude/nvidia_enhance_helper",
+				ScriptPath:  helpers.ScriptPath(ScriptNvidiaEnhanceHelper),
 				Description: "Multimodal analysis including OCR",
 				MaxTokens:   65536,
 				Timeout:     90 * time.Second,
@@ -72,7 +119,7 @@ func NewHelperManager() *HelperManager {
 			},
 			HelperGemini: {
 				Type:        HelperGemini,
-				ScriptPath:  "/home/niko/.claude/gemini_code_analyzer",
+				ScriptPath:  helpers.ScriptPath(ScriptGeminiCodeAnalyzer),
 				Description: "Comprehensive multimodal analysis",
 				MaxTokens:   8192,
 				Timeout:     120 * time.Second,
@@ -80,7 +127,7 @@ func NewHelperManager() *HelperManager {
 			},
 			HelperGrok: {
 				Type:        HelperGrok,
-				ScriptPath:  "/home/niko/.claude/grok_code_helper",
+				ScriptPath:  helpers.ScriptPath(ScriptGrokCodeHelper),
 				Description: "Creative solutions and multimodal analysis",
 				MaxTokens:   8192,
 				Timeout:     120 * time.Second,
@@ -88,7 +135,7 @@ func NewHelperManager() *HelperManager {
 			},
 			HelperGroq: {
 				Type:        HelperGroq,
-				ScriptPath:  "/home/niko/.claude/groq_fast_analyzer",
+				ScriptPath:  helpers.ScriptPath(ScriptGroqFastAnalyzer),
 				Description: "Ultra-fast inference for speed-critical tasks",
 				MaxTokens:   4096,
 				Timeout:     30 * time.Second,
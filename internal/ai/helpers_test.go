@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestHelpersScriptPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		helpers HelpersConfig
+		script  string
+		want    string
+	}{
+		{
+			name:   "default directory",
+			script: ScriptGroqFastAnalyzer,
+			want:   filepath.Join(DefaultHelperScriptDir, ScriptGroqFastAnalyzer),
+		},
+		{
+			name:    "configured directory",
+			helpers: HelpersConfig{ScriptDir: "/opt/helpers"},
+			script:  ScriptGroqFastAnalyzer,
+			want:    "/opt/helpers/" + ScriptGroqFastAnalyzer,
+		},
+		{
+			name:    "environment overrides configured directory",
+			env:     "/srv/helpers",
+			helpers: HelpersConfig{ScriptDir: "/opt/helpers"},
+			script:  ScriptGroqFastAnalyzer,
+			want:    "/srv/helpers/" + ScriptGroqFastAnalyzer,
+		},
+		{
+			name: "relative override is under the directory",
+			helpers: HelpersConfig{
+				ScriptDir: "/opt/helpers",
+				Scripts:   map[string]string{ScriptGroqFastAnalyzer: "bin/groq"},
+			},
+			script: ScriptGroqFastAnalyzer,
+			want:   "/opt/helpers/bin/groq",
+		},
+		{
+			name: "absolute override",
+			helpers: HelpersConfig{
+				ScriptDir: "/opt/helpers",
+				Scripts:   map[string]string{ScriptGroqFastAnalyzer: "/usr/local/bin/groq"},
+			},
+			script: ScriptGroqFastAnalyzer,
+			want:   "/usr/local/bin/groq",
+		},
+		{
+			name: "empty override keeps the script name",
+			helpers: HelpersConfig{
+				ScriptDir: "/opt/helpers",
+				Scripts:   map[string]string{ScriptGroqFastAnalyzer: ""},
+			},
+			script: ScriptGroqFastAnalyzer,
+			want:   "/opt/helpers/" + ScriptGroqFastAnalyzer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(HelperScriptDirEnv, tt.env)
+			if got := tt.helpers.ScriptPath(tt.script); got != tt.want {
+				t.Errorf("ScriptPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultResponseCallTimeout bounds a shared upstream call
+const DefaultResponseCallTimeout = 5 * time.Minute
+
+// responseEntry is a cached response
+type responseEntry struct {
+	response Response
+	expires  time.Time
+}
+
+// responseCall is an upstream call that identical requests wait on
+type responseCall struct {
+	done     chan struct{}
+	response Response
+	err      error
+}
+
+// ResponseCache answers identical requests, common when many workers ask the
+// same thing, with one upstream call. Concurrent misses for the same request
+// wait for the first caller's call and share its outcome, errors included.
+// Successful responses are kept for ttl when both capacity and ttl are set;
+// otherwise only in-flight requests are shared.
+type ResponseCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[[sha256.Size]byte]responseEntry
+	inflight map[[sha256.Size]byte]*responseCall
+	now      func() time.Time
+	hits     uint64 // Answered from a stored response
+	shared   uint64 // Answered by waiting on another caller's call
+	misses   uint64 // Sent upstream
+
+	// Bounds a shared call, which no caller's context can cancel
+	callTimeout time.Duration
+}
+
+// NewResponseCache creates a cache holding at most capacity responses for ttl
+func NewResponseCache(capacity int, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[[sha256.Size]byte]responseEntry),
+		inflight: make(map[[sha256.Size]byte]*responseCall),
+		now:      time.Now,
+
+		callTimeout: DefaultResponseCallTimeout,
+	}
+}
+
+// Do returns the response for key, calling fn only when no stored response
+// and no identical call in flight can answer it. The call runs on a context
+// detached from every caller's, bounded by the call timeout, so a caller
+// whose ctx ends, the first one included, only stops waiting; the call
+// carries on for the others. A panic in fn fails the call instead of
+// leaving it in flight.
+func (c *ResponseCache) Do(ctx context.Context, key [sha256.Size]byte, fn func(ctx context.Context) (Response, error)) (Response, error) {
+	c.mu.Lock()
+	if entry, exists := c.entries[key]; exists {
+		if c.now().Before(entry.expires) {
+			c.hits++
+			c.mu.Unlock()
+			return entry.response, nil
+		}
+		delete(c.entries, key)
+	}
+
+	call, exists := c.inflight[key]
+	if exists {
+		c.shared++
+	} else {
+		call = &responseCall{done: make(chan struct{})}
+		c.inflight[key] = call
+		c.misses++
+		go c.run(context.WithoutCancel(ctx), c.callTimeout, key, call, fn)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.response, call.err
+	case <-ctx.Done():
+		return Response{}, ctx.Err()
+	}
+}
+
+// run makes the shared call for key and hands its outcome to every waiter
+func (c *ResponseCache) run(ctx context.Context, timeout time.Duration, key [sha256.Size]byte, call *responseCall, fn func(ctx context.Context) (Response, error)) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			call.response, call.err = Response{}, fmt.Errorf("shared response call panicked: %v", r)
+		}
+
+		c.mu.Lock()
+		delete(c.inflight, key)
+		if call.err == nil {
+			c.store(key, call.response)
+		}
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	call.response, call.err = fn(ctx)
+}
+
+// SetCallTimeout bounds each shared upstream call; zero or less restores
+// DefaultResponseCallTimeout
+func (c *ResponseCache) SetCallTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultResponseCallTimeout
+	}
+	c.mu.Lock()
+	c.callTimeout = timeout
+	c.mu.Unlock()
+}
+
+// store keeps a response for ttl, dropping expired entries and then the
+// entry closest to expiry when the cache is full. Callers must hold c.mu.
+func (c *ResponseCache) store(key [sha256.Size]byte, response Response) {
+	if c.capacity <= 0 || c.ttl <= 0 {
+		return
+	}
+
+	now := c.now()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.capacity {
+		var oldestKey [sha256.Size]byte
+		var oldest time.Time
+		for key, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, key)
+				continue
+			}
+			if oldest.IsZero() || entry.expires.Before(oldest) {
+				oldestKey, oldest = key, entry.expires
+			}
+		}
+		if len(c.entries) >= c.capacity {
+			delete(c.entries, oldestKey)
+		}
+	}
+	c.entries[key] = responseEntry{response: response, expires: now.Add(c.ttl)}
+}
+
+// Stats returns the requests answered from a stored response, by sharing an
+// in-flight call, and by calling upstream
+func (c *ResponseCache) Stats() (hits, shared, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.shared, c.misses
+}
+
+// responseKey hashes everything that changes a provider's response
+func responseKey(provider, model string, apiConfig APIConfig, messages []Message) [sha256.Size]byte {
+	var key strings.Builder
+	fmt.Fprintf(&key, "%s\x00%s\x00%d\x00%g\x00%g", provider, model, apiConfig.MaxTokens, apiConfig.Temperature, apiConfig.TopP)
+	for _, message := range messages {
+		fmt.Fprintf(&key, "\x00%s\x00%s", message.Role, message.Content)
+	}
+	return sha256.Sum256([]byte(key.String()))
+}
@@ -0,0 +1,176 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testKey = sha256.Sum256([]byte("request"))
+
+func TestResponseCacheSharesInFlightCall(t *testing.T) {
+	cache := NewResponseCache(0, 0)
+	release := make(chan struct{})
+	var calls atomic.Int32
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make([]string, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := cache.Do(context.Background(), testKey, func(ctx context.Context) (Response, error) {
+				calls.Add(1)
+				<-release
+				return Response{Content: "shared"}, nil
+			})
+			if err != nil {
+				t.Errorf("Do: %v", err)
+			}
+			results[i] = response.Content
+		}()
+	}
+
+	// Let every caller reach the in-flight call before it completes
+	for {
+		if _, shared, misses := cache.Stats(); shared+misses == callers {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("fn ran %d times, want 1", got)
+	}
+	for i, result := range results {
+		if result != "shared" {
+			t.Errorf("caller %d got %q", i, result)
+		}
+	}
+}
+
+func TestResponseCacheLeaderCancelDoesNotFailFollowers(t *testing.T) {
+	cache := NewResponseCache(0, 0)
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := cache.Do(leaderCtx, testKey, func(ctx context.Context) (Response, error) {
+			close(started)
+			select {
+			case <-release:
+				return Response{Content: "done"}, nil
+			case <-ctx.Done():
+				return Response{}, ctx.Err()
+			}
+		})
+		leaderErr <- err
+	}()
+	<-started
+
+	followerDone := make(chan Response, 1)
+	go func() {
+		response, err := cache.Do(context.Background(), testKey, func(context.Context) (Response, error) {
+			t.Error("follower started a second call")
+			return Response{}, nil
+		})
+		if err != nil {
+			t.Errorf("follower: %v", err)
+		}
+		followerDone <- response
+	}()
+	for {
+		if _, shared, _ := cache.Stats(); shared == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancelLeader()
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader err = %v, want context.Canceled", err)
+	}
+
+	close(release)
+	if response := <-followerDone; response.Content != "done" {
+		t.Errorf("follower got %q, want the shared response", response.Content)
+	}
+}
+
+func TestResponseCacheCallTimeout(t *testing.T) {
+	cache := NewResponseCache(0, 0)
+	cache.SetCallTimeout(10 * time.Millisecond)
+
+	_, err := cache.Do(context.Background(), testKey, func(ctx context.Context) (Response, error) {
+		<-ctx.Done()
+		return Response{}, ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestResponseCachePanicClearsInFlight(t *testing.T) {
+	cache := NewResponseCache(0, 0)
+
+	_, err := cache.Do(context.Background(), testKey, func(context.Context) (Response, error) {
+		panic("boom")
+	})
+	if err == nil {
+		t.Fatal("Do succeeded after fn panicked")
+	}
+
+	response, err := cache.Do(context.Background(), testKey, func(context.Context) (Response, error) {
+		return Response{Content: "retried"}, nil
+	})
+	if err != nil || response.Content != "retried" {
+		t.Errorf("Do after panic = %q, %v; want a fresh call", response.Content, err)
+	}
+}
+
+func TestResponseCacheStoresResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		ttl      time.Duration
+		advance  time.Duration
+		fnErr    error
+		wantCall int32
+	}{
+		{"cached", 10, time.Minute, time.Second, nil, 1},
+		{"expired", 10, time.Minute, 2 * time.Minute, nil, 2},
+		{"errors not cached", 10, time.Minute, time.Second, errors.New("upstream"), 2},
+		{"dedup only", 0, 0, 0, nil, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewResponseCache(tt.capacity, tt.ttl)
+			now := time.Now()
+			cache.now = func() time.Time { return now }
+
+			var calls atomic.Int32
+			fn := func(context.Context) (Response, error) {
+				calls.Add(1)
+				return Response{Content: "answer"}, tt.fnErr
+			}
+
+			cache.Do(context.Background(), testKey, fn)
+			now = now.Add(tt.advance)
+			cache.Do(context.Background(), testKey, fn)
+
+			if got := calls.Load(); got != tt.wantCall {
+				t.Errorf("fn ran %d times, want %d", got, tt.wantCall)
+			}
+		})
+	}
+}
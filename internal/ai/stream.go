@@ -0,0 +1,245 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// sseDone is the sentinel data payload OpenAI-style providers send last
+const sseDone = "[DONE]"
+
+// maxSSELineSize bounds a single server-sent event line
+const maxSSELineSize = 1 << 20
+
+// StreamHandler receives each content delta as it arrives
+type StreamHandler func(delta string)
+
+// GenerateStream generates a response using the best available AI API,
+// passing content to onDelta as the provider streams it. The returned
+// Response holds the complete content.
+func (c *AIClient) GenerateStream(ctx context.Context, messages []Message, taskComplexity string, onDelta StreamHandler) (Response, error) {
+	provider, apiConfig, err := c.config.GetPreferredAPI(taskComplexity)
+	if err != nil {
+		return Response{}, fmt.Errorf("no AI API available: %w", err)
+	}
+
+	return c.streamAPI(ctx, provider, apiConfig, messages, onDelta)
+}
+
+// GenerateStreamWithProvider streams a response from a specific provider,
+// pinned to model unless it is empty
+func (c *AIClient) GenerateStreamWithProvider(ctx context.Context, provider, model string, messages []Message, onDelta StreamHandler) (Response, error) {
+	apiConfig, exists := c.config.GetAvailableAPIs()[provider]
+	if !exists {
+		return Response{}, fmt.Errorf("provider %s not available", provider)
+	}
+	if model != "" {
+		if !slices.Contains(apiConfig.Models, model) {
+			return Response{}, fmt.Errorf("model %s not configured for provider %s", model, provider)
+		}
+		apiConfig.Models = []string{model}
+	}
+
+	return c.streamAPI(ctx, provider, apiConfig, messages, onDelta)
+}
+
+// streamAPI makes a streaming request and assembles the deltas. A failure
+// before any delta reached the handler is retried like a non-streamed call;
+// after that a retry would repeat the deltas, so the error is returned.
+func (c *AIClient) streamAPI(ctx context.Context, provider string, apiConfig APIConfig, messages []Message, onDelta StreamHandler) (Response, error) {
+	if len(apiConfig.Models) == 0 {
+		return Response{}, fmt.Errorf("no models configured for provider %s", provider)
+	}
+
+	model := apiConfig.Models[0]
+	messages = c.withCompliance(messages)
+	if err := checkRequestSize(provider, model, apiConfig, messages); err != nil {
+		return Response{}, err
+	}
+
+	var lastErr error
+	var delay time.Duration
+	for attempt := 0; attempt <= c.config.Defaults.RetryCount; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return Response{}, ctx.Err()
+			}
+		}
+
+		var delivered atomic.Bool
+		response, err := c.streamOnce(ctx, provider, model, apiConfig, messages, func(delta string) {
+			delivered.Store(true)
+			if onDelta != nil {
+				onDelta(delta)
+			}
+		})
+		if err == nil {
+			return response, nil
+		}
+		if delivered.Load() {
+			return Response{}, err
+		}
+
+		var retry bool
+		if delay, retry = c.retryDelay(ctx, err); !retry {
+			return Response{}, err
+		}
+		lastErr = err
+	}
+
+	return Response{}, fmt.Errorf("all attempts failed for provider %s: %w", provider, lastErr)
+}
+
+// streamOnce makes one streaming attempt, or answers it from the response
+// cache when an identical request was just made or is in flight. A response
+// this caller did not stream itself reaches onDelta as a single delta.
+func (c *AIClient) streamOnce(ctx context.Context, provider, model string, apiConfig APIConfig, messages []Message, onDelta StreamHandler) (Response, error) {
+	if c.responseCache == nil {
+		return c.sendStreamRequest(ctx, provider, model, apiConfig, messages, onDelta)
+	}
+
+	// The shared call outlives a caller whose ctx ends, so its deltas stop
+	// reaching the handler once this caller has returned
+	var waiting, streamed atomic.Bool
+	waiting.Store(true)
+	defer waiting.Store(false)
+
+	response, err := c.responseCache.Do(ctx, responseKey(provider, model, apiConfig, messages), func(ctx context.Context) (Response, error) {
+		streamed.Store(true)
+		return c.sendStreamRequest(ctx, provider, model, apiConfig, messages, func(delta string) {
+			if waiting.Load() {
+				onDelta(delta)
+			}
+		})
+	})
+	if err == nil && !streamed.Load() {
+		onDelta(response.Content)
+	}
+	return response, err
+}
+
+// sendStreamRequest makes the streaming HTTP request and assembles the
+// deltas. Every outcome, transport and HTTP failures included, is audited.
+func (c *AIClient) sendStreamRequest(ctx context.Context, provider, model string, apiConfig APIConfig, messages []Message, onDelta StreamHandler) (response Response, err error) {
+	startTime := time.Now()
+	defer func() {
+		c.audit(ctx, provider, model, messages, response, err)
+	}()
+
+	adapter := adapterFor(provider)
+	requestBody, err := adapter.buildRequest(model, apiConfig, messages, true)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := newAPIRequest(ctx, provider, apiConfig, adapter.streamURL(resolveAPIURL(apiConfig, model)), requestBody)
+	if err != nil {
+		return Response{}, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// No client timeout: a long stream is bounded by ctx rather than cut off mid-response
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return Response{}, fmt.Errorf("API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return Response{}, newAPIError(resp, body)
+	}
+
+	var result completion
+	var content strings.Builder
+	err = readSSE(resp.Body, func(data []byte) error {
+		if err := checkErrorBody(data); err != nil {
+			return err
+		}
+
+		chunk, err := adapter.parseStreamChunk(data)
+		if err != nil {
+			return err
+		}
+
+		if chunk.Content != "" {
+			content.WriteString(chunk.Content)
+			if onDelta != nil {
+				onDelta(chunk.Content)
+			}
+		}
+		if chunk.FinishReason != "" {
+			result.FinishReason = chunk.FinishReason
+		}
+		if chunk.Usage.TotalTokens > 0 {
+			result.Usage = chunk.Usage
+		}
+		return nil
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("stream from %s failed: %w", provider, err)
+	}
+
+	result.Content = content.String()
+	return buildResponse(provider, model, apiConfig, messages, result, startTime)
+}
+
+// readSSE reads server-sent events from r and calls onData with each event's
+// data. Multi-line data fields are joined with newlines; comments and other
+// fields are ignored. Reading stops at EOF or a [DONE] payload.
+func readSSE(r io.Reader, onData func(data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSSELineSize)
+
+	var data bytes.Buffer
+	dispatch := func() (bool, error) {
+		if data.Len() == 0 {
+			return false, nil
+		}
+		payload := bytes.TrimSuffix(data.Bytes(), []byte("\n"))
+		defer data.Reset()
+
+		if string(payload) == sseDone {
+			return true, nil
+		}
+		return false, onData(payload)
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		// A blank line ends the event
+		if line == "" {
+			done, err := dispatch()
+			if err != nil || done {
+				return err
+			}
+			continue
+		}
+
+		value, found := strings.CutPrefix(line, "data:")
+		if !found {
+			continue
+		}
+		data.WriteString(strings.TrimPrefix(value, " "))
+		data.WriteByte('\n')
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read event stream: %w", err)
+	}
+
+	// Dispatch a final event not followed by a blank line
+	_, err := dispatch()
+	return err
+}
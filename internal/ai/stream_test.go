@@ -0,0 +1,209 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStreamRetriesBeforeFirstDelta(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int32
+		status    int
+		wantCalls int32
+		wantErr   bool
+	}{
+		{"succeeds first time", 0, 0, 1, false},
+		{"retries server error", 2, http.StatusServiceUnavailable, 3, false},
+		{"gives up after retry count", 5, http.StatusServiceUnavailable, 3, true},
+		{"does not retry client error", 1, http.StatusBadRequest, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if calls.Add(1) <= tt.failures {
+					http.Error(w, "unavailable", tt.status)
+					return
+				}
+				writeStream(w, "Hello", ", world")
+			}))
+			client := NewAIClientWithConfig(config)
+
+			var deltas []string
+			response, err := client.GenerateStreamWithProvider(context.Background(), "groq", "", testMessages, func(delta string) {
+				deltas = append(deltas, delta)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("provider got %d requests, want %d", got, tt.wantCalls)
+			}
+			if !tt.wantErr && (response.Content != "Hello, world" || strings.Join(deltas, "") != "Hello, world") {
+				t.Errorf("content = %q, deltas = %q", response.Content, deltas)
+			}
+		})
+	}
+}
+
+func TestStreamNotRetriedAfterDelta(t *testing.T) {
+	var calls atomic.Int32
+	config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"partial\"}}]}\n\ndata: {\"error\":{\"message\":\"overloaded\"}}\n\n"))
+	}))
+	client := NewAIClientWithConfig(config)
+
+	var deltas []string
+	_, err := client.GenerateStreamWithProvider(context.Background(), "groq", "", testMessages, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err == nil {
+		t.Fatal("stream succeeded after a mid-stream error")
+	}
+	if calls.Load() != 1 || len(deltas) != 1 {
+		t.Errorf("calls = %d, deltas = %q; want one attempt and no repeated deltas", calls.Load(), deltas)
+	}
+}
+
+func TestStreamRejectsOversizedRequest(t *testing.T) {
+	var calls atomic.Int32
+	config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	config.Groq.ContextWindow = 300
+	client := NewAIClientWithConfig(config)
+
+	messages := []Message{{Role: "user", Content: strings.Repeat("too long ", 200)}}
+	_, err := client.GenerateStreamWithProvider(context.Background(), "groq", "", messages, nil)
+	if err == nil || calls.Load() != 0 {
+		t.Errorf("err = %v after %d requests; want ErrRequestTooLarge without a request", err, calls.Load())
+	}
+}
+
+func TestStreamSharesCachedResponse(t *testing.T) {
+	var calls atomic.Int32
+	config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		writeStream(w, "cached ", "answer")
+	}))
+	config.Defaults.ResponseCacheSize = 10
+	config.Defaults.ResponseCacheTTL = 60
+	client := NewAIClientWithConfig(config)
+
+	for i := range 2 {
+		var deltas []string
+		response, err := client.GenerateStreamWithProvider(context.Background(), "groq", "", testMessages, func(delta string) {
+			deltas = append(deltas, delta)
+		})
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if response.Content != "cached answer" || strings.Join(deltas, "") != "cached answer" {
+			t.Errorf("request %d: content = %q, deltas = %q", i, response.Content, deltas)
+		}
+	}
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("provider got %d requests, want 1", got)
+	}
+}
+
+func TestReadSSE(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   []string
+	}{
+		{"events", "data: one\n\ndata: two\n\n", []string{"one", "two"}},
+		{"stops at done", "data: one\n\ndata: [DONE]\n\ndata: ignored\n\n", []string{"one"}},
+		{"multi-line data", "data: first\ndata: second\n\n", []string{"first\nsecond"}},
+		{"comments and other fields", ": keep-alive\nevent: message\nid: 7\ndata: one\n\n", []string{"one"}},
+		{"crlf", "data: one\r\n\r\n", []string{"one"}},
+		{"no space after colon", "data:one\n\n", []string{"one"}},
+		{"final event without blank line", "data: one\n\ndata: two", []string{"one", "two"}},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := readSSE(strings.NewReader(tt.stream), func(data []byte) error {
+				got = append(got, string(data))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("readSSE: %v", err)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadSSEStopsOnHandlerError(t *testing.T) {
+	stop := errors.New("bad chunk")
+	calls := 0
+	err := readSSE(strings.NewReader("data: one\n\ndata: two\n\n"), func([]byte) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("readSSE = %v after %d events, want the handler error after 1", err, calls)
+	}
+}
+
+func TestGeminiStreamURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/v1beta/models/gemini:generateContent", "https://example.com/v1beta/models/gemini:streamGenerateContent?alt=sse"},
+		{"https://example.com/v1beta/models/gemini:generateContent?key=k", "https://example.com/v1beta/models/gemini:streamGenerateContent?key=k&alt=sse"},
+	}
+
+	for _, tt := range tests {
+		if got := (geminiAdapter{}).streamURL(tt.url); got != tt.want {
+			t.Errorf("streamURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+	if got := (openAIAdapter{}).streamURL("https://example.com/v1/chat/completions"); got != "https://example.com/v1/chat/completions" {
+		t.Errorf("OpenAI streamURL = %q, want the completions endpoint", got)
+	}
+}
+
+func TestStreamFromGemini(t *testing.T) {
+	var gotQuery string
+	config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, text := range []string{"Wrap ", "with %w."} {
+			fmt.Fprintf(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":%q}]}}]}\n\n", text)
+		}
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[]},\"finishReason\":\"STOP\"}],\"usageMetadata\":{\"promptTokenCount\":4,\"candidatesTokenCount\":3,\"totalTokenCount\":7}}\n\n")
+	}))
+	config.Gemini, config.Groq = config.Groq, APIConfig{}
+
+	var deltas []string
+	response, err := NewAIClientWithConfig(config).GenerateStreamWithProvider(context.Background(), "gemini", "", testMessages, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("GenerateStreamWithProvider: %v", err)
+	}
+	if response.Content != "Wrap with %w." || len(deltas) != 2 || response.FinishReason != "STOP" || response.Usage.TotalTokens != 7 {
+		t.Errorf("response = %+v after deltas %q", response, deltas)
+	}
+	if !strings.Contains(gotQuery, "alt=sse") {
+		t.Errorf("query = %q, want SSE framing requested", gotQuery)
+	}
+}
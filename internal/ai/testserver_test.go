@@ -0,0 +1,48 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testAPIKeyVariable holds the API key of the provider newTestConfig configures
+const testAPIKeyVariable = "AI_TEST_API_KEY"
+
+// newTestConfig returns a configuration whose only available provider is
+// groq, served by handler
+func newTestConfig(t *testing.T, handler http.Handler) *AIHelperConfig {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	t.Setenv(testAPIKeyVariable, "test-key")
+	return &AIHelperConfig{
+		Groq: APIConfig{
+			APIKeyVariable: testAPIKeyVariable,
+			Models:         []string{"test-model"},
+			MaxTokens:      256,
+			Timeout:        5,
+			APIURL:         server.URL,
+		},
+		Defaults: DefaultsConfig{RetryCount: 2},
+	}
+}
+
+// writeCompletion writes a non-streamed chat completion holding content
+func writeCompletion(w http.ResponseWriter, content string) {
+	fmt.Fprintf(w, `{"model":"test-model","choices":[{"message":{"role":"assistant","content":%q},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":3,"total_tokens":8}}`, content)
+}
+
+// writeStream writes a streamed chat completion with one event per delta
+func writeStream(w http.ResponseWriter, deltas ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, delta := range deltas {
+		fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", delta)
+	}
+	fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+var testMessages = []Message{{Role: "user", Content: "hello"}}
@@ -2,6 +2,7 @@ package ai
 
 import (
 	"context"
+	"strings"
 	"time"
 )
 
@@ -64,6 +65,14 @@ type Response struct {
 	Latency time.Duration `json:"latency"`
 	Usage   TokenUsage    `json:"usage"`
 
+	// Why generation stopped; Truncated is set when the token limit was hit
+	FinishReason string `json:"finish_reason,omitempty"`
+	Truncated    bool   `json:"truncated,omitempty"`
+
+	// Context window consumed by the request and the provider's configured limit (0 when unknown)
+	ContextUsed  int `json:"context_used,omitempty"`
+	ContextLimit int `json:"context_limit,omitempty"`
+
 	// Raw response for debugging
 	Raw interface{} `json:"raw,omitempty"`
 
@@ -71,6 +80,30 @@ type Response struct {
 	Error string `json:"error,omitempty"`
 }
 
+// ContextWarningRatio is the share of the context window at which usage is near the limit
+const ContextWarningRatio = 0.9
+
+// NearContextLimit reports whether the request used at least ContextWarningRatio of the context window
+func (r Response) NearContextLimit() bool {
+	return r.ContextLimit > 0 && float64(r.ContextUsed) >= ContextWarningRatio*float64(r.ContextLimit)
+}
+
+// Finish reasons reported in Response
+const (
+	FinishReasonStop   = "stop"
+	FinishReasonLength = "length"
+)
+
+// isLengthFinish reports whether a provider finish reason means the output hit the token limit
+func isLengthFinish(reason string) bool {
+	switch strings.ToLower(reason) {
+	case "length", "max_tokens", "max_output_tokens":
+		return true
+	default:
+		return false
+	}
+}
+
 // TokenUsage holds token counts and cost information
 type TokenUsage struct {
 	InputTokens  int     `json:"input_tokens"`
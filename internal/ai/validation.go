@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// providerErrorBody covers the in-body error shapes providers return, some
+// of them with a 200 status: OpenAI-style {"error": {...}} or {"error": "..."},
+// and Gemini's {"error": {"status": ...}} or a blocked prompt's promptFeedback
+type providerErrorBody struct {
+	Error          json.RawMessage `json:"error"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+}
+
+// providerErrorDetail is the object form of an in-body error
+type providerErrorDetail struct {
+	Message string          `json:"message"`
+	Type    string          `json:"type"`
+	Status  string          `json:"status"`
+	Code    json.RawMessage `json:"code"` // A string for OpenAI-style providers, a number for Gemini
+}
+
+// contentFilterFinishes are finish reasons meaning the provider withheld output
+var contentFilterFinishes = map[string]bool{
+	"content_filter":     true,
+	"safety":             true,
+	"recitation":         true,
+	"blocklist":          true,
+	"prohibited_content": true,
+	"spii":               true,
+}
+
+// checkErrorBody returns a typed error when a response body carries a
+// provider error instead of a completion
+func checkErrorBody(body []byte) error {
+	var errorBody providerErrorBody
+	if err := json.Unmarshal(body, &errorBody); err != nil {
+		// Unparseable bodies are reported by the adapter
+		return nil
+	}
+
+	if errorBody.PromptFeedback != nil && errorBody.PromptFeedback.BlockReason != "" {
+		return fmt.Errorf("%w: prompt blocked (%s)", ErrContentFiltered, errorBody.PromptFeedback.BlockReason)
+	}
+
+	raw := strings.TrimSpace(string(errorBody.Error))
+	if raw == "" || raw == "null" {
+		return nil
+	}
+
+	var message string
+	var detail providerErrorDetail
+	if err := json.Unmarshal(errorBody.Error, &detail); err == nil {
+		message = detail.Message
+		kind := strings.ToLower(detail.Type + " " + detail.Status + " " + strings.Trim(string(detail.Code), `"`))
+		if strings.Contains(kind, "rate_limit") || strings.Contains(kind, "resource_exhausted") || strings.Contains(kind, "429") {
+			return fmt.Errorf("%w: %s", ErrRateLimited, message)
+		}
+	} else if err := json.Unmarshal(errorBody.Error, &message); err != nil {
+		message = raw
+	}
+
+	if message == "" {
+		message = raw
+	}
+	return fmt.Errorf("%w: provider returned an error: %s", ErrProviderFailed, message)
+}
+
+// checkFinishReason returns a typed error for finishes that carry no usable output
+func checkFinishReason(finishReason string) error {
+	reason := strings.ToLower(finishReason)
+	switch {
+	case contentFilterFinishes[reason]:
+		return fmt.Errorf("%w: finish reason %s", ErrContentFiltered, finishReason)
+	case reason == "error":
+		return fmt.Errorf("%w: generation stopped with an error", ErrProviderFailed)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckErrorBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		want        error
+		wantMessage string
+	}{
+		{"completion", `{"choices":[{"message":{"content":"hi"}}]}`, nil, ""},
+		{"null error", `{"error":null,"choices":[]}`, nil, ""},
+		{"not json", `<html>`, nil, ""},
+		{"openai object", `{"error":{"message":"model overloaded","type":"server_error"}}`, ErrProviderFailed, "model overloaded"},
+		{"string error", `{"error":"upstream timeout"}`, ErrProviderFailed, "upstream timeout"},
+		{"rate limit type", `{"error":{"message":"slow down","type":"rate_limit_exceeded"}}`, ErrRateLimited, "slow down"},
+		{"gemini status", `{"error":{"code":429,"message":"quota","status":"RESOURCE_EXHAUSTED"}}`, ErrRateLimited, "quota"},
+		{"gemini blocked prompt", `{"promptFeedback":{"blockReason":"SAFETY"}}`, ErrContentFiltered, "SAFETY"},
+		{"object without message", `{"error":{"type":"server_error"}}`, ErrProviderFailed, "server_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkErrorBody([]byte(tt.body))
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("checkErrorBody = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) || !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("checkErrorBody = %v, want %v mentioning %q", err, tt.want, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestCheckFinishReason(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   error
+	}{
+		{"stop", nil},
+		{"length", nil},
+		{"", nil},
+		{"content_filter", ErrContentFiltered},
+		{"SAFETY", ErrContentFiltered},
+		{"RECITATION", ErrContentFiltered},
+		{"error", ErrProviderFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			if err := checkFinishReason(tt.reason); !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+				t.Errorf("checkFinishReason(%q) = %v, want %v", tt.reason, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateRejectsInvalidResponses(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want error
+	}{
+		{"200 with error body", `{"error":{"message":"internal failure","type":"server_error"}}`, ErrProviderFailed},
+		{"content filtered", `{"choices":[{"message":{"role":"assistant","content":""},"finish_reason":"content_filter"}]}`, ErrContentFiltered},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := newTestConfig(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.body)
+			}))
+
+			_, err := NewAIClientWithConfig(config).GenerateDetailedWithProvider(context.Background(), "groq", testMessages)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
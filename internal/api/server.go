@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/orchestrator"
+	"github.com/niko/mqtt-agent-orchestration/internal/rag"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// RequestTimeout bounds how long a single API request may query its backends
+const RequestTimeout = 10 * time.Second
+
+// WorkerSource provides the latest known status of each worker
+type WorkerSource interface {
+	Workers() []types.ExtendedWorkerStatus
+}
+
+// WorkflowSource provides orchestrator workflow state
+type WorkflowSource interface {
+	ListWorkflows() []orchestrator.Workflow
+	WorkflowHistory() []orchestrator.WorkflowSummary
+}
+
+// CollectionSource provides knowledge base collection statistics
+type CollectionSource interface {
+	CollectionStats(ctx context.Context) ([]rag.CollectionStats, error)
+}
+
+// Server exposes read-only JSON endpoints for operators. Any source may be
+// nil, in which case its endpoint reports 503 Service Unavailable.
+type Server struct {
+	workers     WorkerSource
+	workflows   WorkflowSource
+	collections CollectionSource
+	mux         *http.ServeMux
+}
+
+// NewServer creates an API server backed by the given sources
+func NewServer(workers WorkerSource, workflows WorkflowSource, collections CollectionSource) *Server {
+	s := &Server{
+		workers:     workers,
+		workflows:   workflows,
+		collections: collections,
+		mux:         http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /workers", s.handleWorkers)
+	s.mux.HandleFunc("GET /workflows", s.handleWorkflows)
+	s.mux.HandleFunc("GET /workflows/history", s.handleWorkflowHistory)
+	s.mux.HandleFunc("GET /rag/collections", s.handleCollections)
+	s.mux.Handle("GET /debug/vars", expvar.Handler())
+	return s
+}
+
+// Handler returns the HTTP handler serving the API
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// handleWorkers lists worker status
+func (s *Server) handleWorkers(w http.ResponseWriter, r *http.Request) {
+	if s.workers == nil {
+		http.Error(w, "worker status not available", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.workers.Workers())
+}
+
+// handleWorkflows lists orchestrator workflows
+func (s *Server) handleWorkflows(w http.ResponseWriter, r *http.Request) {
+	if s.workflows == nil {
+		http.Error(w, "workflow state not available", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.workflows.ListWorkflows())
+}
+
+// handleWorkflowHistory lists summaries of workflows evicted after retention
+func (s *Server) handleWorkflowHistory(w http.ResponseWriter, r *http.Request) {
+	if s.workflows == nil {
+		http.Error(w, "workflow state not available", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.workflows.WorkflowHistory())
+}
+
+// handleCollections lists knowledge base collection statistics
+func (s *Server) handleCollections(w http.ResponseWriter, r *http.Request) {
+	if s.collections == nil {
+		http.Error(w, "RAG statistics not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), RequestTimeout)
+	defer cancel()
+
+	stats, err := s.collections.CollectionStats(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write API response: %v", err)
+	}
+}
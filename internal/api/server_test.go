@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/orchestrator"
+	"github.com/niko/mqtt-agent-orchestration/internal/rag"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// stubWorkers serves a fixed worker list
+type stubWorkers []types.ExtendedWorkerStatus
+
+func (s stubWorkers) Workers() []types.ExtendedWorkerStatus { return s }
+
+// stubWorkflows serves fixed orchestrator state
+type stubWorkflows struct {
+	workflows []orchestrator.Workflow
+	history   []orchestrator.WorkflowSummary
+}
+
+func (s stubWorkflows) ListWorkflows() []orchestrator.Workflow          { return s.workflows }
+func (s stubWorkflows) WorkflowHistory() []orchestrator.WorkflowSummary { return s.history }
+
+// stubCollections serves fixed collection statistics or fails with err
+type stubCollections struct {
+	stats []rag.CollectionStats
+	err   error
+}
+
+func (s stubCollections) CollectionStats(context.Context) ([]rag.CollectionStats, error) {
+	return s.stats, s.err
+}
+
+// get requests path from server and returns the response
+func get(t *testing.T, server *Server, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	return recorder
+}
+
+func TestServerEndpoints(t *testing.T) {
+	workers := stubWorkers{{WorkerStatus: types.WorkerStatus{ID: "w1"}, Role: types.RoleDeveloper}}
+	workflows := stubWorkflows{
+		workflows: []orchestrator.Workflow{{ID: "wf-1", Type: "api_guide", Stage: types.StageReview}},
+		history:   []orchestrator.WorkflowSummary{{ID: "wf-0", Type: "api_guide", Stage: types.StageCompleted, RetryCount: 1}},
+	}
+	collections := stubCollections{stats: []rag.CollectionStats{{Name: "coding_standards", Points: 12, Status: "green"}}}
+	server := NewServer(workers, workflows, collections)
+
+	tests := []struct {
+		path string
+		want map[string]any // Fields of the single listed item
+	}{
+		{"/workers", map[string]any{"id": "w1", "role": "developer"}},
+		{"/workflows", map[string]any{"id": "wf-1", "type": "api_guide", "stage": "review"}},
+		{"/workflows/history", map[string]any{"id": "wf-0", "stage": "completed", "retry_count": 1.0}},
+		{"/rag/collections", map[string]any{"name": "coding_standards", "points": 12.0, "status": "green"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			response := get(t, server, tt.path)
+			if response.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200: %s", response.Code, response.Body)
+			}
+			if ct := response.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+
+			var items []map[string]any
+			if err := json.Unmarshal(response.Body.Bytes(), &items); err != nil {
+				t.Fatalf("decode %s: %v", response.Body, err)
+			}
+			if len(items) != 1 {
+				t.Fatalf("got %d items, want 1", len(items))
+			}
+			for field, want := range tt.want {
+				if items[0][field] != want {
+					t.Errorf("%s = %v, want %v", field, items[0][field], want)
+				}
+			}
+		})
+	}
+}
+
+func TestServerUnavailableSources(t *testing.T) {
+	tests := []struct {
+		name   string
+		server *Server
+		path   string
+		want   int
+	}{
+		{"no workers", NewServer(nil, nil, nil), "/workers", http.StatusServiceUnavailable},
+		{"no workflows", NewServer(nil, nil, nil), "/workflows", http.StatusServiceUnavailable},
+		{"no collections", NewServer(nil, nil, nil), "/rag/collections", http.StatusServiceUnavailable},
+		{"collections fail", NewServer(nil, nil, stubCollections{err: errors.New("qdrant down")}), "/rag/collections", http.StatusBadGateway},
+		{"read only", NewServer(stubWorkers{}, nil, nil), "/workers", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if response := get(t, tt.server, tt.path); response.Code != tt.want {
+				t.Errorf("GET %s = %d, want %d", tt.path, response.Code, tt.want)
+			}
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	NewServer(stubWorkers{}, nil, nil).Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/workers", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /workers = %d, want 405", recorder.Code)
+	}
+}
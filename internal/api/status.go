@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// WorkerStatusTopic matches the status topics published by every worker
+const WorkerStatusTopic = "workers/status/#"
+
+// DefaultStaleAfter evicts a worker after three missed 30s status updates
+const DefaultStaleAfter = 90 * time.Second
+
+// statusEntry pairs a worker status with when it was last received
+type statusEntry struct {
+	status     types.ExtendedWorkerStatus
+	receivedAt time.Time
+}
+
+// StatusAggregator tracks the latest status published by each worker and
+// forgets workers that stop reporting
+type StatusAggregator struct {
+	mqttClient mqtt.ClientInterface
+	staleAfter time.Duration
+	mu         sync.RWMutex
+	workers    map[string]statusEntry
+	now        func() time.Time
+}
+
+// NewStatusAggregator creates an aggregator that evicts workers silent for
+// longer than staleAfter
+func NewStatusAggregator(client mqtt.ClientInterface, staleAfter time.Duration) *StatusAggregator {
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+
+	return &StatusAggregator{
+		mqttClient: client,
+		staleAfter: staleAfter,
+		workers:    make(map[string]statusEntry),
+		now:        time.Now,
+	}
+}
+
+// Start subscribes to worker status updates and evicts stale workers until
+// ctx is cancelled
+func (a *StatusAggregator) Start(ctx context.Context) error {
+	if err := a.mqttClient.Subscribe(ctx, WorkerStatusTopic, a.HandleStatus); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", WorkerStatusTopic, err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(a.staleAfter / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				a.EvictStale()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// HandleStatus records a status message. Plain WorkerStatus payloads are
+// accepted too and simply carry no role information.
+func (a *StatusAggregator) HandleStatus(payload []byte) {
+	var status types.ExtendedWorkerStatus
+	if _, err := types.UnwrapMessage(payload, types.MessageTypeWorkerStatus, &status); err != nil {
+		log.Printf("Failed to unmarshal worker status: %v", err)
+		return
+	}
+
+	if status.ID == "" {
+		log.Printf("Ignoring worker status without an ID")
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.workers[status.ID] = statusEntry{status: status, receivedAt: a.now()}
+}
+
+// EvictStale removes workers whose last status is older than the staleness
+// window and returns how many were removed
+func (a *StatusAggregator) EvictStale() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	evicted := 0
+	for id, entry := range a.workers {
+		if now.Sub(entry.receivedAt) > a.staleAfter {
+			delete(a.workers, id)
+			evicted++
+		}
+	}
+
+	if evicted > 0 {
+		log.Printf("Evicted %d stale workers", evicted)
+	}
+	return evicted
+}
+
+// Workers returns the current status of every live worker, sorted by ID.
+// Workers that went stale since the last eviction pass are omitted.
+func (a *StatusAggregator) Workers() []types.ExtendedWorkerStatus {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	now := a.now()
+	workers := make([]types.ExtendedWorkerStatus, 0, len(a.workers))
+	for _, entry := range a.workers {
+		if now.Sub(entry.receivedAt) > a.staleAfter {
+			continue
+		}
+		workers = append(workers, entry.status)
+	}
+
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].ID < workers[j].ID
+	})
+	return workers
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// subscribeClient records the handler subscribed to each topic
+type subscribeClient struct {
+	mu       sync.Mutex
+	handlers map[string]mqtt.MessageHandler
+}
+
+func (c *subscribeClient) Connect(context.Context) error                 { return nil }
+func (c *subscribeClient) Disconnect()                                   {}
+func (c *subscribeClient) IsConnected() bool                             { return true }
+func (c *subscribeClient) Unsubscribe(context.Context, string) error     { return nil }
+func (c *subscribeClient) Publish(context.Context, string, []byte) error { return nil }
+
+func (c *subscribeClient) Subscribe(ctx context.Context, topic string, handler mqtt.MessageHandler) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.handlers == nil {
+		c.handlers = make(map[string]mqtt.MessageHandler)
+	}
+	c.handlers[topic] = handler
+	return nil
+}
+
+// statusPayload wraps a worker status as a worker publishes it
+func statusPayload(t *testing.T, id, state string) []byte {
+	t.Helper()
+	status := types.ExtendedWorkerStatus{WorkerStatus: types.WorkerStatus{ID: id, Status: state}, Role: types.RoleDeveloper}
+	data, err := types.WrapMessage(types.MessageTypeWorkerStatus, id, status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// newTestAggregator creates an aggregator on a settable clock
+func newTestAggregator(staleAfter time.Duration) (*StatusAggregator, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	aggregator := NewStatusAggregator(&subscribeClient{}, staleAfter)
+	aggregator.now = func() time.Time { return now }
+	return aggregator, &now
+}
+
+func TestStatusAggregatorHandleStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		payloads func(t *testing.T) [][]byte
+		want     map[string]string // Worker ID to status
+	}{
+		{"new workers", func(t *testing.T) [][]byte {
+			return [][]byte{statusPayload(t, "w2", "idle"), statusPayload(t, "w1", "busy")}
+		}, map[string]string{"w1": "busy", "w2": "idle"}},
+		{"latest status wins", func(t *testing.T) [][]byte {
+			return [][]byte{statusPayload(t, "w1", "idle"), statusPayload(t, "w1", "busy")}
+		}, map[string]string{"w1": "busy"}},
+		{"plain status", func(t *testing.T) [][]byte {
+			return [][]byte{[]byte(`{"id":"w1","status":"idle"}`)}
+		}, map[string]string{"w1": "idle"}},
+		{"ignored", func(t *testing.T) [][]byte {
+			return [][]byte{[]byte(`not json`), []byte(`{"status":"idle"}`)}
+		}, map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aggregator, _ := newTestAggregator(time.Minute)
+			for _, payload := range tt.payloads(t) {
+				aggregator.HandleStatus(payload)
+			}
+
+			workers := aggregator.Workers()
+			if len(workers) != len(tt.want) {
+				t.Fatalf("got %d workers, want %d", len(workers), len(tt.want))
+			}
+			for i, worker := range workers {
+				if i > 0 && workers[i-1].ID >= worker.ID {
+					t.Errorf("workers not sorted by ID: %s before %s", workers[i-1].ID, worker.ID)
+				}
+				if worker.Status != tt.want[worker.ID] {
+					t.Errorf("%s status = %q, want %q", worker.ID, worker.Status, tt.want[worker.ID])
+				}
+			}
+		})
+	}
+}
+
+func TestStatusAggregatorEvictsStaleWorkers(t *testing.T) {
+	aggregator, now := newTestAggregator(time.Minute)
+	aggregator.HandleStatus(statusPayload(t, "w1", "idle"))
+	*now = now.Add(45 * time.Second)
+	aggregator.HandleStatus(statusPayload(t, "w2", "idle"))
+
+	// w1 is past the window: hidden at once, removed on the next pass
+	*now = now.Add(30 * time.Second)
+	if workers := aggregator.Workers(); len(workers) != 1 || workers[0].ID != "w2" {
+		t.Errorf("Workers = %+v, want only w2", workers)
+	}
+	if evicted := aggregator.EvictStale(); evicted != 1 {
+		t.Errorf("EvictStale = %d, want 1", evicted)
+	}
+
+	// A stale worker that reports again comes back
+	aggregator.HandleStatus(statusPayload(t, "w1", "busy"))
+	if workers := aggregator.Workers(); len(workers) != 2 {
+		t.Errorf("Workers = %+v, want both workers", workers)
+	}
+}
+
+func TestStatusAggregatorServesWorkersEndpoint(t *testing.T) {
+	client := &subscribeClient{}
+	aggregator := NewStatusAggregator(client, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := aggregator.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	handler := client.handlers[WorkerStatusTopic]
+	if handler == nil {
+		t.Fatalf("no subscription to %s", WorkerStatusTopic)
+	}
+	handler(statusPayload(t, "w1", "busy"))
+
+	response := get(t, NewServer(aggregator, nil, nil), "/workers")
+	var workers []types.ExtendedWorkerStatus
+	if err := json.Unmarshal(response.Body.Bytes(), &workers); err != nil {
+		t.Fatalf("decode %s: %v", response.Body, err)
+	}
+	if len(workers) != 1 || workers[0].ID != "w1" || workers[0].Status != "busy" || workers[0].Role != types.RoleDeveloper {
+		t.Errorf("/workers = %+v", workers)
+	}
+}
+
+func TestStatusAggregatorConcurrent(t *testing.T) {
+	aggregator := NewStatusAggregator(&subscribeClient{}, time.Minute)
+	payloads := make([][]byte, 16)
+	for i := range payloads {
+		payloads[i] = statusPayload(t, fmt.Sprintf("w%02d", i), "idle")
+	}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				aggregator.HandleStatus(payloads[(i+j)%len(payloads)])
+				aggregator.Workers()
+				aggregator.EvictStale()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if workers := aggregator.Workers(); len(workers) != len(payloads) {
+		t.Errorf("got %d workers, want %d", len(workers), len(payloads))
+	}
+}
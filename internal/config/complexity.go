@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ComplexityConfig sets the keyword weights and thresholds used to score task
+// complexity. Every keyword found in a task adds its weight to the score:
+// positive weights push toward external APIs, negative toward local models.
+type ComplexityConfig struct {
+	// Keywords maps a lowercase keyword to its weight
+	Keywords map[string]int `yaml:"keywords"`
+
+	// HighThreshold is the score at or above which a task is high complexity
+	HighThreshold int `yaml:"high_threshold"`
+
+	// SimpleThreshold is the score at or below which a task is simple
+	SimpleThreshold int `yaml:"simple_threshold"`
+}
+
+// DefaultComplexityConfig returns weights equivalent to the original keyword
+// tiers: one high keyword is enough for high complexity and one simple
+// keyword for simple, but mixed keywords now add up instead of the first
+// match winning
+func DefaultComplexityConfig() *ComplexityConfig {
+	keywords := make(map[string]int)
+	for _, keyword := range []string{
+		"architecture", "design", "review", "security", "analysis",
+		"refactor", "optimization", "performance", "complex", "comprehensive",
+		"strategy", "planning", "evaluation", "assessment",
+	} {
+		keywords[keyword] = 3
+	}
+	for _, keyword := range []string{
+		"implement", "create", "generate", "modify", "update",
+		"integration", "testing", "validation", "debugging",
+	} {
+		keywords[keyword] = 1
+	}
+	for _, keyword := range []string{
+		"format", "lint", "syntax", "simple", "basic", "quick",
+		"echo", "status", "info", "list", "search", "read", "write",
+		"mcp", "tool", "file operation", "git operation",
+	} {
+		keywords[keyword] = -2
+	}
+
+	return &ComplexityConfig{
+		Keywords:        keywords,
+		HighThreshold:   3,
+		SimpleThreshold: -2,
+	}
+}
+
+// LoadComplexityConfig loads complexity scoring from a YAML file. A file that
+// lists keywords replaces the default keyword set rather than extending it.
+func LoadComplexityConfig(configPath string) (*ComplexityConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read complexity configuration: %w", err)
+	}
+
+	config := DefaultComplexityConfig()
+	defaultKeywords := config.Keywords
+	config.Keywords = nil
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse complexity configuration: %w", err)
+	}
+	if len(config.Keywords) == 0 {
+		config.Keywords = defaultKeywords
+	}
+
+	if err := validateComplexityConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid complexity configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+// validateComplexityConfig validates the complexity configuration and
+// normalizes keywords to lowercase
+func validateComplexityConfig(config *ComplexityConfig) error {
+	if config.HighThreshold <= config.SimpleThreshold {
+		return fmt.Errorf("high_threshold (%d) must be greater than simple_threshold (%d)",
+			config.HighThreshold, config.SimpleThreshold)
+	}
+
+	keywords := make(map[string]int, len(config.Keywords))
+	for keyword, weight := range config.Keywords {
+		normalized := strings.ToLower(strings.TrimSpace(keyword))
+		if normalized == "" {
+			return fmt.Errorf("keywords: empty keyword")
+		}
+		if _, exists := keywords[normalized]; exists {
+			return fmt.Errorf("keywords: %q listed twice", normalized)
+		}
+		keywords[normalized] = weight
+	}
+	config.Keywords = keywords
+
+	return nil
+}
+
+// Score sums the weights of the keywords found in text. Each keyword counts
+// once however often it appears.
+func (c *ComplexityConfig) Score(text string) int {
+	text = strings.ToLower(text)
+	score := 0
+	for keyword, weight := range c.Keywords {
+		if strings.Contains(text, keyword) {
+			score += weight
+		}
+	}
+	return score
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestComplexityScore(t *testing.T) {
+	config := DefaultComplexityConfig()
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"implement a complex security refactor", 1 + 3 + 3 + 3},
+		{"implement the handler", 1},
+		{"quick format", -4},
+		{"Security SECURITY security", 3}, // Each keyword counts once
+		{"hello", 0},
+	}
+
+	for _, tt := range tests {
+		if got := config.Score(tt.text); got != tt.want {
+			t.Errorf("Score(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLoadComplexityConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		text    string
+		want    int
+		wantErr string
+	}{
+		{"tuned keywords", "high_threshold: 5\nsimple_threshold: -1\nkeywords:\n  Migration: 4\n  docs: -1\n", "migration docs", 3, ""},
+		{"keywords replace defaults", "keywords:\n  migration: 4\n", "security refactor", 0, ""},
+		{"defaults kept", "high_threshold: 6\n", "security refactor", 6, ""},
+		{"thresholds inverted", "high_threshold: -2\nsimple_threshold: 3\n", "", 0, "must be greater than"},
+		{"duplicate keyword", "keywords:\n  Docs: 1\n  docs: 2\n", "", 0, "listed twice"},
+		{"empty keyword", "keywords:\n  \" \": 1\n", "", 0, "empty keyword"},
+		{"bad yaml", "keywords: [\n", "", 0, "failed to parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := LoadComplexityConfig(writeConfig(t, "complexity.yaml", tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadComplexityConfig: %v", err)
+			}
+			if got := config.Score(tt.text); got != tt.want {
+				t.Errorf("Score(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepositoryComplexityConfig(t *testing.T) {
+	config, err := LoadComplexityConfig("../../configs/complexity.yaml")
+	if err != nil {
+		t.Fatalf("configs/complexity.yaml: %v", err)
+	}
+	defaults := DefaultComplexityConfig()
+	for _, text := range []string{"implement a complex security refactor", "quick format", "implement the handler"} {
+		if got, want := config.Score(text), defaults.Score(text); got != want {
+			t.Errorf("Score(%q) = %d, want the built-in %d", text, got, want)
+		}
+	}
+}
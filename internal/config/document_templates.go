@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DocumentTemplate describes a document type the workflow can produce and
+// the minimum the tester requires of it
+type DocumentTemplate struct {
+	Description      string   `yaml:"description"`
+	MinLength        int      `yaml:"min_length"`        // Minimum document length in characters; zero disables
+	RequiredSections []string `yaml:"required_sections"` // Headings that must appear in the document
+	Language         string   `yaml:"language"`          // Language code examples must be written in; empty allows any
+}
+
+// DocumentTemplateConfig is the registry of supported document types, keyed
+// by the document_type payload value
+type DocumentTemplateConfig struct {
+	Templates map[string]DocumentTemplate `yaml:"document_templates"`
+}
+
+// DefaultDocumentTemplateConfig returns the document types assumed when no config file is present
+func DefaultDocumentTemplateConfig() *DocumentTemplateConfig {
+	return &DocumentTemplateConfig{
+		Templates: map[string]DocumentTemplate{
+			"go_coding_standards": {
+				Description:      "Go coding standards",
+				MinLength:        2000,
+				RequiredSections: []string{"Core Principles", "Error Handling", "Testing Standards", "Compliance Checklist"},
+				Language:         "go",
+			},
+			"python_coding_standards": {Description: "Python coding standards", MinLength: 2000, Language: "python"},
+			"bash_coding_standards":   {Description: "Bash coding standards", MinLength: 2000, Language: "bash"},
+			"project_documentation":   {Description: "Project documentation", MinLength: 1000},
+			"api_documentation":       {Description: "API documentation", MinLength: 1000},
+		},
+	}
+}
+
+// LoadDocumentTemplateConfig loads the document template registry from a YAML file
+func LoadDocumentTemplateConfig(configPath string) (*DocumentTemplateConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document template configuration: %w", err)
+	}
+
+	var config DocumentTemplateConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse document template configuration: %w", err)
+	}
+
+	if err := validateDocumentTemplateConfig(&config); err != nil {
+		return nil, fmt.Errorf("invalid document template configuration: %w", err)
+	}
+
+	return &config, nil
+}
+
+// validateDocumentTemplateConfig validates the document template configuration
+func validateDocumentTemplateConfig(config *DocumentTemplateConfig) error {
+	if len(config.Templates) == 0 {
+		return fmt.Errorf("no document templates defined in configuration")
+	}
+
+	for documentType, template := range config.Templates {
+		if template.MinLength < 0 {
+			return fmt.Errorf("document template %s: min_length must not be negative", documentType)
+		}
+		for _, section := range template.RequiredSections {
+			if section == "" {
+				return fmt.Errorf("document template %s: empty required section", documentType)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetTemplate returns the template for a document type
+func (c *DocumentTemplateConfig) GetTemplate(documentType string) (DocumentTemplate, bool) {
+	template, exists := c.Templates[documentType]
+	return template, exists
+}
+
+// DocumentTypes returns the registered document types in sorted order
+func (c *DocumentTemplateConfig) DocumentTypes() []string {
+	documentTypes := make([]string, 0, len(c.Templates))
+	for documentType := range c.Templates {
+		documentTypes = append(documentTypes, documentType)
+	}
+	sort.Strings(documentTypes)
+	return documentTypes
+}
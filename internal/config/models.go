@@ -22,6 +22,9 @@ type ManagerConfig struct {
 	MaxGPUMemory    uint64        `yaml:"max_gpu_memory"`
 	NvidiaSMIPath   string        `yaml:"nvidia_smi_path"`
 	MonitorInterval time.Duration `yaml:"monitor_interval"`
+
+	// MaxConcurrentInference bounds simultaneous predictions per model
+	MaxConcurrentInference int `yaml:"max_concurrent_inference"`
 }
 
 // FallbackConfig holds fallback configuration
@@ -86,6 +89,10 @@ func validateModelConfig(config *ModelConfig) error {
 		return fmt.Errorf("max_gpu_memory must be greater than 0")
 	}
 
+	if config.Manager.MaxConcurrentInference < 0 {
+		return fmt.Errorf("max_concurrent_inference must be non-negative")
+	}
+
 	if config.Manager.MonitorInterval == 0 {
 		config.Manager.MonitorInterval = 30 * time.Second // Default value
 	}
@@ -136,6 +143,8 @@ func (mc *ModelConfig) GetModelManagerConfig() localmodels.ModelManagerConfig {
 		NvidiaSMIPath:   mc.Manager.NvidiaSMIPath,
 		MonitorInterval: mc.Manager.MonitorInterval,
 		Models:          mc.Models,
+
+		MaxConcurrentInference: mc.Manager.MaxConcurrentInference,
 	}
 }
 
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prompt sections that may be trimmed to fit the token budget
+const (
+	PromptSectionRAGContext     = "rag_context"
+	PromptSectionPreviousOutput = "previous_output"
+)
+
+// PromptBudgetConfig caps assembled prompt size and sets which sections are
+// trimmed first when a prompt exceeds it
+type PromptBudgetConfig struct {
+	// MaxPromptTokens is the estimated token budget for a whole prompt; zero disables the cap
+	MaxPromptTokens int `yaml:"max_prompt_tokens"`
+
+	// TruncationOrder lists sections in the order they are trimmed
+	TruncationOrder []string `yaml:"truncation_order"`
+}
+
+// DefaultPromptBudgetConfig returns a budget that fits the default 8192 token
+// context window with room left for the response
+func DefaultPromptBudgetConfig() *PromptBudgetConfig {
+	return &PromptBudgetConfig{
+		MaxPromptTokens: 6000,
+		TruncationOrder: []string{PromptSectionRAGContext, PromptSectionPreviousOutput},
+	}
+}
+
+// LoadPromptBudgetConfig loads the prompt budget from a YAML file
+func LoadPromptBudgetConfig(configPath string) (*PromptBudgetConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt budget configuration: %w", err)
+	}
+
+	config := DefaultPromptBudgetConfig()
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt budget configuration: %w", err)
+	}
+
+	if err := validatePromptBudgetConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid prompt budget configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+// validatePromptBudgetConfig validates the prompt budget configuration
+func validatePromptBudgetConfig(config *PromptBudgetConfig) error {
+	if config.MaxPromptTokens < 0 {
+		return fmt.Errorf("max_prompt_tokens must not be negative")
+	}
+
+	for i, section := range config.TruncationOrder {
+		if section != PromptSectionRAGContext && section != PromptSectionPreviousOutput {
+			return fmt.Errorf("truncation_order: unknown section %q", section)
+		}
+		if slices.Contains(config.TruncationOrder[:i], section) {
+			return fmt.Errorf("truncation_order: section %q listed twice", section)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadPromptBudgetConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		wantMax   int
+		wantOrder []string
+		wantErr   string
+	}{
+		{"configured", "max_prompt_tokens: 3000\ntruncation_order: [previous_output, rag_context]\n", 3000, []string{PromptSectionPreviousOutput, PromptSectionRAGContext}, ""},
+		{"defaults kept", "max_prompt_tokens: 3000\n", 3000, []string{PromptSectionRAGContext, PromptSectionPreviousOutput}, ""},
+		{"disabled", "max_prompt_tokens: 0\n", 0, []string{PromptSectionRAGContext, PromptSectionPreviousOutput}, ""},
+		{"negative", "max_prompt_tokens: -1\n", 0, nil, "must not be negative"},
+		{"unknown section", "truncation_order: [system_prompt]\n", 0, nil, `unknown section "system_prompt"`},
+		{"duplicate section", "truncation_order: [rag_context, rag_context]\n", 0, nil, "listed twice"},
+		{"bad yaml", "max_prompt_tokens: [\n", 0, nil, "failed to parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := LoadPromptBudgetConfig(writeConfig(t, "prompt_budget.yaml", tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPromptBudgetConfig: %v", err)
+			}
+			if config.MaxPromptTokens != tt.wantMax || !slices.Equal(config.TruncationOrder, tt.wantOrder) {
+				t.Errorf("config = %+v, want max %d and order %v", config, tt.wantMax, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestRepositoryPromptBudgetConfig(t *testing.T) {
+	if _, err := LoadPromptBudgetConfig("../../configs/prompt_budget.yaml"); err != nil {
+		t.Errorf("configs/prompt_budget.yaml: %v", err)
+	}
+}
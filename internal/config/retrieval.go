@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RetrievalSettings controls how much RAG context is retrieved for a task
+type RetrievalSettings struct {
+	TopK      int     `yaml:"top_k"`
+	Threshold float64 `yaml:"threshold"`
+}
+
+// RetrievalConfig maps task types to retrieval settings
+type RetrievalConfig struct {
+	Default   RetrievalSettings            `yaml:"default"`
+	TaskTypes map[string]RetrievalSettings `yaml:"task_types"`
+
+	// EmbeddingCacheSize is the number of query/prompt vectors kept in memory; zero disables caching
+	EmbeddingCacheSize int `yaml:"embedding_cache_size"`
+
+	// SearchCacheSize is the number of search responses kept in memory; zero disables caching
+	SearchCacheSize int `yaml:"search_cache_size"`
+
+	// SearchCacheTTL is how long a cached search response is served; zero disables caching
+	SearchCacheTTL time.Duration `yaml:"search_cache_ttl"`
+
+	// Timeout bounds a single retrieval; zero leaves only the task deadline
+	Timeout time.Duration `yaml:"timeout"`
+
+	// TimeoutFraction caps a retrieval at this share of the time left before the task deadline; zero disables
+	TimeoutFraction float64 `yaml:"timeout_fraction"`
+}
+
+// DefaultRetrievalConfig returns the settings used when no config file is present
+func DefaultRetrievalConfig() *RetrievalConfig {
+	return &RetrievalConfig{
+		Default: RetrievalSettings{
+			TopK:      3,
+			Threshold: 0.5,
+		},
+		TaskTypes:          make(map[string]RetrievalSettings),
+		EmbeddingCacheSize: 256,
+		SearchCacheSize:    128,
+		SearchCacheTTL:     5 * time.Minute,
+		Timeout:            15 * time.Second,
+		TimeoutFraction:    0.25,
+	}
+}
+
+// LoadRetrievalConfig loads retrieval configuration from a YAML file
+func LoadRetrievalConfig(configPath string) (*RetrievalConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read retrieval configuration: %w", err)
+	}
+
+	config := DefaultRetrievalConfig()
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse retrieval configuration: %w", err)
+	}
+
+	if err := validateRetrievalConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid retrieval configuration: %w", err)
+	}
+
+	return config, nil
+}
+
+// validateRetrievalConfig validates the retrieval configuration
+func validateRetrievalConfig(config *RetrievalConfig) error {
+	if config.EmbeddingCacheSize < 0 {
+		return fmt.Errorf("embedding_cache_size must not be negative")
+	}
+	if config.SearchCacheSize < 0 {
+		return fmt.Errorf("search_cache_size must not be negative")
+	}
+	if config.SearchCacheTTL < 0 {
+		return fmt.Errorf("search_cache_ttl must not be negative")
+	}
+
+	if config.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if config.TimeoutFraction < 0 || config.TimeoutFraction > 1 {
+		return fmt.Errorf("timeout_fraction must be between 0 and 1")
+	}
+
+	if err := validateRetrievalSettings("default", config.Default); err != nil {
+		return err
+	}
+
+	for taskType, settings := range config.TaskTypes {
+		if err := validateRetrievalSettings(taskType, settings); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateRetrievalSettings checks TopK and Threshold bounds
+func validateRetrievalSettings(name string, settings RetrievalSettings) error {
+	if settings.TopK <= 0 {
+		return fmt.Errorf("%s: top_k must be positive", name)
+	}
+	if settings.Threshold < 0 || settings.Threshold > 1 {
+		return fmt.Errorf("%s: threshold must be between 0 and 1", name)
+	}
+	return nil
+}
+
+// ForTaskType returns the settings for a task type, falling back to the default
+func (rc *RetrievalConfig) ForTaskType(taskType string) RetrievalSettings {
+	if settings, exists := rc.TaskTypes[strings.ToLower(taskType)]; exists {
+		return settings
+	}
+	return rc.Default
+}
+
+// RetrievalTimeout returns the time allowed for one retrieval given the time
+// remaining before the task deadline, or zero when neither limit applies
+func (rc *RetrievalConfig) RetrievalTimeout(remaining time.Duration) time.Duration {
+	timeout := rc.Timeout
+	if remaining > 0 && rc.TimeoutFraction > 0 {
+		share := time.Duration(float64(remaining) * rc.TimeoutFraction)
+		if timeout == 0 || share < timeout {
+			timeout = share
+		}
+	}
+	return timeout
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadRetrievalConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"task types", "default:\n  top_k: 4\n  threshold: 0.4\ntask_types:\n  review:\n    top_k: 2\n    threshold: 0.7\n", ""},
+		{"defaults kept", "task_types:\n  review:\n    top_k: 2\n    threshold: 0.7\n", ""},
+		{"zero top k", "task_types:\n  review:\n    top_k: 0\n    threshold: 0.7\n", "review: top_k must be positive"},
+		{"threshold above one", "default:\n  top_k: 3\n  threshold: 1.5\n", "default: threshold must be between 0 and 1"},
+		{"negative cache", "embedding_cache_size: -1\n", "embedding_cache_size must not be negative"},
+		{"bad yaml", "default: [\n", "failed to parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := LoadRetrievalConfig(writeConfig(t, "retrieval.yaml", tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadRetrievalConfig: %v", err)
+			}
+			if got := config.ForTaskType("review"); got.TopK != 2 || got.Threshold != 0.7 {
+				t.Errorf("review settings = %+v", got)
+			}
+			if config.SearchCacheSize != DefaultRetrievalConfig().SearchCacheSize {
+				t.Errorf("SearchCacheSize = %d, want the default", config.SearchCacheSize)
+			}
+		})
+	}
+}
+
+func TestForTaskType(t *testing.T) {
+	config := DefaultRetrievalConfig()
+	config.TaskTypes["create_document"] = RetrievalSettings{TopK: 6, Threshold: 0.35}
+
+	tests := []struct {
+		taskType string
+		want     RetrievalSettings
+	}{
+		{"create_document", RetrievalSettings{TopK: 6, Threshold: 0.35}},
+		{"Create_Document", RetrievalSettings{TopK: 6, Threshold: 0.35}},
+		{"review", RetrievalSettings{TopK: 3, Threshold: 0.5}},
+		{"", RetrievalSettings{TopK: 3, Threshold: 0.5}},
+	}
+
+	for _, tt := range tests {
+		if got := config.ForTaskType(tt.taskType); got != tt.want {
+			t.Errorf("ForTaskType(%q) = %+v, want %+v", tt.taskType, got, tt.want)
+		}
+	}
+}
+
+func TestRepositoryRetrievalConfig(t *testing.T) {
+	config, err := LoadRetrievalConfig("../../configs/retrieval.yaml")
+	if err != nil {
+		t.Fatalf("LoadRetrievalConfig: %v", err)
+	}
+	if got := config.ForTaskType("create_document"); got.TopK != 6 {
+		t.Errorf("create_document TopK = %d, want 6", got.TopK)
+	}
+}
+
+func TestRetrievalTimeout(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		fraction  float64
+		remaining time.Duration
+		want      time.Duration
+	}{
+		{"no limits", 0, 0, time.Minute, 0},
+		{"fixed timeout", 10 * time.Second, 0, time.Minute, 10 * time.Second},
+		{"share of deadline", 0, 0.25, time.Minute, 15 * time.Second},
+		{"timeout below share", 10 * time.Second, 0.25, time.Minute, 10 * time.Second},
+		{"share below timeout", 20 * time.Second, 0.25, time.Minute, 15 * time.Second},
+		{"no deadline", 10 * time.Second, 0.25, 0, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &RetrievalConfig{Timeout: tt.timeout, TimeoutFraction: tt.fraction}
+			if got := config.RetrievalTimeout(tt.remaining); got != tt.want {
+				t.Errorf("RetrievalTimeout(%v) = %v, want %v", tt.remaining, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FilePlaceholder is replaced with the path of the extracted example in toolchain args
+const FilePlaceholder = "{file}"
+
+// Toolchain describes how to validate code examples for a single language
+type Toolchain struct {
+	Path      string   `yaml:"path"`
+	Args      []string `yaml:"args"`
+	Extension string   `yaml:"extension"`
+}
+
+// ToolchainConfig maps fenced code block language tags to their toolchains
+type ToolchainConfig struct {
+	Toolchains map[string]Toolchain `yaml:"toolchains"`
+}
+
+// DefaultToolchainConfig returns the toolchains assumed when no config file is present
+func DefaultToolchainConfig() *ToolchainConfig {
+	return &ToolchainConfig{
+		Toolchains: map[string]Toolchain{
+			"go": {
+				Path:      "go",
+				Args:      []string{"vet", FilePlaceholder},
+				Extension: ".go",
+			},
+			"python": {
+				Path:      "python3",
+				Args:      []string{"-m", "py_compile", FilePlaceholder},
+				Extension: ".py",
+			},
+			"bash": {
+				Path:      "shellcheck",
+				Args:      []string{FilePlaceholder},
+				Extension: ".sh",
+			},
+		},
+	}
+}
+
+// LoadToolchainConfig loads toolchain configuration from a YAML file
+func LoadToolchainConfig(configPath string) (*ToolchainConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read toolchain configuration: %w", err)
+	}
+
+	var config ToolchainConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse toolchain configuration: %w", err)
+	}
+
+	if err := validateToolchainConfig(&config); err != nil {
+		return nil, fmt.Errorf("invalid toolchain configuration: %w", err)
+	}
+
+	return &config, nil
+}
+
+// validateToolchainConfig validates the toolchain configuration
+func validateToolchainConfig(config *ToolchainConfig) error {
+	if len(config.Toolchains) == 0 {
+		return fmt.Errorf("no toolchains defined in configuration")
+	}
+
+	for language, toolchain := range config.Toolchains {
+		if toolchain.Path == "" {
+			return fmt.Errorf("toolchain %s: path is required", language)
+		}
+		if toolchain.Extension == "" {
+			return fmt.Errorf("toolchain %s: extension is required", language)
+		}
+		if !strings.HasPrefix(toolchain.Extension, ".") {
+			return fmt.Errorf("toolchain %s: extension must start with '.'", language)
+		}
+	}
+
+	return nil
+}
+
+// GetToolchain returns the toolchain for a language tag
+func (tc *ToolchainConfig) GetToolchain(language string) (Toolchain, bool) {
+	toolchain, exists := tc.Toolchains[strings.ToLower(language)]
+	return toolchain, exists
+}
+
+// BuildArgs returns the toolchain arguments with the file placeholder resolved.
+// If no placeholder is present the file path is appended.
+func (t Toolchain) BuildArgs(filePath string) []string {
+	args := make([]string, 0, len(t.Args)+1)
+	substituted := false
+	for _, arg := range t.Args {
+		if strings.Contains(arg, FilePlaceholder) {
+			arg = strings.ReplaceAll(arg, FilePlaceholder, filePath)
+			substituted = true
+		}
+		args = append(args, arg)
+	}
+
+	if !substituted {
+		args = append(args, filePath)
+	}
+
+	return args
+}
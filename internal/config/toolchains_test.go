@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a YAML configuration file and returns its path
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadToolchainConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", "toolchains:\n  go:\n    path: go\n    args: [vet, \"{file}\"]\n    extension: .go\n", ""},
+		{"empty", "toolchains: {}\n", "no toolchains defined"},
+		{"missing path", "toolchains:\n  go:\n    extension: .go\n", "path is required"},
+		{"missing extension", "toolchains:\n  go:\n    path: go\n", "extension is required"},
+		{"extension without dot", "toolchains:\n  go:\n    path: go\n    extension: go\n", "must start with '.'"},
+		{"bad yaml", "toolchains: [\n", "failed to parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadToolchainConfig(writeConfig(t, "toolchains.yaml", tt.content))
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("LoadToolchainConfig: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := LoadToolchainConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadToolchainConfig succeeded for a missing file")
+	}
+}
+
+func TestRepositoryToolchainConfig(t *testing.T) {
+	config, err := LoadToolchainConfig("../../configs/toolchains.yaml")
+	if err != nil {
+		t.Fatalf("LoadToolchainConfig: %v", err)
+	}
+	for _, language := range []string{"go", "python", "bash", "sh"} {
+		if _, exists := config.GetToolchain(language); !exists {
+			t.Errorf("no toolchain for %s", language)
+		}
+	}
+}
+
+func TestGetToolchainIgnoresCase(t *testing.T) {
+	config := DefaultToolchainConfig()
+	if toolchain, exists := config.GetToolchain("Go"); !exists || toolchain.Path != "go" {
+		t.Errorf("GetToolchain(Go) = %+v, %v", toolchain, exists)
+	}
+	if _, exists := config.GetToolchain("rust"); exists {
+		t.Error("GetToolchain(rust) found a toolchain")
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"placeholder", []string{"vet", FilePlaceholder}, []string{"vet", "/tmp/example.go"}},
+		{"embedded placeholder", []string{"--file=" + FilePlaceholder}, []string{"--file=/tmp/example.go"}},
+		{"appended", []string{"-n"}, []string{"-n", "/tmp/example.go"}},
+		{"no args", nil, []string{"/tmp/example.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Toolchain{Args: tt.args}).BuildArgs("/tmp/example.go"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildArgs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
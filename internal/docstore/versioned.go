@@ -0,0 +1,139 @@
+package docstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestSuffix is appended to a document path to name its version manifest
+const ManifestSuffix = ".versions.json"
+
+// Version records one write of a document
+type Version struct {
+	Version   int       `json:"version"`
+	Path      string    `json:"path"` // Where this version's content now lives
+	WrittenAt time.Time `json:"written_at"`
+	Size      int64     `json:"size"`
+	SHA256    string    `json:"sha256,omitempty"`
+}
+
+// Manifest lists every version of a document, oldest first. The last entry
+// is the current document; earlier ones were moved to <path>.vN.
+type Manifest struct {
+	Document string    `json:"document"`
+	Versions []Version `json:"versions"`
+}
+
+// Write writes content to path, creating parent directories and replacing
+// any existing file
+func Write(path string, content []byte) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	return nil
+}
+
+// WriteVersioned writes content to path after moving the existing document
+// to <path>.vN, and records the write in <path>.versions.json. It returns
+// the new version.
+func WriteVersioned(path string, content []byte) (Version, error) {
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		return Version{}, err
+	}
+
+	// Adopt a document written before versioning was enabled as version 1
+	if len(manifest.Versions) == 0 {
+		if info, err := os.Stat(path); err == nil {
+			manifest.Versions = append(manifest.Versions, Version{
+				Version:   1,
+				Path:      path,
+				WrittenAt: info.ModTime(),
+				Size:      info.Size(),
+			})
+		}
+	}
+
+	if count := len(manifest.Versions); count > 0 {
+		current := &manifest.Versions[count-1]
+		archivePath := fmt.Sprintf("%s.v%d", path, current.Version)
+		if err := os.Rename(path, archivePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return Version{}, fmt.Errorf("failed to archive version %d: %w", current.Version, err)
+		}
+		current.Path = archivePath
+	}
+
+	if err := Write(path, content); err != nil {
+		return Version{}, err
+	}
+
+	sum := sha256.Sum256(content)
+	version := Version{
+		Version:   nextVersion(manifest),
+		Path:      path,
+		WrittenAt: time.Now(),
+		Size:      int64(len(content)),
+		SHA256:    hex.EncodeToString(sum[:]),
+	}
+	manifest.Versions = append(manifest.Versions, version)
+
+	if err := saveManifest(path, manifest); err != nil {
+		return Version{}, err
+	}
+	return version, nil
+}
+
+// LoadManifest reads the version manifest for path. A document without a
+// manifest has an empty one.
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path + ManifestSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Manifest{Document: path}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse version manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// nextVersion returns the number for the next write
+func nextVersion(manifest *Manifest) int {
+	if len(manifest.Versions) == 0 {
+		return 1
+	}
+	return manifest.Versions[len(manifest.Versions)-1].Version + 1
+}
+
+// saveManifest writes the manifest atomically next to the document
+func saveManifest(path string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal version manifest: %w", err)
+	}
+
+	manifestPath := path + ManifestSuffix
+	tmpPath := manifestPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write version manifest: %w", err)
+	}
+	if err := os.Rename(tmpPath, manifestPath); err != nil {
+		return fmt.Errorf("failed to write version manifest: %w", err)
+	}
+	return nil
+}
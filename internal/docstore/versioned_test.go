@@ -0,0 +1,82 @@
+package docstore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteVersioned(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docs", "standards.md")
+
+	for i := 1; i <= 3; i++ {
+		version, err := WriteVersioned(path, []byte(fmt.Sprintf("draft %d", i)))
+		if err != nil {
+			t.Fatalf("WriteVersioned %d: %v", i, err)
+		}
+		if version.Version != i || version.Path != path || version.Size != int64(len("draft 1")) || version.SHA256 == "" {
+			t.Errorf("write %d returned %+v", i, version)
+		}
+	}
+
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest: %v", err)
+	}
+	if len(manifest.Versions) != 3 {
+		t.Fatalf("manifest has %d versions, want 3", len(manifest.Versions))
+	}
+
+	for i, version := range manifest.Versions {
+		wantPath := fmt.Sprintf("%s.v%d", path, i+1)
+		if i == 2 {
+			wantPath = path
+		}
+		if version.Version != i+1 || version.Path != wantPath {
+			t.Errorf("version %d = %+v, want path %s", i+1, version, wantPath)
+		}
+		content, err := os.ReadFile(version.Path)
+		if err != nil || string(content) != fmt.Sprintf("draft %d", i+1) {
+			t.Errorf("version %d content = %q, %v", i+1, content, err)
+		}
+	}
+}
+
+func TestWriteVersionedAdoptsExistingDocument(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "standards.md")
+	if err := Write(path, []byte("written before versioning")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	version, err := WriteVersioned(path, []byte("new"))
+	if err != nil {
+		t.Fatalf("WriteVersioned: %v", err)
+	}
+	if version.Version != 2 {
+		t.Errorf("Version = %d, want 2 after adopting the existing document", version.Version)
+	}
+	if content, err := os.ReadFile(path + ".v1"); err != nil || string(content) != "written before versioning" {
+		t.Errorf("archived version 1 = %q, %v", content, err)
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+
+	manifest, err := LoadManifest(filepath.Join(dir, "new.md"))
+	if err != nil || len(manifest.Versions) != 0 || manifest.Document != filepath.Join(dir, "new.md") {
+		t.Errorf("LoadManifest for a new document = %+v, %v", manifest, err)
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.md")
+	if err := os.WriteFile(corrupt+ManifestSuffix, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadManifest(corrupt); err == nil {
+		t.Error("LoadManifest accepted a corrupt manifest")
+	}
+	if _, err := WriteVersioned(corrupt, []byte("text")); err == nil {
+		t.Error("WriteVersioned wrote past a corrupt manifest")
+	}
+}
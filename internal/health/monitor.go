@@ -0,0 +1,270 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/ai"
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// SystemHealthTopic receives the retained SystemHealth aggregate
+const SystemHealthTopic = "system/health"
+
+// Monitor defaults
+const (
+	DefaultInterval = 30 * time.Second
+	CheckTimeout    = 10 * time.Second // Bounds the checks of one snapshot
+	PublishTimeout  = 5 * time.Second
+)
+
+// WorkerSource provides the latest status of each live worker
+type WorkerSource interface {
+	Workers() []types.ExtendedWorkerStatus
+}
+
+// RAGChecker reports whether the knowledge base answers
+type RAGChecker interface {
+	IsAvailable(ctx context.Context) bool
+}
+
+// ProviderSource provides the AI providers with an API key and those that
+// failed their latest health check
+type ProviderSource interface {
+	GetAvailableAPIs() map[string]ai.APIConfig
+	ProviderHealth() map[string]string
+}
+
+// GPUSource reads current GPU memory
+type GPUSource func(ctx context.Context) (localmodels.GPUMemoryInfo, error)
+
+// Sources are the subsystems a Monitor checks; any may be nil, in which
+// case its health is reported unknown
+type Sources struct {
+	Workers   WorkerSource
+	RAG       RAGChecker
+	Providers ProviderSource
+	GPU       GPUSource
+}
+
+// Config tunes what a Monitor considers degraded
+type Config struct {
+	Interval time.Duration // Between published snapshots
+
+	// RequiredRoles are expected to have at least one live worker
+	RequiredRoles []types.WorkerRole
+
+	// GPU memory is degraded when less than GPUHeadroom MB is free
+	GPUHeadroom uint64
+}
+
+// DefaultConfig returns a config expecting a worker for every workflow stage
+func DefaultConfig() Config {
+	return Config{
+		Interval:      DefaultInterval,
+		RequiredRoles: []types.WorkerRole{types.RoleDeveloper, types.RoleReviewer, types.RoleApprover, types.RoleTester},
+		GPUHeadroom:   localmodels.DefaultMemoryHeadroom,
+	}
+}
+
+// Monitor aggregates subsystem health into SystemHealth snapshots and
+// publishes them on SystemHealthTopic
+type Monitor struct {
+	mqttClient mqtt.ClientInterface
+	sources    Sources
+	config     Config
+	now        func() time.Time
+}
+
+// NewMonitor creates a monitor publishing through mqttClient
+func NewMonitor(mqttClient mqtt.ClientInterface, sources Sources, config Config) *Monitor {
+	return &Monitor{
+		mqttClient: mqttClient,
+		sources:    sources,
+		config:     config,
+		now:        time.Now,
+	}
+}
+
+// Run publishes a snapshot now and then every Interval until ctx is done
+func (m *Monitor) Run(ctx context.Context) {
+	interval := m.config.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Publish(ctx); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Publish checks every subsystem and publishes the snapshot, retained so
+// subscribers get the latest state as soon as they connect
+func (m *Monitor) Publish(ctx context.Context) error {
+	health := m.Snapshot(ctx)
+	if health.State != types.HealthOK {
+		log.Printf("System health %s: workers %s, rag %s, providers %s, gpu %s", health.State,
+			health.Workers.State, health.RAG.State, health.Providers.State, health.GPU.State)
+	}
+
+	data, err := types.WrapMessage(types.MessageTypeSystemHealth, "", health)
+	if err != nil {
+		return fmt.Errorf("failed to marshal system health: %w", err)
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, PublishTimeout)
+	defer cancel()
+
+	if publisher, ok := m.mqttClient.(mqtt.RetainedPublisher); ok {
+		err = publisher.PublishRetained(publishCtx, SystemHealthTopic, data)
+	} else {
+		err = m.mqttClient.Publish(publishCtx, SystemHealthTopic, data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to publish system health: %w", err)
+	}
+	return nil
+}
+
+// Snapshot checks every subsystem and aggregates the results
+func (m *Monitor) Snapshot(ctx context.Context) types.SystemHealth {
+	ctx, cancel := context.WithTimeout(ctx, CheckTimeout)
+	defer cancel()
+
+	health := types.SystemHealth{
+		CheckedAt: m.now(),
+		Workers:   types.WorkersHealth{State: types.HealthUnknown},
+		RAG:       types.RAGHealth{State: types.HealthUnknown},
+		Providers: types.ProvidersHealth{State: types.HealthUnknown},
+		GPU:       types.GPUHealth{State: types.HealthUnknown},
+	}
+
+	if m.sources.Workers != nil {
+		health.Workers = workersHealth(m.sources.Workers.Workers(), m.config.RequiredRoles)
+	}
+	if m.sources.RAG != nil {
+		health.RAG = ragHealth(m.sources.RAG.IsAvailable(ctx))
+	}
+	if m.sources.Providers != nil {
+		health.Providers = providersHealth(m.sources.Providers.GetAvailableAPIs(), m.sources.Providers.ProviderHealth())
+	}
+	if m.sources.GPU != nil {
+		info, err := m.sources.GPU(ctx)
+		health.GPU = gpuHealth(info, err, m.config.GPUHeadroom)
+	}
+
+	health.State = overallState(health.Workers.State, health.RAG.State, health.Providers.State, health.GPU.State)
+	return health
+}
+
+// workersHealth is down with no live worker and degraded when a required
+// role has none or a worker reports an error
+func workersHealth(workers []types.ExtendedWorkerStatus, required []types.WorkerRole) types.WorkersHealth {
+	health := types.WorkersHealth{
+		State: types.HealthOK,
+		Live:  len(workers),
+		Roles: make(map[types.WorkerRole]int),
+	}
+	for _, worker := range workers {
+		health.Roles[worker.Role]++
+		switch worker.Status {
+		case "busy":
+			health.Busy++
+		case "error":
+			health.Errored++
+		}
+	}
+	for _, role := range required {
+		if health.Roles[role] == 0 {
+			health.MissingRoles = append(health.MissingRoles, role)
+		}
+	}
+
+	switch {
+	case health.Live == 0:
+		health.State = types.HealthDown
+	case len(health.MissingRoles) > 0 || health.Errored > 0:
+		health.State = types.HealthDegraded
+	}
+	return health
+}
+
+// ragHealth is down when the knowledge base does not answer
+func ragHealth(available bool) types.RAGHealth {
+	if !available {
+		return types.RAGHealth{State: types.HealthDown}
+	}
+	return types.RAGHealth{State: types.HealthOK, Available: true}
+}
+
+// providersHealth is down when every provider failed its health check,
+// degraded when some did, and unknown when no provider has an API key
+func providersHealth(available map[string]ai.APIConfig, unhealthy map[string]string) types.ProvidersHealth {
+	if len(available) == 0 {
+		return types.ProvidersHealth{State: types.HealthUnknown}
+	}
+
+	health := types.ProvidersHealth{State: types.HealthOK}
+	for provider := range available {
+		if reason, down := unhealthy[provider]; down {
+			if health.Unhealthy == nil {
+				health.Unhealthy = make(map[string]string)
+			}
+			health.Unhealthy[provider] = reason
+			continue
+		}
+		health.Healthy = append(health.Healthy, provider)
+	}
+	sort.Strings(health.Healthy)
+
+	switch {
+	case len(health.Healthy) == 0:
+		health.State = types.HealthDown
+	case len(health.Unhealthy) > 0:
+		health.State = types.HealthDegraded
+	}
+	return health
+}
+
+// gpuHealth is unknown when memory cannot be read and degraded when less
+// than headroom MB is free
+func gpuHealth(info localmodels.GPUMemoryInfo, err error, headroom uint64) types.GPUHealth {
+	if err != nil {
+		return types.GPUHealth{State: types.HealthUnknown, Error: err.Error()}
+	}
+
+	health := types.GPUHealth{State: types.HealthOK, Total: info.Total, Used: info.Used, Free: info.Free}
+	if info.Free < headroom {
+		health.State = types.HealthDegraded
+	}
+	return health
+}
+
+// overallState is the worst of the monitored states; unknown only when
+// nothing is monitored
+func overallState(states ...types.HealthState) types.HealthState {
+	rank := map[types.HealthState]int{types.HealthOK: 1, types.HealthDegraded: 2, types.HealthDown: 3}
+
+	overall := types.HealthUnknown
+	for _, state := range states {
+		if rank[state] > rank[overall] {
+			overall = state
+		}
+	}
+	return overall
+}
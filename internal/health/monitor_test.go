@@ -0,0 +1,284 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/ai"
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// fakeWorkers is a fixed set of live workers
+type fakeWorkers []types.ExtendedWorkerStatus
+
+func (w fakeWorkers) Workers() []types.ExtendedWorkerStatus { return w }
+
+// fakeRAG is a knowledge base that is up or down
+type fakeRAG bool
+
+func (r fakeRAG) IsAvailable(context.Context) bool { return bool(r) }
+
+// fakeProviders has API keys for available, of which unhealthy failed their check
+type fakeProviders struct {
+	available []string
+	unhealthy map[string]string
+}
+
+func (p fakeProviders) GetAvailableAPIs() map[string]ai.APIConfig {
+	apis := make(map[string]ai.APIConfig)
+	for _, name := range p.available {
+		apis[name] = ai.APIConfig{}
+	}
+	return apis
+}
+
+func (p fakeProviders) ProviderHealth() map[string]string { return p.unhealthy }
+
+// fakeGPU reports freeMB of 8192MB free, or err
+func fakeGPU(freeMB uint64, err error) GPUSource {
+	return func(context.Context) (localmodels.GPUMemoryInfo, error) {
+		return localmodels.GPUMemoryInfo{Total: 8192, Used: 8192 - freeMB, Free: freeMB}, err
+	}
+}
+
+// worker is a live worker of role in status
+func worker(id string, role types.WorkerRole, status string) types.ExtendedWorkerStatus {
+	return types.ExtendedWorkerStatus{WorkerStatus: types.WorkerStatus{ID: id, Status: status}, Role: role}
+}
+
+// healthySources are subsystems that are all up
+func healthySources() Sources {
+	return Sources{
+		Workers: fakeWorkers{
+			worker("dev-1", types.RoleDeveloper, "busy"),
+			worker("rev-1", types.RoleReviewer, "idle"),
+			worker("app-1", types.RoleApprover, "idle"),
+			worker("test-1", types.RoleTester, "idle"),
+		},
+		RAG:       fakeRAG(true),
+		Providers: fakeProviders{available: []string{"groq", "cerebras"}},
+		GPU:       fakeGPU(4096, nil),
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	tests := []struct {
+		name   string
+		seed   func(*Sources)
+		want   types.HealthState
+		verify func(t *testing.T, health types.SystemHealth)
+	}{
+		{
+			name: "all healthy",
+			seed: func(*Sources) {},
+			want: types.HealthOK,
+			verify: func(t *testing.T, health types.SystemHealth) {
+				if health.Workers.Live != 4 || health.Workers.Busy != 1 || health.Workers.Roles[types.RoleDeveloper] != 1 {
+					t.Errorf("Workers = %+v, want 4 live, 1 busy", health.Workers)
+				}
+				if !reflect.DeepEqual(health.Providers.Healthy, []string{"cerebras", "groq"}) {
+					t.Errorf("healthy providers = %v, want both, sorted", health.Providers.Healthy)
+				}
+				if health.GPU.Free != 4096 || health.GPU.Total != 8192 || !health.RAG.Available {
+					t.Errorf("GPU = %+v, RAG = %+v", health.GPU, health.RAG)
+				}
+			},
+		},
+		{
+			name: "nothing monitored",
+			seed: func(s *Sources) { *s = Sources{} },
+			want: types.HealthUnknown,
+		},
+		{
+			name: "missing role",
+			seed: func(s *Sources) {
+				s.Workers = fakeWorkers{worker("dev-1", types.RoleDeveloper, "idle")}
+			},
+			want: types.HealthDegraded,
+			verify: func(t *testing.T, health types.SystemHealth) {
+				want := []types.WorkerRole{types.RoleReviewer, types.RoleApprover, types.RoleTester}
+				if !reflect.DeepEqual(health.Workers.MissingRoles, want) {
+					t.Errorf("MissingRoles = %v, want %v", health.Workers.MissingRoles, want)
+				}
+			},
+		},
+		{
+			name: "errored worker",
+			seed: func(s *Sources) {
+				s.Workers = append(s.Workers.(fakeWorkers), worker("dev-2", types.RoleDeveloper, "error"))
+			},
+			want: types.HealthDegraded,
+		},
+		{
+			name: "no workers",
+			seed: func(s *Sources) { s.Workers = fakeWorkers{} },
+			want: types.HealthDown,
+		},
+		{
+			name: "rag down",
+			seed: func(s *Sources) { s.RAG = fakeRAG(false) },
+			want: types.HealthDown,
+		},
+		{
+			name: "one provider unhealthy",
+			seed: func(s *Sources) {
+				s.Providers = fakeProviders{available: []string{"groq", "cerebras"}, unhealthy: map[string]string{"groq": "timeout"}}
+			},
+			want: types.HealthDegraded,
+			verify: func(t *testing.T, health types.SystemHealth) {
+				if health.Providers.Unhealthy["groq"] != "timeout" || !reflect.DeepEqual(health.Providers.Healthy, []string{"cerebras"}) {
+					t.Errorf("Providers = %+v, want groq unhealthy", health.Providers)
+				}
+			},
+		},
+		{
+			name: "every provider unhealthy",
+			seed: func(s *Sources) {
+				s.Providers = fakeProviders{available: []string{"groq"}, unhealthy: map[string]string{"groq": "401"}}
+			},
+			want: types.HealthDown,
+		},
+		{
+			name: "no provider keys",
+			seed: func(s *Sources) { s.Providers = fakeProviders{} },
+			want: types.HealthOK,
+			verify: func(t *testing.T, health types.SystemHealth) {
+				if health.Providers.State != types.HealthUnknown {
+					t.Errorf("Providers.State = %s, want unknown", health.Providers.State)
+				}
+			},
+		},
+		{
+			name: "gpu under headroom",
+			seed: func(s *Sources) { s.GPU = fakeGPU(256, nil) },
+			want: types.HealthDegraded,
+		},
+		{
+			name: "gpu unreadable",
+			seed: func(s *Sources) { s.GPU = fakeGPU(0, errors.New("nvidia-smi not found")) },
+			want: types.HealthOK,
+			verify: func(t *testing.T, health types.SystemHealth) {
+				if health.GPU.State != types.HealthUnknown || health.GPU.Error != "nvidia-smi not found" {
+					t.Errorf("GPU = %+v, want unknown with the error", health.GPU)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := healthySources()
+			tt.seed(&sources)
+			monitor := NewMonitor(nil, sources, DefaultConfig())
+			checkedAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+			monitor.now = func() time.Time { return checkedAt }
+
+			health := monitor.Snapshot(context.Background())
+			if health.State != tt.want {
+				t.Errorf("State = %s, want %s (%+v)", health.State, tt.want, health)
+			}
+			if !health.CheckedAt.Equal(checkedAt) {
+				t.Errorf("CheckedAt = %v, want %v", health.CheckedAt, checkedAt)
+			}
+			if tt.verify != nil {
+				tt.verify(t, health)
+			}
+		})
+	}
+}
+
+// publishClient records publishes, retained or not
+type publishClient struct {
+	mu        sync.Mutex
+	published []publish
+}
+
+// publish is one message seen by publishClient
+type publish struct {
+	topic    string
+	payload  []byte
+	retained bool
+}
+
+func (c *publishClient) Connect(context.Context) error                                { return nil }
+func (c *publishClient) Disconnect()                                                  {}
+func (c *publishClient) IsConnected() bool                                            { return true }
+func (c *publishClient) Subscribe(context.Context, string, mqtt.MessageHandler) error { return nil }
+func (c *publishClient) Unsubscribe(context.Context, string) error                    { return nil }
+
+func (c *publishClient) Publish(ctx context.Context, topic string, payload []byte) error {
+	return c.record(topic, payload, false)
+}
+
+func (c *publishClient) PublishRetained(ctx context.Context, topic string, payload []byte) error {
+	return c.record(topic, payload, true)
+}
+
+func (c *publishClient) record(topic string, payload []byte, retained bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.published = append(c.published, publish{topic, payload, retained})
+	return nil
+}
+
+// count returns the number of publishes so far
+func (c *publishClient) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.published)
+}
+
+func TestPublish(t *testing.T) {
+	client := &publishClient{}
+	monitor := NewMonitor(client, healthySources(), DefaultConfig())
+
+	if err := monitor.Publish(context.Background()); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if len(client.published) != 1 || client.published[0].topic != SystemHealthTopic || !client.published[0].retained {
+		t.Fatalf("published %+v, want one retained message on %s", client.published, SystemHealthTopic)
+	}
+
+	var health types.SystemHealth
+	if _, err := types.UnwrapMessage(client.published[0].payload, types.MessageTypeSystemHealth, &health); err != nil {
+		t.Fatalf("published payload: %v", err)
+	}
+	if health.State != types.HealthOK || health.Workers.Live != 4 {
+		t.Errorf("published health = %+v, want the ok snapshot", health)
+	}
+}
+
+func TestRunPublishesEveryInterval(t *testing.T) {
+	client := &publishClient{}
+	config := DefaultConfig()
+	config.Interval = time.Millisecond
+	monitor := NewMonitor(client, healthySources(), config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		monitor.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for client.count() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("published %d snapshots, want at least 3", client.count())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not return after the context was cancelled")
+	}
+}
@@ -0,0 +1,79 @@
+package localmodels
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ModelAliases maps friendly model names used in code and task payloads to
+// configured model keys, e.g. "qwen-omni" -> "qwen-omni-3b"
+type ModelAliases map[string]string
+
+// DefaultModelAliases returns the aliases for the names the worker has
+// historically used for the models in configs/models.yaml
+func DefaultModelAliases() ModelAliases {
+	return ModelAliases{
+		"qwen-omni":      "qwen-omni-3b",
+		"qwen-text":      "qwen-omni-3b",
+		"qwen-vl":        "qwen-vl-7b",
+		"qwen-embedding": "qwen-embedding-4b",
+	}
+}
+
+// aliasesFile mirrors the aliases section of configs/models.yaml
+type aliasesFile struct {
+	Aliases ModelAliases `yaml:"aliases"`
+}
+
+// LoadModelAliases reads the aliases section of a model configuration file.
+// A file without one yields the default aliases.
+func LoadModelAliases(configPath string) (ModelAliases, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model configuration: %w", err)
+	}
+
+	var file aliasesFile
+	if err := yaml.Unmarshal([]byte(expandEnvDefaults(string(data))), &file); err != nil {
+		return nil, fmt.Errorf("failed to parse model aliases: %w", err)
+	}
+
+	if len(file.Aliases) == 0 {
+		return DefaultModelAliases(), nil
+	}
+	return file.Aliases, nil
+}
+
+// Resolve returns the configured model key for name. Names that are already
+// configured keys, or that have no alias, are returned unchanged.
+func (a ModelAliases) Resolve(name string) string {
+	if target, ok := a[name]; ok {
+		return target
+	}
+	return name
+}
+
+// Validate checks that every alias points at a configured model and that no
+// alias shadows a configured model key
+func (a ModelAliases) Validate(models map[string]ModelConfig) error {
+	var problems []string
+	for alias, target := range a {
+		if _, exists := models[alias]; exists {
+			problems = append(problems, fmt.Sprintf("alias %s shadows a configured model", alias))
+			continue
+		}
+		if _, exists := models[target]; !exists {
+			problems = append(problems, fmt.Sprintf("alias %s points at unknown model %s", alias, target))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid model aliases: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package localmodels
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestModelAliasesResolve(t *testing.T) {
+	aliases := DefaultModelAliases()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"qwen-omni", "qwen-omni-3b"},
+		{"qwen-text", "qwen-omni-3b"},
+		{"qwen-vl", "qwen-vl-7b"},
+		{"qwen-omni-3b", "qwen-omni-3b"},
+		{"unaliased", "unaliased"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := aliases.Resolve(tt.name); got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	var none ModelAliases
+	if got := none.Resolve("qwen-omni"); got != "qwen-omni" {
+		t.Errorf("nil aliases Resolve() = %q, want the name unchanged", got)
+	}
+}
+
+func TestModelAliasesValidate(t *testing.T) {
+	models := map[string]ModelConfig{
+		"qwen-omni-3b": {Name: "qwen-omni-3b"},
+		"qwen-vl-7b":   {Name: "qwen-vl-7b"},
+	}
+
+	tests := []struct {
+		name    string
+		aliases ModelAliases
+		wantErr string
+	}{
+		{name: "valid", aliases: ModelAliases{"qwen-omni": "qwen-omni-3b", "qwen-vl": "qwen-vl-7b"}},
+		{name: "none", aliases: nil},
+		{name: "missing model", aliases: ModelAliases{"qwen-embedding": "qwen-embedding-4b"}, wantErr: "alias qwen-embedding points at unknown model qwen-embedding-4b"},
+		{name: "shadows a model", aliases: ModelAliases{"qwen-vl-7b": "qwen-omni-3b"}, wantErr: "alias qwen-vl-7b shadows a configured model"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.aliases.Validate(models)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadModelAliases(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    ModelAliases
+		wantErr bool
+	}{
+		{
+			name: "configured",
+			path: write("aliases.yaml", "aliases:\n  fast: qwen-omni-3b\n"),
+			want: ModelAliases{"fast": "qwen-omni-3b"},
+		},
+		{
+			name: "defaults without an aliases section",
+			path: write("models.yaml", "models: {}\n"),
+			want: DefaultModelAliases(),
+		},
+		{name: "missing file", path: filepath.Join(dir, "missing.yaml"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadModelAliases(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadModelAliases() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadModelAliases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewManagerRejectsInvalidAliases(t *testing.T) {
+	_, err := NewManager(ModelManagerConfig{
+		Models:  map[string]ModelConfig{"qwen-omni-3b": {Name: "qwen-omni-3b"}},
+		Aliases: ModelAliases{"qwen-vl": "qwen-vl-7b"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "unknown model qwen-vl-7b") {
+		t.Errorf("NewManager() error = %v, want the missing alias target reported", err)
+	}
+}
+
+func TestManagerResolvesAliases(t *testing.T) {
+	manager, err := NewManager(ModelManagerConfig{
+		DaemonURL: "http://127.0.0.1:0",
+		Models:    map[string]ModelConfig{"qwen-omni-3b": {Name: "qwen-omni-3b"}},
+		Aliases:   ModelAliases{"qwen-omni": "qwen-omni-3b"},
+	})
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	config, ok := manager.GetModelConfig("qwen-omni")
+	if !ok || config.Name != "qwen-omni-3b" {
+		t.Errorf("GetModelConfig(qwen-omni) = %+v, %v; want the qwen-omni-3b config", config, ok)
+	}
+}
@@ -0,0 +1,57 @@
+package localmodels
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envReference matches ${VAR} and ${VAR:-default} references in model configuration
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// modelsFile mirrors the layout of configs/models.yaml
+type modelsFile struct {
+	Models map[string]ModelConfig `yaml:"models"`
+}
+
+// LoadModelConfigs loads model definitions from a YAML file, expanding
+// ${VAR:-default} environment references in the raw document
+func LoadModelConfigs(configPath string) (map[string]ModelConfig, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model configuration: %w", err)
+	}
+
+	var file modelsFile
+	if err := yaml.Unmarshal([]byte(expandEnvDefaults(string(data))), &file); err != nil {
+		return nil, fmt.Errorf("failed to parse model configuration: %w", err)
+	}
+
+	if len(file.Models) == 0 {
+		return nil, fmt.Errorf("no models defined in %s", configPath)
+	}
+
+	for name, config := range file.Models {
+		if config.BinaryPath == "" {
+			return nil, fmt.Errorf("model %s: binary_path is required", name)
+		}
+		if config.ModelPath == "" {
+			return nil, fmt.Errorf("model %s: model_path is required", name)
+		}
+	}
+
+	return file.Models, nil
+}
+
+// expandEnvDefaults replaces ${VAR} and ${VAR:-default} with environment values
+func expandEnvDefaults(s string) string {
+	return envReference.ReplaceAllStringFunc(s, func(ref string) string {
+		match := envReference.FindStringSubmatch(ref)
+		if value, ok := os.LookupEnv(match[1]); ok && value != "" {
+			return value
+		}
+		return match[2]
+	})
+}
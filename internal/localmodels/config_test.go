@@ -0,0 +1,69 @@
+package localmodels
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnvDefaults(t *testing.T) {
+	t.Setenv("MODELS_TEST_DIR", "/opt/models")
+	t.Setenv("MODELS_TEST_EMPTY", "")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"${MODELS_TEST_DIR}/qwen.gguf", "/opt/models/qwen.gguf"},
+		{"${MODELS_TEST_DIR:-/tmp}/qwen.gguf", "/opt/models/qwen.gguf"},
+		{"${MODELS_TEST_UNSET:-/tmp}/qwen.gguf", "/tmp/qwen.gguf"},
+		{"${MODELS_TEST_EMPTY:-/tmp}/qwen.gguf", "/tmp/qwen.gguf"},
+		{"${MODELS_TEST_UNSET}/qwen.gguf", "/qwen.gguf"},
+		{"$MODELS_TEST_DIR/qwen.gguf", "$MODELS_TEST_DIR/qwen.gguf"},
+	}
+
+	for _, tt := range tests {
+		if got := expandEnvDefaults(tt.input); got != tt.want {
+			t.Errorf("expandEnvDefaults(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestLoadModelConfigs(t *testing.T) {
+	t.Setenv("MODELS_TEST_DIR", "/opt/models")
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", "models:\n  qwen:\n    binary_path: llama-server\n    model_path: ${MODELS_TEST_DIR}/qwen.gguf\n", ""},
+		{"no models", "models: {}\n", "no models defined"},
+		{"missing binary", "models:\n  qwen:\n    model_path: qwen.gguf\n", "binary_path is required"},
+		{"missing model", "models:\n  qwen:\n    binary_path: llama-server\n", "model_path is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "models.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			configs, err := LoadModelConfigs(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadModelConfigs: %v", err)
+			}
+			if got := configs["qwen"].ModelPath; got != "/opt/models/qwen.gguf" {
+				t.Errorf("ModelPath = %q, want the expanded path", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package localmodels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/tokenizer"
+)
+
+// ParamContextOverflow sets what happens to a request whose prompt plus
+// max_tokens exceeds the context window: "error" (default) rejects it,
+// "trim" lowers max_tokens to the room the prompt leaves
+const ParamContextOverflow = "context_overflow"
+
+// Context overflow policies
+const (
+	ContextOverflowError = "error"
+	ContextOverflowTrim  = "trim"
+)
+
+// ErrContextOverflow is returned when a request cannot fit the model's context window
+var ErrContextOverflow = errors.New("request exceeds model context window")
+
+// ContextOverflow returns the model's context overflow policy
+func (c ModelConfig) ContextOverflow() string {
+	policy := strings.ToLower(strings.TrimSpace(c.Parameters[ParamContextOverflow]))
+	switch policy {
+	case ContextOverflowError, ContextOverflowTrim:
+		return policy
+	case "":
+		return ContextOverflowError
+	default:
+		log.Printf("Warning: model %s has invalid %s %q, using %s", c.Name, ParamContextOverflow, policy, ContextOverflowError)
+		return ContextOverflowError
+	}
+}
+
+// FitContext checks that the estimated prompt tokens plus the requested
+// output fit the context window, which llama.cpp would otherwise truncate
+// silently. A model that trims gets MaxTokens lowered to the room left; a
+// prompt that fills the window on its own is always rejected.
+func (c ModelConfig) FitContext(input ModelInput) (ModelInput, error) {
+	window := c.ContextLength()
+	promptTokens := tokenizer.Estimate(input.Text)
+	maxTokens := getMaxTokens(input.MaxTokens)
+	if promptTokens+maxTokens <= window {
+		return input, nil
+	}
+
+	if room := window - promptTokens; room > 0 && c.ContextOverflow() == ContextOverflowTrim {
+		log.Printf("Model %s: prompt is ~%d tokens, trimming max_tokens from %d to %d to fit the %d-token context window",
+			c.Name, promptTokens, maxTokens, room, window)
+		input.MaxTokens = room
+		return input, nil
+	}
+
+	return input, fmt.Errorf("%w: prompt is ~%d tokens and %d are requested for output, but %s has a %d-token context window; shorten the prompt or lower max_tokens",
+		ErrContextOverflow, promptTokens, maxTokens, c.Name, window)
+}
+
+// contextGuard checks every prediction against the model's context window
+type contextGuard struct {
+	Model
+	config ModelConfig
+}
+
+// guardContext wraps model so requests that cannot fit its context window
+// are rejected or trimmed before reaching it
+func guardContext(model Model, config ModelConfig) Model {
+	return &contextGuard{Model: model, config: config}
+}
+
+// Predict fits the input to the context window, then runs the prediction
+func (g *contextGuard) Predict(ctx context.Context, input ModelInput) (*ModelOutput, error) {
+	input, err := g.config.FitContext(input)
+	if err != nil {
+		return nil, err
+	}
+	return g.Model.Predict(ctx, input)
+}
@@ -0,0 +1,104 @@
+package localmodels
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// words returns a prompt of n words, which tokenizer.Estimate counts as ~4n/3 tokens
+func words(n int) string {
+	return strings.TrimSpace(strings.Repeat("word ", n))
+}
+
+// windowConfig is a model with a 1000-token context window and the given overflow policy
+func windowConfig(policy string) ModelConfig {
+	return ModelConfig{Name: "qwen-omni-3b", Parameters: map[string]string{
+		ParamContextLength:   "1000",
+		ParamContextOverflow: policy,
+	}}
+}
+
+func TestFitContext(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        string
+		prompt        string // 300 words is ~400 tokens
+		maxTokens     int
+		wantMaxTokens int
+		wantErr       bool
+	}{
+		{name: "fits", prompt: words(300), maxTokens: 500, wantMaxTokens: 500},
+		{name: "fits exactly", prompt: words(300), maxTokens: 600, wantMaxTokens: 600},
+		{name: "default max tokens fits", prompt: words(300), wantMaxTokens: 0},
+		{name: "over context", prompt: words(300), maxTokens: 700, wantErr: true},
+		{name: "explicit error policy", policy: ContextOverflowError, prompt: words(300), maxTokens: 700, wantErr: true},
+		{name: "invalid policy rejects", policy: "truncate", prompt: words(300), maxTokens: 700, wantErr: true},
+		{name: "trim", policy: ContextOverflowTrim, prompt: words(300), maxTokens: 700, wantMaxTokens: 600},
+		{name: "trim default max tokens", policy: " Trim ", prompt: words(600), wantMaxTokens: 200},
+		{name: "prompt fills the window", policy: ContextOverflowTrim, prompt: words(750), maxTokens: 10, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := windowConfig(tt.policy).FitContext(ModelInput{Text: tt.prompt, MaxTokens: tt.maxTokens})
+			if tt.wantErr {
+				if !errors.Is(err, ErrContextOverflow) || !strings.Contains(err.Error(), "1000-token context window") {
+					t.Errorf("FitContext() error = %v, want a context overflow naming the window", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FitContext() error = %v", err)
+			}
+			if got.MaxTokens != tt.wantMaxTokens || got.Text != tt.prompt {
+				t.Errorf("FitContext() MaxTokens = %d, want %d with the prompt unchanged", got.MaxTokens, tt.wantMaxTokens)
+			}
+		})
+	}
+}
+
+// inputModel records the input of its last prediction
+type inputModel struct {
+	stubModel
+	input *ModelInput
+}
+
+func (m *inputModel) Predict(ctx context.Context, input ModelInput) (*ModelOutput, error) {
+	m.input = &input
+	return &ModelOutput{Text: "ok"}, nil
+}
+
+func TestGuardContext(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        string
+		wantMaxTokens int
+		wantErr       bool
+	}{
+		{name: "rejected before the model", policy: ContextOverflowError, wantErr: true},
+		{name: "trimmed for the model", policy: ContextOverflowTrim, wantMaxTokens: 600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model := &inputModel{stubModel: stubModel{name: "qwen-omni-3b"}}
+			guarded := guardContext(model, windowConfig(tt.policy))
+
+			_, err := guarded.Predict(context.Background(), ModelInput{Text: words(300), MaxTokens: 700})
+			if tt.wantErr {
+				if !errors.Is(err, ErrContextOverflow) || model.input != nil {
+					t.Errorf("Predict() error = %v, model called: %v; want rejected before the model", err, model.input != nil)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Predict() error = %v", err)
+			}
+			if model.input == nil || model.input.MaxTokens != tt.wantMaxTokens {
+				t.Errorf("model got %+v, want max tokens %d", model.input, tt.wantMaxTokens)
+			}
+		})
+	}
+}
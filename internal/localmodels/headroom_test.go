@@ -0,0 +1,90 @@
+package localmodels
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeNvidiaSMI writes an nvidia-smi stand-in reporting freeMB of 8192MB free
+func fakeNvidiaSMI(t *testing.T, freeMB int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "nvidia-smi")
+	script := fmt.Sprintf("#!/bin/sh\necho \"8192, %d, %d\"\n", 8192-freeMB, freeMB)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMemoryShortfall(t *testing.T) {
+	tests := []struct {
+		name     string
+		free     uint64
+		limit    uint64
+		headroom uint64
+		want     uint64
+	}{
+		{name: "fits with headroom", free: 5000, limit: 4000, headroom: 512},
+		{name: "fits without headroom", free: 4096, limit: 4000},
+		{name: "headroom tips it over", free: 4096, limit: 4000, headroom: 512, want: 416},
+		{name: "model alone does not fit", free: 2000, limit: 4000, headroom: 512, want: 2512},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{gpuMemory: GPUMemoryInfo{Free: tt.free}, memoryHeadroom: tt.headroom}
+			config := ModelConfig{MemoryLimit: tt.limit}
+
+			if got := m.memoryShortfall(config); got != tt.want {
+				t.Errorf("memoryShortfall() = %d, want %d", got, tt.want)
+			}
+			if got := m.canLoadModel(config); got != (tt.want == 0) {
+				t.Errorf("canLoadModel() = %v, want %v", got, tt.want == 0)
+			}
+		})
+	}
+}
+
+func TestLoadModelRefusesWithoutHeadroom(t *testing.T) {
+	manager, err := NewManager(ModelManagerConfig{
+		NvidiaSMIPath:   fakeNvidiaSMI(t, 4096),
+		MonitorInterval: time.Hour,
+		MemoryHeadroom:  512,
+		Models: map[string]ModelConfig{
+			"qwen-omni-3b": {Name: "qwen-omni-3b", Type: ModelTypeText, MemoryLimit: 4000},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	t.Cleanup(func() { close(manager.stopMonitoring) }) // Shutdown would block if LoadModel deadlocked
+
+	// Evicting this model frees nothing, and eviction refreshes GPU memory
+	// while LoadModel holds the manager lock
+	idle := &stubModel{name: "idle"}
+	addModel(manager, idle, time.Minute)
+
+	done := make(chan error, 1)
+	go func() { done <- manager.LoadModel(context.Background(), "qwen-omni-3b") }()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "insufficient GPU memory") {
+			t.Errorf("LoadModel() error = %v, want insufficient GPU memory", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("LoadModel() did not return; eviction deadlocked on the manager lock")
+	}
+
+	if idle.unloads.Load() != 1 {
+		t.Errorf("idle model unloaded %d times, want it evicted once", idle.unloads.Load())
+	}
+	if free := manager.GetGPUMemoryInfo().Free; free != 4096 {
+		t.Errorf("GPU free memory = %dMB after eviction, want the refreshed 4096MB", free)
+	}
+}
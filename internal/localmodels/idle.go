@@ -0,0 +1,123 @@
+package localmodels
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// idleUnloadTimeout bounds how long unloading one idle model may take
+const idleUnloadTimeout = 30 * time.Second
+
+// inFlightCounter is implemented by models that track running predictions
+type inFlightCounter interface {
+	InFlight() int
+}
+
+// idleModel is a model taken out of service to be unloaded
+type idleModel struct {
+	name  string
+	model Model
+	entry *LRUEntry
+}
+
+// UnloadIdle unloads every loaded model that has not been used for at least
+// idle and returns their names. Models with predictions in flight are kept;
+// an unloaded model is loaded again by the next task that needs it.
+func (m *Manager) UnloadIdle(idle time.Duration) []string {
+	// Unload outside the lock so other models stay usable meanwhile
+	var unloaded []string
+	for _, candidate := range m.takeIdleModels(idle) {
+		ctx, cancel := context.WithTimeout(context.Background(), idleUnloadTimeout)
+		err := candidate.model.Unload(ctx)
+		cancel()
+
+		m.mu.Lock()
+		if err != nil {
+			log.Printf("Failed to unload idle model %s: %v", candidate.name, err)
+			m.models[candidate.name] = candidate.model
+			m.lruMap[candidate.name] = m.lruList.PushBack(candidate.entry)
+		} else {
+			unloaded = append(unloaded, candidate.name)
+			log.Printf("✅ Model %s unloaded after %v idle", candidate.name, time.Since(candidate.entry.lastUsed).Round(time.Second))
+		}
+		close(m.unloading[candidate.name])
+		delete(m.unloading, candidate.name)
+		m.mu.Unlock()
+	}
+	return unloaded
+}
+
+// takeIdleModels removes the models idle for at least idle and without
+// predictions in flight from service and marks them unloading, so loads of
+// the same model wait for the unload to finish
+func (m *Manager) takeIdleModels(idle time.Duration) []idleModel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var idleModels []idleModel
+	for name, elem := range m.lruMap {
+		entry := elem.Value.(*LRUEntry)
+		if now.Sub(entry.lastUsed) < idle {
+			continue
+		}
+
+		model, exists := m.models[name]
+		if !exists {
+			continue
+		}
+		if counter, ok := model.(inFlightCounter); ok && counter.InFlight() > 0 {
+			continue
+		}
+
+		delete(m.models, name)
+		m.removeFromLRU(name)
+		m.unloading[name] = make(chan struct{})
+		idleModels = append(idleModels, idleModel{name: name, model: model, entry: entry})
+	}
+	return idleModels
+}
+
+// SetIdleTimeout unloads models unused for timeout to free GPU memory; they
+// reload on the next task that needs them. Zero keeps models loaded. With a
+// model daemon the daemon owns model lifetimes and the timeout is ignored.
+func (m *Manager) SetIdleTimeout(timeout time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.daemonURL != "" || timeout <= 0 {
+		return
+	}
+
+	started := m.idleTimeout > 0
+	m.idleTimeout = timeout
+	if !started {
+		go m.unloadIdleModels()
+	}
+	log.Printf("Local model manager unloads models after %v idle", timeout)
+}
+
+// unloadIdleModels periodically unloads models idle for longer than the idle timeout
+func (m *Manager) unloadIdleModels() {
+	m.mu.RLock()
+	interval := min(m.idleTimeout/2, time.Minute)
+	m.mu.RUnlock()
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.mu.RLock()
+			timeout := m.idleTimeout
+			m.mu.RUnlock()
+			m.UnloadIdle(timeout)
+		case <-m.stopMonitoring:
+			return
+		}
+	}
+}
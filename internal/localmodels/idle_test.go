@@ -0,0 +1,145 @@
+package localmodels
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubModel is a loaded model whose Unload can block or fail
+type stubModel struct {
+	name      string
+	unloadErr error
+	unloading chan struct{} // Closed when Unload starts, if set
+	release   chan struct{} // Unload waits for it, if set
+	unloads   atomic.Int32
+}
+
+func (s *stubModel) Load(context.Context) error { return nil }
+func (s *stubModel) IsLoaded() bool             { return true }
+func (s *stubModel) GetName() string            { return s.name }
+func (s *stubModel) GetType() ModelType         { return ModelTypeText }
+func (s *stubModel) GetMemoryUsage() uint64     { return 0 }
+
+func (s *stubModel) Predict(context.Context, ModelInput) (*ModelOutput, error) {
+	return &ModelOutput{Text: s.name}, nil
+}
+
+func (s *stubModel) Unload(context.Context) error {
+	s.unloads.Add(1)
+	if s.unloading != nil {
+		close(s.unloading)
+	}
+	if s.release != nil {
+		<-s.release
+	}
+	return s.unloadErr
+}
+
+// newDaemonTestManager creates a manager whose loads go to a daemon accepting
+// every request
+func newDaemonTestManager(t *testing.T) *Manager {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+	manager, err := NewManager(ModelManagerConfig{DaemonURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return manager
+}
+
+// addModel registers model as loaded and last used idle ago
+func addModel(m *Manager, model Model, idle time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.models[model.GetName()] = model
+	m.addToLRU(model.GetName())
+	m.lruMap[model.GetName()].Value.(*LRUEntry).lastUsed = time.Now().Add(-idle)
+}
+
+func TestUnloadIdle(t *testing.T) {
+	tests := []struct {
+		name       string
+		idle       time.Duration
+		inFlight   bool
+		unloadErr  error
+		wantUnload bool
+	}{
+		{"idle", time.Hour, false, nil, true},
+		{"recently used", time.Second, false, nil, false},
+		{"prediction in flight", time.Hour, true, nil, false},
+		{"unload fails", time.Hour, false, errors.New("stuck"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := newDaemonTestManager(t)
+			var model Model = &stubModel{name: "m", unloadErr: tt.unloadErr}
+			if tt.inFlight {
+				limited := newLimitedModel(model, 1)
+				limited.slots <- struct{}{}
+				model = limited
+			}
+			addModel(manager, model, tt.idle)
+
+			unloaded := manager.UnloadIdle(time.Minute)
+			if got := len(unloaded) == 1; got != tt.wantUnload {
+				t.Errorf("UnloadIdle = %v, want unloaded %v", unloaded, tt.wantUnload)
+			}
+			if _, err := manager.GetModel("m"); (err == nil) == tt.wantUnload {
+				t.Errorf("GetModel err = %v, want model kept %v", err, !tt.wantUnload)
+			}
+		})
+	}
+}
+
+func TestUnloadIdleReleasesLock(t *testing.T) {
+	manager := newDaemonTestManager(t)
+	slow := &stubModel{name: "slow", unloading: make(chan struct{}), release: make(chan struct{})}
+	addModel(manager, slow, time.Hour)
+	addModel(manager, &stubModel{name: "busy"}, 0)
+
+	done := make(chan []string, 1)
+	go func() { done <- manager.UnloadIdle(time.Minute) }()
+	<-slow.unloading
+
+	// Other models stay usable while the idle one unloads
+	got := make(chan error, 1)
+	go func() {
+		_, err := manager.GetModel("busy")
+		got <- err
+	}()
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Errorf("GetModel: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetModel blocked on the idle unload")
+	}
+
+	// Loading the unloading model waits for the unload and then loads it anew
+	loaded := make(chan error, 1)
+	go func() { loaded <- manager.LoadModel(context.Background(), "slow") }()
+	select {
+	case err := <-loaded:
+		t.Fatalf("LoadModel returned before the unload finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(slow.release)
+	if unloaded := <-done; len(unloaded) != 1 || unloaded[0] != "slow" {
+		t.Errorf("UnloadIdle = %v, want [slow]", unloaded)
+	}
+	if err := <-loaded; err != nil {
+		t.Fatalf("LoadModel: %v", err)
+	}
+	if model, err := manager.GetModel("slow"); err != nil || model == Model(slow) {
+		t.Errorf("GetModel = %v, %v; want a freshly loaded model", model, err)
+	}
+}
@@ -0,0 +1,232 @@
+package localmodels
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Decoded so GIFs can be converted
+	"image/jpeg"
+	"image/png"
+	"log"
+	"os"
+	"strings"
+)
+
+// Parameter keys for image preprocessing in ModelConfig.Parameters
+const (
+	ParamImageMaxDimension = "image_max_dimension" // Longest side in pixels; larger images are downscaled
+	ParamImageFormat       = "image_format"        // Format images are converted to: png or jpeg
+)
+
+// Image preprocessing defaults
+const (
+	DefaultImageMaxDimension = 1024
+	DefaultImageFormat       = "png"
+)
+
+// imageFormats are the formats multimodal models accept without conversion
+var imageFormats = map[string]bool{"png": true, "jpeg": true}
+
+// ImageMaxDimension returns the longest image side the model accepts
+func (c ModelConfig) ImageMaxDimension() int {
+	return c.IntParameter(ParamImageMaxDimension, DefaultImageMaxDimension)
+}
+
+// ImageFormat returns the format unsupported images are converted to
+func (c ModelConfig) ImageFormat() string {
+	format := strings.ToLower(strings.TrimSpace(c.Parameters[ParamImageFormat]))
+	if format == "jpg" {
+		format = "jpeg"
+	}
+	if !imageFormats[format] {
+		if format != "" {
+			log.Printf("Warning: model %s has unsupported %s %q, using %s", c.Name, ParamImageFormat, format, DefaultImageFormat)
+		}
+		return DefaultImageFormat
+	}
+	return format
+}
+
+// PrepareImages returns the files to pass to the model for every image in
+// input: ImageData is written to temporary files, then all images are
+// preprocessed. The returned cleanup removes every temporary file.
+func PrepareImages(config ModelConfig, input ModelInput) ([]string, func(), error) {
+	dataPaths, cleanupData, err := WriteImageData(input.ImageData)
+	if err != nil {
+		return nil, func() {}, err
+	}
+
+	paths := append(append([]string(nil), input.ImagePaths...), dataPaths...)
+	processed, cleanupProcessed, err := PreprocessImages(config, paths)
+	if err != nil {
+		cleanupData()
+		return nil, func() {}, err
+	}
+
+	return processed, func() {
+		cleanupProcessed()
+		cleanupData()
+	}, nil
+}
+
+// WriteImageData writes decoded image bytes to temporary files for CLIs that
+// read images from disk. The returned cleanup removes the files.
+func WriteImageData(images [][]byte) ([]string, func(), error) {
+	var paths []string
+	cleanup := func() {
+		for _, path := range paths {
+			os.Remove(path)
+		}
+	}
+
+	for i, data := range images {
+		_, format, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			cleanup()
+			return nil, func() {}, fmt.Errorf("inline image %d: unsupported or corrupt image: %w", i+1, err)
+		}
+
+		out, err := os.CreateTemp("", "model-image-*."+format)
+		if err != nil {
+			cleanup()
+			return nil, func() {}, fmt.Errorf("failed to create inline image file: %w", err)
+		}
+		paths = append(paths, out.Name())
+
+		_, err = out.Write(data)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			cleanup()
+			return nil, func() {}, fmt.Errorf("failed to write inline image %d: %w", i+1, err)
+		}
+	}
+	return paths, cleanup, nil
+}
+
+// PreprocessImages returns paths the model can read for each image, decoding
+// and rewriting any that are too large or in an unsupported format. Images
+// that already fit are passed through unchanged. The returned cleanup removes
+// the rewritten copies and must be called once inference finishes.
+func PreprocessImages(config ModelConfig, paths []string) ([]string, func(), error) {
+	var temporary []string
+	cleanup := func() {
+		for _, path := range temporary {
+			os.Remove(path)
+		}
+	}
+
+	processed := make([]string, 0, len(paths))
+	for _, path := range paths {
+		prepared, rewritten, err := preprocessImage(config, path)
+		if err != nil {
+			cleanup()
+			return nil, func() {}, err
+		}
+		if rewritten {
+			temporary = append(temporary, prepared)
+		}
+		processed = append(processed, prepared)
+	}
+	return processed, cleanup, nil
+}
+
+// preprocessImage prepares a single image, reporting whether it wrote a new file
+func preprocessImage(config ModelConfig, path string) (string, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer file.Close()
+
+	header, format, err := image.DecodeConfig(file)
+	if err != nil {
+		return "", false, fmt.Errorf("image %s: unsupported or corrupt image: %w", path, err)
+	}
+
+	maxDimension := config.ImageMaxDimension()
+	oversized := maxDimension > 0 && max(header.Width, header.Height) > maxDimension
+	if !oversized && imageFormats[format] {
+		return path, false, nil
+	}
+
+	if _, err := file.Seek(0, 0); err != nil {
+		return "", false, fmt.Errorf("image %s: %w", path, err)
+	}
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return "", false, fmt.Errorf("image %s: failed to decode: %w", path, err)
+	}
+
+	if oversized {
+		img = downscale(img, maxDimension)
+	}
+
+	target := config.ImageFormat()
+	prepared, err := writeImage(img, target)
+	if err != nil {
+		return "", false, fmt.Errorf("image %s: %w", path, err)
+	}
+
+	bounds := img.Bounds()
+	log.Printf("Preprocessed image %s (%s %dx%d) to %s (%s %dx%d)",
+		path, format, header.Width, header.Height, prepared, target, bounds.Dx(), bounds.Dy())
+	return prepared, true, nil
+}
+
+// writeImage encodes img to a temporary file in format
+func writeImage(img image.Image, format string) (string, error) {
+	out, err := os.CreateTemp("", "model-image-*."+format)
+	if err != nil {
+		return "", fmt.Errorf("failed to create preprocessed image: %w", err)
+	}
+
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(out, img, &jpeg.Options{Quality: 90})
+	default:
+		err = png.Encode(out, img)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("failed to encode preprocessed image: %w", err)
+	}
+	return out.Name(), nil
+}
+
+// downscale shrinks img so its longest side is maxDimension, averaging the
+// source pixels that fall into each destination pixel
+func downscale(img image.Image, maxDimension int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	longest := max(width, height)
+
+	dstWidth := max(1, width*maxDimension/longest)
+	dstHeight := max(1, height*maxDimension/longest)
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*height/dstHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/dstHeight)
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*width/dstWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/dstWidth)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return dst
+}
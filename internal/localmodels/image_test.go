@@ -0,0 +1,160 @@
+package localmodels
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestImage writes a width x height image under dir, encoded as GIF or
+// PNG by the extension of name
+func writeTestImage(t *testing.T, dir, name string, width, height int) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+
+	path := filepath.Join(dir, name)
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	switch filepath.Ext(name) {
+	case ".gif":
+		err = gif.Encode(out, img, nil)
+	default:
+		err = png.Encode(out, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// decodeImage returns the format and size of the image at path
+func decodeImage(t *testing.T, path string) (string, int, int) {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	header, format, err := image.DecodeConfig(file)
+	if err != nil {
+		t.Fatalf("decode %s: %v", path, err)
+	}
+	return format, header.Width, header.Height
+}
+
+func TestPreprocessImages(t *testing.T) {
+	dir := t.TempDir()
+	corrupt := filepath.Join(dir, "corrupt.png")
+	if err := os.WriteFile(corrupt, []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		path          string
+		parameters    map[string]string
+		wantRewritten bool
+		wantFormat    string
+		wantWidth     int
+		wantHeight    int
+		wantErr       bool
+	}{
+		{
+			name:       "fitting png passes through",
+			path:       writeTestImage(t, dir, "small.png", 40, 20),
+			parameters: map[string]string{ParamImageMaxDimension: "64"},
+			wantFormat: "png", wantWidth: 40, wantHeight: 20,
+		},
+		{
+			name:          "oversized png is downscaled",
+			path:          writeTestImage(t, dir, "large.png", 200, 100),
+			parameters:    map[string]string{ParamImageMaxDimension: "64"},
+			wantRewritten: true,
+			wantFormat:    "png", wantWidth: 64, wantHeight: 32,
+		},
+		{
+			name:          "unsupported format is converted",
+			path:          writeTestImage(t, dir, "small.gif", 40, 20),
+			parameters:    map[string]string{ParamImageFormat: "jpg"},
+			wantRewritten: true,
+			wantFormat:    "jpeg", wantWidth: 40, wantHeight: 20,
+		},
+		{
+			name:          "unknown target format falls back to png",
+			path:          writeTestImage(t, dir, "other.gif", 40, 20),
+			parameters:    map[string]string{ParamImageFormat: "bmp"},
+			wantRewritten: true,
+			wantFormat:    "png", wantWidth: 40, wantHeight: 20,
+		},
+		{
+			name:    "corrupt image",
+			path:    corrupt,
+			wantErr: true,
+		},
+		{
+			name:    "missing image",
+			path:    filepath.Join(dir, "missing.png"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ModelConfig{Name: "test-model", Parameters: tt.parameters}
+			paths, cleanup, err := PreprocessImages(config, []string{tt.path})
+			defer cleanup()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PreprocessImages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(paths) != 1 {
+				t.Fatalf("PreprocessImages() returned %d paths, want 1", len(paths))
+			}
+			if rewritten := paths[0] != tt.path; rewritten != tt.wantRewritten {
+				t.Errorf("rewritten = %v, want %v", rewritten, tt.wantRewritten)
+			}
+
+			format, width, height := decodeImage(t, paths[0])
+			if format != tt.wantFormat || width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("prepared image = %s %dx%d, want %s %dx%d",
+					format, width, height, tt.wantFormat, tt.wantWidth, tt.wantHeight)
+			}
+		})
+	}
+}
+
+func TestPreprocessImagesCleanup(t *testing.T) {
+	dir := t.TempDir()
+	original := writeTestImage(t, dir, "large.png", 200, 100)
+	config := ModelConfig{Parameters: map[string]string{ParamImageMaxDimension: "50"}}
+
+	paths, cleanup, err := PreprocessImages(config, []string{original})
+	if err != nil {
+		t.Fatalf("PreprocessImages() error = %v", err)
+	}
+	cleanup()
+
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Errorf("cleanup left the preprocessed copy %s", paths[0])
+	}
+	if _, err := os.Stat(original); err != nil {
+		t.Errorf("cleanup removed the original image: %v", err)
+	}
+}
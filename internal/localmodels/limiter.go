@@ -0,0 +1,49 @@
+package localmodels
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// DefaultMaxConcurrentInference serializes predictions per model; each
+// llama.cpp process holds its own copy of the weights, so parallel calls
+// multiply memory use
+const DefaultMaxConcurrentInference = 1
+
+// limitedModel bounds the number of concurrent Predict calls on a model.
+// Calls beyond the limit queue until a slot frees or their context ends.
+type limitedModel struct {
+	Model
+	slots chan struct{}
+}
+
+// newLimitedModel wraps model so at most limit predictions run at once
+func newLimitedModel(model Model, limit int) *limitedModel {
+	if limit < 1 {
+		limit = 1
+	}
+	return &limitedModel{Model: model, slots: make(chan struct{}, limit)}
+}
+
+// Predict waits for a free inference slot, then runs the prediction
+func (l *limitedModel) Predict(ctx context.Context, input ModelInput) (*ModelOutput, error) {
+	select {
+	case l.slots <- struct{}{}:
+	default:
+		log.Printf("Model %s: %d predictions in flight, queueing request", l.GetName(), cap(l.slots))
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for model %s inference slot: %w", l.GetName(), ctx.Err())
+		}
+	}
+	defer func() { <-l.slots }()
+
+	return l.Model.Predict(ctx, input)
+}
+
+// InFlight returns the number of predictions currently running
+func (l *limitedModel) InFlight() int {
+	return len(l.slots)
+}
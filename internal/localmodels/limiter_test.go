@@ -0,0 +1,116 @@
+package localmodels
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingModel tracks how many predictions run at once
+type countingModel struct {
+	stubModel
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (c *countingModel) Predict(ctx context.Context, input ModelInput) (*ModelOutput, error) {
+	now := c.running.Add(1)
+	defer c.running.Add(-1)
+	for {
+		peak := c.peak.Load()
+		if now <= peak || c.peak.CompareAndSwap(peak, now) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+	return &ModelOutput{Text: "ok"}, nil
+}
+
+func TestLimitedModelBoundsConcurrency(t *testing.T) {
+	tests := []struct {
+		limit    int
+		wantPeak int32
+	}{
+		{0, 1}, // Raised to one
+		{1, 1},
+		{3, 3},
+	}
+
+	for _, tt := range tests {
+		model := &countingModel{}
+		limited := newLimitedModel(model, tt.limit)
+
+		var wg sync.WaitGroup
+		for range 12 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := limited.Predict(context.Background(), ModelInput{}); err != nil {
+					t.Errorf("Predict: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if got := model.peak.Load(); got != tt.wantPeak {
+			t.Errorf("limit %d: peak %d concurrent predictions, want %d", tt.limit, got, tt.wantPeak)
+		}
+		if limited.InFlight() != 0 {
+			t.Errorf("limit %d: %d slots still held", tt.limit, limited.InFlight())
+		}
+	}
+}
+
+func TestLimitedModelQueuedCallHonoursContext(t *testing.T) {
+	limited := newLimitedModel(&stubModel{name: "m"}, 1)
+	limited.slots <- struct{}{} // A prediction is running
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := limited.Predict(ctx, ModelInput{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("queued Predict = %v, want context.DeadlineExceeded", err)
+	}
+	if limited.InFlight() != 1 {
+		t.Errorf("InFlight = %d, want the running prediction only", limited.InFlight())
+	}
+}
+
+func TestLimitInference(t *testing.T) {
+	tests := []struct {
+		name      string
+		daemonURL string
+		manager   int
+		param     string
+		want      int // Slots; zero when left unwrapped
+	}{
+		{"default", "", 0, "", DefaultMaxConcurrentInference},
+		{"manager limit", "", 2, "", 2},
+		{"model limit", "", 2, "4", 4},
+		{"daemon limits itself", "http://daemon", 2, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &Manager{
+				daemonURL:     tt.daemonURL,
+				maxConcurrent: tt.manager,
+				modelConfigs:  map[string]ModelConfig{"m": {Parameters: map[string]string{ParamMaxConcurrent: tt.param}}},
+			}
+			model := manager.limitInference("m", &stubModel{name: "m"})
+
+			limited, wrapped := model.(*limitedModel)
+			if !wrapped {
+				if tt.want != 0 {
+					t.Errorf("model left unwrapped, want %d slots", tt.want)
+				}
+				return
+			}
+			if got := cap(limited.slots); got != tt.want {
+				t.Errorf("got %d slots, want %d", got, tt.want)
+			}
+		})
+	}
+}
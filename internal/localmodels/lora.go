@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"time"
 )
@@ -38,6 +37,7 @@ type LoRATrainer struct {
 	llamaFinetunePath string
 	llamaExportPath   string
 	workingDir        string
+	killGrace         time.Duration // SIGTERM to SIGKILL grace period for cancelled runs
 }
 
 // NewLoRATrainer creates a new LoRA trainer
@@ -46,9 +46,15 @@ func NewLoRATrainer(llamaBinPath, workingDir string) *LoRATrainer {
 		llamaFinetunePath: filepath.Join(llamaBinPath, "llama-finetune"),
 		llamaExportPath:   filepath.Join(llamaBinPath, "llama-export-lora"),
 		workingDir:        workingDir,
+		killGrace:         DefaultKillGracePeriod,
 	}
 }
 
+// SetKillGracePeriod sets how long a cancelled training run has to exit after SIGTERM before it is SIGKILLed
+func (lt *LoRATrainer) SetKillGracePeriod(grace time.Duration) {
+	lt.killGrace = grace
+}
+
 // PrepareTrainingData converts training examples to llama-finetune format
 func (lt *LoRATrainer) PrepareTrainingData(examples []TrainingExample, outputPath string) error {
 	file, err := os.Create(outputPath)
@@ -108,7 +114,7 @@ func (lt *LoRATrainer) TrainLoRA(ctx context.Context, config LoRAConfig) error {
 
 	log.Printf("Starting LoRA training with command: %s %v", lt.llamaFinetunePath, args)
 
-	cmd := exec.CommandContext(ctx, lt.llamaFinetunePath, args...)
+	cmd := commandContext(ctx, lt.killGrace, lt.llamaFinetunePath, args...)
 	cmd.Dir = lt.workingDir
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -141,7 +147,7 @@ func (lt *LoRATrainer) ExportMergedModel(ctx context.Context, baseModelPath, lor
 
 	log.Printf("Exporting merged model with command: %s %v", lt.llamaExportPath, args)
 
-	cmd := exec.CommandContext(ctx, lt.llamaExportPath, args...)
+	cmd := commandContext(ctx, lt.killGrace, lt.llamaExportPath, args...)
 	cmd.Dir = lt.workingDir
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
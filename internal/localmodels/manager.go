@@ -12,6 +12,10 @@ import (
 	"time"
 )
 
+// DefaultMemoryHeadroom is the GPU memory (MB) left free after a load so the
+// KV cache can grow during inference without running out of memory
+const DefaultMemoryHeadroom = 512
+
 // LRUEntry represents an entry in the LRU cache
 type LRUEntry struct {
 	modelName string
@@ -23,11 +27,18 @@ type Manager struct {
 	mu              sync.RWMutex
 	models          map[string]Model
 	modelConfigs    map[string]ModelConfig
+	aliases         ModelAliases // Friendly names resolved to modelConfigs keys
 	gpuMemory       GPUMemoryInfo
 	maxGPUMemory    uint64
 	nvidiaSMIPath   string
 	monitorInterval time.Duration
 	stopMonitoring  chan struct{}
+	daemonURL       string // When set, models are served by a shared model daemon
+	loading         map[string]*loadCall
+	unloading       map[string]chan struct{} // Closed once an idle model finished unloading
+	maxConcurrent   int                      // Default per-model limit on simultaneous predictions
+	idleTimeout     time.Duration            // Unload models unused for this long; zero keeps them loaded
+	memoryHeadroom  uint64                   // MB left free after a load
 
 	// LRU cache management
 	lruList         *list.List
@@ -40,10 +51,16 @@ func NewManager(config ModelManagerConfig) (*Manager, error) {
 	m := &Manager{
 		models:          make(map[string]Model),
 		modelConfigs:    config.Models,
+		aliases:         config.Aliases,
 		maxGPUMemory:    config.MaxGPUMemory,
 		nvidiaSMIPath:   config.NvidiaSMIPath,
 		monitorInterval: config.MonitorInterval,
 		stopMonitoring:  make(chan struct{}),
+		daemonURL:       config.DaemonURL,
+		loading:         make(map[string]*loadCall),
+		unloading:       make(map[string]chan struct{}),
+		maxConcurrent:   config.MaxConcurrentInference,
+		memoryHeadroom:  config.MemoryHeadroom,
 
 		// LRU cache initialization
 		lruList:         list.New(),
@@ -51,6 +68,20 @@ func NewManager(config ModelManagerConfig) (*Manager, error) {
 		maxLoadedModels: 3, // Limit simultaneous loaded models based on GPU memory
 	}
 
+	// Catch aliases pointing at missing models at startup rather than on first use.
+	// With a model daemon the models live there and may not be configured here.
+	if len(config.Models) > 0 {
+		if err := config.Aliases.Validate(config.Models); err != nil {
+			return nil, err
+		}
+	}
+
+	// The daemon owns the GPU - nothing to monitor locally
+	if m.daemonURL != "" {
+		log.Printf("Local model manager using model daemon at %s", m.daemonURL)
+		return m, nil
+	}
+
 	// Initialize GPU memory monitoring
 	if err := m.updateGPUMemoryInfo(); err != nil {
 		log.Printf("Warning: Failed to initialize GPU memory info: %v", err)
@@ -64,29 +95,103 @@ func NewManager(config ModelManagerConfig) (*Manager, error) {
 	return m, nil
 }
 
-// LoadModel loads a specific model if memory allows
+// loadCall tracks an in-flight model load so concurrent callers share its result
+type loadCall struct {
+	done chan struct{}
+	err  error
+}
+
+// LoadModel loads a specific model if memory allows. Concurrent calls for the
+// same model coalesce into a single load and all callers receive its result.
 func (m *Manager) LoadModel(ctx context.Context, modelName string) error {
+	modelName = m.ResolveModel(modelName)
 	m.mu.Lock()
-	defer m.mu.Unlock()
 
 	// Check if already loaded
 	if model, exists := m.models[modelName]; exists && model.IsLoaded() {
+		m.mu.Unlock()
 		log.Printf("Model %s already loaded", modelName)
 		return nil
 	}
 
+	// Wait for a load already in progress instead of starting another
+	if call, inProgress := m.loading[modelName]; inProgress {
+		m.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for model %s to load: %w", modelName, ctx.Err())
+		}
+	}
+
+	// Wait for an idle unload of the model to finish before loading it again
+	if unloaded, unloading := m.unloading[modelName]; unloading {
+		m.mu.Unlock()
+		select {
+		case <-unloaded:
+			return m.LoadModel(ctx, modelName)
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for model %s to unload: %w", modelName, ctx.Err())
+		}
+	}
+
+	call := &loadCall{done: make(chan struct{})}
+	m.loading[modelName] = call
+
+	model, err := m.prepareModel(ctx, modelName)
+	m.mu.Unlock()
+
+	// Load outside the lock so other models stay usable meanwhile
+	if err == nil {
+		if loadErr := model.Load(ctx); loadErr != nil {
+			err = fmt.Errorf("failed to load model %s: %w", modelName, loadErr)
+		}
+	}
+
+	m.mu.Lock()
+	if err == nil {
+		config := m.modelConfigs[modelName]
+		model = filterReasoning(model, config)
+		if m.daemonURL == "" {
+			model = guardContext(model, config) // The daemon guards the models it runs
+		}
+		m.models[modelName] = m.limitInference(modelName, model)
+		m.addToLRU(modelName)
+	}
+	delete(m.loading, modelName)
+	m.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ Model %s loaded successfully", modelName)
+	return nil
+}
+
+// prepareModel frees memory if needed and creates the model instance for
+// modelName without loading it. Callers must hold m.mu.
+func (m *Manager) prepareModel(ctx context.Context, modelName string) (Model, error) {
+	if m.daemonURL != "" {
+		return m.newRemoteModel(modelName), nil
+	}
+
 	// Get model config
 	config, exists := m.modelConfigs[modelName]
 	if !exists {
-		return fmt.Errorf("model config not found for %s", modelName)
+		return nil, fmt.Errorf("model config not found for %s", modelName)
 	}
 
 	// Check GPU memory availability and LRU constraints
 	if !m.canLoadModel(config) {
 		// Try to free memory by evicting LRU models
-		if err := m.evictLRUModels(ctx, config.MemoryLimit); err != nil {
-			return fmt.Errorf("insufficient GPU memory to load model %s (requires ~%dMB, available: %dMB): %w",
-				modelName, config.MemoryLimit, m.gpuMemory.Free, err)
+		if err := m.evictLRUModels(ctx, m.memoryShortfall(config)); err != nil {
+			return nil, fmt.Errorf("insufficient GPU memory to load model %s (requires ~%dMB plus %dMB headroom, available: %dMB): %w",
+				modelName, config.MemoryLimit, m.memoryHeadroom, m.gpuMemory.Free, err)
 		}
 	}
 
@@ -106,29 +211,46 @@ func (m *Manager) LoadModel(ctx context.Context, modelName string) error {
 			model, err = NewMiniCPMModel(config)
 		}
 	default:
-		return fmt.Errorf("unsupported model type: %s", config.Type)
+		return nil, fmt.Errorf("unsupported model type: %s", config.Type)
 	}
 
 	if err != nil {
-		return fmt.Errorf("failed to create model %s: %w", modelName, err)
+		return nil, fmt.Errorf("failed to create model %s: %w", modelName, err)
 	}
 
-	// Load the model
-	if err := model.Load(ctx); err != nil {
-		return fmt.Errorf("failed to load model %s: %w", modelName, err)
-	}
+	return model, nil
+}
 
-	m.models[modelName] = model
+// limitInference bounds concurrent predictions on a locally run model. Daemon
+// models are left unwrapped because the daemon applies its own limit.
+// Callers must hold m.mu.
+func (m *Manager) limitInference(modelName string, model Model) Model {
+	if m.daemonURL != "" {
+		return model
+	}
 
-	// Add to LRU cache
-	m.addToLRU(modelName)
+	limit := m.maxConcurrent
+	if limit <= 0 {
+		limit = DefaultMaxConcurrentInference
+	}
+	if config, exists := m.modelConfigs[modelName]; exists {
+		limit = config.IntParameter(ParamMaxConcurrent, limit)
+	}
+	return newLimitedModel(model, limit)
+}
 
-	log.Printf("✅ Model %s loaded successfully", modelName)
-	return nil
+// newRemoteModel creates a handle to a model served by the model daemon
+func (m *Manager) newRemoteModel(modelName string) Model {
+	config := m.modelConfigs[modelName]
+	if config.Name == "" {
+		config.Name = modelName
+	}
+	return NewRemoteModel(m.daemonURL, modelName, config)
 }
 
 // UnloadModel unloads a specific model
 func (m *Manager) UnloadModel(ctx context.Context, modelName string) error {
+	modelName = m.ResolveModel(modelName)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -152,6 +274,7 @@ func (m *Manager) UnloadModel(ctx context.Context, modelName string) error {
 
 // GetModel returns a loaded model and updates LRU
 func (m *Manager) GetModel(modelName string) (Model, error) {
+	modelName = m.ResolveModel(modelName)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -166,6 +289,21 @@ func (m *Manager) GetModel(modelName string) (Model, error) {
 	return model, nil
 }
 
+// GetModelConfig returns the configuration of a model
+func (m *Manager) GetModelConfig(modelName string) (ModelConfig, bool) {
+	modelName = m.ResolveModel(modelName)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	config, exists := m.modelConfigs[modelName]
+	return config, exists
+}
+
+// ResolveModel returns the configured model key for a model name or alias
+func (m *Manager) ResolveModel(modelName string) string {
+	return m.aliases.Resolve(modelName)
+}
+
 // GetAvailableModels returns list of available model configurations
 func (m *Manager) GetAvailableModels() []string {
 	var models []string
@@ -216,11 +354,20 @@ func (m *Manager) GetModelStatus() map[string]ModelStatus {
 	return status
 }
 
-// canLoadModel checks if there's enough GPU memory to load a model
+// canLoadModel checks if there's enough GPU memory to load a model and
+// still leave the configured headroom free
 func (m *Manager) canLoadModel(config ModelConfig) bool {
-	// Check if we have enough free GPU memory
-	requiredMemory := config.MemoryLimit
-	return m.gpuMemory.Free >= requiredMemory
+	return m.memoryShortfall(config) == 0
+}
+
+// memoryShortfall returns how much GPU memory (MB) must be freed before
+// config can load with the configured headroom to spare
+func (m *Manager) memoryShortfall(config ModelConfig) uint64 {
+	requiredMemory := config.MemoryLimit + m.memoryHeadroom
+	if m.gpuMemory.Free >= requiredMemory {
+		return 0
+	}
+	return requiredMemory - m.gpuMemory.Free
 }
 
 // monitorGPUMemory monitors GPU memory usage in background
@@ -236,11 +383,15 @@ func (m *Manager) monitorGPUMemory() {
 				continue
 			}
 
-			// Check if memory usage is critical
+			// Check if memory usage is critical or inference has eaten into the headroom
 			if m.gpuMemory.Used > m.maxGPUMemory {
 				log.Printf("🚨 GPU memory usage critical: %dMB used, %dMB max",
 					m.gpuMemory.Used, m.maxGPUMemory)
 				m.handleMemoryPressure()
+			} else if m.gpuMemory.Free < m.memoryHeadroom {
+				log.Printf("🚨 GPU memory headroom exhausted: %dMB free, %dMB headroom",
+					m.gpuMemory.Free, m.memoryHeadroom)
+				m.handleMemoryPressure()
 			}
 
 		case <-m.stopMonitoring:
@@ -251,51 +402,61 @@ func (m *Manager) monitorGPUMemory() {
 
 // updateGPUMemoryInfo updates GPU memory information using nvidia-smi
 func (m *Manager) updateGPUMemoryInfo() error {
-	cmd := exec.Command(m.nvidiaSMIPath,
+	info, err := QueryGPUMemory(context.Background(), m.nvidiaSMIPath)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.gpuMemory = info
+	m.mu.Unlock()
+
+	return nil
+}
+
+// QueryGPUMemory reads the memory of the first GPU using nvidia-smi
+func QueryGPUMemory(ctx context.Context, nvidiaSMIPath string) (GPUMemoryInfo, error) {
+	cmd := exec.CommandContext(ctx, nvidiaSMIPath,
 		"--query-gpu=memory.total,memory.used,memory.free",
 		"--format=csv,noheader,nounits")
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("nvidia-smi execution failed: %w, output: %s", err, string(output))
+		return GPUMemoryInfo{}, fmt.Errorf("nvidia-smi execution failed: %w, output: %s", err, string(output))
 	}
 
 	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
 	if len(lines) < 1 {
-		return fmt.Errorf("unexpected nvidia-smi output: %s", string(output))
+		return GPUMemoryInfo{}, fmt.Errorf("unexpected nvidia-smi output: %s", string(output))
 	}
 
 	// Parse first GPU (index 0)
 	values := strings.Split(strings.TrimSpace(lines[0]), ", ")
 	if len(values) != 3 {
-		return fmt.Errorf("unexpected nvidia-smi format: %s", string(output))
+		return GPUMemoryInfo{}, fmt.Errorf("unexpected nvidia-smi format: %s", string(output))
 	}
 
 	total, err := parseMemoryValue(values[0])
 	if err != nil {
-		return fmt.Errorf("failed to parse total memory: %w", err)
+		return GPUMemoryInfo{}, fmt.Errorf("failed to parse total memory: %w", err)
 	}
 
 	used, err := parseMemoryValue(values[1])
 	if err != nil {
-		return fmt.Errorf("failed to parse used memory: %w", err)
+		return GPUMemoryInfo{}, fmt.Errorf("failed to parse used memory: %w", err)
 	}
 
 	free, err := parseMemoryValue(values[2])
 	if err != nil {
-		return fmt.Errorf("failed to parse free memory: %w", err)
+		return GPUMemoryInfo{}, fmt.Errorf("failed to parse free memory: %w", err)
 	}
 
-	m.mu.Lock()
-	m.gpuMemory = GPUMemoryInfo{
+	return GPUMemoryInfo{
 		Total:     total,
 		Used:      used,
 		Free:      free,
 		Timestamp: time.Now(),
-	}
-	m.mu.Unlock()
-
-	return nil
+	}, nil
 }
 
 // parseMemoryValue parses memory value from nvidia-smi output
@@ -441,9 +602,12 @@ func (m *Manager) evictLRUModels(ctx context.Context, requiredMemory uint64) err
 		}
 	}
 
-	// Update GPU memory info
-	if err := m.updateGPUMemoryInfo(); err != nil {
+	// Update GPU memory info; the caller already holds m.mu, so this must
+	// not go through updateGPUMemoryInfo
+	if info, err := QueryGPUMemory(ctx, m.nvidiaSMIPath); err != nil {
 		log.Printf("Warning: Failed to update GPU memory after eviction: %v", err)
+	} else {
+		m.gpuMemory = info
 	}
 
 	if freedMemory < requiredMemory {
@@ -0,0 +1,125 @@
+package localmodels
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// loadDaemon is a model daemon whose load requests wait for release and
+// answer with status
+type loadDaemon struct {
+	loads   atomic.Int32
+	started chan struct{} // Receives once per load request
+	release chan struct{}
+	status  int
+}
+
+func newLoadDaemon(t *testing.T, status int) (*loadDaemon, *Manager) {
+	t.Helper()
+	daemon := &loadDaemon{started: make(chan struct{}, 64), release: make(chan struct{}), status: status}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/load") {
+			daemon.loads.Add(1)
+			daemon.started <- struct{}{}
+			<-daemon.release
+		}
+		w.WriteHeader(daemon.status)
+	}))
+	t.Cleanup(server.Close)
+
+	manager, err := NewManager(ModelManagerConfig{DaemonURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return daemon, manager
+}
+
+func TestLoadModelSingleFlight(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"load succeeds", http.StatusOK, false},
+		{"load fails", http.StatusInternalServerError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon, manager := newLoadDaemon(t, tt.status)
+
+			const callers = 20
+			errs := make(chan error, callers)
+			var wg sync.WaitGroup
+			for range callers {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- manager.LoadModel(context.Background(), "m")
+				}()
+			}
+
+			// Hold the load until every caller has joined it
+			<-daemon.started
+			for {
+				manager.mu.RLock()
+				call := manager.loading["m"]
+				manager.mu.RUnlock()
+				if call != nil {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(50 * time.Millisecond)
+			close(daemon.release)
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				if (err != nil) != tt.wantErr {
+					t.Errorf("LoadModel err = %v, wantErr %v", err, tt.wantErr)
+				}
+			}
+			if got := daemon.loads.Load(); got != 1 {
+				t.Errorf("daemon got %d loads, want 1", got)
+			}
+			if _, err := manager.GetModel("m"); (err != nil) != tt.wantErr {
+				t.Errorf("GetModel err = %v, want loaded %v", err, !tt.wantErr)
+			}
+
+			// A failed load is not remembered; the next caller tries again
+			if tt.wantErr {
+				manager.LoadModel(context.Background(), "m")
+				if got := daemon.loads.Load(); got != 2 {
+					t.Errorf("daemon got %d loads after a retry, want 2", got)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadModelWaiterHonoursContext(t *testing.T) {
+	daemon, manager := newLoadDaemon(t, http.StatusOK)
+
+	leader := make(chan error, 1)
+	go func() { leader <- manager.LoadModel(context.Background(), "m") }()
+	<-daemon.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := manager.LoadModel(ctx, "m"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiting LoadModel = %v, want context.DeadlineExceeded", err)
+	}
+
+	close(daemon.release)
+	if err := <-leader; err != nil {
+		t.Errorf("leading LoadModel: %v", err)
+	}
+}
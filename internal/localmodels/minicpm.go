@@ -6,10 +6,11 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/tokenizer"
 )
 
 // MiniCPMModel implements the MiniCPM-V-4 multimodal model
@@ -78,13 +79,22 @@ func (m *MiniCPMModel) Predict(ctx context.Context, input ModelInput) (*ModelOut
 	startTime := time.Now()
 	m.lastUsed = startTime
 
+	// Write inline images to files and fit all images to the size and formats the model accepts
+	imagePaths, cleanup, err := PrepareImages(m.config, input)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	input.ImagePaths = imagePaths
+	input.ImageData = nil
+
 	// Build command arguments for llama-mtmd-cli
 	args := m.buildCommandArgs(input)
 
 	log.Printf("MiniCPM-V-4: Running inference with %d args", len(args))
 
 	// Execute the command
-	cmd := exec.CommandContext(ctx, m.binaryPath, args...)
+	cmd := commandContext(ctx, m.config.KillGracePeriod(), m.binaryPath, args...)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -114,6 +124,8 @@ func (m *MiniCPMModel) Predict(ctx context.Context, input ModelInput) (*ModelOut
 		Text:           output,
 		ProcessingTime: processingTime,
 		TokensUsed:     promptTokens + completionTokens,
+		ContextUsed:    tokenizer.Estimate(input.Text) + tokenizer.Estimate(output),
+		ContextLimit:   m.config.ContextLength(),
 		Metadata: map[string]string{
 			"model_name":     m.config.Name,
 			"model_type":     string(m.config.Type),
@@ -125,18 +137,24 @@ func (m *MiniCPMModel) Predict(ctx context.Context, input ModelInput) (*ModelOut
 
 // buildCommandArgs constructs the command line arguments for llama-mtmd-cli
 func (m *MiniCPMModel) buildCommandArgs(input ModelInput) []string {
+	// Hardware tuning comes from model parameters, defaulting to values for a 4B model
+	gpuLayers := strconv.Itoa(m.config.IntParameter(ParamGPULayers, 20))
+	batchSize := strconv.Itoa(m.config.IntParameter(ParamBatchSize, 2048))
+	threads := strconv.Itoa(m.config.IntParameter(ParamThreads, 16))
+	contextLength := strconv.Itoa(m.config.ContextLength())
+
 	// Base arguments following your example
 	args := []string{
 		"--offline",
 		"--mmproj", m.projectorPath,
 		"-m", m.modelPath,
-		"-ngl", "20", // GPU layers - will be adjusted by memory manager
-		"-fa",        // Flash attention
-		"-b", "2048", // Batch size (smaller for 4B model)
-		"-t", "16", // Threads
+		"-ngl", gpuLayers, // GPU layers - will be adjusted by memory manager
+		"-fa",           // Flash attention
+		"-b", batchSize, // Batch size (smaller for 4B model)
+		"-t", threads, // Threads
 		"-p", input.Text,
 		"--temp", fmt.Sprintf("%.2f", getTemperature(input.Temperature)),
-		"--ctx-size", "8192",
+		"--ctx-size", contextLength,
 		"-np", "16", // Parallel processing
 		"--prio-batch", "2", // Priority batch
 		"--no-mmproj-offload", // Keep projector on GPU
@@ -155,6 +173,10 @@ func (m *MiniCPMModel) buildCommandArgs(input ModelInput) []string {
 		args = append(args, "--n-predict", "-2") // Use default
 	}
 
+	if seed, ok := m.config.seed(input); ok {
+		args = append(args, "--seed", strconv.Itoa(seed))
+	}
+
 	return args
 }
 
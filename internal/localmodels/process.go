@@ -0,0 +1,67 @@
+package localmodels
+
+import (
+	"context"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// processWaitDelay bounds how long Wait blocks on output pipes after a
+// cancelled subprocess is killed
+const processWaitDelay = 5 * time.Second
+
+// ParamKillGracePeriod is how long a model process has to exit after SIGTERM
+// before it is SIGKILLed, as a Go duration such as "10s"
+const ParamKillGracePeriod = "kill_grace_period"
+
+// DefaultKillGracePeriod is the grace period for processes without one configured
+const DefaultKillGracePeriod = 10 * time.Second
+
+// KillGracePeriod returns the model's configured grace period between SIGTERM and SIGKILL
+func (c ModelConfig) KillGracePeriod() time.Duration {
+	value := strings.TrimSpace(c.Parameters[ParamKillGracePeriod])
+	if value == "" {
+		return DefaultKillGracePeriod
+	}
+
+	grace, err := time.ParseDuration(value)
+	if err != nil || grace < 0 {
+		log.Printf("Warning: model %s has invalid %s %q, using %v", c.Name, ParamKillGracePeriod, value, DefaultKillGracePeriod)
+		return DefaultKillGracePeriod
+	}
+	return grace
+}
+
+// processCommand is an exec.Cmd whose pending SIGKILL is called off once
+// the process has been waited for
+type processCommand struct {
+	*exec.Cmd
+	kill *groupKill
+}
+
+// commandContext returns a command that is stopped, along with any children
+// it spawned, when ctx is cancelled: it is asked to exit with SIGTERM and
+// SIGKILLed if it is still running after grace
+func commandContext(ctx context.Context, grace time.Duration, name string, args ...string) *processCommand {
+	cmd := exec.CommandContext(ctx, name, args...)
+	kill := setProcessGroup(cmd, grace)
+	cmd.WaitDelay = grace + processWaitDelay
+	return &processCommand{Cmd: cmd, kill: kill}
+}
+
+// Run starts the command and waits for it to complete
+func (c *processCommand) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Wait waits for the command to exit, then releases its pending SIGKILL
+func (c *processCommand) Wait() error {
+	err := c.Cmd.Wait()
+	c.kill.release()
+	return err
+}
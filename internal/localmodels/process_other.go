@@ -0,0 +1,18 @@
+//go:build !unix
+
+package localmodels
+
+import (
+	"os/exec"
+	"time"
+)
+
+// groupKill is empty where process groups are unavailable
+type groupKill struct{}
+
+// release is a no-op; nothing is pending
+func (g *groupKill) release() {}
+
+// setProcessGroup is a no-op where process groups and SIGTERM are
+// unavailable; context cancellation kills only the direct child
+func setProcessGroup(cmd *exec.Cmd, grace time.Duration) *groupKill { return nil }
@@ -0,0 +1,96 @@
+//go:build unix
+
+package localmodels
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// groupKill is the SIGKILL of a cancelled command's process group, sent
+// when the group outlives the grace period after SIGTERM
+type groupKill struct {
+	mu     sync.Mutex
+	pgid   int
+	path   string
+	timer  *time.Timer // Pending SIGKILL, nil when none is scheduled
+	exited bool        // The command was waited for
+}
+
+// setProcessGroup starts cmd in its own process group and makes context
+// cancellation stop the whole group, so helpers forked by llama.cpp binaries
+// do not outlive the task. The group gets SIGTERM first so the model can shut
+// down cleanly, then SIGKILL if any member is still running after grace.
+func setProcessGroup(cmd *exec.Cmd, grace time.Duration) *groupKill {
+	kill := &groupKill{path: cmd.Path}
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := cmd.Process.Pid
+		if grace <= 0 {
+			return syscall.Kill(-pgid, syscall.SIGKILL)
+		}
+
+		if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
+			if errors.Is(err, syscall.ESRCH) {
+				return nil
+			}
+			return syscall.Kill(-pgid, syscall.SIGKILL)
+		}
+		kill.schedule(pgid, grace)
+		return nil
+	}
+	return kill
+}
+
+// schedule SIGKILLs the group pgid after grace unless the command is waited
+// for first
+func (g *groupKill) schedule(pgid int, grace time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.exited {
+		return
+	}
+
+	g.pgid = pgid
+	g.timer = time.AfterFunc(grace, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.timer == nil {
+			return
+		}
+		g.timer = nil
+		g.killRemaining(fmt.Sprintf("did not exit %v after SIGTERM", grace))
+	})
+}
+
+// release calls off the pending SIGKILL once the command was waited for, so
+// it never reaches a later group reusing the id. Group members the exited
+// leader left behind are killed now instead.
+func (g *groupKill) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.exited = true
+	if g.timer == nil {
+		return
+	}
+	g.timer.Stop()
+	g.timer = nil
+	g.killRemaining("outlived its leader after SIGTERM")
+}
+
+// killRemaining SIGKILLs the group if any member is still running. Callers
+// must hold g.mu.
+func (g *groupKill) killRemaining(reason string) {
+	// Signal 0 only checks whether any process in the group remains
+	if syscall.Kill(-g.pgid, 0) != nil {
+		return
+	}
+	log.Printf("Warning: process group %d (%s) %s, sending SIGKILL", g.pgid, g.path, reason)
+	syscall.Kill(-g.pgid, syscall.SIGKILL)
+}
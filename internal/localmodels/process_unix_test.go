@@ -0,0 +1,180 @@
+//go:build unix
+
+package localmodels
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCommandContextKillsProcessIgnoringSIGTERM(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := commandContext(ctx, 100*time.Millisecond, "sh", "-c", `trap "" TERM; echo ready; while :; do sleep 0.05; done`)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("StdoutPipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	// Cancel only once the trap is installed
+	if _, err := stdout.Read(make([]byte, 16)); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	start := time.Now()
+	cancel()
+	if err := cmd.Wait(); err == nil {
+		t.Fatal("Wait succeeded for a killed process")
+	}
+	// Stopped by the SIGKILL after grace rather than by the WaitDelay fallback
+	if elapsed := time.Since(start); elapsed >= processWaitDelay {
+		t.Errorf("process stopped after %v, want about the 100ms grace", elapsed)
+	}
+}
+
+func TestCommandContextWaitReleasesKill(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel bool
+	}{
+		{"exited after SIGTERM", true},
+		{"exited by itself", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			script := "exit 0"
+			if tt.cancel {
+				script = "sleep 30"
+			}
+			cmd := commandContext(ctx, time.Minute, "sh", "-c", script)
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			if tt.cancel {
+				cancel()
+			}
+			cmd.Wait()
+
+			cmd.kill.mu.Lock()
+			defer cmd.kill.mu.Unlock()
+			if cmd.kill.timer != nil {
+				t.Error("SIGKILL still scheduled after Wait returned")
+			}
+			if !cmd.kill.exited {
+				t.Error("Wait did not release the pending kill")
+			}
+		})
+	}
+}
+
+// processAlive reports whether pid is running; zombies count as exited
+func processAlive(pid int) bool {
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid)); err == nil {
+		// The state follows the parenthesised command name
+		fields := strings.Fields(string(data[bytes.LastIndexByte(data, ')')+1:]))
+		return len(fields) > 0 && fields[0] != "Z"
+	}
+	return syscall.Kill(pid, 0) == nil
+}
+
+// waitForPID reads the pid a fake model binary wrote to path
+func waitForPID(t *testing.T, path string) int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(path); err == nil {
+			if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+				return pid
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no pid written to %s", path)
+	return 0
+}
+
+// assertExits fails unless pid exits within a few seconds
+func assertExits(t *testing.T, pid int) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("process %d outlived its cancelled command", pid)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// forkingBinary writes a fake model binary that forks a long-running child,
+// records the child's pid in pidPath and waits for it
+func forkingBinary(t *testing.T, pidPath string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "llama-server")
+	script := fmt.Sprintf("#!/bin/sh\nsleep 30 &\necho $! > %s\nwait\n", pidPath)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	return path
+}
+
+func TestCommandContextKillsChildren(t *testing.T) {
+	pidPath := filepath.Join(t.TempDir(), "child.pid")
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := commandContext(ctx, 100*time.Millisecond, forkingBinary(t, pidPath))
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	child := waitForPID(t, pidPath)
+
+	cancel()
+	cmd.Wait()
+	assertExits(t, child)
+}
+
+func TestCancelledServerStartupLeavesNoProcess(t *testing.T) {
+	// Nothing listens on the port, so the fake server never turns healthy
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	serverURL := "http://" + listener.Addr().String()
+	listener.Close()
+
+	pidPath := filepath.Join(t.TempDir(), "child.pid")
+	model := &QwenTextModel{config: ModelConfig{
+		Name:       "qwen",
+		BinaryPath: forkingBinary(t, pidPath),
+		Parameters: map[string]string{ParamKillGracePeriod: "100ms"},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan error, 1)
+	go func() { started <- model.startServer(ctx, serverURL, ModelInput{Text: "hello"}) }()
+	child := waitForPID(t, pidPath)
+
+	cancel()
+	if err := <-started; err == nil || !strings.Contains(err.Error(), "cancelled") {
+		t.Fatalf("startServer = %v, want a cancellation error", err)
+	}
+	assertExits(t, child)
+
+	model.serverMu.Lock()
+	defer model.serverMu.Unlock()
+	if model.server != nil {
+		t.Error("model still tracks the stopped server")
+	}
+}
@@ -9,10 +9,18 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/tokenizer"
+)
+
+// llama-server startup polling
+const (
+	serverStartTimeout = 60 * time.Second
+	serverPollInterval = 500 * time.Millisecond
 )
 
 // QwenTextModel implements Qwen2.5-Omni-3B for text-only tasks
@@ -20,6 +28,11 @@ type QwenTextModel struct {
 	config   ModelConfig
 	isLoaded bool
 	lastUsed time.Time
+
+	serverMu sync.Mutex
+	server   *processCommand    // llama-server started by this model, if any
+	stopFunc context.CancelFunc // Cancels the server's context
+	exited   chan struct{}      // Closed once the server process has been reaped
 }
 
 // NewQwenTextModel creates a new Qwen text model wrapper
@@ -50,6 +63,7 @@ func (q *QwenTextModel) Load(ctx context.Context) error {
 // Unload releases model resources
 func (q *QwenTextModel) Unload(ctx context.Context) error {
 	log.Printf("Qwen2.5-Omni-3B (Text): Releasing model resources")
+	q.stopServer()
 	q.isLoaded = false
 	return nil
 }
@@ -74,16 +88,9 @@ func (q *QwenTextModel) Predict(ctx context.Context, input ModelInput) (*ModelOu
 
 	// Check if server is already running
 	if !q.isServerRunning(serverURL) {
-		log.Printf("Qwen2.5-Omni-3B (Text): Starting llama-server on port %s", serverPort)
-		args := q.buildTextCommandArgs(input)
-		cmd := exec.CommandContext(ctx, q.config.BinaryPath, args...)
-
-		if err := cmd.Start(); err != nil {
-			return nil, fmt.Errorf("failed to start llama-server: %w", err)
+		if err := q.startServer(ctx, serverURL, input); err != nil {
+			return nil, err
 		}
-
-		// Wait for server to start
-		time.Sleep(10 * time.Second)
 	}
 
 	log.Printf("Qwen2.5-Omni-3B (Text): Making inference request")
@@ -97,33 +104,18 @@ func (q *QwenTextModel) Predict(ctx context.Context, input ModelInput) (*ModelOu
 		"top_p":          0.9,
 		"repeat_penalty": 1.1,
 	}
+	if seed, ok := q.config.seed(input); ok {
+		requestBody["seed"] = seed
+	}
 
 	requestJSON, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Make HTTP request to llama-server
-	resp, err := http.Post(serverURL+"/completion", "application/json", bytes.NewBuffer(requestJSON))
-	if err != nil {
-		return nil, fmt.Errorf("failed to make inference request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	response, output, err := q.completeNonEmpty(ctx, serverURL, requestJSON)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Parse response
-	var response map[string]interface{}
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	output, ok := response["content"].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid response format: %s", string(body))
+		return nil, err
 	}
 
 	processingTime := time.Since(startTime)
@@ -132,12 +124,22 @@ func (q *QwenTextModel) Predict(ctx context.Context, input ModelInput) (*ModelOu
 	promptTokens := len(strings.Fields(input.Text))
 	completionTokens := len(strings.Fields(output))
 
+	finishReason := llamaServerFinishReason(response)
+	contextUsed := llamaServerContextUsed(response, input.Text, output)
+	if finishReason == FinishReasonLength {
+		log.Printf("Qwen2.5-Omni-3B (Text): Output truncated at token limit (%d)", getMaxTokens(input.MaxTokens))
+	}
+
 	log.Printf("Qwen2.5-Omni-3B (Text): Inference completed in %v", processingTime)
 
 	return &ModelOutput{
 		Text:           output,
 		ProcessingTime: processingTime,
 		TokensUsed:     promptTokens + completionTokens,
+		FinishReason:   finishReason,
+		Truncated:      finishReason == FinishReasonLength,
+		ContextUsed:    contextUsed,
+		ContextLimit:   q.config.ContextLength(),
 		Metadata: map[string]string{
 			"model_name":     q.config.Name,
 			"model_type":     string(q.config.Type),
@@ -147,19 +149,183 @@ func (q *QwenTextModel) Predict(ctx context.Context, input ModelInput) (*ModelOu
 	}, nil
 }
 
+// completeNonEmpty requests a completion, retrying empty ones so a blank
+// document never flows downstream as a success
+func (q *QwenTextModel) completeNonEmpty(ctx context.Context, serverURL string, requestJSON []byte) (map[string]interface{}, string, error) {
+	maxRetries := q.config.IntParameter(ParamEmptyRetries, DefaultEmptyRetries)
+	for attempt := 0; ; attempt++ {
+		response, output, err := q.requestCompletion(ctx, serverURL, requestJSON)
+		if err != nil {
+			return nil, "", err
+		}
+		if strings.TrimSpace(output) != "" {
+			return response, output, nil
+		}
+		if attempt >= maxRetries {
+			return nil, "", fmt.Errorf("%w after %d attempts", ErrEmptyCompletion, attempt+1)
+		}
+		log.Printf("Qwen2.5-Omni-3B (Text): Empty completion, retrying (%d/%d)", attempt+1, maxRetries)
+	}
+}
+
+// requestCompletion sends one completion request to llama-server and returns
+// the parsed response and its content
+func (q *QwenTextModel) requestCompletion(ctx context.Context, serverURL string, requestJSON []byte) (map[string]interface{}, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, serverURL+"/completion", bytes.NewReader(requestJSON))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create inference request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to make inference request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// Parse response
+	var response map[string]interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	output, ok := response["content"].(string)
+	if !ok {
+		return nil, "", fmt.Errorf("invalid response format: %s", string(body))
+	}
+	return response, output, nil
+}
+
 // buildTextCommandArgs constructs arguments for text-only inference
 func (q *QwenTextModel) buildTextCommandArgs(input ModelInput) []string {
 	// For text-only, use llama-server for inference
 	args := []string{
 		"--model", q.config.ModelPath,
 		"--port", "8082", // Use different port to avoid conflicts
-		"-ngl", "37", // GPU layers for 3B model
-		"--ctx-size", "8192",
+		"-ngl", strconv.Itoa(q.config.IntParameter(ParamGPULayers, 37)), // GPU layers for 3B model
+		"--ctx-size", strconv.Itoa(q.config.ContextLength()),
+	}
+
+	// Batch size and threads use llama-server defaults unless tuned
+	if q.config.HasParameter(ParamBatchSize) {
+		args = append(args, "-b", strconv.Itoa(q.config.IntParameter(ParamBatchSize, 2048)))
+	}
+	if q.config.HasParameter(ParamThreads) {
+		args = append(args, "-t", strconv.Itoa(q.config.IntParameter(ParamThreads, 16)))
 	}
 
 	return args
 }
 
+// llamaServerFinishReason maps llama-server /completion stop flags to a finish reason
+func llamaServerFinishReason(response map[string]interface{}) string {
+	if stopped, _ := response["stopped_limit"].(bool); stopped {
+		return FinishReasonLength
+	}
+	stoppedEOS, _ := response["stopped_eos"].(bool)
+	stoppedWord, _ := response["stopped_word"].(bool)
+	if stoppedEOS || stoppedWord {
+		return FinishReasonStop
+	}
+	return ""
+}
+
+// llamaServerContextUsed returns the prompt and completion token counts
+// llama-server reports, estimating them when the response omits them
+func llamaServerContextUsed(response map[string]interface{}, prompt, output string) int {
+	evaluated, hasEvaluated := response["tokens_evaluated"].(float64)
+	predicted, hasPredicted := response["tokens_predicted"].(float64)
+	if hasEvaluated && hasPredicted {
+		return int(evaluated) + int(predicted)
+	}
+	return tokenizer.Estimate(prompt) + tokenizer.Estimate(output)
+}
+
+// startServer launches llama-server and waits until it reports healthy. The
+// server outlives a single request, so it runs under a context owned by the
+// model and is stopped by Unload; if ctx is cancelled before the server is
+// ready, it is killed rather than left running in the background.
+func (q *QwenTextModel) startServer(ctx context.Context, serverURL string, input ModelInput) error {
+	q.serverMu.Lock()
+	defer q.serverMu.Unlock()
+
+	// Another request may have started it while we waited for the lock
+	if q.isServerRunning(serverURL) {
+		return nil
+	}
+	q.stopServerLocked()
+
+	log.Printf("Qwen2.5-Omni-3B (Text): Starting llama-server at %s", serverURL)
+	serverCtx, cancel := context.WithCancel(context.Background())
+	cmd := commandContext(serverCtx, q.config.KillGracePeriod(), q.config.BinaryPath, q.buildTextCommandArgs(input)...)
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return fmt.Errorf("failed to start llama-server: %w", err)
+	}
+
+	// Reap the process whenever it exits so it never lingers as a zombie
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	q.server = cmd
+	q.stopFunc = cancel
+	q.exited = exited
+
+	deadline := time.NewTimer(serverStartTimeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(serverPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			q.stopServerLocked()
+			return fmt.Errorf("llama-server startup cancelled: %w", ctx.Err())
+		case <-exited:
+			q.stopServerLocked()
+			return fmt.Errorf("llama-server exited during startup")
+		case <-deadline.C:
+			q.stopServerLocked()
+			return fmt.Errorf("llama-server not healthy after %v", serverStartTimeout)
+		case <-ticker.C:
+			if q.isServerRunning(serverURL) {
+				return nil
+			}
+		}
+	}
+}
+
+// stopServer kills the llama-server started by this model, if any
+func (q *QwenTextModel) stopServer() {
+	q.serverMu.Lock()
+	defer q.serverMu.Unlock()
+	q.stopServerLocked()
+}
+
+// stopServerLocked kills the server and waits for it to be reaped; the
+// caller must hold serverMu
+func (q *QwenTextModel) stopServerLocked() {
+	if q.server == nil {
+		return
+	}
+
+	q.stopFunc()
+	<-q.exited
+	log.Printf("Qwen2.5-Omni-3B (Text): Stopped llama-server (pid %d)", q.server.Process.Pid)
+
+	q.server = nil
+	q.stopFunc = nil
+	q.exited = nil
+}
+
 // isServerRunning checks if llama-server is running on the given URL
 func (q *QwenTextModel) isServerRunning(serverURL string) bool {
 	resp, err := http.Get(serverURL + "/health")
@@ -247,13 +413,22 @@ func (q *QwenMultimodalModel) Predict(ctx context.Context, input ModelInput) (*M
 	startTime := time.Now()
 	q.lastUsed = startTime
 
+	// Write inline images to files and fit all images to the size and formats the model accepts
+	imagePaths, cleanup, err := PrepareImages(q.config, input)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	input.ImagePaths = imagePaths
+	input.ImageData = nil
+
 	// Build command arguments for multimodal inference
 	args := q.buildMultimodalCommandArgs(input)
 
 	log.Printf("Qwen2.5-Omni-3B (Multimodal): Running multimodal inference")
 
 	// Execute the command using llama-mtmd-cli
-	cmd := exec.CommandContext(ctx, q.config.BinaryPath, args...)
+	cmd := commandContext(ctx, q.config.KillGracePeriod(), q.config.BinaryPath, args...)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -282,6 +457,8 @@ func (q *QwenMultimodalModel) Predict(ctx context.Context, input ModelInput) (*M
 		Text:           output,
 		ProcessingTime: processingTime,
 		TokensUsed:     promptTokens + completionTokens,
+		ContextUsed:    tokenizer.Estimate(input.Text) + tokenizer.Estimate(output),
+		ContextLimit:   q.config.ContextLength(),
 		Metadata: map[string]string{
 			"model_name":     q.config.Name,
 			"model_type":     string(q.config.Type),
@@ -294,18 +471,24 @@ func (q *QwenMultimodalModel) Predict(ctx context.Context, input ModelInput) (*M
 
 // buildMultimodalCommandArgs constructs arguments for multimodal inference
 func (q *QwenMultimodalModel) buildMultimodalCommandArgs(input ModelInput) []string {
+	// Hardware tuning comes from model parameters, defaulting to values for a 3B model
+	gpuLayers := strconv.Itoa(q.config.IntParameter(ParamGPULayers, 10))
+	batchSize := strconv.Itoa(q.config.IntParameter(ParamBatchSize, 1024))
+	threads := strconv.Itoa(q.config.IntParameter(ParamThreads, 16))
+	contextLength := strconv.Itoa(q.config.ContextLength())
+
 	// Based on your example for Qwen multimodal
 	args := []string{
 		"--offline",
 		"--mmproj", q.projectorPath,
 		"-m", q.config.ModelPath,
-		"-ngl", "10", // Lower for 3B model
-		"-fa",        // Flash attention
-		"-b", "1024", // Smaller batch for 3B
-		"-t", "16", // Threads
+		"-ngl", gpuLayers, // Lower for 3B model
+		"-fa",           // Flash attention
+		"-b", batchSize, // Smaller batch for 3B
+		"-t", threads, // Threads
 		"-p", input.Text,
 		"--temp", fmt.Sprintf("%.2f", getTemperature(input.Temperature)),
-		"--ctx-size", "8192",
+		"--ctx-size", contextLength,
 		"-np", "16",
 		"--prio-batch", "2",
 		"--no-mmproj-offload",
@@ -326,6 +509,10 @@ func (q *QwenMultimodalModel) buildMultimodalCommandArgs(input ModelInput) []str
 		args = append(args, "--n-predict", "-2")
 	}
 
+	if seed, ok := q.config.seed(input); ok {
+		args = append(args, "--seed", strconv.Itoa(seed))
+	}
+
 	return args
 }
 
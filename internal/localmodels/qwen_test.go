@@ -0,0 +1,124 @@
+package localmodels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/tokenizer"
+)
+
+func TestLlamaServerFinishReason(t *testing.T) {
+	tests := []struct {
+		name     string
+		response map[string]interface{}
+		want     string
+	}{
+		{"token limit", map[string]interface{}{"stopped_limit": true, "stopped_eos": false}, FinishReasonLength},
+		{"end of sequence", map[string]interface{}{"stopped_eos": true}, FinishReasonStop},
+		{"stop word", map[string]interface{}{"stopped_word": true}, FinishReasonStop},
+		{"not reported", map[string]interface{}{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := llamaServerFinishReason(tt.response); got != tt.want {
+				t.Errorf("llamaServerFinishReason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLlamaServerContextUsed(t *testing.T) {
+	prompt, output := "write a guide to error wrapping", "wrap errors with fmt.Errorf and %w"
+	estimate := tokenizer.Estimate(prompt) + tokenizer.Estimate(output)
+
+	tests := []struct {
+		name     string
+		response map[string]interface{}
+		want     int
+	}{
+		{"reported", map[string]interface{}{"tokens_evaluated": 12.0, "tokens_predicted": 30.0}, 42},
+		{"estimated", map[string]interface{}{}, estimate},
+		{"partly reported", map[string]interface{}{"tokens_evaluated": 12.0}, estimate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := llamaServerContextUsed(tt.response, prompt, output); got != tt.want {
+				t.Errorf("llamaServerContextUsed = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteNonEmptyRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses []string // Content returned on each call, the last repeating
+		retries   string   // empty_retries parameter; default when blank
+		want      string
+		wantCalls int32
+		wantErr   error
+	}{
+		{"first answer", []string{"document"}, "", "document", 1, nil},
+		{"empty then answer", []string{"", "document"}, "", "document", 2, nil},
+		{"whitespace counts as empty", []string{" \n\t", "document"}, "", "document", 2, nil},
+		{"retries exhausted", []string{""}, "", "", 3, ErrEmptyCompletion},
+		{"configured retries", []string{"", "", "", "document"}, "3", "document", 4, nil},
+		{"retries disabled", []string{"", "document"}, "0", "", 1, ErrEmptyCompletion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				call := int(calls.Add(1)) - 1
+				content := tt.responses[min(call, len(tt.responses)-1)]
+				fmt.Fprintf(w, `{"content":%q,"stopped_eos":true}`, content)
+			}))
+			defer server.Close()
+
+			model := &QwenTextModel{config: ModelConfig{Name: "qwen", Parameters: map[string]string{}}}
+			if tt.retries != "" {
+				model.config.Parameters[ParamEmptyRetries] = tt.retries
+			}
+
+			_, output, err := model.completeNonEmpty(context.Background(), server.URL, []byte(`{"prompt":"write"}`))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if output != tt.want || calls.Load() != tt.wantCalls {
+				t.Errorf("output %q after %d calls, want %q after %d", output, calls.Load(), tt.want, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRequestCompletionErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not json", "model loading"},
+		{"missing content", `{"error":"out of memory"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			model := &QwenTextModel{config: ModelConfig{Name: "qwen"}}
+			if _, _, err := model.completeNonEmpty(context.Background(), server.URL, nil); err == nil || errors.Is(err, ErrEmptyCompletion) {
+				t.Errorf("err = %v, want a response error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package localmodels
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Parameter keys for reasoning output in ModelConfig.Parameters
+const (
+	ParamStripReasoning = "strip_reasoning" // "false" keeps reasoning sections in the output
+	ParamReasoningTags  = "reasoning_tags"  // Comma-separated tag names wrapping reasoning
+)
+
+// DefaultReasoningTags are stripped when a model does not configure its own
+var DefaultReasoningTags = []string{"think"}
+
+// ReasoningTags returns the tags whose sections are removed from the model's
+// output, or nil when stripping is disabled
+func (c ModelConfig) ReasoningTags() []string {
+	if enabled, err := strconv.ParseBool(strings.TrimSpace(c.Parameters[ParamStripReasoning])); err == nil && !enabled {
+		return nil
+	}
+
+	configured, exists := c.Parameters[ParamReasoningTags]
+	if !exists {
+		return DefaultReasoningTags
+	}
+
+	var tags []string
+	for _, tag := range strings.Split(configured, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// StripReasoning removes <tag>...</tag> sections for each tag. An opening tag
+// that is never closed removes the rest of the output, and a closing tag with
+// no opening tag removes everything before it, which happens when the chat
+// template opens the reasoning section in the prompt.
+func StripReasoning(text string, tags []string) string {
+	stripped := text
+	for _, tag := range tags {
+		name := regexp.QuoteMeta(tag)
+		closed := regexp.MustCompile(`(?is)<` + name + `>.*?</` + name + `>`)
+		stripped = closed.ReplaceAllString(stripped, "")
+
+		if orphan := regexp.MustCompile(`(?is)^.*?</` + name + `>`); orphan.MatchString(stripped) {
+			stripped = orphan.ReplaceAllString(stripped, "")
+		}
+		if unclosed := regexp.MustCompile(`(?is)<` + name + `>.*$`); unclosed.MatchString(stripped) {
+			stripped = unclosed.ReplaceAllString(stripped, "")
+		}
+	}
+
+	if stripped == text {
+		return text
+	}
+	return strings.TrimSpace(stripped)
+}
+
+// reasoningFilter removes reasoning sections from a model's predictions
+type reasoningFilter struct {
+	Model
+	tags []string
+}
+
+// filterReasoning wraps model so its output has the configured reasoning
+// sections removed; models with stripping disabled are returned unchanged
+func filterReasoning(model Model, config ModelConfig) Model {
+	tags := config.ReasoningTags()
+	if len(tags) == 0 {
+		return model
+	}
+	return &reasoningFilter{Model: model, tags: tags}
+}
+
+// Predict runs the prediction and strips reasoning from the text
+func (r *reasoningFilter) Predict(ctx context.Context, input ModelInput) (*ModelOutput, error) {
+	output, err := r.Model.Predict(ctx, input)
+	if output != nil {
+		output.Text = StripReasoning(output.Text, r.tags)
+	}
+	return output, err
+}
@@ -0,0 +1,83 @@
+package localmodels
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestStripReasoning(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		tags []string
+		want string
+	}{
+		{"think block", "<think>plan the outline</think>\n# Document\nBody", DefaultReasoningTags, "# Document\nBody"},
+		{"multiline and mixed case", "<THINK>\nstep one\nstep two\n</Think>Answer", DefaultReasoningTags, "Answer"},
+		{"several blocks", "<think>a</think>First<think>b</think> second", DefaultReasoningTags, "First second"},
+		{"unclosed tag drops the rest", "Answer\n<think>still reasoning", DefaultReasoningTags, "Answer"},
+		{"orphan closing tag drops the prefix", "reasoning from the template</think>\nAnswer", DefaultReasoningTags, "Answer"},
+		{"custom tags", "<reasoning>why</reasoning>Answer<think>kept</think>", []string{"reasoning"}, "Answer<think>kept</think>"},
+		{"no reasoning keeps whitespace", "  Answer\n", DefaultReasoningTags, "  Answer\n"},
+		{"no tags", "<think>kept</think>Answer", nil, "<think>kept</think>Answer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripReasoning(tt.text, tt.tags); got != tt.want {
+				t.Errorf("StripReasoning() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReasoningTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		want       []string
+	}{
+		{"default", nil, DefaultReasoningTags},
+		{"configured", map[string]string{ParamReasoningTags: "think, reasoning ,"}, []string{"think", "reasoning"}},
+		{"disabled", map[string]string{ParamStripReasoning: "false"}, nil},
+		{"explicitly enabled", map[string]string{ParamStripReasoning: "true"}, DefaultReasoningTags},
+		{"empty tag list", map[string]string{ParamReasoningTags: ""}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ModelConfig{Parameters: tt.parameters}.ReasoningTags()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReasoningTags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterReasoning(t *testing.T) {
+	// stubModel answers with its name
+	model := &stubModel{name: "<think>draft</think>Final answer"}
+
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		want       string
+	}{
+		{"strips by default", nil, "Final answer"},
+		{"disabled per model", map[string]string{ParamStripReasoning: "false"}, model.name},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered := filterReasoning(model, ModelConfig{Parameters: tt.parameters})
+			output, err := filtered.Predict(context.Background(), ModelInput{})
+			if err != nil {
+				t.Fatalf("Predict() error = %v", err)
+			}
+			if output.Text != tt.want {
+				t.Errorf("Predict() text = %q, want %q", output.Text, tt.want)
+			}
+		})
+	}
+}
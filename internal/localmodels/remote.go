@@ -0,0 +1,129 @@
+package localmodels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RemoteModel proxies inference to a model owned by a shared model daemon,
+// so colocated workers reuse one llama-server instead of spawning their own
+type RemoteModel struct {
+	daemonURL  string
+	modelName  string // Model key in the daemon's configuration
+	config     ModelConfig
+	httpClient *http.Client
+	isLoaded   bool
+	lastUsed   time.Time
+}
+
+// NewRemoteModel creates a model backed by the daemon at daemonURL
+func NewRemoteModel(daemonURL, modelName string, config ModelConfig) *RemoteModel {
+	return &RemoteModel{
+		daemonURL:  strings.TrimRight(daemonURL, "/"),
+		modelName:  modelName,
+		config:     config,
+		httpClient: &http.Client{},
+	}
+}
+
+// Load asks the daemon to load the model if it is not already resident
+func (r *RemoteModel) Load(ctx context.Context) error {
+	if err := r.post(ctx, "load", nil, nil); err != nil {
+		return err
+	}
+
+	r.isLoaded = true
+	r.lastUsed = time.Now()
+	log.Printf("%s: attached to model daemon at %s", r.config.Name, r.daemonURL)
+	return nil
+}
+
+// Unload detaches from the model. The daemon keeps it resident for other workers.
+func (r *RemoteModel) Unload(ctx context.Context) error {
+	r.isLoaded = false
+	return nil
+}
+
+// IsLoaded returns whether the model is attached
+func (r *RemoteModel) IsLoaded() bool {
+	return r.isLoaded
+}
+
+// Predict forwards the input to the daemon and returns its output
+func (r *RemoteModel) Predict(ctx context.Context, input ModelInput) (*ModelOutput, error) {
+	if !r.isLoaded {
+		return nil, fmt.Errorf("model not loaded")
+	}
+
+	r.lastUsed = time.Now()
+
+	var output ModelOutput
+	if err := r.post(ctx, "predict", input, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+// GetName returns the model name
+func (r *RemoteModel) GetName() string {
+	return r.config.Name
+}
+
+// GetType returns the model type
+func (r *RemoteModel) GetType() ModelType {
+	return r.config.Type
+}
+
+// GetMemoryUsage returns zero - GPU memory is accounted for by the daemon
+func (r *RemoteModel) GetMemoryUsage() uint64 {
+	return 0
+}
+
+// post calls a daemon model endpoint, decoding the JSON response into out when non-nil
+func (r *RemoteModel) post(ctx context.Context, action string, body interface{}, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+	}
+
+	endpoint := fmt.Sprintf("%s/models/%s/%s", r.daemonURL, url.PathEscape(r.modelName), action)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("model daemon request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read daemon response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("model daemon returned %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to parse daemon response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package localmodels
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRemoteModel(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		if strings.HasSuffix(r.URL.Path, "/predict") {
+			var input ModelInput
+			json.NewDecoder(r.Body).Decode(&input)
+			json.NewEncoder(w).Encode(ModelOutput{Text: "echo: " + input.Text, FinishReason: FinishReasonStop})
+		}
+	}))
+	defer server.Close()
+
+	model := NewRemoteModel(server.URL+"/", "qwen omni", ModelConfig{Name: "qwen-omni-3b", Type: ModelTypeText})
+	ctx := context.Background()
+
+	if _, err := model.Predict(ctx, ModelInput{Text: "hi"}); err == nil {
+		t.Error("Predict succeeded before Load")
+	}
+	if err := model.Load(ctx); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	output, err := model.Predict(ctx, ModelInput{Text: "hi"})
+	if err != nil {
+		t.Fatalf("Predict: %v", err)
+	}
+	if output.Text != "echo: hi" || output.FinishReason != FinishReasonStop {
+		t.Errorf("output = %+v", output)
+	}
+
+	want := []string{"/models/qwen%20omni/load", "/models/qwen%20omni/predict"}
+	if strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("daemon paths = %q, want %q", paths, want)
+	}
+
+	// Unloading only detaches; the daemon is not asked to unload
+	model.Unload(ctx)
+	if model.IsLoaded() || len(paths) != 2 {
+		t.Errorf("Unload: loaded %v, %d daemon requests", model.IsLoaded(), len(paths))
+	}
+}
+
+func TestRemoteModelDaemonErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"error status", http.StatusServiceUnavailable, "model not configured\n", "returned 503: model not configured"},
+		{"bad output", http.StatusOK, "not json", "failed to parse daemon response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/load") {
+					return
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			model := NewRemoteModel(server.URL, "qwen", ModelConfig{})
+			if err := model.Load(context.Background()); err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			_, err := model.Predict(context.Background(), ModelInput{Text: "hi"})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Predict err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package localmodels
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RolePreferences maps a worker role name to the models it should run on,
+// most preferred first, e.g. "reviewer" -> [fast model, capable model].
+// Entries may be configured model keys or aliases.
+type RolePreferences map[string][]string
+
+// rolePreferencesFile mirrors the role_preferences section of configs/models.yaml
+type rolePreferencesFile struct {
+	RolePreferences RolePreferences `yaml:"role_preferences"`
+}
+
+// LoadRolePreferences reads the role_preferences section of a model
+// configuration file. A file without one yields no preferences.
+func LoadRolePreferences(configPath string) (RolePreferences, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model configuration: %w", err)
+	}
+
+	var file rolePreferencesFile
+	if err := yaml.Unmarshal([]byte(expandEnvDefaults(string(data))), &file); err != nil {
+		return nil, fmt.Errorf("failed to parse role preferences: %w", err)
+	}
+	return file.RolePreferences, nil
+}
+
+// For returns the preferred models of a role, most preferred first
+func (p RolePreferences) For(role string) []string {
+	return p[role]
+}
+
+// Validate checks that every preferred model, after alias resolution, is configured
+func (p RolePreferences) Validate(models map[string]ModelConfig, aliases ModelAliases) error {
+	var problems []string
+	for role, preferred := range p {
+		for _, name := range preferred {
+			if _, exists := models[aliases.Resolve(name)]; !exists {
+				problems = append(problems, fmt.Sprintf("role %s prefers unknown model %s", role, name))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("invalid role preferences: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package localmodels
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadRolePreferences(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		want    RolePreferences
+		wantErr bool
+	}{
+		{
+			name: "configured",
+			path: write("preferences.yaml", "role_preferences:\n  reviewer: [qwen-text, qwen-omni-3b]\n  developer: [qwen-omni-3b]\n"),
+			want: RolePreferences{"reviewer": {"qwen-text", "qwen-omni-3b"}, "developer": {"qwen-omni-3b"}},
+		},
+		{name: "no section", path: write("models.yaml", "models: {}\n")},
+		{name: "missing file", path: filepath.Join(dir, "missing.yaml"), wantErr: true},
+		{name: "invalid yaml", path: write("invalid.yaml", "role_preferences: [\n"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadRolePreferences(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadRolePreferences() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadRolePreferences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolePreferencesValidate(t *testing.T) {
+	models := map[string]ModelConfig{"qwen-omni-3b": {Name: "qwen-omni-3b"}}
+	aliases := ModelAliases{"qwen-text": "qwen-omni-3b"}
+
+	tests := []struct {
+		name        string
+		preferences RolePreferences
+		wantErr     string
+	}{
+		{name: "models and aliases", preferences: RolePreferences{"reviewer": {"qwen-text", "qwen-omni-3b"}}},
+		{name: "none", preferences: nil},
+		{name: "unknown model", preferences: RolePreferences{"developer": {"qwen-omni-3b", "phi-4"}}, wantErr: "role developer prefers unknown model phi-4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.preferences.Validate(models, aliases)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRepoRolePreferencesAreValid(t *testing.T) {
+	path := filepath.Join("..", "..", "configs", "models.yaml")
+	preferences, err := LoadRolePreferences(path)
+	if err != nil {
+		t.Fatalf("LoadRolePreferences() error = %v", err)
+	}
+	aliases, err := LoadModelAliases(path)
+	if err != nil {
+		t.Fatalf("LoadModelAliases() error = %v", err)
+	}
+	models, err := LoadModelConfigs(path)
+	if err != nil {
+		t.Fatalf("LoadModelConfigs() error = %v", err)
+	}
+	if err := preferences.Validate(models, aliases); err != nil {
+		t.Errorf("configs/models.yaml: %v", err)
+	}
+}
@@ -2,6 +2,10 @@ package localmodels
 
 import (
 	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -33,6 +37,56 @@ type ModelConfig struct {
 	Type          ModelType         `yaml:"type"`
 	MemoryLimit   uint64            `yaml:"memory_limit"` // MB
 	Parameters    map[string]string `yaml:"parameters,omitempty"`
+	FallbackModel string            `yaml:"fallback_model,omitempty"` // Text model used when multimodal inference fails
+}
+
+// Parameter keys for llama.cpp runtime tuning in ModelConfig.Parameters
+const (
+	ParamGPULayers     = "gpu_layers"
+	ParamContextLength = "context_length"
+	ParamBatchSize     = "batch_size"
+	ParamThreads       = "threads"
+	ParamEmptyRetries  = "empty_retries"
+	ParamSeed          = "seed"
+	ParamMaxConcurrent = "max_concurrent" // Overrides the manager's max_concurrent_inference for one model
+)
+
+// DefaultEmptyRetries is how many times an empty completion is retried before failing
+const DefaultEmptyRetries = 2
+
+// ErrEmptyCompletion is returned when a model keeps producing empty output
+var ErrEmptyCompletion = errors.New("model returned an empty completion")
+
+// DefaultContextLength is the context window used when a model does not configure one
+const DefaultContextLength = 8192
+
+// ContextWarningRatio is the share of the context window at which usage is near the limit
+const ContextWarningRatio = 0.9
+
+// ContextLength returns the model's configured context window in tokens
+func (c ModelConfig) ContextLength() int {
+	return c.IntParameter(ParamContextLength, DefaultContextLength)
+}
+
+// IntParameter returns an integer parameter, or fallback when unset or invalid
+func (c ModelConfig) IntParameter(key string, fallback int) int {
+	value, exists := c.Parameters[key]
+	if !exists || strings.TrimSpace(value) == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		log.Printf("Warning: model %s has invalid %s %q, using %d", c.Name, key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+// HasParameter reports whether a parameter is explicitly configured
+func (c ModelConfig) HasParameter(key string) bool {
+	_, exists := c.Parameters[key]
+	return exists
 }
 
 // ModelInput represents input to a model
@@ -42,16 +96,44 @@ type ModelInput struct {
 	ImageData   [][]byte `json:"image_data,omitempty"`  // Base64 decoded image data
 	Temperature float64  `json:"temperature,omitempty"`
 	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Seed        int      `json:"seed,omitempty"` // Fixed sampling seed for reproducible output; zero means random
+}
+
+// seed returns the sampling seed for a request, preferring the input over the
+// model's configured seed parameter
+func (c ModelConfig) seed(input ModelInput) (int, bool) {
+	if input.Seed != 0 {
+		return input.Seed, true
+	}
+	if c.HasParameter(ParamSeed) {
+		return c.IntParameter(ParamSeed, 0), true
+	}
+	return 0, false
 }
 
+// Finish reasons reported in ModelOutput
+const (
+	FinishReasonStop   = "stop"   // Natural end of generation or stop word
+	FinishReasonLength = "length" // Hit the token limit - output is truncated
+)
+
 // ModelOutput represents output from a model
 type ModelOutput struct {
 	Text           string            `json:"text"`
 	ProcessingTime time.Duration     `json:"processing_time"`
 	TokensUsed     int               `json:"tokens_used,omitempty"`
+	FinishReason   string            `json:"finish_reason,omitempty"` // Empty when the backend does not report it
+	Truncated      bool              `json:"truncated,omitempty"`
+	ContextUsed    int               `json:"context_used,omitempty"`  // Prompt plus completion tokens
+	ContextLimit   int               `json:"context_limit,omitempty"` // Model context window in tokens
 	Metadata       map[string]string `json:"metadata,omitempty"`
 }
 
+// NearContextLimit reports whether the request used at least ContextWarningRatio of the context window
+func (o *ModelOutput) NearContextLimit() bool {
+	return o.ContextLimit > 0 && float64(o.ContextUsed) >= ContextWarningRatio*float64(o.ContextLimit)
+}
+
 // GPUMemoryInfo stores GPU memory usage information
 type GPUMemoryInfo struct {
 	Total     uint64    `json:"total"` // Total GPU memory (MB)
@@ -66,6 +148,14 @@ type ModelManagerConfig struct {
 	NvidiaSMIPath   string                 `yaml:"nvidia_smi_path"`
 	MonitorInterval time.Duration          `yaml:"monitor_interval"`
 	Models          map[string]ModelConfig `yaml:"models"`
+	DaemonURL       string                 `yaml:"daemon_url,omitempty"` // Delegate model lifecycle to a shared model daemon
+	Aliases         ModelAliases           `yaml:"aliases,omitempty"`    // Friendly names for Models keys, validated by NewManager
+
+	// MaxConcurrentInference bounds simultaneous Predict calls per model; zero uses DefaultMaxConcurrentInference
+	MaxConcurrentInference int `yaml:"max_concurrent_inference,omitempty"`
+
+	// MemoryHeadroom is GPU memory (MB) kept free beyond a model's limit for context growth during inference
+	MemoryHeadroom uint64 `yaml:"memory_headroom,omitempty"`
 }
 
 // LoadingState represents the current state of model loading/unloading
@@ -0,0 +1,154 @@
+package localmodels
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIntParameter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		set   bool
+		want  int
+	}{
+		{"unset", "", false, 37},
+		{"set", "24", true, 24},
+		{"padded", " 24 ", true, 24},
+		{"blank", "  ", true, 37},
+		{"invalid", "all", true, 37},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ModelConfig{Name: "qwen", Parameters: map[string]string{}}
+			if tt.set {
+				config.Parameters[ParamGPULayers] = tt.value
+			}
+			if got := config.IntParameter(ParamGPULayers, 37); got != tt.want {
+				t.Errorf("IntParameter = %d, want %d", got, tt.want)
+			}
+			if got := config.HasParameter(ParamGPULayers); got != tt.set {
+				t.Errorf("HasParameter = %v, want %v", got, tt.set)
+			}
+		})
+	}
+}
+
+// flagValue returns the argument following flag, or "" when flag is absent
+func flagValue(args []string, flag string) string {
+	if i := slices.Index(args, flag); i >= 0 && i+1 < len(args) {
+		return args[i+1]
+	}
+	return ""
+}
+
+func TestCommandArgsUseParameters(t *testing.T) {
+	tuned := ModelConfig{Parameters: map[string]string{
+		ParamGPULayers:     "12",
+		ParamContextLength: "4096",
+		ParamBatchSize:     "256",
+		ParamThreads:       "4",
+	}}
+	want := map[string]string{"-ngl": "12", "--ctx-size": "4096", "-b": "256", "-t": "4"}
+
+	builders := map[string]func(ModelConfig) []string{
+		"qwen text": func(c ModelConfig) []string { return (&QwenTextModel{config: c}).buildTextCommandArgs(ModelInput{}) },
+		"qwen multimodal": func(c ModelConfig) []string {
+			return (&QwenMultimodalModel{config: c}).buildMultimodalCommandArgs(ModelInput{})
+		},
+		"minicpm": func(c ModelConfig) []string { return (&MiniCPMModel{config: c}).buildCommandArgs(ModelInput{}) },
+	}
+
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			args := build(tuned)
+			for flag, value := range want {
+				if got := flagValue(args, flag); got != value {
+					t.Errorf("%s = %q, want %q", flag, got, value)
+				}
+			}
+		})
+	}
+
+	// Untuned, the text server leaves batch size and threads to llama-server
+	args := (&QwenTextModel{}).buildTextCommandArgs(ModelInput{})
+	if flagValue(args, "-ngl") != "37" || slices.Contains(args, "-b") || slices.Contains(args, "-t") {
+		t.Errorf("default text args = %q", args)
+	}
+}
+
+func TestNearContextLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ModelConfig
+		used   int
+		want   bool
+	}{
+		{"default window", ModelConfig{}, 7000, false},
+		{"default window nearly full", ModelConfig{}, 7400, true},
+		{"configured window", ModelConfig{Parameters: map[string]string{ParamContextLength: "4096"}}, 3700, true},
+		{"larger window", ModelConfig{Parameters: map[string]string{ParamContextLength: "32768"}}, 7400, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := &ModelOutput{ContextUsed: tt.used, ContextLimit: tt.config.ContextLength()}
+			if got := output.NearContextLimit(); got != tt.want {
+				t.Errorf("NearContextLimit with %d of %d = %v, want %v", tt.used, output.ContextLimit, got, tt.want)
+			}
+		})
+	}
+
+	if (&ModelOutput{ContextUsed: 100}).NearContextLimit() {
+		t.Error("NearContextLimit = true without a known limit")
+	}
+}
+
+func TestSeed(t *testing.T) {
+	seeded := ModelConfig{Parameters: map[string]string{ParamSeed: "7"}}
+
+	tests := []struct {
+		name     string
+		config   ModelConfig
+		input    ModelInput
+		want     int
+		wantSeed bool
+	}{
+		{"input seed", ModelConfig{}, ModelInput{Seed: 42}, 42, true},
+		{"configured seed", seeded, ModelInput{}, 7, true},
+		{"input overrides config", seeded, ModelInput{Seed: 42}, 42, true},
+		{"no seed", ModelConfig{}, ModelInput{}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.config.seed(tt.input)
+			if got != tt.want || ok != tt.wantSeed {
+				t.Errorf("seed = %d, %v, want %d, %v", got, ok, tt.want, tt.wantSeed)
+			}
+		})
+	}
+}
+
+func TestCommandArgsSeed(t *testing.T) {
+	builders := map[string]func(ModelConfig, ModelInput) []string{
+		"qwen multimodal": func(c ModelConfig, input ModelInput) []string {
+			return (&QwenMultimodalModel{config: c}).buildMultimodalCommandArgs(input)
+		},
+		"minicpm": func(c ModelConfig, input ModelInput) []string {
+			return (&MiniCPMModel{config: c}).buildCommandArgs(input)
+		},
+	}
+
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			if got := flagValue(build(ModelConfig{}, ModelInput{Seed: 42}), "--seed"); got != "42" {
+				t.Errorf("--seed = %q, want 42", got)
+			}
+			if args := build(ModelConfig{}, ModelInput{}); slices.Contains(args, "--seed") {
+				t.Errorf("unseeded args = %q, want no --seed", args)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -15,6 +16,9 @@ type RAGService interface {
 	AddDocument(ctx context.Context, content string, metadata map[string]interface{}) error
 }
 
+// ErrNoCollection is returned when a search names no collection and no default is configured
+var ErrNoCollection = errors.New("no collection specified and no default collection configured")
+
 // MCPClient provides integration between local models and external tools
 type MCPClient struct {
 	ragService RAGService
@@ -28,6 +32,9 @@ type Config struct {
 	MaxRetries     int           `yaml:"max_retries"`
 	RetryDelay     time.Duration `yaml:"retry_delay"`
 	EnableToolCall bool          `yaml:"enable_tool_call"`
+
+	// DefaultCollection is searched when a call names no collection; empty makes such calls fail
+	DefaultCollection string `yaml:"default_collection"`
 }
 
 // ToolCall represents a tool call request
@@ -52,13 +59,35 @@ func NewMCPClient(config *Config, ragService RAGService) *MCPClient {
 	}
 }
 
-// SearchKnowledge searches the RAG database for relevant information
-func (c *MCPClient) SearchKnowledge(ctx context.Context, query string, limit int) (*ToolResponse, error) {
+// resolveCollection returns collection, or the configured default when it is
+// empty. Searches fail closed rather than fall through to a backend default.
+func (c *MCPClient) resolveCollection(collection string) (string, error) {
+	if collection != "" {
+		return collection, nil
+	}
+	if c.config != nil && c.config.DefaultCollection != "" {
+		return c.config.DefaultCollection, nil
+	}
+	return "", ErrNoCollection
+}
+
+// SearchKnowledge searches a RAG collection for relevant information. An
+// empty collection uses the configured default.
+func (c *MCPClient) SearchKnowledge(ctx context.Context, collection, query string, limit int) (*ToolResponse, error) {
 	start := time.Now()
 
+	collection, err := c.resolveCollection(collection)
+	if err != nil {
+		return &ToolResponse{
+			Error:    fmt.Sprintf("Search failed: %v", err),
+			Duration: time.Since(start),
+		}, err
+	}
+
 	ragQuery := types.RAGQuery{
-		Query: query,
-		TopK:  limit,
+		Query:      query,
+		Collection: collection,
+		TopK:       limit,
 	}
 
 	results, err := c.ragService.SearchKnowledge(ctx, ragQuery)
@@ -105,22 +134,33 @@ func (c *MCPClient) AddKnowledge(ctx context.Context, content string, metadata m
 func (c *MCPClient) GetContext(ctx context.Context, task string, contextType string) (*ToolResponse, error) {
 	start := time.Now()
 
-	// Build context-specific query
-	var query string
+	// Build context-specific query; context types backed by a collection search it directly
+	var query, collection string
 	switch contextType {
 	case "coding_standards":
 		query = fmt.Sprintf("coding standards guidelines for: %s", task)
+		collection = contextType
 	case "documentation":
 		query = fmt.Sprintf("documentation examples for: %s", task)
+		collection = contextType
 	case "git_changes":
 		query = fmt.Sprintf("recent changes related to: %s", task)
 	default:
 		query = task
 	}
 
+	collection, err := c.resolveCollection(collection)
+	if err != nil {
+		return &ToolResponse{
+			Error:    fmt.Sprintf("Context retrieval failed: %v", err),
+			Duration: time.Since(start),
+		}, err
+	}
+
 	ragQuery := types.RAGQuery{
-		Query: query,
-		TopK:  3,
+		Query:      query,
+		Collection: collection,
+		TopK:       3,
 	}
 	results, err := c.ragService.SearchKnowledge(ctx, ragQuery)
 	if err != nil {
@@ -174,11 +214,12 @@ func (c *MCPClient) executeToolCallOnce(ctx context.Context, toolCall *ToolCall)
 	switch toolCall.Name {
 	case "search_knowledge":
 		query, _ := toolCall.Parameters["query"].(string)
+		collection, _ := toolCall.Parameters["collection"].(string)
 		limit, _ := toolCall.Parameters["limit"].(int)
 		if limit == 0 {
 			limit = 5
 		}
-		return c.SearchKnowledge(timeoutCtx, query, limit)
+		return c.SearchKnowledge(timeoutCtx, collection, query, limit)
 
 	case "add_knowledge":
 		content, _ := toolCall.Parameters["content"].(string)
@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// recordingRAG records the queries it is asked
+type recordingRAG struct {
+	queries []types.RAGQuery
+}
+
+func (r *recordingRAG) SearchKnowledge(ctx context.Context, query types.RAGQuery) (*types.RAGResponse, error) {
+	r.queries = append(r.queries, query)
+	return &types.RAGResponse{Query: query.Query}, nil
+}
+
+func (r *recordingRAG) AddDocument(context.Context, string, map[string]interface{}) error { return nil }
+
+func TestSearchKnowledgeCollection(t *testing.T) {
+	tests := []struct {
+		name              string
+		defaultCollection string
+		collection        string
+		want              string
+		wantErr           error
+	}{
+		{"explicit collection", "documentation", "coding_standards", "coding_standards", nil},
+		{"default collection", "documentation", "", "documentation", nil},
+		{"no collection", "", "", "", ErrNoCollection},
+	}
+
+	// Without any config, searches still fail closed
+	if _, err := NewMCPClient(nil, &recordingRAG{}).SearchKnowledge(context.Background(), "", "error handling", 3); !errors.Is(err, ErrNoCollection) {
+		t.Errorf("nil config: err = %v, want ErrNoCollection", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rag := &recordingRAG{}
+			client := NewMCPClient(&Config{DefaultCollection: tt.defaultCollection}, rag)
+
+			response, err := client.SearchKnowledge(context.Background(), tt.collection, "error handling", 3)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(rag.queries) != 0 || response.Error == "" {
+					t.Errorf("searched %d times, response error %q; want no search and an error", len(rag.queries), response.Error)
+				}
+				return
+			}
+			if len(rag.queries) != 1 || rag.queries[0].Collection != tt.want {
+				t.Errorf("queries = %+v, want one on %s", rag.queries, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetContextCollection(t *testing.T) {
+	tests := []struct {
+		name              string
+		defaultCollection string
+		contextType       string
+		want              string
+		wantErr           error
+	}{
+		{"collection context", "", "coding_standards", "coding_standards", nil},
+		{"context without collection uses default", "documentation", "git_changes", "documentation", nil},
+		{"context without collection or default", "", "git_changes", "", ErrNoCollection},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rag := &recordingRAG{}
+			client := NewMCPClient(&Config{DefaultCollection: tt.defaultCollection}, rag)
+
+			_, err := client.GetContext(context.Background(), "add retries", tt.contextType)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(rag.queries) != 0 {
+					t.Errorf("searched %+v, want no search", rag.queries)
+				}
+				return
+			}
+			if len(rag.queries) != 1 || rag.queries[0].Collection != tt.want {
+				t.Errorf("queries = %+v, want one on %s", rag.queries, tt.want)
+			}
+		})
+	}
+}
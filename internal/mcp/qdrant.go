@@ -2,8 +2,14 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -19,6 +25,9 @@ type QdrantMCPConfig struct {
 	Timeout    time.Duration `json:"timeout"`
 	MaxRetries int           `json:"max_retries"`
 	RetryDelay time.Duration `json:"retry_delay"`
+
+	// DefaultCollection is searched when a call names no collection
+	DefaultCollection string `json:"default_collection"`
 }
 
 // NewQdrantMCPClient creates a new Qdrant MCP client
@@ -29,6 +38,8 @@ func NewQdrantMCPClient(config *QdrantMCPConfig, ragService RAGService) *QdrantM
 		MaxRetries:     config.MaxRetries,
 		RetryDelay:     config.RetryDelay,
 		EnableToolCall: true,
+
+		DefaultCollection: config.DefaultCollection,
 	}
 
 	return &QdrantMCPClient{
@@ -120,8 +131,9 @@ func (q *QdrantMCPClient) SearchPoints(ctx context.Context, collectionName strin
 	toolCall := &ToolCall{
 		Name: "search_knowledge",
 		Parameters: map[string]interface{}{
-			"query": fmt.Sprintf("Search in collection %s with limit %d", collectionName, limit),
-			"limit": limit,
+			"query":      fmt.Sprintf("Search in collection %s with limit %d", collectionName, limit),
+			"collection": collectionName,
+			"limit":      limit,
 		},
 	}
 
@@ -166,31 +178,117 @@ func (q *QdrantMCPClient) DeleteCollection(ctx context.Context, name string) err
 	return nil
 }
 
-// GetCollectionInfo gets information about a collection
+// GetCollectionInfo reads a collection's vector configuration and point
+// count from the Qdrant REST API
 func (q *QdrantMCPClient) GetCollectionInfo(ctx context.Context, name string) (*CollectionInfo, error) {
-	toolCall := &ToolCall{
-		Name: "search_knowledge",
-		Parameters: map[string]interface{}{
-			"query": fmt.Sprintf("Get info for collection: %s", name),
-			"limit": 1,
-		},
+	if name == "" {
+		return nil, fmt.Errorf("collection name is required")
 	}
 
-	result, err := q.client.ExecuteToolCall(ctx, toolCall)
+	if q.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.config.Timeout)
+		defer cancel()
+	}
+
+	endpoint := fmt.Sprintf("%s/collections/%s", qdrantRESTURL(q.config.QdrantURL), url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection info request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get collection info: %w", err)
 	}
+	defer resp.Body.Close()
 
-	if result.Error != "" {
-		return nil, fmt.Errorf("tool returned error: %s", result.Error)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collection info: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Status struct {
+				Error string `json:"error"`
+			} `json:"status"`
+		}
+		if json.Unmarshal(body, &failure) == nil && failure.Status.Error != "" {
+			return nil, fmt.Errorf("collection %s: %s", name, failure.Status.Error)
+		}
+		return nil, fmt.Errorf("collection %s: Qdrant returned %d: %s", name, resp.StatusCode, string(body))
+	}
+
+	return ParseCollectionInfo(name, body)
+}
+
+// qdrantRESTURL adds the http scheme to a host:port Qdrant address
+func qdrantRESTURL(address string) string {
+	if address == "" {
+		address = "localhost:6333"
+	}
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		address = "http://" + address
+	}
+	return strings.TrimSuffix(address, "/")
+}
+
+// vectorParams is the size and distance of one vector in a collection
+type vectorParams struct {
+	Size     int    `json:"size"`
+	Distance string `json:"distance"`
+}
+
+// ParseCollectionInfo parses a Qdrant GET /collections/{name} response.
+// Collections with named vectors report the first vector by name.
+func ParseCollectionInfo(name string, body []byte) (*CollectionInfo, error) {
+	var response struct {
+		Result *struct {
+			PointsCount int `json:"points_count"`
+			Config      struct {
+				Params struct {
+					Vectors json.RawMessage `json:"vectors"`
+				} `json:"params"`
+			} `json:"config"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse collection info: %w", err)
+	}
+	if response.Result == nil {
+		return nil, fmt.Errorf("collection info for %s has no result", name)
 	}
 
-	// Parse collection info from response
 	info := &CollectionInfo{
-		Name: name,
-		// Other fields would be parsed from structured response
+		Name:        name,
+		PointsCount: response.Result.PointsCount,
 	}
 
+	vectors := response.Result.Config.Params.Vectors
+	if len(vectors) == 0 {
+		return info, nil
+	}
+
+	var single vectorParams
+	if err := json.Unmarshal(vectors, &single); err == nil && single.Size > 0 {
+		info.VectorSize, info.Distance = single.Size, single.Distance
+		return info, nil
+	}
+
+	var named map[string]vectorParams
+	if err := json.Unmarshal(vectors, &named); err != nil {
+		return nil, fmt.Errorf("failed to parse vector config of %s: %w", name, err)
+	}
+	names := make([]string, 0, len(named))
+	for vectorName := range named {
+		names = append(names, vectorName)
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		first := named[names[0]]
+		info.VectorSize, info.Distance = first.Size, first.Distance
+	}
 	return info, nil
 }
 
@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// collectionInfoResponse is a trimmed Qdrant GET /collections/{name} response
+const collectionInfoResponse = `{
+  "result": {
+    "status": "green",
+    "points_count": 1280,
+    "config": {"params": {"vectors": {"size": 2560, "distance": "Cosine"}}}
+  },
+  "status": "ok",
+  "time": 0.0004
+}`
+
+func TestParseCollectionInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    CollectionInfo
+		wantErr bool
+	}{
+		{
+			name: "single vector",
+			body: collectionInfoResponse,
+			want: CollectionInfo{Name: "documentation", VectorSize: 2560, Distance: "Cosine", PointsCount: 1280},
+		},
+		{
+			name: "named vectors report the first by name",
+			body: `{"result": {"points_count": 3, "config": {"params": {"vectors": {
+				"text": {"size": 768, "distance": "Dot"},
+				"image": {"size": 512, "distance": "Euclid"}}}}}}`,
+			want: CollectionInfo{Name: "documentation", VectorSize: 512, Distance: "Euclid", PointsCount: 3},
+		},
+		{
+			name: "no vector config",
+			body: `{"result": {"points_count": 0}}`,
+			want: CollectionInfo{Name: "documentation"},
+		},
+		{name: "no result", body: `{"status": "ok"}`, wantErr: true},
+		{name: "invalid json", body: `{"result":`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCollectionInfo("documentation", []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCollectionInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && *got != tt.want {
+				t.Errorf("ParseCollectionInfo() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCollectionInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/collections/documentation" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status": {"error": "Collection not found"}}`))
+			return
+		}
+		w.Write([]byte(collectionInfoResponse))
+	}))
+	defer server.Close()
+
+	// The configured address is host:port, as for the gRPC client
+	client := NewQdrantMCPClient(&QdrantMCPConfig{QdrantURL: strings.TrimPrefix(server.URL, "http://")}, &recordingRAG{})
+	ctx := context.Background()
+
+	info, err := client.GetCollectionInfo(ctx, "documentation")
+	if err != nil {
+		t.Fatalf("GetCollectionInfo() error = %v", err)
+	}
+	if info.VectorSize != 2560 || info.Distance != "Cosine" || info.PointsCount != 1280 {
+		t.Errorf("GetCollectionInfo() = %+v", *info)
+	}
+
+	_, err = client.GetCollectionInfo(ctx, "missing")
+	if err == nil || !strings.Contains(err.Error(), "Collection not found") {
+		t.Errorf("missing collection: err = %v, want Qdrant's error", err)
+	}
+
+	if _, err := client.GetCollectionInfo(ctx, ""); err == nil {
+		t.Error("empty name: err = nil, want error")
+	}
+}
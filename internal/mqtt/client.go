@@ -3,6 +3,8 @@ package mqtt
 import (
 	"context"
 	"fmt"
+	"log"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -28,8 +30,10 @@ type Client struct {
 	port      int
 	clientID  string
 	client    pahomqtt.Client
+	options   ClientOptions
 	connected bool
 	mu        sync.RWMutex
+	connectMu sync.Mutex // Serializes Connect calls without holding mu while they wait
 }
 
 // ClientOptions configures MQTT client behavior
@@ -38,6 +42,8 @@ type ClientOptions struct {
 	ConnectTimeout       time.Duration
 	ReconnectBackoff     time.Duration
 	MaxReconnectInterval time.Duration
+	MaxConnectAttempts   int // Initial connect attempts before giving up (bounded by ctx)
+	CompressThreshold    int // Gzip published payloads of at least this many bytes (0 disables)
 }
 
 // DefaultClientOptions returns sensible defaults
@@ -47,6 +53,7 @@ func DefaultClientOptions() ClientOptions {
 		ConnectTimeout:       10 * time.Second,
 		ReconnectBackoff:     1 * time.Second,
 		MaxReconnectInterval: 30 * time.Second,
+		MaxConnectAttempts:   5,
 	}
 }
 
@@ -58,24 +65,32 @@ func NewClient(host string, port int) *Client {
 
 // NewClientWithID creates a new MQTT client with explicit client ID
 func NewClientWithID(host string, port int, clientID string) *Client {
+	return NewClientWithOptions(host, port, clientID, DefaultClientOptions())
+}
+
+// NewClientWithOptions creates a new MQTT client with explicit client ID and options
+func NewClientWithOptions(host string, port int, clientID string, options ClientOptions) *Client {
 	return &Client{
 		host:     host,
 		port:     port,
 		clientID: clientID,
+		options:  options,
 	}
 }
 
 // Connect establishes connection to MQTT broker
 func (c *Client) Connect(ctx context.Context) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	c.connectMu.Lock()
+	defer c.connectMu.Unlock()
 
 	// Return early if already connected
+	c.mu.Lock()
 	if c.connected && c.client.IsConnected() {
+		c.mu.Unlock()
 		return nil
 	}
 
-	opts := DefaultClientOptions()
+	opts := c.options
 
 	// Create MQTT client options
 	clientOpts := pahomqtt.NewClientOptions()
@@ -100,28 +115,82 @@ func (c *Client) Connect(ctx context.Context) error {
 		c.mu.Unlock()
 	})
 
-	c.client = pahomqtt.NewClient(clientOpts)
+	client := pahomqtt.NewClient(clientOpts)
+	c.client = client
+	c.mu.Unlock()
 
-	// Connect with context timeout
-	done := make(chan error, 1)
-	go func() {
-		token := c.client.Connect()
-		token.Wait()
-		done <- token.Error()
-	}()
+	maxAttempts := opts.MaxConnectAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	// Retry with exponential backoff and jitter so a broker that is briefly
+	// unavailable during startup does not take the worker down. The lock is
+	// not held while waiting, so Publish and IsConnected fail fast meanwhile.
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(connectBackoff(opts, attempt)):
+			case <-ctx.Done():
+				return fmt.Errorf("connection timeout after %d attempts: %w (last error: %v)", attempt, ctx.Err(), lastErr)
+			}
+		}
+
+		err := c.connectOnce(ctx, client)
+		if err == nil {
+			c.mu.Lock()
+			c.connected = true
+			c.mu.Unlock()
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		lastErr = err
+	}
+
+	return fmt.Errorf("failed to connect to MQTT broker at %s:%d after %d attempts: %w", c.host, c.port, maxAttempts, lastErr)
+}
+
+// connectOnce performs a single connection attempt bounded by ctx. An
+// attempt cut short by ctx is disconnected and waited for, so it cannot
+// complete after Connect has given up.
+func (c *Client) connectOnce(ctx context.Context, client pahomqtt.Client) error {
+	token := client.Connect()
 
 	select {
-	case err := <-done:
-		if err != nil {
+	case <-token.Done():
+		if err := token.Error(); err != nil {
 			return fmt.Errorf("failed to connect to MQTT broker at %s:%d: %w", c.host, c.port, err)
 		}
-		c.connected = true
 		return nil
 	case <-ctx.Done():
+		client.Disconnect(0)
+		<-token.Done() // Bounded by ConnectTimeout
 		return fmt.Errorf("connection timeout: %w", ctx.Err())
 	}
 }
 
+// connectBackoff returns the delay before the given retry attempt:
+// exponential growth from ReconnectBackoff capped at MaxReconnectInterval, plus up to 50% jitter
+func connectBackoff(opts ClientOptions, attempt int) time.Duration {
+	backoff := opts.ReconnectBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if opts.MaxReconnectInterval > 0 && backoff >= opts.MaxReconnectInterval {
+			backoff = opts.MaxReconnectInterval
+			break
+		}
+	}
+
+	jitter := time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+	return backoff + jitter
+}
+
 // Disconnect closes the MQTT connection
 func (c *Client) Disconnect() {
 	c.mu.Lock()
@@ -141,15 +210,49 @@ func (c *Client) IsConnected() bool {
 	return c.connected && c.client != nil && c.client.IsConnected()
 }
 
+// SetCompressThreshold gzips published payloads of at least threshold bytes;
+// zero disables compression. Call before publishing.
+func (c *Client) SetCompressThreshold(threshold int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.options.CompressThreshold = threshold
+}
+
+// RetainedPublisher is implemented by clients that can publish retained
+// messages, which the broker replays to every new subscriber
+type RetainedPublisher interface {
+	PublishRetained(ctx context.Context, topic string, payload []byte) error
+}
+
 // Publish sends a message to the specified topic
 func (c *Client) Publish(ctx context.Context, topic string, payload []byte) error {
+	return c.publish(ctx, topic, payload, false)
+}
+
+// PublishRetained sends a message the broker keeps for later subscribers.
+// An empty payload clears the retained message on topic.
+func (c *Client) PublishRetained(ctx context.Context, topic string, payload []byte) error {
+	return c.publish(ctx, topic, payload, true)
+}
+
+// publish sends a message with QoS 1
+func (c *Client) publish(ctx context.Context, topic string, payload []byte, retained bool) error {
 	if !c.IsConnected() {
 		return fmt.Errorf("client not connected")
 	}
 
+	c.mu.RLock()
+	threshold := c.options.CompressThreshold
+	c.mu.RUnlock()
+
+	payload, err := compressPayload(payload, threshold)
+	if err != nil {
+		return err
+	}
+
 	// Use QoS 1 for reliable delivery
 	const qos = 1
-	const retained = false
 
 	done := make(chan error, 1)
 	go func() {
@@ -178,8 +281,14 @@ func (c *Client) Subscribe(ctx context.Context, topic string, handler MessageHan
 	// Use QoS 1 for reliable delivery
 	const qos = 1
 
+	// Compressed payloads are expanded transparently, whatever the local threshold
 	messageHandler := func(client pahomqtt.Client, msg pahomqtt.Message) {
-		handler(msg.Payload())
+		payload, err := decompressPayload(msg.Payload())
+		if err != nil {
+			log.Printf("Dropping message on %s: %v", msg.Topic(), err)
+			return
+		}
+		handler(payload)
 	}
 
 	done := make(chan error, 1)
@@ -0,0 +1,195 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnectBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ClientOptions
+		attempt int
+		want    time.Duration // Delay before jitter, which adds up to half again
+	}{
+		{"first retry", ClientOptions{ReconnectBackoff: 100 * time.Millisecond}, 1, 100 * time.Millisecond},
+		{"doubles", ClientOptions{ReconnectBackoff: 100 * time.Millisecond}, 3, 400 * time.Millisecond},
+		{"capped", ClientOptions{ReconnectBackoff: 100 * time.Millisecond, MaxReconnectInterval: 250 * time.Millisecond}, 5, 250 * time.Millisecond},
+		{"default base", ClientOptions{}, 1, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 20 {
+				got := connectBackoff(tt.opts, tt.attempt)
+				if got < tt.want || got > tt.want+tt.want/2 {
+					t.Fatalf("connectBackoff = %v, want %v plus up to 50%% jitter", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// unusedPort returns a local port nothing is listening on
+func unusedPort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	return port
+}
+
+// serveConnack accepts MQTT connections on listener and accepts every CONNECT
+func serveConnack(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			header := make([]byte, 2)
+			if _, err := io.ReadFull(conn, header); err != nil {
+				return
+			}
+			if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+				return
+			}
+			conn.Write([]byte{0x20, 0x02, 0x00, 0x00})
+			io.Copy(io.Discard, conn)
+		}()
+	}
+}
+
+// testOptions retries quickly up to attempts times
+func testOptions(attempts int) ClientOptions {
+	return ClientOptions{
+		KeepAlive:            30 * time.Second,
+		ConnectTimeout:       time.Second,
+		ReconnectBackoff:     20 * time.Millisecond,
+		MaxReconnectInterval: 50 * time.Millisecond,
+		MaxConnectAttempts:   attempts,
+	}
+}
+
+func TestConnectRetriesUntilBrokerIsUp(t *testing.T) {
+	port := unusedPort(t)
+
+	// The broker comes up after the first attempt has been refused
+	started := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+		if err != nil {
+			started <- nil
+			return
+		}
+		started <- listener
+		serveConnack(listener)
+	}()
+
+	client := NewClientWithOptions("127.0.0.1", port, "retry-test", testOptions(20))
+	err := client.Connect(context.Background())
+	if listener := <-started; listener == nil {
+		t.Skip("could not listen on the reserved port")
+	} else {
+		defer listener.Close()
+	}
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	defer client.Disconnect()
+	if !client.IsConnected() {
+		t.Error("IsConnected = false after Connect")
+	}
+}
+
+func TestConnectGivesUp(t *testing.T) {
+	port := unusedPort(t)
+
+	client := NewClientWithOptions("127.0.0.1", port, "give-up-test", testOptions(3))
+	err := client.Connect(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("Connect = %v, want failure after 3 attempts", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	client = NewClientWithOptions("127.0.0.1", port, "give-up-test", testOptions(1000))
+	if err := client.Connect(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Connect = %v, want the context deadline", err)
+	}
+}
+
+func TestConnectDoesNotBlockDuringBackoff(t *testing.T) {
+	port := unusedPort(t)
+	options := testOptions(2)
+	options.ReconnectBackoff = time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := NewClientWithOptions("127.0.0.1", port, "backoff-test", options)
+	done := make(chan error, 1)
+	go func() { done <- client.Connect(ctx) }()
+
+	time.Sleep(100 * time.Millisecond) // The first attempt is refused at once
+	checked := make(chan bool, 1)
+	go func() { checked <- client.IsConnected() }()
+	select {
+	case connected := <-checked:
+		if connected {
+			t.Error("IsConnected = true while the broker is down")
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Error("IsConnected blocked while Connect was backing off")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Connect = %v, want the context cancellation", err)
+	}
+}
+
+func TestConnectAbandonsAttemptOnTimeout(t *testing.T) {
+	// The broker accepts connections but never answers CONNECT
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	options := testOptions(1)
+	options.ConnectTimeout = 300 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	client := NewClientWithOptions("127.0.0.1", listener.Addr().(*net.TCPAddr).Port, "abandon-test", options)
+	if err := client.Connect(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Connect = %v, want the context deadline", err)
+	}
+
+	// By the time Connect returns the attempt's connection is closed
+	conn := <-accepted
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, err := io.Copy(io.Discard, conn); err != nil {
+		t.Errorf("abandoned connection still open: %v", err)
+	}
+	if client.IsConnected() {
+		t.Error("IsConnected = true after Connect gave up")
+	}
+}
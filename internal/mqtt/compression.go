@@ -0,0 +1,64 @@
+package mqtt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// MaxDecompressedSize bounds how large a compressed payload may expand
+const MaxDecompressedSize = 64 << 20
+
+// gzipMagic prefixes every gzip stream. JSON payloads never start with it, so
+// it doubles as the flag marking a payload as compressed.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// compressPayload gzips payload when it is at least threshold bytes and
+// compression actually makes it smaller. A threshold of zero disables it.
+func compressPayload(payload []byte, threshold int) ([]byte, error) {
+	if threshold <= 0 || len(payload) < threshold {
+		return payload, nil
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(payload); err != nil {
+		return nil, fmt.Errorf("failed to compress payload: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress payload: %w", err)
+	}
+
+	if buf.Len() >= len(payload) {
+		return payload, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// isCompressed reports whether payload carries the gzip marker
+func isCompressed(payload []byte) bool {
+	return bytes.HasPrefix(payload, gzipMagic)
+}
+
+// decompressPayload returns payload unchanged unless it is gzip compressed
+func decompressPayload(payload []byte) ([]byte, error) {
+	if !isCompressed(payload) {
+		return payload, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open compressed payload: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, MaxDecompressedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress payload: %w", err)
+	}
+	if len(data) > MaxDecompressedSize {
+		return nil, fmt.Errorf("decompressed payload exceeds %d bytes", MaxDecompressedSize)
+	}
+	return data, nil
+}
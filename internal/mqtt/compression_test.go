@@ -0,0 +1,77 @@
+package mqtt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+)
+
+func TestCompressPayloadRoundTrip(t *testing.T) {
+	document := []byte(`{"version":1,"type":"workflow_result","payload":{"result":"` + strings.Repeat("Wrap errors with context. ", 2000) + `"}}`)
+	random := make([]byte, 4096)
+	rand.Read(random)
+
+	tests := []struct {
+		name        string
+		payload     []byte
+		threshold   int
+		wantSmaller bool
+	}{
+		{"large document", document, 1024, true},
+		{"below threshold", []byte(`{"id":"t1"}`), 1024, false},
+		{"disabled", document, 0, false},
+		{"incompressible", random, 1024, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wire, err := compressPayload(tt.payload, tt.threshold)
+			if err != nil {
+				t.Fatalf("compressPayload: %v", err)
+			}
+			if compressed := isCompressed(wire); compressed != tt.wantSmaller {
+				t.Errorf("compressed = %v, want %v", compressed, tt.wantSmaller)
+			}
+			if tt.wantSmaller && len(wire) >= len(tt.payload)/10 {
+				t.Errorf("wire size %d bytes for a %d byte document", len(wire), len(tt.payload))
+			}
+			if !tt.wantSmaller && !bytes.Equal(wire, tt.payload) {
+				t.Error("payload changed although it was not compressed")
+			}
+
+			received, err := decompressPayload(wire)
+			if err != nil {
+				t.Fatalf("decompressPayload: %v", err)
+			}
+			if !bytes.Equal(received, tt.payload) {
+				t.Error("round trip changed the payload")
+			}
+		})
+	}
+}
+
+func TestDecompressPayloadErrors(t *testing.T) {
+	bomb, err := compressPayload(make([]byte, MaxDecompressedSize+1), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		payload []byte
+		wantErr string
+	}{
+		{"corrupt stream", append(append([]byte{}, gzipMagic...), "not gzip"...), "failed to open compressed payload"},
+		{"truncated stream", bomb[:len(bomb)/2], "failed to decompress payload"},
+		{"too large", bomb, "exceeds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decompressPayload(tt.payload); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
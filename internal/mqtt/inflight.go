@@ -0,0 +1,63 @@
+package mqtt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// InflightLimiter bounds the number of published tasks awaiting a result.
+// Publishers call Acquire before publishing and Release when the result arrives.
+type InflightLimiter struct {
+	slots       chan struct{}
+	mu          sync.Mutex
+	outstanding map[string]struct{}
+}
+
+// NewInflightLimiter creates a limiter allowing at most maxInflight outstanding tasks.
+// A non-positive maxInflight disables limiting.
+func NewInflightLimiter(maxInflight int) *InflightLimiter {
+	limiter := &InflightLimiter{
+		outstanding: make(map[string]struct{}),
+	}
+	if maxInflight > 0 {
+		limiter.slots = make(chan struct{}, maxInflight)
+	}
+	return limiter
+}
+
+// Acquire reserves a slot for taskID, blocking while the inflight cap is reached
+func (l *InflightLimiter) Acquire(ctx context.Context, taskID string) error {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for inflight slot: %w", ctx.Err())
+		}
+	}
+
+	l.mu.Lock()
+	l.outstanding[taskID] = struct{}{}
+	l.mu.Unlock()
+	return nil
+}
+
+// Release frees the slot held by taskID. Unknown task IDs are ignored so
+// duplicate or foreign results cannot free slots they never held.
+func (l *InflightLimiter) Release(taskID string) {
+	l.mu.Lock()
+	_, exists := l.outstanding[taskID]
+	delete(l.outstanding, taskID)
+	l.mu.Unlock()
+
+	if exists && l.slots != nil {
+		<-l.slots
+	}
+}
+
+// Inflight returns the number of tasks currently awaiting a result
+func (l *InflightLimiter) Inflight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.outstanding)
+}
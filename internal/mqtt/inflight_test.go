@@ -0,0 +1,127 @@
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInflightLimiterBlocksAtCap(t *testing.T) {
+	limiter := NewInflightLimiter(1)
+	ctx := context.Background()
+	if err := limiter.Acquire(ctx, "task-1"); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- limiter.Acquire(ctx, "task-2") }()
+
+	select {
+	case err := <-acquired:
+		t.Fatalf("second Acquire returned %v while the cap was reached", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	limiter.Release("task-1")
+	if err := <-acquired; err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+	if got := limiter.Inflight(); got != 1 {
+		t.Errorf("Inflight = %d, want 1", got)
+	}
+}
+
+func TestInflightLimiterAcquireHonoursContext(t *testing.T) {
+	limiter := NewInflightLimiter(1)
+	limiter.Acquire(context.Background(), "task-1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Acquire(ctx, "task-2"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if got := limiter.Inflight(); got != 1 {
+		t.Errorf("Inflight = %d, want only the first task", got)
+	}
+}
+
+func TestInflightLimiterRelease(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int
+		release []string
+		want    int
+	}{
+		{"held task", 2, []string{"task-1"}, 1},
+		{"unknown task ignored", 2, []string{"task-9"}, 2},
+		{"duplicate result ignored", 2, []string{"task-1", "task-1"}, 1},
+		{"unlimited", 0, []string{"task-2"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewInflightLimiter(tt.limit)
+			for _, id := range []string{"task-1", "task-2"} {
+				if err := limiter.Acquire(context.Background(), id); err != nil {
+					t.Fatalf("Acquire %s: %v", id, err)
+				}
+			}
+			for _, id := range tt.release {
+				limiter.Release(id)
+			}
+			if got := limiter.Inflight(); got != tt.want {
+				t.Errorf("Inflight = %d, want %d", got, tt.want)
+			}
+			// Freed slots are usable again
+			for i := tt.want; i < tt.limit; i++ {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				err := limiter.Acquire(ctx, fmt.Sprintf("next-%d", i))
+				cancel()
+				if err != nil {
+					t.Errorf("Acquire into free slot: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestInflightLimiterConcurrent(t *testing.T) {
+	const limit, tasks = 3, 50
+	limiter := NewInflightLimiter(limit)
+
+	var current, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := fmt.Sprintf("task-%d", i)
+			if err := limiter.Acquire(context.Background(), id); err != nil {
+				t.Errorf("Acquire: %v", err)
+				return
+			}
+			n := current.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			current.Add(-1)
+			limiter.Release(id)
+		}()
+	}
+	wg.Wait()
+
+	if got := peak.Load(); got > limit {
+		t.Errorf("%d tasks in flight, want at most %d", got, limit)
+	}
+	if got := limiter.Inflight(); got != 0 {
+		t.Errorf("Inflight = %d after every result, want 0", got)
+	}
+}
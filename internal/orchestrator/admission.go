@@ -0,0 +1,92 @@
+package orchestrator
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// Workflow admission metrics (exported via expvar)
+var (
+	workflowsInFlight  = expvar.NewInt("orchestrator_workflows_in_flight")
+	workflowQueueDepth = expvar.NewInt("orchestrator_workflow_queue_depth")
+)
+
+// admitOrQueue gives a new workflow an in-flight slot, or queues it when
+// MaxConcurrentWorkflows are already in flight. It reports whether the
+// workflow was admitted. Callers must hold o.mu and workflow.mu.
+func (o *Orchestrator) admitOrQueue(workflow *Workflow) bool {
+	if limit := o.config.MaxConcurrentWorkflows; limit > 0 && o.inFlight >= limit {
+		workflow.Queued = true
+		o.queue = append(o.queue, workflow)
+		workflowQueueDepth.Set(int64(len(o.queue)))
+		return false
+	}
+
+	o.takeSlot(workflow)
+	return true
+}
+
+// takeSlot counts the workflow as in flight. Callers must hold o.mu and workflow.mu.
+func (o *Orchestrator) takeSlot(workflow *Workflow) {
+	workflow.holdsSlot = true
+	o.inFlight++
+	workflowsInFlight.Set(int64(o.inFlight))
+}
+
+// releaseSlot frees the slot of a workflow that completed or failed and
+// hands it to the longest-queued workflow, which is started. Callers must
+// hold workflow.mu.
+func (o *Orchestrator) releaseSlot(ctx context.Context, workflow *Workflow) {
+	if !workflow.holdsSlot {
+		return
+	}
+	workflow.holdsSlot = false
+
+	o.mu.Lock()
+	o.inFlight--
+	var next *Workflow
+	if len(o.queue) > 0 {
+		next = o.queue[0]
+		o.queue = o.queue[1:]
+		o.inFlight++ // Held for next so no new workflow takes it meanwhile
+	}
+	workflowQueueDepth.Set(int64(len(o.queue)))
+	workflowsInFlight.Set(int64(o.inFlight))
+	o.mu.Unlock()
+
+	if next != nil {
+		o.startQueued(ctx, next)
+	}
+}
+
+// startQueued dispatches the development stage of a queued workflow that
+// was handed a slot. Its deadline starts now rather than when it was requested.
+func (o *Orchestrator) startQueued(ctx context.Context, workflow *Workflow) {
+	workflow.mu.Lock()
+	defer workflow.mu.Unlock()
+
+	now := o.now()
+	workflow.holdsSlot = true
+	workflow.Queued = false
+	workflow.UpdatedAt = now
+	if o.config.WorkflowTimeout > 0 {
+		workflow.Deadline = now.Add(o.config.WorkflowTimeout)
+	}
+	log.Printf("Starting queued workflow %s (%s) after %v, deadline %s", workflow.ID, workflow.Type,
+		now.Sub(workflow.StartedAt).Round(time.Second), workflow.Deadline.Format(time.RFC3339))
+
+	if err := o.dispatch(ctx, workflow, types.StageDevelopment); err != nil {
+		log.Printf("Warning: failed to start queued workflow %s: %v", workflow.ID, err)
+	}
+}
+
+// QueuedWorkflows returns the number of workflows waiting for an in-flight slot
+func (o *Orchestrator) QueuedWorkflows() int {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return len(o.queue)
+}
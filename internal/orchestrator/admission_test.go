@@ -0,0 +1,158 @@
+package orchestrator
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// answerUntilCompleted answers published tasks in order until workflowID completes
+func answerUntilCompleted(t *testing.T, o *Orchestrator, client *taskClient, workflowID string) {
+	t.Helper()
+	for {
+		if workflow, _ := o.GetWorkflow(workflowID); workflow.Stage == types.StageCompleted {
+			return
+		}
+		select {
+		case task := <-client.tasks:
+			if err := o.HandleResult(context.Background(), passingResult(task, false)); err != nil {
+				t.Fatalf("HandleResult(%s): %v", task.Stage, err)
+			}
+		default:
+			t.Fatalf("workflow %s stalled before completing", workflowID)
+		}
+	}
+}
+
+func TestMaxConcurrentWorkflowsQueues(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxConcurrentWorkflows = 2
+	o, client, clock := newTestOrchestrator(config)
+	ctx := context.Background()
+
+	var ids []string
+	for range 4 {
+		id, err := o.StartWorkflow(ctx, WorkflowRequest{Type: "api_guide"})
+		if err != nil {
+			t.Fatalf("StartWorkflow: %v", err)
+		}
+		ids = append(ids, id)
+		clock.Advance(time.Minute)
+	}
+
+	if len(client.tasks) != 2 || o.QueuedWorkflows() != 2 || workflowQueueDepth.Value() != 2 {
+		t.Fatalf("published %d tasks with %d queued (metric %d), want 2 and 2", len(client.tasks), o.QueuedWorkflows(), workflowQueueDepth.Value())
+	}
+	for i, id := range ids {
+		workflow, _ := o.GetWorkflow(id)
+		if queued := i >= 2; workflow.Queued != queued {
+			t.Errorf("workflow %d queued = %v, want %v", i, workflow.Queued, queued)
+		}
+	}
+
+	// Finishing the first workflow starts the oldest queued one, with a fresh deadline
+	answerUntilCompleted(t, o, client, ids[0])
+	third, _ := o.GetWorkflow(ids[2])
+	if third.Queued || third.Stage != types.StageDevelopment || o.QueuedWorkflows() != 1 {
+		t.Errorf("third workflow queued %v at %s with %d waiting, want started with 1 waiting", third.Queued, third.Stage, o.QueuedWorkflows())
+	}
+	if want := clock.Now().Add(config.WorkflowTimeout); !third.Deadline.Equal(want) {
+		t.Errorf("third workflow deadline = %v, want %v", third.Deadline, want)
+	}
+	if fourth, _ := o.GetWorkflow(ids[3]); !fourth.Queued {
+		t.Error("fourth workflow started past the cap")
+	}
+
+	for _, id := range ids[1:] {
+		answerUntilCompleted(t, o, client, id)
+	}
+	if o.QueuedWorkflows() != 0 || workflowsInFlight.Value() != 0 || workflowQueueDepth.Value() != 0 {
+		t.Errorf("%d queued and %d in flight after every workflow completed", o.QueuedWorkflows(), workflowsInFlight.Value())
+	}
+}
+
+func TestUnlimitedConcurrentWorkflows(t *testing.T) {
+	o, client, _ := newTestOrchestrator(DefaultConfig())
+	for range 5 {
+		if _, err := o.StartWorkflow(context.Background(), WorkflowRequest{Type: "api_guide"}); err != nil {
+			t.Fatalf("StartWorkflow: %v", err)
+		}
+	}
+	if len(client.tasks) != 5 || o.QueuedWorkflows() != 0 {
+		t.Errorf("published %d tasks with %d queued, want every workflow started", len(client.tasks), o.QueuedWorkflows())
+	}
+}
+
+func TestMaxConcurrentWorkflowsUnderLoad(t *testing.T) {
+	const limit, workflows = 3, 30
+
+	config := DefaultConfig()
+	config.MaxConcurrentWorkflows = limit
+	config.ResultWorkers = 4
+	client := newTaskClient()
+	o := New(client, config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	o.startResultWorkers(ctx)
+
+	var started sync.WaitGroup
+	for range workflows {
+		started.Add(1)
+		go func() {
+			defer started.Done()
+			if _, err := o.StartWorkflow(ctx, WorkflowRequest{Type: "api_guide"}); err != nil {
+				t.Errorf("StartWorkflow: %v", err)
+			}
+		}()
+	}
+
+	var workers sync.WaitGroup
+	for range 4 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				select {
+				case task := <-client.tasks:
+					o.mu.RLock()
+					inFlight := o.inFlight
+					o.mu.RUnlock()
+					if inFlight > limit {
+						t.Errorf("%d workflows in flight, want at most %d", inFlight, limit)
+					}
+					o.enqueueResult(ctx, passingResult(task, false))
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	started.Wait()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		completed := 0
+		for _, workflow := range o.ListWorkflows() {
+			if workflow.Stage == types.StageCompleted {
+				completed++
+			}
+		}
+		if completed == workflows {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d workflows completed", completed, workflows)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	workers.Wait()
+
+	if o.QueuedWorkflows() != 0 {
+		t.Errorf("%d workflows still queued", o.QueuedWorkflows())
+	}
+}
@@ -0,0 +1,117 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// ResultAggregator collects results from reviewers that all received the
+// same fan-out task and combines them once a quorum has responded
+type ResultAggregator struct {
+	quorum    int
+	startedAt time.Time
+	responses []types.WorkflowResult
+	failures  []types.WorkflowResult
+	seen      map[string]struct{} // Workers that already responded
+}
+
+// AggregateDecision is the combined outcome of the collected responses
+type AggregateDecision struct {
+	Responses     int    // Successful responses counted
+	Rejections    int    // Responses that requested a retry
+	RequiresRetry bool   // Set when at least half of the responses requested a retry
+	Feedback      string // Feedback from every response, labelled by worker
+}
+
+// NewResultAggregator creates an aggregator that waits for quorum responses
+func NewResultAggregator(quorum int, startedAt time.Time) *ResultAggregator {
+	return &ResultAggregator{
+		quorum:    quorum,
+		startedAt: startedAt,
+		seen:      make(map[string]struct{}),
+	}
+}
+
+// Add records a result and reports whether the quorum has been reached.
+// A second result from the same worker is ignored. Failed results are kept
+// for reporting but do not count toward the quorum.
+func (a *ResultAggregator) Add(result types.WorkflowResult) bool {
+	if _, duplicate := a.seen[result.WorkerID]; !duplicate {
+		a.seen[result.WorkerID] = struct{}{}
+		if result.Success {
+			a.responses = append(a.responses, result)
+		} else {
+			a.failures = append(a.failures, result)
+		}
+	}
+	return a.QuorumReached()
+}
+
+// QuorumReached reports whether enough successful responses have arrived
+func (a *ResultAggregator) QuorumReached() bool {
+	return len(a.responses) >= a.quorum
+}
+
+// Responses returns the number of successful responses collected
+func (a *ResultAggregator) Responses() int {
+	return len(a.responses)
+}
+
+// Failures returns the number of failed responses collected
+func (a *ResultAggregator) Failures() int {
+	return len(a.failures)
+}
+
+// Expired reports whether timeout has passed since the aggregator started
+func (a *ResultAggregator) Expired(now time.Time, timeout time.Duration) bool {
+	return timeout > 0 && now.Sub(a.startedAt) >= timeout
+}
+
+// Decision combines the collected responses. A retry is required when at
+// least half of the responses asked for one, so a split vote errs toward
+// another revision.
+func (a *ResultAggregator) Decision() AggregateDecision {
+	decision := AggregateDecision{Responses: len(a.responses)}
+
+	var feedback []string
+	for _, response := range a.responses {
+		if response.RequiresRetry {
+			decision.Rejections++
+		}
+		if text := strings.TrimSpace(response.ReviewFeedback); text != "" {
+			verdict := "approved"
+			if response.RequiresRetry {
+				verdict = "requested changes"
+			}
+			feedback = append(feedback, fmt.Sprintf("Reviewer %s (%s):\n%s", response.WorkerID, verdict, text))
+		}
+	}
+
+	decision.RequiresRetry = decision.Responses > 0 && decision.Rejections*2 >= decision.Responses
+	decision.Feedback = strings.Join(feedback, "\n\n")
+	return decision
+}
+
+// Result merges the collected responses into a single stage result
+func (a *ResultAggregator) Result() types.WorkflowResult {
+	decision := a.Decision()
+	if len(a.responses) == 0 {
+		return types.WorkflowResult{}
+	}
+
+	merged := a.responses[0]
+	merged.WorkerID = fmt.Sprintf("quorum(%d/%d)", decision.Responses, a.quorum)
+	merged.ReviewFeedback = decision.Feedback
+	merged.ReviewItems = nil
+	merged.RequiresRetry = decision.RequiresRetry
+	merged.Approved = !decision.RequiresRetry
+	merged.Status = types.StatusForError(nil, decision.RequiresRetry)
+	for _, response := range a.responses {
+		merged.Truncated = merged.Truncated || response.Truncated
+		merged.ReviewItems = append(merged.ReviewItems, response.ReviewItems...)
+	}
+	return merged
+}
@@ -0,0 +1,165 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// review is a reviewer's response; failed responses carry no verdict
+type review struct {
+	worker string
+	reject bool
+	failed bool
+}
+
+// result builds the workflow result for the review
+func (r review) result() types.WorkflowResult {
+	return types.WorkflowResult{
+		TaskResult:     types.TaskResult{WorkerID: r.worker, Success: !r.failed, Result: "review"},
+		Stage:          types.StageReview,
+		RequiresRetry:  r.reject,
+		ReviewFeedback: "notes from " + r.worker,
+	}
+}
+
+func TestResultAggregator(t *testing.T) {
+	tests := []struct {
+		name         string
+		quorum       int
+		reviews      []review
+		wantQuorum   bool
+		wantCounted  int
+		wantFailures int
+		wantRetry    bool
+	}{
+		{"quorum reached", 2, []review{{worker: "a"}, {worker: "b"}}, true, 2, 0, false},
+		{"waiting for quorum", 3, []review{{worker: "a"}, {worker: "b"}}, false, 2, 0, false},
+		{"unanimous reject", 2, []review{{worker: "a", reject: true}, {worker: "b", reject: true}}, true, 2, 0, true},
+		{"split vote retries", 2, []review{{worker: "a"}, {worker: "b", reject: true}}, true, 2, 0, true},
+		{"majority approves", 3, []review{{worker: "a"}, {worker: "b"}, {worker: "c", reject: true}}, true, 3, 0, false},
+		{"duplicate worker ignored", 2, []review{{worker: "a"}, {worker: "a", reject: true}}, false, 1, 0, false},
+		{"failures do not count", 2, []review{{worker: "a"}, {worker: "b", failed: true}}, false, 1, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aggregator := NewResultAggregator(tt.quorum, time.Now())
+			var reached bool
+			for _, r := range tt.reviews {
+				reached = aggregator.Add(r.result())
+			}
+			if reached != tt.wantQuorum || aggregator.QuorumReached() != tt.wantQuorum {
+				t.Errorf("quorum reached = %v, want %v", reached, tt.wantQuorum)
+			}
+			if aggregator.Responses() != tt.wantCounted || aggregator.Failures() != tt.wantFailures {
+				t.Errorf("got %d responses and %d failures, want %d and %d",
+					aggregator.Responses(), aggregator.Failures(), tt.wantCounted, tt.wantFailures)
+			}
+
+			decision := aggregator.Decision()
+			if decision.RequiresRetry != tt.wantRetry {
+				t.Errorf("RequiresRetry = %v, want %v", decision.RequiresRetry, tt.wantRetry)
+			}
+			merged := aggregator.Result()
+			if merged.RequiresRetry != tt.wantRetry || merged.Approved == tt.wantRetry {
+				t.Errorf("merged result retry=%v approved=%v, want retry=%v", merged.RequiresRetry, merged.Approved, tt.wantRetry)
+			}
+			for _, r := range tt.reviews {
+				if !r.failed && !strings.Contains(merged.ReviewFeedback, "notes from "+r.worker) {
+					t.Errorf("merged feedback %q is missing reviewer %s", merged.ReviewFeedback, r.worker)
+				}
+			}
+		})
+	}
+}
+
+func TestResultAggregatorExpired(t *testing.T) {
+	start := time.Now()
+	aggregator := NewResultAggregator(2, start)
+
+	tests := []struct {
+		elapsed time.Duration
+		timeout time.Duration
+		want    bool
+	}{
+		{time.Minute, 5 * time.Minute, false},
+		{5 * time.Minute, 5 * time.Minute, true},
+		{time.Hour, 0, false}, // No timeout configured
+	}
+	for _, tt := range tests {
+		if got := aggregator.Expired(start.Add(tt.elapsed), tt.timeout); got != tt.want {
+			t.Errorf("Expired after %v with timeout %v = %v, want %v", tt.elapsed, tt.timeout, got, tt.want)
+		}
+	}
+}
+
+// startQuorumReview starts a workflow with a review quorum and returns the
+// review task once development has passed
+func startQuorumReview(t *testing.T, quorum int) (*Orchestrator, *taskClient, *fakeClock, string, types.WorkflowTask) {
+	t.Helper()
+	config := DefaultConfig()
+	config.WorkflowTimeout = 0
+	config.StageTimeout = 0
+	config.ReviewQuorum = quorum
+	config.QuorumTimeout = 5 * time.Minute
+	o, client, clock := newTestOrchestrator(config)
+
+	ctx := context.Background()
+	id, err := o.StartWorkflow(ctx, WorkflowRequest{Type: "api_guide"})
+	if err != nil {
+		t.Fatalf("StartWorkflow: %v", err)
+	}
+	if err := o.HandleResult(ctx, passingResult(<-client.tasks, false)); err != nil {
+		t.Fatalf("HandleResult(development): %v", err)
+	}
+	task := <-client.tasks
+	if task.Stage != types.StageReview {
+		t.Fatalf("next task is for %s, want review", task.Stage)
+	}
+	return o, client, clock, id, task
+}
+
+func TestReviewQuorum(t *testing.T) {
+	tests := []struct {
+		name      string
+		reviews   []review
+		wait      time.Duration // Clock advance before the watchdog pass
+		wantStage types.WorkflowStage
+		wantRetry int
+	}{
+		{"quorum reached", []review{{worker: "a"}, {worker: "b"}}, 0, types.StageApproval, 0},
+		{"waiting for stragglers", []review{{worker: "a"}}, time.Minute, types.StageReview, 0},
+		{"quorum timeout decides with responses so far", []review{{worker: "a"}}, 6 * time.Minute, types.StageApproval, 0},
+		{"quorum timeout with only failures retries", []review{{worker: "a", failed: true}}, 6 * time.Minute, types.StageReview, 1},
+		{"quorum timeout without responses waits", nil, 6 * time.Minute, types.StageReview, 0},
+		{"unanimous reject", []review{{worker: "a", reject: true}, {worker: "b", reject: true}, {worker: "c", reject: true}}, 0, types.StageDevelopment, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quorum := max(2, len(tt.reviews))
+			o, _, clock, id, task := startQuorumReview(t, quorum)
+			ctx := context.Background()
+
+			for _, r := range tt.reviews {
+				result := r.result()
+				result.TaskID = task.ID
+				result.WorkflowID = task.WorkflowID
+				if err := o.HandleResult(ctx, result); err != nil {
+					t.Fatalf("HandleResult(%s): %v", r.worker, err)
+				}
+			}
+			clock.Advance(tt.wait)
+			o.checkQuorumTimeouts(ctx)
+
+			workflow, _ := o.GetWorkflow(id)
+			if workflow.Stage != tt.wantStage || workflow.RetryCount != tt.wantRetry {
+				t.Errorf("workflow at %s after %d retries, want %s after %d", workflow.Stage, workflow.RetryCount, tt.wantStage, tt.wantRetry)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// BatchPolicy decides what a batch does after one of its documents fails
+type BatchPolicy string
+
+const (
+	// BatchStopOnFirstError skips the documents after the first failure
+	BatchStopOnFirstError BatchPolicy = "stop_on_first_error"
+	// BatchContinue runs every document and reports each outcome
+	BatchContinue BatchPolicy = "continue"
+)
+
+// BatchDocumentStatus is the state of one document in a batch
+type BatchDocumentStatus string
+
+const (
+	BatchDocumentPending   BatchDocumentStatus = "pending"
+	BatchDocumentRunning   BatchDocumentStatus = "running"
+	BatchDocumentCompleted BatchDocumentStatus = "completed"
+	BatchDocumentFailed    BatchDocumentStatus = "failed"
+	BatchDocumentSkipped   BatchDocumentStatus = "skipped" // Not started because an earlier document failed
+)
+
+// BatchRequest is the message published on BatchRequestTopic to create
+// several documents. Documents run one at a time in order; Policy defaults
+// to BatchStopOnFirstError.
+type BatchRequest struct {
+	Documents []WorkflowRequest `json:"documents"`
+	Policy    BatchPolicy       `json:"policy,omitempty"`
+}
+
+// BatchDocumentResult reports the outcome of one document in a batch
+type BatchDocumentResult struct {
+	Index      int                 `json:"index"`
+	Type       string              `json:"type"`
+	WorkflowID string              `json:"workflow_id,omitempty"`
+	Status     BatchDocumentStatus `json:"status"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// Batch tracks a batch of documents and the workflow creating each
+type Batch struct {
+	ID         string                `json:"id"`
+	Policy     BatchPolicy           `json:"policy"`
+	Documents  []BatchDocumentResult `json:"documents"`
+	StartedAt  time.Time             `json:"started_at"`
+	FinishedAt time.Time             `json:"finished_at,omitempty"`
+
+	mu       *sync.Mutex // Guards the fields above
+	requests []WorkflowRequest
+}
+
+// Finished reports whether every document has completed, failed or been skipped
+func (b *Batch) Finished() bool {
+	return !b.FinishedAt.IsZero()
+}
+
+// Succeeded reports whether every document completed
+func (b *Batch) Succeeded() bool {
+	for _, document := range b.Documents {
+		if document.Status != BatchDocumentCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// StartBatch registers a batch and starts its first document
+func (o *Orchestrator) StartBatch(ctx context.Context, request BatchRequest) (string, error) {
+	if len(request.Documents) == 0 {
+		return "", fmt.Errorf("batch has no documents")
+	}
+
+	policy := request.Policy
+	switch policy {
+	case "":
+		policy = BatchStopOnFirstError
+	case BatchStopOnFirstError, BatchContinue:
+	default:
+		return "", fmt.Errorf("unknown batch policy %q (must be %s or %s)", policy, BatchStopOnFirstError, BatchContinue)
+	}
+
+	now := o.now()
+	batch := &Batch{
+		ID:        fmt.Sprintf("batch-%d", now.UnixNano()),
+		Policy:    policy,
+		Documents: make([]BatchDocumentResult, len(request.Documents)),
+		StartedAt: now,
+		mu:        &sync.Mutex{},
+		requests:  request.Documents,
+	}
+	for i, document := range request.Documents {
+		batch.Documents[i] = BatchDocumentResult{Index: i, Type: document.Type, Status: BatchDocumentPending}
+	}
+
+	o.mu.Lock()
+	for n := 1; o.batches[batch.ID] != nil; n++ {
+		batch.ID = fmt.Sprintf("batch-%d-%d", now.UnixNano(), n)
+	}
+	o.batches[batch.ID] = batch
+	o.mu.Unlock()
+	log.Printf("Started batch %s: %d documents, policy %s", batch.ID, len(batch.Documents), batch.Policy)
+
+	o.startBatchDocument(ctx, batch, 0)
+	return batch.ID, nil
+}
+
+// GetBatch returns a snapshot of the batch state
+func (o *Orchestrator) GetBatch(batchID string) (Batch, bool) {
+	o.mu.RLock()
+	batch, exists := o.batches[batchID]
+	o.mu.RUnlock()
+	if !exists {
+		return Batch{}, false
+	}
+
+	batch.mu.Lock()
+	defer batch.mu.Unlock()
+	snapshot := *batch
+	snapshot.Documents = append([]BatchDocumentResult(nil), batch.Documents...)
+	return snapshot, true
+}
+
+// startBatchDocument starts the workflow for document index. A document
+// whose workflow cannot be started fails immediately.
+func (o *Orchestrator) startBatchDocument(ctx context.Context, batch *Batch, index int) {
+	batch.mu.Lock()
+	batch.Documents[index].Status = BatchDocumentRunning
+	request := batch.requests[index]
+	batch.mu.Unlock()
+
+	// A workflow failing during dispatch finishes its document before
+	// startWorkflow returns, so the batch must not be locked here
+	workflowID, err := o.startWorkflow(ctx, request, batch.ID, index)
+	if workflowID == "" && err != nil {
+		o.recordBatchDocument(ctx, batch, index, "", BatchDocumentFailed, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("Warning: batch %s document %d: %v", batch.ID, index, err)
+	}
+
+	batch.mu.Lock()
+	if batch.Documents[index].WorkflowID == "" {
+		batch.Documents[index].WorkflowID = workflowID
+	}
+	batch.mu.Unlock()
+}
+
+// finishBatchDocument records the outcome of a finished workflow that is
+// part of a batch. Callers must hold workflow.mu.
+func (o *Orchestrator) finishBatchDocument(ctx context.Context, workflow *Workflow) {
+	if workflow.BatchID == "" {
+		return
+	}
+
+	o.mu.RLock()
+	batch, exists := o.batches[workflow.BatchID]
+	o.mu.RUnlock()
+	if !exists {
+		return
+	}
+
+	status := BatchDocumentCompleted
+	if workflow.Stage == types.StageFailed {
+		status = BatchDocumentFailed
+	}
+	o.recordBatchDocument(ctx, batch, workflow.batchIndex, workflow.ID, status, workflow.Error)
+}
+
+// recordBatchDocument stores a document outcome, then starts the next
+// document or, when none is left to run, publishes the batch outcome
+func (o *Orchestrator) recordBatchDocument(ctx context.Context, batch *Batch, index int, workflowID string, status BatchDocumentStatus, reason string) {
+	batch.mu.Lock()
+	document := &batch.Documents[index]
+	if document.Status != BatchDocumentRunning {
+		// A reopened workflow finishing again; the batch has moved on
+		batch.mu.Unlock()
+		return
+	}
+	document.WorkflowID = workflowID
+	document.Status = status
+	document.Error = reason
+	log.Printf("Batch %s document %d/%d %s", batch.ID, index+1, len(batch.Documents), status)
+
+	next := index + 1
+	if status == BatchDocumentFailed && batch.Policy == BatchStopOnFirstError {
+		for i := next; i < len(batch.Documents); i++ {
+			batch.Documents[i].Status = BatchDocumentSkipped
+		}
+		next = len(batch.Documents)
+	}
+	if next == len(batch.Documents) {
+		batch.FinishedAt = o.now()
+	}
+	batch.mu.Unlock()
+
+	if next < len(batch.Documents) {
+		o.startBatchDocument(ctx, batch, next)
+		return
+	}
+	if err := o.publishBatchOutcome(ctx, batch); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// publishBatchOutcome publishes the per-document results of a finished batch
+func (o *Orchestrator) publishBatchOutcome(ctx context.Context, batch *Batch) error {
+	snapshot, _ := o.GetBatch(batch.ID)
+	log.Printf("Batch %s finished, all documents completed: %t", snapshot.ID, snapshot.Succeeded())
+
+	data, err := types.WrapMessage(types.MessageTypeBatchOutcome, snapshot.ID, snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outcome of batch %s: %w", snapshot.ID, err)
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, o.config.PublishTimeout)
+	defer cancel()
+
+	topic := fmt.Sprintf(BatchOutcomeTopic, snapshot.ID)
+	if err := o.mqttClient.Publish(publishCtx, topic, data); err != nil {
+		return fmt.Errorf("failed to publish outcome of batch %s: %w", snapshot.ID, err)
+	}
+	return nil
+}
+
+// handleBatchRequest starts a batch from an MQTT request
+func (o *Orchestrator) handleBatchRequest(ctx context.Context, payload []byte) {
+	var request BatchRequest
+	if _, err := types.UnwrapMessage(payload, types.MessageTypeBatchRequest, &request); err != nil {
+		log.Printf("Failed to unmarshal batch request: %v", err)
+		return
+	}
+
+	if _, err := o.StartBatch(ctx, request); err != nil {
+		log.Printf("Failed to start batch: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// runBatch answers every task until the batch finishes, failing the tasks of
+// documents whose type is failType
+func runBatch(t *testing.T, o *Orchestrator, client *taskClient, batchID, failType string) Batch {
+	t.Helper()
+	ctx := context.Background()
+
+	for {
+		batch, _ := o.GetBatch(batchID)
+		if batch.Finished() {
+			return batch
+		}
+
+		var task types.WorkflowTask
+		select {
+		case task = <-client.tasks:
+		default:
+			t.Fatalf("batch %s stalled: %+v", batchID, batch.Documents)
+		}
+
+		result := passingResult(task, false)
+		if workflow, _ := o.GetWorkflow(task.WorkflowID); workflow.Type == failType {
+			result.Success, result.Error = false, "model crashed"
+		}
+		if err := o.HandleResult(ctx, result); err != nil {
+			t.Fatalf("HandleResult(%s): %v", task.Stage, err)
+		}
+	}
+}
+
+func TestBatchPolicies(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy BatchPolicy
+		want   []BatchDocumentStatus
+	}{
+		{
+			name: "default stops on first error",
+			want: []BatchDocumentStatus{BatchDocumentCompleted, BatchDocumentFailed, BatchDocumentSkipped},
+		},
+		{
+			name:   "stop on first error",
+			policy: BatchStopOnFirstError,
+			want:   []BatchDocumentStatus{BatchDocumentCompleted, BatchDocumentFailed, BatchDocumentSkipped},
+		},
+		{
+			name:   "continue",
+			policy: BatchContinue,
+			want:   []BatchDocumentStatus{BatchDocumentCompleted, BatchDocumentFailed, BatchDocumentCompleted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.MaxRetries = 0 // The first failed stage fails its document
+			o, client, _ := newTestOrchestrator(config)
+
+			batchID, err := o.StartBatch(context.Background(), BatchRequest{
+				Documents: []WorkflowRequest{{Type: "api_guide"}, {Type: "broken_guide"}, {Type: "user_manual"}},
+				Policy:    tt.policy,
+			})
+			if err != nil {
+				t.Fatalf("StartBatch() error = %v", err)
+			}
+
+			batch := runBatch(t, o, client, batchID, "broken_guide")
+
+			var statuses []BatchDocumentStatus
+			for _, document := range batch.Documents {
+				statuses = append(statuses, document.Status)
+			}
+			if !reflect.DeepEqual(statuses, tt.want) {
+				t.Errorf("document statuses = %v, want %v", statuses, tt.want)
+			}
+			if batch.Succeeded() {
+				t.Error("Succeeded() = true with a failed document")
+			}
+
+			failed := batch.Documents[1]
+			if failed.WorkflowID == "" || failed.Error == "" {
+				t.Errorf("failed document = %+v, want its workflow and error", failed)
+			}
+			if skipped := batch.Documents[2]; skipped.Status == BatchDocumentSkipped && skipped.WorkflowID != "" {
+				t.Errorf("skipped document started workflow %s", skipped.WorkflowID)
+			}
+		})
+	}
+}
+
+func TestStartBatchRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		request BatchRequest
+	}{
+		{name: "no documents", request: BatchRequest{Policy: BatchContinue}},
+		{name: "unknown policy", request: BatchRequest{Documents: []WorkflowRequest{{Type: "api_guide"}}, Policy: "retry"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, client, _ := newTestOrchestrator(DefaultConfig())
+			if _, err := o.StartBatch(context.Background(), tt.request); err == nil {
+				t.Error("StartBatch() error = nil, want error")
+			}
+			if len(client.tasks) != 0 {
+				t.Errorf("published %d tasks for a rejected batch", len(client.tasks))
+			}
+		})
+	}
+}
+
+func TestBatchDocumentFailingToStart(t *testing.T) {
+	o, client, _ := newTestOrchestrator(DefaultConfig())
+
+	// A document without a type cannot start a workflow
+	batchID, err := o.StartBatch(context.Background(), BatchRequest{
+		Documents: []WorkflowRequest{{Type: "api_guide"}, {}, {Type: "user_manual"}},
+		Policy:    BatchContinue,
+	})
+	if err != nil {
+		t.Fatalf("StartBatch() error = %v", err)
+	}
+
+	batch := runBatch(t, o, client, batchID, "")
+	if got := batch.Documents[1]; got.Status != BatchDocumentFailed || got.Error == "" {
+		t.Errorf("untyped document = %+v, want failed with an error", got)
+	}
+	if got := batch.Documents[2].Status; got != BatchDocumentCompleted {
+		t.Errorf("document after the failure is %s, want completed", got)
+	}
+}
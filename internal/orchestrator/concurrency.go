@@ -0,0 +1,91 @@
+package orchestrator
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// DefaultResultWorkers is the number of workflows whose results are handled in parallel
+const DefaultResultWorkers = 4
+
+// resultQueueSize is the number of results buffered per worker before the
+// MQTT callback blocks
+const resultQueueSize = 64
+
+// lookup returns the tracked workflow with workflowID
+func (o *Orchestrator) lookup(workflowID string) (*Workflow, bool) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	workflow, exists := o.workflows[workflowID]
+	return workflow, exists
+}
+
+// trackedWorkflows returns the workflows currently tracked. Callers must lock
+// each workflow before reading its fields.
+func (o *Orchestrator) trackedWorkflows() []*Workflow {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	workflows := make([]*Workflow, 0, len(o.workflows))
+	for _, workflow := range o.workflows {
+		workflows = append(workflows, workflow)
+	}
+	return workflows
+}
+
+// startResultWorkers starts one goroutine per result shard until ctx is done
+func (o *Orchestrator) startResultWorkers(ctx context.Context) {
+	if o.config.ResultWorkers <= 1 {
+		return
+	}
+
+	o.results = make([]chan types.WorkflowResult, o.config.ResultWorkers)
+	for i := range o.results {
+		queue := make(chan types.WorkflowResult, resultQueueSize)
+		o.results[i] = queue
+
+		go func() {
+			for {
+				select {
+				case result := <-queue:
+					o.handleQueuedResult(ctx, result)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+}
+
+// enqueueResult hands result to the worker owning its workflow, so results
+// for one workflow are handled in arrival order while other workflows
+// proceed in parallel. Without result workers it is handled inline.
+func (o *Orchestrator) enqueueResult(ctx context.Context, result types.WorkflowResult) {
+	if len(o.results) == 0 {
+		o.handleQueuedResult(ctx, result)
+		return
+	}
+
+	select {
+	case o.results[shard(result.WorkflowID, len(o.results))] <- result:
+	case <-ctx.Done():
+	}
+}
+
+// handleQueuedResult applies a result, logging rather than returning failures
+func (o *Orchestrator) handleQueuedResult(ctx context.Context, result types.WorkflowResult) {
+	if err := o.HandleResult(ctx, result); err != nil {
+		log.Printf("Ignoring result for task %s: %v", result.TaskID, err)
+	}
+}
+
+// shard maps a workflow ID onto one of n result workers
+func shard(workflowID string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(workflowID))
+	return int(h.Sum32() % uint32(n))
+}
@@ -0,0 +1,733 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/docstore"
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// MQTT topics used by the orchestrator
+const (
+	WorkflowRequestTopic = "orchestrator/workflow"
+	WorkflowTaskTopic    = "tasks/workflow/%s"
+	WorkflowResultTopic  = "results/workflow/+"
+	WorkflowOutcomeTopic = "orchestrator/results/%s"
+	DeadLetterTopic      = "tasks/deadletter/%s"
+	CapabilitiesTopic    = "orchestrator/capabilities"
+	BatchRequestTopic    = "orchestrator/batch"
+	BatchOutcomeTopic    = "orchestrator/batch/results/%s"
+)
+
+// Config controls workflow execution limits
+type Config struct {
+	WorkflowTimeout time.Duration // Overall budget for a workflow across all stages
+	MaxRetries      int           // Retries allowed before a workflow is failed
+	PublishTimeout  time.Duration
+
+	// Watchdog settings for stages whose worker never reports a result
+	StageTimeout     time.Duration // Re-dispatch a stage after this long without a result; zero disables
+	MaxRedispatches  int           // Re-dispatches per stage before the workflow is failed
+	WatchdogInterval time.Duration
+
+	// OutputRoot is the directory final documents are written under; a
+	// workflow's output_file must be a relative path inside it
+	OutputRoot string
+
+	// VersionedOutput keeps earlier final documents as <output_file>.vN with a manifest
+	VersionedOutput bool
+
+	// Review fan-out: every subscribed reviewer receives the review task, and
+	// the stage waits for ReviewQuorum of them before combining their verdicts.
+	// A quorum of 1 or less advances on the first result.
+	ReviewQuorum  int
+	QuorumTimeout time.Duration // Decide with the responses so far after this long; keep below StageTimeout
+
+	// Advertised to clients on CapabilitiesTopic
+	DocumentTypes []string
+	Models        []string
+
+	// Finished workflows are evicted this long after completing or failing,
+	// leaving a summary; zero keeps them forever
+	Retention    time.Duration
+	MaxSummaries int // Evicted workflow summaries kept, oldest dropped first; zero keeps all
+
+	// StageSLA is the target p95 latency per stage, measured from the first
+	// dispatch of the stage to its result; breaches are counted and logged
+	StageSLA  map[types.WorkflowStage]time.Duration
+	SLAWindow int // Recent durations per stage the p95 is computed over
+
+	// Training capture: the fraction of approved documents kept as positive
+	// examples, and whether every rejection is kept as a negative example.
+	// Samples go to the sink set with SetTrainingSink.
+	TrainingSampleRate     float64
+	TrainingKeepRejections bool
+
+	// ResultWorkers handle stage results for different workflows in
+	// parallel. Results are sharded by workflow ID, so each workflow's
+	// results are still handled one at a time in arrival order. One or less
+	// handles every result on the MQTT callback.
+	ResultWorkers int
+
+	// MaxConcurrentWorkflows caps the workflows in flight; new workflows
+	// past the cap are queued and started in request order as others
+	// finish. Zero or less is unlimited.
+	MaxConcurrentWorkflows int
+}
+
+// DefaultConfig returns sensible orchestrator defaults
+func DefaultConfig() Config {
+	return Config{
+		WorkflowTimeout: 30 * time.Minute,
+		MaxRetries:      3,
+		PublishTimeout:  5 * time.Second,
+		OutputRoot:      ".",
+
+		StageTimeout:     12 * time.Minute, // Longer than the worker task timeout
+		MaxRedispatches:  2,
+		WatchdogInterval: 30 * time.Second,
+
+		ReviewQuorum:  1,
+		QuorumTimeout: 5 * time.Minute,
+
+		Retention:    24 * time.Hour,
+		MaxSummaries: 1000,
+
+		SLAWindow: DefaultSLAWindow,
+
+		TrainingSampleRate:     DefaultTrainingSampleRate,
+		TrainingKeepRejections: true,
+
+		ResultWorkers: DefaultResultWorkers,
+	}
+}
+
+// WorkflowRequest is the message published on WorkflowRequestTopic to start a workflow
+type WorkflowRequest struct {
+	Type    string            `json:"type"`
+	Payload map[string]string `json:"payload"`
+
+	// Pin every stage task to a provider and model, bypassing routing
+	ForceProvider string `json:"force_provider,omitempty"`
+	ForceModel    string `json:"force_model,omitempty"`
+}
+
+// Workflow tracks a single document through the development pipeline
+type Workflow struct {
+	ID         string              `json:"id"`
+	Type       string              `json:"type"`
+	Payload    map[string]string   `json:"payload,omitempty"`
+	Stage      types.WorkflowStage `json:"stage"`
+	Document   string              `json:"document,omitempty"`
+	Feedback   string              `json:"feedback,omitempty"`
+	RetryCount int                 `json:"retry_count"`
+	StartedAt  time.Time           `json:"started_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+	Deadline   time.Time           `json:"deadline"`
+	Error      string              `json:"error,omitempty"`
+	Errors     []string            `json:"errors,omitempty"` // Stage failures, oldest first
+
+	// ReviewItems is the structured form of Feedback, when the reviewer gave one
+	ReviewItems []types.ReviewItem `json:"review_items,omitempty"`
+
+	ForceProvider string `json:"force_provider,omitempty"`
+	ForceModel    string `json:"force_model,omitempty"`
+
+	// BatchID names the batch the workflow is one document of
+	BatchID    string `json:"batch_id,omitempty"`
+	batchIndex int    // Position of the document in its batch
+
+	// Queued workflows wait for MaxConcurrentWorkflows to allow them in flight
+	Queued    bool `json:"queued,omitempty"`
+	holdsSlot bool // Counted as in flight
+
+	// mu serializes stage transitions of this workflow and guards its
+	// fields; the orchestrator's lock only guards the workflow map
+	mu      *sync.Mutex
+	evicted bool // Dropped from the orchestrator after Retention
+
+	// Current stage dispatch; results for other task IDs are ignored as stale
+	DispatchedAt time.Time `json:"dispatched_at"`
+	Redispatches int       `json:"redispatches"`
+	pendingTasks map[string]struct{}
+	aggregator   *ResultAggregator // Collects fan-out responses when the stage needs a quorum
+	lastTask     types.WorkflowTask
+	stageStarted time.Time // First dispatch of the current stage, unchanged by re-dispatches
+}
+
+// Orchestrator drives workflows through development, review, approval and testing
+type Orchestrator struct {
+	mqttClient mqtt.ClientInterface
+	config     Config
+	mu         sync.RWMutex         // Guards workflows, batches, summaries and training; never held while waiting on a workflow's lock
+	workflows  map[string]*Workflow // Each workflow's fields are guarded by its own lock
+	batches    map[string]*Batch    // Each batch's fields are guarded by its own lock
+	summaries  []WorkflowSummary    // Workflows evicted after Retention, oldest first
+	sla        *SLATracker
+	sampler    *TrainingSampler
+	training   TrainingSink
+	results    []chan types.WorkflowResult // Per-shard result queues when ResultWorkers > 1
+	now        func() time.Time
+
+	// Admission under MaxConcurrentWorkflows, guarded by mu
+	inFlight int         // Workflows started and not yet finished
+	queue    []*Workflow // Workflows waiting for a slot, oldest first
+}
+
+// New creates an orchestrator publishing stage tasks through mqttClient
+func New(mqttClient mqtt.ClientInterface, config Config) *Orchestrator {
+	return &Orchestrator{
+		mqttClient: mqttClient,
+		config:     config,
+		workflows:  make(map[string]*Workflow),
+		batches:    make(map[string]*Batch),
+		sla:        NewSLATracker(config.StageSLA, config.SLAWindow),
+		sampler:    NewTrainingSampler(config.TrainingSampleRate, config.TrainingKeepRejections),
+		now:        time.Now,
+	}
+}
+
+// Start subscribes to workflow requests and stage results
+func (o *Orchestrator) Start(ctx context.Context) error {
+	if err := o.mqttClient.Subscribe(ctx, WorkflowRequestTopic, func(payload []byte) {
+		o.handleWorkflowRequest(ctx, payload)
+	}); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", WorkflowRequestTopic, err)
+	}
+
+	o.startResultWorkers(ctx)
+	if err := o.mqttClient.Subscribe(ctx, WorkflowResultTopic, func(payload []byte) {
+		o.handleResult(ctx, payload)
+	}); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", WorkflowResultTopic, err)
+	}
+
+	if err := o.mqttClient.Subscribe(ctx, BatchRequestTopic, func(payload []byte) {
+		o.handleBatchRequest(ctx, payload)
+	}); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", BatchRequestTopic, err)
+	}
+
+	if err := o.mqttClient.Subscribe(ctx, CapabilitiesTopic, func(payload []byte) {
+		o.handleCapabilitiesRequest(ctx, payload)
+	}); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", CapabilitiesTopic, err)
+	}
+
+	if o.config.WatchdogInterval > 0 && (o.config.StageTimeout > 0 || o.config.ReviewQuorum > 1 || o.config.Retention > 0) {
+		go o.runWatchdog(ctx)
+	}
+
+	return nil
+}
+
+// StartWorkflow registers a new workflow and dispatches its development stage
+func (o *Orchestrator) StartWorkflow(ctx context.Context, request WorkflowRequest) (string, error) {
+	return o.startWorkflow(ctx, request, "", 0)
+}
+
+// startWorkflow starts a workflow, as document index of batchID when set
+func (o *Orchestrator) startWorkflow(ctx context.Context, request WorkflowRequest, batchID string, index int) (string, error) {
+	if request.Type == "" {
+		return "", fmt.Errorf("workflow type is required")
+	}
+	if outputFile := request.Payload["output_file"]; outputFile != "" {
+		if _, err := o.outputPath(outputFile); err != nil {
+			return "", err
+		}
+	}
+
+	now := o.now()
+	workflow := &Workflow{
+		ID:        fmt.Sprintf("wf-%d", now.UnixNano()),
+		Type:      request.Type,
+		Payload:   request.Payload,
+		Stage:     types.StageDevelopment,
+		StartedAt: now,
+		UpdatedAt: now,
+		mu:        &sync.Mutex{},
+
+		ForceProvider: request.ForceProvider,
+		ForceModel:    request.ForceModel,
+
+		BatchID:    batchID,
+		batchIndex: index,
+	}
+	if workflow.Payload == nil {
+		workflow.Payload = make(map[string]string)
+	}
+	if o.config.WorkflowTimeout > 0 {
+		workflow.Deadline = now.Add(o.config.WorkflowTimeout)
+	}
+
+	workflow.mu.Lock()
+	defer workflow.mu.Unlock()
+
+	o.mu.Lock()
+	// Workflows started in the same nanosecond get a suffix to keep IDs unique
+	for n := 1; o.workflows[workflow.ID] != nil; n++ {
+		workflow.ID = fmt.Sprintf("wf-%d-%d", now.UnixNano(), n)
+	}
+	o.workflows[workflow.ID] = workflow
+	admitted := o.admitOrQueue(workflow)
+	queued := len(o.queue)
+	o.mu.Unlock()
+
+	if !admitted {
+		log.Printf("Queued workflow %s (%s): %d workflows in flight, %d waiting", workflow.ID, workflow.Type,
+			o.config.MaxConcurrentWorkflows, queued)
+		return workflow.ID, nil
+	}
+	log.Printf("Started workflow %s (%s), deadline %s", workflow.ID, workflow.Type, workflow.Deadline.Format(time.RFC3339))
+
+	if err := o.dispatch(ctx, workflow, types.StageDevelopment); err != nil {
+		return workflow.ID, err
+	}
+	return workflow.ID, nil
+}
+
+// GetWorkflow returns a snapshot of the workflow state
+func (o *Orchestrator) GetWorkflow(workflowID string) (Workflow, bool) {
+	workflow, exists := o.lookup(workflowID)
+	if !exists {
+		return Workflow{}, false
+	}
+
+	workflow.mu.Lock()
+	defer workflow.mu.Unlock()
+	return *workflow, true
+}
+
+// ListWorkflows returns snapshots of all tracked workflows, oldest first
+func (o *Orchestrator) ListWorkflows() []Workflow {
+	tracked := o.trackedWorkflows()
+	workflows := make([]Workflow, 0, len(tracked))
+	for _, workflow := range tracked {
+		workflow.mu.Lock()
+		workflows = append(workflows, *workflow)
+		workflow.mu.Unlock()
+	}
+
+	sort.Slice(workflows, func(i, j int) bool {
+		return workflows[i].StartedAt.Before(workflows[j].StartedAt)
+	})
+	return workflows
+}
+
+// HandleResult advances a workflow based on the result of its current stage
+func (o *Orchestrator) HandleResult(ctx context.Context, result types.WorkflowResult) error {
+	workflow, exists := o.lookup(result.WorkflowID)
+	if !exists {
+		return fmt.Errorf("unknown workflow %s", result.WorkflowID)
+	}
+
+	workflow.mu.Lock()
+	defer workflow.mu.Unlock()
+
+	if workflow.evicted {
+		return fmt.Errorf("unknown workflow %s", result.WorkflowID)
+	}
+	if workflow.Stage == types.StageFailed && result.TaskID == workflow.lastTask.ID {
+		o.reopen(workflow, result.Stage)
+	}
+	if workflow.Stage.IsTerminal() {
+		return fmt.Errorf("workflow %s already %s", workflow.ID, workflow.Stage)
+	}
+	if result.Stage != workflow.Stage {
+		return fmt.Errorf("workflow %s: result for stage %s, expected %s", workflow.ID, result.Stage, workflow.Stage)
+	}
+	if _, pending := workflow.pendingTasks[result.TaskID]; !pending {
+		return fmt.Errorf("workflow %s: task %s is stale or already handled", workflow.ID, result.TaskID)
+	}
+
+	workflow.UpdatedAt = o.now()
+
+	if workflow.aggregator != nil {
+		if !workflow.aggregator.Add(result) {
+			log.Printf("Workflow %s %s stage has %d/%d responses (%d failed)", workflow.ID, result.Stage,
+				workflow.aggregator.Responses(), o.config.ReviewQuorum, workflow.aggregator.Failures())
+			return nil
+		}
+		result = workflow.aggregator.Result()
+		log.Printf("Workflow %s %s stage reached quorum", workflow.ID, result.Stage)
+	}
+
+	return o.advance(ctx, workflow, result)
+}
+
+// advance moves the workflow on from a completed stage result. Callers must hold workflow.mu.
+func (o *Orchestrator) advance(ctx context.Context, workflow *Workflow, result types.WorkflowResult) error {
+	workflow.pendingTasks = nil
+	workflow.aggregator = nil
+
+	duration := o.now().Sub(workflow.stageStarted)
+	if o.sla.Record(result.Stage, duration) {
+		log.Printf("Warning: workflow %s %s stage took %v, over its SLA target %v",
+			workflow.ID, result.Stage, duration.Round(time.Second), o.config.StageSLA[result.Stage])
+	}
+
+	if status := result.ResultStatus(); status == types.StatusFailed || status == types.StatusCancelled {
+		log.Printf("Workflow %s stage %s %s: %s", workflow.ID, result.Stage, status, result.Error)
+		workflow.Errors = append(workflow.Errors, fmt.Sprintf("%s: %s", result.Stage, result.Error))
+		return o.retry(ctx, workflow, result.Stage, result.Error)
+	}
+
+	if result.Truncated {
+		log.Printf("Warning: workflow %s stage %s output was truncated at the token limit", workflow.ID, result.Stage)
+	}
+	if result.ServedBy != "" {
+		log.Printf("Workflow %s stage %s was served by fallback model %s", workflow.ID, result.Stage, result.ServedBy)
+	}
+
+	switch result.Stage {
+	case types.StageDevelopment:
+		workflow.Document = result.Result
+		workflow.Feedback = ""
+		workflow.ReviewItems = nil
+	case types.StageReview:
+		workflow.ReviewItems = result.ReviewItems
+		if result.RequiresRetry {
+			o.captureTraining(ctx, workflow, result, false)
+			return o.retry(ctx, workflow, types.StageDevelopment, result.ReviewFeedback)
+		}
+		workflow.Feedback = result.ReviewFeedback
+	case types.StageApproval:
+		o.captureTraining(ctx, workflow, result, result.Approved)
+		if !result.Approved {
+			feedback := result.ReviewFeedback
+			if feedback == "" {
+				feedback = result.Result
+			}
+			workflow.ReviewItems = result.ReviewItems
+			return o.retry(ctx, workflow, types.StageDevelopment, feedback)
+		}
+	case types.StageTesting:
+		if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(result.Result)), "FAILED") {
+			workflow.ReviewItems = nil // The test failures replace the review items
+			return o.retry(ctx, workflow, types.StageDevelopment, result.Result)
+		}
+	}
+
+	next := result.Stage.Next()
+	if next == types.StageCompleted {
+		return o.complete(ctx, workflow)
+	}
+	return o.dispatch(ctx, workflow, next)
+}
+
+// handleWorkflowRequest starts a workflow from an MQTT request
+func (o *Orchestrator) handleWorkflowRequest(ctx context.Context, payload []byte) {
+	var request WorkflowRequest
+	if _, err := types.UnwrapMessage(payload, types.MessageTypeWorkflowRequest, &request); err != nil {
+		log.Printf("Failed to unmarshal workflow request: %v", err)
+		return
+	}
+
+	if _, err := o.StartWorkflow(ctx, request); err != nil {
+		log.Printf("Failed to start workflow: %v", err)
+	}
+}
+
+// Capabilities returns the document types and models the orchestrator advertises
+func (o *Orchestrator) Capabilities() types.OrchestratorCapabilities {
+	return types.OrchestratorCapabilities{
+		DocumentTypes: append([]string(nil), o.config.DocumentTypes...),
+		Models:        append([]string(nil), o.config.Models...),
+	}
+}
+
+// handleCapabilitiesRequest replies to a capabilities request on its reply topic
+func (o *Orchestrator) handleCapabilitiesRequest(ctx context.Context, payload []byte) {
+	var request types.CapabilitiesRequest
+	envelope, err := types.UnwrapMessage(payload, types.MessageTypeCapabilitiesReq, &request)
+	if err != nil {
+		log.Printf("Failed to unmarshal capabilities request: %v", err)
+		return
+	}
+	if request.ReplyTo == "" {
+		log.Printf("Ignoring capabilities request without reply topic")
+		return
+	}
+
+	data, err := types.WrapMessage(types.MessageTypeCapabilities, envelope.CorrelationID, o.Capabilities())
+	if err != nil {
+		log.Printf("Failed to marshal capabilities: %v", err)
+		return
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, o.config.PublishTimeout)
+	defer cancel()
+
+	if err := o.mqttClient.Publish(publishCtx, request.ReplyTo, data); err != nil {
+		log.Printf("Failed to publish capabilities to %s: %v", request.ReplyTo, err)
+	}
+}
+
+// handleResult processes a stage result from an MQTT message
+func (o *Orchestrator) handleResult(ctx context.Context, payload []byte) {
+	var result types.WorkflowResult
+	if _, err := types.UnwrapMessage(payload, types.MessageTypeWorkflowResult, &result); err != nil {
+		log.Printf("Failed to unmarshal workflow result: %v", err)
+		return
+	}
+
+	o.enqueueResult(ctx, result)
+}
+
+// retry sends the workflow back to stage, failing it once retries are exhausted.
+// Callers must hold workflow.mu.
+func (o *Orchestrator) retry(ctx context.Context, workflow *Workflow, stage types.WorkflowStage, feedback string) error {
+	if workflow.RetryCount >= o.config.MaxRetries {
+		return o.fail(ctx, workflow, fmt.Sprintf("max retries (%d) exceeded: %s", o.config.MaxRetries, feedback))
+	}
+
+	workflow.RetryCount++
+	workflow.Feedback = feedback
+	return o.dispatch(ctx, workflow, stage)
+}
+
+// dispatch starts stage for the workflow. The stage and its dispatch state
+// change together, so a task that fails to publish leaves the workflow in the
+// new stage for the watchdog to re-dispatch. Callers must hold workflow.mu.
+func (o *Orchestrator) dispatch(ctx context.Context, workflow *Workflow, stage types.WorkflowStage) error {
+	now := o.now()
+	workflow.Stage = stage
+	workflow.UpdatedAt = now
+	workflow.DispatchedAt = now
+	workflow.stageStarted = now
+	workflow.pendingTasks = make(map[string]struct{})
+	workflow.Redispatches = 0
+	workflow.aggregator = nil
+	if stage == types.StageReview && o.config.ReviewQuorum > 1 {
+		workflow.aggregator = NewResultAggregator(o.config.ReviewQuorum, now)
+	}
+	return o.publishStageTask(ctx, workflow, stage)
+}
+
+// publishStageTask publishes a task for stage, failing the workflow if its
+// deadline has passed. Callers must hold workflow.mu.
+func (o *Orchestrator) publishStageTask(ctx context.Context, workflow *Workflow, stage types.WorkflowStage) error {
+	now := o.now()
+
+	taskID := fmt.Sprintf("%s-%s-%d", workflow.ID, stage, workflow.RetryCount)
+	if workflow.Redispatches > 0 {
+		taskID = fmt.Sprintf("%s-r%d", taskID, workflow.Redispatches)
+	}
+
+	task := types.WorkflowTask{
+		Task: types.Task{
+			ID:        taskID,
+			Type:      workflow.Type,
+			Payload:   workflow.Payload,
+			CreatedAt: now,
+
+			ForceProvider: workflow.ForceProvider,
+			ForceModel:    workflow.ForceModel,
+		},
+		WorkflowID:     workflow.ID,
+		Stage:          stage,
+		RequiredRole:   stage.RequiredRole(),
+		PreviousOutput: workflow.Document,
+		ReviewFeedback: workflow.Feedback,
+		ReviewItems:    workflow.ReviewItems,
+		RetryCount:     workflow.RetryCount,
+		MaxRetries:     o.config.MaxRetries,
+		Deadline:       workflow.Deadline,
+	}
+
+	if task.DeadlineExceeded(now) {
+		return o.fail(ctx, workflow, fmt.Sprintf("workflow deadline exceeded before %s stage", stage))
+	}
+
+	data, err := types.WrapMessage(types.MessageTypeWorkflowTask, workflow.ID, task)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task %s: %w", task.ID, err)
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, o.config.PublishTimeout)
+	defer cancel()
+
+	topic := fmt.Sprintf(WorkflowTaskTopic, stage)
+	if err := o.mqttClient.Publish(publishCtx, topic, data); err != nil {
+		return fmt.Errorf("failed to publish task %s: %w", task.ID, err)
+	}
+
+	workflow.UpdatedAt = now
+	workflow.DispatchedAt = now
+	workflow.pendingTasks[task.ID] = struct{}{}
+	workflow.lastTask = task
+	log.Printf("Workflow %s dispatched %s stage (task %s)", workflow.ID, stage, task.ID)
+	return nil
+}
+
+// complete writes the final document and publishes the workflow outcome.
+// Callers must hold workflow.mu.
+func (o *Orchestrator) complete(ctx context.Context, workflow *Workflow) error {
+	if outputFile := workflow.Payload["output_file"]; outputFile != "" {
+		path, err := o.outputPath(outputFile)
+		if err == nil {
+			err = o.writeDocument(path, workflow.Document)
+		}
+		if err != nil {
+			return o.fail(ctx, workflow, err.Error())
+		}
+		log.Printf("Workflow %s wrote final document to %s", workflow.ID, path)
+	}
+
+	workflow.Stage = types.StageCompleted
+	workflow.UpdatedAt = o.now()
+	log.Printf("Workflow %s completed", workflow.ID)
+
+	err := o.publishOutcome(ctx, workflow, true)
+	o.finishBatchDocument(ctx, workflow)
+	o.releaseSlot(ctx, workflow)
+	return err
+}
+
+// fail marks the workflow failed and publishes the outcome. Callers must hold workflow.mu.
+func (o *Orchestrator) fail(ctx context.Context, workflow *Workflow, reason string) error {
+	failedStage := workflow.Stage
+	workflow.Stage = types.StageFailed
+	workflow.Error = reason
+	workflow.Errors = append(workflow.Errors, fmt.Sprintf("%s: %s", failedStage, reason))
+	workflow.UpdatedAt = o.now()
+	log.Printf("Workflow %s failed: %s", workflow.ID, reason)
+
+	if err := o.publishDeadLetter(ctx, workflow); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	err := o.publishOutcome(ctx, workflow, false)
+	o.finishBatchDocument(ctx, workflow)
+	o.releaseSlot(ctx, workflow)
+	return err
+}
+
+// publishDeadLetter publishes the workflow's last task as a retained dead
+// letter so operators can inspect and requeue it later
+func (o *Orchestrator) publishDeadLetter(ctx context.Context, workflow *Workflow) error {
+	if workflow.lastTask.ID == "" {
+		return nil
+	}
+
+	deadLetter := types.DeadLetter{
+		Task:           workflow.lastTask,
+		Topic:          fmt.Sprintf(WorkflowTaskTopic, workflow.lastTask.Stage),
+		Reason:         workflow.Error,
+		Errors:         workflow.Errors,
+		DeadLetteredAt: workflow.UpdatedAt,
+	}
+
+	data, err := types.WrapMessage(types.MessageTypeDeadLetter, workflow.ID, deadLetter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter for workflow %s: %w", workflow.ID, err)
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, o.config.PublishTimeout)
+	defer cancel()
+
+	topic := fmt.Sprintf(DeadLetterTopic, workflow.ID)
+	if publisher, ok := o.mqttClient.(mqtt.RetainedPublisher); ok {
+		err = publisher.PublishRetained(publishCtx, topic, data)
+	} else {
+		err = o.mqttClient.Publish(publishCtx, topic, data)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to publish dead letter for workflow %s: %w", workflow.ID, err)
+	}
+
+	log.Printf("Workflow %s dead-lettered task %s to %s", workflow.ID, workflow.lastTask.ID, topic)
+	return nil
+}
+
+// reopen revives a failed workflow whose dead-lettered task was requeued,
+// giving it a fresh retry and deadline budget. Callers must hold workflow.mu.
+func (o *Orchestrator) reopen(workflow *Workflow, stage types.WorkflowStage) {
+	now := o.now()
+	workflow.Stage = stage
+	workflow.Error = ""
+	workflow.RetryCount = 0
+	workflow.UpdatedAt = now
+	if o.config.WorkflowTimeout > 0 {
+		workflow.Deadline = now.Add(o.config.WorkflowTimeout)
+	}
+	workflow.aggregator = nil
+	workflow.pendingTasks = map[string]struct{}{workflow.lastTask.ID: {}}
+	workflow.stageStarted = now
+
+	// A requeued task is already running, so it takes a slot past the cap
+	o.mu.Lock()
+	o.takeSlot(workflow)
+	o.mu.Unlock()
+	log.Printf("Workflow %s reopened at %s stage by requeued task %s", workflow.ID, stage, workflow.lastTask.ID)
+}
+
+// publishOutcome publishes the terminal result of a workflow
+func (o *Orchestrator) publishOutcome(ctx context.Context, workflow *Workflow, success bool) error {
+	status := types.StatusCompleted
+	if !success {
+		status = types.StatusFailed
+	}
+
+	outcome := types.WorkflowResult{
+		TaskResult: types.TaskResult{
+			TaskID:      workflow.ID,
+			WorkerID:    string(types.RoleOrchestrator),
+			Success:     success,
+			Status:      status,
+			Result:      workflow.Document,
+			Error:       workflow.Error,
+			ProcessedAt: workflow.UpdatedAt,
+			Duration:    workflow.UpdatedAt.Sub(workflow.StartedAt).Milliseconds(),
+		},
+		WorkflowID: workflow.ID,
+		Stage:      workflow.Stage,
+		WorkerRole: types.RoleOrchestrator,
+		Approved:   success,
+	}
+
+	data, err := types.WrapMessage(types.MessageTypeWorkflowOutcome, workflow.ID, outcome)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workflow outcome: %w", err)
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, o.config.PublishTimeout)
+	defer cancel()
+
+	topic := fmt.Sprintf(WorkflowOutcomeTopic, workflow.ID)
+	if err := o.mqttClient.Publish(publishCtx, topic, data); err != nil {
+		return fmt.Errorf("failed to publish outcome for workflow %s: %w", workflow.ID, err)
+	}
+	return nil
+}
+
+// outputPath resolves a workflow's output_file under the output root,
+// rejecting absolute paths and paths with ".." elements
+func (o *Orchestrator) outputPath(outputFile string) (string, error) {
+	if !filepath.IsLocal(outputFile) || slices.Contains(strings.Split(filepath.ToSlash(outputFile), "/"), "..") {
+		return "", fmt.Errorf("output_file %q must be a relative path inside the output root", outputFile)
+	}
+	return filepath.Join(o.config.OutputRoot, outputFile), nil
+}
+
+// writeDocument writes the final document, keeping earlier versions when configured
+func (o *Orchestrator) writeDocument(path, content string) error {
+	if !o.config.VersionedOutput {
+		return docstore.Write(path, []byte(content))
+	}
+
+	version, err := docstore.WriteVersioned(path, []byte(content))
+	if err != nil {
+		return err
+	}
+	log.Printf("Wrote version %d of %s", version.Version, path)
+	return nil
+}
@@ -0,0 +1,652 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// taskClient records the stage tasks the orchestrator publishes
+type taskClient struct {
+	tasks chan types.WorkflowTask
+
+	mu        sync.Mutex
+	failTopic string // Publishing to this topic fails
+}
+
+func newTaskClient() *taskClient {
+	return &taskClient{tasks: make(chan types.WorkflowTask, 1024)}
+}
+
+func (c *taskClient) Connect(context.Context) error             { return nil }
+func (c *taskClient) Disconnect()                               {}
+func (c *taskClient) IsConnected() bool                         { return true }
+func (c *taskClient) Unsubscribe(context.Context, string) error { return nil }
+
+func (c *taskClient) Subscribe(context.Context, string, mqtt.MessageHandler) error { return nil }
+
+func (c *taskClient) setFailTopic(topic string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failTopic = topic
+}
+
+func (c *taskClient) Publish(ctx context.Context, topic string, payload []byte) error {
+	c.mu.Lock()
+	failTopic := c.failTopic
+	c.mu.Unlock()
+	if topic == failTopic {
+		return errors.New("broker unavailable")
+	}
+
+	if !strings.HasPrefix(topic, "tasks/workflow/") {
+		return nil
+	}
+	var task types.WorkflowTask
+	if _, err := types.UnwrapMessage(payload, types.MessageTypeWorkflowTask, &task); err != nil {
+		return err
+	}
+	c.tasks <- task
+	return nil
+}
+
+// fakeClock is a settable orchestrator clock
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// newTestOrchestrator creates an orchestrator on a fake clock
+func newTestOrchestrator(config Config) (*Orchestrator, *taskClient, *fakeClock) {
+	client := newTaskClient()
+	o := New(client, config)
+	clock := newFakeClock()
+	o.now = clock.Now
+	return o, client, clock
+}
+
+// recordingSink collects training samples
+type recordingSink struct {
+	samples chan TrainingSample
+}
+
+func (s recordingSink) RecordSample(ctx context.Context, sample TrainingSample) error {
+	s.samples <- sample
+	return nil
+}
+
+// passingResult answers task as a worker whose verdict is always positive,
+// or with rejection when reject is set
+func passingResult(task types.WorkflowTask, reject bool) types.WorkflowResult {
+	result := types.WorkflowResult{
+		TaskResult: types.TaskResult{TaskID: task.ID, Success: true, Result: "document"},
+		WorkflowID: task.WorkflowID,
+		Stage:      task.Stage,
+	}
+	switch task.Stage {
+	case types.StageReview:
+		result.RequiresRetry = reject
+		result.ReviewFeedback = "needs work"
+	case types.StageApproval:
+		result.Approved = !reject
+		result.Result = "APPROVED"
+	case types.StageTesting:
+		result.Result = "PASSED"
+	}
+	return result
+}
+
+func TestCaptureTrainingSamplesVerdicts(t *testing.T) {
+	config := DefaultConfig()
+	config.ResultWorkers = 1
+	config.TrainingSampleRate = 1
+	client := newTaskClient()
+	o := New(client, config)
+	sink := recordingSink{samples: make(chan TrainingSample, 8)}
+	o.SetTrainingSink(sink)
+
+	ctx := context.Background()
+	if _, err := o.StartWorkflow(ctx, WorkflowRequest{Type: "api_guide"}); err != nil {
+		t.Fatalf("StartWorkflow: %v", err)
+	}
+
+	// Development, a rejected review, development again, then approvals to the end
+	verdicts := []bool{false, true, false, false, false, false}
+	for _, reject := range verdicts {
+		task := <-client.tasks
+		if err := o.HandleResult(ctx, passingResult(task, reject)); err != nil {
+			t.Fatalf("HandleResult(%s): %v", task.Stage, err)
+		}
+	}
+
+	// Samples are stored in the background, so they may arrive in any order
+	captured := make(map[types.WorkflowStage]TrainingSample)
+	for range 2 {
+		select {
+		case sample := <-sink.samples:
+			captured[sample.Stage] = sample
+		case <-time.After(5 * time.Second):
+			t.Fatalf("captured %d samples, want 2", len(captured))
+		}
+	}
+
+	want := map[types.WorkflowStage]bool{types.StageReview: false, types.StageApproval: true}
+	for stage, approved := range want {
+		sample, ok := captured[stage]
+		if !ok || sample.Approved != approved || sample.Output != "document" {
+			t.Errorf("%s sample = %+v, want approved %v", stage, sample, approved)
+		}
+	}
+}
+
+func TestConcurrentWorkflowsWithInterleavedResults(t *testing.T) {
+	config := DefaultConfig()
+	config.ResultWorkers = 4
+	config.TrainingSampleRate = 0.5
+	client := newTaskClient()
+	o := New(client, config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	o.startResultWorkers(ctx)
+
+	const workflows = 40
+	var started sync.WaitGroup
+	for range workflows {
+		started.Add(1)
+		go func() {
+			defer started.Done()
+			if _, err := o.StartWorkflow(ctx, WorkflowRequest{Type: "api_guide"}); err != nil {
+				t.Errorf("StartWorkflow: %v", err)
+			}
+		}()
+	}
+
+	// Swap the training sink while results are handled
+	go func() {
+		for ctx.Err() == nil {
+			o.SetTrainingSink(recordingSink{samples: make(chan TrainingSample, 1024)})
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	// Every result arrives twice from different goroutines; the duplicate
+	// must be dropped as stale rather than advance the workflow again
+	var workers sync.WaitGroup
+	for range 8 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				select {
+				case task := <-client.tasks:
+					result := passingResult(task, false)
+					go o.enqueueResult(ctx, result)
+					o.enqueueResult(ctx, result)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	started.Wait()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		completed := 0
+		for _, workflow := range o.ListWorkflows() {
+			if workflow.Stage == types.StageCompleted {
+				completed++
+			}
+		}
+		if completed == workflows {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d workflows completed", completed, workflows)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	workers.Wait()
+
+	for _, workflow := range o.ListWorkflows() {
+		if workflow.RetryCount != 0 || len(workflow.Errors) != 0 {
+			t.Errorf("workflow %s retried %d times: %v", workflow.ID, workflow.RetryCount, workflow.Errors)
+		}
+	}
+}
+
+func TestWorkflowRunsEveryStage(t *testing.T) {
+	config := DefaultConfig()
+	config.OutputRoot = t.TempDir()
+	o, client, _ := newTestOrchestrator(config)
+	ctx := context.Background()
+
+	output := filepath.Join(config.OutputRoot, "docs", "guide.md")
+	id, err := o.StartWorkflow(ctx, WorkflowRequest{Type: "api_guide", Payload: map[string]string{"output_file": "docs/guide.md"}})
+	if err != nil {
+		t.Fatalf("StartWorkflow: %v", err)
+	}
+
+	stages := []types.WorkflowStage{types.StageDevelopment, types.StageReview, types.StageApproval, types.StageTesting}
+	for _, stage := range stages {
+		task := <-client.tasks
+		if task.Stage != stage || task.RequiredRole != stage.RequiredRole() || task.WorkflowID != id {
+			t.Fatalf("dispatched %s task for role %s, want %s", task.Stage, task.RequiredRole, stage)
+		}
+		if stage != types.StageDevelopment && task.PreviousOutput != "document" {
+			t.Errorf("%s task PreviousOutput = %q, want the draft", stage, task.PreviousOutput)
+		}
+		if err := o.HandleResult(ctx, passingResult(task, false)); err != nil {
+			t.Fatalf("HandleResult(%s): %v", stage, err)
+		}
+	}
+
+	workflow, _ := o.GetWorkflow(id)
+	if workflow.Stage != types.StageCompleted || workflow.RetryCount != 0 {
+		t.Errorf("workflow = %+v, want completed without retries", workflow)
+	}
+	if data, err := os.ReadFile(output); err != nil || string(data) != "document" {
+		t.Errorf("output file = %q, %v, want the final document", data, err)
+	}
+	if len(client.tasks) != 0 {
+		t.Errorf("published %d tasks after completion", len(client.tasks))
+	}
+}
+
+func TestWorkflowRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		stage        types.WorkflowStage // Stage whose result sends the workflow back
+		result       func(types.WorkflowTask) types.WorkflowResult
+		wantStage    types.WorkflowStage
+		wantFeedback string
+	}{
+		{
+			name:  "review rejects",
+			stage: types.StageReview,
+			result: func(task types.WorkflowTask) types.WorkflowResult {
+				return passingResult(task, true)
+			},
+			wantStage:    types.StageDevelopment,
+			wantFeedback: "needs work",
+		},
+		{
+			name:  "approval rejects",
+			stage: types.StageApproval,
+			result: func(task types.WorkflowTask) types.WorkflowResult {
+				result := passingResult(task, true)
+				result.Result = "REJECTED: missing examples"
+				return result
+			},
+			wantStage:    types.StageDevelopment,
+			wantFeedback: "REJECTED: missing examples",
+		},
+		{
+			name:  "tests fail",
+			stage: types.StageTesting,
+			result: func(task types.WorkflowTask) types.WorkflowResult {
+				result := passingResult(task, false)
+				result.Result = "FAILED: example 1 does not compile"
+				return result
+			},
+			wantStage:    types.StageDevelopment,
+			wantFeedback: "FAILED: example 1 does not compile",
+		},
+		{
+			name:  "stage errors",
+			stage: types.StageReview,
+			result: func(task types.WorkflowTask) types.WorkflowResult {
+				result := passingResult(task, false)
+				result.Success, result.Error = false, "model crashed"
+				return result
+			},
+			wantStage:    types.StageReview,
+			wantFeedback: "model crashed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, client, _ := newTestOrchestrator(DefaultConfig())
+			ctx := context.Background()
+			if _, err := o.StartWorkflow(ctx, WorkflowRequest{Type: "api_guide"}); err != nil {
+				t.Fatalf("StartWorkflow: %v", err)
+			}
+
+			for {
+				task := <-client.tasks
+				result := passingResult(task, false)
+				if task.Stage == tt.stage {
+					result = tt.result(task)
+				}
+				if err := o.HandleResult(ctx, result); err != nil {
+					t.Fatalf("HandleResult(%s): %v", task.Stage, err)
+				}
+				if task.Stage == tt.stage {
+					break
+				}
+			}
+
+			retried := <-client.tasks
+			if retried.Stage != tt.wantStage || retried.RetryCount != 1 || retried.ReviewFeedback != tt.wantFeedback {
+				t.Errorf("retried %s task (retry %d, feedback %q), want %s with %q",
+					retried.Stage, retried.RetryCount, retried.ReviewFeedback, tt.wantStage, tt.wantFeedback)
+			}
+		})
+	}
+}
+
+func TestDispatchPublishFails(t *testing.T) {
+	config := DefaultConfig()
+	config.WorkflowTimeout = 0
+	config.StageTimeout = 10 * time.Minute
+	o, client, clock := newTestOrchestrator(config)
+	ctx := context.Background()
+
+	id, err := o.StartWorkflow(ctx, WorkflowRequest{Type: "api_guide"})
+	if err != nil {
+		t.Fatalf("StartWorkflow: %v", err)
+	}
+	draft := <-client.tasks
+
+	client.setFailTopic(fmt.Sprintf(WorkflowTaskTopic, types.StageReview))
+	clock.Advance(time.Minute)
+	if err := o.HandleResult(ctx, passingResult(draft, false)); err == nil {
+		t.Fatal("HandleResult succeeded, want the publish error")
+	}
+
+	// The workflow moved on to review even though its task never went out
+	workflow, _ := o.GetWorkflow(id)
+	if workflow.Stage != types.StageReview || !workflow.DispatchedAt.Equal(clock.Now()) || workflow.Document != "document" {
+		t.Errorf("workflow at %s dispatched %v, want review dispatched %v", workflow.Stage, workflow.DispatchedAt, clock.Now())
+	}
+	if err := o.HandleResult(ctx, passingResult(draft, false)); err == nil {
+		t.Error("HandleResult accepted the development result twice")
+	}
+
+	// Once the broker is back the watchdog re-dispatches the review
+	client.setFailTopic("")
+	clock.Advance(11 * time.Minute)
+	o.checkStalledStages(ctx)
+	review := <-client.tasks
+	if review.Stage != types.StageReview || review.PreviousOutput != "document" {
+		t.Fatalf("re-dispatched %s task with %q, want the review of the draft", review.Stage, review.PreviousOutput)
+	}
+	if err := o.HandleResult(ctx, passingResult(review, false)); err != nil {
+		t.Fatalf("HandleResult(review): %v", err)
+	}
+	if approval := <-client.tasks; approval.Stage != types.StageApproval {
+		t.Errorf("dispatched %s after the review, want approval", approval.Stage)
+	}
+}
+
+func TestWorkflowFailsAfterMaxRetries(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxRetries = 1
+	o, client, _ := newTestOrchestrator(config)
+	ctx := context.Background()
+
+	id, err := o.StartWorkflow(ctx, WorkflowRequest{Type: "api_guide"})
+	if err != nil {
+		t.Fatalf("StartWorkflow: %v", err)
+	}
+
+	// Development, a rejected review, development again, then a second rejection
+	for _, reject := range []bool{false, true, false, true} {
+		if err := o.HandleResult(ctx, passingResult(<-client.tasks, reject)); err != nil {
+			t.Fatalf("HandleResult: %v", err)
+		}
+	}
+
+	workflow, _ := o.GetWorkflow(id)
+	if workflow.Stage != types.StageFailed || !strings.Contains(workflow.Error, "max retries (1) exceeded") {
+		t.Errorf("workflow = %+v, want failed after one retry", workflow)
+	}
+	if len(client.tasks) != 0 {
+		t.Errorf("published %d tasks after the workflow failed", len(client.tasks))
+	}
+}
+
+func TestHandleResultRejectsUnexpectedResults(t *testing.T) {
+	o, client, _ := newTestOrchestrator(DefaultConfig())
+	ctx := context.Background()
+	id, err := o.StartWorkflow(ctx, WorkflowRequest{Type: "api_guide"})
+	if err != nil {
+		t.Fatalf("StartWorkflow: %v", err)
+	}
+	task := <-client.tasks
+
+	tests := []struct {
+		name   string
+		result types.WorkflowResult
+	}{
+		{"unknown workflow", types.WorkflowResult{WorkflowID: "wf-missing", Stage: types.StageDevelopment}},
+		{"wrong stage", types.WorkflowResult{WorkflowID: id, Stage: types.StageReview}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := o.HandleResult(ctx, tt.result); err == nil {
+				t.Error("HandleResult accepted the result")
+			}
+		})
+	}
+
+	if workflow, _ := o.GetWorkflow(id); workflow.Stage != task.Stage {
+		t.Errorf("stage = %s after rejected results, want %s", workflow.Stage, task.Stage)
+	}
+}
+
+func TestStartWorkflowRequiresType(t *testing.T) {
+	o, client, _ := newTestOrchestrator(DefaultConfig())
+	if _, err := o.StartWorkflow(context.Background(), WorkflowRequest{}); err == nil {
+		t.Error("StartWorkflow accepted a request without a type")
+	}
+	if len(client.tasks) != 0 {
+		t.Errorf("published %d tasks for a rejected request", len(client.tasks))
+	}
+}
+
+func TestStartWorkflowRejectsOutputOutsideRoot(t *testing.T) {
+	outputs := []string{
+		"/etc/guide.md",
+		"../guide.md",
+		"docs/../../guide.md",
+		"docs/../guide.md",
+	}
+
+	for _, output := range outputs {
+		t.Run(output, func(t *testing.T) {
+			config := DefaultConfig()
+			config.OutputRoot = t.TempDir()
+			o, client, _ := newTestOrchestrator(config)
+
+			_, err := o.StartWorkflow(context.Background(), WorkflowRequest{Type: "api_guide", Payload: map[string]string{"output_file": output}})
+			if err == nil || !strings.Contains(err.Error(), "inside the output root") {
+				t.Errorf("StartWorkflow error = %v, want output_file rejected", err)
+			}
+			if len(client.tasks) != 0 {
+				t.Errorf("dispatched %d tasks for a rejected workflow", len(client.tasks))
+			}
+		})
+	}
+}
+
+func TestWorkflowDeadline(t *testing.T) {
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		elapsed   time.Duration
+		wantStage types.WorkflowStage
+	}{
+		{"within deadline", time.Hour, 30 * time.Minute, types.StageReview},
+		{"deadline passed", time.Hour, 2 * time.Hour, types.StageFailed},
+		{"no deadline", 0, 48 * time.Hour, types.StageReview},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.WorkflowTimeout = tt.timeout
+			o, client, clock := newTestOrchestrator(config)
+			started := clock.Now()
+
+			ctx := context.Background()
+			id, err := o.StartWorkflow(ctx, WorkflowRequest{Type: "api_guide"})
+			if err != nil {
+				t.Fatalf("StartWorkflow: %v", err)
+			}
+			task := <-client.tasks
+			if wantDeadline := started.Add(tt.timeout); tt.timeout > 0 && !task.Deadline.Equal(wantDeadline) {
+				t.Errorf("task deadline = %v, want %v", task.Deadline, wantDeadline)
+			}
+			if tt.timeout == 0 && !task.Deadline.IsZero() {
+				t.Errorf("task deadline = %v, want none", task.Deadline)
+			}
+
+			clock.Advance(tt.elapsed)
+			o.HandleResult(ctx, passingResult(task, false))
+
+			workflow, _ := o.GetWorkflow(id)
+			if workflow.Stage != tt.wantStage {
+				t.Fatalf("stage = %s, want %s", workflow.Stage, tt.wantStage)
+			}
+			if tt.wantStage == types.StageFailed {
+				if !strings.Contains(workflow.Error, "deadline exceeded") {
+					t.Errorf("Error = %q, want a deadline failure", workflow.Error)
+				}
+				if len(client.tasks) != 0 {
+					t.Errorf("published %d tasks after the deadline", len(client.tasks))
+				}
+			}
+		})
+	}
+}
+
+// replyClient records every publish by topic
+type replyClient struct {
+	taskClient
+	mu      sync.Mutex
+	replies map[string][]byte
+}
+
+func (c *replyClient) Publish(ctx context.Context, topic string, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.replies[topic] = payload
+	return nil
+}
+
+func TestHandleCapabilitiesRequest(t *testing.T) {
+	config := DefaultConfig()
+	config.DocumentTypes = []string{"api_guide", "incident_runbook"}
+	config.Models = []string{"phi-4"}
+
+	request := func(replyTo string) []byte {
+		data, err := types.WrapMessage(types.MessageTypeCapabilitiesReq, "capabilities-1", types.CapabilitiesRequest{ReplyTo: replyTo})
+		if err != nil {
+			t.Fatalf("WrapMessage: %v", err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		payload []byte
+		reply   bool
+	}{
+		{"request", request("orchestrator/capabilities/reply/capabilities-1"), true},
+		{"no reply topic", request(""), false},
+		{"malformed", []byte(`capabilities`), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &replyClient{replies: make(map[string][]byte)}
+			o := New(client, config)
+			o.handleCapabilitiesRequest(context.Background(), tt.payload)
+
+			data, replied := client.replies["orchestrator/capabilities/reply/capabilities-1"]
+			if replied != tt.reply || len(client.replies) > 1 {
+				t.Fatalf("replies = %d, want reply %v", len(client.replies), tt.reply)
+			}
+			if !tt.reply {
+				return
+			}
+
+			var capabilities types.OrchestratorCapabilities
+			envelope, err := types.UnwrapMessage(data, types.MessageTypeCapabilities, &capabilities)
+			if err != nil {
+				t.Fatalf("reply: %v", err)
+			}
+			if envelope.CorrelationID != "capabilities-1" {
+				t.Errorf("CorrelationID = %q, want the request's", envelope.CorrelationID)
+			}
+			if strings.Join(capabilities.DocumentTypes, ",") != "api_guide,incident_runbook" || strings.Join(capabilities.Models, ",") != "phi-4" {
+				t.Errorf("capabilities = %+v, want the configured ones", capabilities)
+			}
+		})
+	}
+}
+
+func TestRejectedReviewItemsReachRetry(t *testing.T) {
+	o, client, _ := newTestOrchestrator(DefaultConfig())
+	ctx := context.Background()
+	if _, err := o.StartWorkflow(ctx, WorkflowRequest{Type: "api_guide"}); err != nil {
+		t.Fatalf("StartWorkflow: %v", err)
+	}
+
+	items := []types.ReviewItem{{Severity: types.SeverityMajor, Location: "Setup", Message: "Missing install steps"}}
+	for _, stage := range []types.WorkflowStage{types.StageDevelopment, types.StageReview} {
+		task := <-client.tasks
+		if task.Stage != stage {
+			t.Fatalf("published %s task, want %s", task.Stage, stage)
+		}
+		result := passingResult(task, stage == types.StageReview)
+		result.ReviewItems = items
+		if err := o.HandleResult(ctx, result); err != nil {
+			t.Fatalf("HandleResult(%s): %v", stage, err)
+		}
+	}
+
+	retry := <-client.tasks
+	if retry.Stage != types.StageDevelopment || !reflect.DeepEqual(retry.ReviewItems, items) {
+		t.Errorf("retry task %s has review items %+v, want %+v", retry.Stage, retry.ReviewItems, items)
+	}
+
+	// Review items from the last review are not replayed after a fresh draft
+	if err := o.HandleResult(ctx, passingResult(retry, false)); err != nil {
+		t.Fatalf("HandleResult(development): %v", err)
+	}
+	if review := <-client.tasks; len(review.ReviewItems) != 0 {
+		t.Errorf("review task carries review items %+v, want none", review.ReviewItems)
+	}
+}
@@ -0,0 +1,93 @@
+package orchestrator
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// WorkflowSummary is the compact audit record kept after a finished workflow
+// is evicted from memory
+type WorkflowSummary struct {
+	ID         string              `json:"id"`
+	Type       string              `json:"type"`
+	Stage      types.WorkflowStage `json:"stage"` // Completed or failed
+	RetryCount int                 `json:"retry_count"`
+	StartedAt  time.Time           `json:"started_at"`
+	FinishedAt time.Time           `json:"finished_at"`
+	Error      string              `json:"error,omitempty"`
+	Failures   int                 `json:"failures"` // Stage failures recorded over the workflow's life
+}
+
+// summarize reduces a finished workflow to its audit record
+func summarize(workflow *Workflow) WorkflowSummary {
+	return WorkflowSummary{
+		ID:         workflow.ID,
+		Type:       workflow.Type,
+		Stage:      workflow.Stage,
+		RetryCount: workflow.RetryCount,
+		StartedAt:  workflow.StartedAt,
+		FinishedAt: workflow.UpdatedAt,
+		Error:      workflow.Error,
+		Failures:   len(workflow.Errors),
+	}
+}
+
+// evictFinished drops completed and failed workflows that finished more than
+// Retention ago, keeping a summary of each. An evicted failed workflow can no
+// longer be reopened by requeueing its dead letter. Finished batches are
+// dropped after Retention too. Workflows busy handling a result are left for
+// the next pass.
+func (o *Orchestrator) evictFinished() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.config.Retention <= 0 {
+		return
+	}
+
+	now := o.now()
+	var evicted []WorkflowSummary
+	for id, workflow := range o.workflows {
+		if !workflow.mu.TryLock() {
+			continue
+		}
+		if workflow.Stage.IsTerminal() && now.Sub(workflow.UpdatedAt) >= o.config.Retention {
+			workflow.evicted = true
+			evicted = append(evicted, summarize(workflow))
+			delete(o.workflows, id)
+		}
+		workflow.mu.Unlock()
+	}
+	for id, batch := range o.batches {
+		if !batch.mu.TryLock() {
+			continue
+		}
+		if batch.Finished() && now.Sub(batch.FinishedAt) >= o.config.Retention {
+			delete(o.batches, id)
+		}
+		batch.mu.Unlock()
+	}
+	if len(evicted) == 0 {
+		return
+	}
+
+	sort.Slice(evicted, func(i, j int) bool {
+		return evicted[i].FinishedAt.Before(evicted[j].FinishedAt)
+	})
+	o.summaries = append(o.summaries, evicted...)
+	if limit := o.config.MaxSummaries; limit > 0 && len(o.summaries) > limit {
+		o.summaries = append([]WorkflowSummary(nil), o.summaries[len(o.summaries)-limit:]...)
+	}
+	log.Printf("Evicted %d finished workflows older than %v", len(evicted), o.config.Retention)
+}
+
+// WorkflowHistory returns summaries of evicted workflows, oldest first
+func (o *Orchestrator) WorkflowHistory() []WorkflowSummary {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	return append([]WorkflowSummary(nil), o.summaries...)
+}
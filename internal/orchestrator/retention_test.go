@@ -0,0 +1,116 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// newRetentionOrchestrator creates an orchestrator keeping finished
+// workflows for an hour and at most maxSummaries summaries
+func newRetentionOrchestrator(maxSummaries int) (*Orchestrator, *taskClient, *fakeClock) {
+	config := DefaultConfig()
+	config.WorkflowTimeout = 0
+	config.StageTimeout = 0
+	config.MaxRetries = 0
+	config.Retention = time.Hour
+	config.MaxSummaries = maxSummaries
+	return newTestOrchestrator(config)
+}
+
+// runWorkflow starts a workflow and answers its stage tasks until it
+// finishes. A rejecting reviewer fails the workflow since no retries are allowed.
+func runWorkflow(t *testing.T, o *Orchestrator, client *taskClient, reject bool) string {
+	t.Helper()
+	ctx := context.Background()
+	id, err := o.StartWorkflow(ctx, WorkflowRequest{Type: "api_guide"})
+	if err != nil {
+		t.Fatalf("StartWorkflow: %v", err)
+	}
+	for {
+		workflow, _ := o.GetWorkflow(id)
+		if workflow.Stage.IsTerminal() {
+			return id
+		}
+		task := <-client.tasks
+		if err := o.HandleResult(ctx, passingResult(task, reject && task.Stage == types.StageReview)); err != nil {
+			t.Fatalf("HandleResult(%s): %v", task.Stage, err)
+		}
+	}
+}
+
+func TestEvictFinished(t *testing.T) {
+	tests := []struct {
+		name        string
+		reject      bool
+		elapsed     time.Duration
+		wantEvicted bool
+		wantStage   types.WorkflowStage
+	}{
+		{"completed within retention", false, 59 * time.Minute, false, types.StageCompleted},
+		{"completed past retention", false, time.Hour, true, types.StageCompleted},
+		{"failed past retention", true, 2 * time.Hour, true, types.StageFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, client, clock := newRetentionOrchestrator(0)
+			id := runWorkflow(t, o, client, tt.reject)
+
+			clock.Advance(tt.elapsed)
+			o.evictFinished()
+
+			_, tracked := o.GetWorkflow(id)
+			if evicted := !tracked; evicted != tt.wantEvicted {
+				t.Fatalf("evicted = %v, want %v", evicted, tt.wantEvicted)
+			}
+			history := o.WorkflowHistory()
+			if !tt.wantEvicted {
+				if len(history) != 0 {
+					t.Errorf("history = %+v, want none", history)
+				}
+				return
+			}
+			if len(history) != 1 || history[0].ID != id || history[0].Stage != tt.wantStage {
+				t.Fatalf("history = %+v, want a %s summary of %s", history, tt.wantStage, id)
+			}
+			if tt.reject && (history[0].Error == "" || history[0].Failures == 0) {
+				t.Errorf("failed summary = %+v, want its error", history[0])
+			}
+		})
+	}
+}
+
+func TestEvictFinishedKeepsRunningWorkflows(t *testing.T) {
+	o, client, clock := newRetentionOrchestrator(0)
+	id, err := o.StartWorkflow(context.Background(), WorkflowRequest{Type: "api_guide"})
+	if err != nil {
+		t.Fatalf("StartWorkflow: %v", err)
+	}
+	<-client.tasks
+
+	clock.Advance(24 * time.Hour)
+	o.evictFinished()
+	if _, tracked := o.GetWorkflow(id); !tracked {
+		t.Error("running workflow was evicted")
+	}
+}
+
+func TestWorkflowHistoryLimit(t *testing.T) {
+	o, client, clock := newRetentionOrchestrator(2)
+	var ids []string
+	for range 3 {
+		ids = append(ids, runWorkflow(t, o, client, false))
+		clock.Advance(time.Minute) // Distinct finish times
+	}
+
+	clock.Advance(time.Hour)
+	o.evictFinished()
+
+	history := o.WorkflowHistory()
+	if len(history) != 2 || history[0].ID != ids[1] || history[1].ID != ids[2] {
+		t.Errorf("history = %+v, want the two newest of %v", history, ids)
+	}
+}
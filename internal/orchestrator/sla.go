@@ -0,0 +1,194 @@
+package orchestrator
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// DefaultSLAWindow is the number of recent stage durations the p95 is computed over
+const DefaultSLAWindow = 200
+
+// Stage SLA metrics (exported via expvar)
+var (
+	stageSLABreaches = expvar.NewMap("orchestrator_stage_sla_breaches")
+	stageP95Millis   = expvar.NewMap("orchestrator_stage_p95_ms")
+)
+
+// StageSLAReport summarises how a stage is doing against its latency target
+type StageSLAReport struct {
+	Stage    types.WorkflowStage `json:"stage"`
+	Target   time.Duration       `json:"target"`
+	P95      time.Duration       `json:"p95"`
+	Samples  int                 `json:"samples"`
+	Breaches int64               `json:"breaches"` // Stage runs that took longer than Target
+	Met      bool                `json:"met"`      // P95 is within Target
+}
+
+// SLATracker records stage durations and reports breaches of per-stage p95 targets
+type SLATracker struct {
+	mu       sync.Mutex
+	targets  map[types.WorkflowStage]time.Duration
+	window   int
+	samples  map[types.WorkflowStage][]time.Duration // Most recent durations, oldest first
+	breaches map[types.WorkflowStage]int64
+	missing  map[types.WorkflowStage]bool // Stages whose p95 is currently over target
+}
+
+// NewSLATracker creates a tracker for the given per-stage p95 targets.
+// Stages without a target are still measured but never breach.
+func NewSLATracker(targets map[types.WorkflowStage]time.Duration, window int) *SLATracker {
+	if window <= 0 {
+		window = DefaultSLAWindow
+	}
+	copied := make(map[types.WorkflowStage]time.Duration, len(targets))
+	for stage, target := range targets {
+		copied[stage] = target
+	}
+	return &SLATracker{
+		targets:  copied,
+		window:   window,
+		samples:  make(map[types.WorkflowStage][]time.Duration),
+		breaches: make(map[types.WorkflowStage]int64),
+		missing:  make(map[types.WorkflowStage]bool),
+	}
+}
+
+// Record adds a stage duration and reports whether it exceeded the stage's target
+func (t *SLATracker) Record(stage types.WorkflowStage, duration time.Duration) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	samples := append(t.samples[stage], duration)
+	if len(samples) > t.window {
+		samples = samples[len(samples)-t.window:]
+	}
+	t.samples[stage] = samples
+
+	p95 := percentile(samples, 0.95)
+	stageP95Millis.Set(string(stage), expvarFloat(float64(p95.Milliseconds())))
+
+	target, ok := t.targets[stage]
+	if !ok || target <= 0 {
+		return false
+	}
+
+	breach := duration > target
+	if breach {
+		t.breaches[stage]++
+		stageSLABreaches.Add(string(stage), 1)
+	}
+
+	// Warn when the p95 crosses the target, not on every sample over it
+	missing := p95 > target
+	if missing && !t.missing[stage] {
+		log.Printf("Warning: %s stage p95 latency %v exceeds SLA target %v over %d samples",
+			stage, p95.Round(time.Millisecond), target, len(samples))
+	} else if !missing && t.missing[stage] {
+		log.Printf("%s stage p95 latency %v is back within SLA target %v",
+			stage, p95.Round(time.Millisecond), target)
+	}
+	t.missing[stage] = missing
+
+	return breach
+}
+
+// Report returns the current SLA status of every measured or targeted stage
+func (t *SLATracker) Report() []StageSLAReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stages := make(map[types.WorkflowStage]struct{})
+	for stage := range t.targets {
+		stages[stage] = struct{}{}
+	}
+	for stage := range t.samples {
+		stages[stage] = struct{}{}
+	}
+
+	reports := make([]StageSLAReport, 0, len(stages))
+	for stage := range stages {
+		samples := t.samples[stage]
+		target := t.targets[stage]
+		p95 := percentile(samples, 0.95)
+		reports = append(reports, StageSLAReport{
+			Stage:    stage,
+			Target:   target,
+			P95:      p95,
+			Samples:  len(samples),
+			Breaches: t.breaches[stage],
+			Met:      target <= 0 || p95 <= target,
+		})
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Stage < reports[j].Stage })
+	return reports
+}
+
+// percentile returns the nearest-rank percentile p of samples
+func percentile(samples []time.Duration, p float64) time.Duration {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(float64(len(sorted))*p+0.999999) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// expvarFloat wraps a value for expvar.Map.Set
+func expvarFloat(value float64) *expvar.Float {
+	f := new(expvar.Float)
+	f.Set(value)
+	return f
+}
+
+// ParseStageSLA parses per-stage targets of the form "development=10m,review=5m"
+func ParseStageSLA(value string) (map[types.WorkflowStage]time.Duration, error) {
+	targets := make(map[types.WorkflowStage]time.Duration)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, durationText, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid stage SLA %q: expected stage=duration", entry)
+		}
+
+		stage := types.WorkflowStage(strings.TrimSpace(name))
+		switch stage {
+		case types.StageDevelopment, types.StageReview, types.StageApproval, types.StageTesting:
+		default:
+			return nil, fmt.Errorf("invalid stage SLA %q: unknown stage %s", entry, stage)
+		}
+
+		target, err := time.ParseDuration(strings.TrimSpace(durationText))
+		if err != nil {
+			return nil, fmt.Errorf("invalid stage SLA %q: %w", entry, err)
+		}
+		if target <= 0 {
+			return nil, fmt.Errorf("invalid stage SLA %q: target must be positive", entry)
+		}
+		targets[stage] = target
+	}
+	return targets, nil
+}
+
+// StageSLA returns the latency of each stage against its SLA target
+func (o *Orchestrator) StageSLA() []StageSLAReport {
+	return o.sla.Report()
+}
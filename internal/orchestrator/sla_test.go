@@ -0,0 +1,174 @@
+package orchestrator
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+func TestSLATrackerRecord(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       time.Duration
+		durations    []time.Duration
+		wantBreaches int64
+		wantMet      bool
+	}{
+		{
+			name:      "within target",
+			target:    time.Minute,
+			durations: []time.Duration{10 * time.Second, 30 * time.Second, time.Minute},
+			wantMet:   true,
+		},
+		{
+			name:         "slow runs are breaches",
+			target:       time.Minute,
+			durations:    []time.Duration{10 * time.Second, 2 * time.Minute, 90 * time.Second},
+			wantBreaches: 2,
+		},
+		{
+			name:      "no target never breaches",
+			durations: []time.Duration{time.Hour},
+			wantMet:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets := map[types.WorkflowStage]time.Duration{}
+			if tt.target > 0 {
+				targets[types.StageReview] = tt.target
+			}
+			tracker := NewSLATracker(targets, 0)
+
+			var breaches int64
+			for _, duration := range tt.durations {
+				if tracker.Record(types.StageReview, duration) {
+					breaches++
+				}
+			}
+			if breaches != tt.wantBreaches {
+				t.Errorf("Record() reported %d breaches, want %d", breaches, tt.wantBreaches)
+			}
+
+			reports := tracker.Report()
+			if len(reports) != 1 {
+				t.Fatalf("Report() = %+v, want one stage", reports)
+			}
+			report := reports[0]
+			if report.Breaches != tt.wantBreaches || report.Met != tt.wantMet || report.Samples != len(tt.durations) {
+				t.Errorf("Report() = %+v, want %d breaches, met %v", report, tt.wantBreaches, tt.wantMet)
+			}
+		})
+	}
+}
+
+func TestSLATrackerWindow(t *testing.T) {
+	tracker := NewSLATracker(map[types.WorkflowStage]time.Duration{types.StageDevelopment: time.Minute}, 3)
+
+	// The slow early run ages out of the window; its breach stays counted
+	for _, duration := range []time.Duration{time.Hour, time.Second, time.Second, time.Second} {
+		tracker.Record(types.StageDevelopment, duration)
+	}
+
+	report := tracker.Report()[0]
+	if report.Samples != 3 || report.P95 != time.Second || !report.Met || report.Breaches != 1 {
+		t.Errorf("Report() = %+v, want 3 samples, p95 1s, met, 1 breach", report)
+	}
+}
+
+func TestSLATrackerReportsTargetedStages(t *testing.T) {
+	tracker := NewSLATracker(map[types.WorkflowStage]time.Duration{types.StageApproval: time.Minute}, 0)
+	tracker.Record(types.StageDevelopment, time.Second)
+
+	var stages []types.WorkflowStage
+	for _, report := range tracker.Report() {
+		stages = append(stages, report.Stage)
+	}
+	want := []types.WorkflowStage{types.StageApproval, types.StageDevelopment}
+	if !reflect.DeepEqual(stages, want) {
+		t.Errorf("reported stages %v, want %v", stages, want)
+	}
+}
+
+func TestSLATrackerConcurrentRecords(t *testing.T) {
+	tracker := NewSLATracker(map[types.WorkflowStage]time.Duration{types.StageTesting: time.Second}, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tracker.Record(types.StageTesting, 2*time.Second)
+			tracker.Report()
+		}()
+	}
+	wg.Wait()
+
+	if report := tracker.Report()[0]; report.Breaches != 50 || report.Samples != 50 {
+		t.Errorf("Report() = %+v, want 50 breaches over 50 samples", report)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var hundred []time.Duration
+	for i := 100; i >= 1; i-- {
+		hundred = append(hundred, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		name    string
+		samples []time.Duration
+		want    time.Duration
+	}{
+		{"empty", nil, 0},
+		{"single", []time.Duration{time.Second}, time.Second},
+		{"nearest rank", hundred, 95 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := percentile(tt.samples, 0.95); got != tt.want {
+				t.Errorf("percentile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStageSLA(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    map[types.WorkflowStage]time.Duration
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: map[types.WorkflowStage]time.Duration{}},
+		{
+			name:  "several stages",
+			value: "development=10m, review = 5m,",
+			want: map[types.WorkflowStage]time.Duration{
+				types.StageDevelopment: 10 * time.Minute,
+				types.StageReview:      5 * time.Minute,
+			},
+		},
+		{name: "missing duration", value: "review", wantErr: true},
+		{name: "unknown stage", value: "deploy=1m", wantErr: true},
+		{name: "invalid duration", value: "review=soon", wantErr: true},
+		{name: "non-positive duration", value: "review=0s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStageSLA(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStageSLA() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseStageSLA() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package orchestrator
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// Training capture defaults
+const (
+	DefaultTrainingSampleRate = 0.1
+	DefaultTrainingCollection = "training_samples"
+	TrainingCaptureTimeout    = 30 * time.Second
+)
+
+// trainingSamples counts captured and skipped results (exported via expvar)
+var trainingSamples = expvar.NewMap("orchestrator_training_samples")
+
+// TrainingSample is a stage verdict on a workflow document, kept as a
+// training example. Approved samples are positive examples and rejected ones
+// negative examples.
+type TrainingSample struct {
+	WorkflowID string              `json:"workflow_id"`
+	Type       string              `json:"type"`
+	Stage      types.WorkflowStage `json:"stage"` // Stage that judged the document
+	Input      string              `json:"input"` // The workflow request the document was written for
+	Output     string              `json:"output"`
+	Feedback   string              `json:"feedback,omitempty"`
+	Approved   bool                `json:"approved"`
+	CapturedAt time.Time           `json:"captured_at"`
+}
+
+// Score is the reward the training exporter sees for the sample
+func (s TrainingSample) Score() float64 {
+	if s.Approved {
+		return 1.0
+	}
+	return 0.0
+}
+
+// TrainingSink stores sampled training examples
+type TrainingSink interface {
+	RecordSample(ctx context.Context, sample TrainingSample) error
+}
+
+// TrainingSampler decides which stage verdicts are captured for training
+type TrainingSampler struct {
+	approvedRate   float64
+	keepRejections bool
+	mu             sync.Mutex
+	random         *rand.Rand
+}
+
+// NewTrainingSampler captures approvedRate (0 to 1) of approved results and,
+// when keepRejections is set, every rejection
+func NewTrainingSampler(approvedRate float64, keepRejections bool) *TrainingSampler {
+	return &TrainingSampler{
+		approvedRate:   min(max(approvedRate, 0), 1),
+		keepRejections: keepRejections,
+		random:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Sample reports whether a verdict should be captured
+func (s *TrainingSampler) Sample(approved bool) bool {
+	if !approved {
+		return s.keepRejections
+	}
+	if s.approvedRate <= 0 {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.random.Float64() < s.approvedRate
+}
+
+// SetTrainingSink captures sampled review and approval verdicts to sink for
+// the training exporter; nil disables capture
+func (o *Orchestrator) SetTrainingSink(sink TrainingSink) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.training = sink
+}
+
+// captureTraining samples a verdict on the workflow's current document and
+// stores it in the background so a slow sink never holds up the workflow.
+// Callers must hold workflow.mu, which guards the fields copied into the
+// sample; the sink is read under o.mu, taken after workflow.mu as elsewhere.
+func (o *Orchestrator) captureTraining(ctx context.Context, workflow *Workflow, result types.WorkflowResult, approved bool) {
+	o.mu.RLock()
+	sink := o.training
+	o.mu.RUnlock()
+	if sink == nil || workflow.Document == "" {
+		return
+	}
+	if !o.sampler.Sample(approved) {
+		trainingSamples.Add("skipped", 1)
+		return
+	}
+
+	sample := TrainingSample{
+		WorkflowID: workflow.ID,
+		Type:       workflow.Type,
+		Stage:      result.Stage,
+		Input:      trainingInput(workflow),
+		Output:     workflow.Document,
+		Feedback:   result.ReviewFeedback,
+		Approved:   approved,
+		CapturedAt: o.now(),
+	}
+	if approved {
+		trainingSamples.Add("approved", 1)
+	} else {
+		trainingSamples.Add("rejected", 1)
+	}
+
+	go func() {
+		captureCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), TrainingCaptureTimeout)
+		defer cancel()
+
+		if err := sink.RecordSample(captureCtx, sample); err != nil {
+			trainingSamples.Add("errors", 1)
+			log.Printf("Warning: failed to capture training sample for workflow %s: %v", sample.WorkflowID, err)
+		}
+	}()
+}
+
+// trainingInput describes the workflow request a document was written for
+func trainingInput(workflow *Workflow) string {
+	keys := make([]string, 0, len(workflow.Payload))
+	for key := range workflow.Payload {
+		if key != "output_file" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var input strings.Builder
+	fmt.Fprintf(&input, "Write a %s document.\n", workflow.Type)
+	for _, key := range keys {
+		fmt.Fprintf(&input, "%s: %s\n", key, workflow.Payload[key])
+	}
+	return strings.TrimSpace(input.String())
+}
+
+// DocumentStore is the subset of the RAG service training samples are written through
+type DocumentStore interface {
+	AddDocument(ctx context.Context, collection string, doc types.RAGDocument) error
+}
+
+// KnowledgeTrainingSink stores training samples as knowledge base documents
+// carrying the input, output and score fields the training exporter reads
+type KnowledgeTrainingSink struct {
+	store      DocumentStore
+	collection string
+}
+
+// NewKnowledgeTrainingSink creates a sink writing to collection; an empty
+// collection uses DefaultTrainingCollection
+func NewKnowledgeTrainingSink(store DocumentStore, collection string) *KnowledgeTrainingSink {
+	if collection == "" {
+		collection = DefaultTrainingCollection
+	}
+	return &KnowledgeTrainingSink{store: store, collection: collection}
+}
+
+// RecordSample stores the sample with its input, output and score as metadata
+func (s *KnowledgeTrainingSink) RecordSample(ctx context.Context, sample TrainingSample) error {
+	doc := types.RAGDocument{
+		Content: fmt.Sprintf("%s\n\n%s", sample.Input, sample.Output),
+		Source:  fmt.Sprintf("workflow/%s", sample.WorkflowID),
+		Metadata: map[string]string{
+			"type":        "training_sample",
+			"workflow_id": sample.WorkflowID,
+			"doc_type":    sample.Type,
+			"stage":       string(sample.Stage),
+			"input":       sample.Input,
+			"output":      sample.Output,
+			"feedback":    sample.Feedback,
+			"approved":    strconv.FormatBool(sample.Approved),
+			"score":       strconv.FormatFloat(sample.Score(), 'f', 1, 64),
+			"captured_at": sample.CapturedAt.UTC().Format(time.RFC3339),
+		},
+	}
+
+	if err := s.store.AddDocument(ctx, s.collection, doc); err != nil {
+		return fmt.Errorf("failed to store training sample in %s: %w", s.collection, err)
+	}
+	return nil
+}
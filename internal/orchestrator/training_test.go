@@ -0,0 +1,41 @@
+package orchestrator
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTrainingSamplerRate(t *testing.T) {
+	tests := []struct {
+		rate float64
+		want float64
+	}{
+		{0, 0},
+		{0.1, 0.1},
+		{0.5, 0.5},
+		{1, 1},
+		{2, 1}, // Clamped
+	}
+
+	const draws = 20000
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.rate), func(t *testing.T) {
+			sampler := NewTrainingSampler(tt.rate, true)
+			kept := 0
+			for range draws {
+				if sampler.Sample(true) {
+					kept++
+				}
+			}
+			if got := float64(kept) / draws; got < tt.want-0.02 || got > tt.want+0.02 {
+				t.Errorf("kept %.3f of approved results, want about %.2f", got, tt.want)
+			}
+		})
+	}
+
+	for _, keep := range []bool{true, false} {
+		if got := NewTrainingSampler(0, keep).Sample(false); got != keep {
+			t.Errorf("keepRejections %v: Sample(rejected) = %v", keep, got)
+		}
+	}
+}
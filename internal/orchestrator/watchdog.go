@@ -0,0 +1,106 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// runWatchdog periodically re-dispatches stages whose worker went silent and
+// evicts finished workflows past their retention
+func (o *Orchestrator) runWatchdog(ctx context.Context) {
+	ticker := time.NewTicker(o.config.WatchdogInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			o.checkQuorumTimeouts(ctx)
+			o.checkStalledStages(ctx)
+			o.evictFinished()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkStalledStages re-dispatches every stage that has waited longer than
+// StageTimeout for a result. The original task stays pending, so whichever
+// result arrives first advances the workflow and later ones are dropped.
+func (o *Orchestrator) checkStalledStages(ctx context.Context) {
+	if o.config.StageTimeout <= 0 {
+		return
+	}
+
+	for _, workflow := range o.trackedWorkflows() {
+		workflow.mu.Lock()
+		o.checkStalledStage(ctx, workflow)
+		workflow.mu.Unlock()
+	}
+}
+
+// checkStalledStage re-dispatches or fails the workflow if its stage has
+// stalled. Callers must hold workflow.mu.
+func (o *Orchestrator) checkStalledStage(ctx context.Context, workflow *Workflow) {
+	now := o.now()
+	if workflow.evicted || workflow.Queued || workflow.Stage.IsTerminal() || now.Sub(workflow.DispatchedAt) < o.config.StageTimeout {
+		return
+	}
+
+	if workflow.Redispatches >= o.config.MaxRedispatches {
+		reason := fmt.Sprintf("%s stage stalled: no result after %d re-dispatches", workflow.Stage, workflow.Redispatches)
+		if err := o.fail(ctx, workflow, reason); err != nil {
+			log.Printf("Watchdog: %v", err)
+		}
+		return
+	}
+
+	workflow.Redispatches++
+	log.Printf("Watchdog: workflow %s %s stage has no result after %v, re-dispatching (%d/%d)",
+		workflow.ID, workflow.Stage, now.Sub(workflow.DispatchedAt).Round(time.Second),
+		workflow.Redispatches, o.config.MaxRedispatches)
+
+	if err := o.publishStageTask(ctx, workflow, workflow.Stage); err != nil {
+		log.Printf("Watchdog: failed to re-dispatch workflow %s: %v", workflow.ID, err)
+	}
+}
+
+// checkQuorumTimeouts decides fan-out stages whose quorum did not respond in
+// time. With at least one response the stage advances on those; if every
+// response failed the stage is retried; with none at all it is left to the
+// stall watchdog to re-dispatch.
+func (o *Orchestrator) checkQuorumTimeouts(ctx context.Context) {
+	for _, workflow := range o.trackedWorkflows() {
+		workflow.mu.Lock()
+		o.checkQuorumTimeout(ctx, workflow)
+		workflow.mu.Unlock()
+	}
+}
+
+// checkQuorumTimeout decides the workflow's fan-out stage if its quorum
+// timed out. Callers must hold workflow.mu.
+func (o *Orchestrator) checkQuorumTimeout(ctx context.Context, workflow *Workflow) {
+	aggregator := workflow.aggregator
+	if workflow.evicted || workflow.Stage.IsTerminal() || aggregator == nil || !aggregator.Expired(o.now(), o.config.QuorumTimeout) {
+		return
+	}
+
+	var err error
+	switch {
+	case aggregator.Responses() > 0:
+		log.Printf("Watchdog: workflow %s %s stage quorum not reached (%d/%d responses after %v), deciding with responses so far",
+			workflow.ID, workflow.Stage, aggregator.Responses(), o.config.ReviewQuorum, o.config.QuorumTimeout)
+		err = o.advance(ctx, workflow, aggregator.Result())
+	case aggregator.Failures() > 0:
+		reason := fmt.Sprintf("%s stage quorum not reached: all %d responses failed", workflow.Stage, aggregator.Failures())
+		log.Printf("Watchdog: workflow %s %s", workflow.ID, reason)
+		err = o.retry(ctx, workflow, workflow.Stage, reason)
+	default:
+		return
+	}
+
+	if err != nil {
+		log.Printf("Watchdog: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// newWatchdogOrchestrator starts a workflow and returns its first stage task
+func newWatchdogOrchestrator(t *testing.T) (*Orchestrator, *taskClient, *fakeClock, string, types.WorkflowTask) {
+	t.Helper()
+	config := DefaultConfig()
+	config.WorkflowTimeout = 0
+	config.StageTimeout = 10 * time.Minute
+	config.MaxRedispatches = 2
+	o, client, clock := newTestOrchestrator(config)
+
+	id, err := o.StartWorkflow(context.Background(), WorkflowRequest{Type: "api_guide"})
+	if err != nil {
+		t.Fatalf("StartWorkflow: %v", err)
+	}
+	return o, client, clock, id, <-client.tasks
+}
+
+func TestCheckStalledStages(t *testing.T) {
+	tests := []struct {
+		name      string
+		checks    []time.Duration // Clock advance before each watchdog pass
+		wantIDs   []string        // Suffixes of the re-dispatched task IDs
+		wantStage types.WorkflowStage
+	}{
+		{"result still due", []time.Duration{5 * time.Minute}, nil, types.StageDevelopment},
+		{"stalled", []time.Duration{11 * time.Minute}, []string{"-r1"}, types.StageDevelopment},
+		{"stalled again", []time.Duration{11 * time.Minute, 5 * time.Minute, 6 * time.Minute}, []string{"-r1", "-r2"}, types.StageDevelopment},
+		{"re-dispatches exhausted", []time.Duration{11 * time.Minute, 11 * time.Minute, 11 * time.Minute}, []string{"-r1", "-r2"}, types.StageFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, client, clock, id, task := newWatchdogOrchestrator(t)
+			for _, elapsed := range tt.checks {
+				clock.Advance(elapsed)
+				o.checkStalledStages(context.Background())
+			}
+
+			var ids []string
+			for len(client.tasks) > 0 {
+				redispatched := <-client.tasks
+				if redispatched.Stage != task.Stage {
+					t.Errorf("re-dispatched stage %s, want %s", redispatched.Stage, task.Stage)
+				}
+				ids = append(ids, strings.TrimPrefix(redispatched.ID, task.ID))
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("re-dispatched %v, want %v", ids, tt.wantIDs)
+			}
+
+			workflow, _ := o.GetWorkflow(id)
+			if workflow.Stage != tt.wantStage {
+				t.Errorf("stage = %s, want %s", workflow.Stage, tt.wantStage)
+			}
+			if tt.wantStage == types.StageFailed && !strings.Contains(workflow.Error, "stalled") {
+				t.Errorf("Error = %q, want a stalled stage failure", workflow.Error)
+			}
+		})
+	}
+}
+
+func TestStalledStageLateResultIsDeduplicated(t *testing.T) {
+	o, client, clock, id, original := newWatchdogOrchestrator(t)
+	ctx := context.Background()
+
+	// The result is lost, so the watchdog hands the stage to another worker
+	clock.Advance(11 * time.Minute)
+	o.checkStalledStages(ctx)
+	redispatched := <-client.tasks
+
+	// The original worker recovers first; its result advances the workflow
+	if err := o.HandleResult(ctx, passingResult(original, false)); err != nil {
+		t.Fatalf("HandleResult(original): %v", err)
+	}
+	if err := o.HandleResult(ctx, passingResult(redispatched, false)); err == nil {
+		t.Error("HandleResult accepted the re-dispatched duplicate")
+	}
+
+	workflow, _ := o.GetWorkflow(id)
+	if workflow.Stage != types.StageReview || workflow.RetryCount != 0 {
+		t.Errorf("workflow at %s after %d retries, want review after none", workflow.Stage, workflow.RetryCount)
+	}
+	if len(client.tasks) != 1 {
+		t.Errorf("published %d review tasks, want 1", len(client.tasks))
+	}
+	if review := <-client.tasks; review.Stage != types.StageReview {
+		t.Errorf("next task is for %s, want review", review.Stage)
+	}
+}
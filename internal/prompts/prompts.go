@@ -0,0 +1,253 @@
+package prompts
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Template names for each workflow phase
+const (
+	Create            = "create"
+	Review            = "review"
+	Approve           = "approve"
+	GoCodingStandards = "go_coding_standards"
+	Documents         = "documents" // Renders retrieved documents with citation labels into RAGContext
+)
+
+// ReviewItemsFormat asks a reviewer to list the issues it finds as
+// structured review items
+const ReviewItemsFormat = `If the document needs changes, respond with REJECTED: [summary], then list
+every issue in a json code block as an array of objects with the fields
+"severity" (critical, major or minor), "location" (section, line or example),
+"message" and "suggestion". Otherwise respond with APPROVED: [reason].`
+
+// Context holds the values a prompt template can interpolate
+type Context struct {
+	SystemPrompt   string // Role definition from the knowledge base
+	DocumentType   string
+	PreviousOutput string // Document produced by the previous stage
+	ReviewFeedback string
+	ReviewItems    string // Numbered review items from the last review, most severe first
+	RAGContext     string
+	CodeBlocks     string // Numbered index of PreviousOutput's code blocks
+	Documents      []Document
+}
+
+// Document is a retrieved knowledge base document with the label the model
+// cites it by
+type Document struct {
+	Label   string // Citation marker, e.g. "[1]"
+	Source  string
+	Content string
+	Score   float64
+}
+
+// NewDocuments labels retrieved documents [1], [2], ... in retrieval order
+func NewDocuments(docs []types.RAGDocument) []Document {
+	documents := make([]Document, len(docs))
+	for i, doc := range docs {
+		source := doc.Source
+		if source == "" {
+			source = "unknown"
+		}
+		documents[i] = Document{
+			Label:   fmt.Sprintf("[%d]", i+1),
+			Source:  source,
+			Content: strings.TrimSpace(doc.Content),
+			Score:   doc.Score,
+		}
+	}
+	return documents
+}
+
+// defaultSources are the built-in templates. "preamble" is shared by the
+// phase templates so every phase opens with the same role and context.
+var defaultSources = map[string]string{
+	"preamble": `{{if .SystemPrompt}}{{.SystemPrompt}}
+
+{{end}}{{if .RAGContext}}Relevant Context:
+{{.RAGContext}}
+
+{{end}}`,
+
+	Documents: `Cite sources by their label, e.g. [1], when you use them.
+{{range .Documents}}
+{{.Label}} Source: {{.Source}}
+{{.Content}}
+{{end}}`,
+
+	Create: `{{template "preamble" .}}Create a comprehensive {{.DocumentType}} document.{{if .ReviewItems}}
+
+Address each review item:
+{{.ReviewItems}}{{else if .ReviewFeedback}}
+
+Address this review feedback:
+{{.ReviewFeedback}}{{end}}`,
+
+	Review: `{{template "preamble" .}}Review and improve this {{.DocumentType}} document.
+
+Previous version:
+{{.PreviousOutput}}{{.CodeBlocks}}
+
+` + ReviewItemsFormat,
+
+	Approve: `{{template "preamble" .}}Perform final approval for this {{.DocumentType}} document.
+
+Content to approve:
+{{.PreviousOutput}}
+
+Respond with APPROVED: [reason] or REJECTED: [issues]`,
+
+	GoCodingStandards: `Create comprehensive Go coding standards document. Include:
+
+1. Core Principles (explicit over implicit, composition over inheritance)
+2. Package Management (naming, organization, imports)
+3. Variable/Constant Declaration (naming, scoping, zero values)
+4. Function/Method Design (naming, parameters, returns, receivers)
+5. Error Handling (explicit handling, wrapping, checking)
+6. Struct/Interface Design (composition, embedding, small interfaces)
+7. Concurrency Patterns (goroutines, channels, context)
+8. Testing Standards (table-driven, mocking, benchmarks)
+9. Code Organization (directory structure, files)
+10. Performance Guidelines (allocation, strings, profiling)
+11. Documentation (godoc, comments, examples)
+12. Security (validation, secrets)
+13. Compliance Checklist
+
+Use this context: {{.RAGContext}}
+
+Format as markdown with clear good/bad examples. Make it comprehensive but practical.`,
+}
+
+// Set is a collection of named prompt templates that may reference each other
+type Set struct {
+	templates  *template.Template
+	compliance Compliance
+}
+
+// Compliance is boilerplate placed before and after every assembled prompt
+type Compliance struct {
+	Prefix string `yaml:"prefix"`
+	Suffix string `yaml:"suffix"`
+}
+
+// fileFormat is the layout of a prompt template file
+type fileFormat struct {
+	Prompts    map[string]string `yaml:"prompts"`
+	Compliance Compliance        `yaml:"compliance"`
+}
+
+// Default returns the built-in templates
+func Default() *Set {
+	set, err := Parse(defaultSources)
+	if err != nil {
+		panic(fmt.Sprintf("built-in prompt templates are invalid: %v", err))
+	}
+	return set
+}
+
+// Load reads templates from a YAML file. Templates in the file replace the
+// built-in ones of the same name; built-ins the file omits are kept.
+func Load(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt templates: %w", err)
+	}
+
+	var file fileFormat
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse prompt templates: %w", err)
+	}
+
+	sources := maps.Clone(defaultSources)
+	maps.Copy(sources, file.Prompts)
+
+	set, err := Parse(sources)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt templates in %s: %w", path, err)
+	}
+	set.SetCompliance(file.Compliance)
+	return set, nil
+}
+
+// Parse builds a set from template sources keyed by name
+func Parse(sources map[string]string) (*Set, error) {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	root := template.New("prompts").Option("missingkey=error")
+	for _, name := range names {
+		if _, err := root.New(name).Parse(sources[name]); err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+	}
+	return &Set{templates: root}, nil
+}
+
+// SetCompliance sets the boilerplate Wrap adds around prompts
+func (s *Set) SetCompliance(compliance Compliance) {
+	s.compliance = Compliance{
+		Prefix: strings.TrimSpace(compliance.Prefix),
+		Suffix: strings.TrimSpace(compliance.Suffix),
+	}
+}
+
+// Compliance returns the boilerplate Wrap adds around prompts
+func (s *Set) Compliance() Compliance {
+	return s.compliance
+}
+
+// Wrap places the compliance prefix and suffix around prompt
+func (s *Set) Wrap(prompt string) string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{s.compliance.Prefix, prompt, s.compliance.Suffix} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Has reports whether the set defines a template
+func (s *Set) Has(name string) bool {
+	return s.templates.Lookup(name) != nil
+}
+
+// RenderDocuments renders retrieved documents with their citation labels
+// for use as RAGContext; no documents render as an empty string
+func (s *Set) RenderDocuments(documents []Document) (string, error) {
+	if len(documents) == 0 {
+		return "", nil
+	}
+	rendered, err := s.Render(Documents, Context{Documents: documents})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(rendered), nil
+}
+
+// Render executes the named template with ctx. Compliance boilerplate is not
+// added; callers Wrap the final prompt once it is assembled.
+func (s *Set) Render(name string, ctx Context) (string, error) {
+	tmpl := s.templates.Lookup(name)
+	if tmpl == nil {
+		return "", fmt.Errorf("prompt template %s not defined", name)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, ctx); err != nil {
+		return "", fmt.Errorf("failed to render prompt template %s: %w", name, err)
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,265 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// sampleContext is the context a review task renders with
+func sampleContext() Context {
+	return Context{
+		SystemPrompt:   "You are a reviewer.",
+		DocumentType:   "design",
+		PreviousOutput: "# Draft",
+		RAGContext:     "Use table-driven tests.",
+	}
+}
+
+func TestRender(t *testing.T) {
+	set := Default()
+
+	tests := []struct {
+		name     string
+		template string
+		ctx      Context
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "review interpolates every placeholder",
+			template: Review,
+			ctx:      sampleContext(),
+			contains: []string{
+				"You are a reviewer.",
+				"Relevant Context:\nUse table-driven tests.",
+				"Review and improve this design document.",
+				"Previous version:\n# Draft",
+				ReviewItemsFormat,
+			},
+		},
+		{
+			name:     "preamble omits empty sections",
+			template: Create,
+			ctx:      Context{DocumentType: "api"},
+			contains: []string{"Create a comprehensive api document."},
+			excludes: []string{"Relevant Context", "review feedback"},
+		},
+		{
+			name:     "create addresses feedback",
+			template: Create,
+			ctx:      Context{DocumentType: "api", ReviewFeedback: "add examples"},
+			contains: []string{"Address this review feedback:\nadd examples"},
+		},
+		{
+			name:     "review items take precedence over feedback",
+			template: Create,
+			ctx:      Context{DocumentType: "api", ReviewFeedback: "add examples", ReviewItems: "1. [major] intro: too short"},
+			contains: []string{"Address each review item:\n1. [major] intro: too short"},
+			excludes: []string{"add examples"},
+		},
+		{
+			name:     "approve",
+			template: Approve,
+			ctx:      sampleContext(),
+			contains: []string{"Content to approve:\n# Draft", "APPROVED: [reason]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := set.Render(tt.template, tt.ctx)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("Render() missing %q in:\n%s", want, got)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("Render() contains %q in:\n%s", unwanted, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderUnknownTemplate(t *testing.T) {
+	if _, err := Default().Render("missing", Context{}); err == nil {
+		t.Fatal("Render() error = nil, want error for an undefined template")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources map[string]string
+		wantErr bool
+	}{
+		{name: "valid", sources: map[string]string{"greet": "Hello {{.DocumentType}}"}},
+		{name: "syntax error", sources: map[string]string{"greet": "Hello {{.DocumentType"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.sources)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prompts.yaml")
+	file := `prompts:
+  create: "Write the {{.DocumentType}} document."
+`
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	got, err := set.Render(Create, sampleContext())
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if got != "Write the design document." {
+		t.Errorf("Render(create) = %q, want the template from the file", got)
+	}
+
+	// Built-ins the file omits are kept
+	if !set.Has(Review) {
+		t.Error("Load() dropped the built-in review template")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("prompts:\n  create: \"{{.Unclosed\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{name: "missing file", path: filepath.Join(dir, "missing.yaml")},
+		{name: "invalid template", path: invalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.path); err == nil {
+				t.Error("Load() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestLoadRepoTemplates(t *testing.T) {
+	set, err := Load(filepath.Join("..", "..", "configs", "prompts.yaml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, name := range []string{Create, Review, Approve} {
+		if _, err := set.Render(name, sampleContext()); err != nil {
+			t.Errorf("Render(%s) error = %v", name, err)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name       string
+		compliance Compliance
+		want       string
+	}{
+		{"no boilerplate", Compliance{}, "Write the document."},
+		{"prefix and suffix", Compliance{Prefix: " Never output secrets. ", Suffix: "Cite sources.\n"}, "Never output secrets.\n\nWrite the document.\n\nCite sources."},
+		{"prefix only", Compliance{Prefix: "Never output secrets."}, "Never output secrets.\n\nWrite the document."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := Default()
+			set.SetCompliance(tt.compliance)
+			if got := set.Wrap("Write the document."); got != tt.want {
+				t.Errorf("Wrap() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCompliance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompts.yaml")
+	file := `compliance:
+  prefix: "Never output secrets."
+  suffix: "Cite sources."
+`
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := Compliance{Prefix: "Never output secrets.", Suffix: "Cite sources."}
+	if got := set.Compliance(); got != want {
+		t.Errorf("Compliance() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNewDocuments(t *testing.T) {
+	documents := NewDocuments([]types.RAGDocument{
+		{Content: " wrap errors \n", Source: "errors.md", Score: 0.9},
+		{Content: "use table tests"},
+	})
+
+	want := []Document{
+		{Label: "[1]", Source: "errors.md", Content: "wrap errors", Score: 0.9},
+		{Label: "[2]", Source: "unknown", Content: "use table tests"},
+	}
+	if !reflect.DeepEqual(documents, want) {
+		t.Errorf("NewDocuments() = %+v, want %+v", documents, want)
+	}
+}
+
+func TestRenderDocuments(t *testing.T) {
+	set := Default()
+
+	empty, err := set.RenderDocuments(nil)
+	if err != nil || empty != "" {
+		t.Errorf("RenderDocuments(nil) = %q, %v; want empty", empty, err)
+	}
+
+	rendered, err := set.RenderDocuments(NewDocuments([]types.RAGDocument{
+		{Content: "wrap errors with context", Source: "errors.md"},
+		{Content: "use table tests", Source: "testing.md"},
+	}))
+	if err != nil {
+		t.Fatalf("RenderDocuments() error = %v", err)
+	}
+	for _, want := range []string{
+		"Cite sources by their label",
+		"[1] Source: errors.md\nwrap errors with context",
+		"[2] Source: testing.md\nuse table tests",
+	} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("RenderDocuments() missing %q in:\n%s", want, rendered)
+		}
+	}
+}
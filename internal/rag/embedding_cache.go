@@ -0,0 +1,98 @@
+package rag
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"strings"
+	"sync"
+)
+
+// DefaultEmbeddingCacheSize is the number of vectors kept when no size is configured
+const DefaultEmbeddingCacheSize = 256
+
+// embeddingEntry is a cached vector stored in the LRU list
+type embeddingEntry struct {
+	key    [sha256.Size]byte
+	vector []float32
+}
+
+// EmbeddingCache is an LRU cache of embedding vectors keyed by a hash of the
+// normalized input text, so repeated prompts and queries are embedded once
+type EmbeddingCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[[sha256.Size]byte]*list.Element
+	order    *list.List // Front is most recently used
+	hits     uint64
+	misses   uint64
+}
+
+// NewEmbeddingCache creates a cache holding at most capacity vectors.
+// A non-positive capacity disables caching.
+func NewEmbeddingCache(capacity int) *EmbeddingCache {
+	return &EmbeddingCache{
+		capacity: capacity,
+		entries:  make(map[[sha256.Size]byte]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the cached vector for text
+func (c *EmbeddingCache) Get(text string) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.entries[embeddingKey(text)]
+	if !exists {
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*embeddingEntry).vector, true
+}
+
+// Put stores the vector for text, evicting the least recently used entry when full
+func (c *EmbeddingCache) Put(text string, vector []float32) {
+	if c.capacity <= 0 || vector == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := embeddingKey(text)
+	if elem, exists := c.entries[key]; exists {
+		elem.Value.(*embeddingEntry).vector = vector
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&embeddingEntry{key: key, vector: vector})
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*embeddingEntry).key)
+	}
+}
+
+// Stats returns cache hit and miss counts
+func (c *EmbeddingCache) Stats() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Len returns the number of cached vectors
+func (c *EmbeddingCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// embeddingKey hashes text after trimming and collapsing whitespace
+func embeddingKey(text string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(strings.Join(strings.Fields(text), " ")))
+}
@@ -0,0 +1,109 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEmbeddingCacheGet(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		lookup   string
+		wantHit  bool
+	}{
+		{"hit", 4, "wrap errors", true},
+		{"whitespace normalised", 4, "  wrap\n\terrors ", true},
+		{"other text", 4, "wrap panics", false},
+		{"case sensitive", 4, "Wrap errors", false},
+		{"disabled", 0, "wrap errors", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewEmbeddingCache(tt.capacity)
+			cache.Put("wrap errors", []float32{0.5, 0.25})
+
+			vector, hit := cache.Get(tt.lookup)
+			if hit != tt.wantHit {
+				t.Fatalf("Get hit = %v, want %v", hit, tt.wantHit)
+			}
+			if hit && (len(vector) != 2 || vector[0] != 0.5) {
+				t.Errorf("Get = %v, want the stored vector", vector)
+			}
+			hits, misses := cache.Stats()
+			if tt.wantHit && (hits != 1 || misses != 0) || !tt.wantHit && (hits != 0 || misses != 1) {
+				t.Errorf("Stats = %d hits, %d misses", hits, misses)
+			}
+		})
+	}
+}
+
+func TestEmbeddingCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewEmbeddingCache(2)
+	cache.Put("a", []float32{1})
+	cache.Put("b", []float32{2})
+	cache.Get("a") // b is now the least recently used
+	cache.Put("c", []float32{3})
+
+	if cache.Len() != 2 {
+		t.Errorf("Len = %d, want 2", cache.Len())
+	}
+	for text, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, hit := cache.Get(text); hit != want {
+			t.Errorf("Get(%q) hit = %v, want %v", text, hit, want)
+		}
+	}
+}
+
+func TestEmbedServesRepeatsFromCache(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	service := &Service{
+		embeddings:       NewEmbeddingCache(10),
+		embeddingBinary:  fakeEmbeddingBinary(t, `echo x >> `+calls+`; echo '{"data":[{"embedding":[0.1,0.2,0.3]}]}'`),
+		embeddingTimeout: time.Minute,
+	}
+
+	for _, text := range []string{"role prompt", "role prompt", " role  prompt "} {
+		if vector := service.embed(context.Background(), text); len(vector) != 3 {
+			t.Fatalf("embed(%q) returned %d dimensions, want 3", text, len(vector))
+		}
+	}
+
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatalf("read call log: %v", err)
+	}
+	if n := strings.Count(string(data), "x"); n != 1 {
+		t.Errorf("embedder ran %d times, want 1", n)
+	}
+}
+
+func TestEmbeddingCacheConcurrent(t *testing.T) {
+	cache := NewEmbeddingCache(16)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 200 {
+				text := fmt.Sprintf("text %d", (i+j)%32)
+				if _, hit := cache.Get(text); !hit {
+					cache.Put(text, []float32{float32(j)})
+				}
+				cache.Stats()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if cache.Len() > 16 {
+		t.Errorf("Len = %d, want at most the capacity of 16", cache.Len())
+	}
+}
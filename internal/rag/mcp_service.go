@@ -22,6 +22,9 @@ type MCPServiceConfig struct {
 	Timeout    time.Duration `json:"timeout"`
 	MaxRetries int           `json:"max_retries"`
 	RetryDelay time.Duration `json:"retry_delay"`
+
+	// DefaultCollection is searched when a call names no collection
+	DefaultCollection string `json:"default_collection"`
 }
 
 // NewMCPService creates a new MCP-enhanced RAG service
@@ -31,6 +34,8 @@ func NewMCPService(config *MCPServiceConfig) *MCPService {
 		Timeout:    config.Timeout,
 		MaxRetries: config.MaxRetries,
 		RetryDelay: config.RetryDelay,
+
+		DefaultCollection: config.DefaultCollection,
 	}
 
 	// Create a mock RAG service for the MCP client
@@ -0,0 +1,187 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// MemoryService provides an in-memory knowledge base for tests and offline mode.
+// Documents are scored by keyword overlap with the query instead of embeddings.
+type MemoryService struct {
+	mu        sync.RWMutex
+	documents map[string][]types.RAGDocument // collection name -> documents
+	prompts   map[types.WorkerRole]string
+	retrieval *config.RetrievalConfig
+}
+
+// NewMemoryService creates an empty in-memory knowledge base
+func NewMemoryService() *MemoryService {
+	return &MemoryService{
+		documents: make(map[string][]types.RAGDocument),
+		prompts:   make(map[types.WorkerRole]string),
+		retrieval: config.DefaultRetrievalConfig(),
+	}
+}
+
+// SetRetrievalConfig overrides the per-task-type TopK/Threshold used by GetRelevantContext
+func (s *MemoryService) SetRetrievalConfig(retrieval *config.RetrievalConfig) {
+	s.retrieval = retrieval
+}
+
+// AddDocument stores a document in the given collection
+func (s *MemoryService) AddDocument(ctx context.Context, collection string, doc types.RAGDocument) error {
+	if collection == "" {
+		return fmt.Errorf("collection is required")
+	}
+	if doc.Content == "" {
+		return fmt.Errorf("document content is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if doc.Metadata == nil {
+		doc.Metadata = make(map[string]string)
+	}
+	s.documents[collection] = append(s.documents[collection], doc)
+	return nil
+}
+
+// StoreSystemPrompt stores a system prompt for a worker role
+func (s *MemoryService) StoreSystemPrompt(ctx context.Context, role types.WorkerRole, prompt string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prompts[role] = prompt
+	return nil
+}
+
+// GetSystemPrompt retrieves the system prompt for a worker role
+func (s *MemoryService) GetSystemPrompt(ctx context.Context, role types.WorkerRole) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prompt, exists := s.prompts[role]
+	if !exists {
+		return "", fmt.Errorf("no system prompt found for role %s in memory store", role)
+	}
+	return prompt, nil
+}
+
+// SearchKnowledge scores documents in the query collection by keyword overlap
+func (s *MemoryService) SearchKnowledge(ctx context.Context, query types.RAGQuery) (*types.RAGResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("search cancelled: %w", err)
+	}
+
+	collection, err := TenantCollection(query.TenantID, query.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	terms := strings.Fields(strings.ToLower(query.Query))
+
+	var matches []types.RAGDocument
+	for _, doc := range s.documents[collection] {
+		score := keywordScore(terms, doc.Content)
+		if score <= 0 || score < query.Threshold {
+			continue
+		}
+		doc.Score = score
+		matches = append(matches, doc)
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+
+	if query.TopK > 0 && len(matches) > query.TopK {
+		matches = matches[:query.TopK]
+	}
+
+	return &types.RAGResponse{
+		Documents: matches,
+		Query:     query.Query,
+		TotalHits: len(matches),
+	}, nil
+}
+
+// GetRelevantContext gets context for a specific task type
+func (s *MemoryService) GetRelevantContext(ctx context.Context, taskType, content string) (string, error) {
+	documents, err := s.GetRelevantDocuments(ctx, taskType, content)
+	if err != nil {
+		return "", err
+	}
+
+	return formatContext(documents), nil
+}
+
+// GetRelevantDocuments returns the documents retrieved for a task type with
+// their sources, so prompts can label them for citation
+func (s *MemoryService) GetRelevantDocuments(ctx context.Context, taskType, content string) ([]types.RAGDocument, error) {
+	settings := s.retrieval.ForTaskType(taskType)
+	query := types.RAGQuery{
+		Query:      fmt.Sprintf("%s %s", taskType, content),
+		Collection: "coding_standards",
+		TopK:       settings.TopK,
+		Threshold:  settings.Threshold,
+	}
+
+	response, err := s.SearchKnowledge(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Documents, nil
+}
+
+// CollectionStats reports document counts for each in-memory collection
+func (s *MemoryService) CollectionStats(ctx context.Context) ([]CollectionStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := make([]CollectionStats, 0, len(s.documents))
+	for name, docs := range s.documents {
+		stats = append(stats, CollectionStats{
+			Name:   name,
+			Points: uint64(len(docs)),
+			Status: "green",
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats, nil
+}
+
+// IsAvailable always reports true - the in-memory store has no backend to lose
+func (s *MemoryService) IsAvailable(ctx context.Context) bool {
+	return true
+}
+
+// keywordScore returns the fraction of query terms present in content
+func keywordScore(terms []string, content string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+
+	content = strings.ToLower(content)
+	matched := 0
+	for _, term := range terms {
+		if strings.Contains(content, term) {
+			matched++
+		}
+	}
+
+	return float64(matched) / float64(len(terms))
+}
@@ -0,0 +1,186 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// newMemoryStore creates a memory store holding documents in coding_standards
+func newMemoryStore(t *testing.T, documents ...string) *MemoryService {
+	t.Helper()
+	store := NewMemoryService()
+	for _, content := range documents {
+		if err := store.AddDocument(context.Background(), "coding_standards", types.RAGDocument{Content: content}); err != nil {
+			t.Fatalf("AddDocument: %v", err)
+		}
+	}
+	return store
+}
+
+func TestMemorySearchKnowledge(t *testing.T) {
+	store := newMemoryStore(t, "wrap errors with context", "errors are values", "use table tests")
+
+	tests := []struct {
+		name  string
+		query types.RAGQuery
+		want  []string
+	}{
+		{"ranked by overlap", types.RAGQuery{Query: "wrap errors", Collection: "coding_standards"}, []string{"wrap errors with context", "errors are values"}},
+		{"case insensitive", types.RAGQuery{Query: "TABLE", Collection: "coding_standards"}, []string{"use table tests"}},
+		{"top k", types.RAGQuery{Query: "wrap errors", Collection: "coding_standards", TopK: 1}, []string{"wrap errors with context"}},
+		{"threshold", types.RAGQuery{Query: "wrap errors", Collection: "coding_standards", Threshold: 0.75}, []string{"wrap errors with context"}},
+		{"no match", types.RAGQuery{Query: "goroutines", Collection: "coding_standards"}, nil},
+		{"other collection", types.RAGQuery{Query: "errors", Collection: "documentation"}, nil},
+		{"other tenant", types.RAGQuery{Query: "errors", Collection: "coding_standards", TenantID: "acme"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := store.SearchKnowledge(context.Background(), tt.query)
+			if err != nil {
+				t.Fatalf("SearchKnowledge: %v", err)
+			}
+			if response.TotalHits != len(tt.want) || len(response.Documents) != len(tt.want) {
+				t.Fatalf("got %d documents, want %d", len(response.Documents), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if response.Documents[i].Content != want {
+					t.Errorf("document %d = %q, want %q", i, response.Documents[i].Content, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryRetrievalPerTaskType(t *testing.T) {
+	// Each document matches "errors" but not the task type: a score of 0.5
+	store := newMemoryStore(t, "wrap errors", "errors are values", "name errors")
+	retrieval := config.DefaultRetrievalConfig()
+	retrieval.TaskTypes["review"] = config.RetrievalSettings{TopK: 1, Threshold: 0.5}
+	retrieval.TaskTypes["audit"] = config.RetrievalSettings{TopK: 3, Threshold: 0.75}
+	store.SetRetrievalConfig(retrieval)
+
+	tests := []struct {
+		taskType string
+		want     int
+	}{
+		{"review", 1},
+		{"audit", 0},
+		{"create_document", 3}, // The default settings
+	}
+
+	for _, tt := range tests {
+		documents, err := store.GetRelevantDocuments(context.Background(), tt.taskType, "errors")
+		if err != nil {
+			t.Fatalf("GetRelevantDocuments(%s): %v", tt.taskType, err)
+		}
+		if len(documents) != tt.want {
+			t.Errorf("%s: got %d documents, want %d", tt.taskType, len(documents), tt.want)
+		}
+	}
+}
+
+func TestMemorySearchKnowledgeErrors(t *testing.T) {
+	store := newMemoryStore(t, "anything")
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.SearchKnowledge(cancelled, types.RAGQuery{Query: "anything", Collection: "coding_standards"}); err == nil {
+		t.Error("SearchKnowledge succeeded with a cancelled context")
+	}
+	if _, err := store.SearchKnowledge(context.Background(), types.RAGQuery{Query: "anything", Collection: "coding_standards", TenantID: "../x"}); err == nil {
+		t.Error("SearchKnowledge accepted an invalid tenant")
+	}
+
+	for _, tt := range []struct {
+		collection string
+		doc        types.RAGDocument
+	}{
+		{"", types.RAGDocument{Content: "text"}},
+		{"coding_standards", types.RAGDocument{}},
+	} {
+		if err := store.AddDocument(context.Background(), tt.collection, tt.doc); err == nil {
+			t.Errorf("AddDocument(%q, %+v) succeeded", tt.collection, tt.doc)
+		}
+	}
+}
+
+func TestMemorySystemPrompts(t *testing.T) {
+	store := NewMemoryService()
+	ctx := context.Background()
+
+	if _, err := store.GetSystemPrompt(ctx, types.RoleDeveloper); err == nil {
+		t.Error("GetSystemPrompt succeeded before a prompt was stored")
+	}
+	store.StoreSystemPrompt(ctx, types.RoleDeveloper, "You write Go.")
+	if prompt, err := store.GetSystemPrompt(ctx, types.RoleDeveloper); err != nil || prompt != "You write Go." {
+		t.Errorf("GetSystemPrompt = %q, %v", prompt, err)
+	}
+}
+
+func TestMemoryServiceConcurrent(t *testing.T) {
+	store := NewMemoryService()
+	ctx := context.Background()
+	const writers, perWriter = 8, 50
+
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := range perWriter {
+				store.AddDocument(ctx, "coding_standards", types.RAGDocument{Content: fmt.Sprintf("rule %d-%d", i, j)})
+			}
+			store.StoreSystemPrompt(ctx, types.RoleDeveloper, fmt.Sprintf("prompt %d", i))
+		}()
+		go func() {
+			defer wg.Done()
+			for range perWriter {
+				if _, err := store.GetRelevantContext(ctx, "rule", "rule"); err != nil {
+					t.Errorf("GetRelevantContext: %v", err)
+				}
+				store.CollectionStats(ctx)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats, _ := store.CollectionStats(ctx)
+	if len(stats) != 1 || stats[0].Points != writers*perWriter {
+		t.Errorf("CollectionStats = %+v, want %d points", stats, writers*perWriter)
+	}
+}
+
+func TestMemoryRelevantContextCitesSources(t *testing.T) {
+	store := NewMemoryService()
+	for _, doc := range []types.RAGDocument{
+		{Content: "review wrapped errors", Source: "errors.md"},
+		{Content: "review errors as values"},
+	} {
+		if err := store.AddDocument(context.Background(), "coding_standards", doc); err != nil {
+			t.Fatalf("AddDocument: %v", err)
+		}
+	}
+
+	documents, err := store.GetRelevantDocuments(context.Background(), "review", "errors")
+	if err != nil {
+		t.Fatalf("GetRelevantDocuments: %v", err)
+	}
+	if len(documents) != 2 || documents[0].Source != "errors.md" {
+		t.Fatalf("documents = %+v, want both with the first from errors.md", documents)
+	}
+
+	rendered, err := store.GetRelevantContext(context.Background(), "review", "errors")
+	if err != nil {
+		t.Fatalf("GetRelevantContext: %v", err)
+	}
+	want := "Context 1 (source: errors.md): review wrapped errors\n\nContext 2: review errors as values"
+	if rendered != want {
+		t.Errorf("GetRelevantContext = %q, want %q", rendered, want)
+	}
+}
@@ -0,0 +1,61 @@
+package rag
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// payloadValue converts a Qdrant payload value to its plain Go equivalent:
+// string, int64, float64, bool, nil, []any or map[string]any
+func payloadValue(value *qdrant.Value) any {
+	switch kind := value.GetKind().(type) {
+	case *qdrant.Value_StringValue:
+		return kind.StringValue
+	case *qdrant.Value_IntegerValue:
+		return kind.IntegerValue
+	case *qdrant.Value_DoubleValue:
+		return kind.DoubleValue
+	case *qdrant.Value_BoolValue:
+		return kind.BoolValue
+	case *qdrant.Value_ListValue:
+		values := kind.ListValue.GetValues()
+		list := make([]any, len(values))
+		for i, item := range values {
+			list[i] = payloadValue(item)
+		}
+		return list
+	case *qdrant.Value_StructValue:
+		fields := make(map[string]any, len(kind.StructValue.GetFields()))
+		for key, field := range kind.StructValue.GetFields() {
+			fields[key] = payloadValue(field)
+		}
+		return fields
+	default:
+		return nil
+	}
+}
+
+// payloadString renders a Qdrant payload value as metadata text. Scalars use
+// their plain form, null is empty and lists and structs are encoded as JSON.
+func payloadString(value *qdrant.Value) string {
+	switch v := payloadValue(value).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(encoded)
+	}
+}
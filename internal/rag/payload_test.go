@@ -0,0 +1,86 @@
+package rag
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func TestPayloadString(t *testing.T) {
+	tests := []struct {
+		name  string
+		value *qdrant.Value
+		want  string
+	}{
+		{"string", qdrant.NewValueString("text"), "text"},
+		{"integer", qdrant.NewValueInt(42), "42"},
+		{"double", qdrant.NewValueDouble(0.25), "0.25"},
+		{"bool", qdrant.NewValueBool(true), "true"},
+		{"null", qdrant.NewValueNull(), ""},
+		{"list", qdrant.NewValueList(&qdrant.ListValue{Values: []*qdrant.Value{
+			qdrant.NewValueString("go"), qdrant.NewValueInt(1),
+		}}), `["go",1]`},
+		{"struct", qdrant.NewValueStruct(&qdrant.Struct{Fields: map[string]*qdrant.Value{
+			"page": qdrant.NewValueInt(3),
+		}}), `{"page":3}`},
+		{"unset", &qdrant.Value{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := payloadString(tt.value); got != tt.want {
+				t.Errorf("payloadString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchKnowledgeMixedPayload(t *testing.T) {
+	_, service := newFakeQdrant(t, []map[string]any{
+		{
+			"content":  "Use table-driven tests.",
+			"source":   "standards.md",
+			"page":     int64(7),
+			"score":    0.5,
+			"reviewed": false,
+			"tags":     []any{"go", "testing"},
+			"section":  map[string]any{"title": "Testing"},
+		},
+		{"content": int64(12), "source": true},
+	})
+	service.embeddingBinary = fakeEmbeddingBinary(t, `echo '{"data":[{"embedding":[1,0]}]}'`)
+
+	response, err := service.SearchKnowledge(context.Background(), types.RAGQuery{Query: "tests", Collection: "documentation", TopK: 2})
+	if err != nil {
+		t.Fatalf("SearchKnowledge() error = %v", err)
+	}
+	if len(response.Documents) != 2 {
+		t.Fatalf("got %d documents, want 2", len(response.Documents))
+	}
+
+	full := response.Documents[0]
+	wantMetadata := map[string]string{
+		"content":  "Use table-driven tests.",
+		"source":   "standards.md",
+		"page":     "7",
+		"score":    "0.5",
+		"reviewed": "false",
+		"tags":     `["go","testing"]`,
+		"section":  `{"title":"Testing"}`,
+	}
+	if !reflect.DeepEqual(full.Metadata, wantMetadata) {
+		t.Errorf("Metadata = %v, want %v", full.Metadata, wantMetadata)
+	}
+	if full.Content != "Use table-driven tests." || full.Source != "standards.md" {
+		t.Errorf("document = %q from %q", full.Content, full.Source)
+	}
+
+	// Non-string content and source are coerced rather than dropped
+	coerced := response.Documents[1]
+	if coerced.Content != "12" || coerced.Source != "true" {
+		t.Errorf("coerced document = %q from %q, want \"12\" from \"true\"", coerced.Content, coerced.Source)
+	}
+}
@@ -0,0 +1,82 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// DefaultPromptDriftThreshold is the cosine distance above which a new
+// system prompt is treated as a replacement rather than an edit
+const DefaultPromptDriftThreshold = 0.35
+
+// ErrPromptDrift is returned when a new system prompt differs too much from
+// the stored one to overwrite without forcing
+var ErrPromptDrift = errors.New("system prompt differs significantly from the stored prompt")
+
+// cosineDistance returns 1 - cosine similarity; vectors of different length
+// or zero magnitude are maximally distant
+func cosineDistance(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 1
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 1
+	}
+	return 1 - dot/(math.Sqrt(normA)*math.Sqrt(normB))
+}
+
+// storedPromptVector returns the embedding of the stored prompt for role, or
+// nil when none is stored
+func (s *Service) storedPromptVector(ctx context.Context, role types.WorkerRole) ([]float32, error) {
+	points, err := s.get(ctx, &qdrant.GetPoints{
+		CollectionName: "agent_prompts",
+		Ids:            []*qdrant.PointId{qdrant.NewIDNum(uint64(hashString(string(role))))},
+		WithVectors:    qdrant.NewWithVectors(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored system prompt for role %s: %w", role, err)
+	}
+	if len(points) == 0 {
+		return nil, nil
+	}
+
+	vector := points[0].GetVectors().GetVector()
+	if dense := vector.GetDense(); dense != nil {
+		return dense.GetData(), nil
+	}
+	return vector.GetData(), nil
+}
+
+// checkPromptDrift returns ErrPromptDrift when embedding is further than the
+// drift threshold from the prompt already stored for role
+func (s *Service) checkPromptDrift(ctx context.Context, role types.WorkerRole, embedding []float32) error {
+	if s.promptDriftThreshold <= 0 {
+		return nil
+	}
+
+	stored, err := s.storedPromptVector(ctx, role)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return nil
+	}
+
+	if distance := cosineDistance(stored, embedding); distance > s.promptDriftThreshold {
+		return fmt.Errorf("%w for role %s (cosine distance %.2f > %.2f); use force to overwrite",
+			ErrPromptDrift, role, distance, s.promptDriftThreshold)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+func TestCosineDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float64
+	}{
+		{"identical", []float32{1, 2, 3}, []float32{1, 2, 3}, 0},
+		{"scaled", []float32{1, 2, 3}, []float32{2, 4, 6}, 0},
+		{"orthogonal", []float32{1, 0}, []float32{0, 1}, 1},
+		{"opposite", []float32{1, 0}, []float32{-1, 0}, 2},
+		{"different lengths", []float32{1, 0}, []float32{1, 0, 0}, 1},
+		{"empty", nil, nil, 1},
+		{"zero vector", []float32{0, 0}, []float32{1, 0}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cosineDistance(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("cosineDistance = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Prompts the fake embedding binary maps to fixed vectors
+const (
+	goPrompt      = "You write Go."
+	goPromptEdit  = "You write idiomatic Go."
+	breadPrompt   = "You bake bread."
+	embedByPrompt = `case "$4" in
+"You write Go.") echo '{"data":[{"embedding":[1,0,0]}]}' ;;
+"You write idiomatic Go.") echo '{"data":[{"embedding":[0.95,0.2,0]}]}' ;;
+*) echo '{"data":[{"embedding":[0,0,1]}]}' ;;
+esac`
+)
+
+func TestStoreSystemPromptDrift(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		prompt    string
+		force     bool
+		wantDrift bool
+	}{
+		{"similar update", DefaultPromptDriftThreshold, goPromptEdit, false, false},
+		{"divergent update", DefaultPromptDriftThreshold, breadPrompt, false, true},
+		{"divergent update forced", DefaultPromptDriftThreshold, breadPrompt, true, false},
+		{"check disabled", 0, breadPrompt, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, service := newFakeQdrant(t, nil)
+			service.embeddingBinary = fakeEmbeddingBinary(t, embedByPrompt)
+			service.SetPromptDriftThreshold(tt.threshold)
+			ctx := context.Background()
+
+			// Nothing is stored yet, so the first prompt is never drift
+			if err := service.StoreSystemPrompt(ctx, types.RoleDeveloper, goPrompt); err != nil {
+				t.Fatalf("StoreSystemPrompt(first): %v", err)
+			}
+
+			store := service.StoreSystemPrompt
+			if tt.force {
+				store = service.ReplaceSystemPrompt
+			}
+			err := store(ctx, types.RoleDeveloper, tt.prompt)
+			if errors.Is(err, ErrPromptDrift) != tt.wantDrift {
+				t.Fatalf("err = %v, want drift %v", err, tt.wantDrift)
+			}
+			if !tt.wantDrift && err != nil {
+				t.Fatalf("store: %v", err)
+			}
+
+			wantUpserts := 2
+			if tt.wantDrift {
+				wantUpserts = 1
+			}
+			if fake.upserted != wantUpserts {
+				t.Errorf("upserted %d prompts, want %d", fake.upserted, wantUpserts)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package rag
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc"
+)
+
+// fakeQdrant is an in-memory Qdrant gRPC server holding numbered points of
+// a single collection
+type fakeQdrant struct {
+	qdrant.UnimplementedPointsServer
+
+	mu         sync.Mutex
+	points     []*qdrant.RetrievedPoint
+	scrolls    int
+	scrolled   []string // Collection read by each scroll
+	queries    int
+	queried    []string // Collection searched by each query
+	upserted   int
+	upsertedTo []string             // Collection written by each upsert
+	vectors    map[uint64][]float32 // Upserted vectors by point ID, served by Get
+
+	collections []string // Names the collections server lists
+
+	vectorSize uint64 // Vector size every collection reports; zero reports VectorDimension
+}
+
+// fakeCollections reports every collection as existing, with the vector
+// size configured on its points server, and lists the collections named there
+type fakeCollections struct {
+	qdrant.UnimplementedCollectionsServer
+	points *fakeQdrant
+}
+
+func (fakeCollections) CollectionExists(context.Context, *qdrant.CollectionExistsRequest) (*qdrant.CollectionExistsResponse, error) {
+	return &qdrant.CollectionExistsResponse{Result: &qdrant.CollectionExists{Exists: true}}, nil
+}
+
+func (c fakeCollections) List(context.Context, *qdrant.ListCollectionsRequest) (*qdrant.ListCollectionsResponse, error) {
+	c.points.mu.Lock()
+	defer c.points.mu.Unlock()
+
+	response := &qdrant.ListCollectionsResponse{}
+	for _, name := range c.points.collections {
+		response.Collections = append(response.Collections, &qdrant.CollectionDescription{Name: name})
+	}
+	return response, nil
+}
+
+func (c fakeCollections) Get(context.Context, *qdrant.GetCollectionInfoRequest) (*qdrant.GetCollectionInfoResponse, error) {
+	c.points.mu.Lock()
+	size := c.points.vectorSize
+	c.points.mu.Unlock()
+	if size == 0 {
+		size = VectorDimension
+	}
+
+	return &qdrant.GetCollectionInfoResponse{Result: &qdrant.CollectionInfo{
+		Config: &qdrant.CollectionConfig{Params: &qdrant.CollectionParams{
+			VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{Size: size, Distance: qdrant.Distance_Cosine}),
+		}},
+	}}, nil
+}
+
+func (f *fakeQdrant) Upsert(ctx context.Context, request *qdrant.UpsertPoints) (*qdrant.PointsOperationResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.upserted += len(request.GetPoints())
+	f.upsertedTo = append(f.upsertedTo, request.GetCollectionName())
+	if f.vectors == nil {
+		f.vectors = make(map[uint64][]float32)
+	}
+	for _, point := range request.GetPoints() {
+		f.vectors[point.GetId().GetNum()] = point.GetVectors().GetVector().GetData()
+	}
+	return &qdrant.PointsOperationResponse{Result: &qdrant.UpdateResult{Status: qdrant.UpdateStatus_Completed}}, nil
+}
+
+func (f *fakeQdrant) Scroll(ctx context.Context, request *qdrant.ScrollPoints) (*qdrant.ScrollResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scrolls++
+	f.scrolled = append(f.scrolled, request.GetCollectionName())
+
+	start := int(request.GetOffset().GetNum())
+	end := min(start+int(request.GetLimit()), len(f.points))
+	response := &qdrant.ScrollResponse{Result: f.points[start:end]}
+	if end < len(f.points) {
+		response.NextPageOffset = f.points[end].Id
+	}
+	return response, nil
+}
+
+// Get returns the upserted vectors of the requested points
+func (f *fakeQdrant) Get(ctx context.Context, request *qdrant.GetPoints) (*qdrant.GetResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	response := &qdrant.GetResponse{}
+	for _, id := range request.GetIds() {
+		if vector, ok := f.vectors[id.GetNum()]; ok {
+			response.Result = append(response.Result, &qdrant.RetrievedPoint{Id: id, Vectors: &qdrant.VectorsOutput{
+				VectorsOptions: &qdrant.VectorsOutput_Vector{Vector: &qdrant.VectorOutput{
+					Vector: &qdrant.VectorOutput_Dense{Dense: &qdrant.DenseVector{Data: vector}},
+				}},
+			}})
+		}
+	}
+	return response, nil
+}
+
+// Query answers every search with all points
+func (f *fakeQdrant) Query(ctx context.Context, request *qdrant.QueryPoints) (*qdrant.QueryResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries++
+	f.queried = append(f.queried, request.GetCollectionName())
+
+	response := &qdrant.QueryResponse{}
+	for _, point := range f.points {
+		response.Result = append(response.Result, &qdrant.ScoredPoint{Id: point.Id, Payload: point.Payload, Score: 1})
+	}
+	return response, nil
+}
+
+// newFakeQdrant starts a server holding a point per payload and returns a
+// service connected to it
+func newFakeQdrant(t *testing.T, payloads []map[string]any) (*fakeQdrant, *Service) {
+	t.Helper()
+	fake := &fakeQdrant{}
+	for i, payload := range payloads {
+		fake.points = append(fake.points, &qdrant.RetrievedPoint{
+			Id:      qdrant.NewIDNum(uint64(i)),
+			Payload: qdrant.NewValueMap(payload),
+		})
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := grpc.NewServer()
+	qdrant.RegisterPointsServer(server, fake)
+	qdrant.RegisterCollectionsServer(server, fakeCollections{points: fake})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	client, err := qdrant.NewClient(&qdrant.Config{Host: "127.0.0.1", Port: port, SkipCompatibilityCheck: true})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	service := &Service{
+		client:     client,
+		embeddings: NewEmbeddingCache(0),
+		searches:   NewSearchCache(DefaultSearchCacheSize, DefaultSearchCacheTTL),
+		retry:      RetryPolicy{},
+	}
+	return fake, service
+}
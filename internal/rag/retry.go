@@ -0,0 +1,113 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy bounds how Qdrant calls are retried after transient failures
+type RetryPolicy struct {
+	Attempts   int           // Total tries including the first; 1 disables retries
+	Backoff    time.Duration // Wait before the first retry, doubled after each
+	MaxBackoff time.Duration // Upper bound on a single wait
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:   4,
+		Backoff:    200 * time.Millisecond,
+		MaxBackoff: 2 * time.Second,
+	}
+}
+
+// isTransient reports whether err is a dropped or overloaded connection that
+// may succeed on retry, as opposed to an error in the request itself
+func isTransient(err error) bool {
+	if st, ok := status.FromError(err); ok {
+		switch st.Code() {
+		case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+			// withRetry stops on the caller's own deadline before asking
+			return true
+		}
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+// withRetry runs call, retrying transient failures with exponential backoff
+// until the policy's attempts run out or ctx ends
+func withRetry[T any](ctx context.Context, policy RetryPolicy, operation string, call func() (T, error)) (T, error) {
+	backoff := policy.Backoff
+	for attempt := 1; ; attempt++ {
+		result, err := call()
+		if err == nil || ctx.Err() != nil || !isTransient(err) || attempt >= policy.Attempts {
+			return result, err
+		}
+
+		log.Printf("Qdrant %s failed (attempt %d/%d), retrying in %v: %v", operation, attempt, policy.Attempts, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			var zero T
+			return zero, fmt.Errorf("%w (retrying after: %v)", ctx.Err(), err)
+		}
+
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// query runs a Qdrant query, retrying transient failures
+func (s *Service) query(ctx context.Context, request *qdrant.QueryPoints) ([]*qdrant.ScoredPoint, error) {
+	return withRetry(ctx, s.retry, "query", func() ([]*qdrant.ScoredPoint, error) {
+		return s.client.Query(ctx, request)
+	})
+}
+
+// upsert runs a Qdrant upsert, retrying transient failures. Upserts are
+// idempotent, so repeating one the server already applied is harmless.
+func (s *Service) upsert(ctx context.Context, request *qdrant.UpsertPoints) (*qdrant.UpdateResult, error) {
+	return withRetry(ctx, s.retry, "upsert", func() (*qdrant.UpdateResult, error) {
+		return s.client.Upsert(ctx, request)
+	})
+}
+
+// scrollPage runs one page of a Qdrant scroll, retrying transient failures,
+// and returns the offset of the next page; nil when it was the last
+func (s *Service) scrollPage(ctx context.Context, request *qdrant.ScrollPoints) ([]*qdrant.RetrievedPoint, *qdrant.PointId, error) {
+	type page struct {
+		points []*qdrant.RetrievedPoint
+		next   *qdrant.PointId
+	}
+	result, err := withRetry(ctx, s.retry, "scroll", func() (page, error) {
+		points, next, err := s.client.ScrollAndOffset(ctx, request)
+		return page{points: points, next: next}, err
+	})
+	return result.points, result.next, err
+}
+
+// get fetches Qdrant points by ID, retrying transient failures
+func (s *Service) get(ctx context.Context, request *qdrant.GetPoints) ([]*qdrant.RetrievedPoint, error) {
+	return withRetry(ctx, s.retry, "get", func() ([]*qdrant.RetrievedPoint, error) {
+		return s.client.Get(ctx, request)
+	})
+}
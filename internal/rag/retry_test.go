@@ -0,0 +1,100 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unavailable", status.Error(codes.Unavailable, "connection dropped"), true},
+		{"resource exhausted", status.Error(codes.ResourceExhausted, "overloaded"), true},
+		{"deadline exceeded", status.Error(codes.DeadlineExceeded, "slow"), true},
+		{"invalid argument", status.Error(codes.InvalidArgument, "bad vector"), false},
+		{"not found", status.Error(codes.NotFound, "no collection"), false},
+		{"connection refused", fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"unexpected eof", io.ErrUnexpectedEOF, true},
+		{"plain error", errors.New("wrong dimension"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	transient := status.Error(codes.Unavailable, "connection dropped")
+	queryErr := status.Error(codes.InvalidArgument, "bad vector")
+	policy := RetryPolicy{Attempts: 3, Backoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+	tests := []struct {
+		name      string
+		failures  []error // Returned by successive calls before succeeding
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "success", wantCalls: 1},
+		{name: "transient failure is retried", failures: []error{transient, transient}, wantCalls: 3},
+		{name: "query error is not retried", failures: []error{queryErr}, wantCalls: 1, wantErr: queryErr},
+		{name: "attempts run out", failures: []error{transient, transient, transient}, wantCalls: 3, wantErr: transient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			got, err := withRetry(context.Background(), policy, "query", func() (string, error) {
+				calls++
+				if calls <= len(tt.failures) {
+					return "", tt.failures[calls-1]
+				}
+				return "result", nil
+			})
+
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got != "result" {
+				t.Errorf("result = %q, want %q", got, "result")
+			}
+		})
+	}
+}
+
+func TestWithRetryStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := RetryPolicy{Attempts: 10, Backoff: time.Hour}
+
+	calls := 0
+	_, err := withRetry(ctx, policy, "upsert", func() (int, error) {
+		calls++
+		cancel()
+		return 0, status.Error(codes.Unavailable, "connection dropped")
+	})
+
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+	if err == nil {
+		t.Fatal("err = nil, want the failure after cancellation")
+	}
+}
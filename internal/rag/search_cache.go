@@ -0,0 +1,140 @@
+package rag
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// Search cache defaults used when no retrieval config sets them
+const (
+	DefaultSearchCacheSize = 128
+	DefaultSearchCacheTTL  = 5 * time.Minute
+)
+
+// searchEntry is a cached search response
+type searchEntry struct {
+	collection string
+	response   *types.RAGResponse
+	expires    time.Time
+}
+
+// SearchCache keeps recent SearchKnowledge responses for a short TTL so
+// identical searches, common across task retries, do not hit Qdrant again.
+// Writes to a collection invalidate its entries.
+type SearchCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	entries  map[[sha256.Size]byte]searchEntry
+	now      func() time.Time
+	hits     uint64
+	misses   uint64
+}
+
+// NewSearchCache creates a cache holding at most capacity responses for ttl.
+// A non-positive capacity or ttl disables caching.
+func NewSearchCache(capacity int, ttl time.Duration) *SearchCache {
+	return &SearchCache{
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[[sha256.Size]byte]searchEntry),
+		now:      time.Now,
+	}
+}
+
+// Get returns the cached response for a search of collection
+func (c *SearchCache) Get(collection string, query types.RAGQuery) (*types.RAGResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := searchKey(collection, query)
+	entry, exists := c.entries[key]
+	if !exists || !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		c.misses++
+		return nil, false
+	}
+
+	c.hits++
+	return copyResponse(entry.response), true
+}
+
+// Put stores the response for a search of collection. When the cache is full
+// expired entries are dropped first, then the entry closest to expiry.
+func (c *SearchCache) Put(collection string, query types.RAGQuery, response *types.RAGResponse) {
+	if c.capacity <= 0 || c.ttl <= 0 || response == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	key := searchKey(collection, query)
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.capacity {
+		c.evict(now)
+	}
+
+	c.entries[key] = searchEntry{
+		collection: collection,
+		response:   copyResponse(response),
+		expires:    now.Add(c.ttl),
+	}
+}
+
+// evict makes room for one entry
+func (c *SearchCache) evict(now time.Time) {
+	var oldestKey [sha256.Size]byte
+	var oldest time.Time
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest.IsZero() || entry.expires.Before(oldest) {
+			oldestKey, oldest = key, entry.expires
+		}
+	}
+	if len(c.entries) >= c.capacity {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// Invalidate drops every cached search of collection
+func (c *SearchCache) Invalidate(collection string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if entry.collection == collection {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Stats returns cache hit and miss counts
+func (c *SearchCache) Stats() (hits, misses uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// searchKey hashes everything that changes a search's result
+func searchKey(collection string, query types.RAGQuery) [sha256.Size]byte {
+	return sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%g\x00%s",
+		collection, strings.Join(strings.Fields(query.Query), " "), query.TopK, query.Threshold,
+		strings.Join(query.Filters, "\x00"))))
+}
+
+// copyResponse copies a response so callers cannot modify the cached one
+func copyResponse(response *types.RAGResponse) *types.RAGResponse {
+	copied := *response
+	copied.Documents = make([]types.RAGDocument, len(response.Documents))
+	copy(copied.Documents, response.Documents)
+	return &copied
+}
@@ -0,0 +1,118 @@
+package rag
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+func TestSearchCacheGet(t *testing.T) {
+	stored := types.RAGQuery{Query: "api  guide", TopK: 5, Threshold: 0.5}
+
+	tests := []struct {
+		name       string
+		capacity   int
+		ttl        time.Duration
+		advance    time.Duration
+		collection string
+		query      types.RAGQuery
+		want       bool
+	}{
+		{"hit within ttl", 10, time.Minute, time.Second, "documentation", stored, true},
+		{"whitespace normalised", 10, time.Minute, 0, "documentation", types.RAGQuery{Query: " api guide ", TopK: 5, Threshold: 0.5}, true},
+		{"expired", 10, time.Minute, time.Minute, "documentation", stored, false},
+		{"other collection", 10, time.Minute, 0, "code_examples", stored, false},
+		{"other top k", 10, time.Minute, 0, "documentation", types.RAGQuery{Query: "api guide", TopK: 10, Threshold: 0.5}, false},
+		{"other threshold", 10, time.Minute, 0, "documentation", types.RAGQuery{Query: "api guide", TopK: 5, Threshold: 0.7}, false},
+		{"other filters", 10, time.Minute, 0, "documentation", types.RAGQuery{Query: "api guide", TopK: 5, Threshold: 0.5, Filters: []string{"go"}}, false},
+		{"disabled by capacity", 0, time.Minute, 0, "documentation", stored, false},
+		{"disabled by ttl", 10, 0, 0, "documentation", stored, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewSearchCache(tt.capacity, tt.ttl)
+			now := time.Now()
+			cache.now = func() time.Time { return now }
+
+			cache.Put("documentation", stored, &types.RAGResponse{Query: stored.Query, TotalHits: 1})
+			now = now.Add(tt.advance)
+
+			if _, hit := cache.Get(tt.collection, tt.query); hit != tt.want {
+				t.Errorf("hit = %v, want %v", hit, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchCacheReturnsCopies(t *testing.T) {
+	cache := NewSearchCache(10, time.Minute)
+	query := types.RAGQuery{Query: "guide", TopK: 5}
+	response := &types.RAGResponse{Documents: []types.RAGDocument{{Content: "original"}}}
+
+	cache.Put("documentation", query, response)
+	response.Documents[0].Content = "changed by the caller"
+	cached, _ := cache.Get("documentation", query)
+	cached.Documents[0].Content = "changed by a reader"
+
+	if again, _ := cache.Get("documentation", query); again.Documents[0].Content != "original" {
+		t.Errorf("cached content = %q, want the stored response", again.Documents[0].Content)
+	}
+}
+
+func TestSearchCacheEvictsClosestToExpiry(t *testing.T) {
+	cache := NewSearchCache(2, time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	queries := []types.RAGQuery{{Query: "first"}, {Query: "second"}, {Query: "third"}}
+	for _, query := range queries {
+		cache.Put("documentation", query, &types.RAGResponse{})
+		now = now.Add(time.Second)
+	}
+
+	for i, want := range []bool{false, true, true} {
+		if _, hit := cache.Get("documentation", queries[i]); hit != want {
+			t.Errorf("%s: hit = %v, want %v", queries[i].Query, hit, want)
+		}
+	}
+	if hits, misses := cache.Stats(); hits != 2 || misses != 1 {
+		t.Errorf("Stats = %d hits, %d misses; want 2, 1", hits, misses)
+	}
+}
+
+func TestSearchKnowledgeCachesUntilWrite(t *testing.T) {
+	fake, service := newFakeQdrant(t, []map[string]any{{"content": "stored", "source": "guide.md"}})
+	service.embeddings = NewEmbeddingCache(10)
+	service.embeddingBinary = fakeEmbeddingBinary(t, `echo '{"data":[{"embedding":[0.1,0.2,0.3]}]}'`)
+	service.embeddingTimeout = time.Minute
+
+	ctx := context.Background()
+	query := types.RAGQuery{Query: "guide", Collection: "documentation", TopK: 5}
+	search := func() {
+		t.Helper()
+		response, err := service.SearchKnowledge(ctx, query)
+		if err != nil {
+			t.Fatalf("SearchKnowledge: %v", err)
+		}
+		if len(response.Documents) != 1 || response.Documents[0].Content != "stored" {
+			t.Errorf("Documents = %+v", response.Documents)
+		}
+	}
+
+	search()
+	search()
+	if fake.queries != 1 {
+		t.Errorf("Qdrant queried %d times, want 1 with the repeat served from cache", fake.queries)
+	}
+
+	if err := service.AddDocument(ctx, "documentation", types.RAGDocument{Content: "new"}); err != nil {
+		t.Fatalf("AddDocument: %v", err)
+	}
+	search()
+	if fake.queries != 2 {
+		t.Errorf("Qdrant queried %d times, want 2 after the write invalidated the cache", fake.queries)
+	}
+}
@@ -8,18 +8,46 @@ import (
 	"log"
 	"net"
 	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
 	"github.com/niko/mqtt-agent-orchestration/pkg/types"
 	"github.com/qdrant/go-client/qdrant"
 )
 
+// VectorDimension is the size of Qwen3-Embedding-4B vectors stored in every collection
+const VectorDimension = 2560
+
+const (
+	// DefaultEmbeddingTimeout bounds one llama-embedding run before it is killed
+	DefaultEmbeddingTimeout = 2 * time.Minute
+
+	// embeddingWaitDelay is how long output pipes may stay open after the
+	// embedding process is killed before they are closed
+	embeddingWaitDelay = time.Second
+
+	defaultEmbeddingBinary = "/home/niko/bin/llama-embedding"
+	defaultEmbeddingModel  = "/data/models/Qwen3-Embedding-4B-Q8_0.gguf"
+)
+
 // Service provides RAG functionality using qdrant
 type Service struct {
 	client      *qdrant.Client
 	qdrantURL   string
 	collections map[string]string // collection name -> description
+	retrieval   *config.RetrievalConfig
+	embeddings  *EmbeddingCache
+	searches    *SearchCache
+	retry       RetryPolicy
+
+	embeddingBinary  string        // llama-embedding executable
+	embeddingModel   string        // Qwen3-Embedding-4B GGUF file
+	embeddingTimeout time.Duration // Kill an embedding run after this long; zero waits forever
+
+	promptDriftThreshold float64 // Cosine distance beyond which StoreSystemPrompt refuses to overwrite; zero disables
 }
 
 // NewService creates a new RAG service with proper IPv6/IPv4 dual-stack support
@@ -71,21 +99,62 @@ func NewService(qdrantBinary, qdrantURL string) (*Service, error) {
 			"documentation":    "Technical documentation and guides",
 			"code_examples":    "Code examples and patterns",
 			"book_expert":      "Technical book content and knowledge",
+			"ai_audit":         "Redacted AI interactions kept for auditing",
+			"training_samples": "Sampled approved and rejected documents for training",
 		},
+		retrieval:  config.DefaultRetrievalConfig(),
+		embeddings: NewEmbeddingCache(DefaultEmbeddingCacheSize),
+		searches:   NewSearchCache(DefaultSearchCacheSize, DefaultSearchCacheTTL),
+		retry:      DefaultRetryPolicy(),
+
+		embeddingBinary:  defaultEmbeddingBinary,
+		embeddingModel:   defaultEmbeddingModel,
+		embeddingTimeout: DefaultEmbeddingTimeout,
+
+		promptDriftThreshold: DefaultPromptDriftThreshold,
 	}, nil
 }
 
+// SetEmbeddingCacheSize replaces the embedding cache with one holding size vectors; zero disables it
+func (s *Service) SetEmbeddingCacheSize(size int) {
+	s.embeddings = NewEmbeddingCache(size)
+}
+
+// SetEmbeddingTimeout bounds each llama-embedding run; zero waits forever
+func (s *Service) SetEmbeddingTimeout(timeout time.Duration) {
+	s.embeddingTimeout = timeout
+}
+
+// SetSearchCache replaces the search cache with one holding size responses
+// for ttl; a zero size or ttl disables it
+func (s *Service) SetSearchCache(size int, ttl time.Duration) {
+	s.searches = NewSearchCache(size, ttl)
+}
+
+// SetRetryPolicy overrides how Qdrant calls are retried after transient failures
+func (s *Service) SetRetryPolicy(policy RetryPolicy) {
+	s.retry = policy
+}
+
+// SetRetrievalConfig overrides the per-task-type TopK/Threshold used by GetRelevantContext
+func (s *Service) SetRetrievalConfig(retrieval *config.RetrievalConfig) {
+	s.retrieval = retrieval
+}
+
+// SetPromptDriftThreshold sets the cosine distance beyond which a new system
+// prompt requires ReplaceSystemPrompt; zero disables the check
+func (s *Service) SetPromptDriftThreshold(threshold float64) {
+	s.promptDriftThreshold = threshold
+}
+
 // InitializeCollections creates collections if they don't exist
 func (s *Service) InitializeCollections(ctx context.Context) error {
-	// Qwen3-Embedding-4B produces 2560-dimensional vectors - use consistent dimensions
-	const vectorDimension = 2560
-
 	// Create agent_prompts collection for system prompts
 	collectionName := "agent_prompts"
 	err := s.client.CreateCollection(ctx, &qdrant.CreateCollection{
 		CollectionName: collectionName,
 		VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
-			Size:     vectorDimension, // Qwen3-Embedding-4B-Q8_0 dimension
+			Size:     VectorDimension, // Qwen3-Embedding-4B-Q8_0 dimension
 			Distance: qdrant.Distance_Cosine,
 		}),
 	})
@@ -96,14 +165,31 @@ func (s *Service) InitializeCollections(ctx context.Context) error {
 	return nil
 }
 
-// StoreSystemPrompt stores a system prompt for a worker role
+// StoreSystemPrompt stores a system prompt for a worker role. It returns
+// ErrPromptDrift instead of overwriting a stored prompt that is very different.
 func (s *Service) StoreSystemPrompt(ctx context.Context, role types.WorkerRole, prompt string) error {
+	return s.storeSystemPrompt(ctx, role, prompt, false)
+}
+
+// ReplaceSystemPrompt stores a system prompt for a worker role without the drift check
+func (s *Service) ReplaceSystemPrompt(ctx context.Context, role types.WorkerRole, prompt string) error {
+	return s.storeSystemPrompt(ctx, role, prompt, true)
+}
+
+// storeSystemPrompt embeds and upserts the prompt, checking drift unless forced
+func (s *Service) storeSystemPrompt(ctx context.Context, role types.WorkerRole, prompt string, force bool) error {
 	// Generate proper embeddings using Qwen3-Embedding-4B model
-	embedding := s.generateLocalEmbedding(prompt)
+	embedding := s.embed(ctx, prompt)
 	if embedding == nil {
 		return fmt.Errorf("failed to generate embeddings for prompt - embedding model unavailable")
 	}
 
+	if !force {
+		if err := s.checkPromptDrift(ctx, role, embedding); err != nil {
+			return err
+		}
+	}
+
 	// Create point
 	point := &qdrant.PointStruct{
 		Id:      qdrant.NewIDNum(uint64(hashString(string(role)))),
@@ -116,7 +202,7 @@ func (s *Service) StoreSystemPrompt(ctx context.Context, role types.WorkerRole,
 		}),
 	}
 
-	_, err := s.client.Upsert(ctx, &qdrant.UpsertPoints{
+	_, err := s.upsert(ctx, &qdrant.UpsertPoints{
 		CollectionName: "agent_prompts",
 		Points:         []*qdrant.PointStruct{point},
 	})
@@ -124,6 +210,7 @@ func (s *Service) StoreSystemPrompt(ctx context.Context, role types.WorkerRole,
 	if err != nil {
 		return fmt.Errorf("failed to store system prompt: %w", err)
 	}
+	s.searches.Invalidate("agent_prompts")
 
 	log.Printf("Stored system prompt for role: %s", role)
 	return nil
@@ -133,7 +220,7 @@ func (s *Service) StoreSystemPrompt(ctx context.Context, role types.WorkerRole,
 // Fails fast if RAG is unavailable - following Design Principle: "Explicit error handling"
 func (s *Service) GetSystemPrompt(ctx context.Context, role types.WorkerRole) (string, error) {
 	// Query by role - fail fast if no client
-	searchResult, err := s.client.Query(ctx, &qdrant.QueryPoints{
+	searchResult, err := s.query(ctx, &qdrant.QueryPoints{
 		CollectionName: "agent_prompts",
 		Query:          qdrant.NewQueryID(qdrant.NewIDNum(uint64(hashString(string(role))))),
 		Limit:          qdrant.PtrOf(uint64(1)),
@@ -163,15 +250,26 @@ func (s *Service) GetSystemPrompt(ctx context.Context, role types.WorkerRole) (s
 // SearchKnowledge searches the knowledge base for relevant information
 // Fails fast if RAG is unavailable - following Design Principle: "Explicit error handling"
 func (s *Service) SearchKnowledge(ctx context.Context, query types.RAGQuery) (*types.RAGResponse, error) {
+	// Tenants only ever search their own copy of a collection
+	collection, err := TenantCollection(query.TenantID, query.Collection)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retries often repeat a search verbatim
+	if cached, hit := s.searches.Get(collection, query); hit {
+		return cached, nil
+	}
+
 	// Generate embedding for query - fail fast if unavailable
-	queryEmbedding := s.generateLocalEmbedding(query.Query)
+	queryEmbedding := s.embed(ctx, query.Query)
 	if queryEmbedding == nil {
 		return nil, fmt.Errorf("failed to generate query embedding - embedding model unavailable")
 	}
 
 	// Search in Qdrant
-	searchResult, err := s.client.Query(ctx, &qdrant.QueryPoints{
-		CollectionName: query.Collection,
+	searchResult, err := s.query(ctx, &qdrant.QueryPoints{
+		CollectionName: collection,
 		Query:          qdrant.NewQuery(queryEmbedding...),
 		Limit:          qdrant.PtrOf(uint64(query.TopK)),
 		ScoreThreshold: qdrant.PtrOf(float32(query.Threshold)),
@@ -179,7 +277,7 @@ func (s *Service) SearchKnowledge(ctx context.Context, query types.RAGQuery) (*t
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("Qdrant search failed for collection %s: %v", query.Collection, err)
+		return nil, fmt.Errorf("Qdrant search failed for collection %s: %v", collection, err)
 	}
 
 	// Convert to our format
@@ -195,60 +293,100 @@ func (s *Service) SearchKnowledge(ctx context.Context, query types.RAGQuery) (*t
 			Metadata: make(map[string]string),
 		}
 
-		// Extract content and metadata from payload
-		if payload := point.Payload; payload != nil {
-			// Handle payload as map[string]*qdrant.Value
-			if contentField, exists := payload["content"]; exists {
-				if stringValue, ok := contentField.GetKind().(*qdrant.Value_StringValue); ok {
-					doc.Content = stringValue.StringValue
-				}
-			}
-
-			if sourceField, exists := payload["source"]; exists {
-				if stringValue, ok := sourceField.GetKind().(*qdrant.Value_StringValue); ok {
-					doc.Source = stringValue.StringValue
-				}
-			}
-
-			// Extract other metadata
-			for key, field := range payload {
-				if stringValue, ok := field.GetKind().(*qdrant.Value_StringValue); ok {
-					doc.Metadata[key] = stringValue.StringValue
-				}
-			}
+		// Extract content and metadata from payload, whatever type each field holds
+		for key, field := range point.Payload {
+			doc.Metadata[key] = payloadString(field)
 		}
+		doc.Content = doc.Metadata["content"]
+		doc.Source = doc.Metadata["source"]
 
 		response.Documents = append(response.Documents, doc)
 	}
 
+	s.searches.Put(collection, query, response)
 	return response, nil
 }
 
 // GetRelevantContext gets context for a specific task type
 func (s *Service) GetRelevantContext(ctx context.Context, taskType, content string) (string, error) {
+	documents, err := s.GetRelevantDocuments(ctx, taskType, content)
+	if err != nil {
+		return "", err
+	}
+
+	return formatContext(documents), nil
+}
+
+// GetRelevantDocuments returns the documents retrieved for a task type with
+// their sources, so prompts can label them for citation
+func (s *Service) GetRelevantDocuments(ctx context.Context, taskType, content string) ([]types.RAGDocument, error) {
+	settings := s.retrieval.ForTaskType(taskType)
 	query := types.RAGQuery{
 		Query:      fmt.Sprintf("%s %s", taskType, content),
 		Collection: "coding_standards",
-		TopK:       3,
-		Threshold:  0.5,
+		TopK:       settings.TopK,
+		Threshold:  settings.Threshold,
 	}
 
 	response, err := s.SearchKnowledge(ctx, query)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if len(response.Documents) == 0 {
-		return "No relevant context found", nil
+	return response.Documents, nil
+}
+
+// formatContext joins retrieved documents into a prompt-ready context string
+func formatContext(documents []types.RAGDocument) string {
+	if len(documents) == 0 {
+		return "No relevant context found"
 	}
 
-	// Build context string
 	var contextParts []string
-	for i, doc := range response.Documents {
+	for i, doc := range documents {
+		if doc.Source != "" {
+			contextParts = append(contextParts, fmt.Sprintf("Context %d (source: %s): %s", i+1, doc.Source, doc.Content))
+			continue
+		}
 		contextParts = append(contextParts, fmt.Sprintf("Context %d: %s", i+1, doc.Content))
 	}
 
-	return strings.Join(contextParts, "\n\n"), nil
+	return strings.Join(contextParts, "\n\n")
+}
+
+// CollectionStats summarizes a knowledge base collection
+type CollectionStats struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Points      uint64 `json:"points"`
+	Status      string `json:"status"`
+}
+
+// CollectionStats reports point counts for the configured collections
+func (s *Service) CollectionStats(ctx context.Context) ([]CollectionStats, error) {
+	names := make([]string, 0, len(s.collections))
+	for name := range s.collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stats := make([]CollectionStats, 0, len(names))
+	for _, name := range names {
+		info, err := s.client.GetCollectionInfo(ctx, name)
+		if err != nil {
+			stats = append(stats, CollectionStats{Name: name, Description: s.collections[name], Status: "missing"})
+			continue
+		}
+
+		stats = append(stats, CollectionStats{
+			Name:        name,
+			Description: s.collections[name],
+			Points:      info.GetPointsCount(),
+			Status:      strings.ToLower(info.GetStatus().String()),
+		})
+	}
+
+	return stats, nil
 }
 
 // IsAvailable checks if qdrant service is available
@@ -262,17 +400,56 @@ func (s *Service) IsAvailable(ctx context.Context) bool {
 	return err == nil
 }
 
+// embed returns the embedding for text, serving repeated inputs from the cache
+func (s *Service) embed(ctx context.Context, text string) []float32 {
+	if vector, cached := s.embeddings.Get(text); cached {
+		return vector
+	}
+
+	vector := s.generateLocalEmbedding(ctx, text)
+	if vector != nil {
+		s.embeddings.Put(text, vector)
+	}
+	return vector
+}
+
+// Embed returns the embedding for text from the local model, for callers
+// that store or search vectors themselves. Unlike a hash stand-in, a failed
+// or cancelled run is an error.
+func (s *Service) Embed(ctx context.Context, text string) ([]float32, error) {
+	vector := s.embed(ctx, text)
+	if vector == nil {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("embedding generation stopped: %w", err)
+		}
+		return nil, fmt.Errorf("embedding model unavailable")
+	}
+	return vector, nil
+}
+
 // generateLocalEmbedding generates embeddings using local Qwen3-Embedding-4B model
 // Returns nil if the embedding model is unavailable - caller must handle this explicitly
-func (s *Service) generateLocalEmbedding(text string) []float32 {
-	// Use llama-embedding binary with Qwen3-Embedding-4B model
-	// Following "Do more with less" - use existing llama-embedding binary from /home/niko/bin
-	
-	// Implementation using llama-embedding CLI for reliable embeddings
-	cmd := fmt.Sprintf(`/home/niko/bin/llama-embedding -m /data/models/Qwen3-Embedding-4B-Q8_0.gguf -p %q --embd-output-format json --embd-normalize 2`, text)
-	
-	// Execute embedding generation
-	output, err := exec.Command("sh", "-c", cmd).Output()
+func (s *Service) generateLocalEmbedding(ctx context.Context, text string) []float32 {
+	if s.embeddingTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.embeddingTimeout)
+		defer cancel()
+	}
+
+	// The text is passed as its own argument, never through a shell
+	cmd := exec.CommandContext(ctx, s.embeddingBinary,
+		"-m", s.embeddingModel,
+		"-p", text,
+		"--embd-output-format", "json",
+		"--embd-normalize", "2")
+	// Don't wait forever on pipes a killed process left to its children
+	cmd.WaitDelay = embeddingWaitDelay
+
+	output, err := cmd.Output()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		log.Printf("Embedding generation stopped: %v", ctxErr)
+		return nil
+	}
 	if err != nil {
 		log.Printf("Embedding generation failed: %v", err)
 		return nil
@@ -303,6 +480,54 @@ func (s *Service) generateLocalEmbedding(text string) []float32 {
 	return embedding
 }
 
+// AddDocument embeds a document and upserts it into collection. The point ID
+// is derived from the content, so ingesting the same document twice updates
+// it rather than duplicating it.
+func (s *Service) AddDocument(ctx context.Context, collection string, doc types.RAGDocument) error {
+	if collection == "" {
+		return fmt.Errorf("collection is required")
+	}
+	if doc.Content == "" {
+		return fmt.Errorf("document content is required")
+	}
+
+	embedding := s.embed(ctx, doc.Content)
+	if embedding == nil {
+		return fmt.Errorf("failed to generate embeddings for document - embedding model unavailable")
+	}
+
+	payload := map[string]any{
+		"content": doc.Content,
+		"source":  doc.Source,
+	}
+	for key, value := range doc.Metadata {
+		if _, reserved := payload[key]; !reserved {
+			payload[key] = value
+		}
+	}
+
+	_, err := s.upsert(ctx, &qdrant.UpsertPoints{
+		CollectionName: collection,
+		Points: []*qdrant.PointStruct{{
+			Id:      qdrant.NewIDNum(documentID(doc.Content)),
+			Vectors: qdrant.NewVectors(embedding...),
+			Payload: qdrant.NewValueMap(payload),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store document in %s: %w", collection, err)
+	}
+	s.searches.Invalidate(collection)
+	return nil
+}
+
+// documentID derives a stable point ID from document content
+func documentID(content string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(content))
+	return h.Sum64()
+}
+
 // hashString creates a consistent hash for string values
 func hashString(s string) uint32 {
 	h := fnv.New32a()
@@ -0,0 +1,123 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeEmbeddingBinary writes a llama-embedding stand-in running script and
+// returns its path. The script sees the prompt text as $4.
+func fakeEmbeddingBinary(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "llama-embedding")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatalf("write fake binary: %v", err)
+	}
+	return path
+}
+
+func TestGenerateLocalEmbedding(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "injected")
+	received := filepath.Join(dir, "prompt")
+
+	tests := []struct {
+		name    string
+		script  string
+		text    string
+		timeout time.Duration
+		want    int
+	}{
+		{"embeds", `echo '{"data":[{"embedding":[0.1,0.2,0.3]}]}'`, "hello", time.Minute, 3},
+		{"shell syntax stays text", `printf '%s' "$4" > ` + received + `; echo '{"data":[{"embedding":[1]}]}'`, "$(touch " + marker + ") `touch " + marker + "`", time.Minute, 1},
+		{"killed after timeout", `sleep 30`, "hello", 50 * time.Millisecond, 0},
+		{"command fails", `exit 1`, "hello", time.Minute, 0},
+		{"bad output", `echo not json`, "hello", time.Minute, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &Service{
+				embeddings:       NewEmbeddingCache(10),
+				embeddingBinary:  fakeEmbeddingBinary(t, tt.script),
+				embeddingTimeout: tt.timeout,
+			}
+
+			start := time.Now()
+			vector := service.embed(context.Background(), tt.text)
+			if len(vector) != tt.want {
+				t.Errorf("embed returned %d dimensions, want %d", len(vector), tt.want)
+			}
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("embed took %v", elapsed)
+			}
+			// Failures are retried on the next call rather than cached
+			if _, cached := service.embeddings.Get(tt.text); cached != (tt.want > 0) {
+				t.Errorf("cached = %v, want %v", cached, tt.want > 0)
+			}
+		})
+	}
+
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("prompt text was run by a shell")
+	}
+	if prompt, err := os.ReadFile(received); err != nil || string(prompt) != "$(touch "+marker+") `touch "+marker+"`" {
+		t.Errorf("binary received prompt %q (%v), want the text verbatim", prompt, err)
+	}
+}
+
+func TestGenerateLocalEmbeddingHonoursContext(t *testing.T) {
+	service := &Service{
+		embeddings:      NewEmbeddingCache(0),
+		embeddingBinary: fakeEmbeddingBinary(t, `sleep 30`),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if vector := service.generateLocalEmbedding(ctx, "hello"); vector != nil {
+		t.Errorf("got %d dimensions from a cancelled run", len(vector))
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("cancelled run took %v", elapsed)
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		script  string
+		want    int
+		wantErr error
+	}{
+		{"embeds", context.Background(), `echo '{"data":[{"embedding":[0.1,0.2]}]}'`, 2, nil},
+		{"model unavailable", context.Background(), `exit 1`, 0, nil},
+		{"cancelled", cancelled, `sleep 30`, 0, context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &Service{
+				embeddings:      NewEmbeddingCache(0),
+				embeddingBinary: fakeEmbeddingBinary(t, tt.script),
+			}
+
+			// A failed run is an error rather than a stand-in vector
+			vector, err := service.Embed(tt.ctx, "hello")
+			if len(vector) != tt.want || (err == nil) != (tt.want > 0) {
+				t.Fatalf("Embed = %d dimensions, %v; want %d", len(vector), err, tt.want)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package rag
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// SnapshotManifestFile names the file describing a snapshot's collections
+const SnapshotManifestFile = "manifest.json"
+
+// snapshotBatchSize is the number of points read or written per Qdrant call
+const snapshotBatchSize = 256
+
+// SnapshotManifest describes a knowledge base snapshot
+type SnapshotManifest struct {
+	CreatedAt   time.Time            `json:"created_at"`
+	Collections []SnapshotCollection `json:"collections"`
+}
+
+// SnapshotCollection describes one collection in a snapshot. Its points are
+// stored one JSON object per line in File, relative to the snapshot directory.
+type SnapshotCollection struct {
+	Name       string `json:"name"`
+	File       string `json:"file"`
+	VectorSize uint64 `json:"vector_size"`
+	Distance   string `json:"distance"`
+	Points     int    `json:"points"`
+}
+
+// snapshotPoint is a point as stored in a snapshot file. Numeric IDs are
+// written in decimal; anything else is a UUID.
+type snapshotPoint struct {
+	ID      string         `json:"id"`
+	Vector  []float32      `json:"vector"`
+	Payload map[string]any `json:"payload,omitempty"`
+}
+
+// Snapshot exports every point of each configured collection and every
+// tenant's copy of them, with its vector and payload, to portable files in
+// dir. Shared collections that do not exist are skipped.
+func (s *Service) Snapshot(ctx context.Context, dir string) (*SnapshotManifest, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory %s: %w", dir, err)
+	}
+
+	tenants, err := s.ListTenantCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &SnapshotManifest{CreatedAt: time.Now().UTC()}
+	for _, name := range s.baseCollections() {
+		exists, err := s.client.CollectionExists(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check collection %s: %w", name, err)
+		}
+		if !exists {
+			log.Printf("Collection %s does not exist, leaving it out of the snapshot", name)
+			continue
+		}
+
+		collection, err := s.snapshotCollection(ctx, dir, name)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Collections = append(manifest.Collections, collection)
+	}
+
+	// Tenant collections exist by definition, as they were listed
+	tenantIDs := make([]string, 0, len(tenants))
+	for tenant := range tenants {
+		tenantIDs = append(tenantIDs, tenant)
+	}
+	sort.Strings(tenantIDs)
+	for _, tenant := range tenantIDs {
+		for _, base := range tenants[tenant] {
+			name, _ := TenantCollection(tenant, base)
+			collection, err := s.snapshotCollection(ctx, dir, name)
+			if err != nil {
+				return nil, err
+			}
+			manifest.Collections = append(manifest.Collections, collection)
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal snapshot manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, SnapshotManifestFile), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write snapshot manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// snapshotCollection scrolls through a collection, writing its points to
+// <name>.jsonl in dir
+func (s *Service) snapshotCollection(ctx context.Context, dir, name string) (SnapshotCollection, error) {
+	info, err := s.client.GetCollectionInfo(ctx, name)
+	if err != nil {
+		return SnapshotCollection{}, fmt.Errorf("failed to get collection %s: %w", name, err)
+	}
+	collection := SnapshotCollection{
+		Name:       name,
+		File:       name + ".jsonl",
+		VectorSize: collectionVectorSize(info),
+		Distance:   info.GetConfig().GetParams().GetVectorsConfig().GetParams().GetDistance().String(),
+	}
+
+	file, err := os.Create(filepath.Join(dir, collection.File))
+	if err != nil {
+		return SnapshotCollection{}, fmt.Errorf("failed to create snapshot of %s: %w", name, err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+
+	var offset *qdrant.PointId
+	for {
+		points, next, err := s.scrollPage(ctx, &qdrant.ScrollPoints{
+			CollectionName: name,
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(snapshotBatchSize)),
+			WithPayload:    qdrant.NewWithPayload(true),
+			WithVectors:    qdrant.NewWithVectors(true),
+		})
+		if err != nil {
+			return SnapshotCollection{}, fmt.Errorf("failed to scroll collection %s: %w", name, err)
+		}
+
+		for _, point := range points {
+			if err := encoder.Encode(newSnapshotPoint(point)); err != nil {
+				return SnapshotCollection{}, fmt.Errorf("failed to write point %s of %s: %w", point.GetId(), name, err)
+			}
+			collection.Points++
+		}
+
+		if next == nil {
+			break
+		}
+		offset = next
+	}
+
+	if err := writer.Flush(); err != nil {
+		return SnapshotCollection{}, fmt.Errorf("failed to write snapshot of %s: %w", name, err)
+	}
+	if err := file.Close(); err != nil {
+		return SnapshotCollection{}, fmt.Errorf("failed to write snapshot of %s: %w", name, err)
+	}
+
+	log.Printf("Snapshot of %s: %d points", name, collection.Points)
+	return collection, nil
+}
+
+// Restore re-imports a snapshot written by Snapshot, creating collections
+// that do not exist. Points already present with the same ID are overwritten.
+func (s *Service) Restore(ctx context.Context, dir string) (*SnapshotManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, SnapshotManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot manifest: %w", err)
+	}
+
+	var manifest SnapshotManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid snapshot manifest: %w", err)
+	}
+
+	for _, collection := range manifest.Collections {
+		if err := s.restoreCollection(ctx, dir, collection); err != nil {
+			return nil, err
+		}
+	}
+	return &manifest, nil
+}
+
+// restoreCollection creates the collection if needed and upserts its points
+// from the snapshot file in batches
+func (s *Service) restoreCollection(ctx context.Context, dir string, collection SnapshotCollection) error {
+	if err := s.ensureSnapshotCollection(ctx, collection); err != nil {
+		return err
+	}
+
+	file, err := os.Open(filepath.Join(dir, filepath.Base(collection.File)))
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot of %s: %w", collection.Name, err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	decoder.UseNumber()
+
+	restored := 0
+	batch := make([]*qdrant.PointStruct, 0, snapshotBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		_, err := s.upsert(ctx, &qdrant.UpsertPoints{
+			CollectionName: collection.Name,
+			Wait:           qdrant.PtrOf(true),
+			Points:         batch,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore points into %s: %w", collection.Name, err)
+		}
+		s.searches.Invalidate(collection.Name)
+		restored += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for decoder.More() {
+		var stored snapshotPoint
+		if err := decoder.Decode(&stored); err != nil {
+			return fmt.Errorf("invalid point in snapshot of %s: %w", collection.Name, err)
+		}
+
+		point, err := stored.pointStruct()
+		if err != nil {
+			return fmt.Errorf("invalid point %s in snapshot of %s: %w", stored.ID, collection.Name, err)
+		}
+		batch = append(batch, point)
+
+		if len(batch) == snapshotBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	log.Printf("Restored %d points into %s", restored, collection.Name)
+	return nil
+}
+
+// ensureSnapshotCollection creates a snapshotted collection that does not
+// exist, with its recorded vector size and distance
+func (s *Service) ensureSnapshotCollection(ctx context.Context, collection SnapshotCollection) error {
+	exists, err := s.client.CollectionExists(ctx, collection.Name)
+	if err != nil {
+		return fmt.Errorf("failed to check collection %s: %w", collection.Name, err)
+	}
+	if exists {
+		return nil
+	}
+
+	size := collection.VectorSize
+	if size == 0 {
+		size = VectorDimension
+	}
+	distance := qdrant.Distance_Cosine
+	if value, known := qdrant.Distance_value[collection.Distance]; known && value != int32(qdrant.Distance_UnknownDistance) {
+		distance = qdrant.Distance(value)
+	}
+
+	err = s.client.CreateCollection(ctx, &qdrant.CreateCollection{
+		CollectionName: collection.Name,
+		VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
+			Size:     size,
+			Distance: distance,
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create collection %s: %w", collection.Name, err)
+	}
+	log.Printf("Created collection %s for restore", collection.Name)
+	return nil
+}
+
+// newSnapshotPoint converts a retrieved point for writing to a snapshot
+func newSnapshotPoint(point *qdrant.RetrievedPoint) snapshotPoint {
+	stored := snapshotPoint{ID: point.GetId().GetUuid()}
+	if stored.ID == "" {
+		stored.ID = strconv.FormatUint(point.GetId().GetNum(), 10)
+	}
+
+	vector := point.GetVectors().GetVector()
+	if dense := vector.GetDense(); dense != nil {
+		stored.Vector = dense.GetData()
+	} else {
+		stored.Vector = vector.GetData()
+	}
+
+	if len(point.GetPayload()) > 0 {
+		stored.Payload = make(map[string]any, len(point.GetPayload()))
+		for key, value := range point.GetPayload() {
+			stored.Payload[key] = snapshotValue(payloadValue(value))
+		}
+	}
+	return stored
+}
+
+// snapshotValue marks floats so they are restored as doubles even when
+// whole, e.g. 1.0 rather than the integer 1
+func snapshotValue(value any) any {
+	switch v := value.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil
+		}
+		text := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".eE") {
+			text += ".0"
+		}
+		return json.Number(text)
+	case []any:
+		for i, item := range v {
+			v[i] = snapshotValue(item)
+		}
+		return v
+	case map[string]any:
+		for key, item := range v {
+			v[key] = snapshotValue(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// restoredValue converts a payload value decoded with UseNumber back to the
+// types Qdrant stores: numbers without a fraction or exponent are integers
+func restoredValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if integer, err := v.Int64(); err == nil && !strings.ContainsAny(v.String(), ".eE") {
+			return integer
+		}
+		float, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		return float
+	case []any:
+		for i, item := range v {
+			v[i] = restoredValue(item)
+		}
+		return v
+	case map[string]any:
+		for key, item := range v {
+			v[key] = restoredValue(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// pointStruct converts a snapshot point back to a Qdrant point
+func (p snapshotPoint) pointStruct() (*qdrant.PointStruct, error) {
+	id := qdrant.NewIDUUID(p.ID)
+	if num, err := strconv.ParseUint(p.ID, 10, 64); err == nil {
+		id = qdrant.NewIDNum(num)
+	}
+
+	payload := make(map[string]any, len(p.Payload))
+	for key, value := range p.Payload {
+		payload[key] = restoredValue(value)
+	}
+	values, err := qdrant.TryValueMap(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &qdrant.PointStruct{
+		Id:      id,
+		Vectors: qdrant.NewVectorsDense(p.Vector),
+		Payload: values,
+	}, nil
+}
@@ -0,0 +1,85 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+func TestRestoreInvalidatesSearchCache(t *testing.T) {
+	dir := t.TempDir()
+	manifest := `{"collections":[{"name":"documentation","file":"documentation.jsonl","vector_size":3,"distance":"Cosine","points":1}]}`
+	if err := os.WriteFile(filepath.Join(dir, SnapshotManifestFile), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	point := `{"id":"7","vector":[0.1,0.2,0.3],"payload":{"content":"restored"}}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "documentation.jsonl"), []byte(point), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fake, service := newFakeQdrant(t, nil)
+	query := types.RAGQuery{Query: "guide", TopK: 5}
+	for _, collection := range []string{"documentation", "code_examples"} {
+		service.searches.Put(collection, query, &types.RAGResponse{})
+	}
+
+	if _, err := service.Restore(context.Background(), dir); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if fake.upserted != 1 {
+		t.Errorf("upserted %d points, want 1", fake.upserted)
+	}
+
+	tests := []struct {
+		collection string
+		wantCached bool
+	}{
+		{"documentation", false}, // Restored, so cached searches are stale
+		{"code_examples", true},  // Not in the snapshot
+	}
+	for _, tt := range tests {
+		if _, cached := service.searches.Get(tt.collection, query); cached != tt.wantCached {
+			t.Errorf("%s: cached = %v, want %v", tt.collection, cached, tt.wantCached)
+		}
+	}
+}
+
+func TestSnapshotRestoresTenantCollections(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	source, service := newFakeQdrant(t, []map[string]any{{"content": "acme deploys on fridays"}})
+	service.collections = map[string]string{"documentation": "Documentation"}
+	source.collections = []string{"documentation", "acme_documentation", "acme_unknown"}
+
+	manifest, err := service.Snapshot(ctx, dir)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	var names []string
+	for _, collection := range manifest.Collections {
+		names = append(names, collection.Name)
+		if collection.Points != 1 {
+			t.Errorf("%s snapshot has %d points, want 1", collection.Name, collection.Points)
+		}
+	}
+	want := []string{"documentation", "acme_documentation"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("snapshot holds %v, want %v", names, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "acme_documentation.jsonl")); err != nil {
+		t.Errorf("tenant collection file: %v", err)
+	}
+
+	target, restorer := newFakeQdrant(t, nil)
+	if _, err := restorer.Restore(ctx, dir); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if !reflect.DeepEqual(target.upsertedTo, want) || target.upserted != 2 {
+		t.Errorf("restored %d points into %v, want one into each of %v", target.upserted, target.upsertedTo, want)
+	}
+}
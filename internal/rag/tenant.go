@@ -0,0 +1,116 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// maxTenantIDLength keeps tenant collection names well under Qdrant's limit
+const maxTenantIDLength = 48
+
+// ErrInvalidTenant is returned for tenant IDs that cannot prefix a collection name
+var ErrInvalidTenant = errors.New("invalid tenant id")
+
+// ValidateTenantID checks that a tenant ID uses only lowercase letters,
+// digits and hyphens. Underscores are rejected because they separate the
+// tenant from the collection name.
+func ValidateTenantID(tenant string) error {
+	if tenant == "" || len(tenant) > maxTenantIDLength {
+		return fmt.Errorf("%w %q: must be 1-%d characters", ErrInvalidTenant, tenant, maxTenantIDLength)
+	}
+	for _, r := range tenant {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return fmt.Errorf("%w %q: only lowercase letters, digits and hyphens are allowed", ErrInvalidTenant, tenant)
+		}
+	}
+	return nil
+}
+
+// TenantCollection returns the collection a tenant's documents live in,
+// named {tenant}_{collection}. An empty tenant uses the shared collection.
+func TenantCollection(tenant, collection string) (string, error) {
+	if tenant == "" {
+		return collection, nil
+	}
+	if err := ValidateTenantID(tenant); err != nil {
+		return "", err
+	}
+	return tenant + "_" + collection, nil
+}
+
+// groupTenantCollections maps each tenant to the base collections it has,
+// recognising tenant collections by a known base collection suffix
+func groupTenantCollections(names []string, bases []string) map[string][]string {
+	tenants := make(map[string][]string)
+	for _, name := range names {
+		for _, base := range bases {
+			tenant, found := strings.CutSuffix(name, "_"+base)
+			if !found || ValidateTenantID(tenant) != nil {
+				continue
+			}
+			tenants[tenant] = append(tenants[tenant], base)
+			break
+		}
+	}
+
+	for _, collections := range tenants {
+		sort.Strings(collections)
+	}
+	return tenants
+}
+
+// baseCollections returns the names of the shared collections, sorted
+func (s *Service) baseCollections() []string {
+	bases := make([]string, 0, len(s.collections))
+	for name := range s.collections {
+		bases = append(bases, name)
+	}
+	sort.Strings(bases)
+	return bases
+}
+
+// CreateTenantCollections creates a tenant's copy of every shared collection
+func (s *Service) CreateTenantCollections(ctx context.Context, tenant string) error {
+	if err := ValidateTenantID(tenant); err != nil {
+		return err
+	}
+
+	for _, base := range s.baseCollections() {
+		name, _ := TenantCollection(tenant, base)
+		exists, err := s.client.CollectionExists(ctx, name)
+		if err != nil {
+			return fmt.Errorf("failed to check collection %s: %w", name, err)
+		}
+		if exists {
+			continue
+		}
+
+		err = s.client.CreateCollection(ctx, &qdrant.CreateCollection{
+			CollectionName: name,
+			VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
+				Size:     VectorDimension,
+				Distance: qdrant.Distance_Cosine,
+			}),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create collection %s: %w", name, err)
+		}
+		log.Printf("Created collection %s for tenant %s", name, tenant)
+	}
+	return nil
+}
+
+// ListTenantCollections reports which shared collections each tenant has
+func (s *Service) ListTenantCollections(ctx context.Context) (map[string][]string, error) {
+	names, err := s.client.ListCollections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+	return groupTenantCollections(names, s.baseCollections()), nil
+}
@@ -0,0 +1,106 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+func TestTenantCollection(t *testing.T) {
+	tests := []struct {
+		tenant  string
+		want    string
+		wantErr bool
+	}{
+		{"", "documentation", false},
+		{"acme", "acme_documentation", false},
+		{"team-7", "team-7_documentation", false},
+		{"Acme", "", true},
+		{"acme_eu", "", true}, // Underscores would be ambiguous with the separator
+		{"../x", "", true},
+		{strings.Repeat("a", maxTenantIDLength+1), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tenant, func(t *testing.T) {
+			got, err := TenantCollection(tt.tenant, "documentation")
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTenant) {
+					t.Errorf("err = %v, want ErrInvalidTenant", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("TenantCollection = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupTenantCollections(t *testing.T) {
+	names := []string{
+		"documentation", "coding_standards",
+		"acme_documentation", "acme_coding_standards",
+		"globex_documentation",
+		"Bad_documentation", "acme_unknown",
+	}
+	want := map[string][]string{
+		"acme":   {"coding_standards", "documentation"},
+		"globex": {"documentation"},
+	}
+
+	got := groupTenantCollections(names, []string{"coding_standards", "documentation"})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groupTenantCollections = %v, want %v", got, want)
+	}
+}
+
+func TestTenantSearchIsolation(t *testing.T) {
+	store := NewMemoryService()
+	ctx := context.Background()
+	for tenant, content := range map[string]string{"acme": "acme deploys on fridays", "globex": "globex deploys on mondays"} {
+		collection, _ := TenantCollection(tenant, "documentation")
+		if err := store.AddDocument(ctx, collection, types.RAGDocument{Content: content}); err != nil {
+			t.Fatalf("AddDocument: %v", err)
+		}
+	}
+
+	for _, tenant := range []string{"acme", "globex"} {
+		response, err := store.SearchKnowledge(ctx, types.RAGQuery{Query: "deploys", Collection: "documentation", TenantID: tenant})
+		if err != nil {
+			t.Fatalf("SearchKnowledge(%s): %v", tenant, err)
+		}
+		if len(response.Documents) != 1 || !strings.HasPrefix(response.Documents[0].Content, tenant) {
+			t.Errorf("tenant %s found %+v, want only its own document", tenant, response.Documents)
+		}
+	}
+
+	// The shared collection holds neither tenant's documents
+	if response, _ := store.SearchKnowledge(ctx, types.RAGQuery{Query: "deploys", Collection: "documentation"}); len(response.Documents) != 0 {
+		t.Errorf("shared search found %+v", response.Documents)
+	}
+}
+
+func TestServiceSearchUsesTenantCollection(t *testing.T) {
+	fake, service := newFakeQdrant(t, nil)
+	service.embeddingBinary = fakeEmbeddingBinary(t, `echo '{"data":[{"embedding":[1,0]}]}'`)
+	ctx := context.Background()
+
+	for _, tenant := range []string{"acme", "globex", ""} {
+		if _, err := service.SearchKnowledge(ctx, types.RAGQuery{Query: "deploys", Collection: "documentation", TenantID: tenant, TopK: 3}); err != nil {
+			t.Fatalf("SearchKnowledge(%q): %v", tenant, err)
+		}
+	}
+	want := []string{"acme_documentation", "globex_documentation", "documentation"}
+	if !reflect.DeepEqual(fake.queried, want) {
+		t.Errorf("searched %v, want %v", fake.queried, want)
+	}
+
+	if _, err := service.SearchKnowledge(ctx, types.RAGQuery{Query: "deploys", Collection: "documentation", TenantID: "Acme"}); !errors.Is(err, ErrInvalidTenant) {
+		t.Errorf("err = %v, want ErrInvalidTenant", err)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
@@ -13,43 +15,118 @@ import (
 	"github.com/qdrant/go-client/qdrant"
 )
 
+// TrainingExportBatchSize is the number of points read per Qdrant scroll
+// when exporting training data
+const TrainingExportBatchSize = 1000
+
 // TrainingDataExporter handles export of RAG data for training
 type TrainingDataExporter struct {
 	service *Service
+
+	embedderDimension uint64            // Vector size the service's embedder produces
+	dimensions        map[string]uint64 // Vector size of each exported collection, as last read
+	mu                sync.Mutex
 }
 
 // NewTrainingDataExporter creates a new training data exporter
 func NewTrainingDataExporter(service *Service) *TrainingDataExporter {
 	return &TrainingDataExporter{
-		service: service,
+		service:           service,
+		embedderDimension: VectorDimension,
+		dimensions:        make(map[string]uint64),
 	}
 }
 
-// ExportTrainingData exports successful interactions from RAG for training
-func (e *TrainingDataExporter) ExportTrainingData(ctx context.Context, collection string, minScore float64) ([]localmodels.TrainingExample, error) {
-	// Query all documents from the collection with high scores
-	searchResult, err := e.service.client.Scroll(ctx, &qdrant.ScrollPoints{
-		CollectionName: collection,
-		Limit:          qdrant.PtrOf(uint32(1000)), // Batch size
-		WithPayload:    qdrant.NewWithPayload(true),
-	})
+// CollectionDimension returns the vector size recorded for collection by the
+// last export from it
+func (e *TrainingDataExporter) CollectionDimension(collection string) (uint64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	dimension, ok := e.dimensions[collection]
+	return dimension, ok
+}
 
+// checkCollectionDimension reads and records the collection's vector size
+// and reports an error when it differs from the embedder's
+func (e *TrainingDataExporter) checkCollectionDimension(ctx context.Context, collection string) error {
+	info, err := e.service.client.GetCollectionInfo(ctx, collection)
 	if err != nil {
-		return nil, fmt.Errorf("failed to scroll collection %s: %w", collection, err)
+		return fmt.Errorf("failed to read collection %s info: %w", collection, err)
+	}
+
+	dimension := collectionVectorSize(info)
+	if dimension == 0 {
+		return fmt.Errorf("collection %s has no single vector configuration", collection)
+	}
+
+	e.mu.Lock()
+	e.dimensions[collection] = dimension
+	e.mu.Unlock()
+
+	return dimensionMismatch(collection, dimension, e.embedderDimension)
+}
+
+// collectionVectorSize returns the size of a collection's unnamed vector, or
+// of its only named vector; zero when it cannot be determined
+func collectionVectorSize(info *qdrant.CollectionInfo) uint64 {
+	vectors := info.GetConfig().GetParams().GetVectorsConfig()
+	if params := vectors.GetParams(); params != nil {
+		return params.GetSize()
+	}
+	if named := vectors.GetParamsMap().GetMap(); len(named) == 1 {
+		for _, params := range named {
+			return params.GetSize()
+		}
+	}
+	return 0
+}
+
+// dimensionMismatch reports an error when vectors of collectionDimension
+// cannot be compared with embeddings of embedderDimension
+func dimensionMismatch(collection string, collectionDimension, embedderDimension uint64) error {
+	if collectionDimension == embedderDimension {
+		return nil
+	}
+	return fmt.Errorf("collection %s stores %d-dim vectors but the embedder produces %d-dim vectors; re-embedded metrics will not be comparable",
+		collection, collectionDimension, embedderDimension)
+}
+
+// ExportTrainingData exports successful interactions from RAG for training
+func (e *TrainingDataExporter) ExportTrainingData(ctx context.Context, collection string, minScore float64) ([]localmodels.TrainingExample, error) {
+	if err := e.checkCollectionDimension(ctx, collection); err != nil {
+		log.Printf("Warning: %v", err)
 	}
 
+	// Scroll every page of the collection, keeping documents with high scores
 	var examples []localmodels.TrainingExample
-	
-	for _, point := range searchResult {
-		example, err := e.extractTrainingExample(point, minScore)
+	var offset *qdrant.PointId
+	for {
+		points, next, err := e.service.scrollPage(ctx, &qdrant.ScrollPoints{
+			CollectionName: collection,
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(TrainingExportBatchSize)),
+			WithPayload:    qdrant.NewWithPayload(true),
+		})
 		if err != nil {
-			log.Printf("Skipping point %v: %v", point.Id, err)
-			continue
+			return nil, fmt.Errorf("failed to scroll collection %s: %w", collection, err)
 		}
-		
-		if example != nil {
-			examples = append(examples, *example)
+
+		for _, point := range points {
+			example, err := e.extractTrainingExample(point, minScore)
+			if err != nil {
+				log.Printf("Skipping point %v: %v", point.Id, err)
+				continue
+			}
+			
+			if example != nil {
+				examples = append(examples, *example)
+			}
+		}
+
+		if next == nil {
+			break
 		}
+		offset = next
 	}
 
 	log.Printf("Exported %d training examples from collection %s", len(examples), collection)
@@ -94,6 +171,11 @@ func (e *TrainingDataExporter) extractTrainingExample(point *qdrant.RetrievedPoi
 			score = doubleValue.DoubleValue
 		} else if intValue, ok := scoreField.GetKind().(*qdrant.Value_IntegerValue); ok {
 			score = float64(intValue.IntegerValue)
+		} else if stringValue, ok := scoreField.GetKind().(*qdrant.Value_StringValue); ok {
+			// Document metadata, such as orchestrator training samples, is stored as strings
+			if parsed, err := strconv.ParseFloat(stringValue.StringValue, 64); err == nil {
+				score = parsed
+			}
 		}
 	}
 
@@ -228,7 +310,7 @@ func (e *TrainingDataExporter) StoreTrainingMetrics(ctx context.Context, example
 	}
 
 	// Generate embedding for metrics (for future retrieval)
-	embedding := e.service.generateLocalEmbedding("training data quality metrics")
+	embedding := e.service.generateLocalEmbedding(ctx, "training data quality metrics")
 	if embedding == nil {
 		log.Printf("Warning: Could not generate embedding for training metrics")
 		return nil // Don't fail if embedding generation fails
@@ -248,7 +330,7 @@ func (e *TrainingDataExporter) StoreTrainingMetrics(ctx context.Context, example
 		}),
 	}
 
-	_, err = e.service.client.Upsert(ctx, &qdrant.UpsertPoints{
+	_, err = e.service.upsert(ctx, &qdrant.UpsertPoints{
 		CollectionName: "coding_standards",
 		Points:         []*qdrant.PointStruct{point},
 	})
@@ -256,6 +338,7 @@ func (e *TrainingDataExporter) StoreTrainingMetrics(ctx context.Context, example
 	if err != nil {
 		return fmt.Errorf("failed to store training metrics: %w", err)
 	}
+	e.service.searches.Invalidate("coding_standards")
 
 	log.Printf("Stored training metrics: %d examples, avg score %.3f", totalExamples, avgScore)
 	return nil
@@ -0,0 +1,89 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestExportTrainingDataReadsEveryPage(t *testing.T) {
+	tests := []struct {
+		name        string
+		count       int
+		wantScrolls int
+	}{
+		{"empty", 0, 1},
+		{"one page", 10, 1},
+		{"several pages", 2*TrainingExportBatchSize + 1, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payloads []map[string]any
+			for i := range tt.count {
+				payloads = append(payloads, map[string]any{
+					"input":  fmt.Sprintf("write example %d", i),
+					"output": fmt.Sprintf("example output %d", i),
+				})
+			}
+			fake, service := newFakeQdrant(t, payloads)
+
+			examples, err := NewTrainingDataExporter(service).ExportTrainingData(context.Background(), "training_samples", 0.5)
+			if err != nil {
+				t.Fatalf("ExportTrainingData: %v", err)
+			}
+			if len(examples) != tt.count {
+				t.Errorf("exported %d examples, want %d", len(examples), tt.count)
+			}
+			if fake.scrolls != tt.wantScrolls {
+				t.Errorf("scrolled %d pages, want %d", fake.scrolls, tt.wantScrolls)
+			}
+		})
+	}
+}
+
+func TestCheckCollectionDimension(t *testing.T) {
+	tests := []struct {
+		name       string
+		vectorSize uint64
+		wantErr    bool
+	}{
+		{"matching", VectorDimension, false},
+		{"smaller collection", 384, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, service := newFakeQdrant(t, nil)
+			fake.vectorSize = tt.vectorSize
+			exporter := NewTrainingDataExporter(service)
+
+			err := exporter.checkCollectionDimension(context.Background(), "training_samples")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkCollectionDimension() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !strings.Contains(err.Error(), fmt.Sprintf("stores %d-dim vectors", tt.vectorSize)) {
+				t.Errorf("mismatch warning %q does not name the collection dimension", err)
+			}
+			if dimension, ok := exporter.CollectionDimension("training_samples"); !ok || dimension != tt.vectorSize {
+				t.Errorf("CollectionDimension() = %d, %v; want %d recorded", dimension, ok, tt.vectorSize)
+			}
+		})
+	}
+}
+
+func TestExportTrainingDataWithMismatchedDimension(t *testing.T) {
+	fake, service := newFakeQdrant(t, []map[string]any{{"input": "write example 1", "output": "example output 1"}})
+	fake.vectorSize = 384
+	exporter := NewTrainingDataExporter(service)
+
+	// A mismatch is only a warning; the export still runs
+	examples, err := exporter.ExportTrainingData(context.Background(), "training_samples", 0.5)
+	if err != nil || len(examples) != 1 {
+		t.Fatalf("ExportTrainingData() = %d examples, %v; want 1", len(examples), err)
+	}
+	if dimension, _ := exporter.CollectionDimension("training_samples"); dimension != 384 {
+		t.Errorf("CollectionDimension() = %d, want 384", dimension)
+	}
+}
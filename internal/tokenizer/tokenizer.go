@@ -0,0 +1,17 @@
+package tokenizer
+
+import "strings"
+
+// Estimate approximates the token count of text without a model vocabulary.
+// Subword tokenizers produce roughly four tokens for every three words of
+// English prose.
+func Estimate(text string) int {
+	words := len(strings.Fields(text))
+	return (words*4 + 2) / 3
+}
+
+// WordsForTokens returns how many words fit in the given token count under
+// the same ratio Estimate uses
+func WordsForTokens(tokens int) int {
+	return tokens * 3 / 4
+}
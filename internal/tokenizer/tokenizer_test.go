@@ -0,0 +1,34 @@
+package tokenizer
+
+import "testing"
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"one", 2},
+		{"one two three", 4},
+		{"  spread \n across\tlines  ", 4},
+	}
+
+	for _, tt := range tests {
+		if got := Estimate(tt.text); got != tt.want {
+			t.Errorf("Estimate(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWordsForTokens(t *testing.T) {
+	for _, tokens := range []int{0, 4, 100, 8192} {
+		words := WordsForTokens(tokens)
+		text := ""
+		for range words {
+			text += "word "
+		}
+		if got := Estimate(text); got > tokens {
+			t.Errorf("%d words estimate to %d tokens, more than %d", words, got, tokens)
+		}
+	}
+}
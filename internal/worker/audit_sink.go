@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/ai"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// DefaultAuditCollection is the knowledge base collection audited AI interactions are stored in
+const DefaultAuditCollection = "ai_audit"
+
+// KnowledgeAuditSink stores redacted AI interactions as searchable knowledge base documents
+type KnowledgeAuditSink struct {
+	store      DocumentStore
+	collection string
+	workerID   string
+}
+
+// NewKnowledgeAuditSink creates a sink writing to collection; an empty
+// collection uses DefaultAuditCollection
+func NewKnowledgeAuditSink(store DocumentStore, collection, workerID string) *KnowledgeAuditSink {
+	if collection == "" {
+		collection = DefaultAuditCollection
+	}
+	return &KnowledgeAuditSink{store: store, collection: collection, workerID: workerID}
+}
+
+// RecordInteraction stores the interaction transcript with its provider and usage as metadata
+func (s *KnowledgeAuditSink) RecordInteraction(ctx context.Context, interaction ai.Interaction) error {
+	var transcript strings.Builder
+	for _, message := range interaction.Messages {
+		fmt.Fprintf(&transcript, "[%s]\n%s\n\n", message.Role, message.Content)
+	}
+	if interaction.Error != "" {
+		fmt.Fprintf(&transcript, "[error]\n%s\n", interaction.Error)
+	} else {
+		fmt.Fprintf(&transcript, "[response]\n%s\n", interaction.Response)
+	}
+
+	doc := types.RAGDocument{
+		Content: transcript.String(),
+		Source:  fmt.Sprintf("%s/%s", interaction.Provider, interaction.Model),
+		Metadata: map[string]string{
+			"type":         "ai_interaction",
+			"worker_id":    s.workerID,
+			"provider":     interaction.Provider,
+			"model":        interaction.Model,
+			"created_at":   interaction.CreatedAt.UTC().Format(time.RFC3339),
+			"total_tokens": strconv.Itoa(interaction.Usage.TotalTokens),
+			"cost_usd":     strconv.FormatFloat(interaction.Usage.CostUSD, 'f', 6, 64),
+			"failed":       strconv.FormatBool(interaction.Error != ""),
+		},
+	}
+
+	if err := s.store.AddDocument(ctx, s.collection, doc); err != nil {
+		return fmt.Errorf("failed to store audit record in %s: %w", s.collection, err)
+	}
+	return nil
+}
+
+// SetAuditSink records this processor's AI interactions, redacted, to sink
+func (p *RoleBasedProcessor) SetAuditSink(sink ai.AuditSink) {
+	if p.aiClient != nil {
+		p.aiClient.SetAuditSink(sink)
+	}
+}
+
+// WaitForAudits blocks until this processor's pending audit records are stored
+func (p *RoleBasedProcessor) WaitForAudits() {
+	if p.aiClient != nil {
+		p.aiClient.WaitForAudits()
+	}
+}
@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/ai"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// memoryStore keeps added documents per collection
+type memoryStore struct {
+	mu   sync.Mutex
+	docs map[string][]types.RAGDocument
+}
+
+func (s *memoryStore) AddDocument(ctx context.Context, collection string, doc types.RAGDocument) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.docs == nil {
+		s.docs = make(map[string][]types.RAGDocument)
+	}
+	s.docs[collection] = append(s.docs[collection], doc)
+	return nil
+}
+
+func TestKnowledgeAuditSinkRecordsInteraction(t *testing.T) {
+	tests := []struct {
+		name        string
+		interaction ai.Interaction
+		want        string
+		failed      string
+	}{
+		{"response", ai.Interaction{Provider: "groq", Model: "m", Response: "answer"}, "[response]\nanswer", "false"},
+		{"error", ai.Interaction{Provider: "groq", Model: "m", Error: "HTTP 500"}, "[error]\nHTTP 500", "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memoryStore{}
+			sink := NewKnowledgeAuditSink(store, "", "worker-1")
+			tt.interaction.Messages = []ai.Message{{Role: "user", Content: "question"}}
+			tt.interaction.CreatedAt = time.Now()
+
+			if err := sink.RecordInteraction(context.Background(), tt.interaction); err != nil {
+				t.Fatalf("RecordInteraction: %v", err)
+			}
+
+			docs := store.docs[DefaultAuditCollection]
+			if len(docs) != 1 {
+				t.Fatalf("stored %d documents, want 1", len(docs))
+			}
+			if !strings.Contains(docs[0].Content, "[user]\nquestion") || !strings.Contains(docs[0].Content, tt.want) {
+				t.Errorf("Content = %q, want %q", docs[0].Content, tt.want)
+			}
+			if docs[0].Metadata["failed"] != tt.failed || docs[0].Metadata["worker_id"] != "worker-1" {
+				t.Errorf("Metadata = %v", docs[0].Metadata)
+			}
+		})
+	}
+}
+
+func TestProcessorAuditsAPIRequests(t *testing.T) {
+	_, server := newFakeDaemon(t, failingPrediction)
+	_, config := newFakeProvider(t, "audited reply")
+	processor := NewRoleBasedProcessor(types.RoleDeveloper, nil, newDaemonManager(t, server.URL, nil), nil, config)
+	store := &memoryStore{}
+	processor.SetAuditSink(NewKnowledgeAuditSink(store, "", "worker-1"))
+
+	if _, err := processor.ProcessWorkflowTask(context.Background(), NewSelfTestTask(types.RoleDeveloper)); err != nil {
+		t.Fatalf("ProcessWorkflowTask: %v", err)
+	}
+	processor.WaitForAudits()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	docs := store.docs[DefaultAuditCollection]
+	if len(docs) == 0 || !strings.Contains(docs[0].Content, "audited reply") {
+		t.Errorf("audit documents = %+v", docs)
+	}
+}
@@ -3,7 +3,9 @@ package worker
 import (
 	"context"
 	"fmt"
+	"log"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
@@ -13,6 +15,8 @@ import (
 // ContentAnalyzer analyzes task content to determine optimal model routing
 type ContentAnalyzer struct {
 	modelConfigs map[string]localmodels.ModelConfig
+	aliases      localmodels.ModelAliases
+	preferences  localmodels.RolePreferences
 }
 
 // NewContentAnalyzer creates a new content analyzer
@@ -22,6 +26,16 @@ func NewContentAnalyzer(modelConfigs map[string]localmodels.ModelConfig) *Conten
 	}
 }
 
+// SetAliases sets the friendly model names resolved before configured models are matched
+func (ca *ContentAnalyzer) SetAliases(aliases localmodels.ModelAliases) {
+	ca.aliases = aliases
+}
+
+// SetRolePreferences sets the ordered models each worker role prefers
+func (ca *ContentAnalyzer) SetRolePreferences(preferences localmodels.RolePreferences) {
+	ca.preferences = preferences
+}
+
 // AnalysisResult contains the routing decision and reasoning
 type AnalysisResult struct {
 	RecommendedModel  string   `json:"recommended_model"`
@@ -209,11 +223,92 @@ func (ca *ContentAnalyzer) routeTask(contentType, complexity, content string, ta
 	// Adjust for worker role if needed
 	result = ca.adjustForWorkerRole(result, task.RequiredRole)
 
+	// Never recommend an image model that is not configured
+	if contentType == "multimodal" {
+		ca.validateMultimodal(result)
+	}
+
 	return result
 }
 
-// adjustForWorkerRole adjusts the model recommendation based on worker role
+// ModelType resolves name to a configured model and returns that model's name
+// and type. Names match exactly or as a prefix (qwen-vl matches qwen-vl-7b).
+func (ca *ContentAnalyzer) ModelType(name string) (string, localmodels.ModelType, bool) {
+	name = ca.aliases.Resolve(name)
+	if config, exists := ca.modelConfigs[name]; exists {
+		return name, config.Type, true
+	}
+	for _, configured := range ca.modelNames() {
+		if strings.HasPrefix(configured, name) {
+			return configured, ca.modelConfigs[configured].Type, true
+		}
+	}
+	return "", "", false
+}
+
+// ModelsOfType returns the configured models of a type, sorted by name
+func (ca *ContentAnalyzer) ModelsOfType(modelType localmodels.ModelType) []string {
+	var names []string
+	for _, name := range ca.modelNames() {
+		if ca.modelConfigs[name].Type == modelType {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// modelNames returns the configured model names in sorted order
+func (ca *ContentAnalyzer) modelNames() []string {
+	names := make([]string, 0, len(ca.modelConfigs))
+	for name := range ca.modelConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateMultimodal points the recommendation at a configured multimodal
+// model, preferring the recommended one and then its alternatives. With no
+// multimodal model configured the task is downgraded to a text model.
+func (ca *ContentAnalyzer) validateMultimodal(result *AnalysisResult) {
+	candidates := append([]string{result.RecommendedModel}, result.AlternativeModels...)
+	candidates = append(candidates, ca.ModelsOfType(localmodels.ModelTypeMultimodal)...)
+	for _, candidate := range candidates {
+		if name, modelType, exists := ca.ModelType(candidate); exists && modelType == localmodels.ModelTypeMultimodal {
+			result.RecommendedModel = name
+			return
+		}
+	}
+
+	textModel := "qwen-omni"
+	if name, modelType, exists := ca.ModelType(textModel); exists && modelType == localmodels.ModelTypeText {
+		textModel = name
+	} else if textModels := ca.ModelsOfType(localmodels.ModelTypeText); len(textModels) > 0 {
+		textModel = textModels[0]
+	}
+
+	log.Printf("Warning: multimodal content detected but no multimodal model is configured, downgrading to text model %s", textModel)
+	result.RecommendedModel = textModel
+	result.AlternativeModels = nil
+	result.Confidence = 0.5
+	result.Reasoning += fmt.Sprintf(" - Downgraded to text model %s: no multimodal model configured", textModel)
+}
+
+// adjustForWorkerRole adjusts the model recommendation based on worker role.
+// A role with configured preferences gets its first preferred model of the
+// type the content needs, the rest of its preferences as alternatives.
 func (ca *ContentAnalyzer) adjustForWorkerRole(result *AnalysisResult, role types.WorkerRole) *AnalysisResult {
+	modelType := localmodels.ModelTypeText
+	if result.ContentType == "multimodal" {
+		modelType = localmodels.ModelTypeMultimodal
+	}
+	if preferred := preferredModels(ca.preferences, role, modelType, ca.ModelType); len(preferred) > 0 {
+		result.RecommendedModel = preferred[0]
+		result.AlternativeModels = preferred[1:]
+		result.Reasoning += fmt.Sprintf(" - Adjusted for %s role preference", role)
+		return result
+	}
+
 	switch role {
 	case types.RoleTester:
 		// Testers often need quick, simple analysis
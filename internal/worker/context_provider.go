@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"context"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/rag"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// ContextProvider is the knowledge-base abstraction the processor depends on,
+// allowing Qdrant, in-memory, or stub backends to be injected
+type ContextProvider interface {
+	// GetRelevantContext returns prompt-ready context for a task
+	GetRelevantContext(ctx context.Context, taskType, content string) (string, error)
+
+	// GetRelevantDocuments returns the retrieved documents with their sources
+	GetRelevantDocuments(ctx context.Context, taskType, content string) ([]types.RAGDocument, error)
+
+	// SearchKnowledge runs a structured query against a collection
+	SearchKnowledge(ctx context.Context, query types.RAGQuery) (*types.RAGResponse, error)
+
+	// IsAvailable reports whether the backend can currently serve requests
+	IsAvailable(ctx context.Context) bool
+}
+
+// DocumentStore is implemented by knowledge bases that accept new documents
+type DocumentStore interface {
+	// AddDocument embeds a document and stores it in collection
+	AddDocument(ctx context.Context, collection string, doc types.RAGDocument) error
+}
+
+// SystemPromptProvider is implemented by knowledge bases that store a system prompt per worker role
+type SystemPromptProvider interface {
+	// GetSystemPrompt returns the stored system prompt for role
+	GetSystemPrompt(ctx context.Context, role types.WorkerRole) (string, error)
+}
+
+// Compile-time checks that the RAG backends satisfy ContextProvider and DocumentStore
+var (
+	_ ContextProvider = (*rag.Service)(nil)
+	_ ContextProvider = (*rag.MemoryService)(nil)
+	_ DocumentStore   = (*rag.Service)(nil)
+	_ DocumentStore   = (*rag.MemoryService)(nil)
+
+	_ SystemPromptProvider = (*rag.Service)(nil)
+	_ SystemPromptProvider = (*rag.MemoryService)(nil)
+)
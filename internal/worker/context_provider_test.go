@@ -0,0 +1,138 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// stubContextProvider serves fixed documents and a fixed system prompt
+type stubContextProvider struct {
+	documents    []types.RAGDocument
+	systemPrompt string
+	err          error
+}
+
+func (s stubContextProvider) GetRelevantContext(context.Context, string, string) (string, error) {
+	return "", s.err
+}
+
+func (s stubContextProvider) GetRelevantDocuments(context.Context, string, string) ([]types.RAGDocument, error) {
+	return s.documents, s.err
+}
+
+func (s stubContextProvider) SearchKnowledge(context.Context, types.RAGQuery) (*types.RAGResponse, error) {
+	return &types.RAGResponse{Documents: s.documents}, s.err
+}
+
+func (s stubContextProvider) IsAvailable(context.Context) bool { return s.err == nil }
+
+func (s stubContextProvider) GetSystemPrompt(context.Context, types.WorkerRole) (string, error) {
+	return s.systemPrompt, s.err
+}
+
+func TestBuildTaskContextUsesProvider(t *testing.T) {
+	tests := []struct {
+		name         string
+		provider     ContextProvider
+		role         types.WorkerRole
+		wantPrompt   string
+		wantContains string
+	}{
+		{"documents with sources", stubContextProvider{
+			documents:    []types.RAGDocument{{Content: "Wrap errors with %w.", Source: "effective_go.md"}},
+			systemPrompt: "You are a Go developer.",
+		}, types.RoleDeveloper, "You are a Go developer.", "effective_go.md"},
+		{"retrieval fails", stubContextProvider{err: errors.New("qdrant down")}, types.RoleDeveloper, "", ""},
+		{"no provider", nil, types.RoleDeveloper, "", ""},
+		{"role without RAG", stubContextProvider{
+			documents: []types.RAGDocument{{Content: "unused"}},
+		}, types.RoleTester, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewRoleBasedProcessor(tt.role, tt.provider, nil, nil, nil)
+			task := newDocumentTask(tt.role, "api_guide", "")
+
+			taskContext := processor.buildTaskContext(context.Background(), task)
+			if taskContext.SystemPrompt != tt.wantPrompt {
+				t.Errorf("SystemPrompt = %q, want %q", taskContext.SystemPrompt, tt.wantPrompt)
+			}
+			if tt.wantContains == "" {
+				if taskContext.RAGContext != "" || task.Payload["rag_context"] != "" {
+					t.Errorf("RAGContext = %q, want none", taskContext.RAGContext)
+				}
+				return
+			}
+			if !strings.Contains(taskContext.RAGContext, tt.wantContains) {
+				t.Errorf("RAGContext = %q, want it to contain %q", taskContext.RAGContext, tt.wantContains)
+			}
+			if task.Payload["rag_context"] != taskContext.RAGContext {
+				t.Errorf("payload rag_context = %q, want the RAG context", task.Payload["rag_context"])
+			}
+		})
+	}
+}
+
+// slowContextProvider blocks every retrieval until its context ends
+type slowContextProvider struct{}
+
+func (slowContextProvider) GetRelevantContext(ctx context.Context, _, _ string) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func (slowContextProvider) GetRelevantDocuments(ctx context.Context, _, _ string) ([]types.RAGDocument, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (slowContextProvider) SearchKnowledge(ctx context.Context, _ types.RAGQuery) (*types.RAGResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (slowContextProvider) IsAvailable(context.Context) bool { return true }
+
+func TestSlowRetrievalIsBounded(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		fraction float64
+	}{
+		{"fixed timeout", 50 * time.Millisecond, 0},
+		{"share of deadline", 0, 0.02}, // 2% of the 5s task deadline
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon, server := newFakeDaemon(t, echoPrediction("OK"))
+			processor := NewRoleBasedProcessor(types.RoleDeveloper, slowContextProvider{}, newDaemonManager(t, server.URL, nil), nil, nil)
+			retrieval := config.DefaultRetrievalConfig()
+			retrieval.Timeout = tt.timeout
+			retrieval.TimeoutFraction = tt.fraction
+			processor.SetRetrievalConfig(retrieval)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			start := time.Now()
+			outcome, err := processor.ProcessWorkflowTask(ctx, newDocumentTask(types.RoleDeveloper, "api_guide", ""))
+			if err != nil {
+				t.Fatalf("ProcessWorkflowTask: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("task took %v, want retrieval cut short", elapsed)
+			}
+			if outcome.Output != "OK" || len(daemon.prompts("qwen-omni-3b")) != 1 {
+				t.Errorf("Output = %q, want the model to run after retrieval timed out", outcome.Output)
+			}
+		})
+	}
+}
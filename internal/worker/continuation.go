@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"context"
+	"log"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/ai"
+)
+
+// DefaultMaxContinuations is how many follow-up generations extend an output
+// truncated at the token limit
+const DefaultMaxContinuations = 2
+
+// continuationPrompt asks a chat model to resume its truncated answer
+const continuationPrompt = "Your previous response was cut off at the token limit. Continue exactly where it stopped, without repeating any of it or adding commentary."
+
+// generation is one model pass: its output and why it stopped
+type generation struct {
+	Text         string
+	FinishReason string
+	Truncated    bool // Stopped at the token limit
+}
+
+// generateFunc runs a continuation pass given the output generated so far
+type generateFunc func(ctx context.Context, prior string) (generation, error)
+
+// continueTruncated extends a first pass that stopped at the token limit
+// with up to maxContinuations passes, each given everything generated so
+// far, and concatenates their output. A failed continuation keeps what was
+// generated, which stays marked truncated.
+func continueTruncated(ctx context.Context, first generation, maxContinuations int, generate generateFunc) generation {
+	result := first
+	for pass := 1; pass <= maxContinuations && result.Truncated; pass++ {
+		next, err := generate(ctx, result.Text)
+		if err != nil {
+			log.Printf("Warning: continuation %d/%d of truncated output failed, keeping %d characters: %v",
+				pass, maxContinuations, len(result.Text), err)
+			break
+		}
+		result.Text += next.Text
+		result.FinishReason = next.FinishReason
+		result.Truncated = next.Truncated
+	}
+	return result
+}
+
+// continuationMessages extends the original request with the truncated
+// answer and a request to resume it
+func continuationMessages(messages []ai.Message, prior string) []ai.Message {
+	if prior == "" {
+		return messages
+	}
+	return append(append([]ai.Message(nil), messages...),
+		ai.Message{Role: "assistant", Content: prior},
+		ai.Message{Role: "user", Content: continuationPrompt})
+}
+
+// addUsage sums the token usage and cost of two passes
+func addUsage(total, pass ai.TokenUsage) ai.TokenUsage {
+	return ai.TokenUsage{
+		InputTokens:  total.InputTokens + pass.InputTokens,
+		OutputTokens: total.OutputTokens + pass.OutputTokens,
+		TotalTokens:  total.TotalTokens + pass.TotalTokens,
+		CostUSD:      total.CostUSD + pass.CostUSD,
+	}
+}
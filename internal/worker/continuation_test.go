@@ -0,0 +1,191 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/ai"
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+func TestContinueTruncated(t *testing.T) {
+	truncated := func(text string) generation {
+		return generation{Text: text, FinishReason: "length", Truncated: true}
+	}
+	complete := func(text string) generation {
+		return generation{Text: text, FinishReason: "stop"}
+	}
+
+	tests := []struct {
+		name             string
+		first            generation
+		passes           []generation // Continuation replies in order
+		failAt           int          // Continuation that fails, counting from 1; zero never fails
+		maxContinuations int
+		want             generation
+		wantPriors       []string
+	}{
+		{
+			name:             "complete first pass",
+			first:            complete("All done."),
+			maxContinuations: 2,
+			want:             complete("All done."),
+		},
+		{
+			name:             "continued to completion",
+			first:            truncated("The quick brown"),
+			passes:           []generation{truncated(" fox jumps"), complete(" over the dog.")},
+			maxContinuations: 2,
+			want:             complete("The quick brown fox jumps over the dog."),
+			wantPriors:       []string{"The quick brown", "The quick brown fox jumps"},
+		},
+		{
+			name:             "still truncated after the limit",
+			first:            truncated("a"),
+			passes:           []generation{truncated("b"), truncated("c")},
+			maxContinuations: 1,
+			want:             truncated("ab"),
+			wantPriors:       []string{"a"},
+		},
+		{
+			name:             "failed continuation keeps the output",
+			first:            truncated("a"),
+			passes:           []generation{truncated("b")},
+			failAt:           2,
+			maxContinuations: 3,
+			want:             truncated("ab"),
+			wantPriors:       []string{"a", "ab"},
+		},
+		{
+			name:             "continuation disabled",
+			first:            truncated("a"),
+			maxContinuations: 0,
+			want:             truncated("a"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var priors []string
+			got := continueTruncated(context.Background(), tt.first, tt.maxContinuations, func(ctx context.Context, prior string) (generation, error) {
+				priors = append(priors, prior)
+				if len(priors) == tt.failAt {
+					return generation{}, errors.New("model crashed")
+				}
+				return tt.passes[len(priors)-1], nil
+			})
+
+			if got != tt.want {
+				t.Errorf("continueTruncated() = %+v, want %+v", got, tt.want)
+			}
+			if strings.Join(priors, "|") != strings.Join(tt.wantPriors, "|") {
+				t.Errorf("continuations were given %q, want %q", priors, tt.wantPriors)
+			}
+		})
+	}
+}
+
+func TestContinuationMessages(t *testing.T) {
+	messages := []ai.Message{{Role: "system", Content: "Be brief."}, {Role: "user", Content: "Write a guide."}}
+
+	if got := continuationMessages(messages, ""); len(got) != 2 {
+		t.Errorf("continuationMessages() without output = %+v, want the request unchanged", got)
+	}
+
+	got := continuationMessages(messages, "# Guide\nStep 1")
+	if len(got) != 4 || got[2] != (ai.Message{Role: "assistant", Content: "# Guide\nStep 1"}) || got[3].Content != continuationPrompt {
+		t.Errorf("continuationMessages() = %+v, want the output then a request to continue", got)
+	}
+	if len(messages) != 2 {
+		t.Errorf("continuationMessages() modified the original request: %+v", messages)
+	}
+}
+
+func TestLocalTruncatedOutputIsContinued(t *testing.T) {
+	var mu sync.Mutex
+	replies := []localmodels.ModelOutput{
+		{Text: "# Guide\n", FinishReason: localmodels.FinishReasonLength, Truncated: true},
+		{Text: "Step 1. Install.", FinishReason: localmodels.FinishReasonStop},
+	}
+	daemon, server := newFakeDaemon(t, func(string, localmodels.ModelInput) (localmodels.ModelOutput, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		reply := replies[0]
+		replies = replies[1:]
+		return reply, http.StatusOK
+	})
+	processor := NewRoleBasedProcessor(types.RoleDeveloper, nil, newDaemonManager(t, server.URL, nil), nil, nil)
+
+	outcome, err := processor.ProcessWorkflowTask(context.Background(), newDocumentTask(types.RoleDeveloper, "release_notes", ""))
+	if err != nil {
+		t.Fatalf("ProcessWorkflowTask() error = %v", err)
+	}
+	if outcome.Output != "# Guide\nStep 1. Install." || outcome.FinishReason != localmodels.FinishReasonStop {
+		t.Errorf("outcome = %q (%s), want both passes joined", outcome.Output, outcome.FinishReason)
+	}
+
+	prompts := daemon.prompts("qwen-omni-3b")
+	if len(prompts) != 2 || prompts[1] != prompts[0]+"# Guide\n" {
+		t.Errorf("daemon got prompts %q, want the continuation to resume after the first pass", prompts)
+	}
+}
+
+func TestAPITruncatedOutputIsContinued(t *testing.T) {
+	var mu sync.Mutex
+	var requests []ai.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ai.ChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		requests = append(requests, request)
+		pass := len(requests)
+		mu.Unlock()
+
+		content, reason := "# Guide\n", "length"
+		if pass > 1 {
+			content, reason = "Step 1. Install.", "stop"
+		}
+		fmt.Fprintf(w, `{"model":"test-model","choices":[{"message":{"role":"assistant","content":%q},"finish_reason":%q}],"usage":{"prompt_tokens":5,"completion_tokens":3,"total_tokens":8}}`, content, reason)
+	}))
+	t.Cleanup(server.Close)
+
+	_, config := newFakeProvider(t, "unused")
+	config.Groq.APIURL = server.URL
+
+	task := NewSelfTestTask(types.RoleDeveloper)
+	task.ForceProvider = "groq"
+	execution, err := NewTaskRouter(nil, config).RouteTask(context.Background(), task)
+	if err != nil {
+		t.Fatalf("RouteTask() error = %v", err)
+	}
+
+	output, err := execution.Execute(context.Background(), nil, ai.NewAIClientWithConfig(config))
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if output != "# Guide\nStep 1. Install." || execution.FinishReason != "stop" {
+		t.Errorf("Execute() = %q (%s), want both passes joined", output, execution.FinishReason)
+	}
+	if execution.Usage.TotalTokens != 16 {
+		t.Errorf("usage = %d tokens, want both passes counted", execution.Usage.TotalTokens)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("provider got %d requests, want 2", len(requests))
+	}
+	continuation := requests[1].Messages
+	if n := len(continuation); n < 2 || continuation[n-2].Content != "# Guide\n" || continuation[n-1].Content != continuationPrompt {
+		t.Errorf("continuation request = %+v, want the first pass then a request to continue", continuation)
+	}
+}
@@ -0,0 +1,108 @@
+package worker
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log/slog"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/ai"
+	"github.com/niko/mqtt-agent-orchestration/internal/tokenizer"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// estimatedSpend totals the projected cost of the tasks run (exported via expvar)
+var estimatedSpend = expvar.NewFloat("worker_estimated_cost_usd")
+
+// CostEstimate is the projected cost of running a task, computed without executing it
+type CostEstimate struct {
+	Strategy     string  `json:"strategy"`
+	Complexity   string  `json:"complexity"`
+	Provider     string  `json:"provider,omitempty"`
+	Model        string  `json:"model"`
+	InputTokens  int     `json:"input_tokens"`  // Estimated from the assembled prompt
+	OutputTokens int     `json:"output_tokens"` // The provider's max_tokens, an upper bound
+	CostUSD      float64 `json:"cost_usd"`      // Zero for local models
+	Priced       bool    `json:"priced"`        // False when the provider has no pricing configured
+	Reasoning    string  `json:"reasoning"`
+}
+
+// EstimateCost routes task the way RouteTask would and projects its token
+// usage and cost from the prompt that would be sent, without running it or
+// counting it in the routing statistics
+func (tr *TaskRouter) EstimateCost(ctx context.Context, task *types.WorkflowTask) (CostEstimate, error) {
+	complexity := tr.analyzeTaskComplexity(task)
+
+	execution, err := tr.route(ctx, task, complexity)
+	if err != nil {
+		return CostEstimate{}, fmt.Errorf("failed to route task %s: %w", task.ID, err)
+	}
+	execution.Complexity = complexity
+	execution.PromptBudget = tr.promptBudget
+	execution.Prompts = tr.prompts
+	return execution.EstimateCost(), nil
+}
+
+// EstimateCost projects the token usage and cost of the execution plan from
+// the prompt it would send
+func (te *TaskExecution) EstimateCost() CostEstimate {
+	estimate := CostEstimate{
+		Strategy:   te.Strategy.String(),
+		Complexity: te.Complexity.String(),
+		Reasoning:  te.Reasoning,
+	}
+
+	switch te.Strategy {
+	case ExecutionStrategyAPI:
+		prompt := te.Prompt
+		if prompt == "" {
+			prompt = te.buildDetailedPrompt()
+		}
+		apiConfig := te.APIConfig
+		estimate.Provider = te.APIProvider
+		if len(apiConfig.Models) > 0 {
+			estimate.Model = apiConfig.Models[0]
+		}
+		estimate.InputTokens = tokenizer.Estimate(prompt)
+		estimate.OutputTokens = apiConfig.MaxTokens
+		estimate.CostUSD = apiConfig.CostUSD(ai.TokenUsage{
+			InputTokens:  estimate.InputTokens,
+			OutputTokens: estimate.OutputTokens,
+		})
+		_, estimate.Priced = apiConfig.PricePerKTokens()
+	default:
+		prompt := te.Prompt
+		if prompt == "" {
+			prompt = te.buildLocalPrompt()
+		}
+		estimate.Model = te.ModelName
+		estimate.InputTokens = tokenizer.Estimate(prompt)
+		estimate.OutputTokens = te.getMaxTokensForTask()
+		estimate.Priced = true
+	}
+	return estimate
+}
+
+// logCostEstimate logs the projected cost of a task about to run, so it can
+// be compared with the usage the provider reports, and adds it to the
+// estimated spend counter
+func logCostEstimate(task *types.WorkflowTask, estimate CostEstimate) {
+	estimatedSpend.Add(estimate.CostUSD)
+
+	slog.Info("task cost estimate",
+		"task_id", task.ID,
+		"workflow_id", task.WorkflowID,
+		"strategy", estimate.Strategy,
+		"provider", estimate.Provider,
+		"model", estimate.Model,
+		"input_tokens", estimate.InputTokens,
+		"output_tokens", estimate.OutputTokens,
+		"cost_usd", estimate.CostUSD,
+		"priced", estimate.Priced,
+	)
+}
+
+// EstimateCost projects the cost of a workflow task without executing it
+func (p *RoleBasedProcessor) EstimateCost(ctx context.Context, task *types.WorkflowTask) (CostEstimate, error) {
+	return p.taskRouter.EstimateCost(ctx, task)
+}
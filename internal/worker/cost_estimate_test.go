@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/tokenizer"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+func TestEstimateCost(t *testing.T) {
+	provider, aiConfig := newFakeProvider(t, "unused")
+	aiConfig.Groq.PricePerKInputTokens = 1.0
+	aiConfig.Groq.PricePerKOutputTokens = 2.0
+
+	tests := []struct {
+		name     string
+		taskType string
+		local    bool
+		strategy string
+		priced   bool
+	}{
+		{"complex task uses the API", "architecture_security_review", false, "api", true},
+		{"simple task stays local", "echo_status", true, "local", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewTaskRouter(nil, aiConfig)
+			if tt.local {
+				router = NewTaskRouter(newDaemonManager(t, "http://127.0.0.1:0", nil), aiConfig)
+			}
+			task := NewSelfTestTask(types.RoleDeveloper)
+			task.Type = tt.taskType
+
+			estimate, err := router.EstimateCost(context.Background(), task)
+			if err != nil {
+				t.Fatalf("EstimateCost: %v", err)
+			}
+			if estimate.Strategy != tt.strategy || estimate.Priced != tt.priced || estimate.InputTokens <= 0 {
+				t.Fatalf("estimate = %+v", estimate)
+			}
+
+			if tt.local {
+				if estimate.CostUSD != 0 {
+					t.Errorf("local CostUSD = %v, want 0", estimate.CostUSD)
+				}
+				return
+			}
+			want := (float64(estimate.InputTokens)*1.0 + float64(estimate.OutputTokens)*2.0) / 1000
+			if estimate.Provider != "groq" || estimate.Model != "test-model" || math.Abs(estimate.CostUSD-want) > 1e-9 {
+				t.Errorf("estimate = %+v, want groq/test-model costing %v", estimate, want)
+			}
+		})
+	}
+
+	if got := provider.requests.Load(); got != 0 {
+		t.Errorf("estimating sent %d requests, want none", got)
+	}
+}
+
+func TestEstimateCostUsesStagePrompt(t *testing.T) {
+	execution := &TaskExecution{Strategy: ExecutionStrategyLocal, ModelName: "qwen-omni-3b", Task: NewSelfTestTask(types.RoleDeveloper)}
+	generic := execution.EstimateCost()
+
+	execution.Prompt = strings.Repeat("a stage prompt much longer than the generic one ", 100)
+	staged := execution.EstimateCost()
+	if staged.InputTokens != tokenizer.Estimate(execution.Prompt) || staged.InputTokens <= generic.InputTokens {
+		t.Errorf("staged input tokens = %d, generic = %d", staged.InputTokens, generic.InputTokens)
+	}
+}
@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+)
+
+// fakeDaemon is a model daemon serving canned predictions so tests run the
+// local execution path without llama.cpp
+type fakeDaemon struct {
+	mu      sync.Mutex
+	inputs  map[string][]localmodels.ModelInput // Predict inputs per model
+	predict func(model string, input localmodels.ModelInput) (localmodels.ModelOutput, int)
+}
+
+// newFakeDaemon starts a daemon answering every prediction with predict
+func newFakeDaemon(t *testing.T, predict func(model string, input localmodels.ModelInput) (localmodels.ModelOutput, int)) (*fakeDaemon, *httptest.Server) {
+	t.Helper()
+	daemon := &fakeDaemon{inputs: make(map[string][]localmodels.ModelInput), predict: predict}
+	server := httptest.NewServer(http.HandlerFunc(daemon.serve))
+	t.Cleanup(server.Close)
+	return daemon, server
+}
+
+// serve handles /models/{name}/{action}
+func (d *fakeDaemon) serve(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "models" {
+		http.NotFound(w, r)
+		return
+	}
+
+	model, action := parts[1], parts[2]
+	if action != "predict" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var input localmodels.ModelInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d.mu.Lock()
+	d.inputs[model] = append(d.inputs[model], input)
+	d.mu.Unlock()
+
+	output, status := d.predict(model, input)
+	if status != http.StatusOK {
+		http.Error(w, "prediction failed", status)
+		return
+	}
+	json.NewEncoder(w).Encode(output)
+}
+
+// prompts returns the prompt texts sent to model
+func (d *fakeDaemon) prompts(model string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var texts []string
+	for _, input := range d.inputs[model] {
+		texts = append(texts, input.Text)
+	}
+	return texts
+}
+
+// newDaemonManager creates a model manager backed by the daemon at url
+func newDaemonManager(t *testing.T, url string, models map[string]localmodels.ModelConfig) *localmodels.Manager {
+	t.Helper()
+	manager, err := localmodels.NewManager(localmodels.ModelManagerConfig{DaemonURL: url, Models: models})
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return manager
+}
+
+// echoPrediction answers with a fixed text
+func echoPrediction(text string) func(string, localmodels.ModelInput) (localmodels.ModelOutput, int) {
+	return func(string, localmodels.ModelInput) (localmodels.ModelOutput, int) {
+		return localmodels.ModelOutput{Text: text, FinishReason: localmodels.FinishReasonStop}, http.StatusOK
+	}
+}
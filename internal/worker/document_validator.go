@@ -0,0 +1,96 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
+	"github.com/niko/mqtt-agent-orchestration/internal/worker/markdown"
+)
+
+// DocumentValidator checks documents of one type for the tester role. Each
+// method returns the problems found, phrased as feedback for the developer;
+// an empty result means the check passed.
+type DocumentValidator interface {
+	ValidateStructure(content string) []string
+	ValidateExamples(ctx context.Context, content string) []string
+	ValidateLinks(content string) []string
+}
+
+// RegisterDocumentValidator routes documents of documentType to validator
+// instead of the template-based checks
+func (p *RoleBasedProcessor) RegisterDocumentValidator(documentType string, validator DocumentValidator) {
+	if p.validators == nil {
+		p.validators = make(map[string]DocumentValidator)
+	}
+	p.validators[documentType] = validator
+}
+
+// documentValidator returns the validator for documentType: a registered
+// one, else one built from the type's document template
+func (p *RoleBasedProcessor) documentValidator(documentType string) (DocumentValidator, bool) {
+	if validator, exists := p.validators[documentType]; exists {
+		return validator, true
+	}
+	if template, exists := p.templates.GetTemplate(documentType); exists {
+		return &templateValidator{template: template, processor: p}, true
+	}
+	return nil, false
+}
+
+// templateValidator validates a document against its configured template:
+// required sections and length, example language and toolchain checks, and
+// links to headings within the document
+type templateValidator struct {
+	template  config.DocumentTemplate
+	processor *RoleBasedProcessor
+}
+
+// ValidateStructure checks the template's minimum length and required sections
+func (v *templateValidator) ValidateStructure(content string) []string {
+	return checkDocumentTemplate(v.template, content)
+}
+
+// ValidateExamples checks example languages, then runs the configured toolchains
+func (v *templateValidator) ValidateExamples(ctx context.Context, content string) []string {
+	if mismatches := checkCodeLanguage(v.template.Language, content); len(mismatches) > 0 {
+		problems := make([]string, len(mismatches))
+		for i, mismatch := range mismatches {
+			problems[i] = fmt.Sprintf("%s, not %s", mismatch, v.template.Language)
+		}
+		return problems
+	}
+	return v.processor.validateCodeExamples(ctx, content)
+}
+
+// ValidateLinks checks links are well formed and anchors name a heading
+func (v *templateValidator) ValidateLinks(content string) []string {
+	return checkLinks(content)
+}
+
+// checkLinks reports empty or malformed link targets and in-document anchors
+// that match no heading
+func checkLinks(content string) []string {
+	anchors := markdown.HeadingAnchors(content)
+
+	var problems []string
+	for _, link := range markdown.ExtractLinks(content) {
+		switch {
+		case link.Target == "":
+			problems = append(problems, fmt.Sprintf("link %q (line %d) has no target", link.Text, link.Line))
+		case strings.HasPrefix(link.Target, "#"):
+			if !anchors[strings.ToLower(strings.TrimPrefix(link.Target, "#"))] {
+				problems = append(problems, fmt.Sprintf("link %q (line %d) points at missing section %s",
+					link.Text, link.Line, link.Target))
+			}
+		default:
+			if _, err := url.Parse(link.Target); err != nil {
+				problems = append(problems, fmt.Sprintf("link %q (line %d) has a malformed target: %v",
+					link.Text, link.Line, err))
+			}
+		}
+	}
+	return problems
+}
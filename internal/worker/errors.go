@@ -0,0 +1,16 @@
+package worker
+
+import "errors"
+
+// Worker errors; failures are wrapped with these so callers can branch on errors.Is
+var (
+	// Task errors
+	ErrUnsupportedTaskType = errors.New("unsupported task type")
+	ErrRoleMismatch        = errors.New("task requires a different role")
+	ErrMissingPayloadField = errors.New("missing payload field")
+	ErrValidationFailed    = errors.New("validation failed")
+
+	// Execution errors
+	ErrModelUnavailable = errors.New("model unavailable")
+	ErrAPIUnavailable   = errors.New("AI API unavailable")
+)
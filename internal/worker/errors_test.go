@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+func TestProcessorErrorSentinels(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		run  func() error
+		want error
+	}{
+		{
+			name: "unsupported simple task",
+			run: func() error {
+				_, err := NewRoleBasedProcessor(types.RoleDeveloper, nil, nil, nil, nil).ProcessTask(ctx, types.Task{ID: "t1", Type: "compile"})
+				return err
+			},
+			want: ErrUnsupportedTaskType,
+		},
+		{
+			name: "invalid workflow task",
+			run: func() error {
+				task := newDocumentTask(types.RoleDeveloper, "api_guide", "")
+				task.ID = ""
+				_, err := NewRoleBasedProcessor(types.RoleDeveloper, nil, nil, nil, nil).ProcessWorkflowTask(ctx, task)
+				return err
+			},
+			want: ErrValidationFailed,
+		},
+		{
+			name: "role mismatch",
+			run: func() error {
+				task := newDocumentTask(types.RoleReviewer, "api_guide", "# Draft")
+				_, err := NewRoleBasedProcessor(types.RoleDeveloper, nil, nil, nil, nil).ProcessWorkflowTask(ctx, task)
+				return err
+			},
+			want: ErrRoleMismatch,
+		},
+		{
+			name: "missing previous output",
+			run: func() error {
+				task := newDocumentTask(types.RoleApprover, "api_guide", "")
+				_, err := NewRoleBasedProcessor(types.RoleApprover, nil, nil, nil, nil).ProcessWorkflowTask(ctx, task)
+				return err
+			},
+			want: ErrMissingPayloadField,
+		},
+		{
+			name: "no model manager",
+			run: func() error {
+				_, err := NewTaskRouter(nil, nil).RouteTask(ctx, NewSelfTestTask(types.RoleDeveloper))
+				return err
+			},
+			want: ErrModelUnavailable,
+		},
+		{
+			name: "no AI client",
+			run: func() error {
+				execution := &TaskExecution{Strategy: ExecutionStrategyAPI, Task: NewSelfTestTask(types.RoleDeveloper)}
+				_, err := execution.Execute(ctx, nil, nil)
+				return err
+			},
+			want: ErrAPIUnavailable,
+		},
+		{
+			name: "invalid image data",
+			run: func() error {
+				task := NewSelfTestTask(types.RoleDeveloper)
+				task.Payload[PayloadImageData] = "not base64!"
+				_, err := (&TaskExecution{Task: task}).imageData()
+				return err
+			},
+			want: ErrValidationFailed,
+		},
+		{
+			name: "failing code example",
+			run: func() error {
+				processor := NewRoleBasedProcessor(types.RoleTester, nil, nil, nil, nil)
+				return processor.runToolchain(ctx, config.Toolchain{Path: "false", Extension: ".txt"}, "broken")
+			},
+			want: ErrValidationFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// ForceProviderLocal pins a task to a local model
+const ForceProviderLocal = "local"
+
+// isForced reports whether the task pins its provider or model
+func isForced(task *types.WorkflowTask) bool {
+	return task.ForceProvider != "" || task.ForceModel != ""
+}
+
+// forcedTarget describes what the task is pinned to, e.g. "groq/llama-3.3-70b"
+func forcedTarget(task *types.WorkflowTask) string {
+	switch {
+	case task.ForceProvider == "":
+		return task.ForceModel
+	case task.ForceModel == "":
+		return task.ForceProvider
+	default:
+		return task.ForceProvider + "/" + task.ForceModel
+	}
+}
+
+// routeForced plans execution on the provider and model the task pins,
+// bypassing complexity analysis. A pinned target that does not exist is an
+// error rather than a reason to route elsewhere.
+func (tr *TaskRouter) routeForced(ctx context.Context, task *types.WorkflowTask) (*TaskExecution, error) {
+	if task.ForceProvider == "" || task.ForceProvider == ForceProviderLocal {
+		return tr.routeForcedLocal(ctx, task)
+	}
+	return tr.routeForcedAPI(task)
+}
+
+// routeForcedLocal plans execution on the pinned local model, or the model
+// routing would pick when only the provider is pinned
+func (tr *TaskRouter) routeForcedLocal(ctx context.Context, task *types.WorkflowTask) (*TaskExecution, error) {
+	execution, err := tr.routeToLocalModel(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.ForceModel != "" {
+		execution.ModelName = task.ForceModel
+	}
+	if _, exists := tr.localModelManager.GetModelConfig(execution.ModelName); !exists {
+		return nil, fmt.Errorf("%w: forced local model %s is not configured", ErrModelUnavailable, execution.ModelName)
+	}
+
+	execution.Reasoning = fmt.Sprintf("Forced to local model %s", execution.ModelName)
+	return execution, nil
+}
+
+// routeForcedAPI plans execution on the pinned provider, ignoring its health
+// so a suspect provider can still be debugged
+func (tr *TaskRouter) routeForcedAPI(task *types.WorkflowTask) (*TaskExecution, error) {
+	if tr.aiConfig == nil {
+		return nil, fmt.Errorf("%w: AI configuration not available", ErrAPIUnavailable)
+	}
+
+	apiConfig, exists := tr.aiConfig.GetAvailableAPIs()[task.ForceProvider]
+	if !exists {
+		return nil, fmt.Errorf("%w: forced provider %s is unknown or has no API key", ErrAPIUnavailable, task.ForceProvider)
+	}
+
+	reasoning := fmt.Sprintf("Forced to %s API", task.ForceProvider)
+	if task.ForceModel != "" {
+		if !slices.Contains(apiConfig.Models, task.ForceModel) {
+			return nil, fmt.Errorf("%w: forced model %s is not configured for provider %s",
+				ErrAPIUnavailable, task.ForceModel, task.ForceProvider)
+		}
+		apiConfig.Models = []string{task.ForceModel}
+		reasoning = fmt.Sprintf("Forced to %s API model %s", task.ForceProvider, task.ForceModel)
+	}
+
+	return &TaskExecution{
+		Strategy:    ExecutionStrategyAPI,
+		APIProvider: task.ForceProvider,
+		APIConfig:   apiConfig,
+		APIModel:    task.ForceModel,
+		Task:        task,
+		Reasoning:   reasoning,
+	}, nil
+}
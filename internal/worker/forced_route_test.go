@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+func TestRouteTaskForced(t *testing.T) {
+	models := map[string]localmodels.ModelConfig{
+		"qwen-omni-3b": {Name: "qwen-omni-3b"},
+		"qwen-vl-7b":   {Name: "qwen-vl-7b"},
+	}
+
+	tests := []struct {
+		name         string
+		provider     string
+		model        string
+		wantStrategy ExecutionStrategy
+		wantProvider string
+		wantModel    string // Local model, or pinned API model
+		wantErr      error
+	}{
+		{name: "api provider", provider: "groq", wantStrategy: ExecutionStrategyAPI, wantProvider: "groq"},
+		{name: "api provider and model", provider: "groq", model: "test-model", wantStrategy: ExecutionStrategyAPI, wantProvider: "groq", wantModel: "test-model"},
+		{name: "local model", provider: ForceProviderLocal, model: "qwen-vl-7b", wantStrategy: ExecutionStrategyLocal, wantModel: "qwen-vl-7b"},
+		{name: "model only is local", model: "qwen-vl-7b", wantStrategy: ExecutionStrategyLocal, wantModel: "qwen-vl-7b"},
+		{name: "unknown provider", provider: "nvidia", wantErr: ErrAPIUnavailable},
+		{name: "unknown api model", provider: "groq", model: "missing", wantErr: ErrAPIUnavailable},
+		{name: "unknown local model", provider: ForceProviderLocal, model: "missing", wantErr: ErrModelUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, server := newFakeDaemon(t, echoPrediction("OK"))
+			_, aiConfig := newFakeProvider(t, "from the API")
+			router := NewTaskRouter(newDaemonManager(t, server.URL, models), aiConfig)
+
+			// A self-test task is simple, so routing alone would keep it on qwen-omni-3b
+			task := NewSelfTestTask(types.RoleDeveloper)
+			task.ForceProvider, task.ForceModel = tt.provider, tt.model
+
+			execution, err := router.RouteTask(context.Background(), task)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("RouteTask() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RouteTask() error = %v", err)
+			}
+
+			if execution.Strategy != tt.wantStrategy || execution.APIProvider != tt.wantProvider {
+				t.Errorf("routed to strategy %v provider %q, want %v %q", execution.Strategy, execution.APIProvider, tt.wantStrategy, tt.wantProvider)
+			}
+			model := execution.ModelName
+			if execution.Strategy == ExecutionStrategyAPI {
+				model = execution.APIModel
+			}
+			if model != tt.wantModel {
+				t.Errorf("routed to model %q, want %q", model, tt.wantModel)
+			}
+		})
+	}
+}
+
+func TestForcedProviderExecutes(t *testing.T) {
+	daemon, server := newFakeDaemon(t, echoPrediction("from the local model"))
+	provider, aiConfig := newFakeProvider(t, "from the API")
+	processor := NewRoleBasedProcessor(types.RoleDeveloper, nil, newDaemonManager(t, server.URL, nil), nil, aiConfig)
+
+	task := NewSelfTestTask(types.RoleDeveloper)
+	task.ForceProvider, task.ForceModel = "groq", "test-model"
+
+	outcome, err := processor.ProcessWorkflowTask(context.Background(), task)
+	if err != nil {
+		t.Fatalf("ProcessWorkflowTask: %v", err)
+	}
+	if outcome.Output != "from the API" || provider.requests.Load() != 1 {
+		t.Errorf("output %q after %d API requests, want the API reply", outcome.Output, provider.requests.Load())
+	}
+	if prompts := daemon.prompts("qwen-omni-3b"); len(prompts) != 0 {
+		t.Errorf("local model got %d prompts, want none", len(prompts))
+	}
+}
+
+func TestFallbackToAPIRefusesForcedTasks(t *testing.T) {
+	_, aiConfig := newFakeProvider(t, "unused")
+	router := NewTaskRouter(nil, aiConfig)
+
+	task := NewSelfTestTask(types.RoleDeveloper)
+	task.ForceProvider, task.ForceModel = ForceProviderLocal, "qwen-omni-3b"
+
+	localErr := errors.New("model crashed")
+	if _, err := router.FallbackToAPI(context.Background(), task, localErr); !errors.Is(err, localErr) {
+		t.Errorf("FallbackToAPI() error = %v, want the local failure", err)
+	}
+}
@@ -0,0 +1,25 @@
+package worker
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// checkGoFragment reports whether code is a Go fragment without a package
+// clause and, for a fragment, its syntax error. Like gofmt -e, a fragment is
+// parsed as a list of declarations, then as a list of statements; the
+// prefixes stay on the first line so error positions match the example.
+func checkGoFragment(code string) (fragment bool, err error) {
+	fset := token.NewFileSet()
+	if _, err = parser.ParseFile(fset, "example.go", code, parser.AllErrors); err == nil || !strings.Contains(err.Error(), "expected 'package'") {
+		return false, nil // A complete file goes through the toolchain
+	}
+
+	if _, err = parser.ParseFile(fset, "example.go", "package p;"+code, parser.AllErrors); err == nil || !strings.Contains(err.Error(), "expected declaration") {
+		return true, err
+	}
+
+	_, err = parser.ParseFile(fset, "example.go", "package p; func _() {"+code+"\n}", parser.AllErrors)
+	return true, err
+}
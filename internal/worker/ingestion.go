@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/rag"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// MQTT topics for document ingestion
+const (
+	IngestionTopic       = "tasks/ingest"
+	IngestionResultTopic = "results/ingest/%s"
+)
+
+// Ingester embeds and stores documents for the embedder role
+type Ingester struct {
+	store DocumentStore
+}
+
+// NewIngester creates an ingester writing to store
+func NewIngester(store DocumentStore) *Ingester {
+	return &Ingester{store: store}
+}
+
+// Ingest stores every document in the task and returns how many were
+// stored. It stops at the first failure so the caller can retry the task;
+// re-ingesting already stored documents overwrites them.
+func (i *Ingester) Ingest(ctx context.Context, task types.IngestionTask) (int, error) {
+	if err := task.Validate(); err != nil {
+		return 0, err
+	}
+
+	collection, err := rag.TenantCollection(task.TenantID, task.Collection)
+	if err != nil {
+		return 0, err
+	}
+
+	for stored, doc := range task.Documents {
+		if err := ctx.Err(); err != nil {
+			return stored, fmt.Errorf("ingestion cancelled: %w", err)
+		}
+		if err := i.store.AddDocument(ctx, collection, doc); err != nil {
+			return stored, fmt.Errorf("document %d of %d (source %q): %w", stored+1, len(task.Documents), doc.Source, err)
+		}
+	}
+
+	log.Printf("Ingested %d documents into %s (task %s)", len(task.Documents), collection, task.ID)
+	return len(task.Documents), nil
+}
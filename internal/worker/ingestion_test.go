@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// recordingStore records stored documents by collection, failing the
+// document whose content is failOn
+type recordingStore struct {
+	stored map[string][]string
+	failOn string
+}
+
+func (s *recordingStore) AddDocument(ctx context.Context, collection string, doc types.RAGDocument) error {
+	if doc.Content == s.failOn {
+		return errors.New("qdrant unavailable")
+	}
+	s.stored[collection] = append(s.stored[collection], doc.Content)
+	return nil
+}
+
+func TestIngest(t *testing.T) {
+	documents := []types.RAGDocument{{Content: "first", Source: "a.md"}, {Content: "second", Source: "b.md"}}
+
+	tests := []struct {
+		name           string
+		task           types.IngestionTask
+		failOn         string
+		cancelled      bool
+		wantStored     int
+		wantCollection string
+		wantErr        bool
+	}{
+		{"stores every document", types.IngestionTask{ID: "i1", Collection: "documentation", Documents: documents}, "", false, 2, "documentation", false},
+		{"tenant collection", types.IngestionTask{ID: "i2", Collection: "documentation", TenantID: "acme", Documents: documents}, "", false, 2, "acme_documentation", false},
+		{"stops at failure", types.IngestionTask{ID: "i3", Collection: "documentation", Documents: documents}, "second", false, 1, "documentation", true},
+		{"cancelled", types.IngestionTask{ID: "i4", Collection: "documentation", Documents: documents}, "", true, 0, "", true},
+		{"invalid task", types.IngestionTask{ID: "i5", Documents: documents}, "", false, 0, "", true},
+		{"invalid tenant", types.IngestionTask{ID: "i6", Collection: "documentation", TenantID: "../x", Documents: documents}, "", false, 0, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &recordingStore{stored: make(map[string][]string), failOn: tt.failOn}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelled {
+				cancel()
+			}
+
+			stored, err := NewIngester(store).Ingest(ctx, tt.task)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ingest err = %v, want error %v", err, tt.wantErr)
+			}
+			if stored != tt.wantStored {
+				t.Errorf("stored = %d, want %d", stored, tt.wantStored)
+			}
+			if tt.wantCollection != "" && len(store.stored[tt.wantCollection]) != tt.wantStored {
+				t.Errorf("collection %s holds %v, want %d documents", tt.wantCollection, store.stored[tt.wantCollection], tt.wantStored)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package markdown
+
+import "strings"
+
+// CodeBlock is a fenced code block extracted from a markdown document
+type CodeBlock struct {
+	Index     int    // 1-based position among the document's code blocks
+	Language  string // First word of the info string, lowercased; empty when untagged
+	Info      string // Full info string after the opening fence
+	Code      string // Block contents without the fences
+	StartLine int    // 1-based line of the opening fence
+	EndLine   int    // 1-based line of the closing fence, or the last line when unterminated
+
+	// Unterminated is set when the document ended before a closing fence
+	Unterminated bool
+}
+
+// fence describes an opening code fence
+type fence struct {
+	char   byte
+	length int
+	indent int
+}
+
+// ExtractCodeBlocks returns the fenced code blocks in content in document
+// order. Fences follow CommonMark: three or more backticks or tildes
+// indented at most three spaces, closed by a fence of the same character at
+// least as long, so shorter fences inside a block are treated as code.
+func ExtractCodeBlocks(content string) []CodeBlock {
+	lines := strings.Split(content, "\n")
+
+	var blocks []CodeBlock
+	var open *fence
+	var current CodeBlock
+	var body []string
+
+	for i, line := range lines {
+		lineNumber := i + 1
+		line = strings.TrimSuffix(line, "\r")
+
+		if open == nil {
+			opening, info, ok := parseOpeningFence(line)
+			if !ok {
+				continue
+			}
+			open = &opening
+			current = CodeBlock{
+				Index:     len(blocks) + 1,
+				Language:  languageFromInfo(info),
+				Info:      info,
+				StartLine: lineNumber,
+			}
+			body = body[:0]
+			continue
+		}
+
+		if isClosingFence(line, *open) {
+			current.Code = joinBody(body)
+			current.EndLine = lineNumber
+			blocks = append(blocks, current)
+			open = nil
+			continue
+		}
+
+		body = append(body, stripIndent(line, open.indent))
+	}
+
+	if open != nil {
+		current.Code = joinBody(body)
+		current.EndLine = len(lines)
+		current.Unterminated = true
+		blocks = append(blocks, current)
+	}
+
+	return blocks
+}
+
+// parseOpeningFence recognizes an opening fence and returns its info string
+func parseOpeningFence(line string) (fence, string, bool) {
+	indent := leadingSpaces(line)
+	if indent > 3 {
+		return fence{}, "", false
+	}
+
+	rest := line[indent:]
+	if rest == "" || (rest[0] != '`' && rest[0] != '~') {
+		return fence{}, "", false
+	}
+
+	char := rest[0]
+	length := 0
+	for length < len(rest) && rest[length] == char {
+		length++
+	}
+	if length < 3 {
+		return fence{}, "", false
+	}
+
+	info := strings.TrimSpace(rest[length:])
+	// Backtick fences may not carry backticks in the info string
+	if char == '`' && strings.ContainsRune(info, '`') {
+		return fence{}, "", false
+	}
+
+	return fence{char: char, length: length, indent: indent}, info, true
+}
+
+// isClosingFence reports whether line closes the open fence
+func isClosingFence(line string, open fence) bool {
+	indent := leadingSpaces(line)
+	if indent > 3 {
+		return false
+	}
+
+	rest := strings.TrimRight(line[indent:], " \t")
+	if len(rest) < open.length {
+		return false
+	}
+	for i := 0; i < len(rest); i++ {
+		if rest[i] != open.char {
+			return false
+		}
+	}
+	return true
+}
+
+// languageFromInfo returns the language tag from a fence info string
+func languageFromInfo(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+	// Accept attribute-style tags such as {.go}
+	language := strings.Trim(fields[0], "{}.")
+	return strings.ToLower(language)
+}
+
+// leadingSpaces counts the spaces at the start of line
+func leadingSpaces(line string) int {
+	count := 0
+	for count < len(line) && line[count] == ' ' {
+		count++
+	}
+	return count
+}
+
+// stripIndent removes up to indent leading spaces, matching the opening fence indentation
+func stripIndent(line string, indent int) string {
+	spaces := min(leadingSpaces(line), indent)
+	return line[spaces:]
+}
+
+// joinBody joins block lines, keeping a trailing newline when there is code
+func joinBody(body []string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	return strings.Join(body, "\n") + "\n"
+}
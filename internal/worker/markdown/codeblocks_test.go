@@ -0,0 +1,41 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []CodeBlock
+	}{
+		{"several languages", "# Guide\n\n```go\nfunc main() {}\n```\n\nText\n\n```Python\nprint(1)\n```\n",
+			[]CodeBlock{
+				{Index: 1, Language: "go", Info: "go", Code: "func main() {}\n", StartLine: 3, EndLine: 5},
+				{Index: 2, Language: "python", Info: "Python", Code: "print(1)\n", StartLine: 9, EndLine: 11},
+			}},
+		{"untagged", "```\nplain\n```", []CodeBlock{{Index: 1, Code: "plain\n", StartLine: 1, EndLine: 3}}},
+		{"info string attributes", "``` {.go} title=\"main.go\"\ncode\n```",
+			[]CodeBlock{{Index: 1, Language: "go", Info: "{.go} title=\"main.go\"", Code: "code\n", StartLine: 1, EndLine: 3}}},
+		{"shorter fence inside", "````markdown\n```go\nx := 1\n```\n````",
+			[]CodeBlock{{Index: 1, Language: "markdown", Info: "markdown", Code: "```go\nx := 1\n```\n", StartLine: 1, EndLine: 5}}},
+		{"tilde fence keeps backticks", "~~~sh\necho `date`\n```\n~~~",
+			[]CodeBlock{{Index: 1, Language: "sh", Info: "sh", Code: "echo `date`\n```\n", StartLine: 1, EndLine: 4}}},
+		{"indented fence", "  ```go\n  x := 1\n    y := 2\n  ```",
+			[]CodeBlock{{Index: 1, Language: "go", Info: "go", Code: "x := 1\n  y := 2\n", StartLine: 1, EndLine: 4}}},
+		{"crlf line endings", "```go\r\nx := 1\r\n```\r\n", []CodeBlock{{Index: 1, Language: "go", Info: "go", Code: "x := 1\n", StartLine: 1, EndLine: 3}}},
+		{"empty block", "```go\n```", []CodeBlock{{Index: 1, Language: "go", Info: "go", StartLine: 1, EndLine: 2}}},
+		{"unterminated", "```go\nx := 1\n", []CodeBlock{{Index: 1, Language: "go", Info: "go", Code: "x := 1\n\n", StartLine: 1, EndLine: 3, Unterminated: true}}},
+		{"not fences", "    ```go\n``go``\nInline ``` text\n```go`x`\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractCodeBlocks(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractCodeBlocks =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+)
+
+// languageAliases maps common fence tags to a canonical language name
+var languageAliases = map[string]string{
+	"golang":  "go",
+	"py":      "python",
+	"python3": "python",
+	"sh":      "bash",
+	"shell":   "bash",
+	"zsh":     "bash",
+}
+
+// NormalizeLanguage returns the canonical name for a fence language tag
+func NormalizeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if canonical, exists := languageAliases[language]; exists {
+		return canonical
+	}
+	return language
+}
+
+// languageSignals are patterns characteristic of each detectable language
+var languageSignals = map[string][]*regexp.Regexp{
+	"go": {
+		regexp.MustCompile(`(?m)^package \w+\s*$`),
+		regexp.MustCompile(`(?m)^func (\(\w+ \*?\w+\) )?\w+\(`),
+		regexp.MustCompile(`:= `),
+		regexp.MustCompile(`\bfmt\.\w+\(`),
+		regexp.MustCompile(`(?m)^import \($`),
+		regexp.MustCompile(`\bif err != nil\b`),
+	},
+	"python": {
+		regexp.MustCompile(`(?m)^\s*def \w+\(.*\)( -> [\w\[\], ]+)?:\s*$`),
+		regexp.MustCompile(`(?m)^\s*class \w+(\(.*\))?:\s*$`),
+		regexp.MustCompile(`(?m)^(from [\w.]+ )?import [\w.]+( as \w+)?\s*$`),
+		regexp.MustCompile(`\bself\.`),
+		regexp.MustCompile(`(?m)^\s*(elif|except)\b.*:\s*$`),
+		regexp.MustCompile(`\bprint\(`),
+		regexp.MustCompile(`__\w+__`),
+	},
+	"bash": {
+		regexp.MustCompile(`^#!\s*/(usr/)?bin/(env )?(ba)?sh`),
+		regexp.MustCompile(`(?m)^\s*(fi|done|esac)\s*$`),
+		regexp.MustCompile(`(?m);\s*then\s*$`),
+		regexp.MustCompile(`\$\{?\w+\}?`),
+		regexp.MustCompile(`\$\(`),
+		regexp.MustCompile(`(?m)^\s*(local|readonly|declare) `),
+		regexp.MustCompile(`(?m)^\s*echo `),
+	},
+}
+
+// minLanguageSignals is how many patterns must match before a language is reported
+const minLanguageSignals = 2
+
+// DetectLanguage guesses the programming language of a code sample from
+// characteristic syntax. It returns "" when no language clearly wins.
+func DetectLanguage(code string) string {
+	best, bestScore, tied := "", 0, false
+	for language, signals := range languageSignals {
+		score := 0
+		for _, signal := range signals {
+			if signal.MatchString(code) {
+				score++
+			}
+		}
+		switch {
+		case score > bestScore:
+			best, bestScore, tied = language, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+
+	if bestScore < minLanguageSignals || tied {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,83 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Link is an inline markdown link extracted from a document
+type Link struct {
+	Text   string
+	Target string // Link destination without any title
+	Line   int    // 1-based line the link appears on
+}
+
+// inlineLinkPattern matches [text](target "optional title"), excluding images
+var inlineLinkPattern = regexp.MustCompile(`(^|[^!])\[([^\]]*)\]\(\s*([^)\s]*)(?:\s+"[^"]*")?\s*\)`)
+
+// headingPattern matches ATX headings
+var headingPattern = regexp.MustCompile(`^ {0,3}#{1,6}\s+(.*?)\s*#*\s*$`)
+
+// ExtractLinks returns the inline links in content outside fenced code blocks
+func ExtractLinks(content string) []Link {
+	var links []Link
+	forEachProseLine(content, func(lineNumber int, line string) {
+		for _, match := range inlineLinkPattern.FindAllStringSubmatch(line, -1) {
+			links = append(links, Link{Text: match[2], Target: match[3], Line: lineNumber})
+		}
+	})
+	return links
+}
+
+// HeadingAnchors returns the anchors GitHub generates for the document's
+// headings, so "#error-handling" style links can be checked
+func HeadingAnchors(content string) map[string]bool {
+	anchors := make(map[string]bool)
+	seen := make(map[string]int)
+	forEachProseLine(content, func(_ int, line string) {
+		match := headingPattern.FindStringSubmatch(line)
+		if match == nil {
+			return
+		}
+		anchor := headingAnchor(match[1])
+		if count := seen[anchor]; count > 0 {
+			anchors[anchor+"-"+strconv.Itoa(count)] = true
+		} else {
+			anchors[anchor] = true
+		}
+		seen[anchor]++
+	})
+	return anchors
+}
+
+// headingAnchor lowercases a heading, drops punctuation and joins words with hyphens
+func headingAnchor(heading string) string {
+	var anchor strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(heading)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			anchor.WriteRune(r)
+		case r == ' ':
+			anchor.WriteRune('-')
+		}
+	}
+	return anchor.String()
+}
+
+// forEachProseLine calls fn for every line of content outside fenced code blocks
+func forEachProseLine(content string, fn func(lineNumber int, line string)) {
+	inCode := make(map[int]bool)
+	for _, block := range ExtractCodeBlocks(content) {
+		for line := block.StartLine; line <= block.EndLine; line++ {
+			inCode[line] = true
+		}
+	}
+
+	for i, line := range strings.Split(content, "\n") {
+		if !inCode[i+1] {
+			fn(i+1, strings.TrimSuffix(line, "\r"))
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+)
+
+// Payload keys for multimodal tasks
+const (
+	PayloadImagePaths = "image_paths" // Comma-separated image files passed to multimodal models
+	PayloadImageData  = "image_data"  // Comma-separated base64 images, optionally as data: URIs
+)
+
+// textPayload returns payload without the base64 image data, which would
+// swamp keyword scoring and prompts; the decoded images reach the model
+// through ModelInput.ImageData instead
+func textPayload(payload map[string]string) map[string]string {
+	if _, exists := payload[PayloadImageData]; !exists {
+		return payload
+	}
+
+	text := make(map[string]string, len(payload)-1)
+	for key, value := range payload {
+		if key != PayloadImageData {
+			text[key] = value
+		}
+	}
+	return text
+}
+
+// imagePaths returns the images attached to the task
+func (te *TaskExecution) imagePaths() []string {
+	var paths []string
+	for _, path := range strings.Split(te.Task.Payload[PayloadImagePaths], ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// imageData decodes the base64 images carried in the task payload
+func (te *TaskExecution) imageData() ([][]byte, error) {
+	var images [][]byte
+	entries := strings.Split(te.Task.Payload[PayloadImageData], ",")
+	for i := 0; i < len(entries); i++ {
+		entry := strings.TrimSpace(entries[i])
+
+		// A data URI's own comma separates its header from the data
+		if strings.HasPrefix(entry, "data:") {
+			if !strings.HasSuffix(entry, ";base64") || i+1 >= len(entries) {
+				return nil, fmt.Errorf("%w: %s: image %d is not a base64 data URI", ErrValidationFailed, PayloadImageData, len(images)+1)
+			}
+			i++
+			entry = strings.TrimSpace(entries[i])
+		}
+		if entry == "" {
+			continue
+		}
+
+		data, err := base64.StdEncoding.DecodeString(entry)
+		if err != nil {
+			data, err = base64.RawStdEncoding.DecodeString(entry)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: image %d is not valid base64: %w", ErrValidationFailed, PayloadImageData, len(images)+1, err)
+		}
+		images = append(images, data)
+	}
+	return images, nil
+}
+
+// predictTextFallback retries a failed multimodal request on the model's
+// configured fallback_model without the images. The prompt tells the model
+// the images were unavailable so it answers from the text alone.
+func (te *TaskExecution) predictTextFallback(ctx context.Context, localManager *localmodels.Manager, input localmodels.ModelInput, cause error) (*localmodels.ModelOutput, error) {
+	config, exists := localManager.GetModelConfig(te.ModelName)
+	if !exists || config.FallbackModel == "" {
+		return nil, cause
+	}
+
+	log.Printf("Warning: multimodal model %s failed for task %s, falling back to text model %s: %v",
+		te.ModelName, te.Task.ID, config.FallbackModel, cause)
+
+	if err := localManager.LoadModel(ctx, config.FallbackModel); err != nil {
+		return nil, fmt.Errorf("%w (fallback model %s failed to load: %v)", cause, config.FallbackModel, err)
+	}
+
+	model, err := localManager.GetModel(config.FallbackModel)
+	if err != nil {
+		return nil, fmt.Errorf("%w (fallback model %s unavailable: %v)", cause, config.FallbackModel, err)
+	}
+
+	fallbackInput := input
+	fallbackInput.ImagePaths = nil
+	fallbackInput.ImageData = nil
+	if len(input.ImagePaths) > 0 || len(input.ImageData) > 0 {
+		names := make([]string, 0, len(input.ImagePaths)+len(input.ImageData))
+		for _, path := range input.ImagePaths {
+			names = append(names, filepath.Base(path))
+		}
+		for i := range input.ImageData {
+			names = append(names, fmt.Sprintf("inline image %d", i+1))
+		}
+		fallbackInput.Text = fmt.Sprintf("Note: the attached images (%s) could not be processed. Answer from the text alone and say what the images would be needed for.\n\n%s",
+			strings.Join(names, ", "), input.Text)
+	}
+
+	output, err := model.Predict(ctx, fallbackInput)
+	if err != nil {
+		return nil, fmt.Errorf("%w (fallback model %s failed: %v)", cause, config.FallbackModel, err)
+	}
+
+	te.ServedBy = config.FallbackModel
+	return output, nil
+}
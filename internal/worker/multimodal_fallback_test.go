@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+func TestImageDataKeptOutOfPromptsAndScoring(t *testing.T) {
+	// Base64 that decodes to nothing meaningful but spells scoring keywords
+	imageData := "architecturesecuritydesign" + base64.StdEncoding.EncodeToString([]byte("png bytes"))
+
+	task := NewSelfTestTask(types.RoleDeveloper)
+	task.Type = "describe_image"
+	plain := NewSelfTestTask(types.RoleDeveloper)
+	plain.Type = "describe_image"
+	task.Payload[PayloadImageData] = imageData
+
+	router := NewTaskRouter(nil, nil)
+	if got, want := router.analyzeTaskComplexity(task), router.analyzeTaskComplexity(plain); got != want {
+		t.Errorf("complexity with image data = %v, want %v", got, want)
+	}
+	if router.isMCPTask(task) != router.isMCPTask(plain) {
+		t.Errorf("image data changed MCP detection")
+	}
+
+	execution := &TaskExecution{Task: task}
+	for name, prompt := range map[string]string{
+		"local":    execution.buildLocalPrompt(),
+		"detailed": execution.buildDetailedPrompt(),
+	} {
+		if strings.Contains(prompt, imageData) || strings.Contains(prompt, PayloadImageData) {
+			t.Errorf("%s prompt contains the image data: %q", name, prompt)
+		}
+		if !strings.Contains(prompt, "reply with the single word OK") {
+			t.Errorf("%s prompt lost the text payload: %q", name, prompt)
+		}
+	}
+
+	if _, exists := task.Payload[PayloadImageData]; !exists {
+		t.Errorf("textPayload modified the task payload")
+	}
+}
@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// DefaultMaxPayloadSize caps incoming task messages at 1 MiB
+const DefaultMaxPayloadSize = 1 << 20
+
+// RejectedTaskTopic receives dead letters for tasks a worker refused to decode
+const RejectedTaskTopic = "tasks/deadletter/rejected/%s"
+
+// ErrPayloadTooLarge is returned for messages above the configured size limit
+var ErrPayloadTooLarge = errors.New("payload too large")
+
+// CheckPayloadSize rejects payloads larger than maxSize bytes before they are
+// unmarshalled. A maxSize of zero or less disables the check.
+func CheckPayloadSize(payload []byte, maxSize int) error {
+	if maxSize > 0 && len(payload) > maxSize {
+		return fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrPayloadTooLarge, len(payload), maxSize)
+	}
+	return nil
+}
+
+// RejectedPayloadDeadLetter describes a task that was dropped without being
+// decoded. Only the size is recorded since the payload itself is untrusted;
+// the empty task marks it as not requeueable.
+func RejectedPayloadDeadLetter(topic string, size int, reason error, now time.Time) types.DeadLetter {
+	return types.DeadLetter{
+		Topic:          topic,
+		Reason:         reason.Error(),
+		PayloadSize:    size,
+		DeadLetteredAt: now,
+	}
+}
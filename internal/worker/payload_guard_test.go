@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckPayloadSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		maxSize int
+		wantErr bool
+	}{
+		{"under limit", 10, 11, false},
+		{"at limit", 11, 11, false},
+		{"over limit", 12, 11, true},
+		{"check disabled", 1 << 21, 0, false},
+		{"negative disables", 1 << 21, -1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPayloadSize(make([]byte, tt.size), tt.maxSize)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckPayloadSize = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrPayloadTooLarge) {
+				t.Errorf("err = %v, want ErrPayloadTooLarge", err)
+			}
+		})
+	}
+}
+
+func TestRejectedPayloadDeadLetter(t *testing.T) {
+	now := time.Now()
+	reason := CheckPayloadSize(make([]byte, 12), 11)
+	deadLetter := RejectedPayloadDeadLetter("tasks/workflow/review", 12, reason, now)
+
+	if deadLetter.Task.ID != "" || deadLetter.PayloadSize != 12 || !deadLetter.DeadLetteredAt.Equal(now) {
+		t.Errorf("dead letter = %+v, want only the size recorded", deadLetter)
+	}
+	if deadLetter.Topic != "tasks/workflow/review" || !strings.Contains(deadLetter.Reason, "payload too large") {
+		t.Errorf("dead letter topic %q reason %q", deadLetter.Topic, deadLetter.Reason)
+	}
+}
@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"sync"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/tokenizer"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// progressStep is the percentage a generation must advance before listeners
+// are told again, so status publishes stay infrequent
+const progressStep = 10
+
+// ProgressTracker follows the generation of the task a processor is running.
+// It is safe for concurrent use.
+type ProgressTracker struct {
+	mu        sync.Mutex
+	taskID    string
+	tokens    int
+	maxTokens int
+	streaming bool
+	notified  int // Percent at the last notification
+	onChange  func()
+}
+
+// NewProgressTracker creates a tracker with no task running
+func NewProgressTracker() *ProgressTracker {
+	return &ProgressTracker{}
+}
+
+// OnChange calls fn when a task starts or finishes and every progressStep
+// percent in between. fn is called without the tracker locked.
+func (t *ProgressTracker) OnChange(fn func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onChange = fn
+}
+
+// Start marks taskID as running with no generation yet
+func (t *ProgressTracker) Start(taskID string) {
+	t.mu.Lock()
+	t.taskID = taskID
+	t.tokens, t.maxTokens, t.notified = 0, 0, 0
+	t.streaming = false
+	fn := t.onChange
+	t.mu.Unlock()
+
+	notify(fn)
+}
+
+// Finish marks the running task as done
+func (t *ProgressTracker) Finish() {
+	t.mu.Lock()
+	t.taskID = ""
+	t.streaming = false
+	fn := t.onChange
+	t.mu.Unlock()
+
+	notify(fn)
+}
+
+// Begin starts counting a streamed generation limited to maxTokens
+func (t *ProgressTracker) Begin(maxTokens int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tokens, t.maxTokens, t.notified = 0, maxTokens, 0
+	t.streaming = true
+}
+
+// Advance counts a streamed content delta
+func (t *ProgressTracker) Advance(delta string) {
+	t.mu.Lock()
+	t.tokens += tokenizer.Estimate(delta)
+	percent := t.percent()
+	var fn func()
+	if percent >= t.notified+progressStep {
+		t.notified = percent
+		fn = t.onChange
+	}
+	t.mu.Unlock()
+
+	notify(fn)
+}
+
+// Current returns the running task and, while it is streaming, its progress
+func (t *ProgressTracker) Current() (string, *types.TaskProgress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.taskID == "" || !t.streaming {
+		return t.taskID, nil
+	}
+	return t.taskID, &types.TaskProgress{
+		Tokens:    t.tokens,
+		MaxTokens: t.maxTokens,
+		Percent:   t.percent(),
+	}
+}
+
+// percent is the share of the token limit generated, capped below 100 until
+// the task finishes. Callers must hold t.mu.
+func (t *ProgressTracker) percent() int {
+	if t.maxTokens <= 0 {
+		return 0
+	}
+	return min(t.tokens*100/t.maxTokens, 99)
+}
+
+// notify calls fn when set
+func notify(fn func()) {
+	if fn != nil {
+		fn()
+	}
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"log"
+	"unicode"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
+	"github.com/niko/mqtt-agent-orchestration/internal/tokenizer"
+)
+
+// truncationMarker is appended to a section trimmed to fit the prompt budget
+const truncationMarker = "\n[... truncated to fit prompt budget]"
+
+// promptSections holds the variable-size parts of a prompt that may be trimmed
+type promptSections struct {
+	RAGContext     string
+	PreviousOutput string
+}
+
+// renderWithinBudget renders a prompt after trimming its sections so the
+// result fits the budget. render must produce the fixed parts of the prompt
+// when given empty sections.
+func renderWithinBudget(budget *config.PromptBudgetConfig, sections promptSections, render func(promptSections) string) string {
+	fixedTokens := tokenizer.Estimate(render(promptSections{}))
+	fitPromptBudget(budget, fixedTokens, &sections)
+	return render(sections)
+}
+
+// fitPromptBudget trims sections in the configured truncation order until
+// fixedTokens plus the sections fit within the budget
+func fitPromptBudget(budget *config.PromptBudgetConfig, fixedTokens int, sections *promptSections) {
+	if budget == nil || budget.MaxPromptTokens == 0 {
+		return
+	}
+
+	fields := map[string]*string{
+		config.PromptSectionRAGContext:     &sections.RAGContext,
+		config.PromptSectionPreviousOutput: &sections.PreviousOutput,
+	}
+
+	excess := fixedTokens + tokenizer.Estimate(sections.RAGContext) + tokenizer.Estimate(sections.PreviousOutput) - budget.MaxPromptTokens
+	for _, name := range budget.TruncationOrder {
+		if excess <= 0 {
+			return
+		}
+
+		section := fields[name]
+		tokens := tokenizer.Estimate(*section)
+		if tokens == 0 {
+			continue
+		}
+
+		keep := max(tokens-excess, 0)
+		*section = truncateToTokens(*section, keep)
+		excess -= tokens - keep
+		log.Printf("Trimmed %s from ~%d to ~%d tokens to fit prompt budget of %d", name, tokens, keep, budget.MaxPromptTokens)
+	}
+
+	if excess > 0 {
+		log.Printf("Warning: prompt still exceeds budget of %d tokens by ~%d after truncation", budget.MaxPromptTokens, excess)
+	}
+}
+
+// truncateToTokens keeps the leading words of text that fit in the given
+// number of estimated tokens, preserving the original whitespace
+func truncateToTokens(text string, tokens int) string {
+	words := tokenizer.WordsForTokens(tokens)
+	if words <= 0 {
+		return ""
+	}
+
+	count := 0
+	inWord := false
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if inWord {
+				count++
+				if count == words {
+					return text[:i] + truncationMarker
+				}
+			}
+			inWord = false
+			continue
+		}
+		inWord = true
+	}
+	return text
+}
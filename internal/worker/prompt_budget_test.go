@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
+	"github.com/niko/mqtt-agent-orchestration/internal/tokenizer"
+)
+
+// words returns text of n words
+func words(n int) string {
+	return strings.TrimSpace(strings.Repeat("word ", n))
+}
+
+// keptWords counts the words of a section left after truncation
+func keptWords(section string) (int, bool) {
+	kept, truncated := strings.CutSuffix(section, truncationMarker)
+	return len(strings.Fields(kept)), truncated
+}
+
+func TestFitPromptBudget(t *testing.T) {
+	ragFirst := []string{config.PromptSectionRAGContext, config.PromptSectionPreviousOutput}
+	previousFirst := []string{config.PromptSectionPreviousOutput, config.PromptSectionRAGContext}
+
+	tests := []struct {
+		name         string
+		budget       *config.PromptBudgetConfig
+		fixedTokens  int
+		rag          int // Words of RAG context
+		previous     int // Words of previous output
+		wantRAG      int
+		wantPrevious int
+	}{
+		{"fits", &config.PromptBudgetConfig{MaxPromptTokens: 200, TruncationOrder: ragFirst}, 20, 60, 30, 60, 30},
+		{"rag context trimmed first", &config.PromptBudgetConfig{MaxPromptTokens: 100, TruncationOrder: ragFirst}, 20, 60, 30, 30, 30},
+		{"then previous output", &config.PromptBudgetConfig{MaxPromptTokens: 60, TruncationOrder: ragFirst}, 20, 30, 60, 0, 30},
+		{"configured order", &config.PromptBudgetConfig{MaxPromptTokens: 100, TruncationOrder: previousFirst}, 20, 60, 30, 60, 0},
+		{"section not listed is kept", &config.PromptBudgetConfig{MaxPromptTokens: 60, TruncationOrder: previousFirst[:1]}, 20, 30, 60, 30, 0},
+		{"disabled", &config.PromptBudgetConfig{MaxPromptTokens: 0, TruncationOrder: ragFirst}, 20, 600, 300, 600, 300},
+		{"no budget", nil, 20, 600, 300, 600, 300},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections := promptSections{RAGContext: words(tt.rag), PreviousOutput: words(tt.previous)}
+			fitPromptBudget(tt.budget, tt.fixedTokens, &sections)
+
+			rag, ragTruncated := keptWords(sections.RAGContext)
+			previous, previousTruncated := keptWords(sections.PreviousOutput)
+			if rag != tt.wantRAG || previous != tt.wantPrevious {
+				t.Errorf("kept %d RAG and %d previous words, want %d and %d", rag, previous, tt.wantRAG, tt.wantPrevious)
+			}
+			if wantTrimmed := tt.wantRAG > 0 && tt.wantRAG < tt.rag; ragTruncated != wantTrimmed {
+				t.Errorf("RAG context marked truncated = %v, want %v", ragTruncated, wantTrimmed)
+			}
+			if wantTrimmed := tt.wantPrevious > 0 && tt.wantPrevious < tt.previous; previousTruncated != wantTrimmed {
+				t.Errorf("previous output marked truncated = %v, want %v", previousTruncated, wantTrimmed)
+			}
+		})
+	}
+}
+
+func TestRenderWithinBudget(t *testing.T) {
+	budget := &config.PromptBudgetConfig{
+		MaxPromptTokens: 200,
+		TruncationOrder: []string{config.PromptSectionRAGContext, config.PromptSectionPreviousOutput},
+	}
+	render := func(sections promptSections) string {
+		return "System prompt.\n\nContext:\n" + sections.RAGContext + "\n\nPrevious:\n" + sections.PreviousOutput + "\n\nWrite the document."
+	}
+
+	prompt := renderWithinBudget(budget, promptSections{RAGContext: words(500), PreviousOutput: words(100)}, render)
+	if !strings.HasPrefix(prompt, "System prompt.") || !strings.HasSuffix(prompt, "Write the document.") {
+		t.Errorf("fixed parts of the prompt were lost: %q", prompt)
+	}
+	withoutMarkers := strings.ReplaceAll(prompt, truncationMarker, "")
+	if tokens := tokenizer.Estimate(withoutMarkers); tokens > budget.MaxPromptTokens {
+		t.Errorf("prompt is ~%d tokens, want at most %d", tokens, budget.MaxPromptTokens)
+	}
+	if !strings.Contains(prompt, "Previous:\n"+words(100)) {
+		t.Error("previous output was trimmed although trimming the RAG context was enough")
+	}
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		tokens int
+		want   string
+	}{
+		{"keeps leading words", "one two  three\nfour five", 4, "one two  three" + truncationMarker},
+		{"fits", "one two", 40, "one two"},
+		{"nothing fits", "one two", 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateToTokens(tt.text, tt.tokens); got != tt.want {
+				t.Errorf("truncateToTokens = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/ai"
+)
+
+// fakeProvider is an OpenAI-compatible chat completions endpoint answering
+// every request, streamed or not, with a fixed reply
+type fakeProvider struct {
+	reply    string
+	requests atomic.Int32
+}
+
+// newFakeProvider starts a provider and returns a configuration where it is
+// the only available API, registered as groq
+func newFakeProvider(t *testing.T, reply string) (*fakeProvider, *ai.AIHelperConfig) {
+	t.Helper()
+	provider := &fakeProvider{reply: reply}
+	server := httptest.NewServer(http.HandlerFunc(provider.serve))
+	t.Cleanup(server.Close)
+
+	t.Setenv("WORKER_TEST_API_KEY", "test-key")
+	config := &ai.AIHelperConfig{
+		Groq: ai.APIConfig{
+			APIKeyVariable: "WORKER_TEST_API_KEY",
+			Models:         []string{"test-model"},
+			MaxTokens:      256,
+			Timeout:        5,
+			APIURL:         server.URL,
+		},
+	}
+	return provider, config
+}
+
+func (p *fakeProvider) serve(w http.ResponseWriter, r *http.Request) {
+	p.requests.Add(1)
+
+	var request ai.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if request.Stream {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", p.reply)
+		fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{},\"finish_reason\":\"stop\"}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		return
+	}
+
+	fmt.Fprintf(w, `{"model":"test-model","choices":[{"message":{"role":"assistant","content":%q},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":3,"total_tokens":8}}`, p.reply)
+}
@@ -0,0 +1,161 @@
+package worker
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// WorkerClaimTopic is where workers announce and defend their IDs
+const WorkerClaimTopic = "workers/claims/%s"
+
+// DefaultClaimWindow is how long a starting worker listens for a conflicting claim
+const DefaultClaimWindow = 2 * time.Second
+
+// ErrDuplicateWorkerID is returned when another live worker already uses the ID
+var ErrDuplicateWorkerID = errors.New("worker ID already in use")
+
+// WorkerClaim announces that an instance owns a worker ID. Active is set on
+// replies from an instance that has already completed registration.
+type WorkerClaim struct {
+	WorkerID string             `json:"worker_id"`
+	Roles    []types.WorkerRole `json:"roles,omitempty"` // Every role the instance serves
+	Instance string             `json:"instance"`
+	Active   bool               `json:"active"`
+}
+
+// Registration claims a worker ID on startup and answers later claims for
+// the same ID so a second instance refuses to start
+type Registration struct {
+	mqttClient mqtt.ClientInterface
+	workerID   string
+	roles      []types.WorkerRole
+	instance   string
+
+	mu         sync.Mutex
+	registered bool
+	conflict   *WorkerClaim
+}
+
+// NewInstanceID returns a random identifier for one worker process
+func NewInstanceID() string {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// NewRegistration creates a registration for the given worker instance
+// serving roles
+func NewRegistration(client mqtt.ClientInterface, workerID string, roles []types.WorkerRole, instance string) *Registration {
+	return &Registration{
+		mqttClient: client,
+		workerID:   workerID,
+		roles:      roles,
+		instance:   instance,
+	}
+}
+
+// Claim announces the worker ID and waits for window to hear from another
+// instance using it. It returns ErrDuplicateWorkerID on a conflict; otherwise
+// the registration keeps defending the ID until the client disconnects.
+func (r *Registration) Claim(ctx context.Context, window time.Duration) error {
+	topic := fmt.Sprintf(WorkerClaimTopic, r.workerID)
+	if err := r.mqttClient.Subscribe(ctx, topic, r.handleClaim); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", topic, err)
+	}
+
+	if err := r.publish(ctx, false); err != nil {
+		return fmt.Errorf("failed to claim worker ID %s: %w", r.workerID, err)
+	}
+
+	select {
+	case <-time.After(window):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conflict != nil {
+		return fmt.Errorf("%w: %s is already claimed by instance %s (roles %s); choose a different --id",
+			ErrDuplicateWorkerID, r.workerID, r.conflict.Instance, RoleLabel(r.conflict.Roles))
+	}
+
+	r.registered = true
+	return nil
+}
+
+// handleClaim records a conflicting claim while registering and answers
+// claims from newcomers once registered
+func (r *Registration) handleClaim(payload []byte) {
+	var claim WorkerClaim
+	if _, err := types.UnwrapMessage(payload, types.MessageTypeWorkerClaim, &claim); err != nil {
+		log.Printf("Failed to unmarshal worker claim: %v", err)
+		return
+	}
+
+	if claim.Instance == r.instance {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.registered {
+		r.conflict = &claim
+		return
+	}
+
+	log.Printf("Warning: instance %s (roles %s) tried to claim worker ID %s", claim.Instance, RoleLabel(claim.Roles), r.workerID)
+
+	// Only answer fresh claims, so two registered instances never reply to each other forever
+	if claim.Active {
+		return
+	}
+
+	// Reply off the delivery goroutine; publishing from a handler can stall the client
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := r.publish(ctx, true); err != nil {
+			log.Printf("Failed to answer claim for worker ID %s: %v", r.workerID, err)
+		}
+	}()
+}
+
+// publish sends this instance's claim
+func (r *Registration) publish(ctx context.Context, active bool) error {
+	claim := WorkerClaim{
+		WorkerID: r.workerID,
+		Roles:    r.roles,
+		Instance: r.instance,
+		Active:   active,
+	}
+
+	data, err := types.WrapMessage(types.MessageTypeWorkerClaim, r.workerID, claim)
+	if err != nil {
+		return err
+	}
+	return r.mqttClient.Publish(ctx, fmt.Sprintf(WorkerClaimTopic, r.workerID), data)
+}
+
+// RoleLabel names a set of roles in logs and client IDs
+func RoleLabel(roles []types.WorkerRole) string {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+	return strings.Join(names, "+")
+}
@@ -0,0 +1,131 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/mqtt"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// fakeBroker delivers every publish to the handlers subscribed to its topic
+type fakeBroker struct {
+	mu       sync.Mutex
+	handlers map[string][]mqtt.MessageHandler
+}
+
+// brokerClient is one connection to a fakeBroker
+type brokerClient struct {
+	broker *fakeBroker
+}
+
+func (c brokerClient) Connect(context.Context) error             { return nil }
+func (c brokerClient) Disconnect()                               {}
+func (c brokerClient) IsConnected() bool                         { return true }
+func (c brokerClient) Unsubscribe(context.Context, string) error { return nil }
+
+func (c brokerClient) Subscribe(ctx context.Context, topic string, handler mqtt.MessageHandler) error {
+	c.broker.mu.Lock()
+	defer c.broker.mu.Unlock()
+	if c.broker.handlers == nil {
+		c.broker.handlers = make(map[string][]mqtt.MessageHandler)
+	}
+	c.broker.handlers[topic] = append(c.broker.handlers[topic], handler)
+	return nil
+}
+
+func (c brokerClient) Publish(ctx context.Context, topic string, payload []byte) error {
+	c.broker.mu.Lock()
+	handlers := append([]mqtt.MessageHandler(nil), c.broker.handlers[topic]...)
+	c.broker.mu.Unlock()
+	for _, handler := range handlers {
+		handler(payload)
+	}
+	return nil
+}
+
+func TestRegistrationClaimsEveryRole(t *testing.T) {
+	broker := &fakeBroker{}
+	roles := []types.WorkerRole{types.RoleDeveloper, types.RoleReviewer}
+	first := NewRegistration(brokerClient{broker}, "worker-1", roles, "first")
+	if err := first.Claim(context.Background(), 10*time.Millisecond); err != nil {
+		t.Fatalf("first Claim: %v", err)
+	}
+
+	second := NewRegistration(brokerClient{broker}, "worker-1", []types.WorkerRole{types.RoleTester}, "second")
+	err := second.Claim(context.Background(), 200*time.Millisecond)
+	if !errors.Is(err, ErrDuplicateWorkerID) {
+		t.Fatalf("second Claim = %v, want ErrDuplicateWorkerID", err)
+	}
+
+	second.mu.Lock()
+	defer second.mu.Unlock()
+	if got := second.conflict.Roles; len(got) != 2 || got[0] != types.RoleDeveloper || got[1] != types.RoleReviewer {
+		t.Errorf("conflicting claim roles = %v, want %v", got, roles)
+	}
+	if !strings.Contains(err.Error(), "developer+reviewer") {
+		t.Errorf("error %q does not name every role", err)
+	}
+}
+
+func TestRoleLabel(t *testing.T) {
+	tests := []struct {
+		roles []types.WorkerRole
+		want  string
+	}{
+		{nil, ""},
+		{[]types.WorkerRole{types.RoleTester}, "tester"},
+		{[]types.WorkerRole{types.RoleDeveloper, types.RoleReviewer, types.RoleApprover}, "developer+reviewer+approver"},
+	}
+
+	for _, tt := range tests {
+		if got := RoleLabel(tt.roles); got != tt.want {
+			t.Errorf("RoleLabel(%v) = %q, want %q", tt.roles, got, tt.want)
+		}
+	}
+}
+
+func TestRegistrationClaim(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string // Worker ID already registered on the broker, if any
+		workerID string
+		wantErr  error
+	}{
+		{"sole worker", "", "worker-1", nil},
+		{"other ID", "worker-2", "worker-1", nil},
+		{"duplicate ID", "worker-1", "worker-1", ErrDuplicateWorkerID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker := &fakeBroker{}
+			if tt.existing != "" {
+				existing := NewRegistration(brokerClient{broker}, tt.existing, []types.WorkerRole{types.RoleDeveloper}, "existing")
+				if err := existing.Claim(context.Background(), 10*time.Millisecond); err != nil {
+					t.Fatalf("existing Claim: %v", err)
+				}
+			}
+
+			// The broker echoes the worker's own claim back, which must not count as a conflict
+			registration := NewRegistration(brokerClient{broker}, tt.workerID, []types.WorkerRole{types.RoleDeveloper}, "new")
+			err := registration.Claim(context.Background(), 100*time.Millisecond)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Claim = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistrationClaimHonoursContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	registration := NewRegistration(brokerClient{&fakeBroker{}}, "worker-1", nil, "only")
+	if err := registration.Claim(ctx, time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Claim = %v, want the context deadline", err)
+	}
+}
@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/prompts"
+	"github.com/niko/mqtt-agent-orchestration/internal/worker/markdown"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// ParseReviewItems extracts the review items a reviewer listed in json code
+// blocks of its output, as prompts.ReviewItemsFormat asks. Blocks that are not an array of items are ignored,
+// as are items without a message; unknown severities are read as minor.
+func ParseReviewItems(output string) []types.ReviewItem {
+	var items []types.ReviewItem
+	for _, block := range markdown.ExtractCodeBlocks(output) {
+		if block.Language != "json" && block.Language != "" {
+			continue
+		}
+
+		var parsed []types.ReviewItem
+		if err := json.Unmarshal([]byte(block.Code), &parsed); err != nil {
+			continue
+		}
+		for _, item := range parsed {
+			item.Message = strings.TrimSpace(item.Message)
+			if item.Message == "" {
+				continue
+			}
+			item.Severity = normalizeSeverity(item.Severity)
+			item.Location = strings.TrimSpace(item.Location)
+			item.Suggestion = strings.TrimSpace(item.Suggestion)
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// normalizeSeverity maps a reviewer's severity onto the known ones
+func normalizeSeverity(severity types.ReviewSeverity) types.ReviewSeverity {
+	switch normalized := types.ReviewSeverity(strings.ToLower(strings.TrimSpace(string(severity)))); normalized {
+	case types.SeverityCritical, types.SeverityMajor:
+		return normalized
+	default:
+		return types.SeverityMinor
+	}
+}
+
+// FormatReviewItems renders review items as a numbered list for the
+// developer's retry prompt, most severe first
+func FormatReviewItems(items []types.ReviewItem) string {
+	var formatted strings.Builder
+	n := 0
+	for _, severity := range []types.ReviewSeverity{types.SeverityCritical, types.SeverityMajor, types.SeverityMinor} {
+		for _, item := range items {
+			if normalizeSeverity(item.Severity) != severity {
+				continue
+			}
+			n++
+			fmt.Fprintf(&formatted, "%d. [%s]", n, severity)
+			if item.Location != "" {
+				fmt.Fprintf(&formatted, " %s:", item.Location)
+			}
+			fmt.Fprintf(&formatted, " %s\n", item.Message)
+			if item.Suggestion != "" {
+				fmt.Fprintf(&formatted, "   Suggestion: %s\n", item.Suggestion)
+			}
+		}
+	}
+	return strings.TrimSuffix(formatted.String(), "\n")
+}
+
+// reviewInstructions returns the review-related part of a stage prompt:
+// the format reviewers list issues in, and on a developer retry what must be
+// addressed, as structured review items when the reviewer gave them, else
+// its free-form feedback
+func reviewInstructions(task *types.WorkflowTask) string {
+	switch task.Stage {
+	case types.StageReview:
+		return prompts.ReviewItemsFormat
+	case types.StageDevelopment:
+		if len(task.ReviewItems) > 0 {
+			return "Address each review item:\n" + FormatReviewItems(task.ReviewItems)
+		}
+		if feedback := strings.TrimSpace(task.ReviewFeedback); feedback != "" {
+			return "Address this review feedback:\n" + feedback
+		}
+	}
+	return ""
+}
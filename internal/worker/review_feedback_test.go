@@ -0,0 +1,126 @@
+package worker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/prompts"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+func TestParseReviewItems(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []types.ReviewItem
+	}{
+		{
+			name: "json block",
+			output: "NEEDS_REVISION: incomplete\n\n```json\n" +
+				`[{"severity":"Major","location":" Setup ","message":" Missing install steps ","suggestion":"Add a go install line"},` +
+				`{"severity":"critical","message":"Example leaks a token"}]` + "\n```\n",
+			want: []types.ReviewItem{
+				{Severity: types.SeverityMajor, Location: "Setup", Message: "Missing install steps", Suggestion: "Add a go install line"},
+				{Severity: types.SeverityCritical, Message: "Example leaks a token"},
+			},
+		},
+		{
+			name:   "unknown severity is minor",
+			output: "```\n[{\"severity\":\"nit\",\"message\":\"Typo\"}]\n```",
+			want:   []types.ReviewItem{{Severity: types.SeverityMinor, Message: "Typo"}},
+		},
+		{
+			name:   "items without a message are dropped",
+			output: "```json\n[{\"severity\":\"major\",\"message\":\"  \"}]\n```",
+		},
+		{
+			name:   "other languages and objects are ignored",
+			output: "```go\n[{\"message\":\"not a review\"}]\n```\n```json\n{\"message\":\"not a list\"}\n```",
+		},
+		{
+			name:   "free-form feedback",
+			output: "APPROVED: looks good",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseReviewItems(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseReviewItems() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatReviewItems(t *testing.T) {
+	items := []types.ReviewItem{
+		{Severity: types.SeverityMinor, Message: "Typo in title"},
+		{Severity: types.SeverityCritical, Location: "Auth", Message: "Example leaks a token", Suggestion: "Use a placeholder"},
+		{Severity: types.SeverityMajor, Location: "Setup", Message: "Missing install steps"},
+	}
+
+	want := "1. [critical] Auth: Example leaks a token\n" +
+		"   Suggestion: Use a placeholder\n" +
+		"2. [major] Setup: Missing install steps\n" +
+		"3. [minor] Typo in title"
+	if got := FormatReviewItems(items); got != want {
+		t.Errorf("FormatReviewItems() = %q, want %q", got, want)
+	}
+	if got := FormatReviewItems(nil); got != "" {
+		t.Errorf("FormatReviewItems(nil) = %q, want empty", got)
+	}
+}
+
+func TestRetryPromptAddressesReviewItems(t *testing.T) {
+	items := ParseReviewItems("```json\n[{\"severity\":\"major\",\"location\":\"Setup\",\"message\":\"Missing install steps\"}]\n```")
+
+	tests := []struct {
+		name     string
+		task     func() *types.WorkflowTask
+		contains string
+		excludes string
+	}{
+		{
+			name: "review items",
+			task: func() *types.WorkflowTask {
+				task := newDocumentTask(types.RoleDeveloper, "api_guide", "")
+				task.ReviewFeedback, task.ReviewItems = "needs work", items
+				return task
+			},
+			contains: "Address each review item:\n1. [major] Setup: Missing install steps",
+			excludes: "needs work",
+		},
+		{
+			name: "free-form feedback",
+			task: func() *types.WorkflowTask {
+				task := newDocumentTask(types.RoleDeveloper, "api_guide", "")
+				task.ReviewFeedback = "needs work"
+				return task
+			},
+			contains: "Address this review feedback:\nneeds work",
+		},
+		{
+			name:     "reviewer is asked for items",
+			task:     func() *types.WorkflowTask { return newDocumentTask(types.RoleReviewer, "api_guide", "# Draft") },
+			contains: prompts.ReviewItemsFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execution := &TaskExecution{Task: tt.task()}
+			for name, prompt := range map[string]string{
+				"local":    execution.buildLocalPrompt(),
+				"detailed": execution.buildDetailedPrompt(),
+			} {
+				if !strings.Contains(prompt, tt.contains) {
+					t.Errorf("%s prompt missing %q in:\n%s", name, tt.contains, prompt)
+				}
+				if tt.excludes != "" && strings.Contains(prompt, tt.excludes) {
+					t.Errorf("%s prompt contains %q in:\n%s", name, tt.excludes, prompt)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"slices"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// modelLookup resolves a model name or alias to a configured model and its type
+type modelLookup func(name string) (string, localmodels.ModelType, bool)
+
+// preferredModels returns the configured models of modelType that role
+// prefers, most preferred first, skipping unknown and repeated entries
+func preferredModels(preferences localmodels.RolePreferences, role types.WorkerRole, modelType localmodels.ModelType, lookup modelLookup) []string {
+	var models []string
+	for _, name := range preferences.For(string(role)) {
+		configured, configuredType, exists := lookup(name)
+		if !exists || configuredType != modelType || slices.Contains(models, configured) {
+			continue
+		}
+		models = append(models, configured)
+	}
+	return models
+}
+
+// preferredLocalModels returns the text models the model manager has
+// configured that role prefers, most preferred first
+func (tr *TaskRouter) preferredLocalModels(role types.WorkerRole) []string {
+	return preferredModels(tr.preferences, role, localmodels.ModelTypeText, tr.lookupModel)
+}
+
+// lookupModel resolves a name or alias against the model manager's configs
+func (tr *TaskRouter) lookupModel(name string) (string, localmodels.ModelType, bool) {
+	config, exists := tr.localModelManager.GetModelConfig(name)
+	return tr.localModelManager.ResolveModel(name), config.Type, exists
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// preferenceModels are the models the role preference tests route between
+func preferenceModels() map[string]localmodels.ModelConfig {
+	return map[string]localmodels.ModelConfig{
+		"qwen-omni-3b": {Name: "qwen-omni-3b", Type: localmodels.ModelTypeText},
+		"phi-4":        {Name: "phi-4", Type: localmodels.ModelTypeText},
+		"qwen-vl-7b":   {Name: "qwen-vl-7b", Type: localmodels.ModelTypeMultimodal},
+	}
+}
+
+// preferences has the reviewer on a fast model and the developer on a capable one
+var preferences = localmodels.RolePreferences{
+	"developer": {"qwen-omni-3b", "phi-4"},
+	"reviewer":  {"missing", "qwen-vl-7b", "fast", "qwen-omni-3b"},
+	"approver":  {"qwen-omni-3b"},
+}
+
+func TestSelectLocalModelByRolePreference(t *testing.T) {
+	manager, err := localmodels.NewManager(localmodels.ModelManagerConfig{
+		DaemonURL: "http://127.0.0.1:0",
+		Models:    preferenceModels(),
+		Aliases:   localmodels.ModelAliases{"fast": "phi-4"},
+	})
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	router := NewTaskRouter(manager, nil)
+	router.SetRolePreferences(preferences)
+
+	tests := []struct {
+		role types.WorkerRole
+		want string
+	}{
+		{types.RoleDeveloper, "qwen-omni-3b"},
+		{types.RoleReviewer, "phi-4"}, // Unknown and multimodal entries are skipped, the alias resolved
+		{types.RoleApprover, "qwen-omni-3b"},
+		{types.RoleTester, "qwen-omni-3b"}, // No preferences keeps the built-in default
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			task := NewSelfTestTask(tt.role)
+			task.Type = "create_document"
+			if got := router.selectLocalModel(task); got != tt.want {
+				t.Errorf("selectLocalModel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeContentRolePreferences(t *testing.T) {
+	analyzer := NewContentAnalyzer(preferenceModels())
+	analyzer.SetAliases(localmodels.ModelAliases{"fast": "phi-4"})
+	analyzer.SetRolePreferences(preferences)
+
+	tests := []struct {
+		role             types.WorkerRole
+		wantModel        string
+		wantAlternatives []string
+	}{
+		{types.RoleDeveloper, "qwen-omni-3b", []string{"phi-4"}},
+		{types.RoleReviewer, "phi-4", []string{"qwen-omni-3b"}},
+		{types.RoleApprover, "qwen-omni-3b", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			result, err := analyzer.AnalyzeContent(context.Background(), newDocumentTask(tt.role, "release_notes", "# Draft"))
+			if err != nil {
+				t.Fatalf("AnalyzeContent() error = %v", err)
+			}
+			if result.RecommendedModel != tt.wantModel || !reflect.DeepEqual(result.AlternativeModels, tt.wantAlternatives) {
+				t.Errorf("AnalyzeContent() recommended %q with alternatives %v, want %q with %v",
+					result.RecommendedModel, result.AlternativeModels, tt.wantModel, tt.wantAlternatives)
+			}
+		})
+	}
+}
@@ -1,32 +1,54 @@
 package worker
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/niko/mqtt-agent-orchestration/internal/ai"
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
 	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
-	"github.com/niko/mqtt-agent-orchestration/internal/rag"
+	"github.com/niko/mqtt-agent-orchestration/internal/prompts"
+	"github.com/niko/mqtt-agent-orchestration/internal/worker/markdown"
 	"github.com/niko/mqtt-agent-orchestration/pkg/types"
 )
 
+// PayloadSeed is the payload key that fixes the local model sampling seed for reproducible output
+const PayloadSeed = "seed"
+
 // RoleBasedProcessor implements role-specific task processing
 type RoleBasedProcessor struct {
 	role            types.WorkerRole
 	capabilities    types.WorkerCapabilities
-	ragService      *rag.Service
+	ragService      ContextProvider
 	modelManager    *localmodels.Manager
 	contentAnalyzer *ContentAnalyzer
 	aiClient        *ai.AIClient
 	taskRouter      *TaskRouter
+	toolchains      *config.ToolchainConfig
+	promptBudget    *config.PromptBudgetConfig
+	templates       *config.DocumentTemplateConfig
+	validators      map[string]DocumentValidator // Registered per document type; others use their template
+	retrieval       *config.RetrievalConfig
+	prompts         *prompts.Set
+	progress        *ProgressTracker
 }
 
 // NewRoleBasedProcessor creates a processor for a specific role
-func NewRoleBasedProcessor(role types.WorkerRole, ragService *rag.Service, modelManager *localmodels.Manager, contentAnalyzer *ContentAnalyzer, aiConfig *ai.AIHelperConfig) *RoleBasedProcessor {
+func NewRoleBasedProcessor(role types.WorkerRole, ragService ContextProvider, modelManager *localmodels.Manager, contentAnalyzer *ContentAnalyzer, aiConfig *ai.AIHelperConfig) *RoleBasedProcessor {
 	capabilities := GetCapabilitiesForRole(role)
 	taskRouter := NewTaskRouter(modelManager, aiConfig)
+	var aiClient *ai.AIClient
+	if aiConfig != nil {
+		aiClient = ai.NewAIClientWithConfig(aiConfig)
+	}
 	
 	return &RoleBasedProcessor{
 		role:            role,
@@ -34,10 +56,71 @@ func NewRoleBasedProcessor(role types.WorkerRole, ragService *rag.Service, model
 		ragService:      ragService,
 		modelManager:    modelManager,
 		contentAnalyzer: contentAnalyzer,
+		aiClient:        aiClient,
 		taskRouter:      taskRouter,
+		toolchains:      config.DefaultToolchainConfig(),
+		promptBudget:    config.DefaultPromptBudgetConfig(),
+		templates:       config.DefaultDocumentTemplateConfig(),
+		retrieval:       config.DefaultRetrievalConfig(),
+		prompts:         prompts.Default(),
+		progress:        NewProgressTracker(),
 	}
 }
 
+// Progress returns the tracker following the workflow task being processed
+func (p *RoleBasedProcessor) Progress() *ProgressTracker {
+	return p.progress
+}
+
+// SetDocumentTemplates overrides the per-document-type requirements the tester enforces
+func (p *RoleBasedProcessor) SetDocumentTemplates(templates *config.DocumentTemplateConfig) {
+	p.templates = templates
+}
+
+// SetPrompts overrides the templates used to build each phase's prompt and
+// the compliance boilerplate added to every prompt
+func (p *RoleBasedProcessor) SetPrompts(set *prompts.Set) {
+	p.prompts = set
+	p.taskRouter.prompts = set
+	if p.aiClient != nil {
+		compliance := set.Compliance()
+		p.aiClient.SetCompliance(compliance.Prefix, compliance.Suffix)
+	}
+}
+
+// SetRetrievalConfig overrides the time budget for fetching RAG context
+func (p *RoleBasedProcessor) SetRetrievalConfig(retrieval *config.RetrievalConfig) {
+	p.retrieval = retrieval
+}
+
+// SetToolchains overrides the toolchains the tester uses to validate code examples
+func (p *RoleBasedProcessor) SetToolchains(toolchains *config.ToolchainConfig) {
+	p.toolchains = toolchains
+}
+
+// SetPromptBudget overrides the token budget applied when assembling prompts
+func (p *RoleBasedProcessor) SetPromptBudget(budget *config.PromptBudgetConfig) {
+	p.promptBudget = budget
+	p.taskRouter.promptBudget = budget
+}
+
+// SetComplexityConfig overrides the keyword weights used to route tasks
+func (p *RoleBasedProcessor) SetComplexityConfig(complexity *config.ComplexityConfig) {
+	p.taskRouter.SetComplexityConfig(complexity)
+}
+
+// SetRolePreferences sets the ordered local models each role prefers, used as
+// the routing order for text tasks
+func (p *RoleBasedProcessor) SetRolePreferences(preferences localmodels.RolePreferences) {
+	p.taskRouter.SetRolePreferences(preferences)
+}
+
+// SetMaxContinuations sets how many follow-up generations extend output
+// truncated at the token limit; zero disables continuation
+func (p *RoleBasedProcessor) SetMaxContinuations(maxContinuations int) {
+	p.taskRouter.SetMaxContinuations(maxContinuations)
+}
+
 // ProcessTask processes tasks according to the worker's role
 func (p *RoleBasedProcessor) ProcessTask(ctx context.Context, task types.Task) (string, error) {
 	// For now, this will be called with regular tasks and we'll extend them
@@ -54,306 +137,359 @@ func (p *RoleBasedProcessor) processSimpleTask(ctx context.Context, task types.T
 		}
 		return fmt.Sprintf("Echo from %s: (no message)", p.role), nil
 	default:
-		return "", fmt.Errorf("simple task type %s not supported by role %s", task.Type, p.role)
+		return "", fmt.Errorf("%w: simple task type %s not supported by role %s", ErrUnsupportedTaskType, task.Type, p.role)
 	}
 }
 
+// TaskOutcome is the output of a workflow task and how it was produced
+type TaskOutcome struct {
+	Output       string
+	FinishReason string // Why the model stopped; localmodels.FinishReasonLength when truncated
+	ServedBy     string // Model that produced the output when a fallback replaced the routed one
+}
+
+// retrievalContext bounds RAG retrieval so the model keeps most of the task deadline
+func (p *RoleBasedProcessor) retrievalContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	var remaining time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining = time.Until(deadline)
+	}
+
+	timeout := p.retrieval.RetrievalTimeout(remaining)
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
 // ProcessWorkflowTask processes workflow tasks according to the worker's role
-func (p *RoleBasedProcessor) ProcessWorkflowTask(ctx context.Context, workflowTask *types.WorkflowTask) (string, error) {
+func (p *RoleBasedProcessor) ProcessWorkflowTask(ctx context.Context, workflowTask *types.WorkflowTask) (TaskOutcome, error) {
+	if err := workflowTask.Validate(); err != nil {
+		return TaskOutcome{}, fmt.Errorf("%w: %w", ErrValidationFailed, err)
+	}
+
 	// Verify role match
 	if workflowTask.RequiredRole != p.role {
-		return "", fmt.Errorf("task requires role %s, but worker is %s", workflowTask.RequiredRole, p.role)
+		return TaskOutcome{}, fmt.Errorf("%w: task requires role %s, but worker is %s", ErrRoleMismatch, workflowTask.RequiredRole, p.role)
+	}
+
+	p.progress.Start(workflowTask.ID)
+	defer p.progress.Finish()
+
+	// Testers validate a document draft themselves rather than asking a model
+	documentType := workflowTask.Payload["document_type"]
+	if documentType != "" && p.role == types.RoleTester {
+		output, err := p.testDocument(ctx, workflowTask)
+		return TaskOutcome{Output: output}, err
+	}
+
+	// Document stages prompt with their phase template instead of the generic task prompt
+	phase, staged := stagePhases[p.role]
+	staged = staged && documentType != ""
+	if staged && phase != prompts.Create && workflowTask.PreviousOutput == "" {
+		return TaskOutcome{}, fmt.Errorf("%w: %s task requires previous output", ErrMissingPayloadField, p.role)
 	}
 
 	// Use task router to determine optimal execution strategy
 	execution, err := p.taskRouter.RouteTask(ctx, workflowTask)
 	if err != nil {
-		return "", fmt.Errorf("task routing failed: %w", err)
+		return TaskOutcome{}, fmt.Errorf("task routing failed: %w", err)
 	}
 
 	// Log routing decision for monitoring
-	fmt.Printf("Task routed: %s (Strategy: %v, Model: %s, API: %s)\n", 
-		execution.Reasoning, execution.Strategy, execution.ModelName, execution.APIProvider)
-
-	// Get RAG context if enabled
-	if p.ragService != nil && p.capabilities.RAGEnabled {
-		ragContext, err := p.ragService.GetRelevantContext(ctx, workflowTask.Type,
-			fmt.Sprintf("%s %s", workflowTask.Type, workflowTask.Payload["document_type"]))
-		if err == nil {
-			// Add RAG context to task payload for execution
-			if workflowTask.Payload == nil {
-				workflowTask.Payload = make(map[string]string)
-			}
-			workflowTask.Payload["rag_context"] = ragContext
-		}
+	logRoutingDecision(workflowTask, execution)
+
+	taskContext := p.buildTaskContext(ctx, workflowTask)
+	if staged {
+		execution.Prompt = p.buildOptimizedPrompt(taskContext, phase, documentType)
 	}
 
+	logCostEstimate(workflowTask, execution.EstimateCost())
+
 	// Execute using the determined strategy
+	execution.Progress = p.progress
 	result, err := execution.Execute(ctx, p.modelManager, p.aiClient)
 	if err != nil {
-		return "", fmt.Errorf("task execution failed: %w", err)
+		return TaskOutcome{}, fmt.Errorf("task execution failed: %w", err)
+	}
+
+	outcome := TaskOutcome{Output: result, FinishReason: execution.FinishReason}
+	if execution.ServedBy != "" && execution.ServedBy != execution.ModelName {
+		outcome.ServedBy = execution.ServedBy
 	}
+	return outcome, nil
+}
 
-	return result, nil
+// stagePhases maps the roles that write or judge a document to their prompt template
+var stagePhases = map[types.WorkerRole]string{
+	types.RoleDeveloper: prompts.Create,
+	types.RoleReviewer:  prompts.Review,
+	types.RoleApprover:  prompts.Approve,
 }
 
 // EnhancedTaskContext provides optimized context for AI API calls
 type EnhancedTaskContext struct {
-	SystemPrompt  string
-	RAGContext    string
-	Task          *types.WorkflowTask
-	ModelAnalysis *AnalysisResult
+	SystemPrompt string
+	RAGContext   string
+	Task         *types.WorkflowTask
 }
 
-// processDeveloperTask handles initial content creation
-func (p *RoleBasedProcessor) processDeveloperTask(ctx context.Context, taskContext *EnhancedTaskContext) (string, error) {
-	switch taskContext.Task.Type {
-	case "create_document":
-		return p.createDocument(ctx, taskContext)
-	default:
-		return "", fmt.Errorf("developer role doesn't support task type: %s", taskContext.Task.Type)
+// buildTaskContext gathers the role's system prompt and the RAG context for
+// a task. The RAG context is also added to the payload for the generic task
+// prompt. Retrieval failures are logged and the task continues without it.
+func (p *RoleBasedProcessor) buildTaskContext(ctx context.Context, workflowTask *types.WorkflowTask) *EnhancedTaskContext {
+	taskContext := &EnhancedTaskContext{Task: workflowTask}
+	if p.ragService == nil || !p.capabilities.RAGEnabled {
+		return taskContext
 	}
-}
 
-// processReviewerTask handles content review and improvement
-func (p *RoleBasedProcessor) processReviewerTask(ctx context.Context, taskContext *EnhancedTaskContext) (string, error) {
-	if taskContext.Task.PreviousOutput == "" {
-		return "", fmt.Errorf("reviewer task requires previous output")
-	}
+	ragCtx, ragCancel := p.retrievalContext(ctx)
+	defer ragCancel()
 
-	switch taskContext.Task.Type {
-	case "create_document":
-		return p.reviewDocument(ctx, taskContext)
-	default:
-		return "", fmt.Errorf("reviewer role doesn't support task type: %s", taskContext.Task.Type)
+	if provider, ok := p.ragService.(SystemPromptProvider); ok {
+		if systemPrompt, err := provider.GetSystemPrompt(ragCtx, p.role); err == nil {
+			taskContext.SystemPrompt = systemPrompt
+		}
 	}
-}
 
-// processApproverTask handles final approval
-func (p *RoleBasedProcessor) processApproverTask(ctx context.Context, taskContext *EnhancedTaskContext) (string, error) {
-	if taskContext.Task.PreviousOutput == "" {
-		return "", fmt.Errorf("approver task requires previous output")
+	documents, err := p.ragService.GetRelevantDocuments(ragCtx, workflowTask.Type,
+		fmt.Sprintf("%s %s", workflowTask.Type, workflowTask.Payload["document_type"]))
+	var ragContext string
+	if err == nil {
+		// Label each document with its source so the model can cite it
+		ragContext, err = p.prompts.RenderDocuments(prompts.NewDocuments(documents))
 	}
-
-	switch taskContext.Task.Type {
-	case "create_document":
-		return p.approveDocument(ctx, taskContext)
-	default:
-		return "", fmt.Errorf("approver role doesn't support task type: %s", taskContext.Task.Type)
+	if err != nil {
+		log.Printf("RAG context unavailable for task %s, continuing without it: %v", workflowTask.ID, err)
+		return taskContext
 	}
-}
-
-// processTesterTask handles testing and validation
-func (p *RoleBasedProcessor) processTesterTask(ctx context.Context, taskContext *EnhancedTaskContext) (string, error) {
-	switch taskContext.Task.Type {
-	case "create_document":
-		return p.testDocument(ctx, taskContext)
-	default:
-		return "", fmt.Errorf("tester role doesn't support task type: %s", taskContext.Task.Type)
+	if ragContext != "" {
+		taskContext.RAGContext = ragContext
+		if workflowTask.Payload == nil {
+			workflowTask.Payload = make(map[string]string)
+		}
+		workflowTask.Payload["rag_context"] = ragContext
 	}
+	return taskContext
 }
 
-// createDocument creates initial document content with optimized context
-func (p *RoleBasedProcessor) createDocument(ctx context.Context, taskContext *EnhancedTaskContext) (string, error) {
-	documentType := taskContext.Task.Payload["document_type"]
+// testDocument validates the document
+func (p *RoleBasedProcessor) testDocument(ctx context.Context, workflowTask *types.WorkflowTask) (string, error) {
+	content := workflowTask.PreviousOutput
+	documentType := workflowTask.Payload["document_type"]
 
-	// Try local model first if available
-	if p.modelManager != nil && taskContext.ModelAnalysis != nil {
-		result, err := p.processWithLocalModel(ctx, taskContext, taskContext.ModelAnalysis.RecommendedModel)
-		if err == nil {
-			return result, nil
-		}
-		// Fall back to external AI helper if local model fails
+	validator, exists := p.documentValidator(documentType)
+	if !exists {
+		return "Document testing not implemented for this type", nil
 	}
 
-	// Build optimized prompt using system prompt and RAG context
-	optimizedPrompt := p.buildOptimizedPrompt(taskContext, "create", documentType)
-
-	// Use most appropriate AI helper for initial creation
-	aiHelper := p.selectAIHelper("development")
-	cmd := exec.CommandContext(ctx, aiHelper, optimizedPrompt)
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("AI helper %s failed: %w", aiHelper, err)
+	if problems := validator.ValidateStructure(content); len(problems) > 0 {
+		return fmt.Sprintf("FAILED: Document does not meet the %s requirements:\n- %s",
+			documentType, strings.Join(problems, "\n- ")), nil
 	}
 
-	return string(output), nil
-}
-
-// reviewDocument reviews and improves existing content with optimized context
-func (p *RoleBasedProcessor) reviewDocument(ctx context.Context, taskContext *EnhancedTaskContext) (string, error) {
-	// Build optimized prompt for review phase
-	optimizedPrompt := p.buildOptimizedPrompt(taskContext, "review", taskContext.Task.Payload["document_type"])
+	if problems := validator.ValidateExamples(ctx, content); len(problems) > 0 {
+		return fmt.Sprintf("FAILED: Code examples did not validate:\n- %s", strings.Join(problems, "\n- ")), nil
+	}
 
-	// Use AI helper focused on review/analysis
-	aiHelper := p.selectAIHelper("review")
-	cmd := exec.CommandContext(ctx, aiHelper, optimizedPrompt)
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("review AI helper %s failed: %w", aiHelper, err)
+	if problems := validator.ValidateLinks(content); len(problems) > 0 {
+		return fmt.Sprintf("FAILED: Document links are broken:\n- %s", strings.Join(problems, "\n- ")), nil
 	}
 
-	return string(output), nil
+	return "PASSED: Document structure validates successfully", nil
 }
 
-// approveDocument performs final approval check with optimized context
-func (p *RoleBasedProcessor) approveDocument(ctx context.Context, taskContext *EnhancedTaskContext) (string, error) {
-	// Build optimized prompt for approval phase
-	optimizedPrompt := p.buildOptimizedPrompt(taskContext, "approve", taskContext.Task.Payload["document_type"])
+// checkDocumentTemplate returns what content lacks to meet the template,
+// phrased as feedback the developer can act on
+func checkDocumentTemplate(template config.DocumentTemplate, content string) []string {
+	var problems []string
 
-	// Use AI helper best for final analysis
-	aiHelper := p.selectAIHelper("approval")
-	cmd := exec.CommandContext(ctx, aiHelper, optimizedPrompt)
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("approval AI helper %s failed: %w", aiHelper, err)
+	if length := len(strings.TrimSpace(content)); length < template.MinLength {
+		problems = append(problems, fmt.Sprintf("Document is %d characters, below the minimum of %d. Expand every section with explanation and examples.",
+			length, template.MinLength))
 	}
 
-	return string(output), nil
-}
-
-// testDocument validates the document
-func (p *RoleBasedProcessor) testDocument(ctx context.Context, taskContext *EnhancedTaskContext) (string, error) {
-	content := taskContext.Task.PreviousOutput
-
-	// For coding standards, test by checking examples compile/lint
-	if taskContext.Task.Payload["document_type"] == "go_coding_standards" {
-		return p.testGoCodingStandards(ctx, content)
+	for _, section := range template.RequiredSections {
+		if !strings.Contains(content, section) {
+			problems = append(problems, fmt.Sprintf("Missing required section %q. Add a heading with this title and its content.", section))
+		}
 	}
 
-	return "Document testing not implemented for this type", nil
+	return problems
 }
 
-// buildOptimizedPrompt creates token-efficient prompts using system prompt and RAG context
-func (p *RoleBasedProcessor) buildOptimizedPrompt(taskContext *EnhancedTaskContext, phase, documentType string) string {
-	var prompt strings.Builder
-
-	// Start with system prompt for role context (reduces token usage by providing clear role definition)
-	if taskContext.SystemPrompt != "" {
-		prompt.WriteString(taskContext.SystemPrompt)
-		prompt.WriteString("\n\n")
-	}
+// languageNeutralTags are fence tags allowed in any document: shell commands,
+// program output and data formats rather than example source code
+var languageNeutralTags = map[string]bool{
+	"bash": true, "console": true, "text": true, "plaintext": true, "output": true,
+	"json": true, "yaml": true, "yml": true, "toml": true, "xml": true, "ini": true,
+	"markdown": true, "md": true, "diff": true, "sql": true, "dockerfile": true, "makefile": true,
+}
 
-	// Add relevant RAG context (reduces token usage by providing specific domain knowledge)
-	if taskContext.RAGContext != "" {
-		prompt.WriteString("Relevant Context:\n")
-		prompt.WriteString(taskContext.RAGContext)
-		prompt.WriteString("\n\n")
+// checkCodeLanguage reports code blocks written in a programming language
+// other than the requested one, judged by their fence tag and by detecting
+// the language of the code itself so a mis-tagged block is still caught
+func checkCodeLanguage(language, content string) []string {
+	requested := markdown.NormalizeLanguage(language)
+	if requested == "" {
+		return nil
 	}
 
-	// Add phase-specific instructions (concise and targeted)
-	switch phase {
-	case "create":
-		prompt.WriteString(fmt.Sprintf("Create a comprehensive %s document.", documentType))
-
-	case "review":
-		prompt.WriteString(fmt.Sprintf("Review and improve this %s document.\n\nPrevious version:\n%s",
-			documentType, taskContext.Task.PreviousOutput))
+	var mismatches []string
+	for _, block := range markdown.ExtractCodeBlocks(content) {
+		tagged := markdown.NormalizeLanguage(block.Language)
+		detected := markdown.DetectLanguage(block.Code)
+
+		found := tagged
+		switch {
+		case tagged == requested:
+			found = detected
+		case tagged == "":
+			found = detected
+		case languageNeutralTags[tagged]:
+			// Shell snippets look like bash, so only flag them when detection says otherwise
+			if detected != "" && detected != "bash" && detected != requested {
+				found = detected
+			} else {
+				found = ""
+			}
+		}
 
-	case "approve":
-		prompt.WriteString(fmt.Sprintf("Perform final approval for this %s document.\n\nContent to approve:\n%s\n\nRespond with APPROVED: [reason] or REJECTED: [issues]",
-			documentType, taskContext.Task.PreviousOutput))
+		if found != "" && found != requested {
+			mismatches = append(mismatches, fmt.Sprintf("example %d (lines %d-%d) looks like %s",
+				block.Index, block.StartLine, block.EndLine, found))
+		}
 	}
-
-	return prompt.String()
+	return mismatches
 }
 
-// processWithLocalModel processes a task using a local model
-func (p *RoleBasedProcessor) processWithLocalModel(ctx context.Context, taskContext *EnhancedTaskContext, modelName string) (string, error) {
-	if p.modelManager == nil {
-		return "", fmt.Errorf("model manager not available")
-	}
-
-	// Load the model if not already loaded
-	if err := p.modelManager.LoadModel(ctx, modelName); err != nil {
-		return "", fmt.Errorf("failed to load model %s: %w", modelName, err)
+// buildOptimizedPrompt renders the phase's prompt template with the system prompt and RAG context.
+// RAG context and previous output are trimmed to fit the prompt budget.
+func (p *RoleBasedProcessor) buildOptimizedPrompt(taskContext *EnhancedTaskContext, phase, documentType string) string {
+	sections := promptSections{
+		RAGContext:     taskContext.RAGContext,
+		PreviousOutput: taskContext.Task.PreviousOutput,
 	}
 
-	// Get model instance
-	model, err := p.modelManager.GetModel(modelName)
-	if err != nil {
-		return "", fmt.Errorf("failed to get model %s: %w", modelName, err)
-	}
+	return renderWithinBudget(p.promptBudget, sections, func(sections promptSections) string {
+		promptContext := prompts.Context{
+			SystemPrompt:   taskContext.SystemPrompt,
+			DocumentType:   documentType,
+			PreviousOutput: sections.PreviousOutput,
+			ReviewFeedback: taskContext.Task.ReviewFeedback,
+			ReviewItems:    FormatReviewItems(taskContext.Task.ReviewItems),
+			RAGContext:     sections.RAGContext,
+		}
+		if phase == prompts.Review {
+			promptContext.CodeBlocks = codeBlockIndex(sections.PreviousOutput)
+		}
 
-	// Build optimized prompt
-	documentType := taskContext.Task.Payload["document_type"]
-	optimizedPrompt := p.buildOptimizedPrompt(taskContext, "create", documentType)
+		prompt, err := p.prompts.Render(phase, promptContext)
+		if err != nil {
+			// A broken custom template must not stall the workflow
+			log.Printf("Warning: %v, using built-in template", err)
+			prompt, _ = prompts.Default().Render(phase, promptContext)
+		}
+		return p.prompts.Wrap(prompt)
+	})
+}
 
-	// Create model input
-	input := localmodels.ModelInput{
-		Text:        optimizedPrompt,
-		Temperature: 0.7,
-		MaxTokens:   2048,
+// codeBlockIndex lists a document's code blocks by number and line range so
+// review comments can point at a specific example
+func codeBlockIndex(content string) string {
+	blocks := markdown.ExtractCodeBlocks(content)
+	if len(blocks) == 0 {
+		return ""
 	}
 
-	// Process with local model
-	output, err := model.Predict(ctx, input)
-	if err != nil {
-		return "", fmt.Errorf("local model prediction failed: %w", err)
+	var index strings.Builder
+	index.WriteString("\n\nCode blocks (refer to them by number when commenting):\n")
+	for _, block := range blocks {
+		language := block.Language
+		if language == "" {
+			language = "untagged"
+		}
+		index.WriteString(fmt.Sprintf("%d. %s, lines %d-%d\n", block.Index, language, block.StartLine, block.EndLine))
 	}
-
-	return output.Text, nil
+	return index.String()
 }
 
 // Helper methods
 
 func (p *RoleBasedProcessor) buildGoCodingStandardsPrompt(ragContext string) string {
-	return fmt.Sprintf(`Create comprehensive Go coding standards document. Include:
-
-1. Core Principles (explicit over implicit, composition over inheritance)
-2. Package Management (naming, organization, imports)
-3. Variable/Constant Declaration (naming, scoping, zero values)
-4. Function/Method Design (naming, parameters, returns, receivers)
-5. Error Handling (explicit handling, wrapping, checking)
-6. Struct/Interface Design (composition, embedding, small interfaces)
-7. Concurrency Patterns (goroutines, channels, context)
-8. Testing Standards (table-driven, mocking, benchmarks)
-9. Code Organization (directory structure, files)
-10. Performance Guidelines (allocation, strings, profiling)
-11. Documentation (godoc, comments, examples)
-12. Security (validation, secrets)
-13. Compliance Checklist
-
-Use this context: %s
-
-Format as markdown with clear good/bad examples. Make it comprehensive but practical.`, ragContext)
+	prompt, err := p.prompts.Render(prompts.GoCodingStandards, prompts.Context{RAGContext: ragContext})
+	if err != nil {
+		log.Printf("Warning: %v, using built-in template", err)
+		prompt, _ = prompts.Default().Render(prompts.GoCodingStandards, prompts.Context{RAGContext: ragContext})
+	}
+	return prompt
 }
 
-func (p *RoleBasedProcessor) selectAIHelper(phase string) string {
-	switch phase {
-	case "development":
-		return "gemini_code_analyzer" // Best for comprehensive content
-	case "review":
-		return "cerebras_code_analyzer" // Fast and good for improvements
-	case "approval":
-		return "groq_fast_analyzer" // Quick final checks
-	default:
-		return "gemini_code_analyzer"
+// validateCodeExamples runs each fenced code block through the toolchain configured for its language
+func (p *RoleBasedProcessor) validateCodeExamples(ctx context.Context, content string) []string {
+	if p.toolchains == nil {
+		return nil
+	}
+
+	var failures []string
+	for _, block := range markdown.ExtractCodeBlocks(content) {
+		toolchain, exists := p.toolchains.GetToolchain(block.Language)
+		if !exists {
+			continue
+		}
+
+		// No Go toolchain accepts a fragment without a package clause, so
+		// those are only checked for syntax
+		if toolchain.Extension == ".go" {
+			if fragment, err := checkGoFragment(block.Code); fragment {
+				if err != nil {
+					failures = append(failures, fmt.Sprintf("example %d (%s, lines %d-%d): %v",
+						block.Index, block.Language, block.StartLine, block.EndLine, err))
+				}
+				continue
+			}
+		}
+
+		if err := p.runToolchain(ctx, toolchain, block.Code); err != nil {
+			failures = append(failures, fmt.Sprintf("example %d (%s, lines %d-%d): %v",
+				block.Index, block.Language, block.StartLine, block.EndLine, err))
+		}
 	}
+
+	return failures
 }
 
-func (p *RoleBasedProcessor) testGoCodingStandards(ctx context.Context, content string) (string, error) {
-	// Extract Go code examples and test them
-	// For now, just validate the document structure
-	requiredSections := []string{
-		"Core Principles",
-		"Error Handling",
-		"Testing Standards",
-		"Compliance Checklist",
+// runToolchain writes a code example to a temp file and runs the toolchain against it
+func (p *RoleBasedProcessor) runToolchain(ctx context.Context, toolchain config.Toolchain, code string) error {
+	dir, err := os.MkdirTemp("", "tester-example-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp dir: %w", err)
 	}
+	defer os.RemoveAll(dir)
 
-	var missingSection []string
-	for _, section := range requiredSections {
-		if !strings.Contains(content, section) {
-			missingSection = append(missingSection, section)
-		}
+	filePath := filepath.Join(dir, "example"+toolchain.Extension)
+	if err := os.WriteFile(filePath, []byte(code), 0644); err != nil {
+		return fmt.Errorf("failed to write example: %w", err)
 	}
 
-	if len(missingSection) > 0 {
-		return fmt.Sprintf("FAILED: Missing required sections: %s", strings.Join(missingSection, ", ")), nil
+	cmd := exec.CommandContext(ctx, toolchain.Path, toolchain.BuildArgs(filePath)...)
+	cmd.Dir = dir
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		// A toolchain that is not installed says nothing about the example
+		var execErr *exec.Error
+		if errors.As(err, &execErr) {
+			log.Printf("Warning: toolchain %s unavailable, skipped validating the example: %v", toolchain.Path, err)
+			return nil
+		}
+		return fmt.Errorf("%w: %s failed: %w: %s", ErrValidationFailed, toolchain.Path, err, strings.TrimSpace(output.String()))
 	}
 
-	return "PASSED: Document structure validates successfully", nil
+	return nil
 }
 
 // GetCapabilitiesForRole returns capabilities for each role (exported)
@@ -391,6 +527,20 @@ func GetCapabilitiesForRole(role types.WorkerRole) types.WorkerCapabilities {
 			Specialization: "validation",
 			RAGEnabled:     false,
 		}
+	case types.RoleEmbedder:
+		return types.WorkerCapabilities{
+			Roles:          []types.WorkerRole{types.RoleEmbedder},
+			AIHelpers:      []string{},
+			Specialization: "embedding",
+			RAGEnabled:     true,
+		}
+	case types.RoleOrchestrator:
+		return types.WorkerCapabilities{
+			Roles:          []types.WorkerRole{types.RoleOrchestrator},
+			AIHelpers:      []string{},
+			Specialization: "coordination",
+			RAGEnabled:     true,
+		}
 	default:
 		return types.WorkerCapabilities{}
 	}
@@ -0,0 +1,256 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
+	"github.com/niko/mqtt-agent-orchestration/internal/rag"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// newDocumentTask creates a document workflow task for role
+func newDocumentTask(role types.WorkerRole, documentType, previousOutput string) *types.WorkflowTask {
+	task := &types.WorkflowTask{WorkflowID: "wf-1", Stage: selfTestStage(role), RequiredRole: role, PreviousOutput: previousOutput}
+	task.ID = "task-1"
+	task.Type = "create_document"
+	task.Payload = map[string]string{"document_type": documentType}
+	task.CreatedAt = time.Now()
+	return task
+}
+
+func TestProcessWorkflowTaskUsesStagePrompt(t *testing.T) {
+	tests := []struct {
+		role     types.WorkerRole
+		previous string
+		want     []string
+	}{
+		{types.RoleDeveloper, "", []string{"Create a comprehensive api_guide document."}},
+		{types.RoleReviewer, "draft body", []string{"Review and improve this api_guide document.", "draft body"}},
+		{types.RoleApprover, "final body", []string{"Perform final approval for this api_guide document.", "final body"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			daemon, server := newFakeDaemon(t, echoPrediction("APPROVED: looks good"))
+			processor := NewRoleBasedProcessor(tt.role, nil, newDaemonManager(t, server.URL, nil), nil, nil)
+
+			outcome, err := processor.ProcessWorkflowTask(context.Background(), newDocumentTask(tt.role, "api_guide", tt.previous))
+			if err != nil {
+				t.Fatalf("ProcessWorkflowTask: %v", err)
+			}
+			if outcome.Output != "APPROVED: looks good" {
+				t.Errorf("Output = %q", outcome.Output)
+			}
+
+			prompts := daemon.prompts("qwen-omni-3b")
+			if len(prompts) != 1 {
+				t.Fatalf("got %d predictions, want 1", len(prompts))
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(prompts[0], want) {
+					t.Errorf("prompt %q does not contain %q", prompts[0], want)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessWorkflowTaskGenericPrompt(t *testing.T) {
+	daemon, server := newFakeDaemon(t, echoPrediction("OK"))
+	processor := NewRoleBasedProcessor(types.RoleDeveloper, nil, newDaemonManager(t, server.URL, nil), nil, nil)
+
+	outcome, err := processor.ProcessWorkflowTask(context.Background(), NewSelfTestTask(types.RoleDeveloper))
+	if err != nil {
+		t.Fatalf("ProcessWorkflowTask: %v", err)
+	}
+	if outcome.Output != "OK" || outcome.FinishReason != "stop" || outcome.ServedBy != "" {
+		t.Errorf("outcome = %+v", outcome)
+	}
+	if prompts := daemon.prompts("qwen-omni-3b"); len(prompts) != 1 || !strings.Contains(prompts[0], "Task: "+SelfTestTaskType) {
+		t.Errorf("prompts = %q, want the generic task prompt", prompts)
+	}
+}
+
+func TestProcessWorkflowTaskRequiresPreviousOutput(t *testing.T) {
+	for _, role := range []types.WorkerRole{types.RoleReviewer, types.RoleApprover} {
+		processor := NewRoleBasedProcessor(role, nil, nil, nil, nil)
+		_, err := processor.ProcessWorkflowTask(context.Background(), newDocumentTask(role, "api_guide", ""))
+		if !errors.Is(err, ErrMissingPayloadField) {
+			t.Errorf("%s: err = %v, want ErrMissingPayloadField", role, err)
+		}
+	}
+}
+
+// stubValidator reports fixed problems for each check
+type stubValidator struct {
+	structure, examples, links []string
+}
+
+func (v stubValidator) ValidateStructure(string) []string                 { return v.structure }
+func (v stubValidator) ValidateExamples(context.Context, string) []string { return v.examples }
+func (v stubValidator) ValidateLinks(string) []string                     { return v.links }
+
+func TestProcessWorkflowTaskTesterValidates(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator DocumentValidator
+		document  string
+		want      string
+	}{
+		{"passes", stubValidator{}, "custom", "PASSED:"},
+		{"structure", stubValidator{structure: []string{"no title"}}, "custom", "FAILED: Document does not meet the custom requirements:\n- no title"},
+		{"examples", stubValidator{examples: []string{"bad code"}}, "custom", "FAILED: Code examples did not validate:\n- bad code"},
+		{"links", stubValidator{links: []string{"dead link"}}, "custom", "FAILED: Document links are broken:\n- dead link"},
+		{"unknown type", nil, "unregistered", "Document testing not implemented"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewRoleBasedProcessor(types.RoleTester, nil, nil, nil, nil)
+			if tt.validator != nil {
+				processor.RegisterDocumentValidator(tt.document, tt.validator)
+			}
+
+			outcome, err := processor.ProcessWorkflowTask(context.Background(), newDocumentTask(types.RoleTester, tt.document, "# Doc"))
+			if err != nil {
+				t.Fatalf("ProcessWorkflowTask: %v", err)
+			}
+			if !strings.HasPrefix(outcome.Output, tt.want) {
+				t.Errorf("Output = %q, want prefix %q", outcome.Output, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCodeLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		content  string
+		want     int
+	}{
+		{"go in go doc", "go", "```go\npackage main\n\nfunc main() {}\n```", 0},
+		{"python in go doc", "go", "```python\ndef main():\n    print('hi')\n```", 1},
+		{"mis-tagged python", "go", "```go\ndef main():\n    print('hi')\n    return None\n```", 1},
+		{"shell is neutral", "go", "```bash\ngo test ./...\n```", 0},
+		{"no language requested", "", "```python\nprint('hi')\n```", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkCodeLanguage(tt.language, tt.content); len(got) != tt.want {
+				t.Errorf("checkCodeLanguage = %q, want %d mismatches", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCodeExamplesRunsToolchains(t *testing.T) {
+	// The text toolchain accepts examples containing "valid"; the Go one
+	// rejects everything it is given
+	processor := NewRoleBasedProcessor(types.RoleTester, nil, nil, nil, nil)
+	processor.SetToolchains(&config.ToolchainConfig{Toolchains: map[string]config.Toolchain{
+		"text":    {Path: "grep", Args: []string{"-q", "valid", config.FilePlaceholder}, Extension: ".txt"},
+		"go":      {Path: "false", Extension: ".go"},
+		"missing": {Path: "no-such-toolchain", Extension: ".txt"},
+	}})
+
+	tests := []struct {
+		name    string
+		content string
+		want    string // Example named by the only failure; empty for none
+	}{
+		{"passes", "```text\nvalid\n```\n\n```rust\nunchecked\n```\n", ""},
+		{"fails", "```text\nvalid\n```\n\n```text\nbroken\n```\n", "example 2 (text"},
+		{"toolchain not installed", "```missing\nanything\n```\n", ""},
+		{"go declarations", "```go\nfunc add(a, b int) int { return a + b }\n```\n", ""},
+		{"go statements", "```go\nif err != nil {\n\treturn fmt.Errorf(\"load: %w\", err)\n}\n```\n", ""},
+		{"broken go fragment", "```go\nif err != nil {\n```\n", "example 1 (go"},
+		{"complete go file", "```go\npackage main\n\nfunc main() {}\n```\n", "example 1 (go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failures := processor.validateCodeExamples(context.Background(), tt.content)
+			if tt.want == "" {
+				if len(failures) != 0 {
+					t.Errorf("failures = %q, want none", failures)
+				}
+				return
+			}
+			if len(failures) != 1 || !strings.Contains(failures[0], tt.want) {
+				t.Errorf("failures = %q, want one naming %q", failures, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCapabilitiesForRole(t *testing.T) {
+	tests := []struct {
+		role               types.WorkerRole
+		wantSpecialization string
+		wantRAG            bool
+		wantHelpers        bool
+	}{
+		{types.RoleDeveloper, "content_creation", true, true},
+		{types.RoleReviewer, "content_review", true, true},
+		{types.RoleApprover, "final_approval", true, true},
+		{types.RoleTester, "validation", false, true},
+		{types.RoleEmbedder, "embedding", true, false},
+		{types.RoleOrchestrator, "coordination", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			got := GetCapabilitiesForRole(tt.role)
+			if len(got.Roles) != 1 || got.Roles[0] != tt.role {
+				t.Errorf("Roles = %v, want [%s]", got.Roles, tt.role)
+			}
+			if got.Specialization != tt.wantSpecialization {
+				t.Errorf("Specialization = %q, want %q", got.Specialization, tt.wantSpecialization)
+			}
+			if got.RAGEnabled != tt.wantRAG {
+				t.Errorf("RAGEnabled = %v, want %v", got.RAGEnabled, tt.wantRAG)
+			}
+			if hasHelpers := len(got.AIHelpers) > 0; hasHelpers != tt.wantHelpers {
+				t.Errorf("AIHelpers = %v, want helpers %v", got.AIHelpers, tt.wantHelpers)
+			}
+		})
+	}
+
+	if got := GetCapabilitiesForRole("unknown"); len(got.Roles) != 0 {
+		t.Errorf("unknown role capabilities = %+v, want empty", got)
+	}
+}
+
+func TestProcessWorkflowTaskCitesRetrievedSources(t *testing.T) {
+	store := rag.NewMemoryService()
+	for _, doc := range []types.RAGDocument{
+		{Content: "create_document guides need examples", Source: "guides.md"},
+		{Content: "create_document an api_guide lists every endpoint", Source: "api.md"},
+	} {
+		if err := store.AddDocument(context.Background(), "coding_standards", doc); err != nil {
+			t.Fatalf("AddDocument: %v", err)
+		}
+	}
+
+	daemon, server := newFakeDaemon(t, echoPrediction("# API guide"))
+	processor := NewRoleBasedProcessor(types.RoleDeveloper, store, newDaemonManager(t, server.URL, nil), nil, nil)
+	if _, err := processor.ProcessWorkflowTask(context.Background(), newDocumentTask(types.RoleDeveloper, "api_guide", "")); err != nil {
+		t.Fatalf("ProcessWorkflowTask: %v", err)
+	}
+
+	prompts := daemon.prompts("qwen-omni-3b")
+	if len(prompts) != 1 {
+		t.Fatalf("got %d predictions, want 1", len(prompts))
+	}
+	for _, want := range []string{"Source: guides.md", "Source: api.md", "Cite sources by their label"} {
+		if !strings.Contains(prompts[0], want) {
+			t.Errorf("prompt %q does not contain %q", prompts[0], want)
+		}
+	}
+}
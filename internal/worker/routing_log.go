@@ -0,0 +1,66 @@
+package worker
+
+import (
+	"expvar"
+	"log/slog"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// routingDecisions counts routing decisions per execution strategy (exported via expvar)
+var routingDecisions = expvar.NewMap("worker_routing_decisions")
+
+// String returns the strategy name used in logs and metrics
+func (s ExecutionStrategy) String() string {
+	switch s {
+	case ExecutionStrategyLocal:
+		return "local"
+	case ExecutionStrategyAPI:
+		return "api"
+	case ExecutionStrategyHybrid:
+		return "hybrid"
+	default:
+		return "unknown"
+	}
+}
+
+// String returns the complexity name used in logs
+func (c TaskComplexity) String() string {
+	switch c {
+	case ComplexitySimple:
+		return "simple"
+	case ComplexityMedium:
+		return "medium"
+	case ComplexityHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// logRoutingDecision emits a structured log entry for a routing decision and
+// increments the per-strategy counter
+func logRoutingDecision(task *types.WorkflowTask, execution *TaskExecution) {
+	strategy := execution.Strategy.String()
+	routingDecisions.Add(strategy, 1)
+
+	slog.Info("task routed",
+		"task_id", task.ID,
+		"workflow_id", task.WorkflowID,
+		"stage", string(task.Stage),
+		"strategy", strategy,
+		"complexity", execution.Complexity.String(),
+		"model", execution.ModelName,
+		"provider", execution.APIProvider,
+		"mcp_enabled", execution.MCPEnabled,
+		"reasoning", execution.Reasoning,
+	)
+}
+
+// RoutingDecisionCount returns how many tasks were routed with strategy
+func RoutingDecisionCount(strategy ExecutionStrategy) int64 {
+	if counter, ok := routingDecisions.Get(strategy.String()).(*expvar.Int); ok {
+		return counter.Value()
+	}
+	return 0
+}
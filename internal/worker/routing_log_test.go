@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// captureLogs sends slog output to a JSON buffer for the rest of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func TestLogRoutingDecision(t *testing.T) {
+	tests := []struct {
+		name      string
+		execution TaskExecution
+		want      map[string]any
+	}{
+		{"local", TaskExecution{
+			Strategy:   ExecutionStrategyLocal,
+			Complexity: ComplexitySimple,
+			ModelName:  "qwen",
+			Reasoning:  "simple task",
+		}, map[string]any{"strategy": "local", "complexity": "simple", "model": "qwen", "provider": "", "reasoning": "simple task", "mcp_enabled": false}},
+		{"api", TaskExecution{
+			Strategy:    ExecutionStrategyAPI,
+			Complexity:  ComplexityHigh,
+			ModelName:   "gemini-2.5-pro",
+			APIProvider: "gemini",
+			MCPEnabled:  true,
+			Reasoning:   "complex task",
+		}, map[string]any{"strategy": "api", "complexity": "high", "model": "gemini-2.5-pro", "provider": "gemini", "reasoning": "complex task", "mcp_enabled": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			before := RoutingDecisionCount(tt.execution.Strategy)
+			task := &types.WorkflowTask{Task: types.Task{ID: "t1"}, WorkflowID: "wf-1", Stage: types.StageDevelopment}
+
+			logRoutingDecision(task, &tt.execution)
+
+			var entry map[string]any
+			if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+				t.Fatalf("log entry %q: %v", logs.String(), err)
+			}
+			if entry["msg"] != "task routed" || entry["task_id"] != "t1" || entry["workflow_id"] != "wf-1" || entry["stage"] != "development" {
+				t.Errorf("entry = %v, want the task identified", entry)
+			}
+			for field, want := range tt.want {
+				if entry[field] != want {
+					t.Errorf("%s = %v, want %v", field, entry[field], want)
+				}
+			}
+			if got := RoutingDecisionCount(tt.execution.Strategy); got != before+1 {
+				t.Errorf("RoutingDecisionCount = %d, want %d", got, before+1)
+			}
+		})
+	}
+}
+
+func TestRoutingNames(t *testing.T) {
+	strategies := map[ExecutionStrategy]string{
+		ExecutionStrategyLocal:  "local",
+		ExecutionStrategyAPI:    "api",
+		ExecutionStrategyHybrid: "hybrid",
+		ExecutionStrategy(99):   "unknown",
+	}
+	for strategy, want := range strategies {
+		if got := strategy.String(); got != want {
+			t.Errorf("ExecutionStrategy(%d).String() = %q, want %q", strategy, got, want)
+		}
+	}
+
+	complexities := map[TaskComplexity]string{
+		ComplexitySimple:   "simple",
+		ComplexityMedium:   "medium",
+		ComplexityHigh:     "high",
+		TaskComplexity(99): "unknown",
+	}
+	for complexity, want := range complexities {
+		if got := complexity.String(); got != want {
+			t.Errorf("TaskComplexity(%d).String() = %q, want %q", complexity, got, want)
+		}
+	}
+}
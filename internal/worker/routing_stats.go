@@ -0,0 +1,78 @@
+package worker
+
+import "sync"
+
+// RoutingStats is a snapshot of the routing decisions a TaskRouter has made
+type RoutingStats struct {
+	Total        int64            `json:"total"`
+	Local        int64            `json:"local"`
+	API          int64            `json:"api"`
+	Hybrid       int64            `json:"hybrid"`
+	Fallbacks    int64            `json:"fallbacks"` // Tasks sent to an API after their local model failed
+	Failures     int64            `json:"failures"`  // Tasks no execution strategy could be found for
+	ByComplexity map[string]int64 `json:"by_complexity"`
+}
+
+// routingCounters accumulates routing decisions; safe for concurrent use
+type routingCounters struct {
+	mu    sync.Mutex
+	stats RoutingStats
+}
+
+// record counts a routed task under its strategy and complexity
+func (c *routingCounters) record(execution *TaskExecution) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Total++
+	switch execution.Strategy {
+	case ExecutionStrategyLocal:
+		c.stats.Local++
+	case ExecutionStrategyAPI:
+		c.stats.API++
+	case ExecutionStrategyHybrid:
+		c.stats.Hybrid++
+	}
+
+	if c.stats.ByComplexity == nil {
+		c.stats.ByComplexity = make(map[string]int64)
+	}
+	c.stats.ByComplexity[execution.Complexity.String()]++
+}
+
+// recordFallback counts a task rerouted to an API after a local failure
+func (c *routingCounters) recordFallback() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Fallbacks++
+}
+
+// recordFailure counts a task that could not be routed
+func (c *routingCounters) recordFailure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Failures++
+}
+
+// snapshot returns a copy of the counters
+func (c *routingCounters) snapshot() RoutingStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.ByComplexity = make(map[string]int64, len(c.stats.ByComplexity))
+	for complexity, count := range c.stats.ByComplexity {
+		stats.ByComplexity[complexity] = count
+	}
+	return stats
+}
+
+// Stats returns the routing decisions this router has made so far
+func (tr *TaskRouter) Stats() RoutingStats {
+	return tr.stats.snapshot()
+}
+
+// RoutingStats returns the routing decisions made for this processor's tasks
+func (p *RoleBasedProcessor) RoutingStats() RoutingStats {
+	return p.taskRouter.Stats()
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRoutingCountersRecord(t *testing.T) {
+	tests := []struct {
+		name       string
+		executions []TaskExecution
+		fallbacks  int
+		failures   int
+		want       RoutingStats
+	}{
+		{"empty", nil, 0, 0, RoutingStats{ByComplexity: map[string]int64{}}},
+		{"by strategy", []TaskExecution{
+			{Strategy: ExecutionStrategyLocal, Complexity: ComplexitySimple},
+			{Strategy: ExecutionStrategyLocal, Complexity: ComplexityMedium},
+			{Strategy: ExecutionStrategyAPI, Complexity: ComplexityHigh},
+			{Strategy: ExecutionStrategyHybrid, Complexity: ComplexityHigh},
+		}, 0, 0, RoutingStats{
+			Total: 4, Local: 2, API: 1, Hybrid: 1,
+			ByComplexity: map[string]int64{"simple": 1, "medium": 1, "high": 2},
+		}},
+		{"fallbacks and failures", []TaskExecution{
+			{Strategy: ExecutionStrategyAPI, Complexity: ComplexityMedium},
+		}, 1, 2, RoutingStats{
+			Total: 1, API: 1, Fallbacks: 1, Failures: 2,
+			ByComplexity: map[string]int64{"medium": 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var counters routingCounters
+			for i := range tt.executions {
+				counters.record(&tt.executions[i])
+			}
+			for range tt.fallbacks {
+				counters.recordFallback()
+			}
+			for range tt.failures {
+				counters.recordFailure()
+			}
+
+			if got := counters.snapshot(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("snapshot = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoutingCountersConcurrent(t *testing.T) {
+	var counters routingCounters
+	const goroutines, perGoroutine = 8, 200
+
+	var wg sync.WaitGroup
+	for i := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			execution := &TaskExecution{Strategy: ExecutionStrategyLocal, Complexity: TaskComplexity(i % 3)}
+			for range perGoroutine {
+				counters.record(execution)
+				counters.recordFallback()
+				counters.recordFailure()
+				// Snapshots taken mid-run must not share the live map
+				counters.snapshot().ByComplexity["simple"]++
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := counters.snapshot()
+	const want = goroutines * perGoroutine
+	if stats.Total != want || stats.Local != want || stats.Fallbacks != want || stats.Failures != want {
+		t.Errorf("stats = %+v, want %d of each", stats, want)
+	}
+	var byComplexity int64
+	for _, count := range stats.ByComplexity {
+		byComplexity += count
+	}
+	if byComplexity != want {
+		t.Errorf("ByComplexity sums to %d, want %d", byComplexity, want)
+	}
+}
@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// SelfTestTaskType marks the synthetic task run by a worker self-test
+const SelfTestTaskType = "self_test"
+
+// selfTestStage returns the workflow stage a role normally processes
+func selfTestStage(role types.WorkerRole) types.WorkflowStage {
+	switch role {
+	case types.RoleReviewer:
+		return types.StageReview
+	case types.RoleApprover:
+		return types.StageApproval
+	case types.RoleTester:
+		return types.StageTesting
+	default:
+		return types.StageDevelopment
+	}
+}
+
+// NewSelfTestTask builds a trivial synthetic task for role. The wording scores
+// as simple so the task is routed to a local model rather than a paid API.
+func NewSelfTestTask(role types.WorkerRole) *types.WorkflowTask {
+	now := time.Now()
+	return &types.WorkflowTask{
+		Task: types.Task{
+			ID:        fmt.Sprintf("self-test-%d", now.UnixNano()),
+			Type:      SelfTestTaskType,
+			Payload:   map[string]string{"message": "Simple status check: reply with the single word OK."},
+			CreatedAt: now,
+		},
+		WorkflowID:   "self-test",
+		Stage:        selfTestStage(role),
+		RequiredRole: role,
+	}
+}
+
+// SelfTest runs a synthetic task through the full processing path, including
+// RAG retrieval and model execution, and checks that it produced output
+func (p *RoleBasedProcessor) SelfTest(ctx context.Context) (string, error) {
+	outcome, err := p.ProcessWorkflowTask(ctx, NewSelfTestTask(p.role))
+	if err != nil {
+		return "", fmt.Errorf("self-test task failed: %w", err)
+	}
+	if strings.TrimSpace(outcome.Output) == "" {
+		return "", fmt.Errorf("self-test task returned an empty result")
+	}
+	return outcome.Output, nil
+}
@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+func TestNewSelfTestTask(t *testing.T) {
+	tests := []struct {
+		role  types.WorkerRole
+		stage types.WorkflowStage
+	}{
+		{types.RoleDeveloper, types.StageDevelopment},
+		{types.RoleReviewer, types.StageReview},
+		{types.RoleApprover, types.StageApproval},
+		{types.RoleTester, types.StageTesting},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			task := NewSelfTestTask(tt.role)
+			if err := task.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if task.Stage != tt.stage || task.RequiredRole != tt.role || task.Type != SelfTestTaskType {
+				t.Errorf("task = %s/%s/%s, want %s/%s/%s", task.Stage, task.RequiredRole, task.Type, tt.stage, tt.role, SelfTestTaskType)
+			}
+		})
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	blank := func(string, localmodels.ModelInput) (localmodels.ModelOutput, int) {
+		return localmodels.ModelOutput{Text: "  \n", FinishReason: localmodels.FinishReasonStop}, http.StatusOK
+	}
+
+	tests := []struct {
+		name    string
+		predict func(string, localmodels.ModelInput) (localmodels.ModelOutput, int)
+		wantErr bool
+	}{
+		{"model answers", echoPrediction("OK"), false},
+		{"empty answer", blank, true},
+		{"model fails", failingPrediction, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, server := newFakeDaemon(t, tt.predict)
+			processor := NewRoleBasedProcessor(types.RoleDeveloper, nil, newDaemonManager(t, server.URL, nil), nil, nil)
+
+			output, err := processor.SelfTest(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SelfTest = %q, %v, want error %v", output, err, tt.wantErr)
+			}
+			if !tt.wantErr && output != "OK" {
+				t.Errorf("output = %q, want OK", output)
+			}
+		})
+	}
+}
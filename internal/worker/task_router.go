@@ -3,10 +3,15 @@ package worker
 import (
 	"context"
 	"fmt"
+	"log"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/niko/mqtt-agent-orchestration/internal/ai"
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
 	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+	"github.com/niko/mqtt-agent-orchestration/internal/prompts"
 	"github.com/niko/mqtt-agent-orchestration/pkg/types"
 )
 
@@ -24,6 +29,14 @@ type TaskRouter struct {
 	localModelManager *localmodels.Manager
 	aiConfig          *ai.AIHelperConfig
 	mcpEnabled        bool
+	promptBudget      *config.PromptBudgetConfig
+	complexity        *config.ComplexityConfig
+	prompts           *prompts.Set
+	preferences       localmodels.RolePreferences
+	stats             routingCounters
+
+	// Follow-up generations allowed for truncated output
+	maxContinuations int
 }
 
 // NewTaskRouter creates a new task router
@@ -32,13 +45,58 @@ func NewTaskRouter(localManager *localmodels.Manager, aiConfig *ai.AIHelperConfi
 		localModelManager: localManager,
 		aiConfig:          aiConfig,
 		mcpEnabled:        true, // Enable MCP for local operations
+		promptBudget:      config.DefaultPromptBudgetConfig(),
+		complexity:        config.DefaultComplexityConfig(),
+		prompts:           prompts.Default(),
+		maxContinuations:  DefaultMaxContinuations,
 	}
 }
 
+// SetComplexityConfig overrides the keyword weights used to score task complexity
+func (tr *TaskRouter) SetComplexityConfig(complexity *config.ComplexityConfig) {
+	tr.complexity = complexity
+}
+
+// SetRolePreferences sets the ordered local models each worker role prefers
+func (tr *TaskRouter) SetRolePreferences(preferences localmodels.RolePreferences) {
+	tr.preferences = preferences
+}
+
+// SetMaxContinuations sets how many follow-up generations extend output
+// truncated at the token limit; zero disables continuation
+func (tr *TaskRouter) SetMaxContinuations(maxContinuations int) {
+	tr.maxContinuations = maxContinuations
+}
+
 // RouteTask determines the best execution strategy for a task
 func (tr *TaskRouter) RouteTask(ctx context.Context, task *types.WorkflowTask) (*TaskExecution, error) {
 	complexity := tr.analyzeTaskComplexity(task)
-	
+
+	execution, err := tr.route(ctx, task, complexity)
+	if err != nil {
+		tr.stats.recordFailure()
+		return nil, err
+	}
+
+	execution.Complexity = complexity
+	execution.PromptBudget = tr.promptBudget
+	execution.Prompts = tr.prompts
+	execution.MaxContinuations = tr.maxContinuations
+	execution.router = tr
+	tr.stats.record(execution)
+	return execution, nil
+}
+
+// route honors a forced provider or model, otherwise routes by complexity
+func (tr *TaskRouter) route(ctx context.Context, task *types.WorkflowTask, complexity TaskComplexity) (*TaskExecution, error) {
+	if isForced(task) {
+		return tr.routeForced(ctx, task)
+	}
+	return tr.routeByComplexity(ctx, task, complexity)
+}
+
+// routeByComplexity picks local or API execution for the given complexity
+func (tr *TaskRouter) routeByComplexity(ctx context.Context, task *types.WorkflowTask, complexity TaskComplexity) (*TaskExecution, error) {
 	switch complexity {
 	case ComplexitySimple:
 		return tr.routeToLocalModel(ctx, task)
@@ -49,66 +107,73 @@ func (tr *TaskRouter) RouteTask(ctx context.Context, task *types.WorkflowTask) (
 		}
 		return tr.routeToExternalAPI(ctx, task, "medium")
 	case ComplexityHigh:
-		return tr.routeToExternalAPI(ctx, task, "high")
+		execution, err := tr.routeToExternalAPI(ctx, task, "high")
+		if err == nil {
+			return execution, nil
+		}
+		// Degrade to a local model rather than failing outright when no provider is usable
+		localExecution, localErr := tr.routeToLocalModel(ctx, task)
+		if localErr != nil {
+			return nil, fmt.Errorf("%w (local fallback failed: %v)", err, localErr)
+		}
+		log.Printf("Warning: no external API available for high-complexity task %s, falling back to local model %s: %v",
+			task.ID, localExecution.ModelName, err)
+		localExecution.Reasoning = fmt.Sprintf("Task complexity: high, no external API available (%v), falling back to local model %s",
+			err, localExecution.ModelName)
+		return localExecution, nil
 	default:
 		return tr.routeToLocalModel(ctx, task)
 	}
 }
 
-// analyzeTaskComplexity determines task complexity based on content and requirements
-func (tr *TaskRouter) analyzeTaskComplexity(task *types.WorkflowTask) TaskComplexity {
-	taskType := strings.ToLower(task.Type)
-	content := strings.ToLower(fmt.Sprintf("%v", task.Payload))
-	
-	// High complexity indicators
-	highComplexityKeywords := []string{
-		"architecture", "design", "review", "security", "analysis", 
-		"refactor", "optimization", "performance", "complex", "comprehensive",
-		"strategy", "planning", "evaluation", "assessment",
-	}
-	
-	// Medium complexity indicators  
-	mediumComplexityKeywords := []string{
-		"implement", "create", "generate", "modify", "update",
-		"integration", "testing", "validation", "debugging",
-	}
-	
-	// Simple task indicators (MCP/local model territory)
-	simpleTaskKeywords := []string{
-		"format", "lint", "syntax", "simple", "basic", "quick",
-		"echo", "status", "info", "list", "search", "read", "write",
-		"mcp", "tool", "file operation", "git operation",
+// FallbackToAPI plans external API execution for a task whose local model
+// failed, choosing the provider the same way routing would for its complexity
+func (tr *TaskRouter) FallbackToAPI(ctx context.Context, task *types.WorkflowTask, localErr error) (*TaskExecution, error) {
+	if isForced(task) {
+		return nil, fmt.Errorf("task %s is forced to %s, not falling back: %w", task.ID, forcedTarget(task), localErr)
 	}
-	
-	// Check for high complexity
-	for _, keyword := range highComplexityKeywords {
-		if strings.Contains(content, keyword) || strings.Contains(taskType, keyword) {
-			return ComplexityHigh
-		}
+
+	complexity := tr.analyzeTaskComplexity(task)
+	level := "medium"
+	if complexity == ComplexityHigh {
+		level = "high"
 	}
-	
-	// Check for simple tasks (MCP territory)
-	for _, keyword := range simpleTaskKeywords {
-		if strings.Contains(content, keyword) || strings.Contains(taskType, keyword) {
-			return ComplexitySimple
-		}
+
+	execution, err := tr.routeToExternalAPI(ctx, task, level)
+	if err != nil {
+		tr.stats.recordFailure()
+		return nil, fmt.Errorf("%w (after local model failure: %v)", err, localErr)
 	}
-	
-	// Check for medium complexity
-	for _, keyword := range mediumComplexityKeywords {
-		if strings.Contains(content, keyword) || strings.Contains(taskType, keyword) {
-			return ComplexityMedium
-		}
+	tr.stats.recordFallback()
+
+	execution.Complexity = complexity
+	execution.PromptBudget = tr.promptBudget
+	execution.Prompts = tr.prompts
+	execution.MaxContinuations = tr.maxContinuations
+	execution.Reasoning = fmt.Sprintf("Local model failed (%v), falling back to %s API", localErr, execution.APIProvider)
+	return execution, nil
+}
+
+// analyzeTaskComplexity scores the task type and payload against the
+// configured keyword weights
+func (tr *TaskRouter) analyzeTaskComplexity(task *types.WorkflowTask) TaskComplexity {
+	content := fmt.Sprintf("%s %v", task.Type, textPayload(task.Payload))
+	score := tr.complexity.Score(content)
+
+	switch {
+	case score >= tr.complexity.HighThreshold:
+		return ComplexityHigh
+	case score <= tr.complexity.SimpleThreshold:
+		return ComplexitySimple
+	default:
+		return ComplexityMedium
 	}
-	
-	// Default to medium complexity
-	return ComplexityMedium
 }
 
 // routeToLocalModel routes task to local model with MCP capabilities
 func (tr *TaskRouter) routeToLocalModel(ctx context.Context, task *types.WorkflowTask) (*TaskExecution, error) {
 	if tr.localModelManager == nil {
-		return nil, fmt.Errorf("local model manager not available")
+		return nil, fmt.Errorf("%w: local model manager not available", ErrModelUnavailable)
 	}
 	
 	// Select appropriate local model based on task type
@@ -128,13 +193,13 @@ func (tr *TaskRouter) routeToLocalModel(ctx context.Context, task *types.Workflo
 // routeToExternalAPI routes task to external AI API
 func (tr *TaskRouter) routeToExternalAPI(ctx context.Context, task *types.WorkflowTask, complexity string) (*TaskExecution, error) {
 	if tr.aiConfig == nil {
-		return nil, fmt.Errorf("AI configuration not available")
+		return nil, fmt.Errorf("%w: AI configuration not available", ErrAPIUnavailable)
 	}
 	
 	// Get preferred API based on complexity
 	provider, apiConfig, err := tr.aiConfig.GetPreferredAPI(complexity)
 	if err != nil {
-		return nil, fmt.Errorf("no suitable API found: %w", err)
+		return nil, fmt.Errorf("%w: no suitable API found: %w", ErrAPIUnavailable, err)
 	}
 	
 	execution := &TaskExecution{
@@ -157,13 +222,61 @@ func (tr *TaskRouter) selectLocalModel(task *types.WorkflowTask) string {
 	switch {
 	case strings.Contains(taskType, "embed") || strings.Contains(taskType, "search"):
 		return "qwen-embedding-4b"
-	case strings.Contains(taskType, "code") || strings.Contains(taskType, "develop"):
-		return "qwen-omni-3b" // Good for coding tasks
-	case strings.Contains(taskType, "visual") || strings.Contains(taskType, "image"):
-		return "qwen-vl-7b" // Vision-language model
-	default:
-		return "qwen-omni-3b" // Default general purpose model
+	case strings.Contains(taskType, "visual") || strings.Contains(taskType, "image") || hasImages(task):
+		if model, ok := tr.multimodalModel(task.RequiredRole); ok {
+			return model
+		}
+		textModel := tr.textModel(task.RequiredRole)
+		log.Printf("Warning: task %s has images but no multimodal model is configured, downgrading to text model %s", task.ID, textModel)
+		return textModel
 	}
+	
+	return tr.textModel(task.RequiredRole)
+}
+
+// defaultMultimodalModel is the vision-language model image tasks use unless the role prefers another
+const defaultMultimodalModel = "qwen-vl-7b"
+
+// defaultTextModel is the general purpose model, good for coding tasks, used when the role prefers none
+const defaultTextModel = "qwen-omni-3b"
+
+// hasImages reports whether the task payload attaches images
+func hasImages(task *types.WorkflowTask) bool {
+	return strings.TrimSpace(task.Payload[PayloadImagePaths]) != "" || strings.TrimSpace(task.Payload[PayloadImageData]) != ""
+}
+
+// textModel returns the first configured text model the role prefers
+func (tr *TaskRouter) textModel(role types.WorkerRole) string {
+	if preferred := tr.preferredLocalModels(role); len(preferred) > 0 {
+		return preferred[0]
+	}
+	return defaultTextModel
+}
+
+// multimodalModel returns a configured multimodal model for an image task:
+// the role's preferred one, else the default, else any in name order. With
+// no local model configs the model daemon owns them, so the default is
+// trusted as is.
+func (tr *TaskRouter) multimodalModel(role types.WorkerRole) (string, bool) {
+	available := tr.localModelManager.GetAvailableModels()
+	if len(available) == 0 {
+		return defaultMultimodalModel, true
+	}
+
+	if preferred := preferredModels(tr.preferences, role, localmodels.ModelTypeMultimodal, tr.lookupModel); len(preferred) > 0 {
+		return preferred[0], true
+	}
+	if name, modelType, exists := tr.lookupModel(defaultMultimodalModel); exists && modelType == localmodels.ModelTypeMultimodal {
+		return name, true
+	}
+
+	slices.Sort(available)
+	for _, name := range available {
+		if _, modelType, _ := tr.lookupModel(name); modelType == localmodels.ModelTypeMultimodal {
+			return name, true
+		}
+	}
+	return "", false
 }
 
 // isMCPTask determines if a task should use MCP tools
@@ -173,7 +286,7 @@ func (tr *TaskRouter) isMCPTask(task *types.WorkflowTask) bool {
 		"directory", "repository", "database", "tool",
 	}
 	
-	content := strings.ToLower(fmt.Sprintf("%s %v", task.Type, task.Payload))
+	content := strings.ToLower(fmt.Sprintf("%s %v", task.Type, textPayload(task.Payload)))
 	for _, keyword := range mcpKeywords {
 		if strings.Contains(content, keyword) {
 			return true
@@ -197,16 +310,50 @@ type TaskExecution struct {
 	ModelName   string // For local execution
 	APIProvider string // For API execution
 	APIConfig   ai.APIConfig
+	APIModel    string // Pins the API model; empty tries the provider's models in order
 	Task        *types.WorkflowTask
 	MCPEnabled  bool
 	Reasoning   string
+	Complexity  TaskComplexity
+
+	// PromptBudget caps the size of the assembled prompt
+	PromptBudget *config.PromptBudgetConfig
+
+	// Prompts supplies the compliance boilerplate wrapped around the prompt
+	Prompts *prompts.Set
+
+	// FinishReason is set after execution; FinishReasonLength means the output was truncated
+	FinishReason string
+
+	// ServedBy is the model that produced the output; it differs from ModelName after a fallback
+	ServedBy string
+
+	// Prompt replaces the generic task prompt when set
+	Prompt string
+
+	// Usage is the token usage and cost reported by the API provider
+	Usage ai.TokenUsage
+
+	// Progress, when set, makes API execution stream and count its output
+	Progress *ProgressTracker
+
+	// MaxContinuations bounds the follow-up generations that extend output
+	// truncated at the token limit; zero leaves it truncated
+	MaxContinuations int
+
+	// router plans the API fallback when local execution fails
+	router *TaskRouter
 }
 
 // Execute runs the task according to the execution plan
 func (te *TaskExecution) Execute(ctx context.Context, localManager *localmodels.Manager, aiClient *ai.AIClient) (string, error) {
 	switch te.Strategy {
 	case ExecutionStrategyLocal:
-		return te.executeLocal(ctx, localManager)
+		output, err := te.executeLocal(ctx, localManager)
+		if err != nil && te.router != nil {
+			return te.fallBackToAPI(ctx, aiClient, err)
+		}
+		return output, err
 	case ExecutionStrategyAPI:
 		return te.executeAPI(ctx, aiClient)
 	default:
@@ -214,30 +361,60 @@ func (te *TaskExecution) Execute(ctx context.Context, localManager *localmodels.
 	}
 }
 
+// fallBackToAPI re-plans a task whose local model failed as API execution
+// and runs it, unless the task deadline is already spent and the workflow
+// should retry instead
+func (te *TaskExecution) fallBackToAPI(ctx context.Context, aiClient *ai.AIClient, localErr error) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", fmt.Errorf("local model failed and no time left for API fallback: %w (local: %v)", err, localErr)
+	}
+	if aiClient == nil {
+		return "", fmt.Errorf("%w: local model failed and no AI API is configured: %w", ErrAPIUnavailable, localErr)
+	}
+
+	fallback, err := te.router.FallbackToAPI(ctx, te.Task, localErr)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Task %s: %s", te.Task.ID, fallback.Reasoning)
+
+	te.Strategy = fallback.Strategy
+	te.APIProvider = fallback.APIProvider
+	te.APIConfig = fallback.APIConfig
+	te.Reasoning = fallback.Reasoning
+	return te.executeAPI(ctx, aiClient)
+}
+
 // executeLocal executes task using local model
 func (te *TaskExecution) executeLocal(ctx context.Context, localManager *localmodels.Manager) (string, error) {
 	if localManager == nil {
-		return "", fmt.Errorf("local model manager not available")
+		return "", fmt.Errorf("%w: local model manager not available", ErrModelUnavailable)
 	}
 	
 	// Load model if needed
 	if err := localManager.LoadModel(ctx, te.ModelName); err != nil {
-		return "", fmt.Errorf("failed to load model %s: %w", te.ModelName, err)
+		return "", fmt.Errorf("%w: failed to load model %s: %w", ErrModelUnavailable, te.ModelName, err)
 	}
 	
 	// Get model instance
 	model, err := localManager.GetModel(te.ModelName)
 	if err != nil {
-		return "", fmt.Errorf("failed to get model %s: %w", te.ModelName, err)
+		return "", fmt.Errorf("%w: failed to get model %s: %w", ErrModelUnavailable, te.ModelName, err)
 	}
 	
 	// Prepare input
-	prompt := te.buildLocalPrompt()
+	prompt := te.Prompt
+	if prompt == "" {
+		prompt = te.buildLocalPrompt()
+	}
 	input := localmodels.ModelInput{
 		Text:        prompt,
 		Temperature: 0.7,
 		MaxTokens:   te.getMaxTokensForTask(),
 	}
+	if seed, err := strconv.Atoi(te.Task.Payload[PayloadSeed]); err == nil {
+		input.Seed = seed
+	}
 	
 	// Add MCP context if enabled
 	if te.MCPEnabled {
@@ -246,45 +423,178 @@ func (te *TaskExecution) executeLocal(ctx context.Context, localManager *localmo
 		input.Text = fmt.Sprintf("MCP Tools Available: %v\n\n%s", te.getRequiredMCPTools(), input.Text)
 	}
 	
-	// Execute
+	input.ImagePaths = te.imagePaths()
+	imageData, err := te.imageData()
+	if err != nil {
+		return "", err
+	}
+	input.ImageData = imageData
+	
+	// Execute, falling back to a text model if multimodal inference fails
+	te.ServedBy = te.ModelName
 	output, err := model.Predict(ctx, input)
+	if err != nil && model.GetType() == localmodels.ModelTypeMultimodal {
+		output, err = te.predictTextFallback(ctx, localManager, input, err)
+	}
 	if err != nil {
 		return "", fmt.Errorf("local model prediction failed: %w", err)
 	}
+	if output.NearContextLimit() {
+		log.Printf("Warning: task %s used %d of %d context tokens on model %s", te.Task.ID, output.ContextUsed, output.ContextLimit, te.ModelName)
+	}
+
+	// Continue output truncated at the token limit on the model that served it
+	first := generation{Text: output.Text, FinishReason: output.FinishReason, Truncated: output.Truncated}
+	result := continueTruncated(ctx, first, te.MaxContinuations, func(ctx context.Context, prior string) (generation, error) {
+		return te.continueLocal(ctx, localManager, input, prior)
+	})
+
+	te.FinishReason = result.FinishReason
+	if result.Truncated {
+		log.Printf("Warning: model %s output truncated at %d tokens for task %s", te.ServedBy, input.MaxTokens, te.Task.ID)
+	}
 	
-	return output.Text, nil
+	return result.Text, nil
+}
+
+// continueLocal resumes truncated output by prompting the model that served
+// the task with the original prompt followed by the output so far
+func (te *TaskExecution) continueLocal(ctx context.Context, localManager *localmodels.Manager, input localmodels.ModelInput, prior string) (generation, error) {
+	model, err := localManager.GetModel(te.ServedBy)
+	if err != nil {
+		return generation{}, err
+	}
+
+	input.Text += prior
+	if model.GetType() != localmodels.ModelTypeMultimodal {
+		input.ImagePaths, input.ImageData = nil, nil
+	}
+	output, err := model.Predict(ctx, input)
+	if err != nil {
+		return generation{}, err
+	}
+	return generation{Text: output.Text, FinishReason: output.FinishReason, Truncated: output.Truncated}, nil
 }
 
 // executeAPI executes task using external API
 func (te *TaskExecution) executeAPI(ctx context.Context, aiClient *ai.AIClient) (string, error) {
 	if aiClient == nil {
-		return "", fmt.Errorf("AI client not available")
+		return "", fmt.Errorf("%w: AI client not available", ErrAPIUnavailable)
+	}
+	
+	prompt := te.Prompt
+	if prompt == "" {
+		prompt = te.buildDetailedPrompt()
+	}
+	
+	messages := []ai.Message{{Role: "user", Content: prompt}}
+	var response ai.Response
+	var err error
+	if te.Progress != nil {
+		maxTokens := te.APIConfig.MaxTokens
+		if maxTokens <= 0 {
+			maxTokens = te.getMaxTokensForTask()
+		}
+		te.Progress.Begin(maxTokens)
+		response, err = aiClient.GenerateStreamWithProvider(ctx, te.APIProvider, te.APIModel, messages, te.Progress.Advance)
+	} else if te.APIModel != "" {
+		response, err = aiClient.GenerateDetailedWithModel(ctx, te.APIProvider, te.APIModel, messages)
+	} else {
+		response, err = aiClient.GenerateDetailedWithProvider(ctx, te.APIProvider, messages)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s API execution failed: %w", te.APIProvider, err)
 	}
 	
-	// For now, return a placeholder since we need to implement the full AI client
-	// This would integrate with the AI helper scripts via MQTT
-	return fmt.Sprintf("API execution not yet implemented for provider: %s, model: %s, prompt: %s", 
-		te.APIProvider, te.APIConfig.Models[0], te.buildDetailedPrompt()), nil
+	te.ServedBy = response.Model
+	te.Usage = response.Usage
+
+	// Continue output truncated at the token limit on the model that served it
+	first := generation{Text: response.Content, FinishReason: response.FinishReason, Truncated: response.Truncated}
+	result := continueTruncated(ctx, first, te.MaxContinuations, func(ctx context.Context, prior string) (generation, error) {
+		return te.continueAPI(ctx, aiClient, messages, prior)
+	})
+
+	te.FinishReason = result.FinishReason
+	log.Printf("Task %s served by %s/%s: %d tokens, $%.4f", te.Task.ID, te.APIProvider, te.ServedBy,
+		te.Usage.TotalTokens, te.Usage.CostUSD)
+	if result.Truncated {
+		log.Printf("Warning: %s output truncated for task %s", te.APIProvider, te.Task.ID)
+	}
+	
+	return strings.TrimSpace(result.Text), nil
+}
+
+// continueAPI resumes truncated output by sending the model that served the
+// task the conversation so far and a request to continue
+func (te *TaskExecution) continueAPI(ctx context.Context, aiClient *ai.AIClient, messages []ai.Message, prior string) (generation, error) {
+	messages = continuationMessages(messages, prior)
+
+	var response ai.Response
+	var err error
+	switch {
+	case te.Progress != nil:
+		response, err = aiClient.GenerateStreamWithProvider(ctx, te.APIProvider, te.ServedBy, messages, te.Progress.Advance)
+	case te.ServedBy != "":
+		response, err = aiClient.GenerateDetailedWithModel(ctx, te.APIProvider, te.ServedBy, messages)
+	default:
+		response, err = aiClient.GenerateDetailedWithProvider(ctx, te.APIProvider, messages)
+	}
+	if err != nil {
+		return generation{}, err
+	}
+
+	te.Usage = addUsage(te.Usage, response.Usage)
+	return generation{Text: response.Content, FinishReason: response.FinishReason, Truncated: response.Truncated}, nil
 }
 
 // buildLocalPrompt creates a prompt optimized for local models
 func (te *TaskExecution) buildLocalPrompt() string {
-	var prompt strings.Builder
-	
-	// Keep it simple and direct for local models
-	prompt.WriteString(fmt.Sprintf("Task: %s\n", te.Task.Type))
-	
-	if te.Task.PreviousOutput != "" {
-		prompt.WriteString(fmt.Sprintf("Previous output: %s\n", te.Task.PreviousOutput))
+	return renderWithinBudget(te.PromptBudget, te.promptSections(), func(sections promptSections) string {
+		var prompt strings.Builder
+		
+		// Keep it simple and direct for local models
+		prompt.WriteString(fmt.Sprintf("Task: %s\n", te.Task.Type))
+		
+		if sections.PreviousOutput != "" {
+			prompt.WriteString(fmt.Sprintf("Previous output: %s\n", sections.PreviousOutput))
+		}
+		
+		for key, value := range te.Task.Payload {
+			if key == config.PromptSectionRAGContext || key == PayloadImageData {
+				continue
+			}
+			prompt.WriteString(fmt.Sprintf("%s: %v\n", key, value))
+		}
+		
+		if sections.RAGContext != "" {
+			prompt.WriteString(fmt.Sprintf("%s: %s\n", config.PromptSectionRAGContext, sections.RAGContext))
+		}
+		
+		if instructions := reviewInstructions(te.Task); instructions != "" {
+			prompt.WriteString(fmt.Sprintf("\n%s\n", instructions))
+		}
+		
+		prompt.WriteString("\nPlease provide a clear, concise response.")
+		
+		return te.wrapPrompt(prompt.String())
+	})
+}
+
+// wrapPrompt adds the compliance boilerplate around an assembled prompt
+func (te *TaskExecution) wrapPrompt(prompt string) string {
+	if te.Prompts == nil {
+		return prompt
 	}
-	
-	for key, value := range te.Task.Payload {
-		prompt.WriteString(fmt.Sprintf("%s: %v\n", key, value))
+	return te.Prompts.Wrap(prompt)
+}
+
+// promptSections returns the trimmable parts of the task prompt
+func (te *TaskExecution) promptSections() promptSections {
+	return promptSections{
+		RAGContext:     te.Task.Payload[config.PromptSectionRAGContext],
+		PreviousOutput: te.Task.PreviousOutput,
 	}
-	
-	prompt.WriteString("\nPlease provide a clear, concise response.")
-	
-	return prompt.String()
 }
 
 // buildAPIMessages would create messages for API calls when implemented
@@ -292,23 +602,36 @@ func (te *TaskExecution) buildLocalPrompt() string {
 
 // buildDetailedPrompt creates a comprehensive prompt for API calls
 func (te *TaskExecution) buildDetailedPrompt() string {
-	var prompt strings.Builder
-	
-	prompt.WriteString(fmt.Sprintf("Task Type: %s\n", te.Task.Type))
-	prompt.WriteString(fmt.Sprintf("Required Role: %s\n\n", te.Task.RequiredRole))
-	
-	if te.Task.PreviousOutput != "" {
-		prompt.WriteString(fmt.Sprintf("Previous Output to Review/Improve:\n%s\n\n", te.Task.PreviousOutput))
-	}
-	
-	prompt.WriteString("Task Details:\n")
-	for key, value := range te.Task.Payload {
-		prompt.WriteString(fmt.Sprintf("- %s: %v\n", key, value))
-	}
-	
-	prompt.WriteString("\nPlease provide a comprehensive, high-quality response that demonstrates expertise in this domain.")
-	
-	return prompt.String()
+	return renderWithinBudget(te.PromptBudget, te.promptSections(), func(sections promptSections) string {
+		var prompt strings.Builder
+		
+		prompt.WriteString(fmt.Sprintf("Task Type: %s\n", te.Task.Type))
+		prompt.WriteString(fmt.Sprintf("Required Role: %s\n\n", te.Task.RequiredRole))
+		
+		if sections.PreviousOutput != "" {
+			prompt.WriteString(fmt.Sprintf("Previous Output to Review/Improve:\n%s\n\n", sections.PreviousOutput))
+		}
+		
+		prompt.WriteString("Task Details:\n")
+		for key, value := range te.Task.Payload {
+			if key == config.PromptSectionRAGContext || key == PayloadImageData {
+				continue
+			}
+			prompt.WriteString(fmt.Sprintf("- %s: %v\n", key, value))
+		}
+		
+		if sections.RAGContext != "" {
+			prompt.WriteString(fmt.Sprintf("- %s: %s\n", config.PromptSectionRAGContext, sections.RAGContext))
+		}
+		
+		if instructions := reviewInstructions(te.Task); instructions != "" {
+			prompt.WriteString(fmt.Sprintf("\n%s\n", instructions))
+		}
+		
+		prompt.WriteString("\nPlease provide a comprehensive, high-quality response that demonstrates expertise in this domain.")
+		
+		return te.wrapPrompt(prompt.String())
+	})
 }
 
 // getMaxTokensForTask returns appropriate token limits based on task complexity
@@ -331,7 +654,7 @@ func (te *TaskExecution) getMaxTokensForTask() int {
 func (te *TaskExecution) getRequiredMCPTools() []string {
 	var tools []string
 	
-	taskContent := strings.ToLower(fmt.Sprintf("%s %v", te.Task.Type, te.Task.Payload))
+	taskContent := strings.ToLower(fmt.Sprintf("%s %v", te.Task.Type, textPayload(te.Task.Payload)))
 	
 	if strings.Contains(taskContent, "file") || strings.Contains(taskContent, "read") || strings.Contains(taskContent, "write") {
 		tools = append(tools, "filesystem")
@@ -0,0 +1,213 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/internal/config"
+	"github.com/niko/mqtt-agent-orchestration/internal/localmodels"
+	"github.com/niko/mqtt-agent-orchestration/internal/prompts"
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// failingPrediction makes every local prediction fail
+func failingPrediction(string, localmodels.ModelInput) (localmodels.ModelOutput, int) {
+	return localmodels.ModelOutput{}, http.StatusInternalServerError
+}
+
+func TestExecuteFallsBackToAPI(t *testing.T) {
+	_, server := newFakeDaemon(t, failingPrediction)
+	provider, aiConfig := newFakeProvider(t, "from the API")
+	processor := NewRoleBasedProcessor(types.RoleDeveloper, nil, newDaemonManager(t, server.URL, nil), nil, aiConfig)
+
+	outcome, err := processor.ProcessWorkflowTask(context.Background(), NewSelfTestTask(types.RoleDeveloper))
+	if err != nil {
+		t.Fatalf("ProcessWorkflowTask: %v", err)
+	}
+	if outcome.Output != "from the API" {
+		t.Errorf("Output = %q, want the API reply", outcome.Output)
+	}
+	if outcome.ServedBy != "test-model" {
+		t.Errorf("ServedBy = %q, want the API model", outcome.ServedBy)
+	}
+	if got := provider.requests.Load(); got != 1 {
+		t.Errorf("provider got %d requests, want 1", got)
+	}
+}
+
+func TestExecuteFallbackErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		ctx     func() context.Context
+		withAPI bool
+		wantErr error
+	}{
+		{"no API configured", context.Background, false, ErrAPIUnavailable},
+		{"deadline spent", func() context.Context {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx
+		}, true, context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, server := newFakeDaemon(t, failingPrediction)
+			manager := newDaemonManager(t, server.URL, nil)
+			router := NewTaskRouter(manager, nil)
+			processor := NewRoleBasedProcessor(types.RoleDeveloper, nil, manager, nil, nil)
+			if tt.withAPI {
+				_, aiConfig := newFakeProvider(t, "unused")
+				router = NewTaskRouter(manager, aiConfig)
+				processor = NewRoleBasedProcessor(types.RoleDeveloper, nil, manager, nil, aiConfig)
+			}
+
+			execution, err := router.RouteTask(context.Background(), NewSelfTestTask(types.RoleDeveloper))
+			if err != nil {
+				t.Fatalf("RouteTask: %v", err)
+			}
+			// Load the model first so only the prediction sees the cancelled context
+			if err := manager.LoadModel(context.Background(), execution.ModelName); err != nil {
+				t.Fatalf("LoadModel: %v", err)
+			}
+
+			_, err = execution.Execute(tt.ctx(), manager, processor.aiClient)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelectLocalModelImages(t *testing.T) {
+	text := localmodels.ModelConfig{Type: localmodels.ModelTypeText}
+	vision := localmodels.ModelConfig{Type: localmodels.ModelTypeMultimodal}
+
+	tests := []struct {
+		name        string
+		models      map[string]localmodels.ModelConfig
+		preferences localmodels.RolePreferences
+		taskType    string
+		payload     map[string]string
+		want        string
+	}{
+		{"default vision model", map[string]localmodels.ModelConfig{"qwen-omni-3b": text, "qwen-vl-7b": vision}, nil, "describe_image", nil, "qwen-vl-7b"},
+		{"other vision model", map[string]localmodels.ModelConfig{"qwen-omni-3b": text, "minicpm-v": vision}, nil, "describe_image", nil, "minicpm-v"},
+		{"preferred vision model", map[string]localmodels.ModelConfig{"qwen-vl-7b": vision, "minicpm-v": vision},
+			localmodels.RolePreferences{"developer": {"minicpm-v"}}, "describe_image", nil, "minicpm-v"},
+		{"images in payload", map[string]localmodels.ModelConfig{"qwen-vl-7b": vision}, nil, "create_document",
+			map[string]string{PayloadImagePaths: "a.png"}, "qwen-vl-7b"},
+		{"downgrade to text", map[string]localmodels.ModelConfig{"qwen-omni-3b": text}, nil, "describe_image", nil, "qwen-omni-3b"},
+		{"downgrade to preferred text", map[string]localmodels.ModelConfig{"phi-4": text},
+			localmodels.RolePreferences{"developer": {"phi-4"}}, "visual_diff", nil, "phi-4"},
+		{"daemon owns configs", nil, nil, "describe_image", nil, "qwen-vl-7b"},
+		{"text task", map[string]localmodels.ModelConfig{"qwen-vl-7b": vision}, nil, "create_document", nil, "qwen-omni-3b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewTaskRouter(newDaemonManager(t, "http://127.0.0.1:0", tt.models), nil)
+			router.SetRolePreferences(tt.preferences)
+
+			task := NewSelfTestTask(types.RoleDeveloper)
+			task.Type = tt.taskType
+			task.Payload = tt.payload
+			if got := router.selectLocalModel(task); got != tt.want {
+				t.Errorf("selectLocalModel = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzeTaskComplexityWeighsKeywords(t *testing.T) {
+	tuned := &config.ComplexityConfig{
+		Keywords:        map[string]int{"implement": 1, "complex": 3, "security": 4, "refactor": 3, "docs": -3},
+		HighThreshold:   6,
+		SimpleThreshold: -2,
+	}
+
+	tests := []struct {
+		name    string
+		config  *config.ComplexityConfig
+		request string
+		want    TaskComplexity
+	}{
+		{"mixed keywords add up", tuned, "implement a complex security refactor", ComplexityHigh},
+		{"below the high threshold", tuned, "implement a security fix", ComplexityMedium},
+		{"weights cancel out", tuned, "security docs", ComplexityMedium},
+		{"simple", tuned, "tidy the docs", ComplexitySimple},
+		{"no keywords", tuned, "hello", ComplexityMedium},
+		{"default weights", config.DefaultComplexityConfig(), "implement a complex security refactor", ComplexityHigh},
+		{"default simple", config.DefaultComplexityConfig(), "quick format", ComplexitySimple},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewTaskRouter(nil, nil)
+			router.SetComplexityConfig(tt.config)
+			task := &types.WorkflowTask{Task: types.Task{ID: "t1", Type: "task", Payload: map[string]string{"request": tt.request}}}
+			if got := router.analyzeTaskComplexity(task); got != tt.want {
+				t.Errorf("complexity of %q = %s, want %s", tt.request, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteForwardsSeed(t *testing.T) {
+	tests := []struct {
+		name string
+		seed string
+		want int
+	}{
+		{"seeded", "42", 42},
+		{"unseeded", "", 0},
+		{"invalid", "random", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon, server := newFakeDaemon(t, echoPrediction("OK"))
+			manager := newDaemonManager(t, server.URL, nil)
+			router := NewTaskRouter(manager, nil)
+
+			task := NewSelfTestTask(types.RoleDeveloper)
+			task.Payload[PayloadSeed] = tt.seed
+			execution, err := router.RouteTask(context.Background(), task)
+			if err != nil {
+				t.Fatalf("RouteTask: %v", err)
+			}
+			if _, err := execution.Execute(context.Background(), manager, nil); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+
+			daemon.mu.Lock()
+			inputs := daemon.inputs[execution.ModelName]
+			daemon.mu.Unlock()
+			if len(inputs) != 1 || inputs[0].Seed != tt.want {
+				t.Errorf("daemon inputs = %+v, want one with seed %d", inputs, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptsCarryCompliance(t *testing.T) {
+	set := prompts.Default()
+	set.SetCompliance(prompts.Compliance{Prefix: "Never output secrets.", Suffix: "Cite sources."})
+
+	execution := &TaskExecution{Task: NewSelfTestTask(types.RoleDeveloper), Prompts: set}
+	tests := map[string]string{
+		"local":    execution.buildLocalPrompt(),
+		"detailed": execution.buildDetailedPrompt(),
+	}
+
+	for name, prompt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if !strings.HasPrefix(prompt, "Never output secrets.\n\n") || !strings.HasSuffix(prompt, "\n\nCite sources.") {
+				t.Errorf("prompt lacks the compliance boilerplate:\n%s", prompt)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultTaskTTL discards tasks consumed more than 15 minutes after they
+// were published, by which time the orchestrator has re-dispatched them
+const DefaultTaskTTL = 15 * time.Minute
+
+// ErrStaleTask is returned for tasks older than the configured TTL
+var ErrStaleTask = errors.New("task is stale")
+
+// CheckTaskAge rejects a task created more than ttl before now. Tasks without
+// a creation time pass, and a ttl of zero or less disables the check.
+func CheckTaskAge(createdAt time.Time, ttl time.Duration, now time.Time) error {
+	if ttl <= 0 || createdAt.IsZero() {
+		return nil
+	}
+	if age := now.Sub(createdAt); age > ttl {
+		return fmt.Errorf("%w: created %v ago, over the %v TTL", ErrStaleTask, age.Round(time.Second), ttl)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckTaskAge(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		ttl       time.Duration
+		wantStale bool
+	}{
+		{"fresh", now.Add(-time.Minute), DefaultTaskTTL, false},
+		{"exactly the TTL", now.Add(-DefaultTaskTTL), DefaultTaskTTL, false},
+		{"stale", now.Add(-time.Hour), DefaultTaskTTL, true},
+		{"no creation time", time.Time{}, DefaultTaskTTL, false},
+		{"check disabled", now.Add(-time.Hour), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckTaskAge(tt.createdAt, tt.ttl, now)
+			if stale := errors.Is(err, ErrStaleTask); stale != tt.wantStale || (err != nil && !stale) {
+				t.Errorf("CheckTaskAge() error = %v, want stale %v", err, tt.wantStale)
+			}
+		})
+	}
+}
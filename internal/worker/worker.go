@@ -82,6 +82,7 @@ func (w *Worker) ProcessTask(ctx context.Context, task types.Task) types.TaskRes
 		taskResult.Success = true
 		taskResult.Result = result
 	}
+	taskResult.Status = types.StatusForError(err, false)
 
 	return taskResult
 }
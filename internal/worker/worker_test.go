@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/niko/mqtt-agent-orchestration/pkg/types"
+)
+
+// stubProcessor returns a fixed result
+type stubProcessor struct {
+	result string
+	err    error
+}
+
+func (s stubProcessor) ProcessTask(context.Context, types.Task) (string, error) {
+	return s.result, s.err
+}
+
+func TestWorkerProcessTaskStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		processor   stubProcessor
+		wantStatus  types.ResultStatus
+		wantSuccess bool
+	}{
+		{"completed", stubProcessor{result: "done"}, types.StatusCompleted, true},
+		{"failed", stubProcessor{err: errors.New("model crashed")}, types.StatusFailed, false},
+		{"cancelled", stubProcessor{err: context.Canceled}, types.StatusCancelled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NewWorker("worker-1", tt.processor).ProcessTask(context.Background(), types.Task{ID: "t1", Type: "echo"})
+			if result.Status != tt.wantStatus || result.Success != tt.wantSuccess {
+				t.Errorf("result status %s, success %v; want %s, %v", result.Status, result.Success, tt.wantStatus, tt.wantSuccess)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// EnvelopeVersion is the envelope format version written by this build
+const EnvelopeVersion = 1
+
+// MessageType identifies the payload carried by an envelope
+type MessageType string
+
+const (
+	MessageTypeTask            MessageType = "task"
+	MessageTypeTaskResult      MessageType = "task_result"
+	MessageTypeWorkflowTask    MessageType = "workflow_task"
+	MessageTypeWorkflowResult  MessageType = "workflow_result"
+	MessageTypeWorkflowRequest MessageType = "workflow_request"
+	MessageTypeWorkflowOutcome MessageType = "workflow_outcome"
+	MessageTypeWorkerStatus    MessageType = "worker_status"
+	MessageTypeWorkerClaim     MessageType = "worker_claim"
+	MessageTypeDeadLetter      MessageType = "dead_letter"
+	MessageTypeCapabilitiesReq MessageType = "capabilities_request"
+	MessageTypeCapabilities    MessageType = "capabilities"
+	MessageTypeIngestionTask   MessageType = "ingestion_task"
+	MessageTypeIngestionResult MessageType = "ingestion_result"
+	MessageTypeBatchRequest    MessageType = "batch_request"
+	MessageTypeBatchOutcome    MessageType = "batch_outcome"
+	MessageTypeSystemHealth    MessageType = "system_health"
+)
+
+// Envelope is the common wrapper for every MQTT message
+type Envelope struct {
+	Version       int             `json:"version"`
+	Type          MessageType     `json:"type"`
+	Timestamp     time.Time       `json:"timestamp"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// WrapMessage marshals payload into an envelope of the given type.
+// correlationID ties related messages together, e.g. a task and its result.
+func WrapMessage(msgType MessageType, correlationID string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", msgType, err)
+	}
+
+	envelope := Envelope{
+		Version:       EnvelopeVersion,
+		Type:          msgType,
+		Timestamp:     time.Now(),
+		CorrelationID: correlationID,
+		Payload:       data,
+	}
+
+	wrapped, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s envelope: %w", msgType, err)
+	}
+	return wrapped, nil
+}
+
+// UnwrapMessage decodes an envelope of the expected type into v and returns
+// the envelope metadata. Messages without a version field predate envelopes
+// and are decoded into v directly, so older publishers keep working.
+func UnwrapMessage(data []byte, expected MessageType, v interface{}) (*Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Version == 0 {
+		if err := json.Unmarshal(data, v); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", expected, err)
+		}
+		return &Envelope{Type: expected, Payload: data}, nil
+	}
+
+	if envelope.Version > EnvelopeVersion {
+		return nil, fmt.Errorf("unsupported envelope version %d (max %d)", envelope.Version, EnvelopeVersion)
+	}
+
+	if envelope.Type != expected {
+		return nil, fmt.Errorf("unexpected message type %q, want %q", envelope.Type, expected)
+	}
+
+	if err := json.Unmarshal(envelope.Payload, v); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s payload: %w", expected, err)
+	}
+	return &envelope, nil
+}
@@ -0,0 +1,90 @@
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkflowTaskEnvelopeRoundTrip(t *testing.T) {
+	task := WorkflowTask{
+		Task: Task{
+			ID:        "wf-1-review-0",
+			Type:      "api_guide",
+			Payload:   map[string]string{"topic": "errors"},
+			CreatedAt: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+			ReplyTo:   "clients/c1/results",
+		},
+		WorkflowID:   "wf-1",
+		Stage:        StageReview,
+		RequiredRole: RoleReviewer,
+		Deadline:     time.Date(2025, 1, 1, 12, 30, 0, 0, time.UTC),
+	}
+
+	data, err := WrapMessage(MessageTypeWorkflowTask, task.WorkflowID, task)
+	if err != nil {
+		t.Fatalf("WrapMessage: %v", err)
+	}
+
+	var got WorkflowTask
+	envelope, err := UnwrapMessage(data, MessageTypeWorkflowTask, &got)
+	if err != nil {
+		t.Fatalf("UnwrapMessage: %v", err)
+	}
+	if !reflect.DeepEqual(got, task) {
+		t.Errorf("round trip = %+v, want %+v", got, task)
+	}
+	if envelope.Version != EnvelopeVersion || envelope.Type != MessageTypeWorkflowTask || envelope.CorrelationID != "wf-1" || envelope.Timestamp.IsZero() {
+		t.Errorf("envelope = %+v", envelope)
+	}
+}
+
+func TestUnwrapMessage(t *testing.T) {
+	wrapped, err := WrapMessage(MessageTypeTask, "t1", Task{ID: "t1", Type: "echo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	future, _ := json.Marshal(Envelope{Version: EnvelopeVersion + 1, Type: MessageTypeTask, Payload: json.RawMessage(`{}`)})
+
+	tests := []struct {
+		name     string
+		data     string
+		expected MessageType
+		wantID   string
+		wantErr  string
+	}{
+		{"envelope", string(wrapped), MessageTypeTask, "t1", ""},
+		{"pre-envelope message", `{"id":"t1","type":"echo"}`, MessageTypeTask, "t1", ""},
+		{"wrong type", string(wrapped), MessageTypeTaskResult, "", "unexpected message type"},
+		{"newer version", string(future), MessageTypeTask, "", "unsupported envelope version"},
+		{"bad payload", `{"version":1,"type":"task","payload":"text"}`, MessageTypeTask, "", "failed to unmarshal task payload"},
+		{"not json", `task`, MessageTypeTask, "", "failed to unmarshal task"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var task Task
+			_, err := UnwrapMessage([]byte(tt.data), tt.expected, &task)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnwrapMessage: %v", err)
+			}
+			if task.ID != tt.wantID {
+				t.Errorf("task ID = %q, want %q", task.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestWrapMessageRejectsUnmarshalable(t *testing.T) {
+	if _, err := WrapMessage(MessageTypeTask, "", make(chan int)); err == nil {
+		t.Error("WrapMessage succeeded for a channel payload")
+	}
+}
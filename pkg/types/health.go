@@ -0,0 +1,56 @@
+package types
+
+import "time"
+
+// HealthState grades a subsystem, or the system as a whole
+type HealthState string
+
+// Health states, healthiest first
+const (
+	HealthOK       HealthState = "ok"
+	HealthDegraded HealthState = "degraded" // Working with reduced capacity
+	HealthDown     HealthState = "down"
+	HealthUnknown  HealthState = "unknown" // Not monitored or could not be checked
+)
+
+// SystemHealth aggregates the health of every subsystem for external alerting
+type SystemHealth struct {
+	State     HealthState     `json:"state"` // Worst state of the monitored subsystems
+	CheckedAt time.Time       `json:"checked_at"`
+	Workers   WorkersHealth   `json:"workers"`
+	RAG       RAGHealth       `json:"rag"`
+	Providers ProvidersHealth `json:"providers"`
+	GPU       GPUHealth       `json:"gpu"`
+}
+
+// WorkersHealth reports the workers currently sending status updates
+type WorkersHealth struct {
+	State        HealthState        `json:"state"`
+	Live         int                `json:"live"`
+	Busy         int                `json:"busy"`
+	Errored      int                `json:"errored"`
+	Roles        map[WorkerRole]int `json:"roles,omitempty"`         // Live workers per role
+	MissingRoles []WorkerRole       `json:"missing_roles,omitempty"` // Workflow roles without a live worker
+}
+
+// RAGHealth reports whether the knowledge base answers
+type RAGHealth struct {
+	State     HealthState `json:"state"`
+	Available bool        `json:"available"`
+}
+
+// ProvidersHealth reports the AI providers with an API key and their latest health check
+type ProvidersHealth struct {
+	State     HealthState       `json:"state"`
+	Healthy   []string          `json:"healthy,omitempty"`
+	Unhealthy map[string]string `json:"unhealthy,omitempty"` // Provider -> reason of its failed check
+}
+
+// GPUHealth reports GPU memory in MB
+type GPUHealth struct {
+	State HealthState `json:"state"`
+	Total uint64      `json:"total_mb"`
+	Used  uint64      `json:"used_mb"`
+	Free  uint64      `json:"free_mb"`
+	Error string      `json:"error,omitempty"`
+}
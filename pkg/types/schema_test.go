@@ -0,0 +1,88 @@
+package types
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestJSONSchemaTask(t *testing.T) {
+	schema := JSONSchema("Task", Task{})
+	if schema["$schema"] != JSONSchemaDraft || schema["title"] != "Task" || schema["type"] != "object" {
+		t.Fatalf("schema header = %v %v %v", schema["$schema"], schema["title"], schema["type"])
+	}
+
+	required := schema["required"].([]string)
+	for _, field := range []string{"id", "type", "payload", "created_at", "priority"} {
+		if !slices.Contains(required, field) {
+			t.Errorf("required = %v, missing %s", required, field)
+		}
+	}
+	for _, field := range []string{"reply_to", "force_provider", "force_model"} {
+		if slices.Contains(required, field) {
+			t.Errorf("optional field %s is required", field)
+		}
+	}
+
+	properties := schema["properties"].(map[string]any)
+	tests := []struct {
+		field string
+		key   string
+		want  any
+	}{
+		{"id", "type", "string"},
+		{"priority", "type", "integer"},
+		{"created_at", "format", "date-time"},
+	}
+	for _, tt := range tests {
+		property := properties[tt.field].(map[string]any)
+		if property[tt.key] != tt.want {
+			t.Errorf("%s %s = %v, want %v", tt.field, tt.key, property[tt.key], tt.want)
+		}
+	}
+}
+
+func TestJSONSchemaFlattensEmbeddedStructs(t *testing.T) {
+	schema := JSONSchema("WorkflowTask", WorkflowTask{})
+	properties := schema["properties"].(map[string]any)
+	for _, field := range []string{"id", "type", "workflow_id", "stage", "required_role"} {
+		if _, ok := properties[field]; !ok {
+			t.Errorf("WorkflowTask schema is missing %s", field)
+		}
+	}
+	if _, nested := properties["Task"]; nested {
+		t.Error("embedded Task is nested instead of flattened")
+	}
+}
+
+func TestJSONSchemaCoversEncoding(t *testing.T) {
+	for name, value := range MessageTypes() {
+		t.Run(name, func(t *testing.T) {
+			schema := JSONSchema(name, value)
+			if _, err := json.Marshal(schema); err != nil {
+				t.Fatalf("schema does not encode: %v", err)
+			}
+
+			// Every key encoding/json writes for the zero value has a property
+			data, err := json.Marshal(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var encoded map[string]any
+			if err := json.Unmarshal(data, &encoded); err != nil {
+				t.Fatal(err)
+			}
+			properties := schema["properties"].(map[string]any)
+			for key := range encoded {
+				if _, ok := properties[key]; !ok {
+					t.Errorf("encoded key %s has no schema property", key)
+				}
+			}
+			for _, key := range schema["required"].([]string) {
+				if _, ok := encoded[key]; !ok {
+					t.Errorf("required key %s is not always encoded", key)
+				}
+			}
+		})
+	}
+}
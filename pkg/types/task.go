@@ -1,6 +1,10 @@
 package types
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -11,17 +15,76 @@ type Task struct {
 	Payload   map[string]string `json:"payload"`
 	CreatedAt time.Time         `json:"created_at"`
 	Priority  int               `json:"priority"`
+	ReplyTo   string            `json:"reply_to,omitempty"` // Extra topic the result is published to
+
+	// Debugging escape hatch: pin the task to a provider ("local" or an API
+	// provider such as "groq") and optionally a model, bypassing routing
+	ForceProvider string `json:"force_provider,omitempty"`
+	ForceModel    string `json:"force_model,omitempty"`
+}
+
+// Validate checks that the task carries the fields workers require
+func (t *Task) Validate() error {
+	if t.ID == "" {
+		return fmt.Errorf("task id is required")
+	}
+	if t.Type == "" {
+		return fmt.Errorf("task %s: type is required", t.ID)
+	}
+	if strings.ContainsAny(t.ReplyTo, "+#") {
+		return fmt.Errorf("task %s: reply_to must not contain MQTT wildcards", t.ID)
+	}
+	return nil
+}
+
+// ResultStatus says how a task ended, separating a negative decision from a
+// system failure
+type ResultStatus string
+
+const (
+	StatusCompleted ResultStatus = "completed" // The task produced its output
+	StatusRejected  ResultStatus = "rejected"  // The task worked and decided against the document
+	StatusFailed    ResultStatus = "failed"    // The task errored or timed out
+	StatusCancelled ResultStatus = "cancelled" // The task was abandoned, e.g. on worker shutdown
+)
+
+// StatusForError returns the status of a task that finished with err, and
+// with a rejection decision when it finished without one
+func StatusForError(err error, rejected bool) ResultStatus {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return StatusCancelled
+	case err != nil:
+		return StatusFailed
+	case rejected:
+		return StatusRejected
+	default:
+		return StatusCompleted
+	}
 }
 
 // TaskResult represents the result of processing a task
 type TaskResult struct {
-	TaskID      string    `json:"task_id"`
-	WorkerID    string    `json:"worker_id"`
-	Success     bool      `json:"success"`
-	Result      string    `json:"result"`
-	Error       string    `json:"error,omitempty"`
-	ProcessedAt time.Time `json:"processed_at"`
-	Duration    int64     `json:"duration_ms"`
+	TaskID      string       `json:"task_id"`
+	WorkerID    string       `json:"worker_id"`
+	Success     bool         `json:"success"` // False for failed and cancelled tasks; rejections succeed
+	Status      ResultStatus `json:"status,omitempty"`
+	Result      string       `json:"result"`
+	Error       string       `json:"error,omitempty"`
+	ProcessedAt time.Time    `json:"processed_at"`
+	Duration    int64        `json:"duration_ms"`
+}
+
+// ResultStatus returns the result's status, deriving it from Success for
+// results from workers that predate the status field
+func (r TaskResult) ResultStatus() ResultStatus {
+	if r.Status != "" {
+		return r.Status
+	}
+	if r.Success {
+		return StatusCompleted
+	}
+	return StatusFailed
 }
 
 // WorkerStatus represents the current status of a worker
@@ -32,4 +95,14 @@ type WorkerStatus struct {
 	TasksTotal  int       `json:"tasks_total"`
 	TasksError  int       `json:"tasks_error"`
 	CurrentTask string    `json:"current_task,omitempty"`
+
+	// Progress is set while a streamed generation for CurrentTask is running
+	Progress *TaskProgress `json:"progress,omitempty"`
+}
+
+// TaskProgress reports how far a generation has got
+type TaskProgress struct {
+	Tokens    int `json:"tokens"`     // Tokens generated so far (estimated)
+	MaxTokens int `json:"max_tokens"` // Token limit of the generation
+	Percent   int `json:"percent"`    // Tokens against the limit, 0-100
 }
@@ -0,0 +1,79 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestTaskValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		task    Task
+		wantErr bool
+	}{
+		{"valid", Task{ID: "t1", Type: "echo"}, false},
+		{"reply topic", Task{ID: "t1", Type: "echo", ReplyTo: "clients/c1/results"}, false},
+		{"missing id", Task{Type: "echo"}, true},
+		{"missing type", Task{ID: "t1"}, true},
+		{"single level wildcard", Task{ID: "t1", Type: "echo", ReplyTo: "clients/+/results"}, true},
+		{"multi level wildcard", Task{ID: "t1", Type: "echo", ReplyTo: "clients/#"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.task.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+			workflowTask := WorkflowTask{Task: tt.task, WorkflowID: "wf-1", Stage: "development", RequiredRole: "developer"}
+			if err := workflowTask.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("WorkflowTask.Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStatusForError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		rejected bool
+		want     ResultStatus
+	}{
+		{"completed", nil, false, StatusCompleted},
+		{"rejected", nil, true, StatusRejected},
+		{"failed", errors.New("model crashed"), false, StatusFailed},
+		{"failure outranks rejection", errors.New("model crashed"), true, StatusFailed},
+		{"timed out", context.DeadlineExceeded, false, StatusFailed},
+		{"cancelled", fmt.Errorf("processing: %w", context.Canceled), false, StatusCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StatusForError(tt.err, tt.rejected); got != tt.want {
+				t.Errorf("StatusForError() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskResultStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		result TaskResult
+		want   ResultStatus
+	}{
+		{"explicit status", TaskResult{Success: true, Status: StatusRejected}, StatusRejected},
+		{"legacy success", TaskResult{Success: true}, StatusCompleted},
+		{"legacy failure", TaskResult{Success: false}, StatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.ResultStatus(); got != tt.want {
+				t.Errorf("ResultStatus() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
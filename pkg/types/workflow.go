@@ -1,5 +1,10 @@
 package types
 
+import (
+	"fmt"
+	"time"
+)
+
 // WorkerRole defines the role of a worker in the pipeline
 type WorkerRole string
 
@@ -9,6 +14,7 @@ const (
 	RoleApprover     WorkerRole = "approver"
 	RoleTester       WorkerRole = "tester"
 	RoleOrchestrator WorkerRole = "orchestrator"
+	RoleEmbedder     WorkerRole = "embedder" // Embeds and stores documents for ingestion pipelines
 )
 
 // WorkflowStage represents a stage in the autonomous pipeline
@@ -23,6 +29,43 @@ const (
 	StageFailed      WorkflowStage = "failed"
 )
 
+// RequiredRole returns the worker role that handles the stage
+func (s WorkflowStage) RequiredRole() WorkerRole {
+	switch s {
+	case StageDevelopment:
+		return RoleDeveloper
+	case StageReview:
+		return RoleReviewer
+	case StageApproval:
+		return RoleApprover
+	case StageTesting:
+		return RoleTester
+	default:
+		return RoleOrchestrator
+	}
+}
+
+// Next returns the stage that follows a successful completion of s
+func (s WorkflowStage) Next() WorkflowStage {
+	switch s {
+	case StageDevelopment:
+		return StageReview
+	case StageReview:
+		return StageApproval
+	case StageApproval:
+		return StageTesting
+	case StageTesting:
+		return StageCompleted
+	default:
+		return StageFailed
+	}
+}
+
+// IsTerminal reports whether the workflow has finished
+func (s WorkflowStage) IsTerminal() bool {
+	return s == StageCompleted || s == StageFailed
+}
+
 // WorkflowTask extends Task with role-based information
 type WorkflowTask struct {
 	Task
@@ -31,9 +74,74 @@ type WorkflowTask struct {
 	RequiredRole   WorkerRole    `json:"required_role"`
 	PreviousOutput string        `json:"previous_output,omitempty"`
 	ReviewFeedback string        `json:"review_feedback,omitempty"`
+	ReviewItems    []ReviewItem  `json:"review_items,omitempty"` // Structured form of ReviewFeedback, when the reviewer gave one
 	RAGContext     string        `json:"rag_context,omitempty"`
 	RetryCount     int           `json:"retry_count"`
 	MaxRetries     int           `json:"max_retries"`
+	Deadline       time.Time     `json:"deadline,omitempty"` // Overall workflow deadline set by the orchestrator
+}
+
+// Validate checks that the workflow task can be routed to a stage worker
+func (t *WorkflowTask) Validate() error {
+	if err := t.Task.Validate(); err != nil {
+		return err
+	}
+	if t.WorkflowID == "" {
+		return fmt.Errorf("task %s: workflow_id is required", t.ID)
+	}
+	if t.Stage == "" {
+		return fmt.Errorf("task %s: stage is required", t.ID)
+	}
+	if t.RequiredRole == "" {
+		return fmt.Errorf("task %s: required_role is required", t.ID)
+	}
+	return nil
+}
+
+// DeadlineExceeded reports whether the workflow deadline has passed at now
+func (t *WorkflowTask) DeadlineExceeded(now time.Time) bool {
+	return !t.Deadline.IsZero() && now.After(t.Deadline)
+}
+
+// CapabilitiesRequest asks the orchestrator what it supports; the reply is
+// published to ReplyTo
+type CapabilitiesRequest struct {
+	ReplyTo string `json:"reply_to"`
+}
+
+// OrchestratorCapabilities lists what the orchestrator can currently serve
+type OrchestratorCapabilities struct {
+	DocumentTypes []string `json:"document_types"`
+	Models        []string `json:"models"`
+}
+
+// DeadLetter records the last task of a failed workflow so it can be
+// inspected and republished to its stage topic
+type DeadLetter struct {
+	Task           WorkflowTask `json:"task"`
+	Topic          string       `json:"topic"` // Stage topic the task was published on
+	Reason         string       `json:"reason"`
+	Errors         []string     `json:"errors,omitempty"`       // Every failure recorded for the workflow, oldest first
+	PayloadSize    int          `json:"payload_size,omitempty"` // Set when a worker rejected the raw message unread
+	DeadLetteredAt time.Time    `json:"dead_lettered_at"`
+}
+
+// ReviewSeverity ranks how much a review item matters
+type ReviewSeverity string
+
+// Review severities, most severe first
+const (
+	SeverityCritical ReviewSeverity = "critical" // Wrong or unsafe; the document cannot ship
+	SeverityMajor    ReviewSeverity = "major"    // Missing or misleading content
+	SeverityMinor    ReviewSeverity = "minor"    // Style, wording or polish
+)
+
+// ReviewItem is one issue a reviewer raised against a document
+type ReviewItem struct {
+	Severity   ReviewSeverity `json:"severity"`
+	Location   string         `json:"location,omitempty"` // Section, line or example the item refers to
+	Message    string         `json:"message"`
+	Suggestion string         `json:"suggestion,omitempty"`
 }
 
 // WorkflowResult extends TaskResult with workflow information
@@ -44,8 +152,11 @@ type WorkflowResult struct {
 	WorkerRole     WorkerRole    `json:"worker_role"`
 	NextStage      WorkflowStage `json:"next_stage,omitempty"`
 	ReviewFeedback string        `json:"review_feedback,omitempty"`
+	ReviewItems    []ReviewItem  `json:"review_items,omitempty"` // Issues the reviewer listed, parsed from Result
 	Approved       bool          `json:"approved"`
 	RequiresRetry  bool          `json:"requires_retry"`
+	Truncated      bool          `json:"truncated,omitempty"` // Output hit the model token limit
+	ServedBy       string        `json:"served_by,omitempty"` // Fallback model that replaced the routed one
 }
 
 // WorkerCapabilities defines what a worker can do
@@ -73,6 +184,41 @@ type RAGQuery struct {
 	TopK       int      `json:"top_k"`
 	Threshold  float64  `json:"threshold"`
 	Filters    []string `json:"filters,omitempty"`
+	TenantID   string   `json:"tenant_id,omitempty"` // Searches the tenant's copy of Collection when set
+}
+
+// IngestionTask asks an embedder to embed documents and store them in a collection
+type IngestionTask struct {
+	ID         string        `json:"id"`
+	Collection string        `json:"collection"`
+	Documents  []RAGDocument `json:"documents"`
+	ReplyTo    string        `json:"reply_to,omitempty"`  // Extra topic to publish the result to
+	TenantID   string        `json:"tenant_id,omitempty"` // Stores into the tenant's copy of Collection when set
+}
+
+// Validate checks that the ingestion task has somewhere to store its documents
+func (t *IngestionTask) Validate() error {
+	if t.ID == "" {
+		return fmt.Errorf("ingestion task ID is required")
+	}
+	if t.Collection == "" {
+		return fmt.Errorf("ingestion task %s: collection is required", t.ID)
+	}
+	if len(t.Documents) == 0 {
+		return fmt.Errorf("ingestion task %s: no documents", t.ID)
+	}
+	return nil
+}
+
+// IngestionResult reports how many documents of an ingestion task were stored
+type IngestionResult struct {
+	TaskID      string    `json:"task_id"`
+	WorkerID    string    `json:"worker_id"`
+	Collection  string    `json:"collection"`
+	Stored      int       `json:"stored"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+	ProcessedAt time.Time `json:"processed_at"`
 }
 
 // RAGResponse represents response from knowledge base
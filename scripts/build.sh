@@ -281,6 +281,10 @@ function main() {
     build_go_binary "role-worker" "./cmd/role-worker"
     build_go_binary "client" "./cmd/client"
     build_go_binary "rag-service" "./cmd/rag-service"
+    build_go_binary "model-daemon" "./cmd/model-daemon"
+    build_go_binary "deadletter" "./cmd/deadletter"
+    build_go_binary "schema" "./cmd/schema"
+    build_go_binary "monitor" "./cmd/monitor"
     
     log_info "Build completed successfully"
     log_info "Binaries available in: $BUILD_DIR_GLOBAL/"